@@ -0,0 +1,382 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"hyperliquid-recon/services"
+	"log"
+	"net/http"
+	"time"
+)
+
+// GetFeeSimulation handles GET /api/analytics/fees requests
+// Recomputes the fees paid on the cached fills of address under every fee
+// tier, with an optional staking discount (staking=gold) and window (days=30).
+func (h *Handler) GetFeeSimulation(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, trades, ok := analyticsTrades(w, r, t)
+	if !ok {
+		return
+	}
+
+	simulation, err := services.SimulateFees(address, trades, r.URL.Query().Get("staking"))
+	if errors.Is(err, services.ErrUnknownStakingTier) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	simulation.Label = t.ReconService.Label(address)
+
+	respondWithJSON(w, http.StatusOK, simulation)
+}
+
+// GetPnLSeries handles GET /api/analytics/series requests
+// Returns daily and cumulative P&L per coin and in total for the cached trades
+// of address, optionally for one coin (coin=ETH) and recent days (days=30).
+// With market=1 each coin line also carries the recorded exchange-wide volume
+// and open interest of each day. points=365 merges the days into at most that
+// many buckets.
+func (h *Handler) GetPnLSeries(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var query struct {
+		Points int  `query:"points" validate:"min=1"`
+		Market bool `query:"market"`
+	}
+	if !decodeQuery(w, r, &query) {
+		return
+	}
+
+	address, trades, ok := analyticsTrades(w, r, t)
+	if !ok {
+		return
+	}
+
+	series := services.BuildPnLSeries(address, trades, services.Symbols().Canonical(r.URL.Query().Get("coin")))
+	series.Label = t.ReconService.Label(address)
+	if query.Market {
+		services.AddMarketContext(&series, services.CurrentMarketContexts())
+	}
+	services.DownsampleSeries(&series, query.Points)
+
+	respondWithJSON(w, http.StatusOK, series)
+}
+
+// GetPnLHeatmap handles GET /api/analytics/calendar requests
+// Returns the daily P&L of one year (default: the current year) of address
+// bucketed into intensity levels for a calendar heatmap.
+func (h *Handler) GetPnLHeatmap(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	query := struct {
+		Year int `query:"year" validate:"min=2000,max=9999"`
+	}{Year: time.Now().Year()}
+	if !decodeQuery(w, r, &query) {
+		return
+	}
+	year := query.Year
+
+	address, ok := analyticsAddress(w, r, t)
+	if !ok {
+		return
+	}
+
+	records, exists := t.ReconService.CachedDailyRecords(address)
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "no cached trades for address; refresh it first")
+		return
+	}
+
+	heatmap := services.BuildPnLHeatmap(address, records, year)
+	heatmap.Label = t.ReconService.Label(address)
+
+	respondWithJSON(w, http.StatusOK, heatmap)
+}
+
+// GetPerformanceProfile handles GET /api/analytics/profile requests
+// Aggregates realized P&L and win rate of address by weekday and hour of day
+// in the timezone tz (an IANA name such as America/New_York; default: the server's).
+func (h *Handler) GetPerformanceProfile(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	loc := time.Local
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		parsed, err := time.LoadLocation(tz)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("unknown timezone %q", tz))
+			return
+		}
+		loc = parsed
+	}
+
+	address, trades, since, ok := analyticsHistory(w, r, t)
+	if !ok {
+		return
+	}
+
+	profile := services.BuildPerformanceProfile(address, trades, since, loc)
+	profile.Label = t.ReconService.Label(address)
+
+	respondWithJSON(w, http.StatusOK, profile)
+}
+
+// GetTradeDistribution handles GET /api/analytics/distribution requests
+// Reports the fill notional histogram of address and, per month or week
+// (period=week), how much of the realized P&L came from the top five trades
+// and how concentrated volume was by coin.
+func (h *Handler) GetTradeDistribution(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, trades, since, ok := analyticsHistory(w, r, t)
+	if !ok {
+		return
+	}
+
+	distribution, err := services.BuildTradeDistribution(address, trades, since, r.URL.Query().Get("period"))
+	if errors.Is(err, services.ErrInvalidPeriod) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	distribution.Label = t.ReconService.Label(address)
+
+	respondWithJSON(w, http.StatusOK, distribution)
+}
+
+// GetDirection handles GET /api/analytics/direction requests
+// Classes each trading day of address as opening, closing or churn by its
+// position changes, over the last days days if set, and totals the P&L of
+// each class. day=utc buckets by UTC day instead of the configured basis.
+func (h *Handler) GetDirection(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	basis := models.DayBasis(config.DayBasis)
+	if day := r.URL.Query().Get("day"); day != "" {
+		parsed, err := services.ParseDayBasis(day)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		basis = parsed
+	}
+
+	address, trades, since, ok := analyticsHistory(w, r, t)
+	if !ok {
+		return
+	}
+
+	analysis := services.AnalyzeDirection(address, trades, since, basis)
+	analysis.Label = t.ReconService.Label(address)
+	respondWithJSON(w, http.StatusOK, analysis)
+}
+
+// GetRoundTrips handles GET /api/roundtrips requests
+// Pairs the entries and exits of address into completed round trips by method
+// (fifo, lifo or position), optionally for one coin and recent days. With
+// excursions=true, measures how far each went against and for the position
+// from candles. Returns JSON, or CSV with format=csv, localized like the
+// trades export.
+func (h *Handler) GetRoundTrips(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != services.FormatCSV {
+		respondWithError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	report, ok := roundTripReport(w, r, t)
+	if !ok {
+		return
+	}
+	if r.URL.Query().Get("excursions") == "true" {
+		if err := t.ReconService.AddExcursions(r.Context(), &report); err != nil {
+			log.Printf("Error measuring excursions for %s: %v", report.Address, err)
+			respondWithError(w, http.StatusBadGateway, "Failed to fetch candles. Please try again later.")
+			return
+		}
+	}
+
+	if format != services.FormatCSV {
+		respondWithJSON(w, http.StatusOK, report)
+		return
+	}
+
+	csvFormat, ok := parseCSVFormat(w, r)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	if err := services.WriteRoundTripsCSV(&buf, report.RoundTrips, csvFormat); err != nil {
+		log.Printf("Error encoding round trips for %s: %v", report.Address, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to export round trips")
+		return
+	}
+	services.WriteExportFooter(&buf, csvFormat)
+	writeExport(w, t, services.ContentType(services.FormatCSV), fmt.Sprintf("roundtrips_%s.csv", report.Address), buf.Bytes())
+}
+
+// GetHoldTimes handles GET /api/analytics/holdtime requests
+// Computes mean and median holding durations and P&L by hold time (<1h,
+// 1-24h, >1d) from the round trips of address, per coin and overall. Takes
+// the same parameters as /api/roundtrips.
+func (h *Handler) GetHoldTimes(w http.ResponseWriter, r *http.Request) {
+	report, ok := roundTripReport(w, r, tenantFrom(r))
+	if !ok {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, services.AnalyzeHoldTimes(report))
+}
+
+// GetChart handles GET /api/chart requests
+// Returns the candles of coin between the from and to dates (default: the
+// last ChartDays days) with the fills of address in the same window, for
+// plotting entries and exits on the price. interval sets the candle interval,
+// picked to suit the range if unset.
+func (h *Handler) GetChart(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	coin := services.Symbols().Canonical(r.URL.Query().Get("coin"))
+	if coin == "" {
+		respondWithError(w, http.StatusBadRequest, "coin parameter is required")
+		return
+	}
+	today := time.Now().Format("2006-01-02")
+	from, to, ok := parseDateRange(w, r, "", today)
+	if !ok {
+		return
+	}
+	if from == "" {
+		end, _ := time.Parse("2006-01-02", to)
+		from = end.AddDate(0, 0, -config.ChartDays+1).Format("2006-01-02")
+	}
+
+	address, ok := analyticsAddress(w, r, t)
+	if !ok {
+		return
+	}
+	trades, exists := t.ReconService.CachedTrades(address)
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "no cached trades for address; refresh it first")
+		return
+	}
+
+	chart, err := t.ReconService.Chart(r.Context(), address, trades, coin, from, to, r.URL.Query().Get("interval"))
+	if errors.Is(err, services.ErrInvalidChartInterval) || errors.Is(err, services.ErrTooManyCandles) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching %s candles: %v", coin, err)
+		respondWithError(w, http.StatusBadGateway, "Failed to fetch candles. Please try again later.")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chart)
+}
+
+// roundTripReport builds the round trips selected by the address, method,
+// coin and days parameters, and writes an error response if it can't
+func roundTripReport(w http.ResponseWriter, r *http.Request, t *services.Tenant) (models.RoundTripReport, bool) {
+	address, trades, since, ok := analyticsHistory(w, r, t)
+	if !ok {
+		return models.RoundTripReport{}, false
+	}
+	if coin := r.URL.Query().Get("coin"); coin != "" {
+		filtered := trades[:0]
+		for _, trade := range trades {
+			if trade.Coin == coin {
+				filtered = append(filtered, trade)
+			}
+		}
+		trades = filtered
+	}
+
+	method := r.URL.Query().Get("method")
+	if method == "" {
+		method = config.RoundTripMethod
+	}
+	report, err := services.BuildRoundTrips(address, trades, method, since)
+	if errors.Is(err, services.ErrInvalidRoundTripMethod) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return models.RoundTripReport{}, false
+	}
+	report.Label = t.ReconService.Label(address)
+	return report, true
+}
+
+// analyticsHistory returns all cached trades of analyticsAddress along with
+// the start of the window set by the days parameter. Analytics that rebuild
+// positions need the whole history even when only recent days are reported.
+func analyticsHistory(w http.ResponseWriter, r *http.Request, t *services.Tenant) (string, []models.Trade, time.Time, bool) {
+	address, ok := analyticsAddress(w, r, t)
+	if !ok {
+		return "", nil, time.Time{}, false
+	}
+	since, ok := analyticsCutoff(w, r)
+	if !ok {
+		return "", nil, time.Time{}, false
+	}
+
+	trades, exists := t.ReconService.CachedTrades(address)
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "no cached trades for address; refresh it first")
+		return "", nil, time.Time{}, false
+	}
+	return address, trades, since, true
+}
+
+// analyticsTrades returns the cached trades analytics endpoints work on: those
+// of analyticsAddress, limited to the last days days if that parameter is set. It writes an error
+// response if they can't be found.
+func analyticsTrades(w http.ResponseWriter, r *http.Request, t *services.Tenant) (string, []models.Trade, bool) {
+	address, ok := analyticsAddress(w, r, t)
+	if !ok {
+		return "", nil, false
+	}
+
+	trades, exists := t.ReconService.CachedTrades(address)
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "no cached trades for address; refresh it first")
+		return "", nil, false
+	}
+
+	cutoff, ok := analyticsCutoff(w, r)
+	if !ok {
+		return "", nil, false
+	}
+	for len(trades) > 0 && trades[0].Time.Before(cutoff) {
+		trades = trades[1:]
+	}
+
+	return address, trades, true
+}
+
+// analyticsCutoff returns the start of the window set by the days parameter,
+// or the zero time if it is unset, and writes an error response if it is invalid
+func analyticsCutoff(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	var query struct {
+		Days int `query:"days" validate:"min=1"`
+	}
+	if !decodeQuery(w, r, &query) {
+		return time.Time{}, false
+	}
+	if query.Days == 0 {
+		return time.Time{}, true
+	}
+	return time.Now().AddDate(0, 0, -query.Days), true
+}
+
+// analyticsAddress resolves the address parameter of analytics endpoints,
+// defaulting to the address of the current summary, and writes an error
+// response if there is none
+func analyticsAddress(w http.ResponseWriter, r *http.Request, t *services.Tenant) (string, bool) {
+	address := t.ReconService.GetPnLSummary().Address
+	if input := r.URL.Query().Get("address"); input != "" || address == "" {
+		return resolveAddress(w, t, input)
+	}
+	return address, true
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"errors"
+	"hyperliquid-recon/services"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// archiveAddress reads the required address parameter of an archive request,
+// and writes an error response if it is missing or archiving is off
+func archiveAddress(w http.ResponseWriter, r *http.Request, t *services.Tenant) (string, bool) {
+	if services.CurrentFillArchive() == nil {
+		respondWithError(w, http.StatusNotFound, "fill archive is not enabled; set RECON_FILL_ARCHIVE_DIR")
+		return "", false
+	}
+	input := r.URL.Query().Get("address")
+	if input == "" {
+		respondWithError(w, http.StatusBadRequest, "address parameter is required")
+		return "", false
+	}
+	return resolveAddress(w, t, input)
+}
+
+// GetArchivedResponses handles GET /api/archive requests
+// Lists the archived fills responses of address whose windows overlap from
+// and to (YYYY-MM-DD, inclusive; default everything), oldest window first.
+func (h *Handler) GetArchivedResponses(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, ok := archiveAddress(w, r, t)
+	if !ok {
+		return
+	}
+	from, to, ok := parseDateRange(w, r, "", "")
+	if !ok {
+		return
+	}
+	var start, end time.Time
+	if from != "" {
+		start, _ = time.Parse("2006-01-02", from)
+	}
+	if to != "" {
+		end, _ = time.Parse("2006-01-02", to)
+		end = end.AddDate(0, 0, 1)
+	}
+
+	responses, err := t.ReconService.ArchivedResponses(address, start, end)
+	if errors.Is(err, services.ErrNotCached) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error listing archived responses of %s: %v", address, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read the fill archive")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, responses)
+}
+
+// GetArchivedResponse handles GET /api/archive/{name} requests
+// Returns an archived fills response of address byte for byte as the API sent it.
+func (h *Handler) GetArchivedResponse(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, ok := archiveAddress(w, r, t)
+	if !ok {
+		return
+	}
+
+	raw, err := t.ReconService.ArchivedResponse(address, mux.Vars(r)["name"])
+	if errors.Is(err, services.ErrNotCached) || errors.Is(err, services.ErrArchiveNotFound) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error reading archived response of %s: %v", address, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read the fill archive")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(raw)
+}
+
+// AuditArchive handles GET /api/archive/audit requests
+// Derives the trades of address again from its archived responses and
+// compares them with the cache.
+func (h *Handler) AuditArchive(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, ok := archiveAddress(w, r, t)
+	if !ok {
+		return
+	}
+
+	audit, err := t.ReconService.AuditArchive(address)
+	if errors.Is(err, services.ErrNotCached) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error auditing archive of %s: %v", address, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to audit the fill archive")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, audit)
+}
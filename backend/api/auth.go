@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIKeyHeader carries the caller's API key; "Authorization: Bearer <key>" and
+// the apiKey query parameter (for calendar subscriptions) are also accepted
+const APIKeyHeader = "X-API-Key"
+
+type tenantContextKey struct{}
+
+// Authenticate is middleware that resolves the tenant of each API request from
+// its API key and rejects requests without a valid key. The health check, the
+// version endpoints, signed download URLs and the frontend are served without
+// authentication.
+func (h *Handler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/api/health" || r.URL.Path == "/api/versions" || r.URL.Path == "/api/version" || strings.HasPrefix(r.URL.Path, "/api/downloads/") || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Admin endpoints act on every tenant, so they need the admin key
+		if strings.HasPrefix(r.URL.Path, "/api/admin/") {
+			if config.AdminAPIKey == "" {
+				respondWithError(w, http.StatusForbidden, "admin endpoints are disabled; set RECON_ADMIN_API_KEY to enable them")
+				return
+			}
+			if !authenticateAdmin(apiKey(r)) {
+				respondWithError(w, http.StatusUnauthorized, "the admin API key is required")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := apiKey(r)
+		tenant, ok := h.tenants.Authenticate(key)
+		if !ok {
+			respondWithError(w, http.StatusUnauthorized, "a valid API key is required")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), tenantContextKey{}, tenant)
+		if policy := h.tenants.Policy(key); policy != nil {
+			ctx = services.WithKeyPolicy(ctx, policy)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticateAdmin checks key against the admin API key. Without one, no key
+// matches, so admin endpoints stay closed in every mode.
+func authenticateAdmin(key string) bool {
+	if config.AdminAPIKey == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(key), []byte(config.AdminAPIKey)) == 1
+}
+
+// tenantFrom returns the tenant Authenticate attached to the request
+func tenantFrom(r *http.Request) *services.Tenant {
+	return r.Context().Value(tenantContextKey{}).(*services.Tenant)
+}
+
+// policyFrom returns the policy of the role of the request's API key, or nil
+// if the key has no role and its refreshes are unlimited. The services enforce
+// it on every refresh made with the request's context; handlers check it first
+// to refuse a refresh before queueing or starting it.
+func policyFrom(r *http.Request) *services.KeyPolicy {
+	return services.KeyPolicyFrom(r.Context())
+}
+
+// PolicyRefusal describes a refresh refused by the policy of the API key's role
+type PolicyRefusal struct {
+	Role    string     `json:"role"`
+	Reason  string     `json:"reason"`            // "range" or "frequency"
+	MaxDays int        `json:"maxDays,omitempty"` // Set for range refusals
+	RetryAt *time.Time `json:"retryAt,omitempty"` // Set for frequency refusals
+}
+
+// admittedByPolicy reports whether err, the verdict of a key policy, admits
+// a refresh, writing the refusal if it doesn't
+func admittedByPolicy(w http.ResponseWriter, err error) bool {
+	var refusal *services.PolicyError
+	if errors.As(err, &refusal) {
+		respondWithPolicyError(w, refusal)
+		return false
+	}
+	return true
+}
+
+// respondWithPolicyError writes the response to a refresh refused by a key
+// policy: a 403 for a range the role may not refresh, or a 429 with
+// Retry-After for a refresh too soon after the last
+func respondWithPolicyError(w http.ResponseWriter, refusal *services.PolicyError) {
+	body := ErrorResponse{Error: refusal.Error(), Policy: &PolicyRefusal{Role: refusal.Role, Reason: "range", MaxDays: refusal.MaxDays}}
+	if errors.Is(refusal, services.ErrRefreshTooSoon) {
+		body.Policy = &PolicyRefusal{Role: refusal.Role, Reason: "frequency", RetryAt: &refusal.RetryAt}
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(refusal.RetryAt).Seconds())+1))
+		respondWithJSON(w, http.StatusTooManyRequests, body)
+		return
+	}
+	respondWithJSON(w, http.StatusForbidden, body)
+}
+
+// apiKey returns the API key sent with the request, if any
+func apiKey(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return r.URL.Query().Get("apiKey")
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"hyperliquid-recon/services"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Backup handles GET /api/admin/backup requests
+// Streams a snapshot of every tenant's stores, the shared stores and the
+// settings files, in the format of "recon backup".
+func (h *Handler) Backup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/zstd")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"snapshot_%s.tar.zst\"", time.Now().UTC().Format("20060102T150405Z")))
+	manifest, err := h.tenants.Backup(w)
+	if err != nil {
+		// The response has started, so the truncated archive fails verification
+		log.Printf("Backup failed after %d files: %v", len(manifest.Files), err)
+		return
+	}
+	log.Printf("Backup of %d files downloaded", len(manifest.Files))
+}
+
+// Restore handles POST /api/admin/restore requests
+// The request body is a snapshot from /api/admin/backup or "recon backup".
+// It is verified and restored on the next startup, since the stores in use
+// can't be replaced while serving.
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	if config.DataDir == "" {
+		respondWithError(w, http.StatusConflict, "restoring through the API needs a data directory; stop the service and run recon restore instead")
+		return
+	}
+
+	snapshot, err := io.ReadAll(http.MaxBytesReader(w, r.Body, config.RestoreMaxBytes))
+	if err != nil {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "upload is too large")
+		return
+	}
+
+	manifest, err := services.StageRestore(services.NewDataDir(config.DataDir), snapshot)
+	if errors.Is(err, services.ErrInvalidBackup) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error staging restore: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to stage snapshot")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, models.RestoreResult{Manifest: manifest, Restored: []string{}, Staged: true})
+}
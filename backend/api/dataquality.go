@@ -0,0 +1,21 @@
+package api
+
+import "net/http"
+
+// GetDataQualityAlerts handles GET /api/dataquality requests
+// Lists the data-quality alerts raised by recent refreshes, newest first,
+// optionally of one address.
+func (h *Handler) GetDataQualityAlerts(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var address string
+	if input := r.URL.Query().Get("address"); input != "" {
+		resolved, ok := resolveAddress(w, t, input)
+		if !ok {
+			return
+		}
+		address = resolved
+	}
+
+	respondWithJSON(w, http.StatusOK, t.DataQuality.Recent(address))
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"hyperliquid-recon/services"
+	"log"
+	"net/http"
+	"time"
+)
+
+// DemoDataset handles GET /api/admin/demo requests
+// Streams an anonymized copy of a tenant's cached trades (tenant, default
+// "default") as a zstd-compressed tar of CSV files for a public demo
+// instance. scale, jitter, priceJitter, timeJitter, shiftDays and seed
+// control the disguise.
+func (h *Handler) DemoDataset(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.adminTenant(w, r)
+	if !ok {
+		return
+	}
+
+	query := struct {
+		Scale       float64       `query:"scale" validate:"min=0"`
+		Jitter      float64       `query:"jitter" validate:"min=0,max=0.5"`
+		PriceJitter float64       `query:"priceJitter" validate:"min=0,max=0.1"`
+		TimeJitter  time.Duration `query:"timeJitter" validate:"min=0"`
+		ShiftDays   int           `query:"shiftDays"`
+		Seed        uint64        `query:"seed"`
+	}{
+		Scale:       services.DefaultDemoOptions.Scale,
+		Jitter:      services.DefaultDemoOptions.Jitter,
+		PriceJitter: services.DefaultDemoOptions.PriceJitter,
+		TimeJitter:  services.DefaultDemoOptions.TimeJitter,
+	}
+	if !decodeQuery(w, r, &query) {
+		return
+	}
+	if query.TimeJitter > 24*time.Hour {
+		respondWithQueryError(w, "timeJitter", "must be at most 24h")
+		return
+	}
+	opts := services.DemoOptions{
+		Scale:       query.Scale,
+		Jitter:      query.Jitter,
+		PriceJitter: query.PriceJitter,
+		TimeJitter:  query.TimeJitter,
+		Shift:       time.Duration(query.ShiftDays) * 24 * time.Hour,
+		Seed:        query.Seed,
+	}
+
+	trades, err := t.ReconService.DemoTrades(opts)
+	if errors.Is(err, services.ErrInvalidDemoOptions) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error anonymizing trades: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to build demo dataset")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zstd")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"demo_%s.tar.zst\"", time.Now().UTC().Format("20060102")))
+	if err := services.WriteDemo(w, trades); err != nil {
+		log.Printf("Demo dataset failed: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"hyperliquid-recon/services"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// How an export is delivered: in the response, as a signed download link, or
+// by a background job whose finished download is linked from the job
+const (
+	deliverInline = "inline"
+	deliverLink   = "link"
+	deliverJob    = "job"
+)
+
+// exportDelivery reads the delivery parameter of an export request, writing a
+// 400 for an unknown delivery, or a 503 for one that needs downloads while
+// they are off, and returning ok false
+func exportDelivery(w http.ResponseWriter, r *http.Request) (delivery string, ok bool) {
+	delivery = r.URL.Query().Get("delivery")
+	switch delivery {
+	case "", deliverInline:
+		return deliverInline, true
+	case deliverLink, deliverJob:
+		if services.CurrentDownloads() == nil {
+			respondWithError(w, http.StatusServiceUnavailable, services.ErrDownloadsDisabled.Error())
+			return "", false
+		}
+		return delivery, true
+	}
+	respondWithError(w, http.StatusBadRequest, "delivery must be inline, link or job")
+	return "", false
+}
+
+// submitExportJob starts a background export of the request and answers with its job
+func submitExportJob(w http.ResponseWriter, r *http.Request, t *services.Tenant, req services.ExportRequest) {
+	req.Tenant, req.Signer = t.ID, t.ExportSigner
+	job := t.Jobs.SubmitExport(r.Context(), req)
+	respondWithJSON(w, http.StatusAccepted, Response{
+		Status:  "accepted",
+		Message: "Export started",
+		Data:    job,
+	})
+}
+
+// writeExportLink writes an export to the download store with encode and
+// answers with the download and its signed URL, so the file never has to be
+// held in memory or fetched with the API key
+func writeExportLink(w http.ResponseWriter, t *services.Tenant, contentType, filename string, encode func(io.Writer) error) {
+	file, err := services.CurrentDownloads().Create(t.ID, filename, contentType, t.ExportSigner)
+	if err != nil {
+		log.Printf("Error creating download %s: %v", filename, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create download")
+		return
+	}
+	if err := encode(file); err != nil {
+		file.Discard()
+		log.Printf("Error writing download %s: %v", filename, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to export")
+		return
+	}
+	download, err := file.Finish()
+	if err != nil {
+		log.Printf("Error saving download %s: %v", filename, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create download")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, download)
+}
+
+// GetDownload handles GET /api/downloads/{token} requests
+// Serves a generated file through the signed URL it was handed out with. No
+// API key is needed: the token names the file and expires with it. It is sent
+// with the digest and signature headers of a direct export, and supports
+// range requests so large files can be resumed.
+func (h *Handler) GetDownload(w http.ResponseWriter, r *http.Request) {
+	download, file, err := services.CurrentDownloads().Open(mux.Vars(r)["token"], time.Now())
+	switch {
+	case errors.Is(err, services.ErrDownloadExpired):
+		respondWithError(w, http.StatusGone, err.Error())
+		return
+	case errors.Is(err, services.ErrDownloadNotFound), errors.Is(err, services.ErrDownloadsDisabled):
+		respondWithError(w, http.StatusNotFound, services.ErrDownloadNotFound.Error())
+		return
+	case err != nil:
+		log.Printf("Error opening download: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to open download")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", download.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", download.Name))
+	w.Header().Set(services.ExportDigestHeader, "sha256="+download.Digest)
+	if download.Signature != "" {
+		w.Header().Set(services.ExportSignatureHeader, "sha256="+download.Signature)
+	}
+	http.ServeContent(w, r, download.Name, download.CreatedAt, file)
+}
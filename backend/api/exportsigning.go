@@ -0,0 +1,51 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/services"
+	"io"
+	"net/http"
+)
+
+// writeExport sends an export file as a download named filename, with its
+// digest and, if a signing key is configured, its signature
+func writeExport(w http.ResponseWriter, t *services.Tenant, contentType, filename string, body []byte) {
+	digest, signature := t.ExportSigner.Sign(body)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	w.Header().Set(services.ExportDigestHeader, "sha256="+digest)
+	if signature != "" {
+		w.Header().Set(services.ExportSignatureHeader, "sha256="+signature)
+	}
+	w.Write(body)
+}
+
+// VerifyExport handles POST /api/export/verify requests
+// The request body is an export file; sha256 and signature are the values of
+// the X-Recon-Digest and X-Recon-Signature headers it was downloaded with.
+func (h *Handler) VerifyExport(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	digest := r.URL.Query().Get("sha256")
+	signature := r.URL.Query().Get("signature")
+	if digest == "" && signature == "" {
+		respondWithError(w, http.StatusBadRequest, "sha256 or signature is required")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, config.ImportMaxBytes))
+	if err != nil {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "upload is too large")
+		return
+	}
+
+	result, err := t.ExportSigner.Verify(body, digest, signature)
+	if errors.Is(err, services.ErrExportSigningDisabled) {
+		respondWithError(w, http.StatusBadRequest, "signature can't be checked: RECON_EXPORT_SIGNING_KEY is not set")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
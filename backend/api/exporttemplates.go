@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"hyperliquid-recon/services"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// GetExportTemplates handles GET /api/export/templates requests
+func (h *Handler) GetExportTemplates(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, tenantFrom(r).ExportTemplates.List())
+}
+
+// GetExportTemplate handles GET /api/export/templates/{name} requests
+func (h *Handler) GetExportTemplate(w http.ResponseWriter, r *http.Request) {
+	template, err := tenantFrom(r).ExportTemplates.Get(mux.Vars(r)["name"])
+	if errors.Is(err, services.ErrExportTemplateNotFound) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, template)
+}
+
+// SaveExportTemplate handles PUT /api/export/templates/{name} requests
+// Creates or replaces a named CSV layout for trade or daily P&L exports.
+func (h *Handler) SaveExportTemplate(w http.ResponseWriter, r *http.Request) {
+	var template models.ExportTemplate
+	if !decodeBody(w, r, &template) {
+		return
+	}
+	template.Name = mux.Vars(r)["name"]
+
+	saved, err := tenantFrom(r).ExportTemplates.Save(template)
+	switch {
+	case errors.Is(err, services.ErrInvalidCSVFormat):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		log.Printf("Error saving export templates: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save export template")
+	default:
+		respondWithJSON(w, http.StatusOK, saved)
+	}
+}
+
+// DeleteExportTemplate handles DELETE /api/export/templates/{name} requests
+func (h *Handler) DeleteExportTemplate(w http.ResponseWriter, r *http.Request) {
+	deleted, err := tenantFrom(r).ExportTemplates.Delete(mux.Vars(r)["name"])
+	if err != nil {
+		log.Printf("Error saving export templates: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save export templates")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, services.ErrExportTemplateNotFound.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// exportLayout reads the CSV layout of an export of kind: the saved template
+// named by the template parameter, if any, overridden by the columns
+// (comma-separated), decimal, thousands, delimiter and dateFormat parameters.
+// It writes an error response if the layout is invalid or format isn't CSV.
+func exportLayout(w http.ResponseWriter, r *http.Request, t *services.Tenant, kind, format string) (services.CSVFormat, bool) {
+	query := r.URL.Query()
+	template := models.ExportTemplate{Kind: kind}
+	if name := query.Get("template"); name != "" {
+		saved, err := t.ExportTemplates.Get(name)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, err.Error())
+			return services.CSVFormat{}, false
+		}
+		if saved.Kind != kind {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("template %s is for %s exports", name, saved.Kind))
+			return services.CSVFormat{}, false
+		}
+		template = saved
+	}
+	if columns := query.Get("columns"); columns != "" {
+		template.Columns = strings.Split(columns, ",")
+	}
+	for _, param := range []struct {
+		name  string
+		value *string
+	}{{"decimal", &template.Decimal}, {"thousands", &template.Thousands}, {"delimiter", &template.Delimiter}, {"dateFormat", &template.DateFormat}} {
+		if value := query.Get(param.name); value != "" {
+			*param.value = value
+		}
+	}
+
+	if format != services.FormatCSV {
+		for _, param := range []string{"template", "columns", "decimal", "thousands", "delimiter", "dateFormat"} {
+			if query.Has(param) {
+				respondWithError(w, http.StatusBadRequest, param+" only applies to CSV exports")
+				return services.CSVFormat{}, false
+			}
+		}
+	}
+
+	csvFormat, err := services.TemplateFormat(template)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return services.CSVFormat{}, false
+	}
+	return csvFormat, true
+}
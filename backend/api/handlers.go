@@ -1,18 +1,30 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
 	"hyperliquid-recon/services"
+	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
 )
 
 // Handler handles HTTP requests for the reconciliation API
 type Handler struct {
-	reconService *services.ReconciliationService
+	tenants       *services.TenantRegistry
+	runtimeConfig *services.RuntimeConfigManager
 }
 
 // Response represents a standard API response
@@ -24,14 +36,15 @@ type Response struct {
 
 // ErrorResponse represents an API error response
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error  string         `json:"error"`
+	Fields []FieldError   `json:"fields,omitempty"` // Set on 422 responses to bodies that failed validation
+	Policy *PolicyRefusal `json:"policy,omitempty"` // Set on refreshes refused by the API key's role
 }
 
-// NewHandler creates a new API handler
-func NewHandler(reconService *services.ReconciliationService) *Handler {
-	return &Handler{
-		reconService: reconService,
-	}
+// NewHandler creates a new API handler. Every request is served by the
+// services of the tenant its API key belongs to; see Authenticate.
+func NewHandler(tenants *services.TenantRegistry, runtimeConfig *services.RuntimeConfigManager) *Handler {
+	return &Handler{tenants: tenants, runtimeConfig: runtimeConfig}
 }
 
 // respondWithJSON writes a JSON response
@@ -48,33 +61,135 @@ func respondWithError(w http.ResponseWriter, statusCode int, message string) {
 	respondWithJSON(w, statusCode, ErrorResponse{Error: message})
 }
 
-// GetPnLSummary handles GET /api/pnl requests
+// GetPnLSummary handles GET /api/pnl requests, with days bucketed by the day
+// query parameter ("local" or "utc") or else the configured day basis. With
+// asOf, the summary of address (default: the current one) is rebuilt from the
+// event log as it stood at that instant.
+// In stale-while-revalidate mode the cached summary is returned immediately with
+// its age, and a background refresh is started if it is older than the threshold.
 func (h *Handler) GetPnLSummary(w http.ResponseWriter, r *http.Request) {
-	summary := h.reconService.GetPnLSummary()
+	t := tenantFrom(r)
+
+	if config.StaleWhileRevalidate {
+		if age, ok := t.ReconService.RevalidateIfStale(r.Context(), config.StaleThreshold); ok {
+			w.Header().Set("Age", strconv.Itoa(int(age.Seconds())))
+			w.Header().Set("X-Data-Stale", strconv.FormatBool(age > config.StaleThreshold))
+		}
+	}
+
+	basis := models.DayBasis(config.DayBasis)
+	if day := r.URL.Query().Get("day"); day != "" {
+		parsed, err := services.ParseDayBasis(day)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		basis = parsed
+	}
+
+	if input := r.URL.Query().Get("asOf"); input != "" {
+		asOf, err := time.Parse(time.RFC3339, input)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "asOf must be an RFC 3339 time such as 2025-06-30T23:59:59Z")
+			return
+		}
+		address, ok := analyticsAddress(w, r, t)
+		if !ok {
+			return
+		}
+
+		summary, err := t.ReconService.SummaryAsOf(address, asOf, basis)
+		switch {
+		case errors.Is(err, services.ErrNoEventLog):
+			respondWithError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, services.ErrNotCached):
+			respondWithError(w, http.StatusNotFound, fmt.Sprintf("no trades had been fetched for %s by %s", address, asOf.Format(time.RFC3339)))
+		case err != nil:
+			log.Printf("Error rebuilding summary for %s as of %s: %v", address, asOf.Format(time.RFC3339), err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to rebuild the summary")
+		default:
+			respondWithJSON(w, http.StatusOK, summary)
+		}
+		return
+	}
+
+	summary := t.ReconService.GetPnLSummaryByDay(basis)
+	if vaults, exists := t.Ledger.Vaults(summary.Address); exists && len(vaults.Vaults) > 0 {
+		summary.VaultPnL = &vaults.PnL
+	}
 	respondWithJSON(w, http.StatusOK, summary)
 }
 
 // TriggerRefresh handles POST /api/refresh requests
 func (h *Handler) TriggerRefresh(w http.ResponseWriter, r *http.Request) {
-	address := r.URL.Query().Get("address")
-	if address == "" {
-		respondWithError(w, http.StatusBadRequest, "address parameter is required")
+	t := tenantFrom(r)
+
+	address, ok := resolveAddress(w, t, r.URL.Query().Get("address"))
+	if !ok {
+		return
+	}
+
+	// days defaults to the configured history; a callback refresh runs at high priority
+	query := struct {
+		Days        int                `query:"days" validate:"min=1"`
+		DryRun      bool               `query:"dryRun"`
+		CallbackURL string             `query:"callbackUrl" validate:"url"`
+		Priority    models.JobPriority `query:"priority" validate:"oneof=high low"`
+	}{Days: config.TradeHistoryDays, Priority: models.PriorityHigh}
+	if !decodeQuery(w, r, &query) {
 		return
 	}
+	days := query.Days
 
-	// Parse days parameter, default to config value if not provided
-	days := config.TradeHistoryDays
-	daysParam := r.URL.Query().Get("days")
-	if daysParam != "" {
-		parsedDays, err := strconv.Atoi(daysParam)
-		if err != nil || parsedDays <= 0 {
-			respondWithError(w, http.StatusBadRequest, "days parameter must be a positive integer")
+	// A dry run reports what would be fetched without fetching it
+	policy := policyFrom(r)
+	if query.DryRun {
+		if !admittedByPolicy(w, policy.CheckDays(days)) {
 			return
 		}
-		days = parsedDays
+		respondWithJSON(w, http.StatusOK, t.ReconService.PlanRefresh(address, days))
+		return
+	}
+	if !admittedByPolicy(w, policy.CheckRefresh(address, days, time.Now())) {
+		return
+	}
+
+	// With a callback URL the refresh runs asynchronously and the result is POSTed there
+	if query.CallbackURL != "" {
+		job := t.Jobs.SubmitRefresh(r.Context(), address, days, query.Priority, query.CallbackURL)
+		respondWithJSON(w, http.StatusAccepted, Response{
+			Status:  "accepted",
+			Message: "Refresh started; the result will be sent to the callback URL",
+			Data:    job,
+		})
+		return
+	}
+
+	// Finish the refresh even if the client goes away, so the cache isn't left with gaps
+	err := t.ReconService.FetchAndReconcile(context.WithoutCancel(r.Context()), address, days)
+	if !admittedByPolicy(w, err) {
+		return
+	}
+
+	// Refused before any request was made, to stay within the API budget
+	var overBudget *services.BudgetError
+	if errors.As(err, &overBudget) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(overBudget.RetryAt).Seconds())+1))
+		respondWithError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+
+	var partial *services.PartialError
+	if errors.As(err, &partial) {
+		log.Printf("Partial refresh for %s (days=%d): %v", address, days, err)
+		respondWithJSON(w, http.StatusOK, Response{
+			Status:  "partial",
+			Message: "Data partially refreshed; some time ranges could not be fetched",
+		})
+		return
 	}
 
-	if err := h.reconService.FetchAndReconcile(address, days); err != nil {
+	if err != nil {
 		log.Printf("Error fetching and reconciling trades for %s (days=%d): %v", address, days, err)
 
 		// Provide more specific error messages
@@ -102,14 +217,1241 @@ func (h *Handler) TriggerRefresh(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// HealthCheck handles GET /api/health requests
-func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	respondWithJSON(w, http.StatusOK, Response{
-		Status:  "healthy",
-		Message: "Service is running",
+// TriggerBatchRefresh handles POST /api/refresh/batch requests
+// The body is a JSON array of {"address", "days"} refreshes, which run one after
+// another in the background. Items whose address can't be resolved are
+// rejected individually; the others are each run as a job. An optional
+// callbackUrl parameter receives the batch when it finishes.
+// Batches run at low priority unless priority=high is given.
+func (h *Handler) TriggerBatchRefresh(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	query := struct {
+		CallbackURL string             `query:"callbackUrl" validate:"url"`
+		Priority    models.JobPriority `query:"priority" validate:"oneof=high low"`
+	}{Priority: models.PriorityLow}
+	if !decodeQuery(w, r, &query) {
+		return
+	}
+
+	var specs []models.RefreshSpec
+	if !decodeBody(w, r, &specs) {
+		return
+	}
+	if len(specs) == 0 || len(specs) > config.MaxBatchRefreshes {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("a batch must contain between 1 and %d refreshes", config.MaxBatchRefreshes))
+		return
+	}
+
+	policy, now := policyFrom(r), time.Now()
+	items := make([]models.RefreshBatchItem, len(specs))
+	for i, spec := range specs {
+		items[i] = models.RefreshBatchItem{Address: spec.Address, Days: spec.Days}
+		if spec.Days == 0 {
+			items[i].Days = config.TradeHistoryDays
+		}
+
+		address, err := t.AddressBook.Resolve(spec.Address)
+		if err != nil {
+			items[i].Error = fmt.Sprintf("%q: %v", spec.Address, err)
+			continue
+		}
+		items[i].Address = address
+		if err := policy.CheckRefresh(address, items[i].Days, now); err != nil {
+			items[i].Error = err.Error()
+		}
+	}
+
+	batch := t.Jobs.SubmitBatch(r.Context(), items, query.Priority, query.CallbackURL)
+	respondWithJSON(w, http.StatusAccepted, Response{
+		Status:  "accepted",
+		Message: fmt.Sprintf("%d refreshes queued, %d rejected", batch.Counts[models.JobPending], batch.Counts[models.JobFailed]),
+		Data:    batch,
+	})
+}
+
+// GetBatch handles GET /api/refresh/batch/{id} requests
+func (h *Handler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	batch, exists := t.Jobs.GetBatch(mux.Vars(r)["id"])
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "batch not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, batch)
+}
+
+// GetJob handles GET /api/jobs/{id} requests
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	job, exists := t.Jobs.GetJob(mux.Vars(r)["id"])
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, job)
+}
+
+// GetWebhookDeliveries handles GET /api/webhooks/deliveries requests
+// An optional status parameter filters deliveries, e.g. status=dead for dead letters.
+func (h *Handler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	status := models.DeliveryStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", models.DeliveryPending, models.DeliveryRetrying, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		respondWithError(w, http.StatusBadRequest, "status must be one of pending, retrying, delivered, dead")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, t.Webhooks.Deliveries(status))
+}
+
+// ExportToSheets handles POST /api/export/sheets requests
+func (h *Handler) ExportToSheets(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	if t.Sheets == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Google Sheets export is not configured")
+		return
+	}
+
+	result, err := t.Sheets.Export()
+	if err != nil {
+		log.Printf("Error exporting to Google Sheets: %v", err)
+		respondWithError(w, http.StatusBadGateway, "Failed to export to Google Sheets. Please try again later.")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// ExportTrades handles GET /api/export/trades requests
+// Returns all cached trades for an address as CSV (default) or Parquet
+// (format=parquet). A saved template, columns, decimal, thousands, delimiter
+// and dateFormat lay out and localize CSV. delivery=link writes the file to
+// the download store and returns a signed URL to fetch it from instead;
+// delivery=job writes it in the background and returns the job, whose export
+// reports the progress and, once finished, the download.
+func (h *Handler) ExportTrades(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, ok := resolveAddress(w, t, r.URL.Query().Get("address"))
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.FormatCSV
+	}
+	if format != services.FormatCSV && format != services.FormatParquet {
+		respondWithError(w, http.StatusBadRequest, "format must be csv or parquet")
+		return
+	}
+	csvFormat, ok := exportLayout(w, r, t, services.ExportKindTrades, format)
+	if !ok {
+		return
+	}
+	delivery, ok := exportDelivery(w, r)
+	if !ok {
+		return
+	}
+
+	trades, exists := t.ReconService.CachedTrades(address)
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "no cached trades for address; refresh it first")
+		return
+	}
+
+	if delivery == deliverJob {
+		submitExportJob(w, r, t, services.ExportRequest{Address: address, Dataset: services.ExportKindTrades, Format: format, CSVFormat: csvFormat})
+		return
+	}
+	filename := fmt.Sprintf("trades_%s.%s", address, format)
+	if delivery == deliverLink {
+		writeExportLink(w, t, services.ContentType(format), filename, func(out io.Writer) error {
+			return services.WriteTradesAs(out, format, trades, csvFormat)
+		})
+		return
+	}
+
+	body, err := services.EncodeTradesAs(format, trades, csvFormat)
+	if err != nil {
+		log.Printf("Error encoding trades export for %s: %v", address, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to export trades")
+		return
+	}
+
+	writeExport(w, t, services.ContentType(format), filename, body)
+}
+
+// ExportDailyPnL handles GET /api/export/pnl requests
+// Returns the daily P&L records of an address as CSV (default) or Parquet
+// (format=parquet), laid out and delivered like the trades export.
+func (h *Handler) ExportDailyPnL(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, ok := resolveAddress(w, t, r.URL.Query().Get("address"))
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.FormatCSV
+	}
+	if format != services.FormatCSV && format != services.FormatParquet {
+		respondWithError(w, http.StatusBadRequest, "format must be csv or parquet")
+		return
+	}
+	csvFormat, ok := exportLayout(w, r, t, services.ExportKindPnL, format)
+	if !ok {
+		return
+	}
+	delivery, ok := exportDelivery(w, r)
+	if !ok {
+		return
+	}
+
+	records, exists := t.ReconService.CachedDailyRecords(address)
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "no cached trades for address; refresh it first")
+		return
+	}
+
+	if delivery == deliverJob {
+		submitExportJob(w, r, t, services.ExportRequest{Address: address, Dataset: services.ExportKindPnL, Format: format, CSVFormat: csvFormat})
+		return
+	}
+	filename := fmt.Sprintf("pnl_%s.%s", address, format)
+	if delivery == deliverLink {
+		writeExportLink(w, t, services.ContentType(format), filename, func(out io.Writer) error {
+			return services.WriteDailyPnLAs(out, format, records, csvFormat)
+		})
+		return
+	}
+
+	body, err := services.EncodeDailyPnLAs(format, records, csvFormat)
+	if err != nil {
+		log.Printf("Error encoding daily P&L export for %s: %v", address, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to export daily P&L")
+		return
+	}
+
+	writeExport(w, t, services.ContentType(format), filename, body)
+}
+
+// GetStatement handles GET /api/export/statement requests
+// Builds the fund-style statement of address from from to to (YYYY-MM-DD,
+// default month to date), with the high-water mark, drawdown and performance
+// fee accrued at feeRate (default PerformanceFeeRate). format=csv downloads the
+// daily lines and totals, localized like the trades export.
+func (h *Handler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, ok := resolveAddress(w, t, r.URL.Query().Get("address"))
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != services.FormatCSV {
+		respondWithError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	now := time.Now()
+	from, to, ok := parseDateRange(w, r, now.AddDate(0, 0, 1-now.Day()).Format("2006-01-02"), now.Format("2006-01-02"))
+	if !ok {
+		return
+	}
+
+	query := struct {
+		FeeRate float64 `query:"feeRate" validate:"min=0,max=1"`
+	}{FeeRate: config.PerformanceFeeRate}
+	if !decodeQuery(w, r, &query) {
+		return
+	}
+
+	records, exists := t.ReconService.CachedDailyRecords(address)
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "no cached trades for address; refresh it first")
+		return
+	}
+
+	var baseCapital models.Money
+	if entry, ok := t.AddressBook.Entry(address); ok && entry.BaseCapital != nil {
+		baseCapital = *entry.BaseCapital
+	}
+
+	statement := services.BuildStatement(address, records, baseCapital, from, to, query.FeeRate)
+	statement.Label = t.ReconService.Label(address)
+	for _, restatement := range t.Periods.Restatements(address, "") {
+		if restatement.Date >= from && restatement.Date <= to {
+			statement.Restatements = append(statement.Restatements, restatement)
+		}
+	}
+
+	if format != services.FormatCSV {
+		respondWithJSON(w, http.StatusOK, statement)
+		return
+	}
+
+	csvFormat, ok := parseCSVFormat(w, r)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	if err := services.WriteStatementCSV(&buf, statement, csvFormat); err != nil {
+		log.Printf("Error encoding statement for %s: %v", address, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to export statement")
+		return
+	}
+	services.WriteExportFooter(&buf, csvFormat)
+	writeExport(w, t, services.ContentType(services.FormatCSV), fmt.Sprintf("statement_%s_%s_%s.csv", address, from, to), buf.Bytes())
+}
+
+// ImportTrades handles POST /api/import requests
+// The request body is a trade file previously downloaded from /api/export/trades.
+func (h *Handler) ImportTrades(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, ok := resolveAddress(w, t, r.URL.Query().Get("address"))
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.FormatCSV
+	}
+	if format != services.FormatCSV && format != services.FormatParquet {
+		respondWithError(w, http.StatusBadRequest, "format must be csv or parquet")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, config.ImportMaxBytes))
+	if err != nil {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "upload is too large")
+		return
+	}
+
+	trades, err := services.DecodeTrades(format, body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid trade file: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, t.ReconService.ImportTrades(address, trades))
+}
+
+// InvalidateCacheRange handles DELETE /api/cache/{address}?from=&to= requests
+// Drops the cached trades from from to to (YYYY-MM-DD, inclusive) and re-fetches
+// that window in the background. Until the re-fetch succeeds the window is
+// reported as missing; if it fails, the next refresh retries it.
+func (h *Handler) InvalidateCacheRange(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, ok := resolveAddress(w, t, mux.Vars(r)["address"])
+	if !ok {
+		return
+	}
+
+	from, to, ok := parseDateRange(w, r, "", "")
+	if !ok {
+		return
+	}
+	if from == "" || to == "" {
+		respondWithError(w, http.StatusBadRequest, "from and to parameters are required")
+		return
+	}
+	start, _ := time.ParseInLocation("2006-01-02", from, time.Local)
+	end, _ := time.ParseInLocation("2006-01-02", to, time.Local)
+	end = end.AddDate(0, 0, 1)
+	days := int(math.Round(end.Sub(start).Hours() / 24))
+	policy := policyFrom(r)
+	if !admittedByPolicy(w, policy.CheckRefresh(address, days, time.Now())) {
+		return
+	}
+
+	invalidation, err := t.ReconService.InvalidateRange(r.Context(), address, start, end)
+	switch {
+	case errors.Is(err, services.ErrNotCached), errors.Is(err, services.ErrRangeNotCached):
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		log.Printf("Error invalidating %s from %s to %s: %v", address, from, to, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to invalidate cached trades")
+		return
+	}
+
+	// The re-fetch is accepted, so it counts as the key's refresh of address
+	policy.RecordRefresh(address, time.Now())
+	window := invalidation.Window
+	go func() {
+		if _, err := t.ReconService.RefetchRange(context.WithoutCancel(r.Context()), address, window.Start, window.End); err != nil {
+			log.Printf("Re-fetch of invalidated window for %s failed, leaving it for the next refresh: %v", address, err)
+		}
+	}()
+
+	respondWithJSON(w, http.StatusAccepted, Response{
+		Status:  "accepted",
+		Message: "Cached trades invalidated; the window is being fetched again",
+		Data:    invalidation,
 	})
 }
 
+// GetAddresses handles GET /api/addresses requests
+func (h *Handler) GetAddresses(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	respondWithJSON(w, http.StatusOK, t.AddressBook.List())
+}
+
+// SaveAddress handles POST /api/addresses requests
+// The address may be given as a hex address or an ENS name, which is resolved now.
+func (h *Handler) SaveAddress(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var req struct {
+		Address string `json:"address" validate:"required"`
+		Label   string `json:"label" validate:"required"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	entry, err := t.AddressBook.Save(req.Address, req.Label)
+	if err != nil {
+		respondWithResolveError(w, req.Address, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, entry)
+}
+
+// SetAddressCapital handles PUT /api/addresses/{address}/capital requests
+// The body sets a fixed baseCapital, or derive to measure returns against the
+// account value history; an empty body stops reporting returns.
+func (h *Handler) SetAddressCapital(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, ok := resolveAddress(w, t, mux.Vars(r)["address"])
+	if !ok {
+		return
+	}
+
+	var req struct {
+		BaseCapital *models.Money `json:"baseCapital"`
+		Derive      bool          `json:"derive"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.BaseCapital != nil && (req.Derive || *req.BaseCapital <= 0) {
+		respondWithError(w, http.StatusBadRequest, "baseCapital must be positive and can't be combined with derive")
+		return
+	}
+
+	entry, err := t.AddressBook.SetCapital(address, req.BaseCapital, req.Derive)
+	if errors.Is(err, services.ErrAddressNotSaved) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error saving address book: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save address book")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, entry)
+}
+
+// SetAddressTimezone handles PUT /api/addresses/{address}/timezone requests
+// The body's timezone is an IANA name such as Asia/Tokyo the address's days
+// are bucketed in by consolidated reports; an empty one restores the day basis.
+func (h *Handler) SetAddressTimezone(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, ok := resolveAddress(w, t, mux.Vars(r)["address"])
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Timezone string `json:"timezone"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	entry, err := t.AddressBook.SetTimezone(address, req.Timezone)
+	if errors.Is(err, services.ErrInvalidTimezone) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrAddressNotSaved) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error saving address book: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save address book")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, entry)
+}
+
+// GetConsolidatedReport handles GET /api/consolidated requests
+// Reports the daily P&L of each address parameter (every saved address if
+// none) in its own time zone, with a UTC roll-up of them all, between from
+// and to (default: the last ConsolidatedDays days).
+func (h *Handler) GetConsolidatedReport(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var addresses []string
+	for _, input := range r.URL.Query()["address"] {
+		address, ok := resolveAddress(w, t, input)
+		if !ok {
+			return
+		}
+		addresses = append(addresses, address)
+	}
+
+	today := time.Now().UTC().Format("2006-01-02")
+	from, to, ok := parseDateRange(w, r, "", today)
+	if !ok {
+		return
+	}
+	if from == "" {
+		end, _ := time.Parse("2006-01-02", to)
+		from = end.AddDate(0, 0, -config.ConsolidatedDays+1).Format("2006-01-02")
+	}
+
+	report, err := t.ReconService.Consolidate(addresses, from, to)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// DeleteAddress handles DELETE /api/addresses/{address} requests
+func (h *Handler) DeleteAddress(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	deleted, err := t.AddressBook.Delete(mux.Vars(r)["address"])
+	if err != nil {
+		log.Printf("Error saving address book: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save address book")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "address not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetLeaderboard handles GET /api/leaderboard requests
+// window selects the ranking period (24h, 7d or 30d; default 24h) and sort ranks
+// by pnl (default) or volume. Stats for every window are included in each entry.
+func (h *Handler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = services.LeaderboardWindows[0].Name
+	}
+	if !services.IsLeaderboardWindow(window) {
+		respondWithError(w, http.StatusBadRequest, "window must be one of 24h, 7d, 30d")
+		return
+	}
+
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy == "" {
+		sortBy = services.SortByPnL
+	}
+	if sortBy != services.SortByPnL && sortBy != services.SortByVolume {
+		respondWithError(w, http.StatusBadRequest, "sort must be pnl or volume")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, t.Leaderboard.Leaderboard(window, sortBy))
+}
+
+// GetDailyReport handles GET /api/reports/daily requests
+// Builds the daily report for one or more address parameters on date (YYYY-MM-DD,
+// default yesterday) without emailing it. format=text returns the email body.
+func (h *Handler) GetDailyReport(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	addresses := r.URL.Query()["address"]
+	if len(addresses) == 0 {
+		respondWithError(w, http.StatusBadRequest, "address parameter is required")
+		return
+	}
+
+	date, ok := parseReportDate(w, r.URL.Query().Get("date"))
+	if !ok {
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = "Daily report"
+	}
+
+	report := t.Reports.BuildDailyReport(context.WithoutCancel(r.Context()), name, addresses, date)
+
+	if r.URL.Query().Get("format") == "text" {
+		body, err := services.FormatReport(report)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to render report")
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(body))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// SendDailyReport handles POST /api/reports/send requests
+// Emails the configured subscription called name for date (default yesterday) now.
+func (h *Handler) SendDailyReport(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	name := r.URL.Query().Get("name")
+
+	var subscription *models.ReportSubscription
+	for _, sub := range t.Reports.Subscriptions() {
+		if sub.Name == name {
+			subscription = &sub
+			break
+		}
+	}
+	if subscription == nil {
+		respondWithError(w, http.StatusNotFound, "report subscription not found")
+		return
+	}
+
+	date, ok := parseReportDate(w, r.URL.Query().Get("date"))
+	if !ok {
+		return
+	}
+
+	if err := t.Reports.Send(context.WithoutCancel(r.Context()), *subscription, date); err != nil {
+		log.Printf("Error sending report %q: %v", name, err)
+		respondWithError(w, http.StatusBadGateway, "Failed to send report: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, Response{
+		Status:  "success",
+		Message: "Report sent to " + strings.Join(subscription.To, ", "),
+	})
+}
+
+// GetCalendar handles GET /api/calendar.ics requests
+// Returns an iCalendar feed with one all-day event per trading day. With an
+// address parameter the feed covers that address's full cache; otherwise it
+// covers the current summary.
+func (h *Handler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var address string
+	var records []models.DailyPnL
+
+	if input := r.URL.Query().Get("address"); input != "" {
+		resolved, ok := resolveAddress(w, t, input)
+		if !ok {
+			return
+		}
+
+		cached, exists := t.ReconService.CachedDailyRecords(resolved)
+		if !exists {
+			respondWithError(w, http.StatusNotFound, "no cached trades for address; refresh it first")
+			return
+		}
+		address, records = resolved, cached
+	} else {
+		summary := t.ReconService.GetPnLSummary()
+		address, records = summary.Address, summary.DailyRecords
+	}
+
+	name := t.ReconService.Label(address)
+	if name == "" {
+		name = address
+	}
+	name = strings.TrimSpace("Hyperliquid P&L " + name)
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	if err := services.WriteCalendar(w, name, address, records, time.Now()); err != nil {
+		log.Printf("Error writing calendar feed: %v", err)
+	}
+}
+
+// GetPositionHistory handles GET /api/positions/history requests
+// Returns the position size and average entry for coin after every cached fill,
+// for address or, if omitted, the address of the current summary.
+func (h *Handler) GetPositionHistory(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	coin := r.URL.Query().Get("coin")
+	if coin == "" {
+		respondWithError(w, http.StatusBadRequest, "coin parameter is required")
+		return
+	}
+	// Trades are grouped under canonical names, but the exchange's symbol works too
+	coin = services.Symbols().Canonical(coin)
+
+	address := t.ReconService.GetPnLSummary().Address
+	if input := r.URL.Query().Get("address"); input != "" {
+		resolved, ok := resolveAddress(w, t, input)
+		if !ok {
+			return
+		}
+		address = resolved
+	}
+
+	trades, exists := t.ReconService.CachedTrades(address)
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "no cached trades for address; refresh it first")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.PositionHistory{
+		Address: address,
+		Label:   t.ReconService.Label(address),
+		Coin:    coin,
+		Points:  services.ReconstructPositions(trades, coin),
+	})
+}
+
+// GetPositionChecks handles GET /api/checks/positions requests
+// Checks every cached fill for address against the exchange-reported start
+// positions and reports gaps per coin and day.
+func (h *Handler) GetPositionChecks(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, ok := resolveAddress(w, t, r.URL.Query().Get("address"))
+	if !ok {
+		return
+	}
+
+	report, exists := t.ReconService.CheckConsistency(address)
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "no cached trades for address; refresh it first")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// GetBreaks handles GET /api/breaks requests
+// Optional status, type and address parameters filter the list.
+func (h *Handler) GetBreaks(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	status := models.BreakStatus(r.URL.Query().Get("status"))
+	if status != "" && !isBreakStatus(status) {
+		respondWithError(w, http.StatusBadRequest, "status must be one of open, acknowledged, resolved")
+		return
+	}
+
+	var address string
+	if input := r.URL.Query().Get("address"); input != "" {
+		resolved, ok := resolveAddress(w, t, input)
+		if !ok {
+			return
+		}
+		address = resolved
+	}
+
+	breaks := t.Breaks.List(status, models.BreakType(r.URL.Query().Get("type")), address)
+	for i := range breaks {
+		breaks[i].Label = t.ReconService.Label(breaks[i].Address)
+	}
+	respondWithJSON(w, http.StatusOK, breaks)
+}
+
+// CreateBreak handles POST /api/breaks requests, for breaks found outside the
+// automatic checks such as mismatches against an external file
+func (h *Handler) CreateBreak(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var req struct {
+		Address     string `json:"address"`
+		Coin        string `json:"coin"`
+		Date        string `json:"date" validate:"date"`
+		Description string `json:"description" validate:"required"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	address, ok := resolveAddress(w, t, req.Address)
+	if !ok {
+		return
+	}
+
+	b, err := t.Breaks.Create(address, req.Coin, req.Date, req.Description)
+	if err != nil {
+		log.Printf("Error saving breaks: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save break")
+		return
+	}
+
+	b.Label = t.ReconService.Label(b.Address)
+	respondWithJSON(w, http.StatusCreated, b)
+}
+
+// GetBreak handles GET /api/breaks/{id} requests
+func (h *Handler) GetBreak(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	b, exists := t.Breaks.Get(mux.Vars(r)["id"])
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "break not found")
+		return
+	}
+
+	b.Label = t.ReconService.Label(b.Address)
+	respondWithJSON(w, http.StatusOK, b)
+}
+
+// UpdateBreak handles PATCH /api/breaks/{id} requests
+// The body may set status and/or assignee; omitted fields are left unchanged.
+func (h *Handler) UpdateBreak(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var req struct {
+		Status   *models.BreakStatus `json:"status" validate:"oneof=open acknowledged resolved"`
+		Assignee *string             `json:"assignee"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	b, err := t.Breaks.Update(mux.Vars(r)["id"], req.Status, req.Assignee)
+	respondWithBreak(w, t, b, err)
+}
+
+// AddBreakNote handles POST /api/breaks/{id}/notes requests
+func (h *Handler) AddBreakNote(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var req struct {
+		Author string `json:"author"`
+		Text   string `json:"text" validate:"required"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	b, err := t.Breaks.AddNote(mux.Vars(r)["id"], req.Author, req.Text)
+	respondWithBreak(w, t, b, err)
+}
+
+// respondWithBreak writes the result of a break update
+func respondWithBreak(w http.ResponseWriter, t *services.Tenant, b models.Break, err error) {
+	switch {
+	case errors.Is(err, services.ErrBreakNotFound):
+		respondWithError(w, http.StatusNotFound, "break not found")
+	case err != nil:
+		log.Printf("Error saving breaks: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save break")
+	default:
+		b.Label = t.ReconService.Label(b.Address)
+		respondWithJSON(w, http.StatusOK, b)
+	}
+}
+
+// GetCloses handles GET /api/closes requests
+// Optional address and date (YYYY-MM-DD) parameters filter the stored snapshots.
+func (h *Handler) GetCloses(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var address string
+	if input := r.URL.Query().Get("address"); input != "" {
+		resolved, ok := resolveAddress(w, t, input)
+		if !ok {
+			return
+		}
+		address = resolved
+	}
+
+	respondWithJSON(w, http.StatusOK, t.Closes.Closes(address, r.URL.Query().Get("date")))
+}
+
+// RunClose handles POST /api/closes requests
+// Closes date (default yesterday) now. Already closed days are skipped unless force=true.
+func (h *Handler) RunClose(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	date, ok := parseReportDate(w, r.URL.Query().Get("date"))
+	if !ok {
+		return
+	}
+
+	closes, err := t.Closes.Close(context.WithoutCancel(r.Context()), date, r.URL.Query().Get("force") == "true")
+	if err != nil {
+		log.Printf("Error closing %s: %v", date.Format("2006-01-02"), err)
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, closes)
+}
+
+// GetPeriods handles GET /api/periods requests
+// Lists locked periods, optionally of one address.
+func (h *Handler) GetPeriods(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var address string
+	if input := r.URL.Query().Get("address"); input != "" {
+		resolved, ok := resolveAddress(w, t, input)
+		if !ok {
+			return
+		}
+		address = resolved
+	}
+
+	respondWithJSON(w, http.StatusOK, t.Periods.Locks(address))
+}
+
+// LockPeriod handles POST /api/periods requests
+// Locks a finished month of an address once its statement is issued. The
+// address's cached trades must cover the whole month.
+func (h *Handler) LockPeriod(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var req struct {
+		Address string `json:"address" validate:"required"`
+		Month   string `json:"month" validate:"required,month"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	address, ok := resolveAddress(w, t, req.Address)
+	if !ok {
+		return
+	}
+
+	lock, err := t.ReconService.LockPeriod(address, req.Month)
+	switch {
+	case errors.Is(err, services.ErrInvalidMonth), errors.Is(err, services.ErrPeriodNotFinished), errors.Is(err, services.ErrPeriodNotCovered):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrPeriodLocked):
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		log.Printf("Error saving periods: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save periods")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, lock)
+}
+
+// GetRestatements handles GET /api/periods/restatements requests
+// Optional address and month (YYYY-MM) parameters filter the restatements.
+func (h *Handler) GetRestatements(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var address string
+	if input := r.URL.Query().Get("address"); input != "" {
+		resolved, ok := resolveAddress(w, t, input)
+		if !ok {
+			return
+		}
+		address = resolved
+	}
+
+	respondWithJSON(w, http.StatusOK, t.Periods.Restatements(address, r.URL.Query().Get("month")))
+}
+
+// ReloadConfig handles POST /api/admin/config/reload requests
+// Re-reads the config file now instead of waiting for the file watcher.
+func (h *Handler) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	reload, err := h.runtimeConfig.Reload()
+	if errors.Is(err, services.ErrInvalidConfig) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error reloading config: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read config file")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, reload)
+}
+
+// GetAdminStats handles GET /api/admin/stats requests
+func (h *Handler) GetAdminStats(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, services.CollectStats(h.tenants))
+}
+
+// GetAPIBudget handles GET /api/admin/budget requests
+// Returns the Hyperliquid rate limit weight used per hour and day against the
+// configured budget; it is shared by all tenants, which fetch from the same IP.
+func (h *Handler) GetAPIBudget(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, services.APIBudgetUsage())
+}
+
+// GetSymbolHistory handles GET /api/admin/symbols/history requests
+func (h *Handler) GetSymbolHistory(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, services.CurrentSymbolHistory().List())
+}
+
+// SaveSymbolChange handles POST /api/admin/symbols/history and
+// PUT /api/admin/symbols/history/{id} requests
+// Records that a symbol was renamed or delisted; effective is an RFC 3339 time
+// or a date (YYYY-MM-DD, UTC midnight). Grouping uses it from the next request.
+func (h *Handler) SaveSymbolChange(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Symbol    string                  `json:"symbol"`
+		Kind      models.SymbolChangeKind `json:"kind"`
+		GroupAs   string                  `json:"groupAs"`
+		Effective string                  `json:"effective" validate:"required"`
+		Note      string                  `json:"note"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	effective, err := time.Parse(time.RFC3339, req.Effective)
+	if err != nil {
+		effective, err = time.Parse("2006-01-02", req.Effective)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "effective must be an RFC 3339 time or a date in YYYY-MM-DD format")
+		return
+	}
+
+	change, err := services.CurrentSymbolHistory().Save(models.SymbolChange{
+		ID:        mux.Vars(r)["id"],
+		Symbol:    req.Symbol,
+		Kind:      req.Kind,
+		GroupAs:   req.GroupAs,
+		Effective: effective,
+		Note:      req.Note,
+	})
+	switch {
+	case errors.Is(err, services.ErrInvalidSymbolChange):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrSymbolChangeNotFound):
+		respondWithError(w, http.StatusNotFound, "symbol change not found")
+		return
+	case err != nil:
+		log.Printf("Error saving symbol history: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save symbol history")
+		return
+	}
+
+	status := http.StatusCreated
+	if r.Method == http.MethodPut {
+		status = http.StatusOK
+	}
+	respondWithJSON(w, status, change)
+}
+
+// DeleteSymbolChange handles DELETE /api/admin/symbols/history/{id} requests
+func (h *Handler) DeleteSymbolChange(w http.ResponseWriter, r *http.Request) {
+	deleted, err := services.CurrentSymbolHistory().Delete(mux.Vars(r)["id"])
+	if err != nil {
+		log.Printf("Error saving symbol history: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save symbol history")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "symbol change not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RebuildCache handles POST /api/admin/cache/rebuild requests
+// Starts a background rebuild of an address's cache from the API; the old
+// cache keeps being used until the rebuilt one passes its checks and replaces it.
+func (h *Handler) RebuildCache(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.adminTenant(w, r)
+	if !ok {
+		return
+	}
+
+	address, ok := resolveAddress(w, t, r.URL.Query().Get("address"))
+	if !ok {
+		return
+	}
+
+	query := struct {
+		Days int `query:"days" validate:"min=1"`
+	}{Days: config.TradeHistoryDays}
+	if !decodeQuery(w, r, &query) {
+		return
+	}
+
+	job := t.Jobs.SubmitCacheRebuild(r.Context(), address, query.Days)
+	respondWithJSON(w, http.StatusAccepted, Response{
+		Status:  "accepted",
+		Message: "Cache rebuild started",
+		Data:    job,
+	})
+}
+
+// GetAdminJob handles GET /api/admin/jobs/{id} requests
+func (h *Handler) GetAdminJob(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.adminTenant(w, r)
+	if !ok {
+		return
+	}
+
+	job, exists := t.Jobs.GetJob(mux.Vars(r)["id"])
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, job)
+}
+
+// GetVersion handles GET /api/version requests
+// Reports the commit, build time and calculator version of the running build.
+func (h *Handler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, services.BuildInfo())
+}
+
+// HealthCheck handles GET /api/health requests
+func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, Response{
+		Status:  "healthy",
+		Message: "Service is running",
+	})
+}
+
+// GetStatus handles GET /api/status: the Hyperliquid API's health and when
+// each of the tenant's cached addresses was last fetched successfully
+func (h *Handler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+	respondWithJSON(w, http.StatusOK, t.ReconService.Status(time.Now()))
+}
+
+// adminTenant returns the tenant named by the tenant parameter of an admin
+// request, the default tenant if it is omitted, writing an error response if
+// there is no such tenant
+func (h *Handler) adminTenant(w http.ResponseWriter, r *http.Request) (*services.Tenant, bool) {
+	id := r.URL.Query().Get("tenant")
+	if id == "" {
+		id = services.DefaultTenantID
+	}
+
+	t, ok := h.tenants.Tenant(id)
+	if !ok {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("tenant %q not found", id))
+		return nil, false
+	}
+	return t, true
+}
+
+// resolveAddress resolves an address parameter, which may be a hex address, a
+// saved label or an ENS name, writing an error response if it can't be resolved
+func resolveAddress(w http.ResponseWriter, t *services.Tenant, input string) (string, bool) {
+	if input == "" {
+		respondWithError(w, http.StatusBadRequest, "address parameter is required")
+		return "", false
+	}
+
+	address, err := t.AddressBook.Resolve(input)
+	if err != nil {
+		respondWithResolveError(w, input, err)
+		return "", false
+	}
+	return address, true
+}
+
+// respondWithResolveError writes the error response for an address that could not be resolved
+func respondWithResolveError(w http.ResponseWriter, input string, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAddress), errors.Is(err, services.ErrENSNotConfigured):
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("%q: %v", input, err))
+	case errors.Is(err, services.ErrENSNotFound):
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("%q: %v", input, err))
+	default:
+		log.Printf("Error resolving %q: %v", input, err)
+		respondWithError(w, http.StatusBadGateway, "Failed to resolve ENS name. Please try again later.")
+	}
+}
+
+// parseReportDate parses a YYYY-MM-DD date parameter in local time, defaulting to
+// yesterday, and writes an error response if it is invalid
+func parseReportDate(w http.ResponseWriter, value string) (time.Time, bool) {
+	if value == "" {
+		return time.Now().AddDate(0, 0, -1), true
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "date must be in YYYY-MM-DD format")
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// parseDateRange reads the from and to parameters (YYYY-MM-DD, inclusive),
+// defaulting to defaultFrom and defaultTo, and writes an error response if they are invalid
+func parseDateRange(w http.ResponseWriter, r *http.Request, defaultFrom, defaultTo string) (string, string, bool) {
+	query := struct {
+		From string `query:"from" validate:"date"`
+		To   string `query:"to" validate:"date"`
+	}{From: defaultFrom, To: defaultTo}
+	if !decodeQuery(w, r, &query) {
+		return "", "", false
+	}
+	if query.From != "" && query.To != "" && query.From > query.To {
+		respondWithQueryError(w, "from", "must not be after to")
+		return "", "", false
+	}
+	return query.From, query.To, true
+}
+
+// parseCSVFormat reads the decimal, thousands, delimiter and dateFormat
+// parameters of a CSV export, and writes an error response if they are invalid
+func parseCSVFormat(w http.ResponseWriter, r *http.Request) (services.CSVFormat, bool) {
+	query := r.URL.Query()
+	csvFormat, err := services.NewCSVFormat(query.Get("decimal"), query.Get("thousands"), query.Get("delimiter"), query.Get("dateFormat"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return services.CSVFormat{}, false
+	}
+	return csvFormat, true
+}
+
+// isBreakStatus checks if status is a valid break status
+func isBreakStatus(status models.BreakStatus) bool {
+	return status == models.BreakOpen || status == models.BreakAcknowledged || status == models.BreakResolved
+}
+
+// isHTTPURL checks if s is an absolute http or https URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
 // contains checks if a string contains a substring (case-insensitive)
 func contains(s, substr string) bool {
 	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
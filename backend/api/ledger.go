@@ -0,0 +1,129 @@
+package api
+
+import (
+	"hyperliquid-recon/models"
+	"hyperliquid-recon/services"
+	"log"
+	"net/http"
+)
+
+// GetTrialBalance handles GET /api/ledger/trial-balance requests
+// Totals the ledger postings of address per account between from and to
+// (YYYY-MM-DD, both optional), with the P&L figures the balances add up to.
+func (h *Handler) GetTrialBalance(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, from, to, entries, ok := ledgerEntries(w, r, t)
+	if !ok {
+		return
+	}
+
+	balance := services.BuildTrialBalance(address, entries, from, to)
+	balance.Label = t.ReconService.Label(address)
+
+	respondWithJSON(w, http.StatusOK, balance)
+}
+
+// GetLedgerEntries handles GET /api/ledger/entries requests
+// Lists the journal entries of address between from and to, oldest first.
+func (h *Handler) GetLedgerEntries(w http.ResponseWriter, r *http.Request) {
+	_, _, _, entries, ok := ledgerEntries(w, r, tenantFrom(r))
+	if !ok {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, entries)
+}
+
+// GetIncome handles GET /api/ledger/income requests
+// Totals the income of address by source per day or month (period, default
+// day) between from and to: trading P&L, fees, rebates, funding, referral
+// rewards and realized vault P&L.
+func (h *Handler) GetIncome(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "day"
+	}
+	if period != "day" && period != "month" {
+		respondWithError(w, http.StatusBadRequest, "period must be day or month")
+		return
+	}
+
+	address, _, _, entries, ok := ledgerEntries(w, r, t)
+	if !ok {
+		return
+	}
+
+	summary := services.BuildIncomeSummary(address, entries, period)
+	summary.Label = t.ReconService.Label(address)
+
+	respondWithJSON(w, http.StatusOK, summary)
+}
+
+// GetFundingContext handles GET /api/analytics/funding requests
+// Sets the funding address paid or received per UTC day and coin against the
+// market's average funding rate that day and over the hours it paid.
+func (h *Handler) GetFundingContext(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, _, _, entries, ok := ledgerEntries(w, r, t)
+	if !ok {
+		return
+	}
+
+	funding := services.BuildFundingContext(r.Context(), services.CurrentFundingRates(), address, entries)
+	funding.Label = t.ReconService.Label(address)
+
+	respondWithJSON(w, http.StatusOK, funding)
+}
+
+// GetVaults handles GET /api/ledger/vaults requests
+// Returns the vault positions of address with their P&L and value history,
+// and its staking actions, after bringing the ledger up to date.
+func (h *Handler) GetVaults(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, _, _, _, ok := ledgerEntries(w, r, t)
+	if !ok {
+		return
+	}
+
+	summary, exists := t.Ledger.Vaults(address)
+	if !exists {
+		summary = models.VaultSummary{Address: address, Vaults: []models.VaultPosition{}, History: []models.VaultValue{}, Staking: []models.StakingAction{}}
+	}
+	summary.Label = t.ReconService.Label(address)
+
+	respondWithJSON(w, http.StatusOK, summary)
+}
+
+// ledgerEntries brings the stored cash flows of the address parameter (default:
+// the current summary's) up to date and returns its journal for the from/to
+// period, writing an error response if it can't
+func ledgerEntries(w http.ResponseWriter, r *http.Request, t *services.Tenant) (string, string, string, []models.JournalEntry, bool) {
+	address, ok := analyticsAddress(w, r, t)
+	if !ok {
+		return "", "", "", nil, false
+	}
+	from, to, ok := parseDateRange(w, r, "", "")
+	if !ok {
+		return "", "", "", nil, false
+	}
+
+	trades, exists := t.ReconService.CachedTrades(address)
+	coverageStart, _ := t.ReconService.CachedCoverageStart(address)
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "no cached trades for address; refresh it first")
+		return "", "", "", nil, false
+	}
+
+	if err := t.Ledger.Sync(r.Context(), address, coverageStart); err != nil {
+		log.Printf("Error syncing ledger for %s: %v", address, err)
+		respondWithError(w, http.StatusBadGateway, "Failed to fetch funding payments and transfers. Please try again later.")
+		return "", "", "", nil, false
+	}
+
+	return address, from, to, t.Ledger.Entries(address, trades, from, to), true
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"hyperliquid-recon/services"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// upgrader accepts WebSocket connections from any origin, like the CORS
+// policy; connections are authenticated by API key like every other request
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// pushReply is sent to a WebSocket subscriber in answer to a message of its own
+type pushReply struct {
+	Type   string             `json:"type"` // "subscribed" or "error"
+	Filter *models.PushFilter `json:"filter,omitempty"`
+	Error  string             `json:"error,omitempty"`
+}
+
+// resolveFilter resolves the addresses of filter, returning the error to send back if one can't be
+func resolveFilter(t *services.Tenant, filter models.PushFilter) (models.PushFilter, string) {
+	addresses := make([]string, len(filter.Addresses))
+	for i, input := range filter.Addresses {
+		address, err := t.AddressBook.Resolve(input)
+		if err != nil {
+			return filter, err.Error()
+		}
+		addresses[i] = address
+	}
+	filter.Addresses = addresses
+	return filter, ""
+}
+
+// StreamEvents handles GET /api/ws requests
+// Upgrades to a WebSocket that pushes the tenant's events as JSON as they
+// happen: P&L updates, data-quality alerts and breaks. Repeated address and
+// type parameters set the initial filter; the client can replace it at any
+// time by sending {"addresses": [...], "types": [...]}, and each filter in
+// effect is confirmed with a "subscribed" message. Browsers, which can't set
+// headers on a WebSocket, pass their key in the apiKey parameter.
+func (h *Handler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	query := r.URL.Query()
+	filter := models.PushFilter{Types: query["type"]}
+	for _, input := range query["address"] {
+		address, ok := resolveAddress(w, t, input)
+		if !ok {
+			return
+		}
+		filter.Addresses = append(filter.Addresses, address)
+	}
+	sub, err := t.Push.Subscribe(filter)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer sub.Close()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // The upgrader has already replied
+	}
+	defer conn.Close()
+
+	// Read the client's filters, and its pongs so the read deadline moves on
+	replies := make(chan pushReply)
+	closed, done := make(chan struct{}), make(chan struct{})
+	defer close(done)
+	reply := func(message pushReply) bool {
+		select {
+		case replies <- message:
+			return true
+		case <-done:
+			return false
+		}
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * config.PushPingInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * config.PushPingInterval))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			var next models.PushFilter
+			err := conn.ReadJSON(&next)
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				if !reply(pushReply{Type: "error", Error: `messages must be a filter such as {"addresses": [], "types": []}`}) {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				return // Closed by the client, or it stopped answering pings
+			}
+
+			next, problem := resolveFilter(t, next)
+			if problem == "" {
+				if err := sub.SetFilter(next); err != nil {
+					problem = err.Error()
+				}
+			}
+			message := pushReply{Type: "subscribed", Filter: &next}
+			if problem != "" {
+				message = pushReply{Type: "error", Error: problem}
+			}
+			if !reply(message) {
+				return
+			}
+		}
+	}()
+
+	write := func(v interface{}) bool {
+		conn.SetWriteDeadline(time.Now().Add(config.PushWriteTimeout))
+		return conn.WriteJSON(v) == nil
+	}
+	current := sub.Filter()
+	if !write(pushReply{Type: "subscribed", Filter: &current}) {
+		return
+	}
+
+	ping := time.NewTicker(config.PushPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case event, open := <-sub.Events():
+			if !open || !write(event) {
+				return
+			}
+		case message := <-replies:
+			if !write(message) {
+				return
+			}
+		case <-ping.C:
+			if conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(config.PushWriteTimeout)) != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
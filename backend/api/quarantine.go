@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"hyperliquid-recon/models"
+	"hyperliquid-recon/services"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// GetQuarantine handles GET /api/quarantine requests
+// Lists fills that couldn't be parsed, newest first, optionally of one
+// address and in one status (quarantined, reprocessed or discarded).
+func (h *Handler) GetQuarantine(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var address string
+	if input := r.URL.Query().Get("address"); input != "" {
+		resolved, ok := resolveAddress(w, t, input)
+		if !ok {
+			return
+		}
+		address = resolved
+	}
+	status := models.QuarantineStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", models.QuarantinePending, models.QuarantineReprocessed, models.QuarantineDiscarded:
+	default:
+		respondWithError(w, http.StatusBadRequest, "status must be quarantined, reprocessed or discarded")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, t.Quarantine.List(address, status))
+}
+
+// FixQuarantinedFill handles PUT /api/quarantine/{id} requests
+// Replaces a quarantined fill with a corrected copy in the API's fill format,
+// to be parsed by the next reprocess.
+func (h *Handler) FixQuarantinedFill(w http.ResponseWriter, r *http.Request) {
+	var fill json.RawMessage
+	if !decodeBody(w, r, &fill) {
+		return
+	}
+
+	fixed, err := tenantFrom(r).Quarantine.Fix(mux.Vars(r)["id"], fill)
+	respondWithQuarantinedFill(w, fixed, err)
+}
+
+// DiscardQuarantinedFill handles DELETE /api/quarantine/{id} requests
+// Takes a fill out of quarantine without merging it. It is kept as discarded
+// so fetching it again doesn't quarantine it again.
+func (h *Handler) DiscardQuarantinedFill(w http.ResponseWriter, r *http.Request) {
+	discarded, err := tenantFrom(r).Quarantine.Discard(mux.Vars(r)["id"])
+	respondWithQuarantinedFill(w, discarded, err)
+}
+
+// ReprocessQuarantine handles POST /api/quarantine/reprocess requests
+// Parses the quarantined fills, optionally of one address, again and merges
+// those that now parse into their address's cache.
+func (h *Handler) ReprocessQuarantine(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var address string
+	if input := r.URL.Query().Get("address"); input != "" {
+		resolved, ok := resolveAddress(w, t, input)
+		if !ok {
+			return
+		}
+		address = resolved
+	}
+
+	result, err := t.ReconService.ReprocessQuarantine(address)
+	if err != nil {
+		log.Printf("Error saving quarantine: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save quarantine")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// respondWithQuarantinedFill writes a changed quarantined fill, or the error changing it
+func respondWithQuarantinedFill(w http.ResponseWriter, fill models.QuarantinedFill, err error) {
+	switch {
+	case errors.Is(err, services.ErrQuarantineNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrNotQuarantined):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidFill):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		log.Printf("Error saving quarantine: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save quarantine")
+	default:
+		respondWithJSON(w, http.StatusOK, fill)
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"errors"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"hyperliquid-recon/services"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Reconcile handles POST /api/reconcile?address={address}&format={format} requests
+// Matches an external trade file, in the export format, against the cached
+// fills of address and records the result. window (e.g. 500ms),
+// priceEpsilon (relative) and aggregate (true/false) override the configured
+// matching tolerances.
+func (h *Handler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, ok := resolveAddress(w, t, r.URL.Query().Get("address"))
+	if !ok {
+		return
+	}
+
+	tolerance := services.DefaultMatchTolerance()
+	query := struct {
+		Window       time.Duration `query:"window" validate:"min=0"`
+		PriceEpsilon float64       `query:"priceEpsilon" validate:"min=0"`
+		Aggregate    bool          `query:"aggregate"`
+		Format       string        `query:"format" validate:"oneof=csv parquet"`
+	}{
+		Window:       time.Duration(tolerance.TimeWindowMs) * time.Millisecond,
+		PriceEpsilon: tolerance.PriceEpsilon,
+		Aggregate:    tolerance.AggregateSizes,
+		Format:       services.FormatCSV,
+	}
+	if !decodeQuery(w, r, &query) {
+		return
+	}
+	// A relative tolerance of 100% or more would match any price
+	if query.PriceEpsilon >= 1 {
+		respondWithQueryError(w, "priceEpsilon", "must be less than 1")
+		return
+	}
+	tolerance.TimeWindowMs = query.Window.Milliseconds()
+	tolerance.PriceEpsilon = query.PriceEpsilon
+	tolerance.AggregateSizes = query.Aggregate
+	format := query.Format
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, config.ImportMaxBytes))
+	if err != nil {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "upload is too large")
+		return
+	}
+	external, err := services.DecodeTrades(format, body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid trade file: "+err.Error())
+		return
+	}
+
+	cached, exists := t.ReconService.CachedTrades(address)
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "no cached trades for address; refresh it first")
+		return
+	}
+
+	recon := services.ReconcileExternal(address, cached, external, tolerance)
+	recon.Label = t.ReconService.Label(address)
+	respondWithJSON(w, http.StatusOK, t.Reconciliations.Record(recon))
+}
+
+// GetReconciliation handles GET /api/reconcile/{id} requests
+func (h *Handler) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	recon, err := tenantFrom(r).Reconciliations.Get(mux.Vars(r)["id"])
+	if errors.Is(err, services.ErrReconciliationNotFound) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, recon)
+}
+
+// ResolveMatch handles POST /api/reconcile/{id}/match requests
+// Links unmatched fills of a reconciliation to unmatched external rows by
+// their refs, or marks fills or rows explained, and saves the resolution for
+// later reconciliations of the address.
+func (h *Handler) ResolveMatch(w http.ResponseWriter, r *http.Request) {
+	var resolution models.MatchResolution
+	if !decodeBody(w, r, &resolution) {
+		return
+	}
+
+	recon, err := tenantFrom(r).Reconciliations.Resolve(mux.Vars(r)["id"], resolution)
+	switch {
+	case errors.Is(err, services.ErrReconciliationNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidResolution):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondWithJSON(w, http.StatusOK, recon)
+	}
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"context"
+	"net/http"
+)
+
+// GetRetention handles GET /api/retention requests
+// Returns the retention policy and how many records pruning has deleted.
+func (h *Handler) GetRetention(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, tenantFrom(r).Retention.Status())
+}
+
+// PruneRetention handles POST /api/retention/prune requests
+// Deletes every record older than the retention policy now. With
+// dryRun=true nothing is deleted and the report lists what would be.
+func (h *Handler) PruneRetention(w http.ResponseWriter, r *http.Request) {
+	var query struct {
+		DryRun bool `query:"dryRun"`
+	}
+	if !decodeQuery(w, r, &query) {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, tenantFrom(r).Retention.Prune(context.WithoutCancel(r.Context()), query.DryRun))
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"hyperliquid-recon/services"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// GetRuns handles GET /api/runs requests
+// Lists recorded reconciliation runs, newest first, optionally of one address.
+func (h *Handler) GetRuns(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var address string
+	if input := r.URL.Query().Get("address"); input != "" {
+		resolved, ok := resolveAddress(w, t, input)
+		if !ok {
+			return
+		}
+		address = resolved
+	}
+
+	respondWithJSON(w, http.StatusOK, t.Runs.List(address))
+}
+
+// GetRun handles GET /api/runs/{id} requests
+// Returns a run with its daily records.
+func (h *Handler) GetRun(w http.ResponseWriter, r *http.Request) {
+	run, err := tenantFrom(r).Runs.Get(mux.Vars(r)["id"])
+	if errors.Is(err, services.ErrRunNotFound) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, run)
+}
+
+// CompareRuns handles GET /api/runs/compare?a={id}&b={id} requests
+// Lists the days whose P&L, trade count or volume differ between two runs of
+// the same address.
+func (h *Handler) CompareRuns(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	a, b := r.URL.Query().Get("a"), r.URL.Query().Get("b")
+	if a == "" || b == "" {
+		respondWithError(w, http.StatusBadRequest, "a and b run IDs are required")
+		return
+	}
+
+	comparison, err := t.Runs.Compare(a, b)
+	if errors.Is(err, services.ErrRunNotFound) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if comparison.A.Address != comparison.B.Address {
+		respondWithError(w, http.StatusBadRequest, "runs are of different addresses")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, comparison)
+}
+
+// GetTradeDiff handles GET /api/diff?address={address}&from={date}&to={date} requests
+// Lists the fills added, removed or changed from from to to (YYYY-MM-DD,
+// inclusive; the whole history by default) by the last refresh of address
+// that changed its trades.
+func (h *Handler) GetTradeDiff(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	address, ok := resolveAddress(w, t, r.URL.Query().Get("address"))
+	if !ok {
+		return
+	}
+	from, to, ok := parseDateRange(w, r, "", "")
+	if !ok {
+		return
+	}
+	var start, end time.Time
+	if from != "" {
+		start, _ = time.ParseInLocation("2006-01-02", from, time.Local)
+	}
+	if to != "" {
+		end, _ = time.ParseInLocation("2006-01-02", to, time.Local)
+		end = end.AddDate(0, 0, 1)
+	}
+
+	diff, err := t.ReconService.TradeDiff(address, start, end)
+	if errors.Is(err, services.ErrNotCached) || errors.Is(err, services.ErrNoSnapshot) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	diff.From, diff.To = from, to
+
+	respondWithJSON(w, http.StatusOK, diff)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/services"
+	"net/http"
+)
+
+// SearchTrades handles GET /api/search requests
+// Finds cached trades matching q, e.g. "coin=ETH AND notional>10000 AND
+// side=B", across every cached address or only those given as address, which
+// may be repeated. sort is time (newest first, the default) or notional
+// (largest first); limit caps the hits.
+func (h *Handler) SearchTrades(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+	params := r.URL.Query()
+
+	query, err := services.ParseTradeQuery(params.Get("q"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var addresses []string
+	for _, input := range params["address"] {
+		address, ok := resolveAddress(w, t, input)
+		if !ok {
+			return
+		}
+		addresses = append(addresses, address)
+	}
+
+	options := struct {
+		Sort  string `query:"sort" validate:"oneof=time notional"`
+		Limit int    `query:"limit" validate:"min=1"`
+	}{Sort: services.SearchSortTime, Limit: config.SearchLimit}
+	if !decodeQuery(w, r, &options) {
+		return
+	}
+	if options.Limit > config.SearchMaxLimit {
+		respondWithQueryError(w, "limit", fmt.Sprintf("must be at most %d", config.SearchMaxLimit))
+		return
+	}
+
+	result := t.ReconService.SearchTrades(query, addresses, options.Sort, options.Limit)
+	result.Query = params.Get("q")
+	respondWithJSON(w, http.StatusOK, result)
+}
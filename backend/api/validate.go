@@ -0,0 +1,293 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError is a request body field or query parameter that failed validation
+type FieldError struct {
+	Field   string `json:"field"` // JSON path of the field, e.g. "items[2].days", or the parameter name
+	Message string `json:"message"`
+}
+
+// decodeBody decodes the JSON request body into dst, a pointer to a struct or
+// slice, and checks it against the validate tags of its fields. A body that
+// isn't valid JSON is a 400; one that breaks a rule is a 422 listing every
+// failing field. It writes the error response and returns false on failure.
+//
+// Rules are comma separated, e.g. `validate:"required,max=64"`:
+//
+//	required    not the zero value; for strings, not blank
+//	min=N max=N bounds on a number, or on the length of a string or slice
+//	oneof=a b   one of the space separated values
+//	date        a YYYY-MM-DD date
+//	month       a YYYY-MM month
+//	url         an absolute http or https URL
+//
+// Empty optional fields and nil pointers skip every rule but required, and
+// structs inside slices and pointers are validated too.
+func decodeBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON body")
+		return false
+	}
+	if fields := validateValue(reflect.ValueOf(dst).Elem(), ""); len(fields) > 0 {
+		respondWithJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: "request body failed validation", Fields: fields})
+		return false
+	}
+	return true
+}
+
+// decodeQuery fills dst, a pointer to a struct, from the query parameters
+// named by the query tags of its fields, and checks each parameter given
+// against the field's validate tag as decodeBody does. Fields whose parameter
+// is absent or empty keep their value, so dst holds the defaults. Strings, integers,
+// floats, booleans (true, false, 1 or 0) and durations (e.g. 500ms) can be filled. A parameter that
+// doesn't parse or breaks a rule is a 422 listing every failing parameter. It
+// writes the error response and returns false on failure.
+func decodeQuery(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if fields := validateQuery(r.URL.Query(), reflect.ValueOf(dst).Elem()); len(fields) > 0 {
+		respondWithJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: "query parameters failed validation", Fields: fields})
+		return false
+	}
+	return true
+}
+
+// validateQuery fills the fields of v from query and checks them
+func validateQuery(query url.Values, v reflect.Value) []FieldError {
+	var fields []FieldError
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := field.Tag.Get("query")
+		value := query.Get(name)
+		if name == "" || value == "" {
+			if tag := field.Tag.Get("validate"); name != "" && strings.Contains(","+tag+",", ",required,") {
+				fields = append(fields, FieldError{Field: name, Message: "is required"})
+			}
+			continue
+		}
+		if message := setQueryValue(v.Field(i), value); message != "" {
+			fields = append(fields, FieldError{Field: name, Message: message})
+			continue
+		}
+		// A parameter that is given is checked even if it is zero, so days=0
+		// fails min=1 rather than falling back to the default
+		if message := checkValue(v.Field(i), field.Tag.Get("validate")); message != "" {
+			fields = append(fields, FieldError{Field: name, Message: message})
+		}
+	}
+	return fields
+}
+
+// respondWithQueryError writes the 422 response for a query parameter that
+// decoded but failed a check its validate tag can't express
+func respondWithQueryError(w http.ResponseWriter, field, message string) {
+	respondWithJSON(w, http.StatusUnprocessableEntity, ErrorResponse{
+		Error:  "query parameters failed validation",
+		Fields: []FieldError{{Field: field, Message: message}},
+	})
+}
+
+// setQueryValue parses value into v, returning what is wrong with it or "" if nothing is
+func setQueryValue(v reflect.Value, value string) string {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return "must be a duration such as 500ms"
+		}
+		v.SetInt(int64(d))
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return "must be an integer"
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return "must be a non-negative integer"
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return "must be a number"
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		switch value {
+		case "true", "1":
+			v.SetBool(true)
+		case "false", "0":
+			v.SetBool(false)
+		default:
+			return "must be true or false"
+		}
+	default:
+		panic(fmt.Sprintf("validate: query parameters can't fill %s", v.Kind()))
+	}
+	return ""
+}
+
+// validateValue checks v, found at path, and everything inside it
+func validateValue(v reflect.Value, path string) []FieldError {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return validateValue(v.Elem(), path)
+	case reflect.Slice, reflect.Array:
+		switch v.Type().Elem().Kind() {
+		case reflect.Struct, reflect.Pointer, reflect.Slice, reflect.Array:
+		default:
+			return nil // Nothing inside can carry rules
+		}
+		var fields []FieldError
+		for i := 0; i < v.Len(); i++ {
+			fields = append(fields, validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return fields
+	case reflect.Struct:
+		var fields []FieldError
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := jsonName(field)
+			if name == "-" {
+				continue
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			if message := checkRules(v.Field(i), field.Tag.Get("validate")); message != "" {
+				fields = append(fields, FieldError{Field: name, Message: message})
+				continue
+			}
+			if field.Type.Kind() != reflect.String {
+				fields = append(fields, validateValue(v.Field(i), name)...)
+			}
+		}
+		return fields
+	}
+	return nil
+}
+
+// jsonName returns the name field is decoded from
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// checkRules checks v against the rules of a validate tag, returning what is
+// wrong with it or "" if nothing is
+func checkRules(v reflect.Value, tag string) string {
+	if tag == "" {
+		return ""
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if strings.Contains(","+tag+",", ",required,") {
+				return "is required"
+			}
+			return ""
+		}
+		v = v.Elem()
+	}
+	if isEmpty(v) {
+		if strings.Contains(","+tag+",", ",required,") {
+			return "is required"
+		}
+		return ""
+	}
+	return checkValue(v, tag)
+}
+
+// checkValue checks v, which isn't empty or nil, against every rule of a validate tag
+func checkValue(v reflect.Value, tag string) string {
+	if tag == "" {
+		return ""
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				panic(fmt.Sprintf("validate: bad %s rule %q", name, rule))
+			}
+			size, what := measure(v)
+			if name == "min" && size < limit {
+				return fmt.Sprintf("must be at least %s%s", arg, what)
+			}
+			if name == "max" && size > limit {
+				return fmt.Sprintf("must be at most %s%s", arg, what)
+			}
+		case "oneof":
+			options := strings.Fields(arg)
+			if !slices.Contains(options, fmt.Sprint(v.Interface())) {
+				return "must be one of " + strings.Join(options, ", ")
+			}
+		case "date":
+			if _, err := time.Parse("2006-01-02", v.String()); err != nil {
+				return "must be a date in YYYY-MM-DD format"
+			}
+		case "month":
+			if _, err := time.Parse("2006-01", v.String()); err != nil {
+				return "must be a month in YYYY-MM format"
+			}
+		case "url":
+			if !isHTTPURL(v.String()) {
+				return "must be an absolute http or https URL"
+			}
+		default:
+			panic(fmt.Sprintf("validate: unknown rule %q", rule))
+		}
+	}
+	return ""
+}
+
+// isEmpty reports whether v is its zero value, counting a blank string as empty
+func isEmpty(v reflect.Value) bool {
+	if v.Kind() == reflect.String {
+		return strings.TrimSpace(v.String()) == ""
+	}
+	return v.IsZero()
+}
+
+// measure returns what min and max compare for v, with the unit to name in errors
+func measure(v reflect.Value) (float64, string) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(len([]rune(v.String()))), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	}
+	panic(fmt.Sprintf("validate: min and max don't apply to %s", v.Kind()))
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"hyperliquid-recon/services"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test each validate rule against values that pass and fail it
+func TestCheckRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		value   any
+		message string // "" if the value passes
+	}{
+		{"required string", "required", "desk A", ""},
+		{"required blank string", "required", "   ", "is required"},
+		{"required zero number", "required", 0, "is required"},
+		{"required nil pointer", "required", (*string)(nil), "is required"},
+		{"required set pointer", "required", ptr(""), "is required"},
+		{"min number", "min=1", 1, ""},
+		{"min number below", "min=1", -2, "must be at least 1"},
+		{"min string length", "min=3", "ab", "must be at least 3 characters"},
+		{"min slice length", "min=2", []int{1}, "must be at least 2 items"},
+		{"max number", "max=90", 90.0, ""},
+		{"max number above", "max=90", 90.5, "must be at most 90"},
+		{"max string length counts runes", "max=2", "éé", ""},
+		{"max string length", "max=2", "abc", "must be at most 2 characters"},
+		{"oneof", "oneof=high low", "low", ""},
+		{"oneof other", "oneof=high low", "urgent", "must be one of high, low"},
+		{"date", "date", "2025-06-30", ""},
+		{"date invalid", "date", "2025-02-30", "must be a date in YYYY-MM-DD format"},
+		{"date as month", "date", "2025-06", "must be a date in YYYY-MM-DD format"},
+		{"month", "month", "2025-06", ""},
+		{"month invalid", "month", "2025-13", "must be a month in YYYY-MM format"},
+		{"url", "url", "https://example.com/hook", ""},
+		{"url without scheme", "url", "example.com/hook", "must be an absolute http or https URL"},
+		{"url other scheme", "url", "ftp://example.com", "must be an absolute http or https URL"},
+		{"optional empty skips rules", "min=1,date", "", ""},
+		{"optional nil pointer skips rules", "oneof=a b", (*string)(nil), ""},
+		{"first failing rule wins", "required,min=5,max=1", "abc", "must be at least 5 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if message := checkRules(reflect.ValueOf(tt.value), tt.tag); message != tt.message {
+				t.Errorf("Expected %q for %v against %q, got %q", tt.message, tt.value, tt.tag, message)
+			}
+		})
+	}
+
+	t.Run("should panic on a bad tag", func(t *testing.T) {
+		for _, tag := range []string{"min=one", "max=", "between=1 2", "required,email"} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("Expected tag %q to panic", tag)
+					}
+				}()
+				checkRules(reflect.ValueOf("value"), tag)
+			}()
+		}
+	})
+}
+
+// Test request bodies are checked field by field, with JSON paths
+func TestDecodeBody(t *testing.T) {
+	type item struct {
+		Address string `json:"address" validate:"required"`
+		Days    int    `json:"days,omitempty" validate:"min=1"`
+	}
+	type body struct {
+		Month  string  `json:"month" validate:"required,month"`
+		Items  []item  `json:"items"`
+		Status *string `json:"status" validate:"oneof=open resolved"`
+		secret string  `validate:"required"`
+	}
+
+	decode := func(payload string) (*httptest.ResponseRecorder, body) {
+		var dst body
+		w := httptest.NewRecorder()
+		decodeBody(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload)), &dst)
+		return w, dst
+	}
+
+	t.Run("should accept a valid body", func(t *testing.T) {
+		w, dst := decode(`{"month":"2025-06","items":[{"address":"0xabc","days":3}],"status":"open"}`)
+		if w.Code != http.StatusOK || dst.Items[0].Days != 3 {
+			t.Errorf("Expected the body to be decoded, got %d: %s", w.Code, w.Body)
+		}
+	})
+
+	t.Run("should reject invalid JSON with 400", func(t *testing.T) {
+		if w, _ := decode(`{"month":`); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("should list every failing field with 422", func(t *testing.T) {
+		w, _ := decode(`{"month":"June","items":[{"address":"0xabc"},{"days":-1}],"status":"closed"}`)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected 422, got %d", w.Code)
+		}
+		var response ErrorResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		want := []FieldError{
+			{Field: "month", Message: "must be a month in YYYY-MM format"},
+			{Field: "items[1].address", Message: "is required"},
+			{Field: "items[1].days", Message: "must be at least 1"},
+			{Field: "status", Message: "must be one of open, resolved"},
+		}
+		if !reflect.DeepEqual(response.Fields, want) {
+			t.Errorf("Expected %+v, got %+v", want, response.Fields)
+		}
+	})
+}
+
+// Test query parameters are parsed into their fields and checked
+func TestDecodeQuery(t *testing.T) {
+	type params struct {
+		Days     int           `query:"days" validate:"min=1"`
+		DryRun   bool          `query:"dryRun"`
+		Priority string        `query:"priority" validate:"oneof=high low"`
+		From     string        `query:"from" validate:"date"`
+		Rate     float64       `query:"rate" validate:"max=1"`
+		Wait     time.Duration `query:"wait" validate:"min=0"`
+		Ignored  string
+	}
+
+	decode := func(target string) (*httptest.ResponseRecorder, params) {
+		dst := params{Days: 10, Priority: "high"}
+		w := httptest.NewRecorder()
+		decodeQuery(w, httptest.NewRequest(http.MethodGet, target, nil), &dst)
+		return w, dst
+	}
+
+	t.Run("should keep the defaults for absent or empty parameters", func(t *testing.T) {
+		w, dst := decode("/?days=&priority=")
+		if w.Code != http.StatusOK || dst.Days != 10 || dst.Priority != "high" || dst.DryRun {
+			t.Errorf("Expected the defaults, got %+v (%d)", dst, w.Code)
+		}
+	})
+
+	t.Run("should fill each parameter", func(t *testing.T) {
+		w, dst := decode("/?days=30&dryRun=1&priority=low&from=2025-06-01&rate=0.5&wait=1.5s")
+		want := params{Days: 30, DryRun: true, Priority: "low", From: "2025-06-01", Rate: 0.5, Wait: 1500 * time.Millisecond}
+		if w.Code != http.StatusOK || dst != want {
+			t.Errorf("Expected %+v, got %+v (%d)", want, dst, w.Code)
+		}
+	})
+
+	tests := []struct {
+		query   string
+		field   string
+		message string
+	}{
+		{"days=0", "days", "must be at least 1"},
+		{"days=ten", "days", "must be an integer"},
+		{"dryRun=yes", "dryRun", "must be true or false"},
+		{"priority=urgent", "priority", "must be one of high, low"},
+		{"from=06/01/2025", "from", "must be a date in YYYY-MM-DD format"},
+		{"rate=NaN", "rate", "must be a number"},
+		{"rate=2", "rate", "must be at most 1"},
+		{"wait=soon", "wait", "must be a duration such as 500ms"},
+		{"wait=-1s", "wait", "must be at least 0"},
+	}
+	for _, tt := range tests {
+		t.Run("should reject "+tt.query, func(t *testing.T) {
+			w, _ := decode("/?" + tt.query)
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("Expected 422, got %d", w.Code)
+			}
+			var response ErrorResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			want := []FieldError{{Field: tt.field, Message: tt.message}}
+			if !reflect.DeepEqual(response.Fields, want) {
+				t.Errorf("Expected %+v, got %+v", want, response.Fields)
+			}
+		})
+	}
+
+	t.Run("should panic on a field it can't fill", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a slice field to panic")
+			}
+		}()
+		var dst struct {
+			Coins []string `query:"coins"`
+		}
+		decodeQuery(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?coins=BTC", nil), &dst)
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// Test handlers answer invalid query parameters with field errors before
+// doing anything with them
+func TestHandlerQueryValidation(t *testing.T) {
+	tenant, err := services.NewTenant(services.DefaultTenantConfig(), services.SharedServices{})
+	if err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	h := NewHandler(services.NewSingleTenantRegistry(tenant), nil)
+	const address = "0x1234567890abcdef1234567890abcdef12345678"
+
+	tests := []struct {
+		name    string
+		handle  http.HandlerFunc
+		query   string
+		field   string
+		message string
+	}{
+		{"statement fee rate NaN", h.GetStatement, "feeRate=NaN", "feeRate", "must be a number"},
+		{"statement fee rate above 1", h.GetStatement, "feeRate=1.5", "feeRate", "must be at most 1"},
+		{"reconcile price epsilon NaN", h.Reconcile, "priceEpsilon=NaN", "priceEpsilon", "must be a number"},
+		{"reconcile price epsilon of 1", h.Reconcile, "priceEpsilon=1", "priceEpsilon", "must be less than 1"},
+		{"reconcile window", h.Reconcile, "window=fast", "window", "must be a duration such as 500ms"},
+		{"reconcile aggregate", h.Reconcile, "aggregate=maybe", "aggregate", "must be true or false"},
+		{"reconcile format", h.Reconcile, "format=xlsx", "format", "must be one of csv, parquet"},
+		{"demo scale NaN", h.DemoDataset, "scale=NaN", "scale", "must be a number"},
+		{"demo scale infinite", h.DemoDataset, "scale=%2BInf", "scale", "must be a number"},
+		{"demo jitter NaN", h.DemoDataset, "jitter=NaN", "jitter", "must be a number"},
+		{"demo jitter above 0.5", h.DemoDataset, "jitter=0.6", "jitter", "must be at most 0.5"},
+		{"demo price jitter", h.DemoDataset, "priceJitter=0.2", "priceJitter", "must be at most 0.1"},
+		{"demo time jitter", h.DemoDataset, "timeJitter=25h", "timeJitter", "must be at most 24h"},
+		{"demo shift days", h.DemoDataset, "shiftDays=1.5", "shiftDays", "must be an integer"},
+		{"demo seed", h.DemoDataset, "seed=-1", "seed", "must be a non-negative integer"},
+		{"pnl series points", h.GetPnLSeries, "points=0", "points", "must be at least 1"},
+		{"pnl series market", h.GetPnLSeries, "market=yes", "market", "must be true or false"},
+		{"pnl heatmap year", h.GetPnLHeatmap, "year=25", "year", "must be at least 2000"},
+		{"pnl heatmap year text", h.GetPnLHeatmap, "year=last", "year", "must be an integer"},
+		{"search sort", h.SearchTrades, "q=coin%3DETH&sort=size", "sort", "must be one of time, notional"},
+		{"search limit zero", h.SearchTrades, "q=coin%3DETH&limit=0", "limit", "must be at least 1"},
+		{"search limit above the maximum", h.SearchTrades, "q=coin%3DETH&limit=1001", "limit", "must be at most 1000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?address="+address+"&"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), tenantContextKey{}, tenant))
+			w := httptest.NewRecorder()
+			tt.handle(w, req)
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("Expected 422, got %d: %s", w.Code, w.Body)
+			}
+			var response ErrorResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			want := []FieldError{{Field: tt.field, Message: tt.message}}
+			if !reflect.DeepEqual(response.Fields, want) {
+				t.Errorf("Expected %+v, got %+v", want, response.Fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"hyperliquid-recon/models"
+	"net/http"
+	"strings"
+)
+
+// Headers describing the API version of a response
+const (
+	APIVersionHeader  = "API-Version"
+	DeprecationHeader = "Deprecation"
+)
+
+// CurrentAPIVersion is the version served under /api/v1 and, deprecated, under /api
+const CurrentAPIVersion = "v1"
+
+// versionPrefix is the path prefix of the current API version
+const versionPrefix = "/api/" + CurrentAPIVersion
+
+// Versioned serves the versioned routes (/api/v1/...) from the routes
+// registered under /api, so each handler is registered once. Responses carry
+// the version they were served as. Requests to the unversioned /api routes are
+// still answered, but marked deprecated with a link to their versioned
+// successor, so dashboards can move over before a breaking change ships as v2.
+// It wraps the whole router, since routes are matched before mux middleware runs.
+func Versioned(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		switch {
+		case path == versionPrefix || path == versionPrefix+"/":
+			// The bare prefix describes the versions rather than falling through to the frontend
+			w.Header().Set(APIVersionHeader, CurrentAPIVersion)
+			r.URL.Path = "/api/versions"
+			r.URL.RawPath = ""
+
+		case strings.HasPrefix(path, versionPrefix+"/"):
+			w.Header().Set(APIVersionHeader, CurrentAPIVersion)
+			r.URL.Path = "/api" + strings.TrimPrefix(path, versionPrefix)
+			r.URL.RawPath = ""
+
+		case strings.HasPrefix(path, "/api/") && path != "/api/versions":
+			w.Header().Set(APIVersionHeader, CurrentAPIVersion)
+			w.Header().Set(DeprecationHeader, "true")
+			w.Header().Add("Link", "<"+versionPrefix+strings.TrimPrefix(path, "/api")+`>; rel="successor-version"`)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetVersions handles GET /api/versions requests
+// Lists the API versions this server answers and which one is current.
+func (h *Handler) GetVersions(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, models.APIVersions{
+		Current: CurrentAPIVersion,
+		Versions: []models.APIVersion{
+			{Version: CurrentAPIVersion, Prefix: versionPrefix, Status: "current"},
+			{Version: "unversioned", Prefix: "/api", Status: "deprecated"},
+		},
+	})
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test versioned paths are served from the unversioned routes, and unversioned
+// paths are marked deprecated
+func TestVersioned(t *testing.T) {
+	var served string
+	handler := Versioned(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = r.URL.Path
+	}))
+
+	tests := []struct {
+		name        string
+		path        string
+		served      string
+		version     string
+		deprecation string
+		link        string
+	}{
+		{"versioned route", "/api/v1/pnl", "/api/pnl", "v1", "", ""},
+		{"versioned route with parameters", "/api/v1/refresh/batch/42", "/api/refresh/batch/42", "v1", "", ""},
+		{"bare version prefix", "/api/v1", "/api/versions", "v1", "", ""},
+		{"bare version prefix with slash", "/api/v1/", "/api/versions", "v1", "", ""},
+		{"unversioned route", "/api/pnl", "/api/pnl", "v1", "true", `</api/v1/pnl>; rel="successor-version"`},
+		{"unversioned admin route", "/api/admin/stats", "/api/admin/stats", "v1", "true", `</api/v1/admin/stats>; rel="successor-version"`},
+		{"version listing", "/api/versions", "/api/versions", "", "", ""},
+		{"frontend", "/index.html", "/index.html", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if served != tt.served {
+				t.Errorf("Expected %s to be served as %s, got %s", tt.path, tt.served, served)
+			}
+			if got := w.Header().Get(APIVersionHeader); got != tt.version {
+				t.Errorf("Expected %s %q, got %q", APIVersionHeader, tt.version, got)
+			}
+			if got := w.Header().Get(DeprecationHeader); got != tt.deprecation {
+				t.Errorf("Expected %s %q, got %q", DeprecationHeader, tt.deprecation, got)
+			}
+			if got := w.Header().Get("Link"); got != tt.link {
+				t.Errorf("Expected Link %q, got %q", tt.link, got)
+			}
+		})
+	}
+}
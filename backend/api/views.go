@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"hyperliquid-recon/services"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// GetViews handles GET /api/views requests
+func (h *Handler) GetViews(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, tenantFrom(r).Views.List())
+}
+
+// GetView handles GET /api/views/{name} requests
+func (h *Handler) GetView(w http.ResponseWriter, r *http.Request) {
+	view, err := tenantFrom(r).Views.Get(mux.Vars(r)["name"])
+	if errors.Is(err, services.ErrViewNotFound) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, view)
+}
+
+// SaveView handles PUT /api/views/{name} requests
+// Creates or replaces a named view. Addresses may be labels or ENS names;
+// they are resolved when the view is saved.
+func (h *Handler) SaveView(w http.ResponseWriter, r *http.Request) {
+	t := tenantFrom(r)
+
+	var view models.View
+	if !decodeBody(w, r, &view) {
+		return
+	}
+	view.Name = mux.Vars(r)["name"]
+	for i, input := range view.Addresses {
+		address, ok := resolveAddress(w, t, input)
+		if !ok {
+			return
+		}
+		view.Addresses[i] = address
+	}
+
+	saved, err := t.Views.Save(view)
+	switch {
+	case errors.Is(err, services.ErrInvalidView):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		log.Printf("Error saving views: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save view")
+	default:
+		respondWithJSON(w, http.StatusOK, saved)
+	}
+}
+
+// DeleteView handles DELETE /api/views/{name} requests
+func (h *Handler) DeleteView(w http.ResponseWriter, r *http.Request) {
+	deleted, err := tenantFrom(r).Views.Delete(mux.Vars(r)["name"])
+	if err != nil {
+		log.Printf("Error saving views: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save views")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, services.ErrViewNotFound.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ApplyView is middleware that fills in the parameters of the saved view
+// named by a request's view parameter. Parameters the request sets itself
+// are kept, so a view can be narrowed per request. It must run after
+// Authenticate.
+func (h *Handler) ApplyView(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		name := query.Get("view")
+		t, authenticated := r.Context().Value(tenantContextKey{}).(*services.Tenant)
+		if name == "" || !authenticated || strings.HasPrefix(r.URL.Path, "/api/views") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		view, err := t.Views.Get(name)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "view "+name+" not found")
+			return
+		}
+		for param, values := range services.ViewQuery(view) {
+			if !query.Has(param) {
+				query[param] = values
+			}
+		}
+		query.Del("view")
+
+		applied := r.Clone(r.Context())
+		applied.URL.RawQuery = query.Encode()
+		next.ServeHTTP(w, applied)
+	})
+}
@@ -1,27 +1,376 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"errors"
+	"flag"
 	"fmt"
 	"hyperliquid-recon/api"
 	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
 	"hyperliquid-recon/services"
+	"io"
 	"io/fs"
 	"log"
 	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/robfig/cron/v3"
 )
 
 //go:embed frontend/build
 var frontendFS embed.FS
 
 func main() {
-	// Initialize reconciliation service
-	reconService := services.NewReconciliationService()
+	importDir := flag.String("import", "", "directory of previously exported trade files to load into the cache on startup")
+	importTenant := flag.String("tenant", services.DefaultTenantID, "tenant whose cache -import loads into and rebuild reports on")
+	dataRoot := flag.String("data-dir", config.DataDir, "directory to keep the database, exports, logs and fixtures in, created on first run")
+	flag.Parse()
+
+	// "install" and "uninstall" register the reconciler with systemd or the
+	// Windows service manager, so it starts at boot and resumes its schedules
+	switch flag.Arg(0) {
+	case "install":
+		if err := services.InstallService(serviceArgs()); err != nil {
+			log.Fatal("Failed to install service:", err)
+		}
+		log.Printf("Installed and started service %s", config.ServiceName)
+		return
+	case "uninstall":
+		if err := services.UninstallService(); err != nil {
+			log.Fatal("Failed to uninstall service:", err)
+		}
+		log.Printf("Uninstalled service %s", config.ServiceName)
+		return
+	}
+
+	// Lay out the data directory and log to it as well as stderr
+	var dataDir services.DataDir
+	if *dataRoot != "" {
+		var err error
+		dataDir, err = services.PrepareDataDir(*dataRoot)
+		if err != nil {
+			log.Fatal("Failed to prepare data directory:", err)
+		}
+		logFile, err := dataDir.OpenLog()
+		if err != nil {
+			log.Fatal("Failed to open log file:", err)
+		}
+		defer logFile.Close()
+		log.SetOutput(io.MultiWriter(os.Stderr, logFile))
+
+		config.DataDir = dataDir.Root
+		if os.Getenv("RECON_TAPE_FILE") == "" {
+			config.TapeFile = filepath.Join(dataDir.Fixtures, config.TapeFile)
+		}
+		log.Printf("Using data directory %s", dataDir.Root)
+	}
+
+	// Export traces if an OTLP endpoint is configured
+	shutdownTracing, err := services.InitTracing(context.Background())
+	if err != nil {
+		log.Fatal("Failed to initialize tracing:", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Read API keys, passwords and signing secrets from the secrets provider
+	secrets, err := services.NewSecretsProvider(config.SecretsProvider)
+	if err != nil {
+		log.Fatal("Failed to initialize secrets:", err)
+	}
+	if err := loadSecrets(secrets); err != nil {
+		log.Fatal("Failed to load secrets:", err)
+	}
+	if err := services.UseStorageKey(config.StorageKey); err != nil {
+		log.Fatal("Invalid RECON_STORAGE_KEY:", err)
+	}
+
+	// Stores shared by every tenant are kept in the data directory unless configured
+	if config.DataDir != "" {
+		for file, name := range map[*string]string{
+			&config.SymbolHistoryFile: "symbol-history.json",
+			&config.FundingRatesFile:  "funding-rates.json",
+			&config.MarketContextFile: "market-context.json",
+		} {
+			if *file == "" {
+				*file = filepath.Join(dataDir.DB, name)
+			}
+		}
+	}
+
+	// "backup" and "restore" snapshot the persistent store or put a snapshot
+	// back, e.g. to move to another host, instead of serving
+	switch flag.Arg(0) {
+	case "backup":
+		if err := runBackup(flag.Args()[1:], secrets); err != nil {
+			log.Fatal("Backup failed:", err)
+		}
+		return
+	case "restore":
+		if err := runRestore(flag.Args()[1:], secrets); err != nil {
+			log.Fatal("Restore failed:", err)
+		}
+		return
+	}
+
+	// Put back a snapshot uploaded through /api/admin/restore before any store is loaded
+	if config.DataDir != "" {
+		if err := restorePending(dataDir, secrets); err != nil {
+			log.Fatal("Failed to restore snapshot:", err)
+		}
+	}
+
+	// Send Hyperliquid requests through the configured proxy with the configured headers
+	if config.HyperliquidProxyURL != "" {
+		if err := services.UseHyperliquidProxy(config.HyperliquidProxyURL); err != nil {
+			log.Fatal("Failed to configure proxy:", err)
+		}
+	}
+	if err := services.UseHyperliquidHeaders(config.HyperliquidUserAgent, config.HyperliquidHeaders); err != nil {
+		log.Fatal("Failed to configure RECON_HYPERLIQUID_HEADERS:", err)
+	}
+
+	if _, err := services.ParseDayBasis(config.DayBasis); err != nil {
+		log.Fatalf("Invalid RECON_DAY_BASIS: %v", err)
+	}
+
+	// Serve synthetic fills instead of calling Hyperliquid in fake-data mode
+	if config.FakeData {
+		fills, err := services.SyntheticFillsFromConfig()
+		if err != nil {
+			log.Fatal("Failed to initialize synthetic data:", err)
+		}
+		services.UseHyperliquidTransport(fills)
+		log.Printf("Using synthetic trade data for %s (RECON_FAKE_DATA=1)", config.FakeCoins)
+	}
+
+	// Record Hyperliquid traffic to a tape, or replay one instead of calling the API
+	switch config.TapeMode {
+	case "":
+	case services.TapeRecord:
+		tape, err := services.NewRecordingTape(config.TapeFile, services.HyperliquidTransport())
+		if err != nil {
+			log.Fatal("Failed to start recording:", err)
+		}
+		services.UseHyperliquidTransport(tape)
+		log.Printf("Recording Hyperliquid traffic to %s", config.TapeFile)
+	case services.TapeReplay:
+		tape, err := services.LoadTape(config.TapeFile)
+		if err != nil {
+			log.Fatal("Failed to load tape:", err)
+		}
+		services.UseHyperliquidTransport(tape)
+		log.Printf("Replaying %d recorded Hyperliquid responses from %s", tape.Len(), config.TapeFile)
+	default:
+		log.Fatalf("Unknown RECON_TAPE_MODE %q (want record or replay)", config.TapeMode)
+	}
+
+	// Symbol renames and delistings apply to every tenant's trades
+	symbolHistory, err := services.NewSymbolHistory(config.SymbolHistoryFile)
+	if err != nil {
+		log.Fatal("Failed to load symbol history:", err)
+	}
+	services.UseSymbolHistory(symbolHistory)
+
+	// Market funding rates are the same for every tenant
+	fundingRates, err := services.NewFundingRateStore(config.FundingRatesFile)
+	if err != nil {
+		log.Fatal("Failed to load funding rates:", err)
+	}
+	services.UseFundingRates(fundingRates)
+
+	// So are volume and open interest, recorded once a day if enabled
+	marketContexts, err := services.NewMarketContextStore(config.MarketContextFile)
+	if err != nil {
+		log.Fatal("Failed to load market context:", err)
+	}
+	services.UseMarketContexts(marketContexts)
+
+	// Fills responses are archived as received for audit if enabled
+	if config.FillArchiveDir == "" && config.FillArchiveEnabled && config.DataDir != "" {
+		config.FillArchiveDir = filepath.Join(dataDir.Root, "archive")
+	}
+	if config.FillArchiveDir != "" {
+		archive, err := services.NewFillArchive(config.FillArchiveDir)
+		if err != nil {
+			log.Fatal("Failed to open fill archive:", err)
+		}
+		services.UseFillArchive(archive)
+		log.Printf("Archiving fills responses to %s", config.FillArchiveDir)
+	}
+
+	// Generated files are kept for download through signed URLs until they expire
+	if config.DownloadsDir == "" {
+		config.DownloadsDir = filepath.Join(os.TempDir(), "hyperliquid-recon-downloads")
+		if config.DataDir != "" {
+			config.DownloadsDir = filepath.Join(dataDir.Root, "downloads")
+		}
+	}
+	downloadStore, err := services.NewDownloadStore(config.DownloadsDir, config.DownloadURLKey)
+	if err != nil {
+		log.Fatal("Failed to open downloads directory:", err)
+	}
+	services.UseDownloads(downloadStore)
+
+	// Dependencies shared by every tenant
+	var shared services.SharedServices
+	if config.EthRPCURL != "" {
+		shared.ENS = services.NewENSResolver(config.EthRPCURL, config.ENSTimeout)
+	}
+	if config.SMTPHost != "" && config.SMTPFrom != "" {
+		shared.Mailer = services.NewMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.SMTPFrom)
+	}
+	if config.S3Endpoint != "" && config.S3Bucket != "" {
+		shared.Exports = services.NewS3Client(config.S3Endpoint, config.S3Region, config.S3Bucket, config.S3AccessKey, config.S3SecretKey, config.S3Timeout)
+	} else if config.DataDir != "" {
+		shared.Exports = services.NewDirStore(dataDir.Exports)
+	}
+	if config.HyperEVMRPCURL != "" {
+		tokens, err := services.ParseEVMTokens(config.HyperEVMTokens)
+		if err != nil {
+			log.Fatal("Invalid RECON_HYPEREVM_TOKENS:", err)
+		}
+		shared.HyperEVM = services.NewHyperEVMClient(config.HyperEVMRPCURL, tokens, config.HyperEVMLogRange, config.HyperEVMTimeout)
+		log.Printf("Fetching HyperEVM transfers of %d tokens from %s", len(tokens), config.HyperEVMRPCURL)
+	}
+	if config.OtherIncomeFile != "" {
+		shared.IncomeSources = append(shared.IncomeSources, services.NewFileIncomeSource(config.OtherIncomeFile))
+		log.Printf("Posting other income from %s", config.OtherIncomeFile)
+	}
+	if config.OtherIncomePrices != "" {
+		prices, err := services.ParseStaticPrices(config.OtherIncomePrices)
+		if err != nil {
+			log.Fatal("Invalid RECON_OTHER_INCOME_PRICES:", err)
+		}
+		shared.Valuation = prices
+	}
+
+	// Build each tenant's isolated services. Without a tenants file there is a
+	// single tenant configured from the environment and no API key is required.
+	configs, err := tenantConfigs(secrets)
+	if err != nil {
+		log.Fatal("Failed to load tenants:", err)
+	}
+	built := make([]*services.Tenant, 0, len(configs))
+	for _, cfg := range configs {
+		tenant, err := services.NewTenant(cfg, shared)
+		if err != nil {
+			log.Fatalf("Failed to initialize tenant %s: %v", cfg.ID, err)
+		}
+		built = append(built, tenant)
+	}
+	var tenants *services.TenantRegistry
+	if config.TenantsFile != "" {
+		tenants = services.NewTenantRegistry(built, configs)
+	} else {
+		tenants = services.NewSingleTenantRegistry(built[0])
+	}
+
+	// "recon rebuild" replays the event log and reports the rebuilt P&L instead of serving
+	if flag.Arg(0) == "rebuild" {
+		tenant, ok := tenants.Tenant(*importTenant)
+		if !ok {
+			log.Fatalf("Unknown tenant %q", *importTenant)
+		}
+		if err := printRebuild(tenant); err != nil {
+			log.Fatal("Rebuild failed:", err)
+		}
+		return
+	}
+
+	// "recon demo" writes an anonymized copy of the cached trades for a public demo instance instead of serving
+	if flag.Arg(0) == "demo" {
+		tenant, ok := tenants.Tenant(*importTenant)
+		if !ok {
+			log.Fatalf("Unknown tenant %q", *importTenant)
+		}
+		if err := runDemo(flag.Args()[1:], tenant); err != nil {
+			log.Fatal("Demo dataset failed:", err)
+		}
+		return
+	}
+
+	// Bootstrap the cache from exported files so history doesn't have to be re-fetched
+	if *importDir != "" {
+		tenant, ok := tenants.Tenant(*importTenant)
+		if !ok {
+			log.Fatalf("Unknown tenant %q", *importTenant)
+		}
+		results, err := tenant.ReconService.ImportDirectory(*importDir)
+		if err != nil {
+			log.Fatal("Failed to import trades:", err)
+		}
+		for _, result := range results {
+			log.Printf("Imported %s: %d files, %d trades (%d duplicates)", result.Address, result.Files, result.TradesAdded, result.Duplicates)
+		}
+	}
+
+	scheduler := cron.New()
+
+	for _, tenant := range tenants.Tenants() {
+		// Export to Google Sheets if configured
+		if tenant.Sheets != nil {
+			go tenant.Sheets.RunSchedule(config.SheetsExportInterval)
+			log.Printf("[%s] Google Sheets export enabled (every %s)", tenant.ID, config.SheetsExportInterval)
+		}
+
+		// Refresh the leaderboard of tracked addresses once at startup; later
+		// refreshes are scheduled with the other recurring jobs below
+		go tenant.Leaderboard.Refresh()
+
+		// Schedule daily email reports
+		for _, sub := range tenant.Reports.Subscriptions() {
+			spec, _ := services.DailyCronSpec(sub.Time)
+			reports := tenant.Reports
+			if _, err := scheduler.AddFunc(spec, func() { reports.SendScheduled(sub) }); err != nil {
+				log.Fatal("Invalid report schedule:", err)
+			}
+			log.Printf("[%s] Daily report %q scheduled at %s for %s", tenant.ID, sub.Name, sub.Time, strings.Join(sub.To, ", "))
+		}
+	}
+
+	// Schedule the leaderboard, end-of-day close, S3 export and connectors, and
+	// apply the rest of the live config. The config file is watched for changes.
+	runtimeConfig := services.NewRuntimeConfigManager(config.ConfigFile, tenants, scheduler)
+	runtimeConfig.UseSecrets(secrets)
+	if _, err := runtimeConfig.Reload(); err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+	if config.ConfigFile != "" {
+		go runtimeConfig.Watch(config.ConfigWatchInterval)
+		log.Printf("Watching %s for config changes", config.ConfigFile)
+	}
+	if shared.Exports != nil {
+		log.Printf("Scheduled export enabled to %s", shared.Exports.Location())
+	}
+
+	if config.MarketContextEnabled {
+		if _, err := scheduler.AddFunc(config.MarketContextSchedule, marketContexts.RecordScheduled); err != nil {
+			log.Fatal("Failed to schedule market context:", err)
+		}
+		go marketContexts.RecordScheduled()
+		log.Printf("Recording market context at %q", config.MarketContextSchedule)
+	}
+
+	scheduler.Start()
+
+	// Probe the Hyperliquid API so its status is current between fetches. A
+	// replayed tape only answers the requests it recorded.
+	if config.TapeMode != services.TapeReplay {
+		go services.RunUpstreamProbe(config.UpstreamProbeInterval)
+	}
+
+	build := services.BuildInfo()
+	log.Printf("Build %s (built %s), calculator version %s", build.Commit, build.BuildTime, build.CalculatorVersion)
 
 	// Initialize API handler
-	handler := api.NewHandler(reconService)
+	handler := api.NewHandler(tenants, runtimeConfig)
 
 	// Setup router
 	router := mux.NewRouter()
@@ -30,8 +379,9 @@ func main() {
 	router.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+			w.Header().Set("Access-Control-Expose-Headers", "Age, X-Data-Stale, API-Version, Deprecation, Link")
 
 			if r.Method == "OPTIONS" {
 				w.WriteHeader(http.StatusOK)
@@ -41,11 +391,105 @@ func main() {
 			next.ServeHTTP(w, r)
 		})
 	})
+	router.Use(api.Trace)
+	router.Use(handler.Authenticate)
+	router.Use(handler.ApplyView)
 
-	// API routes
+	// API routes, also served under /api/v1 (see api.Versioned)
 	router.HandleFunc("/api/health", handler.HealthCheck).Methods("GET")
+	router.HandleFunc("/api/status", handler.GetStatus).Methods("GET")
+	router.HandleFunc("/api/versions", handler.GetVersions).Methods("GET")
+	router.HandleFunc("/api/version", handler.GetVersion).Methods("GET")
 	router.HandleFunc("/api/pnl", handler.GetPnLSummary).Methods("GET")
 	router.HandleFunc("/api/refresh", handler.TriggerRefresh).Methods("POST")
+	router.HandleFunc("/api/refresh/batch", handler.TriggerBatchRefresh).Methods("POST")
+	router.HandleFunc("/api/refresh/batch/{id}", handler.GetBatch).Methods("GET")
+	router.HandleFunc("/api/jobs/{id}", handler.GetJob).Methods("GET")
+	router.HandleFunc("/api/webhooks/deliveries", handler.GetWebhookDeliveries).Methods("GET")
+	router.HandleFunc("/api/retention", handler.GetRetention).Methods("GET")
+	router.HandleFunc("/api/retention/prune", handler.PruneRetention).Methods("POST")
+	router.HandleFunc("/api/export/sheets", handler.ExportToSheets).Methods("POST")
+	router.HandleFunc("/api/search", handler.SearchTrades).Methods("GET")
+	router.HandleFunc("/api/export/trades", handler.ExportTrades).Methods("GET")
+	router.HandleFunc("/api/export/pnl", handler.ExportDailyPnL).Methods("GET")
+	router.HandleFunc("/api/export/statement", handler.GetStatement).Methods("GET")
+	router.HandleFunc("/api/export/verify", handler.VerifyExport).Methods("POST")
+	router.HandleFunc("/api/export/templates", handler.GetExportTemplates).Methods("GET")
+	router.HandleFunc("/api/export/templates/{name}", handler.GetExportTemplate).Methods("GET")
+	router.HandleFunc("/api/export/templates/{name}", handler.SaveExportTemplate).Methods("PUT")
+	router.HandleFunc("/api/export/templates/{name}", handler.DeleteExportTemplate).Methods("DELETE")
+	router.HandleFunc("/api/downloads/{token}", handler.GetDownload).Methods("GET")
+	router.HandleFunc("/api/views", handler.GetViews).Methods("GET")
+	router.HandleFunc("/api/views/{name}", handler.GetView).Methods("GET")
+	router.HandleFunc("/api/views/{name}", handler.SaveView).Methods("PUT")
+	router.HandleFunc("/api/views/{name}", handler.DeleteView).Methods("DELETE")
+	router.HandleFunc("/api/import", handler.ImportTrades).Methods("POST")
+	router.HandleFunc("/api/cache/{address}", handler.InvalidateCacheRange).Methods("DELETE")
+	router.HandleFunc("/api/addresses", handler.GetAddresses).Methods("GET")
+	router.HandleFunc("/api/addresses", handler.SaveAddress).Methods("POST")
+	router.HandleFunc("/api/addresses/{address}", handler.DeleteAddress).Methods("DELETE")
+	router.HandleFunc("/api/addresses/{address}/capital", handler.SetAddressCapital).Methods("PUT")
+	router.HandleFunc("/api/addresses/{address}/timezone", handler.SetAddressTimezone).Methods("PUT")
+	router.HandleFunc("/api/consolidated", handler.GetConsolidatedReport).Methods("GET")
+	router.HandleFunc("/api/leaderboard", handler.GetLeaderboard).Methods("GET")
+	router.HandleFunc("/api/reports/daily", handler.GetDailyReport).Methods("GET")
+	router.HandleFunc("/api/calendar.ics", handler.GetCalendar).Methods("GET")
+	router.HandleFunc("/api/positions/history", handler.GetPositionHistory).Methods("GET")
+	router.HandleFunc("/api/checks/positions", handler.GetPositionChecks).Methods("GET")
+	router.HandleFunc("/api/breaks", handler.GetBreaks).Methods("GET")
+	router.HandleFunc("/api/breaks", handler.CreateBreak).Methods("POST")
+	router.HandleFunc("/api/breaks/{id}", handler.GetBreak).Methods("GET")
+	router.HandleFunc("/api/breaks/{id}", handler.UpdateBreak).Methods("PATCH")
+	router.HandleFunc("/api/breaks/{id}/notes", handler.AddBreakNote).Methods("POST")
+	router.HandleFunc("/api/closes", handler.GetCloses).Methods("GET")
+	router.HandleFunc("/api/closes", handler.RunClose).Methods("POST")
+	router.HandleFunc("/api/dataquality", handler.GetDataQualityAlerts).Methods("GET")
+	router.HandleFunc("/api/ws", handler.StreamEvents).Methods("GET")
+	router.HandleFunc("/api/quarantine", handler.GetQuarantine).Methods("GET")
+	router.HandleFunc("/api/quarantine/reprocess", handler.ReprocessQuarantine).Methods("POST")
+	router.HandleFunc("/api/quarantine/{id}", handler.FixQuarantinedFill).Methods("PUT")
+	router.HandleFunc("/api/quarantine/{id}", handler.DiscardQuarantinedFill).Methods("DELETE")
+	router.HandleFunc("/api/archive", handler.GetArchivedResponses).Methods("GET")
+	router.HandleFunc("/api/archive/audit", handler.AuditArchive).Methods("GET")
+	router.HandleFunc("/api/archive/{name:[0-9-]+}", handler.GetArchivedResponse).Methods("GET")
+	router.HandleFunc("/api/periods", handler.GetPeriods).Methods("GET")
+	router.HandleFunc("/api/periods", handler.LockPeriod).Methods("POST")
+	router.HandleFunc("/api/periods/restatements", handler.GetRestatements).Methods("GET")
+	router.HandleFunc("/api/ledger/trial-balance", handler.GetTrialBalance).Methods("GET")
+	router.HandleFunc("/api/ledger/entries", handler.GetLedgerEntries).Methods("GET")
+	router.HandleFunc("/api/ledger/income", handler.GetIncome).Methods("GET")
+	router.HandleFunc("/api/ledger/vaults", handler.GetVaults).Methods("GET")
+	router.HandleFunc("/api/runs", handler.GetRuns).Methods("GET")
+	router.HandleFunc("/api/runs/compare", handler.CompareRuns).Methods("GET")
+	router.HandleFunc("/api/runs/{id}", handler.GetRun).Methods("GET")
+	router.HandleFunc("/api/diff", handler.GetTradeDiff).Methods("GET")
+	router.HandleFunc("/api/reconcile", handler.Reconcile).Methods("POST")
+	router.HandleFunc("/api/reconcile/{id}", handler.GetReconciliation).Methods("GET")
+	router.HandleFunc("/api/reconcile/{id}/match", handler.ResolveMatch).Methods("POST")
+	router.HandleFunc("/api/reports/send", handler.SendDailyReport).Methods("POST")
+	router.HandleFunc("/api/analytics/fees", handler.GetFeeSimulation).Methods("GET")
+	router.HandleFunc("/api/analytics/series", handler.GetPnLSeries).Methods("GET")
+	router.HandleFunc("/api/analytics/calendar", handler.GetPnLHeatmap).Methods("GET")
+	router.HandleFunc("/api/analytics/profile", handler.GetPerformanceProfile).Methods("GET")
+	router.HandleFunc("/api/analytics/distribution", handler.GetTradeDistribution).Methods("GET")
+	router.HandleFunc("/api/analytics/direction", handler.GetDirection).Methods("GET")
+	router.HandleFunc("/api/roundtrips", handler.GetRoundTrips).Methods("GET")
+	router.HandleFunc("/api/analytics/holdtime", handler.GetHoldTimes).Methods("GET")
+	router.HandleFunc("/api/analytics/funding", handler.GetFundingContext).Methods("GET")
+	router.HandleFunc("/api/chart", handler.GetChart).Methods("GET")
+	router.HandleFunc("/api/admin/config/reload", handler.ReloadConfig).Methods("POST")
+	router.HandleFunc("/api/admin/stats", handler.GetAdminStats).Methods("GET")
+	router.HandleFunc("/api/admin/budget", handler.GetAPIBudget).Methods("GET")
+	router.HandleFunc("/api/admin/symbols/history", handler.GetSymbolHistory).Methods("GET")
+	router.HandleFunc("/api/admin/symbols/history", handler.SaveSymbolChange).Methods("POST")
+	router.HandleFunc("/api/admin/symbols/history/{id}", handler.SaveSymbolChange).Methods("PUT")
+	router.HandleFunc("/api/admin/symbols/history/{id}", handler.DeleteSymbolChange).Methods("DELETE")
+	router.HandleFunc("/api/admin/backup", handler.Backup).Methods("GET")
+	router.HandleFunc("/api/admin/restore", handler.Restore).Methods("POST")
+	router.HandleFunc("/api/admin/demo", handler.DemoDataset).Methods("GET")
+	router.HandleFunc("/api/admin/cache/rebuild", handler.RebuildCache).Methods("POST")
+	router.HandleFunc("/api/admin/jobs/{id}", handler.GetAdminJob).Methods("GET")
+	router.PathPrefix("/api/admin/debug/pprof/").Handler(http.StripPrefix("/api/admin", pprofHandler()))
 
 	// Serve embedded frontend (production) or allow CORS for development
 	if _, err := fs.Stat(frontendFS, "frontend/build/index.html"); err == nil {
@@ -89,5 +533,246 @@ func main() {
 	if _, err := fs.Stat(frontendFS, "frontend/build/index.html"); err == nil {
 		fmt.Printf("Access the application at: http://localhost%s\n", addr)
 	}
-	log.Fatal(http.ListenAndServe(addr, router))
+	server := &http.Server{Addr: addr, Handler: api.Versioned(router)}
+
+	// "run" serves as an installed service, shutting down gracefully when the
+	// service manager stops it
+	if flag.Arg(0) == "run" {
+		err := services.RunService(server.ListenAndServe, func() {
+			scheduler.Stop()
+			ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+			defer cancel()
+			server.Shutdown(ctx)
+		})
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+		log.Println("Service stopped")
+		return
+	}
+	log.Fatal(server.ListenAndServe())
+}
+
+// serviceArgs returns the flags the installed service runs with: those given
+// to install, with an absolute data directory that defaults to the system's
+func serviceArgs() []string {
+	dataDir := config.DataDir
+	if dataDir == "" {
+		dataDir = services.DefaultServiceDataDir()
+	}
+
+	var args []string
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "data-dir" {
+			dataDir = f.Value.String()
+			return
+		}
+		args = append(args, "-"+f.Name+"="+f.Value.String())
+	})
+	if dataDir != "" {
+		if abs, err := filepath.Abs(dataDir); err == nil {
+			dataDir = abs
+		}
+		args = append(args, "-data-dir="+dataDir)
+	}
+	return args
+}
+
+// printRebuild prints the trades, days and P&L of every address rebuilt from a
+// tenant's event log. The caches were already rebuilt when the tenant was built.
+func printRebuild(tenant *services.Tenant) error {
+	if tenant.Events == nil {
+		return fmt.Errorf("tenant %s has no event log (set RECON_EVENTS_FILE or the tenant's dataDir)", tenant.ID)
+	}
+
+	fmt.Printf("Replayed %d events for tenant %s\n", tenant.Events.Len(), tenant.ID)
+	for _, address := range tenant.ReconService.CachedAddresses() {
+		trades, _ := tenant.ReconService.CachedTrades(address)
+		records, _ := tenant.ReconService.CachedDailyRecords(address)
+		var total models.Money
+		for _, record := range records {
+			total += record.DailyPnL
+		}
+		fmt.Printf("%s: %d trades, %d days, P&L %.2f\n", address, len(trades), len(records), total)
+	}
+	return nil
+}
+
+// runDemo writes an anonymized copy of a tenant's cached trades to the
+// directory named by --out, one file per address, for -import to load
+func runDemo(args []string, tenant *services.Tenant) error {
+	flags := flag.NewFlagSet("demo", flag.ExitOnError)
+	out := flags.String("out", "demo", "directory to write the trade files to")
+	scale := flags.Float64("scale", services.DefaultDemoOptions.Scale, "factor every size is multiplied by")
+	jitter := flags.Float64("jitter", services.DefaultDemoOptions.Jitter, "fraction each size is randomly moved by, up to 0.5")
+	priceJitter := flags.Float64("price-jitter", services.DefaultDemoOptions.PriceJitter, "fraction each price is randomly moved by, at least half of it and up to 0.1")
+	timeJitter := flags.Duration("time-jitter", services.DefaultDemoOptions.TimeJitter, "how far each time is randomly moved back, up to 24h")
+	shiftDays := flags.Int("shift-days", 0, "days to move every trade by; 0 moves the newest into the last day")
+	seed := flags.Uint64("seed", 0, "seed that makes the copy reproducible; random if 0")
+	flags.Parse(args)
+
+	opts := services.DemoOptions{Scale: *scale, Jitter: *jitter, PriceJitter: *priceJitter, TimeJitter: *timeJitter, Shift: time.Duration(*shiftDays) * 24 * time.Hour, Seed: *seed}
+	trades, err := tenant.ReconService.DemoTrades(opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+	for address, addressTrades := range trades {
+		data, err := services.EncodeTrades(services.FormatCSV, addressTrades)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(*out, services.DemoFileName(address)), data, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d trades\n", address, len(addressTrades))
+	}
+	fmt.Printf("Wrote %d anonymized addresses to %s\n", len(trades), *out)
+	return nil
+}
+
+// tenantConfigs returns the settings of every tenant: those in the tenants
+// file, or the single tenant configured from the environment
+func tenantConfigs(secrets services.Secrets) ([]models.TenantConfig, error) {
+	if config.TenantsFile == "" {
+		return []models.TenantConfig{services.DefaultTenantConfig()}, nil
+	}
+	return services.LoadTenantConfigs(config.TenantsFile, secrets)
+}
+
+// runBackup writes a snapshot of the persistent store to the file named by
+// --out. It can run while the service is serving from the same files.
+func runBackup(args []string, secrets services.Secrets) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := flags.String("out", "snapshot.tar.zst", "file to write the snapshot to")
+	flags.Parse(args)
+
+	configs, err := tenantConfigs(secrets)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(*out + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	manifest, err := services.WriteBackup(file, services.BackupPaths(configs), nil)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), *out); err != nil {
+		return err
+	}
+
+	for _, f := range manifest.Files {
+		fmt.Printf("%s (%d bytes)\n", f.Name, f.Size)
+	}
+	fmt.Printf("Wrote %d files to %s\n", len(manifest.Files), *out)
+	return nil
+}
+
+// runRestore restores the snapshot named by --in. The service must be stopped.
+func runRestore(args []string, secrets services.Secrets) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	in := flags.String("in", "snapshot.tar.zst", "snapshot to restore")
+	flags.Parse(args)
+
+	file, err := os.Open(*in)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	backup, err := services.ReadBackup(file)
+	if err != nil {
+		return err
+	}
+
+	result, err := restoreBackup(backup, secrets)
+	if err != nil {
+		return err
+	}
+	for _, path := range result.Restored {
+		fmt.Printf("Restored %s\n", path)
+	}
+	for _, name := range result.Skipped {
+		fmt.Printf("Skipped %s: not configured on this host\n", name)
+	}
+	fmt.Printf("Restored snapshot of %s\n", backup.Manifest.CreatedAt.Format(time.RFC3339))
+	return nil
+}
+
+// restorePending restores a snapshot staged through /api/admin/restore, if
+// there is one, and removes it
+func restorePending(dataDir services.DataDir, secrets services.Secrets) error {
+	backup, err := services.ReadStagedRestore(dataDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	result, err := restoreBackup(backup, secrets)
+	if err != nil {
+		return err
+	}
+	log.Printf("Restored snapshot of %s: %d files, %d skipped", backup.Manifest.CreatedAt.Format(time.RFC3339), len(result.Restored), len(result.Skipped))
+	return os.Remove(dataDir.PendingRestore())
+}
+
+// restoreBackup puts a snapshot's files in place. The settings files go
+// first, so the tenants file being restored decides where tenants' stores go.
+func restoreBackup(backup *services.Backup, secrets services.Secrets) (models.RestoreResult, error) {
+	if _, _, err := backup.Restore(services.BackupPaths(nil)); err != nil {
+		return models.RestoreResult{}, err
+	}
+	configs, err := tenantConfigs(secrets)
+	if err != nil {
+		return models.RestoreResult{}, fmt.Errorf("failed to load restored tenants: %w", err)
+	}
+	restored, skipped, err := backup.Restore(services.BackupPaths(configs))
+	return models.RestoreResult{Manifest: backup.Manifest, Restored: restored, Skipped: skipped}, err
+}
+
+// pprofHandler serves the net/http/pprof profiles under /debug/pprof/. They are
+// mounted below /api/admin so only admin keys can reach them.
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// loadSecrets replaces each sensitive setting with its value from secrets,
+// keeping the value from the environment if the provider doesn't have it
+func loadSecrets(secrets services.Secrets) error {
+	sensitive := map[string]*string{
+		"RECON_WEBHOOK_SECRET":     &config.WebhookSigningSecret,
+		"RECON_EXPORT_SIGNING_KEY": &config.ExportSigningKey,
+		"RECON_STORAGE_KEY":        &config.StorageKey,
+		"RECON_ADMIN_API_KEY":      &config.AdminAPIKey,
+		"RECON_SMTP_USERNAME":      &config.SMTPUsername,
+		"RECON_SMTP_PASSWORD":      &config.SMTPPassword,
+		"AWS_ACCESS_KEY_ID":        &config.S3AccessKey,
+		"AWS_SECRET_ACCESS_KEY":    &config.S3SecretKey,
+		// Proxy credentials and header tokens
+		"RECON_HYPERLIQUID_PROXY":   &config.HyperliquidProxyURL,
+		"RECON_HYPERLIQUID_HEADERS": &config.HyperliquidHeaders,
+	}
+	for name, setting := range sensitive {
+		value, err := services.LookupSecret(secrets, name, *setting)
+		if err != nil {
+			return err
+		}
+		*setting = value
+	}
+	return nil
 }
\ No newline at end of file
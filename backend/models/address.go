@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// AddressEntry is a saved address with a friendly label
+type AddressEntry struct {
+	Address   string    `json:"address"`
+	Label     string    `json:"label"`
+	ENSName   string    `json:"ensName,omitempty"` // Name the address was resolved from, if any
+	UpdatedAt time.Time `json:"updatedAt"`
+	// BaseCapital is the starting capital, in USDC, returns are measured against
+	BaseCapital *Money `json:"baseCapital,omitempty"`
+	// DeriveCapital measures returns against the account value history instead of BaseCapital
+	DeriveCapital bool `json:"deriveCapital,omitempty"`
+	// Timezone is the IANA time zone the address's days are bucketed in by
+	// consolidated reports; the day basis applies if empty
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// AccountValue is an account's value at a point in time, as reported by the exchange
+type AccountValue struct {
+	Time  time.Time `json:"time"`
+	Value Money     `json:"value"`
+}
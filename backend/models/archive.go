@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// ArchivedResponse is one fills response kept exactly as the API sent it
+type ArchivedResponse struct {
+	Name       string    `json:"name"` // Identifies the response within its address's archive
+	Address    string    `json:"address"`
+	Start      time.Time `json:"start"` // The requested window, inclusive
+	End        time.Time `json:"end"`
+	ReceivedAt time.Time `json:"receivedAt"`
+	Bytes      int64     `json:"bytes"` // Compressed size
+}
+
+// ArchiveAudit compares an address's cache with the archived responses it
+// was built from. Only cached trades inside an archived window are compared,
+// since the archive may have been turned on after part of the history was fetched.
+type ArchiveAudit struct {
+	Address          string            `json:"address"`
+	Responses        int               `json:"responses"`
+	ArchivedFills    int               `json:"archivedFills"` // Distinct fills across the responses
+	Matched          int               `json:"matched"`
+	MissingFromCache []Trade           `json:"missingFromCache"` // Archived fills the cache doesn't have
+	NotArchived      []Trade           `json:"notArchived"`      // Cached trades in an archived window that no response contained
+	Differing        []ArchiveMismatch `json:"differing"`
+	Unparseable      int               `json:"unparseable"` // Archived fills that don't parse, e.g. those quarantined
+}
+
+// ArchiveMismatch is a cached trade that differs from the archived fill at the same millisecond, coin and side
+type ArchiveMismatch struct {
+	Archived Trade `json:"archived"`
+	Cached   Trade `json:"cached"`
+}
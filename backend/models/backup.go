@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// BackupManifest describes a snapshot of the persistent store
+type BackupManifest struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"createdAt"`
+	GitCommit string       `json:"gitCommit,omitempty"` // Build that wrote the snapshot
+	Files     []BackupFile `json:"files"`
+}
+
+// BackupFile is one file in a snapshot
+type BackupFile struct {
+	Name   string `json:"name"` // Path in the archive, e.g. tenants/default/events.jsonl
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// RestoreResult is what restoring a snapshot wrote
+type RestoreResult struct {
+	Manifest BackupManifest `json:"manifest"`
+	Restored []string       `json:"restored"`          // Paths written
+	Skipped  []string       `json:"skipped,omitempty"` // Files in the snapshot this configuration has no place for
+	Staged   bool           `json:"staged,omitempty"`  // Restored on the next startup rather than now
+}
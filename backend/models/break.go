@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// BreakStatus is the workflow state of a reconciliation break
+type BreakStatus string
+
+const (
+	BreakOpen         BreakStatus = "open"
+	BreakAcknowledged BreakStatus = "acknowledged"
+	BreakResolved     BreakStatus = "resolved"
+)
+
+// BreakType identifies the check that raised a break
+type BreakType string
+
+const (
+	BreakPositionGap  BreakType = "position_gap"  // Start positions show missing or duplicated fills
+	BreakMissingRange BreakType = "missing_range" // A time range could not be fetched
+	BreakManual       BreakType = "manual"        // Raised by a user, e.g. an external file mismatch
+	BreakExternal     BreakType = "external"      // Fills and rows a connector's file left unmatched
+)
+
+// BreakNote is a comment on a break
+type BreakNote struct {
+	Author    string    `json:"author,omitempty"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Break is a reconciliation discrepancy tracked until someone resolves it
+type Break struct {
+	ID          string      `json:"id"`
+	Type        BreakType   `json:"type"`
+	Address     string      `json:"address"`
+	Label       string      `json:"label,omitempty"`
+	Coin        string      `json:"coin,omitempty"`
+	Date        string      `json:"date,omitempty"`
+	Description string      `json:"description"`
+	Status      BreakStatus `json:"status"`
+	Assignee    string      `json:"assignee,omitempty"`
+	Notes       []BreakNote `json:"notes"`
+	DetectedAt  time.Time   `json:"detectedAt"`
+	LastSeenAt  time.Time   `json:"lastSeenAt"` // Last time a check found the break
+	ResolvedAt  *time.Time  `json:"resolvedAt,omitempty"`
+	Key         string      `json:"key"` // Identifies the same discrepancy across checks
+}
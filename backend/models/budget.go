@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// WeightUsage is the Hyperliquid rate limit weight used in one hour or day
+type WeightUsage struct {
+	Start           time.Time `json:"start"`
+	Requests        int       `json:"requests"`
+	Weight          int       `json:"weight"`          // Measured from the requests made and fills returned
+	EstimatedWeight int       `json:"estimatedWeight"` // Estimated by the refreshes admitted before they ran
+	Refreshes       int       `json:"refreshes"`
+	Refused         int       `json:"refused"` // Refreshes refused for exceeding the budget
+}
+
+// APIBudget is the Hyperliquid rate limit weight used against the configured
+// budget, returned by /api/admin/budget. Budgets of 0 are unlimited.
+type APIBudget struct {
+	PerHour     int           `json:"perHour"`
+	PerDay      int           `json:"perDay"`
+	CurrentHour WeightUsage   `json:"currentHour"`
+	Today       WeightUsage   `json:"today"` // UTC day
+	Hours       []WeightUsage `json:"hours"` // Last 24 hours with any usage, oldest first
+	Days        []WeightUsage `json:"days"`  // Last 7 UTC days with any usage, oldest first
+}
@@ -0,0 +1,10 @@
+package models
+
+// BuildInfo identifies the code a deployment runs, so figures that differ
+// between deployments can be traced to the build that produced them
+type BuildInfo struct {
+	Commit            string `json:"commit"`
+	BuildTime         string `json:"buildTime"`
+	CalculatorVersion string `json:"calculatorVersion"`
+	GoVersion         string `json:"goVersion"`
+}
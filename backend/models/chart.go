@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Candle is the price action of a coin over one interval
+type Candle struct {
+	Time   time.Time `json:"time"` // Open time
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"` // In units of the coin
+	Trades int       `json:"trades"`
+}
+
+// ChartFill is a fill marked on a price chart
+type ChartFill struct {
+	Time  time.Time `json:"time"`
+	Side  string    `json:"side"` // "B" for buy, "A" for sell
+	Price Money     `json:"px"`
+	Size  Quantity  `json:"sz"`
+	Fee   Money     `json:"fee"`
+}
+
+// Chart is the candles of a coin with the fills of an address in the same
+// window, so entries and exits can be plotted on the price
+type Chart struct {
+	Address  string      `json:"address"`
+	Label    string      `json:"label,omitempty"`
+	Coin     string      `json:"coin"`
+	Interval string      `json:"interval"`
+	From     string      `json:"from"` // YYYY-MM-DD
+	To       string      `json:"to"`   // YYYY-MM-DD, inclusive
+	Candles  []Candle    `json:"candles"`
+	Fills    []ChartFill `json:"fills"` // Oldest first
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// PositionDiscrepancy is a fill whose exchange-reported startPosition does not
+// match the position implied by the previous fill in the same coin, meaning
+// fills between the two are missing or duplicated
+type PositionDiscrepancy struct {
+	Coin         string    `json:"coin"`
+	Date         string    `json:"date"`
+	Time         time.Time `json:"time"`         // Fill whose startPosition didn't match
+	PreviousTime time.Time `json:"previousTime"` // Previous fill in the same coin; the gap lies between the two
+	Expected     Position  `json:"expected"`     // Previous startPosition plus the previous fill
+	Reported     Position  `json:"reported"`
+	Difference   Position  `json:"difference"` // Reported minus expected: the net size of the unexplained fills
+}
+
+// CoinDayGaps counts position discrepancies for one coin on one day
+type CoinDayGaps struct {
+	Coin          string   `json:"coin"`
+	Date          string   `json:"date"`
+	Count         int      `json:"count"`
+	NetDifference Position `json:"netDifference"`
+}
+
+// ConsistencyReport is the result of checking an address's fills for gaps
+type ConsistencyReport struct {
+	Address       string                `json:"address"`
+	Label         string                `json:"label,omitempty"`
+	CheckedFills  int                   `json:"checkedFills"`
+	Unchecked     int                   `json:"unchecked"` // Fills without a reported startPosition
+	Consistent    bool                  `json:"consistent"`
+	Gaps          []CoinDayGaps         `json:"gaps"`
+	Discrepancies []PositionDiscrepancy `json:"discrepancies"`
+	CheckedAt     time.Time             `json:"checkedAt"`
+}
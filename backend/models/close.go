@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// CloseStatus is the outcome of an end-of-day close for one address
+type CloseStatus string
+
+const (
+	CloseClean  CloseStatus = "clean"  // All checks passed
+	CloseBreaks CloseStatus = "breaks" // Closed, but checks raised breaks
+	CloseFailed CloseStatus = "failed" // The day's fills could not be fetched
+)
+
+// DayClose is the frozen end-of-day snapshot of one address's trading day.
+// Once stored it is only replaced by an explicitly forced re-close.
+type DayClose struct {
+	Address      string        `json:"address"`
+	Label        string        `json:"label,omitempty"`
+	Date         string        `json:"date"`
+	Status       CloseStatus   `json:"status"`
+	Report       AccountReport `json:"report"`
+	Trades       []Trade       `json:"trades"`
+	TradesDigest string        `json:"tradesDigest"` // SHA-256 of the day's trades in CSV export format
+	ClosedAt     time.Time     `json:"closedAt"`
+}
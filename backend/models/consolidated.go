@@ -0,0 +1,28 @@
+package models
+
+// ConsolidatedReport shows the daily P&L of several addresses over the same
+// dates, each bucketed in its own time zone, along with a roll-up of all of
+// them bucketed by UTC day
+type ConsolidatedReport struct {
+	From      string                `json:"from"` // YYYY-MM-DD, inclusive
+	To        string                `json:"to"`
+	Accounts  []ConsolidatedAccount `json:"accounts"`
+	RollUp    ConsolidatedTotals    `json:"rollUp"`              // Always by UTC day
+	NotCached []string              `json:"notCached,omitempty"` // Addresses asked for that have no cached trades
+}
+
+// ConsolidatedAccount is one address's days of a consolidated report
+type ConsolidatedAccount struct {
+	Address  string `json:"address"`
+	Label    string `json:"label,omitempty"`
+	Timezone string `json:"timezone"` // Zone the address's days are bucketed in
+	ConsolidatedTotals
+}
+
+// ConsolidatedTotals are daily records and their totals over a report's dates
+type ConsolidatedTotals struct {
+	Days       []DailyPnL `json:"days"` // Newest first
+	TradeCount int        `json:"tradeCount"`
+	Volume     Money      `json:"volume"`
+	TotalPnL   Money      `json:"totalPnL"`
+}
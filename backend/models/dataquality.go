@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// AnomalyKind is a kind of data-quality anomaly found while ingesting trades
+type AnomalyKind string
+
+const (
+	AnomalyNoFills       AnomalyKind = "no_fills"       // An address that usually trades had no fills for a day
+	AnomalyParseFailures AnomalyKind = "parse_failures" // Too many fetched fills couldn't be parsed
+	AnomalyKeyCollisions AnomalyKind = "key_collisions" // Different fills shared a millisecond, coin and side, so merging kept one
+	AnomalyCoverageGap   AnomalyKind = "coverage_gap"   // A window couldn't be fetched, or start positions don't chain
+)
+
+// DataQualityAlert is an anomaly raised while ingesting the trades of an address
+type DataQualityAlert struct {
+	Kind     AnomalyKind `json:"kind"`
+	Address  string      `json:"address"`
+	Label    string      `json:"label,omitempty"`
+	Message  string      `json:"message"`
+	RaisedAt time.Time   `json:"raisedAt"`
+}
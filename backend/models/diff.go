@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// TradeDiff lists the fills of an address that were added, removed or changed
+// between its previous trade snapshot and its cached trades
+type TradeDiff struct {
+	Address      string       `json:"address"`
+	Label        string       `json:"label,omitempty"`
+	From         string       `json:"from,omitempty"` // Window compared, YYYY-MM-DD inclusive; the whole history if unset
+	To           string       `json:"to,omitempty"`
+	SnapshotAt   time.Time    `json:"snapshotAt"`   // When the refresh that replaced the snapshot ran
+	TradesBefore int          `json:"tradesBefore"` // Trades in the window in the snapshot
+	TradesAfter  int          `json:"tradesAfter"`  // Trades in the window now
+	Added        []Trade      `json:"added"`
+	Removed      []Trade      `json:"removed"`
+	Changed      []FillChange `json:"changed"`
+}
+
+// FillChange is a fill whose price, size, value, fee or start position changed
+type FillChange struct {
+	Before Trade `json:"before"`
+	After  Trade `json:"after"`
+}
@@ -0,0 +1,49 @@
+package models
+
+// Classes of trading day by the direction of their position changes
+const (
+	DayOpening = "opening" // Mostly built positions
+	DayClosing = "closing" // Mostly reduced positions
+	DayChurn   = "churn"   // Opened and closed about as much
+)
+
+// DirectionAnalysis classes each trading day as opening, closing or churn by
+// how much notional it added to and took off positions, and totals the P&L
+// of each class
+type DirectionAnalysis struct {
+	Address    string           `json:"address"`
+	Label      string           `json:"label,omitempty"`
+	DayBasis   DayBasis         `json:"dayBasis"`
+	ChurnShare float64          `json:"churnShare"` // Largest net share of a churn day
+	Classes    []DirectionClass `json:"classes"`    // Opening, closing, churn
+	Days       []DirectionDay   `json:"days"`       // Oldest first
+}
+
+// DirectionDay is one trading day and its class
+type DirectionDay struct {
+	Date           string  `json:"date"`
+	Class          string  `json:"class"`
+	Fills          int     `json:"fills"`
+	OpenedNotional float64 `json:"openedNotional"` // Notional that increased positions
+	ClosedNotional float64 `json:"closedNotional"` // Notional that reduced positions
+	// NetShare is (opened - closed) / (opened + closed): 1 for a day that only
+	// opened, -1 for one that only closed
+	NetShare    float64 `json:"netShare"`
+	RealizedPnL float64 `json:"realizedPnl"`
+	Fees        float64 `json:"fees"`
+	NetPnL      float64 `json:"netPnl"` // Realized P&L less fees
+}
+
+// DirectionClass totals the days of one class
+type DirectionClass struct {
+	Class          string  `json:"class"`
+	Days           int     `json:"days"`
+	WinningDays    int     `json:"winningDays"` // Days with positive net P&L
+	Fills          int     `json:"fills"`
+	OpenedNotional float64 `json:"openedNotional"`
+	ClosedNotional float64 `json:"closedNotional"`
+	RealizedPnL    float64 `json:"realizedPnl"`
+	Fees           float64 `json:"fees"`
+	NetPnL         float64 `json:"netPnl"`
+	AvgNetPnL      float64 `json:"avgNetPnl"` // Per day
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// TradeDistribution describes how fill sizes, P&L and volume are spread, to
+// show whether a few trades or one coin carry the results
+type TradeDistribution struct {
+	Address   string               `json:"address"`
+	Label     string               `json:"label,omitempty"`
+	Period    string               `json:"period"`    // "month" or "week"
+	Histogram []NotionalBucket     `json:"histogram"` // Fill notional, smallest first
+	Periods   []DistributionPeriod `json:"periods"`   // Oldest first
+}
+
+// NotionalBucket counts fills whose notional is at least Min and below Max
+type NotionalBucket struct {
+	Min    float64  `json:"min"`
+	Max    *float64 `json:"max,omitempty"` // Omitted for the open-ended top bucket
+	Fills  int      `json:"fills"`
+	Volume float64  `json:"volume"`
+}
+
+// DistributionPeriod is the concentration of one month or week
+type DistributionPeriod struct {
+	Period      string  `json:"period"` // e.g. "2025-06" or "2025-W23"
+	Fills       int     `json:"fills"`
+	Volume      float64 `json:"volume"`
+	RealizedPnL float64 `json:"realizedPnl"`
+	// TopTrades are the fills that realized the most profit, best first
+	TopTrades    []TopTrade `json:"topTrades"`
+	TopTradesPnL float64    `json:"topTradesPnl"`
+	// TopTradesShare is TopTradesPnL as a share of RealizedPnL; omitted unless the period made a profit
+	TopTradesShare *float64 `json:"topTradesShare,omitempty"`
+	// CoinConcentration is the Herfindahl index of the coins' volume shares:
+	// 1 when one coin has all the volume, 1/n when n coins share it evenly
+	CoinConcentration float64     `json:"coinConcentration"`
+	Coins             []CoinShare `json:"coins"` // Largest volume first
+}
+
+// TopTrade is one fill and the P&L it realized
+type TopTrade struct {
+	Time        time.Time `json:"time"`
+	Coin        string    `json:"coin"`
+	Side        string    `json:"side"`
+	Value       float64   `json:"value"`
+	RealizedPnL float64   `json:"realizedPnl"`
+}
+
+// CoinShare is one coin's share of a period's volume
+type CoinShare struct {
+	Coin        string  `json:"coin"`
+	Volume      float64 `json:"volume"`
+	Share       float64 `json:"share"`
+	RealizedPnL float64 `json:"realizedPnl"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Download is a generated file that can be fetched without an API key
+// through its signed URL until it expires
+type Download struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"` // File name offered to the browser
+	ContentType string    `json:"contentType"`
+	Bytes       int64     `json:"bytes"`
+	Digest      string    `json:"sha256"`              // Hex SHA-256 of the file
+	Signature   string    `json:"signature,omitempty"` // Hex HMAC-SHA256 under the export signing key, if one is configured
+	CreatedAt   time.Time `json:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	URL         string    `json:"url,omitempty"` // Signed path to fetch it from; set when it is handed out
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// TradeEventType is what a trade event did to an address's cached trades
+type TradeEventType string
+
+const (
+	// EventTradesFetched merges trades fetched for Window into the cache,
+	// clearing missing ranges inside Window
+	EventTradesFetched TradeEventType = "trades.fetched"
+	// EventTradesReset replaces the whole cache with a full fetch of Window
+	EventTradesReset TradeEventType = "trades.reset"
+	// EventTradesRefetched replaces the cached trades in Window with a
+	// re-fetch of it, or merges them if the re-fetch was partial
+	EventTradesRefetched TradeEventType = "trades.refetched"
+	// EventTradesImported merges trades read from export files
+	EventTradesImported TradeEventType = "trades.imported"
+	// EventTradesInvalidated drops the cached trades in Window and marks it
+	// missing until it is fetched again. The dropped trades stay in the log.
+	EventTradesInvalidated TradeEventType = "trades.invalidated"
+	// EventTradesPruned drops the cached trades before Window.End, which
+	// have passed the retention period, and moves the start of the cache's
+	// coverage up to it. Compacting the log then removes them from earlier events.
+	EventTradesPruned TradeEventType = "trades.pruned"
+	// EventTradesRecovered merges fills recovered from quarantine into an
+	// existing cache without changing what it covers
+	EventTradesRecovered TradeEventType = "trades.recovered"
+)
+
+// TradeEvent is an immutable record of trades received for an address. The
+// cached trades, and everything computed from them, are a projection of the
+// address's events replayed in order.
+type TradeEvent struct {
+	Seq        int64          `json:"seq"`
+	Type       TradeEventType `json:"type"`
+	Address    string         `json:"address"`
+	Window     *TimeRange     `json:"window,omitempty"`  // Period the trades were fetched for
+	Missing    *TimeRange     `json:"missing,omitempty"` // Part of Window that could not be fetched
+	Days       int            `json:"days,omitempty"`    // Days requested by a reset
+	Trades     []Trade        `json:"trades"`
+	RecordedAt time.Time      `json:"recordedAt"`
+}
+
+// CacheInvalidation describes a window of cached trades that was invalidated
+// and is being fetched again
+type CacheInvalidation struct {
+	Address       string    `json:"address"`
+	Label         string    `json:"label,omitempty"`
+	Window        TimeRange `json:"window"`
+	TradesRemoved int       `json:"tradesRemoved"`
+}
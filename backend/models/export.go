@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// SheetsExportResult describes a completed export of daily P&L to Google Sheets
+type SheetsExportResult struct {
+	SpreadsheetID string    `json:"spreadsheetId"`
+	Range         string    `json:"range"`
+	RowsWritten   int       `json:"rowsWritten"`
+	ExportedAt    time.Time `json:"exportedAt"`
+}
+
+// S3ExportResult describes a completed export of trades and daily P&L to an S3-compatible bucket
+type S3ExportResult struct {
+	Bucket           string    `json:"bucket"`
+	Addresses        int       `json:"addresses"`
+	ObjectsWritten   int       `json:"objectsWritten"`
+	ObjectsUnchanged int       `json:"objectsUnchanged"`
+	ExportedAt       time.Time `json:"exportedAt"`
+}
+
+// ImportResult summarizes trades imported for one address
+type ImportResult struct {
+	Address     string `json:"address"`
+	Label       string `json:"label,omitempty"`
+	Files       int    `json:"files,omitempty"`
+	TradesRead  int    `json:"tradesRead"`
+	TradesAdded int    `json:"tradesAdded"`
+	Duplicates  int    `json:"duplicates"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// ExportVerification is the result of checking an export file against the
+// digest and signature it was downloaded with
+type ExportVerification struct {
+	SHA256         string `json:"sha256"`                   // Digest of the file as received
+	DigestValid    *bool  `json:"digestValid,omitempty"`    // nil if no digest was given
+	SignatureValid *bool  `json:"signatureValid,omitempty"` // nil if no signature was given
+	Valid          bool   `json:"valid"`                    // Every given check passed
+}
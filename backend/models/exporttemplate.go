@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ExportTemplate is a saved layout for CSV exports: which columns appear, in
+// which order and under which headers, and how numbers and dates are written
+type ExportTemplate struct {
+	Name       string            `json:"name"`
+	Kind       string            `json:"kind"`              // trades or pnl
+	Columns    []string          `json:"columns,omitempty"` // In order; all columns if empty
+	Headers    map[string]string `json:"headers,omitempty"` // key: column
+	Decimal    string            `json:"decimal,omitempty"`
+	Thousands  string            `json:"thousands,omitempty"`
+	Delimiter  string            `json:"delimiter,omitempty"`
+	DateFormat string            `json:"dateFormat,omitempty"`
+	UpdatedAt  time.Time         `json:"updatedAt"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// FeeSimulation compares the fees an address paid with what its fills would
+// have cost under each fee tier
+type FeeSimulation struct {
+	Address     string        `json:"address"`
+	Label       string        `json:"label,omitempty"`
+	Start       *time.Time    `json:"start,omitempty"` // Time of the first fill simulated
+	End         *time.Time    `json:"end,omitempty"`   // Time of the last fill simulated
+	Fills       int           `json:"fills"`
+	Volume      Money         `json:"volume"`
+	MakerVolume Money         `json:"makerVolume"` // Volume of fills inferred to have added liquidity
+	TakerVolume Money         `json:"takerVolume"`
+	ActualFees  Money         `json:"actualFees"`
+	Staking     string        `json:"staking"` // Staking discount applied to every scenario
+	Scenarios   []FeeScenario `json:"scenarios"`
+}
+
+// FeeScenario is the fee cost of the simulated fills under one tier. Savings
+// are positive when the scenario costs less than was actually paid.
+type FeeScenario struct {
+	Tier         string  `json:"tier"`
+	MinVolume14d Money   `json:"minVolume14d,omitempty"` // 14-day volume needed for the tier
+	TakerRate    float64 `json:"takerRate,omitempty"`    // Fractions of fill value after the staking discount
+	MakerRate    float64 `json:"makerRate,omitempty"`
+	Fees         Money   `json:"fees"`
+	Savings      Money   `json:"savings"`
+	SavingsPct   float64 `json:"savingsPct"` // Savings as a percentage of the actual fees
+}
+
+// FeeTier is one row of the exchange's volume-based fee schedule
+type FeeTier struct {
+	Name         string  `json:"name"`
+	MinVolume14d Money   `json:"minVolume14d"`
+	TakerRate    float64 `json:"takerRate"`
+	MakerRate    float64 `json:"makerRate"`
+}
+
+// StakingTier is a fee discount for staking HYPE
+type StakingTier struct {
+	Name      string   `json:"name"`
+	MinStaked Quantity `json:"minStaked"`
+	Discount  float64  `json:"discount"` // Fraction taken off every fee
+}
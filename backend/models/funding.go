@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// FundingSample is one hourly funding rate of a coin
+type FundingSample struct {
+	Time time.Time `json:"time"`
+	Rate float64   `json:"rate"`
+}
+
+// FundingRateDay is the market funding of one coin on one UTC day
+type FundingRateDay struct {
+	Coin    string          `json:"coin"`
+	Date    string          `json:"date"`    // YYYY-MM-DD (UTC)
+	AvgRate float64         `json:"avgRate"` // Mean of the hourly rates
+	Samples []FundingSample `json:"samples"` // Oldest first
+}
+
+// FundingDay sets an address's funding of one coin on one UTC day against
+// the market's. HeldRate is the market rate averaged over the hours the
+// address paid or received funding; well above AvgRate, its carry cost came
+// from when it held the position rather than from the market as a whole.
+type FundingDay struct {
+	Date     string   `json:"date"` // YYYY-MM-DD (UTC)
+	Coin     string   `json:"coin"`
+	Funding  float64  `json:"funding"`  // Received; negative if paid
+	Payments int      `json:"payments"` // Hourly funding payments
+	AvgRate  *float64 `json:"avgRate,omitempty"`
+	HeldRate *float64 `json:"heldRate,omitempty"`
+}
+
+// FundingContext is the funding of an address per day and coin traded or
+// held, returned by /api/analytics/funding
+type FundingContext struct {
+	Address string       `json:"address"`
+	Label   string       `json:"label,omitempty"`
+	Days    []FundingDay `json:"days"` // Oldest first, then by coin
+	Funding float64      `json:"funding"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// PnLHeatmap is one year of daily P&L bucketed into intensity levels for a
+// calendar heatmap. Levels run from -MaxLevel (largest losses) to MaxLevel
+// (largest profits); dates without trades are left out.
+type PnLHeatmap struct {
+	Address string                `json:"address"`
+	Label   string                `json:"label,omitempty"`
+	Year    int                   `json:"year"`
+	Days    map[string]HeatmapDay `json:"days"` // key: date (YYYY-MM-DD)
+	// Thresholds are the absolute P&L upper bounds of levels 1 to MaxLevel-1;
+	// anything larger is at the top level
+	Thresholds []float64 `json:"thresholds"`
+	MaxLevel   int       `json:"maxLevel"`
+}
+
+// HeatmapDay is one date of a heatmap
+type HeatmapDay struct {
+	PnL        float64 `json:"pnl"`
+	TradeCount int     `json:"tradeCount"`
+	Level      int     `json:"level"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// HoldTimeAnalysis breaks round-trip performance down by how long positions were held
+type HoldTimeAnalysis struct {
+	Address string          `json:"address"`
+	Label   string          `json:"label,omitempty"`
+	Method  string          `json:"method"` // Round-trip pairing method
+	Overall HoldTimeStats   `json:"overall"`
+	Coins   []HoldTimeStats `json:"coins"` // Sorted by coin
+}
+
+// HoldTimeStats are the holding durations and P&L by hold time of one coin, or of all coins
+type HoldTimeStats struct {
+	Coin         string           `json:"coin,omitempty"`
+	RoundTrips   int              `json:"roundTrips"`
+	MeanHold     string           `json:"meanHold"`
+	MeanHoldMs   int64            `json:"meanHoldMs"`
+	MedianHold   string           `json:"medianHold"`
+	MedianHoldMs int64            `json:"medianHoldMs"`
+	Buckets      []HoldTimeBucket `json:"buckets"` // Shortest holds first
+}
+
+// HoldTimeBucket is the performance of round trips held for a range of time
+type HoldTimeBucket struct {
+	Name       string  `json:"name"` // "<1h", "1-24h" or ">1d"
+	RoundTrips int     `json:"roundTrips"`
+	Wins       int     `json:"wins"`
+	WinRate    float64 `json:"winRate"`
+	NetPnL     float64 `json:"netPnl"`
+	AvgNetPnL  float64 `json:"avgNetPnl"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// IncomeLine is an address's income of one day or month by source. Fees are
+// the fees paid and Rebates the maker rebates received, both positive.
+type IncomeLine struct {
+	Period     string `json:"period"` // YYYY-MM-DD or YYYY-MM; empty for totals
+	TradingPnL Money  `json:"tradingPnl"`
+	Fees       Money  `json:"fees"`
+	Rebates    Money  `json:"rebates"`
+	Funding    Money  `json:"funding"`
+	Referrals  Money  `json:"referrals"`
+	VaultPnL   Money  `json:"vaultPnl"`
+	// OtherIncome is value accrued outside trading, e.g. points and airdrops
+	OtherIncome Money `json:"otherIncome"`
+	// Total is TradingPnL - Fees + Rebates + Funding + Referrals + VaultPnL + OtherIncome
+	Total Money `json:"total"`
+}
+
+// IncomeSummary is an address's income per day or month from its ledger,
+// returned by /api/ledger/income
+type IncomeSummary struct {
+	Address string       `json:"address"`
+	Label   string       `json:"label,omitempty"`
+	Period  string       `json:"period"` // "day" or "month"
+	Lines   []IncomeLine `json:"lines"`  // Periods with any income, oldest first
+	Totals  IncomeLine   `json:"totals"`
+}
+
+// Accrual is value an address accrued outside trading, such as points or an
+// airdrop, recorded by an other-income source. Value is in USD; if it is zero
+// the ledger values Quantity of Asset with its valuation source.
+type Accrual struct {
+	ID       string    `json:"id"`                // Unique within the source
+	Address  string    `json:"address,omitempty"` // Set in accrual files
+	Time     time.Time `json:"time"`
+	Program  string    `json:"program"` // e.g. "points" or "airdrop"
+	Asset    string    `json:"asset,omitempty"`
+	Quantity Quantity  `json:"quantity,omitempty"`
+	Value    Money     `json:"value,omitempty"`
+	Note     string    `json:"note,omitempty"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import "time"
+
+// JobStatus is the lifecycle state of an asynchronous job
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobPartial   JobStatus = "partial" // Finished, but some time ranges could not be fetched
+	JobFailed    JobStatus = "failed"
+)
+
+// JobPriority decides which queued jobs run first and which upstream requests
+// give way to others
+type JobPriority string
+
+const (
+	PriorityHigh JobPriority = "high" // Interactive work, e.g. a refresh started from the UI
+	PriorityLow  JobPriority = "low"  // Background work such as backfills and scheduled refreshes
+)
+
+// JobKind is the work an asynchronous job does
+type JobKind string
+
+const (
+	JobRefresh      JobKind = "refresh"      // Refresh the address's cache and summary
+	JobCacheRebuild JobKind = "cacheRebuild" // Rebuild the address's cache from scratch; see CacheRebuild
+	JobExport       JobKind = "export"       // Write an export of the address to a download; see ExportJob
+)
+
+// RefreshJob tracks an asynchronous refresh, cache rebuild or export of an address
+type RefreshJob struct {
+	ID          string        `json:"id"`
+	Kind        JobKind       `json:"kind"`
+	Address     string        `json:"address"`
+	Label       string        `json:"label,omitempty"`
+	Days        int           `json:"days"`
+	Priority    JobPriority   `json:"priority"`
+	Status      JobStatus     `json:"status"`
+	Error       string        `json:"error,omitempty"`
+	CallbackURL string        `json:"callbackUrl,omitempty"`
+	Rebuild     *CacheRebuild `json:"rebuild,omitempty"` // Set once a cache rebuild job has finished
+	Export      *ExportJob    `json:"export,omitempty"`  // Set for export jobs, with their progress
+	CreatedAt   time.Time     `json:"createdAt"`
+	FinishedAt  *time.Time    `json:"finishedAt,omitempty"`
+}
+
+// CacheRebuild is the outcome of rebuilding an address's cache. The new cache
+// replaces the old one only if the fetch was complete and its fills pass the
+// start position check; otherwise the old cache is kept.
+type CacheRebuild struct {
+	Address        string `json:"address"`
+	TradesFetched  int    `json:"tradesFetched"`
+	Discrepancies  int    `json:"discrepancies"`  // Start position discrepancies in the fetched fills
+	Swapped        bool   `json:"swapped"`        // Whether the rebuilt cache replaced the old one
+	TradesReplaced int    `json:"tradesReplaced"` // Trades in the cache that was replaced
+}
+
+// ExportJob is the progress of an export job. The file is written in chunks
+// of rows, and can be fetched from Download's URL once the job has succeeded.
+type ExportJob struct {
+	Dataset     string    `json:"dataset"` // "trades" or "pnl"
+	Format      string    `json:"format"`
+	Rows        int       `json:"rows"`        // Rows to write
+	RowsWritten int       `json:"rowsWritten"` // Rows written so far
+	Progress    float64   `json:"progress"`    // RowsWritten as a fraction of Rows, from 0 to 1
+	Download    *Download `json:"download,omitempty"`
+}
+
+// RefreshCallback is the body POSTed to a refresh job's callback URL when the job finishes
+type RefreshCallback struct {
+	Job     RefreshJob  `json:"job"`
+	Summary *PnLSummary `json:"summary,omitempty"`
+}
+
+// RefreshSpec is one refresh requested in a batch
+type RefreshSpec struct {
+	Address string `json:"address" validate:"required"` // Address, address book label or ENS name
+	Days    int    `json:"days,omitempty" validate:"min=1"`
+}
+
+// RefreshBatch tracks refreshes submitted together. Its status is pending
+// until a job starts and running until every job has finished; it is then
+// succeeded if every item succeeded, failed if none did and partial otherwise.
+type RefreshBatch struct {
+	ID          string             `json:"id"`
+	Status      JobStatus          `json:"status"`
+	Counts      map[JobStatus]int  `json:"counts"` // Items by job status; rejected items count as failed
+	Items       []RefreshBatchItem `json:"items"`
+	CallbackURL string             `json:"callbackUrl,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	FinishedAt  *time.Time         `json:"finishedAt,omitempty"`
+}
+
+// RefreshBatchItem is one refresh of a batch
+type RefreshBatchItem struct {
+	Address string      `json:"address"` // As requested
+	Days    int         `json:"days"`
+	Job     *RefreshJob `json:"job,omitempty"`   // nil if the item was rejected
+	Error   string      `json:"error,omitempty"` // Why the item was rejected
+}
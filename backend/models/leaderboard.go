@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// WindowStats is the P&L and traded volume of an address over a rolling window
+type WindowStats struct {
+	PnL        Money `json:"pnl"`
+	Volume     Money `json:"volume"`
+	TradeCount int   `json:"tradeCount"`
+}
+
+// LeaderboardEntry is one tracked address on the leaderboard
+type LeaderboardEntry struct {
+	Rank        int                    `json:"rank"`
+	Address     string                 `json:"address"`
+	Label       string                 `json:"label,omitempty"`
+	Windows     map[string]WindowStats `json:"windows"`         // key: window name, e.g. "24h"
+	Incomplete  bool                   `json:"incomplete"`      // Some trades could not be fetched
+	Error       string                 `json:"error,omitempty"` // Set if the last refresh of this address failed
+	RefreshedAt time.Time              `json:"refreshedAt"`
+}
+
+// Leaderboard ranks tracked addresses by P&L or volume over one window
+type Leaderboard struct {
+	Window      string             `json:"window"`
+	SortBy      string             `json:"sortBy"`
+	Entries     []LeaderboardEntry `json:"entries"`
+	RefreshedAt *time.Time         `json:"refreshedAt,omitempty"`
+}
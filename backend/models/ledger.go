@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// JournalEntry is one balanced double-entry transaction: the amounts of its
+// postings sum to zero
+type JournalEntry struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"` // "fill", "fee", "funding", "deposit", "withdrawal", "bridge", "vaultDeposit", "vaultWithdrawal",
+	// "referral", "rewardsClaim" or "otherIncome"
+	Coin     string    `json:"coin,omitempty"` // Vault address of vault entries
+	Ref      string    `json:"ref,omitempty"`  // Description, or exchange or HyperEVM transaction hash
+	Postings []Posting `json:"postings"`
+}
+
+// Posting moves Amount into Account: positive amounts are debits, negative
+// amounts credits. Amounts are in USD, except on the HyperEVM and HyperCore
+// token accounts, which are kept in token units.
+type Posting struct {
+	Account string `json:"account"`
+	Amount  Money  `json:"amount"`
+}
+
+// TrialBalance totals the postings of an address per account over a period,
+// with the P&L figures they add up to
+type TrialBalance struct {
+	Address      string           `json:"address"`
+	Label        string           `json:"label,omitempty"`
+	From         string           `json:"from,omitempty"` // First date included (YYYY-MM-DD); unbounded if empty
+	To           string           `json:"to,omitempty"`   // Last date included (YYYY-MM-DD); unbounded if empty
+	Entries      int              `json:"entries"`
+	Accounts     []AccountBalance `json:"accounts"` // Sorted by account
+	TotalDebits  Money            `json:"totalDebits"`
+	TotalCredits Money            `json:"totalCredits"`
+	Balanced     bool             `json:"balanced"`
+	// TradingPnL is sell value less buy value, the credit balance of the position accounts
+	TradingPnL  Money `json:"tradingPnl"`
+	Fees        Money `json:"fees"`    // Debit balance of the fees account
+	Funding     Money `json:"funding"` // Credit balance of the funding account
+	NetPnL      Money `json:"netPnl"`  // TradingPnL - Fees + Funding
+	NetDeposits Money `json:"netDeposits"`
+	// VaultPnL is the credit balance of the vault income account, the P&L
+	// realized on vault withdrawals; it isn't part of NetPnL
+	VaultPnL Money `json:"vaultPnl"`
+	// Referrals is the credit balance of the referral income account; it isn't part of NetPnL
+	Referrals Money `json:"referrals"`
+	// OtherIncome is the credit balance of the other income accounts, value
+	// accrued outside trading such as points and airdrops; it isn't part of NetPnL
+	OtherIncome Money `json:"otherIncome"`
+}
+
+// AccountBalance is the total debits and credits posted to one account
+type AccountBalance struct {
+	Account string `json:"account"`
+	Debits  Money  `json:"debits"`
+	Credits Money  `json:"credits"`
+	Balance Money  `json:"balance"` // Debits less credits
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// MarketContext is a coin's exchange-wide activity as recorded on one local
+// day: the rolling 24-hour notional volume and the open interest valued at
+// the mark price
+type MarketContext struct {
+	Coin            string    `json:"coin"`
+	Date            string    `json:"date"` // YYYY-MM-DD
+	DayVolume       float64   `json:"dayVolume"`
+	OpenInterest    float64   `json:"openInterest"` // In coins
+	OpenInterestUSD float64   `json:"openInterestUsd"`
+	MarkPrice       float64   `json:"markPrice"`
+	RecordedAt      time.Time `json:"recordedAt"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import "time"
+
+// MatchTolerance is how far an external row may be from a fill and still
+// match it
+type MatchTolerance struct {
+	TimeWindowMs int64   `json:"timeWindowMs"`
+	PriceEpsilon float64 `json:"priceEpsilon"` // Relative, e.g. 0.0001 for 1 bp
+	// AggregateSizes matches fills and rows split differently within the time
+	// window by their total size and average price
+	AggregateSizes bool `json:"aggregateSizes"`
+}
+
+// ReconFill is a fill or an external row in an external reconciliation. Ref
+// identifies it by its contents, so it is the same across runs.
+type ReconFill struct {
+	Ref   string    `json:"ref"`
+	Time  time.Time `json:"time"`
+	Coin  string    `json:"coin"`
+	Side  string    `json:"side"`
+	Price Money     `json:"px"`
+	Size  Quantity  `json:"sz"`
+}
+
+// Kinds of FillMatch
+const (
+	MatchExact      = "exact"
+	MatchFuzzy      = "fuzzy"      // One fill to one row within the tolerances
+	MatchAggregated = "aggregated" // Fills and rows split differently
+	MatchManual     = "manual"     // Linked by hand
+	MatchExplained  = "explained"  // Marked explained by hand, without a counterpart
+)
+
+// FillMatch links fills to the external rows they were matched with
+type FillMatch struct {
+	Kind     string      `json:"kind"`
+	Internal []ReconFill `json:"internal"`
+	External []ReconFill `json:"external"`
+	// Score is 1 for a perfect match, falling towards 0 as time and price
+	// differences approach the tolerances
+	Score float64 `json:"score"`
+	// Candidates is the most fills or rows either side could have been
+	// matched with; above 1 the match is ambiguous
+	Candidates    int      `json:"candidates"`
+	RunnerUpScore *float64 `json:"runnerUpScore,omitempty"` // Best score among the other candidates
+	// ResolutionID is the resolution a manual or explained match comes from
+	ResolutionID string `json:"resolutionId,omitempty"`
+	Note         string `json:"note,omitempty"`
+}
+
+// MatchResolution links unmatched fills of an address to external rows by
+// hand, or marks them explained. It is applied to every later reconciliation
+// of the address in which all its fills and rows are unmatched.
+type MatchResolution struct {
+	ID        string    `json:"id"`
+	Address   string    `json:"address"`
+	ReconID   string    `json:"reconId"`  // The reconciliation it was made on
+	Internal  []string  `json:"internal"` // Refs of fills
+	External  []string  `json:"external"` // Refs of external rows
+	Explained bool      `json:"explained"`
+	Note      string    `json:"note,omitempty"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ExternalRecon is one reconciliation of an address's fills against an
+// external file, such as a broker or fund administrator statement
+type ExternalRecon struct {
+	ID            string         `json:"id"`
+	Address       string         `json:"address"`
+	Label         string         `json:"label,omitempty"`
+	Source        string         `json:"source,omitempty"` // Connector that pulled the file; empty for uploads
+	File          string         `json:"file,omitempty"`   // Name of the pulled file
+	Digest        string         `json:"digest,omitempty"` // SHA-256 of the pulled file
+	Tolerance     MatchTolerance `json:"tolerance"`
+	Window        TimeRange      `json:"window"` // Fills in this range were compared
+	ExternalRows  int            `json:"externalRows"`
+	InternalFills int            `json:"internalFills"`
+	Exact         int            `json:"exact"`
+	Fuzzy         int            `json:"fuzzy"`
+	Aggregated    int            `json:"aggregated"`
+	Manual        int            `json:"manual"`
+	Explained     int            `json:"explained"`
+	// Matches are the fuzzy, aggregated, manual and explained matches, oldest
+	// first; exact matches are only counted
+	Matches []FillMatch `json:"matches"`
+	// Ambiguous are the matches with more than one candidate, lowest score first
+	Ambiguous         []FillMatch `json:"ambiguous"`
+	UnmatchedInternal []ReconFill `json:"unmatchedInternal"`
+	UnmatchedExternal []ReconFill `json:"unmatchedExternal"`
+	CreatedAt         time.Time   `json:"createdAt"`
+}
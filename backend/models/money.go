@@ -0,0 +1,163 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+var (
+	// ErrInvalidMoney is returned for an amount that is NaN or infinite
+	ErrInvalidMoney = errors.New("invalid amount")
+	// ErrInvalidQuantity is returned for a quantity that is NaN, infinite or negative
+	ErrInvalidQuantity = errors.New("invalid quantity")
+	// ErrInvalidPosition is returned for a position that is NaN or infinite
+	ErrInvalidPosition = errors.New("invalid position")
+)
+
+// Decimals Money and Quantity values are rounded to, half away from zero.
+// Eight covers every Hyperliquid price and size; USDC itself has six.
+const (
+	MoneyDecimals    = 8
+	QuantityDecimals = 8
+)
+
+// Money is an amount in USD: a price, value, fee or P&L. Values made with
+// NewMoney or ParseMoney are always finite, so they can't turn a total into NaN.
+type Money float64
+
+// Quantity is an amount of a coin, such as a fill size. It is never negative;
+// the side of a fill says which way it went.
+type Quantity float64
+
+// Position is the amount of a coin held: positive when long, negative when
+// short. It is rounded to QuantityDecimals like the fills that change it.
+type Position float64
+
+// roundTo rounds v to decimals places, half away from zero
+func roundTo(v float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(v*scale) / scale
+}
+
+// NewMoney returns v as Money, rounded to MoneyDecimals
+func NewMoney(v float64) (Money, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidMoney, v)
+	}
+	return Money(roundTo(v, MoneyDecimals)), nil
+}
+
+// ParseMoney parses a decimal string such as "3100.5" as Money
+func ParseMoney(s string) (Money, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	return NewMoney(v)
+}
+
+// Round returns m rounded to MoneyDecimals
+func (m Money) Round() Money {
+	return Money(roundTo(float64(m), MoneyDecimals))
+}
+
+// MarshalJSON writes m as a JSON number rounded to MoneyDecimals
+func (m Money) MarshalJSON() ([]byte, error) {
+	return marshalDecimal(float64(m), MoneyDecimals, ErrInvalidMoney)
+}
+
+// UnmarshalJSON reads m from a JSON number or decimal string, as the API sends amounts
+func (m *Money) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseMoney(string(bytes.Trim(data, `"`)))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// NewQuantity returns v as a Quantity, rounded to QuantityDecimals
+func NewQuantity(v float64) (Quantity, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidQuantity, v)
+	}
+	return Quantity(roundTo(v, QuantityDecimals)), nil
+}
+
+// ParseQuantity parses a decimal string such as "0.25" as a Quantity
+func ParseQuantity(s string) (Quantity, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidQuantity, s)
+	}
+	return NewQuantity(v)
+}
+
+// Round returns q rounded to QuantityDecimals
+func (q Quantity) Round() Quantity {
+	return Quantity(roundTo(float64(q), QuantityDecimals))
+}
+
+// MarshalJSON writes q as a JSON number rounded to QuantityDecimals
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return marshalDecimal(float64(q), QuantityDecimals, ErrInvalidQuantity)
+}
+
+// UnmarshalJSON reads q from a JSON number or decimal string
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseQuantity(string(bytes.Trim(data, `"`)))
+	if err != nil {
+		return err
+	}
+	*q = parsed
+	return nil
+}
+
+// NewPosition returns v as a Position, rounded to QuantityDecimals
+func NewPosition(v float64) (Position, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidPosition, v)
+	}
+	return Position(roundTo(v, QuantityDecimals)), nil
+}
+
+// ParsePosition parses a signed decimal string such as "-1.5" as a Position
+func ParsePosition(s string) (Position, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidPosition, s)
+	}
+	return NewPosition(v)
+}
+
+// Round returns p rounded to QuantityDecimals
+func (p Position) Round() Position {
+	return Position(roundTo(float64(p), QuantityDecimals))
+}
+
+// MarshalJSON writes p as a JSON number rounded to QuantityDecimals
+func (p Position) MarshalJSON() ([]byte, error) {
+	return marshalDecimal(float64(p), QuantityDecimals, ErrInvalidPosition)
+}
+
+// UnmarshalJSON reads p from a JSON number or decimal string
+func (p *Position) UnmarshalJSON(data []byte) error {
+	parsed, err := ParsePosition(string(bytes.Trim(data, `"`)))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// marshalDecimal writes v rounded to decimals as the shortest JSON number
+// that reads back the same, or fails with invalid if it isn't finite
+func marshalDecimal(v float64, decimals int, invalid error) ([]byte, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, fmt.Errorf("%w: %v", invalid, v)
+	}
+	return strconv.AppendFloat(nil, roundTo(v, decimals), 'f', -1, 64), nil
+}
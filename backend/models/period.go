@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// PeriodLock freezes the daily P&L of one address for a calendar month once
+// its statement is issued. Later changes are recorded as restatements rather
+// than overwriting the locked numbers.
+type PeriodLock struct {
+	Address    string     `json:"address"`
+	Label      string     `json:"label,omitempty"`
+	Month      string     `json:"month"` // YYYY-MM
+	Days       []DailyPnL `json:"days"`  // Trading days of the month as locked, newest first
+	TotalPnL   Money      `json:"totalPnL"`
+	TradeCount int        `json:"tradeCount"`
+	Volume     Money      `json:"volume"`
+	LockedAt   time.Time  `json:"lockedAt"`
+}
+
+// Restatement records a locked number that later data changed
+type Restatement struct {
+	ID         string    `json:"id"`
+	Address    string    `json:"address"`
+	Month      string    `json:"month"` // YYYY-MM of the locked period
+	Date       string    `json:"date"`  // Day whose number changed (YYYY-MM-DD)
+	Field      string    `json:"field"` // "dailyPnL", "tradeCount" or "volume"
+	OldValue   Money     `json:"oldValue"`
+	NewValue   Money     `json:"newValue"`
+	Reason     string    `json:"reason"` // What brought in the new data, e.g. "refresh" or "import"
+	RecordedAt time.Time `json:"recordedAt"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// How a dry run estimated the fills in a window
+const (
+	EstimateFromCache = "cache" // Counted from the cached trades the window covers
+	EstimateFromRate  = "rate"  // Partly extrapolated from the cached trading rate
+	EstimateNone      = "none"  // Nothing is cached, so only the first batch is counted
+)
+
+// Refresh modes
+const (
+	RefreshIncremental = "incremental" // Top up the cache since its last fetch
+	RefreshFull        = "full"        // Fetch the whole range again
+)
+
+// PlannedFetch is one window a refresh would fetch, paginated in batches
+type PlannedFetch struct {
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end"`
+	Reason           string    `json:"reason"` // "missing", "incremental" or "full"
+	EstimatedFills   int       `json:"estimatedFills"`
+	EstimatedBatches int       `json:"estimatedBatches"`
+	EstimatedWeight  int       `json:"estimatedWeight"`
+	EstimateBasis    string    `json:"estimateBasis"`
+}
+
+// RefreshPlan is what a refresh would fetch, reported by a dry run without making any requests
+type RefreshPlan struct {
+	Address             string         `json:"address"`
+	Days                int            `json:"days"`
+	Mode                string         `json:"mode"`
+	Fetches             []PlannedFetch `json:"fetches"`
+	EstimatedFills      int            `json:"estimatedFills"`
+	EstimatedBatches    int            `json:"estimatedBatches"`
+	EstimatedWeight     int            `json:"estimatedWeight"`     // Hyperliquid rate limit weight
+	EstimatedDurationMs int64          `json:"estimatedDurationMs"` // At the configured request pacing and rate limit
+	PlannedAt           time.Time      `json:"plannedAt"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// PositionPoint is the position in one coin immediately after a fill. Consecutive
+// points form a step series: the position holds until the next point's time.
+type PositionPoint struct {
+	Time      time.Time `json:"time"`
+	Side      string    `json:"side"` // Side of the fill that produced this point
+	FillPrice Money     `json:"fillPx"`
+	FillSize  Quantity  `json:"fillSz"`
+	Size      Position  `json:"size"`
+	AvgEntry  Money     `json:"avgEntry"` // 0 when flat
+}
+
+// PositionHistory is the reconstructed position timeline for one coin
+type PositionHistory struct {
+	Address string          `json:"address"`
+	Label   string          `json:"label,omitempty"`
+	Coin    string          `json:"coin"`
+	Points  []PositionPoint `json:"points"`
+}
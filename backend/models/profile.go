@@ -0,0 +1,27 @@
+package models
+
+// PerformanceProfile aggregates realized P&L by weekday and by hour of day in
+// Timezone, to show when trading pays off
+type PerformanceProfile struct {
+	Address  string          `json:"address"`
+	Label    string          `json:"label,omitempty"`
+	Timezone string          `json:"timezone"`
+	Weekdays []ProfileBucket `json:"weekdays"` // Sunday first
+	Hours    []ProfileBucket `json:"hours"`    // Hour 0 first
+	// NetPnLGrid is net realized P&L by weekday (Sunday first) and hour
+	NetPnLGrid [][]float64 `json:"netPnlGrid"`
+}
+
+// ProfileBucket is the performance of the fills in one weekday or hour.
+// P&L is realized against each coin's average entry, as in position history.
+type ProfileBucket struct {
+	Name         string  `json:"name"` // e.g. "Sunday" or "22:00"
+	Fills        int     `json:"fills"`
+	ClosingFills int     `json:"closingFills"` // Fills that reduced or closed a position
+	Wins         int     `json:"wins"`         // Closing fills with positive realized P&L
+	WinRate      float64 `json:"winRate"`      // Wins as a share of closing fills
+	Volume       float64 `json:"volume"`
+	RealizedPnL  float64 `json:"realizedPnl"`
+	Fees         float64 `json:"fees"`
+	NetPnL       float64 `json:"netPnl"` // Realized P&L after fees
+}
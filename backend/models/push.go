@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// PushEvent is an event sent to WebSocket subscribers as it happens
+type PushEvent struct {
+	Type    string      `json:"type"` // e.g. "pnl.updated", "dataquality.alert", "break.raised"
+	Address string      `json:"address,omitempty"`
+	Time    time.Time   `json:"time"`
+	Data    interface{} `json:"data"`
+}
+
+// PushFilter selects the events a subscriber receives. Empty lists match everything.
+type PushFilter struct {
+	Addresses []string `json:"addresses"`
+	Types     []string `json:"types"`
+}
+
+// PnLUpdate is the payload of a "pnl.updated" event, sent when a refresh finishes
+type PnLUpdate struct {
+	Label      string `json:"label,omitempty"`
+	Days       int    `json:"days"`
+	TradeCount int    `json:"tradeCount"`
+	TotalPnL   Money  `json:"totalPnL"`
+	Incomplete bool   `json:"incomplete"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// QuarantineStatus is the state of a quarantined fill
+type QuarantineStatus string
+
+const (
+	QuarantinePending     QuarantineStatus = "quarantined"
+	QuarantineReprocessed QuarantineStatus = "reprocessed" // Parsed and merged into the cache
+	QuarantineDiscarded   QuarantineStatus = "discarded"
+)
+
+// QuarantinedFill is a fill from the API that couldn't be parsed, kept as it
+// was received so it can be fixed and reprocessed instead of being lost.
+// Fills that left quarantine are kept too, so fetching them again doesn't
+// quarantine them a second time.
+type QuarantinedFill struct {
+	ID            string           `json:"id"`
+	Address       string           `json:"address"`
+	Fill          json.RawMessage  `json:"fill"`
+	Error         string           `json:"error"` // Why the fill couldn't be parsed when last tried
+	Status        QuarantineStatus `json:"status"`
+	QuarantinedAt time.Time        `json:"quarantinedAt"`
+	FixedAt       *time.Time       `json:"fixedAt,omitempty"` // When the fill was last corrected by hand
+	ResolvedAt    *time.Time       `json:"resolvedAt,omitempty"`
+}
+
+// QuarantineReprocess is the result of reprocessing quarantined fills
+type QuarantineReprocess struct {
+	Recovered int               `json:"recovered"` // Fills that now parse and were merged into their address's cache
+	Failed    int               `json:"failed"`    // Fills left in quarantine
+	Fills     []QuarantinedFill `json:"fills"`     // Every fill reprocessed, with its new status
+}
+
+// DataQualitySummary counts what is known to be wrong with the data behind a summary
+type DataQualitySummary struct {
+	QuarantinedFills int `json:"quarantinedFills"` // Fills of the address that couldn't be parsed and aren't in the totals
+	Alerts           int `json:"alerts"`           // Recent data-quality alerts for the address
+}
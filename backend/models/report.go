@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// CoinPnL is the P&L of one coin within a report period
+type CoinPnL struct {
+	Coin       string `json:"coin"`
+	PnL        Money  `json:"pnl"`
+	TradeCount int    `json:"tradeCount"`
+}
+
+// AccountReport summarizes one address's trading on a report day
+type AccountReport struct {
+	Address       string        `json:"address"`
+	Label         string        `json:"label,omitempty"`
+	TradeCount    int           `json:"tradeCount"`
+	PnL           Money         `json:"pnl"`
+	Volume        Money         `json:"volume"`
+	Fees          Money         `json:"fees"`        // Net of Rebates
+	Rebates       Money         `json:"rebates"`     // Maker rebates received
+	Referrals     Money         `json:"referrals"`   // Referral rewards posted to the ledger on the day
+	OtherIncome   Money         `json:"otherIncome"` // Points, airdrops and other non-trade value posted on the day
+	TopWinners    []CoinPnL     `json:"topWinners"`
+	TopLosers     []CoinPnL     `json:"topLosers"`
+	MissingRanges []TimeRange   `json:"missingRanges,omitempty"` // Unfetched windows overlapping the day
+	PositionGaps  []CoinDayGaps `json:"positionGaps,omitempty"`  // Start position mismatches on the day
+	Error         string        `json:"error,omitempty"`         // Set if the address could not be refreshed
+}
+
+// DailyReport is the daily summary emailed for an address or portfolio of addresses
+type DailyReport struct {
+	Name        string          `json:"name"`
+	Date        string          `json:"date"`
+	Accounts    []AccountReport `json:"accounts"`
+	TotalPnL    Money           `json:"totalPnL"`
+	TotalFees   Money           `json:"totalFees"`
+	GeneratedAt time.Time       `json:"generatedAt"`
+}
+
+// ReportSubscription configures a daily email report for one or more addresses
+type ReportSubscription struct {
+	Name      string   `json:"name"`
+	Addresses []string `json:"addresses"` // Hex addresses, saved labels or ENS names
+	To        []string `json:"to"`
+	Time      string   `json:"time"` // Local time of day to send at, "HH:MM"
+}
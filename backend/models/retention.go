@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Kinds of records a retention policy applies to
+const (
+	RetentionFills = "fills" // Cached fills and their copies in the event log
+	RetentionAudit = "audit" // Reconciliation runs and external reconciliations
+	RetentionJobs  = "jobs"  // Finished refresh jobs and batches
+)
+
+// RetentionPolicy is how long each kind of record is kept, as a duration
+// such as "720h" or a number of days such as "730d". Fills and audit records
+// are kept forever if their retention is empty.
+type RetentionPolicy struct {
+	Fills string `yaml:"fills" json:"fills,omitempty"`
+	Audit string `yaml:"audit" json:"audit,omitempty"`
+	Jobs  string `yaml:"jobs" json:"jobs,omitempty"`
+}
+
+// RetentionResult is what one pruning run deleted of one kind of record, or
+// would have deleted in a dry run
+type RetentionResult struct {
+	Kind      string         `json:"kind"`
+	Retention string         `json:"retention"`
+	Cutoff    time.Time      `json:"cutoff"` // Records older than this are deleted
+	Deleted   int            `json:"deleted"`
+	Addresses map[string]int `json:"addresses,omitempty"` // Fills deleted per address
+	Error     string         `json:"error,omitempty"`
+}
+
+// RetentionReport is the outcome of one pruning run
+type RetentionReport struct {
+	DryRun     bool              `json:"dryRun"`
+	StartedAt  time.Time         `json:"startedAt"`
+	DurationMs int64             `json:"durationMs"`
+	Results    []RetentionResult `json:"results"`
+}
+
+// RetentionStatus is a tenant's retention policy and pruning metrics
+type RetentionStatus struct {
+	Policy     RetentionPolicy  `json:"policy"`
+	Runs       int              `json:"runs"`     // Pruning runs since startup, excluding dry runs
+	Failures   int              `json:"failures"` // Runs with an error in any kind
+	Deleted    map[string]int   `json:"deleted"`  // Records deleted since startup; key: kind
+	LastRun    *RetentionReport `json:"lastRun,omitempty"`
+	LastDryRun *RetentionReport `json:"lastDryRun,omitempty"`
+}
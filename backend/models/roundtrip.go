@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// RoundTrip is a completed trade: an entry and the exit that closed it
+type RoundTrip struct {
+	Coin       string    `json:"coin"`
+	Direction  string    `json:"direction"` // "long" or "short"
+	EntryTime  time.Time `json:"entryTime"`
+	ExitTime   time.Time `json:"exitTime"`
+	Duration   string    `json:"duration"`
+	DurationMs int64     `json:"durationMs"`
+	Size       Quantity  `json:"size"`
+	EntryPrice Money     `json:"entryPx"` // Size-weighted when several fills are paired
+	ExitPrice  Money     `json:"exitPx"`
+	GrossPnL   Money     `json:"grossPnl"`
+	Fees       Money     `json:"fees"`
+	NetPnL     Money     `json:"netPnl"`
+	ReturnPct  float64   `json:"returnPct"` // Net P&L as a percentage of entry notional
+	// Excursion is how far the price moved against and for the position
+	// while it was open; only set when excursions are requested
+	Excursion *Excursion `json:"excursion,omitempty"`
+}
+
+// Excursion is the maximum adverse (MAE) and favorable (MFE) excursion of a
+// round trip: the furthest the price went against and for it between entry
+// and exit, measured from the entry price on candles of Interval
+type Excursion struct {
+	Interval   string    `json:"interval"`
+	MAE        Money     `json:"mae"` // In USDC, for the round trip's size
+	MFE        Money     `json:"mfe"`
+	MAEPct     float64   `json:"maePct"` // As a percentage of the entry price
+	MFEPct     float64   `json:"mfePct"`
+	WorstPrice Money     `json:"worstPx"`
+	BestPrice  Money     `json:"bestPx"`
+	WorstTime  time.Time `json:"worstTime"` // Open time of the candle the worst price was in
+	BestTime   time.Time `json:"bestTime"`
+	// Capture is gross P&L as a share of MFE; omitted if the price never moved in favor
+	Capture *float64 `json:"capture,omitempty"`
+}
+
+// RoundTripReport lists the round trips of an address, oldest exit first
+type RoundTripReport struct {
+	Address    string      `json:"address"`
+	Label      string      `json:"label,omitempty"`
+	Method     string      `json:"method"` // "fifo", "lifo" or "position"
+	RoundTrips []RoundTrip `json:"roundTrips"`
+	Count      int         `json:"count"`
+	Wins       int         `json:"wins"` // Round trips with positive net P&L
+	WinRate    float64     `json:"winRate"`
+	NetPnL     Money       `json:"netPnl"`
+	// ExcursionsSkipped counts the older round trips left without an
+	// excursion because of ExcursionMaxRoundTrips
+	ExcursionsSkipped int `json:"excursionsSkipped,omitempty"`
+}
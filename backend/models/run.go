@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Run is one reconciliation of an address: the P&L it produced, the version
+// of the calculation that produced it and a hash of the trades it was
+// computed from, so runs before and after a calculation change can be compared
+type Run struct {
+	ID                string     `json:"id"`
+	Address           string     `json:"address"`
+	Label             string     `json:"label,omitempty"`
+	Days              int        `json:"days"`
+	CalculatorVersion string     `json:"calculatorVersion"`
+	InputsHash        string     `json:"inputsHash"` // SHA-256 of the trades reconciled
+	TradeCount        int        `json:"tradeCount"`
+	Coverage          TimeRange  `json:"coverage"`
+	TotalPnL          Money      `json:"totalPnL"`
+	Records           []DailyPnL `json:"records,omitempty"` // Newest first; omitted from run listings
+	CreatedAt         time.Time  `json:"createdAt"`
+}
+
+// RunComparison lists the days on which two runs disagree
+type RunComparison struct {
+	A            Run          `json:"a"`
+	B            Run          `json:"b"`
+	SameVersion  bool         `json:"sameVersion"`
+	SameInputs   bool         `json:"sameInputs"`
+	DaysCompared int          `json:"daysCompared"`
+	TotalPnLDiff Money        `json:"totalPnLDiff"` // B less A
+	Days         []RunDayDiff `json:"days"`         // Days that differ, oldest first
+}
+
+// RunDayDiff is one day's numbers in two runs. A run that has no record for
+// the day has nil numbers.
+type RunDayDiff struct {
+	Date        string `json:"date"`
+	PnLA        *Money `json:"pnlA"`
+	PnLB        *Money `json:"pnlB"`
+	PnLDiff     Money  `json:"pnlDiff"` // B less A
+	TradeCountA int    `json:"tradeCountA"`
+	TradeCountB int    `json:"tradeCountB"`
+	VolumeA     Money  `json:"volumeA"`
+	VolumeB     Money  `json:"volumeB"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// RuntimeConfig is the live-reloadable part of the configuration, read from
+// the YAML file named by RECON_CONFIG_FILE. Empty fields keep their defaults.
+type RuntimeConfig struct {
+	RateLimitDelay string                         `yaml:"rateLimitDelay" json:"rateLimitDelay"` // Delay between paginated upstream requests, e.g. "300ms"
+	Schedules      ScheduleConfig                 `yaml:"schedules" json:"schedules"`
+	Retention      RetentionPolicy                `yaml:"retention" json:"retention"`
+	Tenants        map[string]TenantRuntimeConfig `yaml:"tenants" json:"tenants,omitempty"` // key: tenant ID
+	Symbols        []SymbolMapping                `yaml:"symbols" json:"symbols,omitempty"` // Canonical coin names, shared by all tenants
+}
+
+// ScheduleConfig holds the schedules of recurring jobs
+type ScheduleConfig struct {
+	Leaderboard string `yaml:"leaderboard" json:"leaderboard"` // Cron expression
+	S3Export    string `yaml:"s3Export" json:"s3Export"`       // Cron expression
+	EODClose    string `yaml:"eodClose" json:"eodClose"`       // Local time of day, "HH:MM"
+	Retention   string `yaml:"retention" json:"retention"`     // Cron expression
+}
+
+// TenantRuntimeConfig holds one tenant's live settings
+type TenantRuntimeConfig struct {
+	// Alerts replaces the tenant's startup alert settings if set
+	Alerts *AlertConfig `yaml:"alerts" json:"alerts,omitempty"`
+	// Addresses are kept in the tenant's address book; addresses removed from
+	// the file are removed from the address book on reload
+	Addresses []TrackedAddress `yaml:"addresses" json:"addresses,omitempty"`
+	// Connectors pull external trade files on a schedule and reconcile them
+	Connectors []ConnectorConfig `yaml:"connectors" json:"connectors,omitempty"`
+}
+
+// AlertConfig says where end-of-day close reports and data-quality alerts are sent
+type AlertConfig struct {
+	EODWebhookURL string   `yaml:"eodWebhookUrl" json:"eodWebhookUrl,omitempty"`
+	EODReportTo   []string `yaml:"eodReportTo" json:"eodReportTo,omitempty"`
+	// DataQuality tunes which ingest anomalies raise alerts; nil keeps the defaults
+	DataQuality *DataQualityConfig `yaml:"dataQuality" json:"dataQuality,omitempty"`
+}
+
+// DataQualityConfig tunes the data-quality checks run on every refresh. Zero
+// fields keep their defaults.
+type DataQualityConfig struct {
+	Mute             []AnomalyKind `yaml:"mute" json:"mute,omitempty"`                         // Kinds that don't raise alerts
+	ActiveDays       int           `yaml:"activeDays" json:"activeDays,omitempty"`             // Days of the week before that an address must have traded on for a day without fills to be an anomaly
+	ParseFailureRate float64       `yaml:"parseFailureRate" json:"parseFailureRate,omitempty"` // Share of fetched fills that may fail to parse
+}
+
+// TrackedAddress is an address tracked through the config file
+type TrackedAddress struct {
+	Address string `yaml:"address" json:"address"`
+	Label   string `yaml:"label" json:"label"`
+}
+
+// ConnectorConfig pulls an external trade file, such as a prime broker or
+// custodian export, from an HTTPS URL or an SFTP folder on a schedule and
+// reconciles it against an address's fills. Credentials are named secrets.
+type ConnectorConfig struct {
+	Name     string `yaml:"name" json:"name"`
+	Address  string `yaml:"address" json:"address"`
+	URL      string `yaml:"url" json:"url"`                   // https://host/file.csv or sftp://user@host[:port]/dir
+	Pattern  string `yaml:"pattern" json:"pattern,omitempty"` // SFTP file names to consider, e.g. "fills_*.csv"; the newest is pulled
+	Format   string `yaml:"format" json:"format,omitempty"`   // csv or parquet; by default from the file extension
+	Schedule string `yaml:"schedule" json:"schedule"`         // Cron expression
+	// AuthSecret is the secret holding the HTTP Authorization header value,
+	// or the SFTP password
+	AuthSecret string `yaml:"authSecret" json:"authSecret,omitempty"`
+	KeySecret  string `yaml:"keySecret" json:"keySecret,omitempty"` // Secret holding the SFTP private key (PEM)
+	HostKey    string `yaml:"hostKey" json:"hostKey,omitempty"`     // SFTP server public key, in authorized_keys format
+}
+
+// ConfigReload is the outcome of applying the config file
+type ConfigReload struct {
+	Path       string        `json:"path,omitempty"`
+	Config     RuntimeConfig `json:"config"`
+	Changes    []string      `json:"changes"`
+	ReloadedAt time.Time     `json:"reloadedAt"`
+}
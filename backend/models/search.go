@@ -0,0 +1,17 @@
+package models
+
+// SearchHit is a cached trade that matched a search, with the address it belongs to
+type SearchHit struct {
+	Address string `json:"address"`
+	Label   string `json:"label,omitempty"`
+	Trade
+}
+
+// TradeSearch is the result of a trade search
+type TradeSearch struct {
+	Query     string      `json:"query"`
+	Sort      string      `json:"sort"`    // "time" (newest first) or "notional" (largest first)
+	Matches   int         `json:"matches"` // Trades that matched, including those past the limit
+	Truncated bool        `json:"truncated"`
+	Hits      []SearchHit `json:"hits"`
+}
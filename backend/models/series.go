@@ -0,0 +1,26 @@
+package models
+
+// PnLSeries is daily and cumulative P&L per coin, shaped for charting: every
+// line has one value per entry in Dates, oldest first, including days without
+// trades. A downsampled series has one value per bucket of BucketDays days,
+// dated by the bucket's first day.
+type PnLSeries struct {
+	Address    string       `json:"address"`
+	Label      string       `json:"label,omitempty"`
+	Dates      []string     `json:"dates"`
+	BucketDays int          `json:"bucketDays"`
+	Coins      []SeriesLine `json:"coins"` // Sorted by coin
+	Total      SeriesLine   `json:"total"` // Sum over the coins included
+}
+
+// SeriesLine is the P&L of one coin, or of all coins for the total line
+type SeriesLine struct {
+	Coin       string    `json:"coin"`
+	Daily      []float64 `json:"daily"`
+	Cumulative []float64 `json:"cumulative"`
+	// MarketVolume and OpenInterestUSD are the coin's exchange-wide volume and
+	// open interest per date, null where none was recorded; set only when
+	// market context is asked for
+	MarketVolume    []*float64 `json:"marketVolume,omitempty"`
+	OpenInterestUSD []*float64 `json:"openInterestUsd,omitempty"`
+}
@@ -0,0 +1,42 @@
+package models
+
+// Statement is a fund-style statement of an address over a period. Equity is
+// the base capital plus cumulative trading P&L, so deposits and withdrawals
+// neither raise the high-water mark nor earn a fee. Fees are accrued, not
+// deducted from equity.
+type Statement struct {
+	Address     string  `json:"address"`
+	Label       string  `json:"label,omitempty"`
+	From        string  `json:"from"` // First date of the period (YYYY-MM-DD)
+	To          string  `json:"to"`   // Last date of the period (YYYY-MM-DD)
+	BaseCapital Money   `json:"baseCapital"`
+	FeeRate     float64 `json:"feeRate"` // Share of gains above the high-water mark charged as a fee
+	// OpeningEquity and OpeningHighWaterMark are as of the start of From
+	OpeningEquity        Money `json:"openingEquity"`
+	OpeningHighWaterMark Money `json:"openingHighWaterMark"`
+	ClosingEquity        Money `json:"closingEquity"`
+	HighWaterMark        Money `json:"highWaterMark"` // As of the end of To
+	PeriodPnL            Money `json:"periodPnl"`
+	// MaxDrawdown is the largest fall below the high-water mark during the period; zero or negative
+	MaxDrawdown    Money    `json:"maxDrawdown"`
+	MaxDrawdownPct *float64 `json:"maxDrawdownPct,omitempty"`
+	// PerformanceFee is the fee accrued by the end of the period
+	PerformanceFee Money           `json:"performanceFee"`
+	NetPnL         Money           `json:"netPnl"` // PeriodPnL less PerformanceFee
+	Lines          []StatementLine `json:"lines"`  // Trading days of the period, oldest first
+	// Restatements are changes to locked numbers within the period, newest first
+	Restatements []Restatement `json:"restatements,omitempty"`
+}
+
+// StatementLine is one trading day of a statement
+type StatementLine struct {
+	Date          string `json:"date"`
+	TradeCount    int    `json:"tradeCount"`
+	PnL           Money  `json:"pnl"`
+	Equity        Money  `json:"equity"` // At the end of the day
+	HighWaterMark Money  `json:"highWaterMark"`
+	Drawdown      Money  `json:"drawdown"` // Equity less the high-water mark; zero or negative
+	// DrawdownPct is Drawdown as a percentage of the high-water mark, when it is positive
+	DrawdownPct *float64 `json:"drawdownPct,omitempty"`
+	AccruedFee  Money    `json:"accruedFee"` // Fee accrued since the start of the period
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// RuntimeStats is a snapshot of the process's resource usage for diagnosing
+// memory growth in production
+type RuntimeStats struct {
+	Goroutines  int           `json:"goroutines"`
+	Heap        HeapStats     `json:"heap"`
+	GC          GCStats       `json:"gc"`
+	Upstream    UpstreamStats `json:"upstream"`
+	Tenants     []TenantStats `json:"tenants"`
+	Uptime      string        `json:"uptime"`
+	CollectedAt time.Time     `json:"collectedAt"`
+}
+
+// HeapStats summarizes runtime.MemStats heap figures, in bytes
+type HeapStats struct {
+	Alloc      uint64 `json:"alloc"`      // Bytes of allocated heap objects
+	InUse      uint64 `json:"inUse"`      // Bytes in in-use spans
+	Idle       uint64 `json:"idle"`       // Bytes in idle spans, which may be returned to the OS
+	Released   uint64 `json:"released"`   // Bytes returned to the OS
+	Sys        uint64 `json:"sys"`        // Bytes of heap memory obtained from the OS
+	Objects    uint64 `json:"objects"`    // Number of allocated heap objects
+	TotalAlloc uint64 `json:"totalAlloc"` // Cumulative bytes allocated
+	ProcessSys uint64 `json:"processSys"` // Total bytes of memory obtained from the OS
+}
+
+// GCStats summarizes garbage collector activity
+type GCStats struct {
+	NumGC       uint32     `json:"numGC"`
+	PauseTotal  string     `json:"pauseTotal"`
+	LastPause   string     `json:"lastPause"`
+	LastGC      *time.Time `json:"lastGC,omitempty"`
+	NextGCBytes uint64     `json:"nextGCBytes"` // Heap size the next collection is triggered at
+	CPUFraction float64    `json:"cpuFraction"` // Fraction of CPU time used by the GC since start
+}
+
+// UpstreamStats counts Hyperliquid API requests and the connections they used since startup
+type UpstreamStats struct {
+	Requests          int64   `json:"requests"`
+	NewConnections    int64   `json:"newConnections"`
+	ReusedConnections int64   `json:"reusedConnections"`
+	ReuseRate         float64 `json:"reuseRate"`     // Share of requests sent on an existing connection
+	TLSHandshakes     int64   `json:"tlsHandshakes"` // Full or resumed
+	TLSResumed        int64   `json:"tlsResumed"`    // Handshakes that resumed a cached session
+	HTTP2Responses    int64   `json:"http2Responses"`
+	CachedResponses   int64   `json:"cachedResponses"` // Batches answered from the response cache instead of a request
+	ClockSkewMs       int64   `json:"clockSkewMs"`     // How far the local clock is ahead of the exchange's; fetch windows are corrected by it
+	PacingDelayMs     int64   `json:"pacingDelayMs"`   // Pause between paginated requests in effect
+	PacingBackoffs    int64   `json:"pacingBackoffs"`  // Times pacing slowed down after a sign of pressure from the API
+}
+
+// TenantStats counts what one tenant keeps in memory
+type TenantStats struct {
+	ID                string     `json:"id"`
+	Cache             CacheStats `json:"cache"`
+	Jobs              int        `json:"jobs"`
+	WebhookDeliveries int        `json:"webhookDeliveries"`
+	Breaks            int        `json:"breaks"`
+	Closes            int        `json:"closes"`
+	AddressBook       int        `json:"addressBook"`
+}
+
+// CacheStats describes the trade cache of a reconciliation service
+type CacheStats struct {
+	Addresses      int                 `json:"addresses"`
+	Trades         int                 `json:"trades"`
+	EstimatedBytes int64               `json:"estimatedBytes"` // Approximate memory held by cached trades
+	Accounts       []AccountCacheStats `json:"accounts"`       // Largest first
+}
+
+// AccountCacheStats describes the cached trades of one address
+type AccountCacheStats struct {
+	Address        string     `json:"address"`
+	Trades         int        `json:"trades"`
+	EstimatedBytes int64      `json:"estimatedBytes"`
+	CachedDays     int        `json:"cachedDays"`
+	MissingRanges  int        `json:"missingRanges"`
+	LastFetchTime  *time.Time `json:"lastFetchTime,omitempty"`
+}
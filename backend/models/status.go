@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// UpstreamState is how well the Hyperliquid API is answering
+type UpstreamState string
+
+const (
+	UpstreamUnknown  UpstreamState = "unknown" // No request has completed yet
+	UpstreamOK       UpstreamState = "ok"
+	UpstreamDegraded UpstreamState = "degraded" // The last request failed or was slow
+	UpstreamDown     UpstreamState = "down"     // Several requests in a row failed
+)
+
+// UpstreamStatus is the health of the Hyperliquid API as seen by the status
+// probe and every other request
+type UpstreamStatus struct {
+	State               UpstreamState `json:"state"`
+	LastCheckAt         *time.Time    `json:"lastCheckAt,omitempty"`
+	LastSuccessAt       *time.Time    `json:"lastSuccessAt,omitempty"`
+	LatencyMs           int64         `json:"latencyMs"` // Of the last request
+	ConsecutiveFailures int           `json:"consecutiveFailures"`
+	LastError           string        `json:"lastError,omitempty"`
+}
+
+// AddressFetchStatus is when an address's trades were last fetched successfully
+type AddressFetchStatus struct {
+	Address     string    `json:"address"`
+	Label       string    `json:"label,omitempty"`
+	LastFetchAt time.Time `json:"lastFetchAt"` // End of the last fetched window
+	AgeSeconds  int64     `json:"ageSeconds"`
+	Stale       bool      `json:"stale"`    // Older than FetchStaleAfter
+	Imported    bool      `json:"imported"` // Only imported so far, never fetched
+}
+
+// ServiceStatus is the upstream health and fetch freshness returned by /api/status
+type ServiceStatus struct {
+	Degraded  bool                 `json:"degraded"` // Data may be stale because the API is not answering normally
+	Upstream  UpstreamStatus       `json:"upstream"`
+	Addresses []AddressFetchStatus `json:"addresses"`
+	CheckedAt time.Time            `json:"checkedAt"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// SymbolMapping names one coin across sources: the symbol in Hyperliquid fills,
+// the tickers other sources use, and the canonical name trades are grouped,
+// reported and exported under
+type SymbolMapping struct {
+	Canonical string `yaml:"canonical" json:"canonical"`
+	// Hyperliquid is the symbol in the exchange's fills, e.g. "kPEPE" or a spot
+	// index such as "@107"; the canonical name if empty
+	Hyperliquid string `yaml:"hyperliquid" json:"hyperliquid,omitempty"`
+	// Multiplier is how many canonical units one Hyperliquid unit is, e.g. 1000
+	// for kPEPE; sizes are multiplied and prices divided by it. 1 if unset.
+	Multiplier float64 `yaml:"multiplier" json:"multiplier,omitempty"`
+	// Aliases are tickers other sources use for the coin, in canonical units
+	Aliases []string `yaml:"aliases" json:"aliases,omitempty"`
+}
+
+// SymbolChangeKind is why a symbol's earlier fills are grouped under another name
+type SymbolChangeKind string
+
+const (
+	// SymbolRenamed means the coin trades under a new symbol from the effective time
+	SymbolRenamed SymbolChangeKind = "rename"
+	// SymbolDelisted means the coin stopped trading at the effective time; a
+	// later listing under the same symbol is a different coin
+	SymbolDelisted SymbolChangeKind = "delisting"
+)
+
+// SymbolChange groups the fills of Symbol before Effective under GroupAs, so a
+// renamed coin's history aggregates with its fills under the new symbol and a
+// delisted coin's history stays apart from a later coin reusing the symbol
+type SymbolChange struct {
+	ID        string           `json:"id"`
+	Symbol    string           `json:"symbol"` // As in Hyperliquid fills
+	Kind      SymbolChangeKind `json:"kind"`
+	GroupAs   string           `json:"groupAs"` // The new symbol of a renamed coin; "<symbol> (delisted)" by default for a delisting
+	Effective time.Time        `json:"effective"`
+	Note      string           `json:"note,omitempty"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// TapeEntry is one upstream request and its response, as recorded on a tape.
+// Tapes are JSON Lines files with one entry per line.
+type TapeEntry struct {
+	Request    TapeRequest  `json:"request"`
+	Response   TapeResponse `json:"response"`
+	RecordedAt time.Time    `json:"recordedAt"`
+}
+
+// TapeRequest is a recorded upstream request
+type TapeRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// TapeResponse is a recorded upstream response. JSON bodies are kept as JSON
+// so tapes stay readable; anything else is kept as text.
+type TapeResponse struct {
+	Status int             `json:"status"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+}
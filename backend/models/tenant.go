@@ -0,0 +1,46 @@
+package models
+
+// TenantConfig describes one tenant (e.g. a trading desk) of a hosted
+// deployment. Every tenant gets its own caches, schedules, alerts and storage.
+type TenantConfig struct {
+	ID      string   `json:"id"`
+	APIKeys []string `json:"apiKeys,omitempty"`
+	// APIKeySecrets names secrets holding further API keys, so keys need not be stored in the file
+	APIKeySecrets []string `json:"apiKeySecrets,omitempty"`
+	// Roles are further API keys whose refreshes are limited; keys in APIKeys are not
+	Roles []KeyRole `json:"roles,omitempty"`
+	// DataDir holds the tenant's address book, breaks, closes, periods, ledger, event, run, snapshot and reconciliation files; state is kept in memory if unset
+	DataDir             string   `json:"dataDir,omitempty"`
+	ReportsFile         string   `json:"reportsFile,omitempty"`
+	EODReportTo         []string `json:"eodReportTo,omitempty"`
+	EODWebhookURL       string   `json:"eodWebhookUrl,omitempty"`
+	SheetsSpreadsheetID string   `json:"sheetsSpreadsheetId,omitempty"`
+
+	// Storage locations, derived from DataDir for hosted tenants
+	AddressBookFile     string `json:"-"`
+	BreaksFile          string `json:"-"`
+	ClosesFile          string `json:"-"`
+	PeriodsFile         string `json:"-"`
+	LedgerFile          string `json:"-"`
+	EventsFile          string `json:"-"`
+	RunsFile            string `json:"-"`
+	SnapshotsFile       string `json:"-"`
+	ReconciliationsFile string `json:"-"`
+	ExportTemplatesFile string `json:"-"`
+	ViewsFile           string `json:"-"`
+	QuarantineFile      string `json:"-"`
+	S3Prefix            string `json:"-"`
+}
+
+// KeyRole limits how much history the API keys given the role may refresh and
+// how often, so a shared deployment can give analysts read-heavy but
+// fetch-light access. Its keys can read everything the tenant's other keys can.
+type KeyRole struct {
+	Name          string   `json:"name"`
+	APIKeys       []string `json:"apiKeys,omitempty"`
+	APIKeySecrets []string `json:"apiKeySecrets,omitempty"`
+	MaxDays       int      `json:"maxDays,omitempty"` // Longest history a refresh may cover; unlimited if 0
+	// MinRefreshInterval is the least time between refreshes of an address by
+	// the role's keys, e.g. "15m"; unlimited if unset
+	MinRefreshInterval string `json:"minRefreshInterval,omitempty"`
+}
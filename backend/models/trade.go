@@ -3,22 +3,124 @@ package models
 import "time"
 
 type Trade struct {
-	Time      time.Time `json:"time"`
-	Coin      string    `json:"coin"`
-	Side      string    `json:"side"` // "B" for buy, "A" for sell
-	Price     float64   `json:"px"`
-	Size      float64   `json:"sz"`
-	Value     float64   `json:"value"`
+	Time  time.Time `json:"time"`
+	Coin  string    `json:"coin"`
+	Side  string    `json:"side"` // "B" for buy, "A" for sell
+	Price Money     `json:"px"`
+	Size  Quantity  `json:"sz"`
+	Value Money     `json:"value"`
+	Fee   Money     `json:"fee"` // Fee paid in USDC; negative for rebates
+	// StartPosition is the exchange-reported position in Coin before this fill;
+	// nil for trades imported from files that don't record it
+	StartPosition *Position `json:"startPosition,omitempty"`
+	// RealizedPnL is the P&L this fill realized: the exchange-reported closedPnl
+	// for fetched fills, or matched against the coin's average entry for
+	// imported trades that don't record it (see AttributeRealizedPnL)
+	RealizedPnL *Money `json:"realizedPnl,omitempty"`
 }
 
 type DailyPnL struct {
-	Date          string  `json:"date"`
-	TradeCount    int     `json:"tradeCount"`
-	DailyPnL      float64 `json:"dailyPnL"`
-	CumulativePnL float64 `json:"cumulativePnL"`
+	Date          string `json:"date"`
+	TradeCount    int    `json:"tradeCount"`
+	Volume        Money  `json:"volume"` // Total value of the day's trades
+	DailyPnL      Money  `json:"dailyPnL"`
+	CumulativePnL Money  `json:"cumulativePnL"`
+	// ReturnPct is DailyPnL as a percentage of the capital at the start of the
+	// day; set only when capital is configured for the address
+	ReturnPct *float64 `json:"returnPct,omitempty"`
+	// CumulativeReturnPct compounds the daily returns up to and including this day
+	CumulativeReturnPct *float64 `json:"cumulativeReturnPct,omitempty"`
 }
 
+// TimeRange is a closed time window, used to describe data that was not fetched
+type TimeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// DayBasis is the calendar P&L is bucketed into days by
+type DayBasis string
+
+const (
+	// DayLocal buckets by the server's local calendar day, the trade date
+	DayLocal DayBasis = "local"
+	// DayUTC buckets by UTC day, the exchange's funding and settlement day, so
+	// records line up with exchange statements
+	DayUTC DayBasis = "utc"
+)
+
+// PnLSummary is the reconciled P&L for the most recent refresh. The coverage
+// fields tell consumers which period was actually fetched, so a day without
+// records inside the coverage window had no trades, while a day outside it
+// (or inside a missing range) was never fetched.
 type PnLSummary struct {
-	DailyRecords []DailyPnL `json:"dailyRecords"`
-	TotalPnL     float64    `json:"totalPnL"`
-}
\ No newline at end of file
+	Address         string      `json:"address,omitempty"`
+	Label           string      `json:"label,omitempty"` // Address book label for Address, if saved
+	DayBasis        DayBasis    `json:"dayBasis"`        // Calendar the daily records are bucketed by
+	DailyRecords    []DailyPnL  `json:"dailyRecords"`
+	TotalPnL        Money       `json:"totalPnL"`
+	CoverageStart   *time.Time  `json:"coverageStart,omitempty"`
+	CoverageEnd     *time.Time  `json:"coverageEnd,omitempty"`
+	LastRefreshedAt *time.Time  `json:"lastRefreshedAt,omitempty"`
+	AsOf            *time.Time  `json:"asOf,omitempty"` // Set when the summary was rebuilt as it stood at a past instant
+	Incomplete      bool        `json:"incomplete"`
+	Degraded        bool        `json:"degraded"` // The Hyperliquid API is not answering normally, so the data may be stale
+	MissingRanges   []TimeRange `json:"missingRanges,omitempty"`
+	// PositionGaps lists coin/days where reported start positions show missing or duplicated fills
+	PositionGaps []CoinDayGaps `json:"positionGaps,omitempty"`
+	// Windows summarizes performance over standard trailing periods
+	Windows []PerformanceWindow `json:"windows"`
+	// Stats are day-level trading statistics over all DailyRecords
+	Stats PerformanceStats `json:"stats"`
+	// CapitalSource is how returns are measured: "fixed" or "accountValue"; empty without capital
+	CapitalSource string `json:"capitalSource,omitempty"`
+	// BaseCapital is the capital at the start of the first day with a return
+	BaseCapital    *Money   `json:"baseCapital,omitempty"`
+	TotalReturnPct *float64 `json:"totalReturnPct,omitempty"`
+	// VaultPnL is the P&L of the address's vault deposits, such as HLP, as of
+	// the last ledger sync. It isn't part of TotalPnL.
+	VaultPnL *float64 `json:"vaultPnl,omitempty"`
+	// DataQuality counts quarantined fills and recent data-quality alerts of the address
+	DataQuality DataQualitySummary `json:"dataQuality"`
+}
+
+// PerformanceStats are trading statistics over daily P&L, treating each
+// trading day as one outcome. Ratios that would divide by zero are omitted.
+type PerformanceStats struct {
+	TradingDays int     `json:"tradingDays"`
+	WinningDays int     `json:"winningDays"`
+	LosingDays  int     `json:"losingDays"`
+	WinRate     float64 `json:"winRate"`
+	AvgWin      float64 `json:"avgWin"`  // Average P&L of winning days
+	AvgLoss     float64 `json:"avgLoss"` // Average P&L of losing days; negative
+	LargestWin  float64 `json:"largestWin"`
+	LargestLoss float64 `json:"largestLoss"`
+	// ProfitFactor is gross profit divided by gross loss
+	ProfitFactor *float64 `json:"profitFactor,omitempty"`
+	// PayoffRatio is AvgWin divided by the size of AvgLoss
+	PayoffRatio *float64 `json:"payoffRatio,omitempty"`
+	// Expectancy is the expected P&L of a trading day
+	Expectancy float64 `json:"expectancy"`
+	// Kelly is the Kelly fraction WinRate - (1-WinRate)/PayoffRatio; negative means no edge
+	Kelly             *float64 `json:"kelly,omitempty"`
+	LongestWinStreak  int      `json:"longestWinStreak"`  // Consecutive winning trading days
+	LongestLossStreak int      `json:"longestLossStreak"` // Consecutive losing trading days
+	CurrentStreak     int      `json:"currentStreak"`     // Positive for wins, negative for losses
+}
+
+// PerformanceWindow summarizes the daily records of the trailing Days days,
+// today included. Complete is false when the coverage doesn't reach back to
+// Start, so some of the window was never fetched.
+type PerformanceWindow struct {
+	Name        string  `json:"name"` // e.g. "7d"
+	Days        int     `json:"days"`
+	Start       string  `json:"start"` // First date of the window (YYYY-MM-DD)
+	PnL         float64 `json:"pnl"`
+	TradeCount  int     `json:"tradeCount"`
+	Volume      float64 `json:"volume"`
+	TradingDays int     `json:"tradingDays"` // Days with trades
+	WinningDays int     `json:"winningDays"`
+	WinRate     float64 `json:"winRate"`     // Share of trading days with positive P&L
+	AvgDailyPnL float64 `json:"avgDailyPnL"` // P&L per trading day
+	Complete    bool    `json:"complete"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// VaultValue is an address's equity in a vault, such as HLP, at a point in time
+type VaultValue struct {
+	Time   time.Time `json:"time"`
+	Vault  string    `json:"vault"`
+	Equity float64   `json:"equity"`
+}
+
+// VaultPosition is an address's stake in one vault. Cost is the basis of the
+// equity still deposited; realized P&L was made on withdrawals, unrealized
+// P&L is equity above cost.
+type VaultPosition struct {
+	Vault         string    `json:"vault"`
+	Equity        float64   `json:"equity"`
+	Cost          float64   `json:"cost"`
+	RealizedPnL   float64   `json:"realizedPnl"`
+	UnrealizedPnL float64   `json:"unrealizedPnl"`
+	PnL           float64   `json:"pnl"`
+	ValuedAt      time.Time `json:"valuedAt"` // When Equity was recorded; zero if never
+}
+
+// Kinds of staking actions
+const (
+	StakingDeposit    = "deposit"    // HYPE moved from spot to staking
+	StakingWithdrawal = "withdrawal" // HYPE moved from staking back to spot
+	StakingDelegate   = "delegate"
+	StakingUndelegate = "undelegate"
+)
+
+// StakingAction is an address's staking deposit, withdrawal or delegation, in HYPE
+type StakingAction struct {
+	Time      time.Time `json:"time"`
+	Hash      string    `json:"hash,omitempty"`
+	Kind      string    `json:"kind"`
+	Validator string    `json:"validator,omitempty"` // Delegations only
+	Amount    float64   `json:"amount"`
+}
+
+// VaultSummary is an address's vault positions, their value history and its
+// staking actions, returned by /api/ledger/vaults
+type VaultSummary struct {
+	Address string          `json:"address"`
+	Label   string          `json:"label,omitempty"`
+	Vaults  []VaultPosition `json:"vaults"` // Sorted by vault
+	Equity  float64         `json:"equity"`
+	PnL     float64         `json:"pnl"`
+	History []VaultValue    `json:"history"` // Oldest first
+	Staking []StakingAction `json:"staking"` // Oldest first
+	Staked  float64         `json:"staked"`  // HYPE deposited to staking less withdrawn
+}
@@ -0,0 +1,14 @@
+package models
+
+// APIVersion is one version of the HTTP API and the path prefix it is served under
+type APIVersion struct {
+	Version string `json:"version"`
+	Prefix  string `json:"prefix"`
+	Status  string `json:"status"` // "current" or "deprecated"
+}
+
+// APIVersions lists the API versions the server answers, for clients to pick one
+type APIVersions struct {
+	Current  string       `json:"current"`
+	Versions []APIVersion `json:"versions"`
+}
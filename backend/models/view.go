@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// View is a saved set of report parameters that requests apply with
+// ?view=<name>, so a team reads reports defined the same way
+type View struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Addresses   []string  `json:"addresses,omitempty"`
+	From        string    `json:"from,omitempty"` // YYYY-MM-DD
+	To          string    `json:"to,omitempty"`   // YYYY-MM-DD, inclusive
+	Days        int       `json:"days,omitempty"` // Trailing days; an alternative to From and To
+	Coins       []string  `json:"coins,omitempty"`
+	Grouping    string    `json:"grouping,omitempty"` // day, week or month
+	DayBasis    DayBasis  `json:"dayBasis,omitempty"`
+	Currency    string    `json:"currency,omitempty"` // USD; reports are in USDC
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// Errors returned when an address book input can't be turned into an address
+var (
+	ErrInvalidAddress   = errors.New("not a valid address, ENS name or saved label")
+	ErrENSNotConfigured = errors.New("ENS resolution is not configured")
+)
+
+// ErrAddressNotSaved is returned when changing settings of an address that isn't in the address book
+var ErrAddressNotSaved = errors.New("address is not in the address book")
+
+// AddressBook keeps friendly labels for addresses. Entries are persisted as JSON
+// to path if one is set; ENS names are resolved through ens if it is set.
+type AddressBook struct {
+	entries map[string]*models.AddressEntry // key: lowercase address
+	mu      sync.RWMutex
+	path    string
+	ens     *ENSResolver
+}
+
+// NewAddressBook creates an address book, loading saved entries from path if it exists
+func NewAddressBook(path string, ens *ENSResolver) (*AddressBook, error) {
+	ab := &AddressBook{
+		entries: make(map[string]*models.AddressEntry),
+		path:    path,
+		ens:     ens,
+	}
+	if path == "" {
+		return ab, nil
+	}
+
+	data, err := readStoreFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ab, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read address book: %w", err)
+	}
+
+	var entries []models.AddressEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse address book: %w", err)
+	}
+	for i := range entries {
+		ab.entries[strings.ToLower(entries[i].Address)] = &entries[i]
+	}
+
+	return ab, nil
+}
+
+// Resolve turns user input into a lowercase address. Input may be a hex
+// address, a saved label (case-insensitive) or an ENS name.
+func (ab *AddressBook) Resolve(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if addressPattern.MatchString(input) {
+		return strings.ToLower(input), nil
+	}
+
+	ab.mu.RLock()
+	for address, entry := range ab.entries {
+		if strings.EqualFold(entry.Label, input) || strings.EqualFold(entry.ENSName, input) {
+			ab.mu.RUnlock()
+			return address, nil
+		}
+	}
+	ab.mu.RUnlock()
+
+	if !strings.Contains(input, ".") {
+		return "", ErrInvalidAddress
+	}
+	if ab.ens == nil {
+		return "", ErrENSNotConfigured
+	}
+	return ab.ens.Resolve(input)
+}
+
+// Save resolves input and stores it under label, replacing any existing entry for the address
+func (ab *AddressBook) Save(input, label string) (models.AddressEntry, error) {
+	address, err := ab.Resolve(input)
+	if err != nil {
+		return models.AddressEntry{}, err
+	}
+
+	entry := &models.AddressEntry{
+		Address:   address,
+		Label:     strings.TrimSpace(label),
+		UpdatedAt: time.Now(),
+	}
+	if !addressPattern.MatchString(strings.TrimSpace(input)) {
+		entry.ENSName = strings.ToLower(strings.TrimSpace(input))
+	}
+
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	// Keep the ENS name when relabelling by address, and the capital and time zone settings always
+	if existing, ok := ab.entries[address]; ok {
+		if entry.ENSName == "" {
+			entry.ENSName = existing.ENSName
+		}
+		entry.BaseCapital, entry.DeriveCapital = existing.BaseCapital, existing.DeriveCapital
+		entry.Timezone = existing.Timezone
+	}
+	ab.entries[address] = entry
+
+	return *entry, ab.persist()
+}
+
+// SetCapital sets what returns of a saved address are measured against: its
+// account value history if derive is set, otherwise baseCapital, or nothing if
+// baseCapital is nil
+func (ab *AddressBook) SetCapital(address string, baseCapital *models.Money, derive bool) (models.AddressEntry, error) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	entry, ok := ab.entries[strings.ToLower(address)]
+	if !ok {
+		return models.AddressEntry{}, ErrAddressNotSaved
+	}
+
+	updated := *entry
+	updated.BaseCapital, updated.DeriveCapital = baseCapital, derive
+	if derive {
+		updated.BaseCapital = nil
+	}
+	updated.UpdatedAt = time.Now()
+	ab.entries[updated.Address] = &updated
+
+	return updated, ab.persist()
+}
+
+// SetTimezone sets the IANA time zone the days of a saved address are
+// bucketed in, or clears it if timezone is empty
+func (ab *AddressBook) SetTimezone(address, timezone string) (models.AddressEntry, error) {
+	timezone = strings.TrimSpace(timezone)
+	if _, err := loadTimezone(timezone); err != nil {
+		return models.AddressEntry{}, err
+	}
+
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	entry, ok := ab.entries[strings.ToLower(address)]
+	if !ok {
+		return models.AddressEntry{}, ErrAddressNotSaved
+	}
+
+	updated := *entry
+	updated.Timezone = timezone
+	updated.UpdatedAt = time.Now()
+	ab.entries[updated.Address] = &updated
+
+	return updated, ab.persist()
+}
+
+// Delete removes the entry for address and reports whether it existed
+func (ab *AddressBook) Delete(address string) (bool, error) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	address = strings.ToLower(address)
+	if _, ok := ab.entries[address]; !ok {
+		return false, nil
+	}
+	delete(ab.entries, address)
+
+	return true, ab.persist()
+}
+
+// List returns all entries sorted by label
+func (ab *AddressBook) List() []models.AddressEntry {
+	ab.mu.RLock()
+	defer ab.mu.RUnlock()
+
+	entries := make([]models.AddressEntry, 0, len(ab.entries))
+	for _, entry := range ab.entries {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].Label) < strings.ToLower(entries[j].Label)
+	})
+	return entries
+}
+
+// Label returns the label saved for address, or "" if there is none.
+// It is safe to call on a nil address book.
+func (ab *AddressBook) Label(address string) string {
+	if ab == nil {
+		return ""
+	}
+
+	ab.mu.RLock()
+	defer ab.mu.RUnlock()
+
+	if entry, ok := ab.entries[strings.ToLower(address)]; ok {
+		return entry.Label
+	}
+	return ""
+}
+
+// Entry returns the entry saved for address. It is safe to call on a nil address book.
+func (ab *AddressBook) Entry(address string) (models.AddressEntry, bool) {
+	if ab == nil {
+		return models.AddressEntry{}, false
+	}
+
+	ab.mu.RLock()
+	defer ab.mu.RUnlock()
+
+	entry, ok := ab.entries[strings.ToLower(address)]
+	if !ok {
+		return models.AddressEntry{}, false
+	}
+	return *entry, true
+}
+
+// persist writes all entries to the address book file; caller must hold ab.mu
+func (ab *AddressBook) persist() error {
+	if ab.path == "" {
+		return nil
+	}
+
+	entries := make([]models.AddressEntry, 0, len(ab.entries))
+	for _, entry := range ab.entries {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Address < entries[j].Address })
+
+	return writeJSONFile(ab.path, entries)
+}
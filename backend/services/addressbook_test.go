@@ -0,0 +1,142 @@
+package services
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test namehash against the EIP-137 reference values
+func TestNamehash(t *testing.T) {
+	tests := map[string]string{
+		"":        "0000000000000000000000000000000000000000000000000000000000000000",
+		"eth":     "93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae",
+		"foo.eth": "de9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f",
+	}
+
+	for name, expected := range tests {
+		node := namehash(name)
+		if got := hex.EncodeToString(node[:]); got != expected {
+			t.Errorf("namehash(%q) = %s, expected %s", name, got, expected)
+		}
+	}
+}
+
+// newTestENSServer serves eth_call, answering the registry with a resolver and the resolver with address
+func newTestENSServer(t *testing.T, address string) *httptest.Server {
+	const resolver = "0x4976fb03c32e5b8cfe2b6ccb31c09ba78ebaba41"
+	word := func(addr string) string {
+		return "0x" + strings.Repeat("0", 24) + strings.TrimPrefix(addr, "0x")
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var call struct {
+			To   string `json:"to"`
+			Data string `json:"data"`
+		}
+		json.Unmarshal(req.Params[0], &call)
+
+		result := word("0x0000000000000000000000000000000000000000")
+		switch {
+		case strings.EqualFold(call.To, ensRegistry) && strings.HasPrefix(call.Data, "0x"+selectorResolver):
+			result = word(resolver)
+		case call.To == resolver && strings.HasPrefix(call.Data, "0x"+selectorAddr) && address != "":
+			result = word(address)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+}
+
+// Test AddressBook resolution, labels and persistence
+func TestAddressBook(t *testing.T) {
+	t.Run("should resolve hex addresses, labels and ENS names", func(t *testing.T) {
+		server := newTestENSServer(t, testAddress)
+		defer server.Close()
+
+		ab, _ := NewAddressBook("", NewENSResolver(server.URL, time.Second))
+
+		if address, err := ab.Resolve(testAddress[:20]); err == nil {
+			t.Errorf("Expected malformed input to be rejected, got %s", address)
+		}
+		if address, _ := ab.Resolve("0x091144E651B334341EABDBBBFED644AD0100023E"); address != testAddress {
+			t.Errorf("Expected hex address to be lowercased, got %s", address)
+		}
+
+		entry, err := ab.Save("Trader.eth", "Main account")
+		if err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		if entry.Address != testAddress || entry.ENSName != "trader.eth" {
+			t.Errorf("Expected ENS name to resolve to %s, got %+v", testAddress, entry)
+		}
+
+		if address, _ := ab.Resolve("main account"); address != testAddress {
+			t.Errorf("Expected label to resolve, got %s", address)
+		}
+		if label := ab.Label(strings.ToUpper(testAddress)); label != "Main account" {
+			t.Errorf("Expected label lookup to ignore case, got %q", label)
+		}
+	})
+
+	t.Run("should report unknown ENS names and missing configuration", func(t *testing.T) {
+		server := newTestENSServer(t, "")
+		defer server.Close()
+
+		ab, _ := NewAddressBook("", NewENSResolver(server.URL, time.Second))
+		if _, err := ab.Resolve("nobody.eth"); !errors.Is(err, ErrENSNotFound) {
+			t.Errorf("Expected ErrENSNotFound, got %v", err)
+		}
+
+		ab, _ = NewAddressBook("", nil)
+		if _, err := ab.Resolve("trader.eth"); !errors.Is(err, ErrENSNotConfigured) {
+			t.Errorf("Expected ErrENSNotConfigured, got %v", err)
+		}
+		if _, err := ab.Resolve("not an address"); !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("Expected ErrInvalidAddress, got %v", err)
+		}
+	})
+
+	t.Run("should persist entries across restarts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "addressbook.json")
+
+		ab, _ := NewAddressBook(path, nil)
+		ab.Save(testAddress, "Main account")
+
+		reloaded, err := NewAddressBook(path, nil)
+		if err != nil {
+			t.Fatalf("Reload failed: %v", err)
+		}
+		if label := reloaded.Label(testAddress); label != "Main account" {
+			t.Errorf("Expected persisted label, got %q", label)
+		}
+
+		reloaded.Delete(testAddress)
+		reloaded, _ = NewAddressBook(path, nil)
+		if len(reloaded.List()) != 0 {
+			t.Errorf("Expected deleted entry to stay deleted")
+		}
+	})
+
+	t.Run("should label summaries", func(t *testing.T) {
+		ab, _ := NewAddressBook("", nil)
+		ab.Save(testAddress, "Main account")
+
+		rs := NewReconciliationService()
+		rs.UseAddressBook(ab)
+		rs.publish(func(s *summarySnapshot) { s.address = testAddress })
+
+		if summary := rs.GetPnLSummary(); summary.Label != "Main account" {
+			t.Errorf("Expected summary label, got %q", summary.Label)
+		}
+	})
+}
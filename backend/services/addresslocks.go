@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// addressLocks serializes work on each address's cache while letting work on
+// different addresses run in parallel. Waiters for an address are served in
+// the order they arrived.
+type addressLocks struct {
+	locks map[string]*addressLock // key: address; removed once no one holds or waits for it
+	mu    sync.Mutex
+}
+
+// addressLock is held by whoever has put a token in held. Blocked senders on a
+// channel are woken first-in first-out, which queues the waiters.
+type addressLock struct {
+	held chan struct{}
+	refs int // Holder and waiters
+}
+
+func newAddressLocks() *addressLocks {
+	return &addressLocks{locks: make(map[string]*addressLock)}
+}
+
+// lock waits until address is free or ctx is done, and returns the function
+// that releases it
+func (l *addressLocks) lock(ctx context.Context, address string) (func(), error) {
+	l.mu.Lock()
+	lock, exists := l.locks[address]
+	if !exists {
+		lock = &addressLock{held: make(chan struct{}, 1)}
+		l.locks[address] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	select {
+	case lock.held <- struct{}{}:
+		return func() {
+			<-lock.held
+			l.release(address, lock)
+		}, nil
+	case <-ctx.Done():
+		l.release(address, lock)
+		return nil, ctx.Err()
+	}
+}
+
+// release drops a reference to lock, removing it once unused
+func (l *addressLocks) release(address string, lock *addressLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, address)
+	}
+}
+
+// waiting returns the number of callers holding or waiting for address
+func (l *addressLocks) waiting(address string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if lock, exists := l.locks[address]; exists {
+		return lock.refs
+	}
+	return 0
+}
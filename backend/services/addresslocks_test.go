@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrencyServer answers fill requests with one fill per request after a
+// pause, recording how many requests were in flight at once in total and per user
+type concurrencyServer struct {
+	*httptest.Server
+	mu          sync.Mutex
+	inFlight    map[string]int
+	maxPerUser  map[string]int
+	total       int
+	maxTotal    int
+	requests    int
+	pause       time.Duration
+	waitForPeer bool // Hold each request until another is in flight, up to a second
+}
+
+func newConcurrencyServer(pause time.Duration, waitForPeer bool) *concurrencyServer {
+	s := &concurrencyServer{inFlight: make(map[string]int), maxPerUser: make(map[string]int), pause: pause, waitForPeer: waitForPeer}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request UserFillsRequest
+		json.NewDecoder(r.Body).Decode(&request)
+
+		s.mu.Lock()
+		s.requests++
+		fillTime := *request.EndTime - int64(s.requests)
+		s.inFlight[request.User]++
+		s.total++
+		s.maxPerUser[request.User] = max(s.maxPerUser[request.User], s.inFlight[request.User])
+		s.maxTotal = max(s.maxTotal, s.total)
+		s.mu.Unlock()
+
+		time.Sleep(s.pause)
+		for deadline := time.Now().Add(time.Second); s.waitForPeer && time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			s.mu.Lock()
+			peers := s.maxTotal
+			s.mu.Unlock()
+			if peers > 1 {
+				break
+			}
+		}
+
+		s.mu.Lock()
+		s.inFlight[request.User]--
+		s.total--
+		s.mu.Unlock()
+
+		json.NewEncoder(w).Encode([]FillResponse{{Time: fillTime, Coin: "ETH", Side: "B", Price: "100", Size: "1"}})
+	}))
+	return s
+}
+
+// Test concurrent refreshes of the same and of different addresses
+func TestConcurrentFetchAndReconcile(t *testing.T) {
+	other := "0x1111111111111111111111111111111111111111"
+
+	t.Run("should run refreshes of the same address one at a time", func(t *testing.T) {
+		server := newConcurrencyServer(20*time.Millisecond, false)
+		defer server.Close()
+		rs := NewReconciliationService()
+		rs.hlClient.apiURL = server.URL
+		// The server answers every window with a new fill, which revalidation would
+		// take as a correction of the previous one
+		rs.overlap = 0
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := rs.FetchAndReconcile(context.Background(), testAddress, 1); err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if server.maxPerUser[testAddress] != 1 {
+			t.Errorf("Expected one request at a time, got %d in flight", server.maxPerUser[testAddress])
+		}
+		// Every request returned a distinct fill, and none was lost to an interleaved merge
+		if trades, _ := rs.CachedTrades(testAddress); len(trades) != server.requests {
+			t.Errorf("Expected %d cached trades, got %d", server.requests, len(trades))
+		}
+	})
+
+	t.Run("should refresh different addresses in parallel", func(t *testing.T) {
+		server := newConcurrencyServer(0, true)
+		defer server.Close()
+		rs := NewReconciliationService()
+		rs.hlClient.apiURL = server.URL
+
+		var wg sync.WaitGroup
+		for _, address := range []string{testAddress, other} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := rs.FetchAndReconcile(context.Background(), address, 1); err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if server.maxTotal != 2 {
+			t.Errorf("Expected both addresses to be fetched at once, got at most %d in flight", server.maxTotal)
+		}
+		for _, address := range []string{testAddress, other} {
+			if trades, _ := rs.CachedTrades(address); len(trades) != 1 {
+				t.Errorf("Expected 1 cached trade for %s, got %d", address, len(trades))
+			}
+		}
+		if summary := rs.GetPnLSummary(); summary.Address != testAddress && summary.Address != other {
+			t.Errorf("Expected the summary of one of the addresses, got %q", summary.Address)
+		}
+	})
+}
+
+// Test that waiters for an address lock are served in the order they arrived
+func TestAddressLocksQueue(t *testing.T) {
+	locks := newAddressLocks()
+	unlock, _ := locks.lock(context.Background(), testAddress)
+
+	order := make(chan int, 3)
+	for i := 1; i <= 3; i++ {
+		go func() {
+			release, err := locks.lock(context.Background(), testAddress)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+				return
+			}
+			order <- i
+			release()
+		}()
+		// Wait for this waiter to queue before starting the next
+		for locks.waiting(testAddress) != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	t.Run("should time out waiting", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := locks.lock(ctx, testAddress); err != context.DeadlineExceeded {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+
+	t.Run("should not block other addresses", func(t *testing.T) {
+		release, err := locks.lock(context.Background(), "0x1111111111111111111111111111111111111111")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		release()
+	})
+
+	unlock()
+	for want := 1; want <= 3; want++ {
+		if got := <-order; got != want {
+			t.Errorf("Expected waiter %d next, got %d", want, got)
+		}
+	}
+	// The last waiter releases the lock just after reporting in
+	for deadline := time.Now().Add(time.Second); locks.waiting(testAddress) != 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	locks.mu.Lock()
+	defer locks.mu.Unlock()
+	if len(locks.locks) != 0 {
+		t.Error("Expected unused locks to be removed")
+	}
+}
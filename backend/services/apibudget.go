@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// upstreamUsage meters the Hyperliquid rate limit weight used by every client,
+// since they all draw on the same per-IP limit
+var upstreamUsage = newWeightMeter(int(config.APIBudgetPerHour), int(config.APIBudgetPerDay))
+
+// weightMeterHours is how many hours of usage are kept
+const weightMeterHours = 7 * 24
+
+// BudgetError is returned by refreshes refused because their estimated weight
+// exceeds what is left of the hourly or daily budget
+type BudgetError struct {
+	Period    string // "hour" or "day"
+	Estimated int
+	Remaining int
+	RetryAt   time.Time // When the period's budget is renewed
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("refresh would use an estimated weight of %d, but only %d of this %s's API budget is left", e.Estimated, e.Remaining, e.Period)
+}
+
+// weightMeter counts the rate limit weight used per clock hour, and admits
+// refreshes while their estimated weight fits in the hourly and daily budgets
+type weightMeter struct {
+	perHour int // 0 is unlimited
+	perDay  int
+	hours   map[int64]*models.WeightUsage // key: Unix time of the hour's start
+	mu      sync.Mutex
+}
+
+func newWeightMeter(perHour, perDay int) *weightMeter {
+	return &weightMeter{perHour: perHour, perDay: perDay, hours: make(map[int64]*models.WeightUsage)}
+}
+
+// hour returns the usage of the hour containing t, dropping hours too old to
+// keep. Caller must hold m.mu.
+func (m *weightMeter) hour(t time.Time) *models.WeightUsage {
+	start := t.Truncate(time.Hour)
+	usage, exists := m.hours[start.Unix()]
+	if !exists {
+		usage = &models.WeightUsage{Start: start}
+		m.hours[start.Unix()] = usage
+
+		for key := range m.hours {
+			if key <= start.Add(-weightMeterHours*time.Hour).Unix() {
+				delete(m.hours, key)
+			}
+		}
+	}
+	return usage
+}
+
+// day returns the usage of the UTC day containing t. Caller must hold m.mu.
+func (m *weightMeter) day(t time.Time) models.WeightUsage {
+	start := t.UTC().Truncate(24 * time.Hour)
+	total := models.WeightUsage{Start: start}
+	for _, usage := range m.hours {
+		if !usage.Start.Before(start) && usage.Start.Before(start.Add(24*time.Hour)) {
+			addUsage(&total, usage)
+		}
+	}
+	return total
+}
+
+// record counts requests made and the weight they used
+func (m *weightMeter) record(requests, weight int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage := m.hour(time.Now())
+	usage.Requests += requests
+	usage.Weight += weight
+}
+
+// admit counts a refresh estimated to use estimate, or refuses it with a
+// BudgetError if that is more than is left of the hour's or day's budget
+func (m *weightMeter) admit(estimate int, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hour := m.hour(now)
+	today := m.day(now)
+
+	var refusal *BudgetError
+	switch {
+	case m.perHour > 0 && hour.Weight+estimate > m.perHour:
+		refusal = &BudgetError{Period: "hour", Estimated: estimate, Remaining: max(m.perHour-hour.Weight, 0), RetryAt: hour.Start.Add(time.Hour)}
+	case m.perDay > 0 && today.Weight+estimate > m.perDay:
+		refusal = &BudgetError{Period: "day", Estimated: estimate, Remaining: max(m.perDay-today.Weight, 0), RetryAt: today.Start.Add(24 * time.Hour)}
+	}
+	if refusal != nil {
+		hour.Refused++
+		return refusal
+	}
+
+	hour.Refreshes++
+	hour.EstimatedWeight += estimate
+	return nil
+}
+
+// Usage returns the usage and budget as of now
+func (m *weightMeter) Usage(now time.Time) models.APIBudget {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	budget := models.APIBudget{
+		PerHour:     m.perHour,
+		PerDay:      m.perDay,
+		CurrentHour: *m.hour(now),
+		Today:       m.day(now),
+		Hours:       []models.WeightUsage{},
+		Days:        []models.WeightUsage{},
+	}
+
+	days := make(map[int64]*models.WeightUsage)
+	for _, usage := range m.hours {
+		if usage.Start.After(now.Add(-24 * time.Hour)) {
+			budget.Hours = append(budget.Hours, *usage)
+		}
+		dayStart := usage.Start.UTC().Truncate(24 * time.Hour)
+		day, exists := days[dayStart.Unix()]
+		if !exists {
+			day = &models.WeightUsage{Start: dayStart}
+			days[dayStart.Unix()] = day
+		}
+		addUsage(day, usage)
+	}
+	for _, day := range days {
+		budget.Days = append(budget.Days, *day)
+	}
+
+	sort.Slice(budget.Hours, func(i, j int) bool { return budget.Hours[i].Start.Before(budget.Hours[j].Start) })
+	sort.Slice(budget.Days, func(i, j int) bool { return budget.Days[i].Start.Before(budget.Days[j].Start) })
+	return budget
+}
+
+// addUsage adds usage to total
+func addUsage(total *models.WeightUsage, usage *models.WeightUsage) {
+	total.Requests += usage.Requests
+	total.Weight += usage.Weight
+	total.EstimatedWeight += usage.EstimatedWeight
+	total.Refreshes += usage.Refreshes
+	total.Refused += usage.Refused
+}
+
+// APIBudgetUsage returns the Hyperliquid rate limit weight used per hour and
+// day against the configured budget
+func APIBudgetUsage() models.APIBudget {
+	return upstreamUsage.Usage(time.Now())
+}
+
+// admitRefresh checks a refresh of the last days days of address against the
+// key policy of ctx and the API budget, returning a PolicyError or BudgetError
+// if it would exceed them, or ErrInvalidDays if days isn't positive
+func (rs *ReconciliationService) admitRefresh(ctx context.Context, address string, days int) error {
+	if days <= 0 {
+		return fmt.Errorf("%w, got %d", ErrInvalidDays, days)
+	}
+	if err := KeyPolicyFrom(ctx).CheckRefresh(address, days, time.Now()); err != nil {
+		return err
+	}
+	plan := rs.PlanRefresh(address, days)
+	if err := upstreamUsage.admit(plan.EstimatedWeight, time.Now()); err != nil {
+		log.Printf("Refused refresh of %s (days=%d): %v", address, days, err)
+		return err
+	}
+	return nil
+}
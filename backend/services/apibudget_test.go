@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"hyperliquid-recon/config"
+	"testing"
+	"time"
+)
+
+// Test metering rate limit weight against the hourly and daily budgets
+func TestWeightMeter(t *testing.T) {
+	t.Run("should refuse refreshes that exceed what is left of the hour", func(t *testing.T) {
+		m := newWeightMeter(100, 0)
+		now := time.Now()
+
+		if err := m.admit(60, now); err != nil {
+			t.Fatalf("Expected the first refresh to be admitted, got %v", err)
+		}
+		m.record(3, 60)
+
+		err := m.admit(60, now)
+		var overBudget *BudgetError
+		if !errors.As(err, &overBudget) {
+			t.Fatalf("Expected a BudgetError, got %v", err)
+		}
+		if overBudget.Period != "hour" || overBudget.Remaining != 40 || !overBudget.RetryAt.Equal(now.Truncate(time.Hour).Add(time.Hour)) {
+			t.Errorf("Expected 40 left until the next hour, got %+v", overBudget)
+		}
+
+		usage := m.Usage(now)
+		hour := usage.CurrentHour
+		if hour.Requests != 3 || hour.Weight != 60 || hour.EstimatedWeight != 60 || hour.Refreshes != 1 || hour.Refused != 1 {
+			t.Errorf("Unexpected usage: %+v", hour)
+		}
+		if len(usage.Hours) != 1 || len(usage.Days) != 1 || usage.Today.Weight != 60 {
+			t.Errorf("Expected the hour in the history, got %+v", usage)
+		}
+	})
+
+	t.Run("should count earlier hours of the day against the daily budget", func(t *testing.T) {
+		m := newWeightMeter(0, 100)
+		now := time.Date(2026, 1, 2, 12, 30, 0, 0, time.UTC)
+
+		m.mu.Lock()
+		m.hour(now.Add(-2 * time.Hour)).Weight = 90
+		m.hour(now.Add(-13 * time.Hour)).Weight = 500 // Yesterday
+		m.mu.Unlock()
+
+		var overBudget *BudgetError
+		if err := m.admit(20, now); !errors.As(err, &overBudget) || overBudget.Period != "day" || overBudget.Remaining != 10 {
+			t.Fatalf("Expected the daily budget to be exceeded with 10 left, got %v", err)
+		}
+		if err := m.admit(10, now); err != nil {
+			t.Errorf("Expected a refresh within the daily budget to be admitted, got %v", err)
+		}
+		if days := m.Usage(now).Days; len(days) != 2 || days[0].Weight != 500 {
+			t.Errorf("Expected yesterday and today, oldest first, got %+v", days)
+		}
+	})
+
+	t.Run("should record the weight of a refresh's requests", func(t *testing.T) {
+		fills := newTestSyntheticFills(t, 100)
+		UseHyperliquidTransport(fills)
+		defer UseHyperliquidTransport(nil)
+		SetRateLimitDelay(0)
+		defer SetRateLimitDelay(config.RateLimitDelay)
+
+		before := APIBudgetUsage().CurrentHour
+		rs := NewReconciliationService()
+		if err := rs.FetchAndReconcile(WithFreshResponses(context.Background()), testAddress, 2); err != nil {
+			t.Fatalf("Refresh failed: %v", err)
+		}
+		after := APIBudgetUsage().CurrentHour
+
+		if after.Requests <= before.Requests || after.Weight-before.Weight < config.InfoRequestWeight {
+			t.Errorf("Expected the refresh's requests to be recorded, got %+v then %+v", before, after)
+		}
+		if after.Refreshes != before.Refreshes+1 {
+			t.Errorf("Expected the refresh to be admitted, got %+v then %+v", before, after)
+		}
+	})
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrArchiveNotFound is returned for an archived response that doesn't exist
+var ErrArchiveNotFound = errors.New("archived response not found")
+
+// archiveExt ends the file name of every archived response
+const archiveExt = ".json.gz"
+
+// fillArchive keeps raw fills responses if set, shared by every tenant since
+// they share the responses too
+var fillArchive atomic.Pointer[FillArchive]
+
+// UseFillArchive archives every fills response received from now on to
+// archive; nil stops archiving
+func UseFillArchive(archive *FillArchive) {
+	fillArchive.Store(archive)
+}
+
+// CurrentFillArchive returns the fill archive in effect, or nil if responses aren't archived
+func CurrentFillArchive() *FillArchive {
+	return fillArchive.Load()
+}
+
+// FillArchive keeps userFillsByTime responses exactly as received, gzipped,
+// so any figure can be derived again from the original payloads. Each
+// response is a file <address>/<start>-<end>-<received>.json.gz of Unix
+// milliseconds under the archive directory, encrypted like the stores if a
+// storage key is in use.
+type FillArchive struct {
+	dir string
+}
+
+// NewFillArchive creates an archive in dir, creating the directory if needed
+func NewFillArchive(dir string) (*FillArchive, error) {
+	if err := os.MkdirAll(dir, dataDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create fill archive: %w", err)
+	}
+	return &FillArchive{dir: dir}, nil
+}
+
+// put archives the response body raw to a request for the fills of address
+// from start to end. It is safe to call on a nil archive.
+func (a *FillArchive) put(address string, start, end int64, received time.Time, raw []byte) {
+	if a == nil {
+		return
+	}
+	name := fmt.Sprintf("%d-%d-%d", start, end, received.UnixMilli())
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Name = name + ".json"
+	writer.ModTime = received
+	writer.Write(raw)
+	writer.Close()
+
+	dir := filepath.Join(a.dir, strings.ToLower(address))
+	err := os.MkdirAll(dir, dataDirPerm)
+	if err == nil {
+		err = writeStoreFile(filepath.Join(dir, name+archiveExt), compressed.Bytes())
+	}
+	if err != nil {
+		log.Printf("Failed to archive fills response for %s: %v", address, err)
+	}
+}
+
+// parseArchiveName reads the window and receipt time from the name of an archived response
+func parseArchiveName(name string) (start, end, received int64, ok bool) {
+	parts := strings.Split(name, "-")
+	if len(parts) != 3 {
+		return 0, 0, 0, false
+	}
+	var values [3]int64
+	for i, part := range parts {
+		value, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || value < 0 {
+			return 0, 0, 0, false
+		}
+		values[i] = value
+	}
+	return values[0], values[1], values[2], true
+}
+
+// List returns the archived responses of address whose windows overlap from
+// to to (either may be zero for no bound), oldest window first. It is safe to
+// call on a nil archive.
+func (a *FillArchive) List(address string, from, to time.Time) ([]models.ArchivedResponse, error) {
+	responses := []models.ArchivedResponse{}
+	if a == nil {
+		return responses, nil
+	}
+	address = strings.ToLower(address)
+	entries, err := os.ReadDir(filepath.Join(a.dir, address))
+	if errors.Is(err, os.ErrNotExist) {
+		return responses, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fill archive: %w", err)
+	}
+
+	for _, entry := range entries {
+		name, isArchive := strings.CutSuffix(entry.Name(), archiveExt)
+		start, end, received, ok := parseArchiveName(name)
+		if !isArchive || !ok {
+			continue
+		}
+		response := models.ArchivedResponse{
+			Name:       name,
+			Address:    address,
+			Start:      time.UnixMilli(start).UTC(),
+			End:        time.UnixMilli(end).UTC(),
+			ReceivedAt: time.UnixMilli(received).UTC(),
+		}
+		if (!from.IsZero() && response.End.Before(from)) || (!to.IsZero() && response.Start.After(to)) {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			response.Bytes = info.Size()
+		}
+		responses = append(responses, response)
+	}
+	sort.Slice(responses, func(i, j int) bool {
+		if !responses[i].Start.Equal(responses[j].Start) {
+			return responses[i].Start.Before(responses[j].Start)
+		}
+		return responses[i].ReceivedAt.Before(responses[j].ReceivedAt)
+	})
+	return responses, nil
+}
+
+// Open returns an archived response of address exactly as it was received
+func (a *FillArchive) Open(address, name string) ([]byte, error) {
+	if _, _, _, ok := parseArchiveName(name); !ok || a == nil {
+		return nil, ErrArchiveNotFound
+	}
+	data, err := readStoreFile(filepath.Join(a.dir, strings.ToLower(address), name+archiveExt))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrArchiveNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archived response: %w", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archived response: %w", err)
+	}
+	return io.ReadAll(reader)
+}
+
+// ArchivedResponses lists the archived responses of address, which must be
+// cached, whose windows overlap from to to. The archive is shared, so an
+// address this service doesn't cache is treated as unknown.
+func (rs *ReconciliationService) ArchivedResponses(address string, from, to time.Time) ([]models.ArchivedResponse, error) {
+	if _, exists := rs.cached(address); !exists {
+		return nil, ErrNotCached
+	}
+	return fillArchive.Load().List(address, from, to)
+}
+
+// ArchivedResponse returns an archived response of address, which must be cached, as received
+func (rs *ReconciliationService) ArchivedResponse(address, name string) ([]byte, error) {
+	if _, exists := rs.cached(address); !exists {
+		return nil, ErrNotCached
+	}
+	return fillArchive.Load().Open(address, name)
+}
+
+// AuditArchive derives the trades of address again from its archived
+// responses and compares them with its cache. Where a fill was archived more
+// than once, the latest response counts.
+func (rs *ReconciliationService) AuditArchive(address string) (models.ArchiveAudit, error) {
+	audit := models.ArchiveAudit{
+		Address:          address,
+		MissingFromCache: []models.Trade{},
+		NotArchived:      []models.Trade{},
+		Differing:        []models.ArchiveMismatch{},
+	}
+	cached, exists := rs.CachedTrades(address)
+	if !exists {
+		return audit, ErrNotCached
+	}
+	archive := fillArchive.Load()
+	responses, err := archive.List(address, time.Time{}, time.Time{})
+	if err != nil {
+		return audit, err
+	}
+	sort.SliceStable(responses, func(i, j int) bool { return responses[i].ReceivedAt.Before(responses[j].ReceivedAt) })
+	audit.Responses = len(responses)
+
+	key := func(trade models.Trade) string {
+		return fmt.Sprintf("%d_%s_%s", trade.Time.UnixMilli(), trade.Coin, trade.Side)
+	}
+	var derived []models.Trade // In the order received
+	for _, response := range responses {
+		raw, err := archive.Open(address, response.Name)
+		if err != nil {
+			return audit, err
+		}
+		var fills []FillResponse
+		if err := json.Unmarshal(raw, &fills); err != nil {
+			return audit, fmt.Errorf("archived response %s: %w", response.Name, err)
+		}
+		for _, fill := range fills {
+			trade, err := rs.hlClient.convertFillToTrade(fill)
+			if err != nil {
+				audit.Unparseable++
+				continue
+			}
+			derived = append(derived, trade)
+		}
+	}
+	// Compare under canonical coin names, as the cached trades are
+	canonicalize(derived)
+	archived := make(map[string]models.Trade)
+	for _, trade := range derived {
+		archived[key(trade)] = trade
+	}
+	audit.ArchivedFills = len(archived)
+
+	inCache := make(map[string]models.Trade, len(cached))
+	for _, trade := range cached {
+		inCache[key(trade)] = trade
+		if _, found := archived[key(trade)]; found {
+			continue
+		}
+		for _, response := range responses {
+			if !trade.Time.Before(response.Start) && !trade.Time.After(response.End) {
+				audit.NotArchived = append(audit.NotArchived, trade)
+				break
+			}
+		}
+	}
+	for _, trade := range sortedByTime(slices.Collect(maps.Values(archived))) {
+		switch cachedTrade, found := inCache[key(trade)]; {
+		case !found:
+			audit.MissingFromCache = append(audit.MissingFromCache, trade)
+		case sameFill(trade, cachedTrade):
+			audit.Matched++
+		default:
+			audit.Differing = append(audit.Differing, models.ArchiveMismatch{Archived: trade, Cached: cachedTrade})
+		}
+	}
+	return audit, nil
+}
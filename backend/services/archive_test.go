@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test archiving fills responses as received and auditing the cache against them
+func TestFillArchive(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	// Spacing and field order are kept exactly as sent
+	body := fmt.Sprintf(`[ {"time":%d,"coin":"ETH","side":"B","px":"3000","sz":"1","fee":"0.5"},
+  {"coin":"ETH","time":%d,"side":"A","px":"3100","sz":"1","fee":"0.5"} ]`,
+		start.Add(time.Hour).UnixMilli(), start.Add(2*time.Hour).UnixMilli())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	archive, err := NewFillArchive(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	UseFillArchive(archive)
+	defer UseFillArchive(nil)
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = server.URL
+	trades, err := rs.hlClient.FetchTradesInRange(context.Background(), testAddress, start, start.Add(24*time.Hour))
+	if err != nil || len(trades) != 2 {
+		t.Fatalf("Expected 2 trades, got %d (%v)", len(trades), err)
+	}
+
+	if _, err := rs.ArchivedResponses(testAddress, time.Time{}, time.Time{}); !errors.Is(err, ErrNotCached) {
+		t.Errorf("Expected ErrNotCached for an address the service doesn't cache, got %v", err)
+	}
+	rs.ImportTrades(testAddress, trades)
+
+	responses, err := rs.ArchivedResponses(testAddress, time.Time{}, time.Time{})
+	if err != nil || len(responses) != 1 || !responses[0].Start.Equal(start) || responses[0].Bytes == 0 {
+		t.Fatalf("Expected one archived response from %s, got %+v (%v)", start, responses, err)
+	}
+
+	t.Run("should return the response byte for byte", func(t *testing.T) {
+		raw, err := rs.ArchivedResponse(testAddress, responses[0].Name)
+		if err != nil || string(raw) != body {
+			t.Errorf("Expected the body as sent, got %q (%v)", raw, err)
+		}
+		if _, err := rs.ArchivedResponse(testAddress, "../../etc/passwd"); !errors.Is(err, ErrArchiveNotFound) {
+			t.Errorf("Expected ErrArchiveNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should filter responses by window", func(t *testing.T) {
+		later, _ := rs.ArchivedResponses(testAddress, start.AddDate(0, 0, 2), time.Time{})
+		if len(later) != 0 {
+			t.Errorf("Expected no responses overlapping later days, got %+v", later)
+		}
+	})
+
+	t.Run("should match the cache it was fetched into", func(t *testing.T) {
+		audit, err := rs.AuditArchive(testAddress)
+		if err != nil || audit.Matched != 2 || len(audit.MissingFromCache)+len(audit.NotArchived)+len(audit.Differing) != 0 {
+			t.Errorf("Expected both fills to match, got %+v (%v)", audit, err)
+		}
+	})
+
+	t.Run("should report cached trades that differ from the payloads", func(t *testing.T) {
+		changed := trades[1]
+		changed.Price, changed.Value = 3200, 3200
+		extra := models.Trade{Time: start.Add(3 * time.Hour), Coin: "ETH", Side: "B", Price: 3000, Size: 1, Value: 3000}
+		rs.ImportTrades(testAddress, []models.Trade{changed, extra})
+
+		audit, err := rs.AuditArchive(testAddress)
+		if err != nil {
+			t.Fatalf("Audit failed: %v", err)
+		}
+		if audit.Matched != 1 || len(audit.Differing) != 1 || audit.Differing[0].Archived.Price != 3100 {
+			t.Errorf("Expected the changed price to differ, got %+v", audit)
+		}
+		if len(audit.NotArchived) != 1 || !audit.NotArchived[0].Time.Equal(extra.Time) {
+			t.Errorf("Expected the extra trade not to be archived, got %+v", audit.NotArchived)
+		}
+	})
+}
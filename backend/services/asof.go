@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"time"
+)
+
+// ErrNoEventLog is returned by queries that need the event log when none is configured
+var ErrNoEventLog = errors.New("no event log is configured; set RECON_EVENTS_FILE")
+
+// SummaryAsOf returns the P&L summary of address as it stood at asOf, with days
+// bucketed by basis. The cache is rebuilt from the events recorded up to asOf
+// rather than read from the live cache, and only trades made by then count, so
+// a past report can be reproduced after later fetches, corrections and
+// invalidations. Returns ErrNoEventLog without an event log and ErrNotCached
+// if nothing had been fetched for address by then.
+func (rs *ReconciliationService) SummaryAsOf(address string, asOf time.Time, basis models.DayBasis) (models.PnLSummary, error) {
+	if rs.events == nil {
+		return models.PnLSummary{}, ErrNoEventLog
+	}
+
+	past := &ReconciliationService{accountCache: make(map[string]*AccountCache)}
+	var recordedAt time.Time
+	err := rs.events.Replay(func(event models.TradeEvent) {
+		if event.Address != address || event.RecordedAt.After(asOf) {
+			return
+		}
+		past.apply(event)
+		recordedAt = event.RecordedAt
+	})
+	if err != nil {
+		return models.PnLSummary{}, err
+	}
+
+	cache, exists := past.accountCache[address]
+	if !exists {
+		return models.PnLSummary{}, ErrNotCached
+	}
+
+	// Imports can hold trades newer than the instant asked for
+	trades := tradesInRange(cache.trades, models.TimeRange{End: asOf.Add(time.Millisecond)})
+	coverageEnd := cache.lastFetchTime
+	if coverageEnd.After(asOf) {
+		coverageEnd = asOf
+	}
+
+	var missing []models.TimeRange
+	for _, r := range rangesEndingAfter(cache.missingRanges, cache.coverageStart) {
+		if !r.Start.After(asOf) {
+			missing = append(missing, r)
+		}
+	}
+	discrepancies, _, _ := CheckStartPositions(trades)
+
+	// The account value history isn't in the log, but the latest fetch of it
+	// covers the past too
+	var accountValues []models.AccountValue
+	if current := rs.snapshot(); current.address == address {
+		for _, value := range current.accountValues {
+			if !value.Time.After(asOf) {
+				accountValues = append(accountValues, value)
+			}
+		}
+	}
+
+	summary := rs.summarize(&summarySnapshot{
+		dailyPnL:      rs.buildDailyPnL(trades),
+		dailyPnLUTC:   rs.buildDailyPnLIn(trades, time.UTC),
+		coverage:      models.TimeRange{Start: cache.coverageStart, End: coverageEnd},
+		missing:       missing,
+		positionGaps:  groupGaps(discrepancies),
+		accountValues: accountValues,
+		refreshedAt:   recordedAt,
+		address:       address,
+		days:          cache.cachedDays,
+	}, basis, asOf)
+	summary.AsOf = &asOf
+	return summary, nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"hyperliquid-recon/models"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test rebuilding a summary as it stood at a past instant
+func TestSummaryAsOf(t *testing.T) {
+	UseHyperliquidTransport(newTestSyntheticFills(t, 6))
+	defer UseHyperliquidTransport(nil)
+
+	previousDelay := RateLimitDelay()
+	SetRateLimitDelay(0)
+	defer SetRateLimitDelay(previousDelay)
+
+	rs := NewReconciliationService()
+	ctx := context.Background()
+
+	t.Run("should need the event log", func(t *testing.T) {
+		if _, err := rs.SummaryAsOf(testAddress, time.Now(), models.DayLocal); !errors.Is(err, ErrNoEventLog) {
+			t.Errorf("Expected ErrNoEventLog, got %v", err)
+		}
+	})
+
+	events, err := OpenEventStore(filepath.Join(t.TempDir(), "events.jsonl"))
+	if err != nil {
+		t.Fatalf("Failed to open event log: %v", err)
+	}
+	defer events.Close()
+	rs.UseEventStore(events)
+
+	before := time.Now()
+	if err := rs.FetchAndReconcile(ctx, testAddress, 2); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	reported := rs.GetPnLSummary()
+	asOf := time.Now()
+	time.Sleep(5 * time.Millisecond)
+
+	// Later, a day of the cache is invalidated
+	cache, _ := rs.cached(testAddress)
+	if _, err := rs.InvalidateRange(ctx, testAddress, cache.coverageStart, cache.coverageStart.Add(24*time.Hour)); err != nil {
+		t.Fatalf("Invalidation failed: %v", err)
+	}
+
+	t.Run("should reproduce the summary reported at the time", func(t *testing.T) {
+		summary, err := rs.SummaryAsOf(testAddress, asOf, models.DayLocal)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if summary.TotalPnL != reported.TotalPnL || len(summary.DailyRecords) != len(reported.DailyRecords) || summary.Incomplete {
+			t.Errorf("Expected total %v over %d days, got %v over %d days", reported.TotalPnL, len(reported.DailyRecords), summary.TotalPnL, len(summary.DailyRecords))
+		}
+		if summary.AsOf == nil || !summary.AsOf.Equal(asOf) {
+			t.Errorf("Expected asOf to be set, got %v", summary.AsOf)
+		}
+	})
+
+	t.Run("should include later events for later instants", func(t *testing.T) {
+		summary, err := rs.SummaryAsOf(testAddress, time.Now(), models.DayLocal)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !summary.Incomplete || len(summary.MissingRanges) != 1 {
+			t.Errorf("Expected the invalidated day to be missing, got %v", summary.MissingRanges)
+		}
+	})
+
+	t.Run("should report addresses not yet fetched", func(t *testing.T) {
+		if _, err := rs.SummaryAsOf(testAddress, before, models.DayLocal); !errors.Is(err, ErrNotCached) {
+			t.Errorf("Expected ErrNotCached, got %v", err)
+		}
+	})
+}
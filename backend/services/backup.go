@@ -0,0 +1,267 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrInvalidBackup is returned for a snapshot that is corrupt or wasn't written by WriteBackup
+var ErrInvalidBackup = errors.New("invalid backup")
+
+const (
+	backupVersion      = 1
+	backupManifestName = "manifest.json"
+)
+
+// BackupPaths returns where each file of a snapshot is kept in this
+// configuration, by its name in the archive: the config and tenants files
+// under settings/, the stores shared by all tenants under shared/ and each
+// tenant's stores and report subscriptions under tenants/<id>/. Files that
+// aren't configured are left out, as they are kept in memory.
+func BackupPaths(tenants []models.TenantConfig) map[string]string {
+	paths := make(map[string]string)
+	add := func(name, file string) {
+		if file != "" {
+			paths[name] = file
+		}
+	}
+
+	add("settings/config"+filepath.Ext(config.ConfigFile), config.ConfigFile)
+	add("settings/tenants.json", config.TenantsFile)
+	add("shared/symbol-history.json", config.SymbolHistoryFile)
+	add("shared/funding-rates.json", config.FundingRatesFile)
+	add("shared/market-context.json", config.MarketContextFile)
+
+	for _, tenant := range tenants {
+		prefix := "tenants/" + tenant.ID + "/"
+		for name, file := range storageFiles(&tenant) {
+			add(prefix+name, *file)
+		}
+		add(prefix+"reports"+filepath.Ext(tenant.ReportsFile), tenant.ReportsFile)
+	}
+	return paths
+}
+
+// WriteBackup writes a zstd-compressed tar of the files at paths that exist,
+// followed by a manifest of their checksums. Each file is read whole, so a
+// store saved while the backup runs is either before or after the save.
+// Event logs are copied through their open store in events (key: name in the
+// archive), if any, so no event being appended is included; otherwise they
+// are cut after their last complete line.
+func WriteBackup(w io.Writer, paths map[string]string, events map[string]*EventStore) (models.BackupManifest, error) {
+	manifest := models.BackupManifest{Version: backupVersion, CreatedAt: time.Now().UTC(), GitCommit: config.GitCommit, Files: []models.BackupFile{}}
+
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return manifest, err
+	}
+	tw := tar.NewWriter(zw)
+
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var data []byte
+		if store, ok := events[name]; ok && store != nil {
+			data, err = store.Snapshot()
+		} else {
+			data, err = os.ReadFile(paths[name])
+			if strings.HasSuffix(name, ".jsonl") {
+				data = data[:bytes.LastIndexByte(data, '\n')+1]
+			}
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return manifest, fmt.Errorf("failed to read %s: %w", paths[name], err)
+		}
+
+		if err := writeTarFile(tw, name, data, manifest.CreatedAt); err != nil {
+			return manifest, err
+		}
+		manifest.Files = append(manifest.Files, models.BackupFile{Name: name, Size: int64(len(data)), SHA256: sha256Hex(data)})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	if err := writeTarFile(tw, backupManifestName, data, manifest.CreatedAt); err != nil {
+		return manifest, err
+	}
+	if err := tw.Close(); err != nil {
+		return manifest, err
+	}
+	return manifest, zw.Close()
+}
+
+// writeTarFile adds one file to a tar archive
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0o640, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Backup is a snapshot read back by ReadBackup, with every file checked
+// against the manifest
+type Backup struct {
+	Manifest models.BackupManifest
+	files    map[string][]byte // key: name in the archive
+}
+
+// ReadBackup reads and verifies a snapshot written by WriteBackup
+func ReadBackup(r io.Reader) (*Backup, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	defer zr.Close()
+
+	b := &Backup{files: make(map[string][]byte)}
+	var manifest []byte
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+		if header.Typeflag != tar.TypeReg || !validBackupName(header.Name) {
+			return nil, fmt.Errorf("%w: unexpected entry %q", ErrInvalidBackup, header.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+		if header.Name == backupManifestName {
+			manifest = data
+		} else {
+			b.files[header.Name] = data
+		}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: no manifest", ErrInvalidBackup)
+	}
+	if err := json.Unmarshal(manifest, &b.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: manifest: %v", ErrInvalidBackup, err)
+	}
+	if b.Manifest.Version != backupVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidBackup, b.Manifest.Version)
+	}
+	if len(b.Manifest.Files) != len(b.files) {
+		return nil, fmt.Errorf("%w: manifest lists %d files, archive has %d", ErrInvalidBackup, len(b.Manifest.Files), len(b.files))
+	}
+	for _, file := range b.Manifest.Files {
+		data, ok := b.files[file.Name]
+		if !ok || int64(len(data)) != file.Size || sha256Hex(data) != file.SHA256 {
+			return nil, fmt.Errorf("%w: %s is missing or doesn't match its checksum", ErrInvalidBackup, file.Name)
+		}
+	}
+	return b, nil
+}
+
+// validBackupName reports whether name is a relative path inside the archive
+func validBackupName(name string) bool {
+	return name != "" && !path.IsAbs(name) && path.Clean(name) == name && !strings.HasPrefix(name, "../") && name != ".."
+}
+
+// Restore writes each file of the snapshot to its path in paths, replacing
+// the file there, and returns the paths written and the names of files that
+// have no path in this configuration. The service must not be running on the
+// same files, or it will overwrite them with what it has in memory.
+func (b *Backup) Restore(paths map[string]string) (restored, skipped []string, err error) {
+	restored = []string{}
+	for _, file := range b.Manifest.Files {
+		target, ok := paths[file.Name]
+		if !ok {
+			skipped = append(skipped, file.Name)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), dataDirPerm); err != nil {
+			return restored, skipped, fmt.Errorf("failed to restore %s: %w", file.Name, err)
+		}
+		data := b.files[file.Name]
+		if backupStore(file.Name) {
+			data = sealStoreData(data, strings.HasSuffix(file.Name, ".jsonl"))
+		}
+		if err := replaceFile(target, data); err != nil {
+			return restored, skipped, fmt.Errorf("failed to restore %s: %w", file.Name, err)
+		}
+		restored = append(restored, target)
+	}
+	return restored, skipped, nil
+}
+
+// backupStore reports whether the file named name in a snapshot is a store,
+// to be encrypted when restored if a storage key is in use. The settings files
+// and report subscriptions are edited by hand, so they are restored as they are.
+func backupStore(name string) bool {
+	if strings.HasPrefix(name, "shared/") {
+		return true
+	}
+	_, ok := storageFiles(&models.TenantConfig{})[path.Base(name)]
+	return ok && strings.HasPrefix(name, "tenants/")
+}
+
+// Backup writes a snapshot of every tenant's stores, the shared stores and
+// the settings files while the service is running
+func (tr *TenantRegistry) Backup(w io.Writer) (models.BackupManifest, error) {
+	configs := make([]models.TenantConfig, 0, len(tr.tenants))
+	events := make(map[string]*EventStore)
+	for _, tenant := range tr.tenants {
+		configs = append(configs, tenant.Config)
+		if tenant.Events != nil {
+			events["tenants/"+tenant.ID+"/events.jsonl"] = tenant.Events
+		}
+	}
+	return WriteBackup(w, BackupPaths(configs), events)
+}
+
+// StageRestore verifies a snapshot and saves it in the data directory, to be
+// restored by the next startup before any store is loaded. The snapshot is
+// encrypted if a storage key is in use; read it back with ReadStagedRestore.
+func StageRestore(dataDir DataDir, snapshot []byte) (models.BackupManifest, error) {
+	backup, err := ReadBackup(bytes.NewReader(snapshot))
+	if err != nil {
+		return models.BackupManifest{}, err
+	}
+	target := dataDir.PendingRestore()
+	if err := writeStoreFile(target, snapshot); err != nil {
+		return models.BackupManifest{}, fmt.Errorf("failed to stage snapshot: %w", err)
+	}
+	return backup.Manifest, nil
+}
+
+// ReadStagedRestore reads the snapshot staged by StageRestore in the data
+// directory; the error wraps os.ErrNotExist if there is none
+func ReadStagedRestore(dataDir DataDir) (*Backup, error) {
+	data, err := readStoreFile(dataDir.PendingRestore())
+	if err != nil {
+		return nil, err
+	}
+	return ReadBackup(bytes.NewReader(data))
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"hyperliquid-recon/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test writing a snapshot and restoring it on another host
+func TestBackupRestore(t *testing.T) {
+	source := t.TempDir()
+	cfg := models.TenantConfig{
+		ID:              DefaultTenantID,
+		AddressBookFile: filepath.Join(source, "addressbook.json"),
+		BreaksFile:      filepath.Join(source, "breaks.json"), // Never saved
+		EventsFile:      filepath.Join(source, "events.jsonl"),
+		RunsFile:        filepath.Join(source, "runs.json"),
+	}
+	os.WriteFile(cfg.AddressBookFile, []byte(`{"entries":[]}`), 0o644)
+	os.WriteFile(cfg.RunsFile, []byte(`[]`), 0o644)
+	// The last event is still being appended
+	os.WriteFile(cfg.EventsFile, []byte("{\"seq\":1}\n{\"seq\":2}\n{\"se"), 0o644)
+
+	var snapshot bytes.Buffer
+	manifest, err := WriteBackup(&snapshot, BackupPaths([]models.TenantConfig{cfg}), nil)
+	if err != nil {
+		t.Fatalf("Expected a snapshot, got %v", err)
+	}
+	if len(manifest.Files) != 3 {
+		t.Fatalf("Expected the 3 saved stores, got %+v", manifest.Files)
+	}
+
+	t.Run("should restore every file to its place on the new host", func(t *testing.T) {
+		backup, err := ReadBackup(bytes.NewReader(snapshot.Bytes()))
+		if err != nil {
+			t.Fatalf("Expected a valid snapshot, got %v", err)
+		}
+
+		target := t.TempDir()
+		restoredCfg := models.TenantConfig{ID: DefaultTenantID}
+		for name, file := range storageFiles(&restoredCfg) {
+			*file = filepath.Join(target, "db", name)
+		}
+		restored, skipped, err := backup.Restore(BackupPaths([]models.TenantConfig{restoredCfg}))
+		if err != nil || len(restored) != 3 || len(skipped) != 0 {
+			t.Fatalf("Expected 3 files restored, got %v, skipped %v (%v)", restored, skipped, err)
+		}
+
+		events, _ := os.ReadFile(restoredCfg.EventsFile)
+		if string(events) != "{\"seq\":1}\n{\"seq\":2}\n" {
+			t.Errorf("Expected the event log without the partial event, got %q", events)
+		}
+		addressBook, _ := os.ReadFile(restoredCfg.AddressBookFile)
+		if string(addressBook) != `{"entries":[]}` {
+			t.Errorf("Expected the address book, got %q", addressBook)
+		}
+	})
+
+	t.Run("should skip files this host has no place for", func(t *testing.T) {
+		backup, _ := ReadBackup(bytes.NewReader(snapshot.Bytes()))
+		restored, skipped, err := backup.Restore(BackupPaths([]models.TenantConfig{{ID: "other"}}))
+		if err != nil || len(restored) != 0 || len(skipped) != 3 {
+			t.Errorf("Expected every file to be skipped, got %v, skipped %v (%v)", restored, skipped, err)
+		}
+	})
+
+	t.Run("should reject a damaged snapshot", func(t *testing.T) {
+		damaged := bytes.Clone(snapshot.Bytes())
+		damaged[len(damaged)/2] ^= 0xff
+		if _, err := ReadBackup(bytes.NewReader(damaged)); !errors.Is(err, ErrInvalidBackup) {
+			t.Errorf("Expected ErrInvalidBackup, got %v", err)
+		}
+		if _, err := ReadBackup(bytes.NewReader([]byte("not a snapshot"))); !errors.Is(err, ErrInvalidBackup) {
+			t.Errorf("Expected ErrInvalidBackup, got %v", err)
+		}
+	})
+
+	t.Run("should stage a snapshot for the next startup", func(t *testing.T) {
+		dataDir := NewDataDir(t.TempDir())
+		if _, err := StageRestore(dataDir, snapshot.Bytes()); err != nil {
+			t.Fatalf("Expected the snapshot to be staged, got %v", err)
+		}
+		if _, err := os.Stat(dataDir.PendingRestore()); err != nil {
+			t.Errorf("Expected a pending restore, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrBreakNotFound is returned for operations on an unknown break ID
+var ErrBreakNotFound = errors.New("break not found")
+
+// BreakStore keeps reconciliation breaks raised by checks or users and their
+// workflow state. Breaks are persisted as JSON to path if one is set.
+type BreakStore struct {
+	breaks map[string]*models.Break // key: break ID
+	byKey  map[string]string        // discrepancy key -> break ID
+	mu     sync.RWMutex
+	path   string
+	push   *PushHub // Optional; new and updated breaks are sent to its subscribers
+}
+
+// NewBreakStore creates a break store, loading saved breaks from path if it exists
+func NewBreakStore(path string) (*BreakStore, error) {
+	bs := &BreakStore{
+		breaks: make(map[string]*models.Break),
+		byKey:  make(map[string]string),
+		path:   path,
+	}
+	if path == "" {
+		return bs, nil
+	}
+
+	data, err := readStoreFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return bs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read breaks: %w", err)
+	}
+
+	var breaks []models.Break
+	if err := json.Unmarshal(data, &breaks); err != nil {
+		return nil, fmt.Errorf("failed to parse breaks: %w", err)
+	}
+	for i := range breaks {
+		bs.breaks[breaks[i].ID] = &breaks[i]
+		bs.byKey[breaks[i].Key] = breaks[i].ID
+	}
+
+	return bs, nil
+}
+
+// UsePushHub sets the hub live subscribers are sent new and updated breaks through
+func (bs *BreakStore) UsePushHub(push *PushHub) {
+	bs.push = push
+}
+
+// RecordChecks raises breaks for the discrepancies and missing ranges found by a
+// check of address. A discrepancy that already has a break only updates its
+// LastSeenAt, so resolved breaks stay resolved. It is safe to call on a nil store.
+func (bs *BreakStore) RecordChecks(address string, discrepancies []models.PositionDiscrepancy, missing []models.TimeRange) {
+	if bs == nil || (len(discrepancies) == 0 && len(missing) == 0) {
+		return
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	raised := 0
+	for _, gap := range groupGaps(discrepancies) {
+		key := fmt.Sprintf("%s:%s:%s:%s", models.BreakPositionGap, address, gap.Coin, gap.Date)
+		description := fmt.Sprintf("%d start position mismatches, net size %g", gap.Count, gap.NetDifference)
+		if bs.raise(key, models.Break{Type: models.BreakPositionGap, Address: address, Coin: gap.Coin, Date: gap.Date, Description: description}) {
+			raised++
+		}
+	}
+	for _, r := range missing {
+		key := fmt.Sprintf("%s:%s:%d:%d", models.BreakMissingRange, address, r.Start.UnixMilli(), r.End.UnixMilli())
+		description := fmt.Sprintf("Trades from %s to %s could not be fetched", r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339))
+		if bs.raise(key, models.Break{Type: models.BreakMissingRange, Address: address, Date: r.Start.Format("2006-01-02"), Description: description}) {
+			raised++
+		}
+	}
+
+	if raised > 0 {
+		log.Printf("Raised %d new breaks for %s", raised, address)
+	}
+	if err := bs.persist(); err != nil {
+		log.Printf("Failed to save breaks: %v", err)
+	}
+}
+
+// RecordExternal raises a break for each coin/day with fills or rows left
+// unmatched by a connector's reconciliation. Like RecordChecks, a coin/day
+// that already has a break only updates it. It is safe to call on a nil store.
+func (bs *BreakStore) RecordExternal(recon models.ExternalRecon) {
+	if bs == nil || (len(recon.UnmatchedInternal) == 0 && len(recon.UnmatchedExternal) == 0) {
+		return
+	}
+
+	type counts struct {
+		coin, date         string
+		internal, external int
+	}
+	byKey := make(map[string]*counts)
+	var keys []string
+	count := func(fill models.ReconFill) *counts {
+		date := fill.Time.Format("2006-01-02")
+		key := fmt.Sprintf("%s:%s:%s:%s:%s", models.BreakExternal, recon.Address, recon.Source, fill.Coin, date)
+		if byKey[key] == nil {
+			byKey[key] = &counts{coin: fill.Coin, date: date}
+			keys = append(keys, key)
+		}
+		return byKey[key]
+	}
+	for _, fill := range recon.UnmatchedInternal {
+		count(fill).internal++
+	}
+	for _, row := range recon.UnmatchedExternal {
+		count(row).external++
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	raised := 0
+	for _, key := range keys {
+		c := byKey[key]
+		description := fmt.Sprintf("%s: %d fills and %d external rows unmatched in reconciliation %s", recon.Source, c.internal, c.external, recon.ID)
+		if bs.raise(key, models.Break{Type: models.BreakExternal, Address: recon.Address, Label: recon.Label, Coin: c.coin, Date: c.date, Description: description}) {
+			raised++
+		}
+	}
+
+	if raised > 0 {
+		log.Printf("Raised %d new breaks for %s from %s", raised, recon.Address, recon.Source)
+	}
+	if err := bs.persist(); err != nil {
+		log.Printf("Failed to save breaks: %v", err)
+	}
+}
+
+// raise records a break under key, or refreshes the existing one, and reports
+// whether it was new; caller must hold bs.mu
+func (bs *BreakStore) raise(key string, b models.Break) bool {
+	now := time.Now()
+	if id, exists := bs.byKey[key]; exists {
+		bs.breaks[id].LastSeenAt = now
+		bs.breaks[id].Description = b.Description
+		return false
+	}
+
+	b.ID = newID()
+	b.Key = key
+	b.Status = models.BreakOpen
+	b.Notes = []models.BreakNote{}
+	b.DetectedAt = now
+	b.LastSeenAt = now
+
+	bs.breaks[b.ID] = &b
+	bs.byKey[key] = b.ID
+	bs.push.publish(PushBreakRaised, b.Address, b)
+	return true
+}
+
+// Create records a manually raised break
+func (bs *BreakStore) Create(address, coin, date, description string) (models.Break, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	key := fmt.Sprintf("%s:%s", models.BreakManual, newID())
+	bs.raise(key, models.Break{Type: models.BreakManual, Address: address, Coin: coin, Date: date, Description: description})
+
+	return *bs.breaks[bs.byKey[key]], bs.persist()
+}
+
+// List returns breaks matching the filters, newest first. Empty filters match everything.
+func (bs *BreakStore) List(status models.BreakStatus, breakType models.BreakType, address string) []models.Break {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	result := make([]models.Break, 0, len(bs.breaks))
+	for _, b := range bs.breaks {
+		if (status == "" || b.Status == status) && (breakType == "" || b.Type == breakType) && (address == "" || b.Address == address) {
+			result = append(result, *b)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DetectedAt.After(result[j].DetectedAt)
+	})
+	return result
+}
+
+// Get returns a copy of the break with the given ID
+func (bs *BreakStore) Get(id string) (models.Break, bool) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	b, exists := bs.breaks[id]
+	if !exists {
+		return models.Break{}, false
+	}
+	return *b, true
+}
+
+// Update sets a break's status and/or assignee; nil arguments are left unchanged
+func (bs *BreakStore) Update(id string, status *models.BreakStatus, assignee *string) (models.Break, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	b, exists := bs.breaks[id]
+	if !exists {
+		return models.Break{}, ErrBreakNotFound
+	}
+
+	if status != nil && *status != b.Status {
+		b.Status = *status
+		b.ResolvedAt = nil
+		if *status == models.BreakResolved {
+			now := time.Now()
+			b.ResolvedAt = &now
+		}
+	}
+	if assignee != nil {
+		b.Assignee = *assignee
+	}
+	bs.push.publish(PushBreakUpdated, b.Address, *b)
+
+	return *b, bs.persist()
+}
+
+// AddNote appends a note to a break
+func (bs *BreakStore) AddNote(id, author, text string) (models.Break, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	b, exists := bs.breaks[id]
+	if !exists {
+		return models.Break{}, ErrBreakNotFound
+	}
+
+	b.Notes = append(b.Notes, models.BreakNote{Author: author, Text: text, CreatedAt: time.Now()})
+	bs.push.publish(PushBreakUpdated, b.Address, *b)
+
+	return *b, bs.persist()
+}
+
+// persist writes all breaks to the breaks file; caller must hold bs.mu
+func (bs *BreakStore) persist() error {
+	if bs.path == "" {
+		return nil
+	}
+
+	breaks := make([]models.Break, 0, len(bs.breaks))
+	for _, b := range bs.breaks {
+		breaks = append(breaks, *b)
+	}
+	sort.Slice(breaks, func(i, j int) bool { return breaks[i].DetectedAt.Before(breaks[j].DetectedAt) })
+
+	return writeJSONFile(bs.path, breaks)
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test BreakStore raising and workflow
+func TestBreakStore(t *testing.T) {
+	gap := models.PositionDiscrepancy{Coin: "BTC", Date: "2025-01-01", Difference: 0.25}
+	missing := models.TimeRange{Start: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 2, 6, 0, 0, 0, time.UTC)}
+
+	t.Run("should raise each discrepancy once", func(t *testing.T) {
+		bs, _ := NewBreakStore("")
+		bs.RecordChecks(testAddress, []models.PositionDiscrepancy{gap, gap}, []models.TimeRange{missing})
+		bs.RecordChecks(testAddress, []models.PositionDiscrepancy{gap}, []models.TimeRange{missing})
+
+		breaks := bs.List("", "", "")
+		if len(breaks) != 2 {
+			t.Fatalf("Expected 2 breaks, got %d", len(breaks))
+		}
+
+		gaps := bs.List(models.BreakOpen, models.BreakPositionGap, testAddress)
+		if len(gaps) != 1 || gaps[0].Coin != "BTC" || gaps[0].Date != "2025-01-01" {
+			t.Errorf("Unexpected position gap breaks: %+v", gaps)
+		}
+	})
+
+	t.Run("should keep resolved breaks resolved when seen again", func(t *testing.T) {
+		bs, _ := NewBreakStore("")
+		bs.RecordChecks(testAddress, []models.PositionDiscrepancy{gap}, nil)
+		id := bs.List("", "", "")[0].ID
+
+		resolved, assignee := models.BreakResolved, "ops"
+		b, err := bs.Update(id, &resolved, &assignee)
+		if err != nil || b.ResolvedAt == nil || b.Assignee != "ops" {
+			t.Fatalf("Expected resolved break assigned to ops, got %+v (%v)", b, err)
+		}
+
+		bs.RecordChecks(testAddress, []models.PositionDiscrepancy{gap}, nil)
+		if b, _ := bs.Get(id); b.Status != models.BreakResolved {
+			t.Errorf("Expected break to stay resolved, got %s", b.Status)
+		}
+
+		reopened := models.BreakOpen
+		if b, _ := bs.Update(id, &reopened, nil); b.ResolvedAt != nil || b.Assignee != "ops" {
+			t.Errorf("Expected reopening to clear ResolvedAt only, got %+v", b)
+		}
+	})
+
+	t.Run("should persist notes across restarts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "breaks.json")
+		bs, _ := NewBreakStore(path)
+		b, _ := bs.Create(testAddress, "ETH", "2025-01-03", "Fill missing from broker statement")
+		bs.AddNote(b.ID, "ops", "Asked exchange support")
+
+		reloaded, err := NewBreakStore(path)
+		if err != nil {
+			t.Fatalf("Reload failed: %v", err)
+		}
+		got, exists := reloaded.Get(b.ID)
+		if !exists || got.Type != models.BreakManual || len(got.Notes) != 1 || got.Notes[0].Text != "Asked exchange support" {
+			t.Errorf("Expected persisted break with note, got %+v", got)
+		}
+
+		if _, err := reloaded.AddNote("missing", "ops", "x"); err != ErrBreakNotFound {
+			t.Errorf("Expected ErrBreakNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"io"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// BuildInfo returns the commit and build time set with -ldflags at link time.
+// Without them it falls back to the version control information the go tool
+// stamps into binaries built inside a git checkout.
+func BuildInfo() models.BuildInfo {
+	info := models.BuildInfo{
+		Commit:            config.GitCommit,
+		BuildTime:         config.BuildTime,
+		CalculatorVersion: config.CalculatorVersion,
+		GoVersion:         runtime.Version(),
+	}
+
+	if stamped, ok := debug.ReadBuildInfo(); ok && info.Commit == "" {
+		modified := false
+		for _, setting := range stamped.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
+
+// WriteExportFooter writes a comment line identifying the build that produced a
+// CSV export, preceded by one describing its format if that isn't the
+// default. Trade imports skip them.
+func WriteExportFooter(w io.Writer, f CSVFormat) error {
+	if err := f.writeFooter(w); err != nil {
+		return err
+	}
+	info := BuildInfo()
+	_, err := fmt.Fprintf(w, "# hyperliquid-recon commit %s built %s calculator %s\n", info.Commit, info.BuildTime, info.CalculatorVersion)
+	return err
+}
+
+// exportMetadata records the build that produced a Parquet export in the file's key/value metadata
+func exportMetadata() []parquet.WriterOption {
+	info := BuildInfo()
+	return []parquet.WriterOption{
+		parquet.KeyValueMetadata("hyperliquid-recon.commit", info.Commit),
+		parquet.KeyValueMetadata("hyperliquid-recon.buildTime", info.BuildTime),
+		parquet.KeyValueMetadata("hyperliquid-recon.calculatorVersion", info.CalculatorVersion),
+	}
+}
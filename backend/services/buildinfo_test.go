@@ -0,0 +1,46 @@
+package services
+
+import (
+	"bytes"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"strings"
+	"testing"
+)
+
+// Test reporting the build in exports
+func TestBuildInfo(t *testing.T) {
+	previousCommit, previousTime := config.GitCommit, config.BuildTime
+	defer func() { config.GitCommit, config.BuildTime = previousCommit, previousTime }()
+
+	t.Run("should report the commit and time set at link time", func(t *testing.T) {
+		config.GitCommit, config.BuildTime = "abc1234", "2025-06-30T12:00:00Z"
+
+		info := BuildInfo()
+		if info.Commit != "abc1234" || info.BuildTime != "2025-06-30T12:00:00Z" || info.CalculatorVersion != config.CalculatorVersion {
+			t.Errorf("Unexpected build info: %+v", info)
+		}
+
+		var buf bytes.Buffer
+		WriteExportFooter(&buf, DefaultCSVFormat)
+		if !strings.Contains(buf.String(), "commit abc1234 built 2025-06-30T12:00:00Z calculator "+config.CalculatorVersion) {
+			t.Errorf("Unexpected footer: %s", buf.String())
+		}
+	})
+
+	t.Run("should never report an empty commit", func(t *testing.T) {
+		config.GitCommit, config.BuildTime = "", ""
+
+		if info := BuildInfo(); info.Commit == "" || info.BuildTime == "" {
+			t.Errorf("Expected a placeholder, got %+v", info)
+		}
+	})
+
+	t.Run("should skip the footer when importing", func(t *testing.T) {
+		body, _ := EncodeTrades(FormatCSV, []models.Trade{createTestTrade("2025-01-01T10:00:00Z", "BTC", "B", 50000, 0.5)})
+		trades, err := ReadTradesCSV(bytes.NewReader(body))
+		if err != nil || len(trades) != 1 {
+			t.Errorf("Expected 1 trade, got %d (%v)", len(trades), err)
+		}
+	})
+}
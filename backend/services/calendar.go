@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+	"hyperliquid-recon/models"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteCalendar writes daily P&L records as an iCalendar (RFC 5545) feed with
+// one all-day event per trading day. Event UIDs are derived from the address
+// and date so calendar apps update events in place on every poll.
+func WriteCalendar(w io.Writer, name, address string, records []models.DailyPnL, now time.Time) error {
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//hyperliquid-recon//Daily P&L//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + escapeICSText(name),
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H",
+		"X-PUBLISHED-TTL:PT1H",
+	}
+
+	for _, record := range records {
+		date, err := time.Parse("2006-01-02", record.Date)
+		if err != nil {
+			return fmt.Errorf("invalid record date %q: %w", record.Date, err)
+		}
+
+		trades := "trades"
+		if record.TradeCount == 1 {
+			trades = "trade"
+		}
+		summary := fmt.Sprintf("P&L %s (%d %s)", formatSignedUSD(record.DailyPnL), record.TradeCount, trades)
+		description := fmt.Sprintf("Daily P&L: %s\nTrades: %d\nCumulative P&L: %s",
+			formatSignedUSD(record.DailyPnL), record.TradeCount, formatSignedUSD(record.CumulativePnL))
+
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:%s-%s@hyperliquid-recon", date.Format("20060102"), address),
+			"DTSTAMP:"+stamp,
+			"DTSTART;VALUE=DATE:"+date.Format("20060102"),
+			"DTEND;VALUE=DATE:"+date.AddDate(0, 0, 1).Format("20060102"),
+			"SUMMARY:"+escapeICSText(summary),
+			"DESCRIPTION:"+escapeICSText(description),
+			"TRANSP:TRANSPARENT",
+			"END:VEVENT",
+		)
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, foldICSLine(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeICSText escapes a TEXT property value
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// foldICSLine splits lines longer than 75 octets into continuation lines,
+// without breaking UTF-8 sequences
+func foldICSLine(line string) string {
+	const limit = 75
+
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
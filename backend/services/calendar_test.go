@@ -0,0 +1,42 @@
+package services
+
+import (
+	"bytes"
+	"hyperliquid-recon/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test WriteCalendar event output
+func TestWriteCalendar(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCalendar(&buf, "Main account", testAddress, []models.DailyPnL{
+		{Date: "2025-01-02", TradeCount: 1, DailyPnL: -250, CumulativePnL: 1000},
+		{Date: "2025-01-01", TradeCount: 42, DailyPnL: 1250, CumulativePnL: 1250},
+	}, time.Date(2025, 1, 3, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("WriteCalendar failed: %v", err)
+	}
+
+	feed := buf.String()
+	for _, expected := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Main account\r\n",
+		"UID:20250101-" + testAddress + "@hyperliquid-recon\r\n",
+		"DTSTART;VALUE=DATE:20250101\r\nDTEND;VALUE=DATE:20250102\r\n",
+		"SUMMARY:P&L +$1\\,250.00 (42 trades)\r\n",
+		"SUMMARY:P&L -$250.00 (1 trade)\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(feed, expected) {
+			t.Errorf("Expected feed to contain %q", expected)
+		}
+	}
+
+	for _, line := range strings.Split(feed, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Line longer than 75 octets: %q", line)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrInvalidChartInterval is returned for a candle interval Hyperliquid doesn't serve
+	ErrInvalidChartInterval = errors.New("interval must be one of 1m, 3m, 5m, 15m, 30m, 1h, 2h, 4h, 8h, 12h, 1d, 3d or 1w")
+	// ErrTooManyCandles is returned when a chart would need more candles than one request returns
+	ErrTooManyCandles = errors.New("too many candles; pick a longer interval or a shorter range")
+)
+
+// maxCandles is the most candles a candle snapshot returns
+const maxCandles = 5000
+
+// chartIntervals are the candle intervals Hyperliquid serves, shortest first
+var chartIntervals = []struct {
+	name   string
+	length time.Duration
+}{
+	{"1m", time.Minute},
+	{"3m", 3 * time.Minute},
+	{"5m", 5 * time.Minute},
+	{"15m", 15 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"2h", 2 * time.Hour},
+	{"4h", 4 * time.Hour},
+	{"8h", 8 * time.Hour},
+	{"12h", 12 * time.Hour},
+	{"1d", 24 * time.Hour},
+	{"3d", 3 * 24 * time.Hour},
+	{"1w", 7 * 24 * time.Hour},
+}
+
+// candleSnapshotRequest asks for the candles of a coin
+type candleSnapshotRequest struct {
+	Type string        `json:"type"`
+	Req  candleRequest `json:"req"`
+}
+
+type candleRequest struct {
+	Coin      string `json:"coin"`
+	Interval  string `json:"interval"`
+	StartTime int64  `json:"startTime"`
+	EndTime   int64  `json:"endTime"`
+}
+
+// candleResponse is one candle from the API
+type candleResponse struct {
+	OpenTime int64  `json:"t"`
+	Open     string `json:"o"`
+	High     string `json:"h"`
+	Low      string `json:"l"`
+	Close    string `json:"c"`
+	Volume   string `json:"v"`
+	Trades   int    `json:"n"`
+}
+
+// FetchCandles fetches the interval candles of coin opening between start
+// and end, oldest first
+func (c *HyperliquidClient) FetchCandles(ctx context.Context, coin, interval string, start, end time.Time) (candles []models.Candle, err error) {
+	ctx, span := tracer.Start(ctx, "HyperliquidClient.FetchCandles",
+		trace.WithAttributes(attribute.String("coin", coin), attribute.String("interval", interval)))
+	defer func() { endSpan(span, err) }()
+
+	var page []candleResponse
+	request := candleSnapshotRequest{
+		Type: "candleSnapshot",
+		Req:  candleRequest{Coin: coin, Interval: interval, StartTime: start.UnixMilli(), EndTime: end.UnixMilli()},
+	}
+	if err := c.postInfo(ctx, request, &page); err != nil {
+		return nil, fmt.Errorf("failed to fetch %s candles: %w", coin, err)
+	}
+	upstreamUsage.record(0, len(page)/config.UserFillsItemsPerWeight)
+
+	candles = make([]models.Candle, 0, len(page))
+	for _, item := range page {
+		candle := models.Candle{Time: time.UnixMilli(item.OpenTime), Trades: item.Trades}
+		for _, field := range []struct {
+			value string
+			into  *float64
+		}{
+			{item.Open, &candle.Open},
+			{item.High, &candle.High},
+			{item.Low, &candle.Low},
+			{item.Close, &candle.Close},
+			{item.Volume, &candle.Volume},
+		} {
+			if *field.into, err = strconv.ParseFloat(field.value, 64); err != nil {
+				return nil, fmt.Errorf("failed to parse %s candle '%s': %w", coin, field.value, err)
+			}
+		}
+		candles = append(candles, candle)
+	}
+	return candles, nil
+}
+
+// chartInterval checks interval against the range from start to end, or picks
+// the shortest one that needs at most ChartMaxCandles candles if it is empty
+func chartInterval(interval string, start, end time.Time) (string, error) {
+	span := end.Sub(start)
+	for _, candidate := range chartIntervals {
+		if interval == "" && span/candidate.length <= config.ChartMaxCandles {
+			return candidate.name, nil
+		}
+		if candidate.name == interval {
+			if span/candidate.length > maxCandles {
+				return "", ErrTooManyCandles
+			}
+			return interval, nil
+		}
+	}
+	if interval == "" {
+		return chartIntervals[len(chartIntervals)-1].name, nil
+	}
+	return "", ErrInvalidChartInterval
+}
+
+// Chart returns the candles of coin, a name trades are grouped by, between
+// the local dates from and to (YYYY-MM-DD, inclusive) along with the fills of
+// address in trades in that window. An empty interval picks one to suit the
+// range.
+func (rs *ReconciliationService) Chart(ctx context.Context, address string, trades []models.Trade, coin, from, to, interval string) (models.Chart, error) {
+	start, err := time.ParseInLocation("2006-01-02", from, time.Local)
+	if err != nil {
+		return models.Chart{}, fmt.Errorf("invalid date %q", from)
+	}
+	end, err := time.ParseInLocation("2006-01-02", to, time.Local)
+	if err != nil {
+		return models.Chart{}, fmt.Errorf("invalid date %q", to)
+	}
+	end = end.AddDate(0, 0, 1)
+
+	interval, err = chartInterval(interval, start, end)
+	if err != nil {
+		return models.Chart{}, err
+	}
+	candles, err := rs.hlClient.FetchCandles(ctx, exchangeCoin(coin, to), interval, start, end.Add(-time.Millisecond))
+	if err != nil {
+		return models.Chart{}, err
+	}
+
+	chart := models.Chart{
+		Address:  address,
+		Label:    rs.Label(address),
+		Coin:     coin,
+		Interval: interval,
+		From:     from,
+		To:       to,
+		Candles:  candles,
+		Fills:    []models.ChartFill{},
+	}
+	for _, trade := range trades {
+		if trade.Coin != coin || trade.Time.Before(start) || !trade.Time.Before(end) {
+			continue
+		}
+		chart.Fills = append(chart.Fills, models.ChartFill{
+			Time:  trade.Time,
+			Side:  trade.Side,
+			Price: trade.Price,
+			Size:  trade.Size,
+			Fee:   trade.Fee,
+		})
+	}
+	return chart, nil
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test candles joined with the fills of an address
+func TestChart(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.Local)
+	var asked candleSnapshotRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&asked)
+		fmt.Fprintf(w, `[
+			{"t": %d, "T": %d, "s": "BTC", "i": "1h", "o": "100", "c": "105", "h": "110", "l": "95", "v": "12.5", "n": 40},
+			{"t": %d, "T": %d, "s": "BTC", "i": "1h", "o": "105", "c": "102", "h": "106", "l": "101", "v": "3", "n": 9}
+		]`, day.UnixMilli(), day.Add(time.Hour).UnixMilli()-1, day.Add(time.Hour).UnixMilli(), day.Add(2*time.Hour).UnixMilli()-1)
+	}))
+	defer server.Close()
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = server.URL
+
+	trades := []models.Trade{
+		{Time: day.Add(-time.Hour), Coin: "BTC", Side: "B", Price: 90, Size: 1},
+		{Time: day.Add(30 * time.Minute), Coin: "BTC", Side: "B", Price: 104, Size: 0.5, Fee: 0.02},
+		{Time: day.Add(40 * time.Minute), Coin: "ETH", Side: "A", Price: 3000, Size: 1},
+		{Time: day.Add(90 * time.Minute), Coin: "BTC", Side: "A", Price: 103, Size: 0.5},
+	}
+
+	chart, err := rs.Chart(context.Background(), "0xabc", trades, "BTC", "2025-06-01", "2025-06-01", "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if asked.Type != "candleSnapshot" || asked.Req.Coin != "BTC" || asked.Req.Interval != "3m" {
+		t.Errorf("Unexpected candle request %+v", asked)
+	}
+	if len(chart.Candles) != 2 || chart.Candles[0].High != 110 || chart.Candles[1].Trades != 9 {
+		t.Errorf("Unexpected candles %+v", chart.Candles)
+	}
+	if len(chart.Fills) != 2 || chart.Fills[0].Price != 104 || chart.Fills[0].Fee != 0.02 || chart.Fills[1].Side != "A" {
+		t.Errorf("Expected the day's two BTC fills, got %+v", chart.Fills)
+	}
+
+	if _, err := rs.Chart(context.Background(), "0xabc", trades, "BTC", "2025-01-01", "2025-06-01", "1m"); !errors.Is(err, ErrTooManyCandles) {
+		t.Errorf("Expected ErrTooManyCandles, got %v", err)
+	}
+	if _, err := rs.Chart(context.Background(), "0xabc", trades, "BTC", "2025-06-01", "2025-06-01", "7m"); !errors.Is(err, ErrInvalidChartInterval) {
+		t.Errorf("Expected ErrInvalidChartInterval, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"hyperliquid-recon/config"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// upstreamClock tracks how far the local clock is from the exchange's, measured
+// from every API response
+var upstreamClock = &clockSkew{tolerance: config.ClockSkewTolerance}
+
+// clockSkew estimates how far the local clock is ahead of the exchange's. Fetch
+// windows end at the exchange's time rather than ours: a local clock running
+// ahead would otherwise end each window in the exchange's future, and the next
+// incremental fetch would start after fills made in between.
+//
+// The estimate comes from each response's Date header, which only has
+// one-second resolution, so skews within tolerance are ignored. Fill times
+// bound it too, since the exchange can't report a fill from its own future.
+type clockSkew struct {
+	tolerance time.Duration
+	estimate  time.Duration // Latest measurement; positive if the local clock is ahead
+	applied   time.Duration // Skew windows are corrected by
+	mu        sync.Mutex
+}
+
+// observeDate measures the skew from a response's Date header, given when the
+// request was sent and its response received
+func (c *clockSkew) observeDate(date string, sent, received time.Time) {
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return
+	}
+
+	// The header is truncated to the second, so compare the middle of that
+	// second with the middle of the round trip
+	local := sent.Add(received.Sub(sent) / 2)
+	c.set(local.Sub(serverTime.Add(500 * time.Millisecond)))
+}
+
+// observeFill lowers the estimate if a fill received at received shows the
+// exchange's clock was further ahead than measured
+func (c *clockSkew) observeFill(fillTime, received time.Time) {
+	c.mu.Lock()
+	estimate := c.estimate
+	c.mu.Unlock()
+
+	if bound := received.Sub(fillTime); bound < estimate {
+		c.set(bound)
+	}
+}
+
+// set records a new estimate and applies it if it is beyond the tolerance
+func (c *clockSkew) set(estimate time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.estimate = estimate
+	applied := time.Duration(0)
+	if estimate > c.tolerance || estimate < -c.tolerance {
+		applied = estimate.Round(time.Millisecond)
+	}
+
+	if diff := applied - c.applied; diff > c.tolerance || diff < -c.tolerance || (applied == 0) != (c.applied == 0) {
+		switch {
+		case applied == 0:
+			log.Printf("Local clock is back within %s of the exchange", c.tolerance)
+		case applied > 0:
+			log.Printf("Local clock is %s ahead of the exchange, adjusting fetch windows", applied)
+		default:
+			log.Printf("Local clock is %s behind the exchange, adjusting fetch windows", -applied)
+		}
+	}
+	c.applied = applied
+}
+
+// Skew returns how far the local clock is ahead of the exchange's, or 0 if
+// within the tolerance; negative if it is behind
+func (c *clockSkew) Skew() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.applied
+}
+
+// Now returns the current time by the exchange's clock
+func (c *clockSkew) Now() time.Time {
+	return time.Now().Add(-c.Skew())
+}
+
+// exchangeNow returns the current time by the exchange's clock, as far as it
+// has been measured; fetch windows end at it
+func exchangeNow() time.Time {
+	return upstreamClock.Now()
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Test estimating the skew between the local and exchange clocks
+func TestClockSkew(t *testing.T) {
+	sent := time.Now()
+	received := sent.Add(100 * time.Millisecond)
+
+	t.Run("should apply a skew measured from the Date header beyond the tolerance", func(t *testing.T) {
+		c := &clockSkew{tolerance: 2 * time.Second}
+		c.observeDate(sent.Add(-5*time.Second).UTC().Format(http.TimeFormat), sent, received)
+
+		if skew := c.Skew(); skew < 4*time.Second || skew > 6*time.Second {
+			t.Errorf("Expected a skew of about 5s, got %s", skew)
+		}
+		if behind := time.Since(c.Now()); behind < 4*time.Second {
+			t.Errorf("Expected the exchange's time to be about 5s behind, got %s", behind)
+		}
+	})
+
+	t.Run("should ignore skews within the tolerance and unparseable dates", func(t *testing.T) {
+		c := &clockSkew{tolerance: 2 * time.Second}
+		c.observeDate(sent.UTC().Format(http.TimeFormat), sent, received)
+		c.observeDate("yesterday", sent, received)
+
+		if skew := c.Skew(); skew != 0 {
+			t.Errorf("Expected no correction, got %s", skew)
+		}
+	})
+
+	t.Run("should bound the skew by the newest fill", func(t *testing.T) {
+		c := &clockSkew{tolerance: 2 * time.Second}
+		c.observeDate(sent.UTC().Format(http.TimeFormat), sent, received)
+
+		// A fill 10s in the local future means the exchange's clock is at least that far ahead
+		c.observeFill(received.Add(10*time.Second), received)
+		if skew := c.Skew(); skew != -10*time.Second {
+			t.Errorf("Expected the local clock to be 10s behind, got %s", skew)
+		}
+
+		c.observeFill(received.Add(-time.Minute), received)
+		if skew := c.Skew(); skew != -10*time.Second {
+			t.Errorf("Expected an older fill not to change the skew, got %s", skew)
+		}
+	})
+}
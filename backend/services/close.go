@@ -0,0 +1,271 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CloseService runs the end-of-day close: it re-fetches the previous day's
+// fills for every tracked address, runs the consistency checks, freezes the
+// result as a snapshot and emits a report by webhook and/or email
+type CloseService struct {
+	reconService *ReconciliationService
+	addressBook  *AddressBook
+	breaks       *BreakStore
+	webhooks     *WebhookDispatcher
+	mailer       *Mailer
+	webhookURL   string                      // Optional; receives an "eod.closed" event per close run
+	reportTo     []string                    // Optional; receives the close report by email
+	dataQuality  *models.DataQualityConfig   // Optional; tunes the data-quality alerts sent to the same places
+	alertsMu     sync.RWMutex                // Guards webhookURL, reportTo and dataQuality
+	closes       map[string]*models.DayClose // key: address + "/" + date
+	mu           sync.Mutex                  // Serializes close runs and guards closes
+	path         string
+}
+
+// NewCloseService creates a close service, loading stored snapshots from path if it exists
+func NewCloseService(reconService *ReconciliationService, addressBook *AddressBook, breaks *BreakStore, webhooks *WebhookDispatcher, mailer *Mailer, webhookURL string, reportTo []string, path string) (*CloseService, error) {
+	cs := &CloseService{
+		reconService: reconService,
+		addressBook:  addressBook,
+		breaks:       breaks,
+		webhooks:     webhooks,
+		mailer:       mailer,
+		webhookURL:   webhookURL,
+		reportTo:     reportTo,
+		closes:       make(map[string]*models.DayClose),
+		path:         path,
+	}
+	if path == "" {
+		return cs, nil
+	}
+
+	data, err := readStoreFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read closes: %w", err)
+	}
+
+	var closes []models.DayClose
+	if err := json.Unmarshal(data, &closes); err != nil {
+		return nil, fmt.Errorf("failed to parse closes: %w", err)
+	}
+	for i := range closes {
+		cs.closes[closeKey(closes[i].Address, closes[i].Date)] = &closes[i]
+	}
+
+	return cs, nil
+}
+
+// RunScheduled closes the previous day; it is called by the scheduler
+func (cs *CloseService) RunScheduled() {
+	closes, err := cs.Close(context.Background(), time.Now().AddDate(0, 0, -1), false)
+	if err != nil {
+		log.Printf("End-of-day close failed: %v", err)
+		return
+	}
+	log.Printf("End-of-day close finished for %d addresses", len(closes))
+}
+
+// Close closes the local calendar day containing date for every tracked
+// address (or, if the address book is empty, every cached address). Days that
+// are already closed are skipped unless force is set. It returns the new closes.
+func (cs *CloseService) Close(ctx context.Context, date time.Time, force bool) (_ []models.DayClose, err error) {
+	ctx, span := tracer.Start(ctx, "CloseService.Close", trace.WithAttributes(attribute.String("date", date.Format("2006-01-02"))))
+	defer func() { endSpan(span, err) }()
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	if dayEnd.After(time.Now()) {
+		return nil, errors.New("only finished days can be closed")
+	}
+	day := dayStart.Format("2006-01-02")
+
+	var addresses []string
+	for _, entry := range cs.addressBook.List() {
+		addresses = append(addresses, entry.Address)
+	}
+	if len(addresses) == 0 {
+		addresses = cs.reconService.CachedAddresses()
+	}
+
+	closes := []models.DayClose{}
+	for _, address := range addresses {
+		if _, closed := cs.closes[closeKey(address, day)]; closed && !force {
+			continue
+		}
+
+		dayClose := cs.closeAddress(ctx, address, dayStart, dayEnd)
+		cs.closes[closeKey(address, day)] = &dayClose
+		closes = append(closes, dayClose)
+
+		log.Printf("Closed %s for %s: %s", day, address, dayClose.Status)
+	}
+
+	if err := cs.persist(); err != nil {
+		return closes, err
+	}
+
+	if len(closes) > 0 {
+		cs.emit(day, closes)
+	}
+	return closes, nil
+}
+
+// closeAddress re-fetches, checks and snapshots one address's day
+func (cs *CloseService) closeAddress(ctx context.Context, address string, dayStart, dayEnd time.Time) models.DayClose {
+	label := cs.addressBook.Label(address)
+	dayClose := models.DayClose{
+		Address:  address,
+		Label:    label,
+		Date:     dayStart.Format("2006-01-02"),
+		Report:   models.AccountReport{Address: address, Label: label},
+		Trades:   []models.Trade{},
+		ClosedAt: time.Now(),
+	}
+
+	// Make sure the cache reaches back to the start of the day before re-fetching it
+	days := int(math.Ceil(time.Since(dayStart).Hours() / 24))
+	_, err := cs.reconService.RefreshCache(ctx, address, days)
+
+	var partial *PartialError
+	var trades []models.Trade
+	if err == nil || errors.As(err, &partial) {
+		trades, err = cs.reconService.RefetchRange(ctx, address, dayStart, dayEnd)
+	}
+	if err != nil && !errors.As(err, &partial) {
+		dayClose.Status = models.CloseFailed
+		dayClose.Report.Error = err.Error()
+		return dayClose
+	}
+
+	dayTrades, discrepancies := reportDay(&dayClose.Report, trades, cs.reconService.CachedMissingRanges(address), dayStart, dayEnd)
+	cs.breaks.RecordChecks(address, discrepancies, dayClose.Report.MissingRanges)
+
+	if dayTrades != nil {
+		dayClose.Trades = dayTrades
+	}
+	var csv bytes.Buffer
+	WriteTradesCSV(&csv, dayClose.Trades, DefaultCSVFormat)
+	dayClose.TradesDigest = sha256Hex(csv.Bytes())
+
+	dayClose.Status = models.CloseClean
+	if len(dayClose.Report.PositionGaps) > 0 || len(dayClose.Report.MissingRanges) > 0 {
+		dayClose.Status = models.CloseBreaks
+	}
+	return dayClose
+}
+
+// Alerts returns where close reports and data-quality alerts are sent
+func (cs *CloseService) Alerts() models.AlertConfig {
+	cs.alertsMu.RLock()
+	defer cs.alertsMu.RUnlock()
+	return models.AlertConfig{EODWebhookURL: cs.webhookURL, EODReportTo: cs.reportTo, DataQuality: cs.dataQuality}
+}
+
+// SetAlerts changes where close reports and data-quality alerts are sent,
+// taking effect from the next close or refresh
+func (cs *CloseService) SetAlerts(alerts models.AlertConfig) {
+	cs.alertsMu.Lock()
+	defer cs.alertsMu.Unlock()
+	cs.webhookURL = alerts.EODWebhookURL
+	cs.reportTo = alerts.EODReportTo
+	cs.dataQuality = alerts.DataQuality
+}
+
+// emit delivers the close report to the configured webhook and email recipients
+func (cs *CloseService) emit(day string, closes []models.DayClose) {
+	alerts := cs.Alerts()
+
+	if alerts.EODWebhookURL != "" && cs.webhooks != nil {
+		if _, err := cs.webhooks.Enqueue(alerts.EODWebhookURL, "eod.closed", closes); err != nil {
+			log.Printf("Failed to queue end-of-day webhook: %v", err)
+		}
+	}
+
+	if len(alerts.EODReportTo) == 0 || cs.mailer == nil {
+		return
+	}
+
+	report := models.DailyReport{Name: "End-of-day close", Date: day, GeneratedAt: time.Now()}
+	clean := 0
+	for _, dayClose := range closes {
+		report.Accounts = append(report.Accounts, dayClose.Report)
+		report.TotalPnL += dayClose.Report.PnL
+		report.TotalFees += dayClose.Report.Fees
+		if dayClose.Status == models.CloseClean {
+			clean++
+		}
+	}
+
+	body, err := FormatReport(report)
+	if err != nil {
+		log.Printf("Failed to render end-of-day report: %v", err)
+		return
+	}
+	subject := fmt.Sprintf("End-of-day close %s: %d/%d clean, P&L %s", day, clean, len(closes), formatSignedUSD(report.TotalPnL))
+	if err := cs.mailer.Send(alerts.EODReportTo, subject, body); err != nil {
+		log.Printf("Failed to email end-of-day report: %v", err)
+	}
+}
+
+// Closes returns stored closes, newest day first. Empty filters match everything.
+func (cs *CloseService) Closes(address, date string) []models.DayClose {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	result := []models.DayClose{}
+	for _, dayClose := range cs.closes {
+		if (address == "" || dayClose.Address == address) && (date == "" || dayClose.Date == date) {
+			result = append(result, *dayClose)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Date != result[j].Date {
+			return result[i].Date > result[j].Date
+		}
+		return result[i].Address < result[j].Address
+	})
+	return result
+}
+
+// persist writes all closes to the closes file; caller must hold cs.mu
+func (cs *CloseService) persist() error {
+	if cs.path == "" {
+		return nil
+	}
+
+	closes := make([]models.DayClose, 0, len(cs.closes))
+	for _, dayClose := range cs.closes {
+		closes = append(closes, *dayClose)
+	}
+	sort.Slice(closes, func(i, j int) bool {
+		return closeKey(closes[i].Address, closes[i].Date) < closeKey(closes[j].Address, closes[j].Date)
+	})
+
+	return writeJSONFile(cs.path, closes)
+}
+
+func closeKey(address, date string) string {
+	return strings.ToLower(address) + "/" + date
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test CloseService end-of-day close
+func TestCloseService(t *testing.T) {
+	yesterday := time.Now().AddDate(0, 0, -1)
+	dayStart := time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 0, 0, 0, 0, time.Local)
+
+	fills := []FillResponse{
+		{Time: dayStart.Add(9 * time.Hour).UnixMilli(), Coin: "BTC", Side: "B", Price: "100", Size: "1", StartPosition: "0", Fee: "0.1"},
+		// The exchange says we held 1.5 here, so a 0.5 buy is missing
+		{Time: dayStart.Add(10 * time.Hour).UnixMilli(), Coin: "BTC", Side: "A", Price: "120", Size: "1.5", StartPosition: "1.5", Fee: "0.2"},
+	}
+
+	// Serve the fills that fall inside the requested window
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req UserFillsRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		result := []FillResponse{}
+		for _, fill := range fills {
+			if fill.Time >= *req.StartTime && fill.Time <= *req.EndTime {
+				result = append(result, fill)
+			}
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	defer upstream.Close()
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = upstream.URL
+	ab, _ := NewAddressBook("", nil)
+	ab.Save(testAddress, "Main account")
+	breaks, _ := NewBreakStore("")
+
+	cs, _ := NewCloseService(rs, ab, breaks, nil, nil, "", nil, "")
+
+	closes, err := cs.Close(context.Background(), yesterday, false)
+	if err != nil || len(closes) != 1 {
+		t.Fatalf("Expected 1 close, got %d (%v)", len(closes), err)
+	}
+
+	dayClose := closes[0]
+	if dayClose.Status != models.CloseBreaks || dayClose.Label != "Main account" || len(dayClose.Trades) != 2 {
+		t.Errorf("Unexpected close: %+v", dayClose)
+	}
+	if dayClose.Report.PnL != 80 || len(dayClose.Report.PositionGaps) != 1 || dayClose.TradesDigest == "" {
+		t.Errorf("Unexpected close report: %+v", dayClose.Report)
+	}
+	if len(breaks.List(models.BreakOpen, models.BreakPositionGap, testAddress)) != 1 {
+		t.Errorf("Expected a position gap break to be raised")
+	}
+
+	t.Run("should not re-close a closed day unless forced", func(t *testing.T) {
+		if closes, _ := cs.Close(context.Background(), yesterday, false); len(closes) != 0 {
+			t.Errorf("Expected closed day to be skipped, got %d closes", len(closes))
+		}
+
+		// The missing fill turns up in a later fetch
+		fills = append(fills, FillResponse{Time: dayStart.Add(9*time.Hour + 30*time.Minute).UnixMilli(), Coin: "BTC", Side: "B", Price: "110", Size: "0.5", StartPosition: "1"})
+
+		closes, _ := cs.Close(context.Background(), yesterday, true)
+		if len(closes) != 1 || closes[0].Status != models.CloseClean || len(closes[0].Trades) != 3 {
+			t.Errorf("Expected forced re-close to be clean with 3 trades, got %+v", closes)
+		}
+		if stored := cs.Closes(testAddress, dayStart.Format("2006-01-02")); len(stored) != 1 || stored[0].Status != models.CloseClean {
+			t.Errorf("Expected stored snapshot to be replaced, got %+v", stored)
+		}
+	})
+
+	t.Run("should refuse to close an unfinished day", func(t *testing.T) {
+		if _, err := cs.Close(context.Background(), time.Now(), false); err == nil {
+			t.Errorf("Expected error closing today")
+		}
+	})
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrNoConnectorFile is returned when a connector's SFTP folder has no file matching its pattern
+var ErrNoConnectorFile = errors.New("no matching file")
+
+// Connector pulls a tenant's external trade file from an HTTPS URL or an SFTP
+// folder, reconciles it against the address's fills and raises breaks for
+// whatever is left unmatched. A file that was already reconciled is skipped.
+type Connector struct {
+	cfg     models.ConnectorConfig
+	tenant  *Tenant
+	secrets Secrets
+	client  *http.Client
+	mu      sync.Mutex // One run at a time
+}
+
+// NewConnector creates a connector for tenant; cfg must have passed ValidateConnector
+func NewConnector(cfg models.ConnectorConfig, tenant *Tenant, secrets Secrets) *Connector {
+	if secrets == nil {
+		secrets = EnvSecrets{}
+	}
+	return &Connector{
+		cfg:     cfg,
+		tenant:  tenant,
+		secrets: secrets,
+		client:  &http.Client{Timeout: config.ConnectorTimeout},
+	}
+}
+
+// ValidateConnector checks a connector's settings and normalizes its address
+func ValidateConnector(cfg *models.ConnectorConfig) error {
+	if strings.TrimSpace(cfg.Name) == "" {
+		return errors.New("name is required")
+	}
+	cfg.Address = strings.ToLower(strings.TrimSpace(cfg.Address))
+	if !addressPattern.MatchString(cfg.Address) {
+		return fmt.Errorf("%q is not a valid address", cfg.Address)
+	}
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" {
+		return errors.New("url must be an absolute http, https or sftp URL")
+	}
+	switch u.Scheme {
+	case "http", "https":
+	case "sftp":
+		if u.User == nil || u.User.Username() == "" {
+			return errors.New("sftp url must name a user, e.g. sftp://user@host/dir")
+		}
+		if _, hasPassword := u.User.Password(); hasPassword {
+			return errors.New("sftp url must not contain a password; use authSecret")
+		}
+		if cfg.AuthSecret == "" && cfg.KeySecret == "" {
+			return errors.New("sftp needs authSecret or keySecret")
+		}
+		if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(cfg.HostKey)); err != nil {
+			return fmt.Errorf("hostKey must be the server's public key in authorized_keys format: %v", err)
+		}
+		if _, err := path.Match(cfg.Pattern, ""); err != nil {
+			return fmt.Errorf("pattern %q: %v", cfg.Pattern, err)
+		}
+	default:
+		return errors.New("url must be an absolute http, https or sftp URL")
+	}
+
+	if cfg.Format != "" && cfg.Format != FormatCSV && cfg.Format != FormatParquet {
+		return errors.New("format must be csv or parquet")
+	}
+	return nil
+}
+
+// RunScheduled pulls and reconciles the file; it is called by the scheduler
+func (c *Connector) RunScheduled() {
+	ctx, cancel := context.WithTimeout(context.Background(), config.ConnectorTimeout)
+	defer cancel()
+
+	recon, err := c.Run(ctx)
+	switch {
+	case err != nil:
+		log.Printf("[%s] Connector %s failed: %v", c.tenant.ID, c.cfg.Name, err)
+	case recon == nil:
+		log.Printf("[%s] Connector %s: file unchanged", c.tenant.ID, c.cfg.Name)
+	default:
+		log.Printf("[%s] Connector %s reconciled %s: %d rows, %d fills and %d rows unmatched",
+			c.tenant.ID, c.cfg.Name, recon.File, recon.ExternalRows, len(recon.UnmatchedInternal), len(recon.UnmatchedExternal))
+	}
+}
+
+// Run pulls the file and, unless it was already reconciled, refreshes the
+// address's fills over the file's time range, matches them with the default
+// tolerances, records the reconciliation and raises breaks. It returns nil
+// for an unchanged file.
+func (c *Connector) Run(ctx context.Context) (*models.ExternalRecon, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name, data, err := c.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256Hex(data)
+	if c.tenant.Reconciliations.LastDigest(c.cfg.Address, c.cfg.Name) == digest {
+		return nil, nil
+	}
+
+	format := c.cfg.Format
+	if format == "" {
+		format = FormatCSV
+		if strings.EqualFold(path.Ext(name), ".parquet") {
+			format = FormatParquet
+		}
+	}
+	external, err := DecodeTrades(format, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trade file %s: %w", name, err)
+	}
+
+	// Make sure the cache covers the file before matching
+	if len(external) > 0 {
+		earliest := external[0].Time
+		for _, row := range external {
+			if row.Time.Before(earliest) {
+				earliest = row.Time
+			}
+		}
+		days := int(math.Ceil(time.Since(earliest).Hours()/24)) + 1
+		var partial *PartialError
+		if _, err := c.tenant.ReconService.RefreshCache(ctx, c.cfg.Address, days); err != nil && !errors.As(err, &partial) {
+			return nil, fmt.Errorf("failed to refresh %s: %w", c.cfg.Address, err)
+		}
+	}
+	cached, _ := c.tenant.ReconService.CachedTrades(c.cfg.Address)
+
+	recon := ReconcileExternal(c.cfg.Address, cached, external, DefaultMatchTolerance())
+	recon.Label = c.tenant.ReconService.Label(c.cfg.Address)
+	recon.Source = c.cfg.Name
+	recon.File = name
+	recon.Digest = digest
+	recon = c.tenant.Reconciliations.Record(recon)
+	c.tenant.Breaks.RecordExternal(recon)
+	return &recon, nil
+}
+
+// fetch downloads the file and returns its name and contents
+func (c *Connector) fetch(ctx context.Context) (string, []byte, error) {
+	u, err := url.Parse(c.cfg.URL)
+	if err != nil {
+		return "", nil, err
+	}
+	if u.Scheme == "sftp" {
+		return c.fetchSFTP(ctx, u)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.URL, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	if c.cfg.AuthSecret != "" {
+		auth, err := c.secret(c.cfg.AuthSecret)
+		if err != nil {
+			return "", nil, err
+		}
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("GET %s returned %d", u.Redacted(), resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, config.ImportMaxBytes+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(data) > config.ImportMaxBytes {
+		return "", nil, fmt.Errorf("file is larger than %d bytes", config.ImportMaxBytes)
+	}
+	return path.Base(u.Path), data, nil
+}
+
+// fetchSFTP downloads the newest file in the SFTP folder that matches the pattern
+func (c *Connector) fetchSFTP(ctx context.Context, u *url.URL) (string, []byte, error) {
+	// Validated by ValidateConnector
+	hostKey, _, _, _, _ := ssh.ParseAuthorizedKey([]byte(c.cfg.HostKey))
+	sshConfig := &ssh.ClientConfig{
+		User:            u.User.Username(),
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         config.ConnectorTimeout,
+	}
+	if c.cfg.KeySecret != "" {
+		key, err := c.secret(c.cfg.KeySecret)
+		if err != nil {
+			return "", nil, err
+		}
+		signer, err := ssh.ParsePrivateKey([]byte(key))
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid private key in %s: %w", c.cfg.KeySecret, err)
+		}
+		sshConfig.Auth = append(sshConfig.Auth, ssh.PublicKeys(signer))
+	}
+	if c.cfg.AuthSecret != "" {
+		password, err := c.secret(c.cfg.AuthSecret)
+		if err != nil {
+			return "", nil, err
+		}
+		sshConfig.Auth = append(sshConfig.Auth, ssh.Password(password))
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "22")
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", host)
+	if err != nil {
+		return "", nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, host, sshConfig)
+	if err != nil {
+		conn.Close()
+		return "", nil, err
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	remote, err := sftp.NewClient(client)
+	if err != nil {
+		return "", nil, err
+	}
+	defer remote.Close()
+	return c.pullNewest(remote, u.Path)
+}
+
+// pullNewest reads the most recently modified file in dir that matches the
+// pattern, taking the greatest name among files modified at the same time
+func (c *Connector) pullNewest(remote *sftp.Client, dir string) (string, []byte, error) {
+	if dir == "" {
+		dir = "."
+	}
+	pattern := c.cfg.Pattern
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	entries, err := remote.ReadDir(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	var files []os.FileInfo
+	for _, entry := range entries {
+		if matched, _ := path.Match(pattern, entry.Name()); matched && entry.Mode().IsRegular() {
+			files = append(files, entry)
+		}
+	}
+	if len(files) == 0 {
+		return "", nil, fmt.Errorf("%w for %q in %s", ErrNoConnectorFile, pattern, dir)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].ModTime().Equal(files[j].ModTime()) {
+			return files[i].ModTime().After(files[j].ModTime())
+		}
+		return files[i].Name() > files[j].Name()
+	})
+
+	newest := files[0]
+	if newest.Size() > config.ImportMaxBytes {
+		return "", nil, fmt.Errorf("%s is larger than %d bytes", newest.Name(), config.ImportMaxBytes)
+	}
+	file, err := remote.Open(path.Join(dir, newest.Name()))
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+	// The file may have grown since it was listed
+	data, err := io.ReadAll(io.LimitReader(file, config.ImportMaxBytes+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if int64(len(data)) > config.ImportMaxBytes {
+		return "", nil, fmt.Errorf("%s is larger than %d bytes", newest.Name(), config.ImportMaxBytes)
+	}
+	return newest.Name(), data, nil
+}
+
+// secret reads a named credential; a missing secret is an error
+func (c *Connector) secret(name string) (string, error) {
+	value, err := LookupSecret(c.secrets, name, "")
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", fmt.Errorf("secret %s is not set", name)
+	}
+	return value, nil
+}
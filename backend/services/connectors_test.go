@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hyperliquid-recon/models"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// Test pulling an external file over HTTPS and reconciling it
+func TestConnector(t *testing.T) {
+	start := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	fills := []FillResponse{
+		{Time: start.UnixMilli(), Coin: "BTC", Side: "B", Price: "100", Size: "1", StartPosition: "0", Fee: "0.1"},
+		{Time: start.Add(time.Hour).UnixMilli(), Coin: "ETH", Side: "A", Price: "10", Size: "2", StartPosition: "0", Fee: "0.1"},
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req UserFillsRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		result := []FillResponse{}
+		for _, fill := range fills {
+			if fill.Time >= *req.StartTime && fill.Time <= *req.EndTime {
+				result = append(result, fill)
+			}
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	defer upstream.Close()
+
+	file, _ := EncodeTrades(FormatCSV, []models.Trade{
+		createTestTrade(start.UTC().Format(time.RFC3339), "BTC", "B", 100, 1),
+		createTestTrade(start.Add(time.Hour).UTC().Format(time.RFC3339), "ETH", "A", 10, 2),
+		createTestTrade(start.Add(2*time.Hour).UTC().Format(time.RFC3339), "HYPE", "B", 20, 5),
+	})
+	broker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer broker-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write(file)
+	}))
+	defer broker.Close()
+	t.Setenv("RECON_TEST_BROKER_AUTH", "Bearer broker-token")
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = upstream.URL
+	breaks, _ := NewBreakStore("")
+	recons, _ := NewExternalReconStore("")
+	tenant := &Tenant{ID: "default", ReconService: rs, Breaks: breaks, Reconciliations: recons}
+
+	cfg := models.ConnectorConfig{
+		Name:       "prime",
+		Address:    testAddress,
+		URL:        broker.URL + "/exports/fills.csv",
+		Schedule:   "0 6 * * *",
+		AuthSecret: "RECON_TEST_BROKER_AUTH",
+	}
+	if err := ValidateConnector(&cfg); err != nil {
+		t.Fatalf("Expected a valid connector, got %v", err)
+	}
+	connector := NewConnector(cfg, tenant, EnvSecrets{})
+
+	t.Run("should reconcile the file and raise breaks for unmatched rows", func(t *testing.T) {
+		recon, err := connector.Run(context.Background())
+		if err != nil || recon == nil {
+			t.Fatalf("Expected a reconciliation, got %v", err)
+		}
+		if recon.Source != "prime" || recon.File != "fills.csv" || recon.Exact != 2 || len(recon.UnmatchedExternal) != 1 {
+			t.Errorf("Unexpected reconciliation %+v", recon)
+		}
+		raised := breaks.List(models.BreakOpen, models.BreakExternal, testAddress)
+		if len(raised) != 1 || raised[0].Coin != "HYPE" {
+			t.Errorf("Expected one HYPE break, got %+v", raised)
+		}
+	})
+
+	t.Run("should skip a file that was already reconciled", func(t *testing.T) {
+		recon, err := connector.Run(context.Background())
+		if err != nil || recon != nil {
+			t.Errorf("Expected the unchanged file to be skipped, got %+v (%v)", recon, err)
+		}
+	})
+
+	t.Run("should reject invalid connectors", func(t *testing.T) {
+		for _, invalid := range []models.ConnectorConfig{
+			{Name: "ftp", Address: testAddress, URL: "ftp://broker.example.com/fills.csv"},
+			{Name: "no-user", Address: testAddress, URL: "sftp://broker.example.com/out", AuthSecret: "X", HostKey: "ssh-ed25519 AAAA"},
+			{Name: "no-host-key", Address: testAddress, URL: "sftp://recon@broker.example.com/out", AuthSecret: "X"},
+			{Name: "bad-address", Address: "0x1", URL: "https://broker.example.com/fills.csv"},
+		} {
+			if err := ValidateConnector(&invalid); err == nil {
+				t.Errorf("Expected connector %s to be rejected", invalid.Name)
+			}
+		}
+	})
+}
+
+// Test listing and reading files over SFTP
+func TestSFTPPullNewest(t *testing.T) {
+	modified := time.Date(2025, 1, 2, 6, 0, 0, 0, time.UTC)
+	files := testSFTPFiles{
+		{name: "fills_20250101.csv", data: "old", modTime: modified.Add(-24 * time.Hour)},
+		{name: "fills_20250102.csv", data: "new", modTime: modified},
+		{name: "readme.txt", data: "ignored", modTime: modified.Add(time.Hour)},
+	}
+
+	serverConn, clientConn := net.Pipe()
+	handlers := sftp.InMemHandler()
+	handlers.FileGet, handlers.FileList = files, files
+	server := sftp.NewRequestServer(serverConn, handlers)
+	go server.Serve()
+	defer server.Close()
+
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	if err != nil {
+		t.Fatalf("Expected an SFTP session, got %v", err)
+	}
+	defer client.Close()
+
+	t.Run("should read the newest matching file", func(t *testing.T) {
+		connector := NewConnector(models.ConnectorConfig{Name: "custodian", Pattern: "fills_*.csv"}, nil, nil)
+		name, data, err := connector.pullNewest(client, "/out")
+		if err != nil || name != "fills_20250102.csv" || string(data) != "new" {
+			t.Errorf("Expected the newest matching file, got %s %q (%v)", name, data, err)
+		}
+	})
+
+	t.Run("should report a folder without a matching file", func(t *testing.T) {
+		connector := NewConnector(models.ConnectorConfig{Name: "custodian", Pattern: "*.xlsx"}, nil, nil)
+		if _, _, err := connector.pullNewest(client, "/out"); !errors.Is(err, ErrNoConnectorFile) {
+			t.Errorf("Expected ErrNoConnectorFile, got %v", err)
+		}
+	})
+}
+
+// testSFTPFiles is a read-only SFTP folder whose files have fixed
+// modification times, which the in-memory handler can't set
+type testSFTPFiles []testSFTPFile
+
+type testSFTPFile struct {
+	name    string
+	data    string
+	modTime time.Time
+}
+
+func (f testSFTPFile) Name() string       { return f.name }
+func (f testSFTPFile) Size() int64        { return int64(len(f.data)) }
+func (f testSFTPFile) Mode() os.FileMode  { return 0o644 }
+func (f testSFTPFile) ModTime() time.Time { return f.modTime }
+func (f testSFTPFile) IsDir() bool        { return false }
+func (f testSFTPFile) Sys() any           { return nil }
+
+// find returns the file at p, any folder being the same folder
+func (files testSFTPFiles) find(p string) (testSFTPFile, bool) {
+	for _, file := range files {
+		if file.name == path.Base(p) {
+			return file, true
+		}
+	}
+	return testSFTPFile{}, false
+}
+
+func (files testSFTPFiles) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	file, exists := files.find(r.Filepath)
+	if !exists {
+		return nil, os.ErrNotExist
+	}
+	return strings.NewReader(file.data), nil
+}
+
+func (files testSFTPFiles) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	if r.Method == "List" {
+		entries := make(testSFTPListing, len(files))
+		for i, file := range files {
+			entries[i] = file
+		}
+		return entries, nil
+	}
+	file, exists := files.find(r.Filepath)
+	if !exists {
+		return nil, os.ErrNotExist
+	}
+	return testSFTPListing{file}, nil
+}
+
+// testSFTPListing lists a folder's entries to the SFTP server
+type testSFTPListing []os.FileInfo
+
+func (l testSFTPListing) ListAt(entries []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(entries, l[offset:])
+	if n < len(entries) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"math"
+	"sort"
+	"time"
+)
+
+// startPositionTolerance absorbs float rounding in reported and summed sizes
+const startPositionTolerance = 1e-6
+
+// CheckStartPositions walks each coin's fills in time order and flags every fill
+// whose reported startPosition differs from the previous fill's startPosition
+// plus that fill's signed size. Fills without a reported startPosition are
+// skipped and counted as unchecked.
+func CheckStartPositions(trades []models.Trade) (discrepancies []models.PositionDiscrepancy, checked, unchecked int) {
+	discrepancies = []models.PositionDiscrepancy{}
+	previous := make(map[string]models.Trade) // key: coin
+
+	for _, trade := range trades {
+		if trade.StartPosition == nil {
+			unchecked++
+			delete(previous, trade.Coin)
+			continue
+		}
+		checked++
+
+		prev, exists := previous[trade.Coin]
+		previous[trade.Coin] = trade
+		if !exists {
+			continue
+		}
+
+		delta := models.Position(prev.Size)
+		if prev.Side == "A" {
+			delta = -delta
+		}
+		expected := (*prev.StartPosition + delta).Round()
+		reported := *trade.StartPosition
+
+		if math.Abs(float64(reported-expected)) > startPositionTolerance*math.Max(1, math.Abs(float64(expected))) {
+			discrepancies = append(discrepancies, models.PositionDiscrepancy{
+				Coin:         trade.Coin,
+				Date:         trade.Time.Format("2006-01-02"),
+				Time:         trade.Time,
+				PreviousTime: prev.Time,
+				Expected:     expected,
+				Reported:     reported,
+				Difference:   (reported - expected).Round(),
+			})
+		}
+	}
+
+	return discrepancies, checked, unchecked
+}
+
+// CheckConsistency checks all cached fills for address for start position gaps
+func (rs *ReconciliationService) CheckConsistency(address string) (models.ConsistencyReport, bool) {
+	trades, exists := rs.CachedTrades(address)
+	if !exists {
+		return models.ConsistencyReport{}, false
+	}
+
+	discrepancies, checked, unchecked := CheckStartPositions(trades)
+	rs.breaks.RecordChecks(address, discrepancies, nil)
+
+	return models.ConsistencyReport{
+		Address:       address,
+		Label:         rs.Label(address),
+		CheckedFills:  checked,
+		Unchecked:     unchecked,
+		Consistent:    len(discrepancies) == 0,
+		Gaps:          groupGaps(discrepancies),
+		Discrepancies: discrepancies,
+		CheckedAt:     time.Now(),
+	}, true
+}
+
+// groupGaps totals discrepancies per coin and day, newest day first
+func groupGaps(discrepancies []models.PositionDiscrepancy) []models.CoinDayGaps {
+	byKey := make(map[string]*models.CoinDayGaps)
+	for _, d := range discrepancies {
+		key := d.Date + "_" + d.Coin
+		gaps, exists := byKey[key]
+		if !exists {
+			gaps = &models.CoinDayGaps{Coin: d.Coin, Date: d.Date}
+			byKey[key] = gaps
+		}
+		gaps.Count++
+		gaps.NetDifference = (gaps.NetDifference + d.Difference).Round()
+	}
+
+	result := make([]models.CoinDayGaps, 0, len(byKey))
+	for _, gaps := range byKey {
+		result = append(result, *gaps)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Date != result[j].Date {
+			return result[i].Date > result[j].Date
+		}
+		return result[i].Coin < result[j].Coin
+	})
+	return result
+}
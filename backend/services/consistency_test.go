@@ -0,0 +1,70 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"testing"
+)
+
+// Test CheckStartPositions gap detection
+func TestCheckStartPositions(t *testing.T) {
+	fill := func(timestamp, coin, side string, size float64, startPosition models.Position) models.Trade {
+		trade := createTestTrade(timestamp, coin, side, 100, size)
+		trade.StartPosition = &startPosition
+		return trade
+	}
+
+	t.Run("should accept a consistent fill stream", func(t *testing.T) {
+		discrepancies, checked, _ := CheckStartPositions([]models.Trade{
+			fill("2025-01-01T10:00:00Z", "BTC", "B", 1, 0),
+			fill("2025-01-01T10:30:00Z", "ETH", "A", 2, 5),
+			fill("2025-01-01T11:00:00Z", "BTC", "B", 0.5, 1),
+			fill("2025-01-01T12:00:00Z", "BTC", "A", 2, 1.5),
+			fill("2025-01-01T13:00:00Z", "ETH", "B", 1, 3),
+		})
+		if len(discrepancies) != 0 || checked != 5 {
+			t.Errorf("Expected no discrepancies in 5 checked fills, got %+v (%d checked)", discrepancies, checked)
+		}
+	})
+
+	t.Run("should flag a missing fill", func(t *testing.T) {
+		discrepancies, _, _ := CheckStartPositions([]models.Trade{
+			fill("2025-01-01T10:00:00Z", "BTC", "B", 1, 0),
+			// A 0.25 buy between these two was never fetched
+			fill("2025-01-01T12:00:00Z", "BTC", "A", 1, 1.25),
+		})
+		if len(discrepancies) != 1 {
+			t.Fatalf("Expected 1 discrepancy, got %d", len(discrepancies))
+		}
+		d := discrepancies[0]
+		if d.Coin != "BTC" || d.Expected != 1 || d.Reported != 1.25 || d.Difference != 0.25 {
+			t.Errorf("Unexpected discrepancy: %+v", d)
+		}
+	})
+
+	t.Run("should skip fills without a reported start position", func(t *testing.T) {
+		discrepancies, checked, unchecked := CheckStartPositions([]models.Trade{
+			fill("2025-01-01T10:00:00Z", "BTC", "B", 1, 0),
+			createTestTrade("2025-01-01T11:00:00Z", "BTC", "B", 100, 1),
+			fill("2025-01-01T12:00:00Z", "BTC", "A", 1, 2),
+		})
+		if len(discrepancies) != 0 || checked != 2 || unchecked != 1 {
+			t.Errorf("Expected unknown fill to break the chain, got %+v (%d checked, %d unchecked)", discrepancies, checked, unchecked)
+		}
+	})
+}
+
+// Test groupGaps per coin/day totals
+func TestGroupGaps(t *testing.T) {
+	gaps := groupGaps([]models.PositionDiscrepancy{
+		{Coin: "BTC", Date: "2025-01-01", Difference: 0.5},
+		{Coin: "BTC", Date: "2025-01-01", Difference: -0.25},
+		{Coin: "ETH", Date: "2025-01-02", Difference: 1},
+	})
+
+	if len(gaps) != 2 || gaps[0].Date != "2025-01-02" {
+		t.Fatalf("Expected 2 groups newest first, got %+v", gaps)
+	}
+	if gaps[1].Count != 2 || gaps[1].NetDifference != 0.25 {
+		t.Errorf("Unexpected BTC group: %+v", gaps[1])
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"time"
+)
+
+// ErrInvalidTimezone is returned for a time zone that isn't a known IANA name
+var ErrInvalidTimezone = errors.New("unknown timezone")
+
+// loadTimezone returns the IANA time zone name, or the zone of the configured
+// day basis if name is empty
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return dayLocation(models.DayBasis(config.DayBasis)), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrInvalidTimezone, name)
+	}
+	return loc, nil
+}
+
+// Consolidate reports the daily P&L of addresses from from to to
+// (YYYY-MM-DD, inclusive). Each address's days are bucketed in the time zone
+// saved for it in the address book, so a trader's day ends at their own
+// midnight. The roll-up adds up every address by UTC day, since days in
+// different zones don't line up. With no addresses, every saved address is
+// reported, or every cached one if none are saved. Addresses without cached
+// trades are listed as not cached.
+func (rs *ReconciliationService) Consolidate(addresses []string, from, to string) (models.ConsolidatedReport, error) {
+	if len(addresses) == 0 && rs.addressBook != nil {
+		for _, entry := range rs.addressBook.List() {
+			addresses = append(addresses, entry.Address)
+		}
+	}
+	if len(addresses) == 0 {
+		addresses = rs.CachedAddresses()
+	}
+	if _, err := time.Parse("2006-01-02", from); err != nil {
+		return models.ConsolidatedReport{}, fmt.Errorf("invalid date %q", from)
+	}
+	if _, err := time.Parse("2006-01-02", to); err != nil {
+		return models.ConsolidatedReport{}, fmt.Errorf("invalid date %q", to)
+	}
+
+	report := models.ConsolidatedReport{From: from, To: to, Accounts: []models.ConsolidatedAccount{}}
+	rollUp := make(map[string]*models.DailyPnL)
+	for _, address := range addresses {
+		trades, exists := rs.CachedTrades(address)
+		if !exists {
+			report.NotCached = append(report.NotCached, address)
+			continue
+		}
+
+		entry, _ := rs.addressBook.Entry(address)
+		loc, err := loadTimezone(entry.Timezone)
+		if err != nil {
+			// Saved zones were checked when they were set, but may since have left the zone database
+			loc = dayLocation(models.DayBasis(config.DayBasis))
+		}
+		account := models.ConsolidatedAccount{Address: address, Label: rs.Label(address), Timezone: loc.String()}
+		account.ConsolidatedTotals = consolidatedTotals(datesBetween(rs.buildDailyPnLIn(trades, loc), from, to))
+		report.Accounts = append(report.Accounts, account)
+
+		for date, day := range datesBetween(rs.buildDailyPnLIn(trades, time.UTC), from, to) {
+			total, exists := rollUp[date]
+			if !exists {
+				total = &models.DailyPnL{Date: date}
+				rollUp[date] = total
+			}
+			total.TradeCount += day.TradeCount
+			total.Volume += day.Volume
+			total.DailyPnL += day.DailyPnL
+		}
+	}
+	report.RollUp = consolidatedTotals(rollUp)
+	return report, nil
+}
+
+// datesBetween returns the days of dailyPnL from from to to, inclusive
+func datesBetween(dailyPnL map[string]*models.DailyPnL, from, to string) map[string]*models.DailyPnL {
+	for date := range dailyPnL {
+		if date < from || date > to {
+			delete(dailyPnL, date)
+		}
+	}
+	return dailyPnL
+}
+
+// consolidatedTotals sorts the days of dailyPnL newest first and totals them
+func consolidatedTotals(dailyPnL map[string]*models.DailyPnL) models.ConsolidatedTotals {
+	records, total := sortedDailyRecords(dailyPnL)
+	totals := models.ConsolidatedTotals{Days: records, TotalPnL: total}
+	for _, record := range records {
+		totals.TradeCount += record.TradeCount
+		totals.Volume += record.Volume
+	}
+	totals.Volume = totals.Volume.Round()
+	return totals
+}
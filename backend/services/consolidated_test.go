@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"testing"
+)
+
+// Test bucketing each address in its own time zone with a UTC roll-up
+func TestConsolidate(t *testing.T) {
+	const tokyo = "0x2222222222222222222222222222222222222222"
+	const uncached = "0x3333333333333333333333333333333333333333"
+
+	addressBook, err := NewAddressBook("", nil)
+	if err != nil {
+		t.Fatalf("Failed to create address book: %v", err)
+	}
+	rs := NewReconciliationService()
+	rs.UseAddressBook(addressBook)
+
+	for _, address := range []string{testAddress, tokyo} {
+		if _, err := addressBook.Save(address, address[:6]); err != nil {
+			t.Fatalf("Failed to save %s: %v", address, err)
+		}
+	}
+	if _, err := addressBook.SetTimezone(tokyo, "Mars/Olympus"); !errors.Is(err, ErrInvalidTimezone) {
+		t.Errorf("Expected ErrInvalidTimezone, got %v", err)
+	}
+	if _, err := addressBook.SetTimezone(uncached, "UTC"); !errors.Is(err, ErrAddressNotSaved) {
+		t.Errorf("Expected ErrAddressNotSaved, got %v", err)
+	}
+	addressBook.SetTimezone(testAddress, "UTC")
+	addressBook.SetTimezone(tokyo, "Asia/Tokyo")
+
+	rs.ImportTrades(testAddress, []models.Trade{createTestTrade("2025-06-01T12:00:00Z", "ETH", "A", 50, 1)})
+	// 19:00 on June 1 and 05:00 on June 2 in Tokyo, both June 1 in UTC
+	rs.ImportTrades(tokyo, []models.Trade{
+		createTestTrade("2025-06-01T10:00:00Z", "BTC", "A", 110, 1),
+		createTestTrade("2025-06-01T20:00:00Z", "BTC", "B", 100, 1),
+	})
+
+	report, err := rs.Consolidate([]string{testAddress, tokyo, uncached}, "2025-06-01", "2025-06-02")
+	if err != nil {
+		t.Fatalf("Consolidate failed: %v", err)
+	}
+	if len(report.Accounts) != 2 || len(report.NotCached) != 1 || report.NotCached[0] != uncached {
+		t.Fatalf("Expected 2 accounts and 1 uncached address, got %+v", report)
+	}
+
+	t.Run("should bucket an address in its own time zone", func(t *testing.T) {
+		account := report.Accounts[1]
+		if account.Timezone != "Asia/Tokyo" || len(account.Days) != 2 {
+			t.Fatalf("Expected 2 Tokyo days, got %+v", account)
+		}
+		if account.Days[0].Date != "2025-06-02" || account.Days[0].DailyPnL != -100 || account.Days[1].DailyPnL != 110 {
+			t.Errorf("Expected -100 on June 2 and 110 on June 1, got %+v", account.Days)
+		}
+		if account.TotalPnL != 10 || account.TradeCount != 2 {
+			t.Errorf("Expected 10 over 2 trades, got %v over %d", account.TotalPnL, account.TradeCount)
+		}
+	})
+
+	t.Run("should roll every address up by UTC day", func(t *testing.T) {
+		rollUp := report.RollUp
+		if len(rollUp.Days) != 1 || rollUp.Days[0].Date != "2025-06-01" || rollUp.Days[0].DailyPnL != 60 {
+			t.Errorf("Expected 60 on June 1, got %+v", rollUp.Days)
+		}
+		if rollUp.TradeCount != 3 || rollUp.Volume != 260 {
+			t.Errorf("Expected 3 trades worth 260, got %d worth %v", rollUp.TradeCount, rollUp.Volume)
+		}
+	})
+
+	t.Run("should leave out days outside the range", func(t *testing.T) {
+		report, _ := rs.Consolidate([]string{tokyo}, "2025-06-02", "2025-06-02")
+		if len(report.Accounts[0].Days) != 1 || len(report.RollUp.Days) != 0 {
+			t.Errorf("Expected one Tokyo day and no UTC days, got %+v", report)
+		}
+	})
+
+	t.Run("should default to the saved addresses", func(t *testing.T) {
+		report, _ := rs.Consolidate(nil, "2025-06-01", "2025-06-02")
+		if len(report.Accounts) != 2 || report.RollUp.TotalPnL != 60 {
+			t.Errorf("Expected both saved addresses, got %+v", report)
+		}
+	})
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCSVFormat is returned for unsupported CSV formatting options
+var ErrInvalidCSVFormat = errors.New("invalid CSV format")
+
+// Kinds of CSV export whose columns can be chosen
+const (
+	ExportKindTrades = "trades"
+	ExportKindPnL    = "pnl" // Daily P&L records
+)
+
+// CSVFormat is how numbers and dates are written in CSV exports, so files open
+// correctly in spreadsheets set up for other locales, and optionally which
+// columns are written. The zero value is not valid; use DefaultCSVFormat or
+// NewCSVFormat.
+type CSVFormat struct {
+	Decimal    string            // "." or ","
+	Thousands  string            // Grouping separator, "" for none
+	Delimiter  rune              // Field separator
+	DateFormat string            // e.g. "DD.MM.YYYY"; "" for ISO dates and RFC 3339 times
+	Columns    []string          // Columns to write, in order; all by default
+	Headers    map[string]string // Header of a column if not its name
+	dateLayout string            // DateFormat as a Go time layout
+}
+
+// DefaultCSVFormat is the machine-readable format trade imports expect
+var DefaultCSVFormat = CSVFormat{Decimal: ".", Delimiter: ','}
+
+// NewCSVFormat builds a CSV format from the decimal and thousands separators,
+// the field delimiter ("," ";" or "tab") and a date format made of YYYY, MM
+// and DD. Empty options keep their defaults, except that the delimiter
+// defaults to ";" when the decimal separator is ",".
+func NewCSVFormat(decimal, thousands, delimiter, dateFormat string) (CSVFormat, error) {
+	f := DefaultCSVFormat
+	switch decimal {
+	case "", ".":
+	case ",":
+		f.Decimal, f.Delimiter = ",", ';'
+	default:
+		return CSVFormat{}, fmt.Errorf("%w: decimal separator must be . or ,", ErrInvalidCSVFormat)
+	}
+
+	switch thousands {
+	case "", ",", ".", " ", "'":
+		if thousands != "" && thousands == f.Decimal {
+			return CSVFormat{}, fmt.Errorf("%w: thousands separator must differ from the decimal separator", ErrInvalidCSVFormat)
+		}
+		f.Thousands = thousands
+	default:
+		return CSVFormat{}, fmt.Errorf("%w: thousands separator must be , . ' or a space", ErrInvalidCSVFormat)
+	}
+
+	switch delimiter {
+	case "":
+	case ",", ";":
+		f.Delimiter = rune(delimiter[0])
+	case "tab":
+		f.Delimiter = '\t'
+	default:
+		return CSVFormat{}, fmt.Errorf("%w: delimiter must be , ; or tab", ErrInvalidCSVFormat)
+	}
+	if string(f.Delimiter) == f.Decimal || string(f.Delimiter) == f.Thousands {
+		return CSVFormat{}, fmt.Errorf("%w: delimiter must differ from the number separators", ErrInvalidCSVFormat)
+	}
+
+	if dateFormat != "" && dateFormat != "YYYY-MM-DD" {
+		separators := strings.NewReplacer("YYYY", "", "MM", "", "DD", "").Replace(dateFormat)
+		if strings.Count(dateFormat, "YYYY") != 1 || strings.Count(dateFormat, "MM") != 1 || strings.Count(dateFormat, "DD") != 1 ||
+			strings.Trim(separators, "-./ ") != "" {
+			return CSVFormat{}, fmt.Errorf("%w: date format must contain YYYY, MM and DD separated by - . / or spaces", ErrInvalidCSVFormat)
+		}
+		f.DateFormat = dateFormat
+		f.dateLayout = strings.NewReplacer("YYYY", "2006", "MM", "01", "DD", "02").Replace(dateFormat)
+	}
+	return f, nil
+}
+
+// CSVColumns returns the columns of a kind of CSV export in their default
+// order, or nil for an unknown kind
+func CSVColumns(kind string) []string {
+	var columns []string
+	switch kind {
+	case ExportKindTrades:
+		for _, column := range tradeColumns {
+			columns = append(columns, column.name)
+		}
+	case ExportKindPnL:
+		for _, column := range dailyPnLColumns {
+			columns = append(columns, column.name)
+		}
+	}
+	return columns
+}
+
+// WithColumns returns f writing only columns of a kind of export, in that
+// order, under the headers given for them. No columns means all of them.
+func (f CSVFormat) WithColumns(kind string, columns []string, headers map[string]string) (CSVFormat, error) {
+	available := CSVColumns(kind)
+	if available == nil {
+		return CSVFormat{}, fmt.Errorf("%w: unknown export kind %q (want trades or pnl)", ErrInvalidCSVFormat, kind)
+	}
+	seen := make(map[string]bool)
+	for _, column := range columns {
+		if !slices.Contains(available, column) {
+			return CSVFormat{}, fmt.Errorf("%w: unknown %s column %q (want %s)", ErrInvalidCSVFormat, kind, column, strings.Join(available, ", "))
+		}
+		if seen[column] {
+			return CSVFormat{}, fmt.Errorf("%w: column %q is listed twice", ErrInvalidCSVFormat, column)
+		}
+		seen[column] = true
+	}
+	for column, header := range headers {
+		if !slices.Contains(available, column) {
+			return CSVFormat{}, fmt.Errorf("%w: header given for unknown %s column %q", ErrInvalidCSVFormat, kind, column)
+		}
+		if strings.TrimSpace(header) == "" || strings.ContainsAny(header, "\r\n") {
+			return CSVFormat{}, fmt.Errorf("%w: header of %q must be a non-empty single line", ErrInvalidCSVFormat, column)
+		}
+	}
+
+	f.Columns = slices.Clone(columns)
+	f.Headers = maps.Clone(headers)
+	return f, nil
+}
+
+// IsDefault reports whether f writes numbers and dates in the machine-readable default format
+func (f CSVFormat) IsDefault() bool {
+	return f.Decimal == DefaultCSVFormat.Decimal && f.Thousands == "" && f.Delimiter == DefaultCSVFormat.Delimiter && f.DateFormat == ""
+}
+
+// float formats v with the minimum digits needed to round-trip it
+func (f CSVFormat) float(v float64) string {
+	s := formatFloat(v)
+	if f.Decimal == "." && f.Thousands == "" {
+		return s
+	}
+
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	integer, fraction, hasFraction := strings.Cut(s, ".")
+	if f.Thousands != "" {
+		var grouped strings.Builder
+		for i, digit := range integer {
+			if i > 0 && (len(integer)-i)%3 == 0 {
+				grouped.WriteString(f.Thousands)
+			}
+			grouped.WriteRune(digit)
+		}
+		integer = grouped.String()
+	}
+	if hasFraction {
+		return sign + integer + f.Decimal + fraction
+	}
+	return sign + integer
+}
+
+// optionalFloat formats v, or returns an empty string if it is nil
+func (f CSVFormat) optionalFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return f.float(*v)
+}
+
+// int formats a count, grouped like floats
+func (f CSVFormat) int(v int) string {
+	if f.Thousands == "" {
+		return strconv.Itoa(v)
+	}
+	return f.float(float64(v))
+}
+
+// time formats t in UTC, as RFC 3339 or the date format followed by the time of day
+func (f CSVFormat) time(t time.Time) string {
+	if f.dateLayout == "" {
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	return t.UTC().Format(f.dateLayout + " 15:04:05.000")
+}
+
+// date reformats a YYYY-MM-DD date, leaving anything else unchanged
+func (f CSVFormat) date(date string) string {
+	if f.dateLayout == "" {
+		return date
+	}
+	parsed, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return parsed.Format(f.dateLayout)
+}
+
+// writeFooter writes a comment line describing a non-default format, so a
+// reader can tell how to parse the file
+func (f CSVFormat) writeFooter(w io.Writer) error {
+	if f.IsDefault() {
+		return nil
+	}
+	delimiter := string(f.Delimiter)
+	if f.Delimiter == '\t' {
+		delimiter = "tab"
+	}
+	dateFormat := f.DateFormat
+	if dateFormat == "" {
+		dateFormat = "YYYY-MM-DD"
+	}
+	_, err := fmt.Fprintf(w, "# format decimal=%q thousands=%q delimiter=%q date=%q\n", f.Decimal, f.Thousands, delimiter, dateFormat)
+	return err
+}
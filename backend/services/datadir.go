@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"hyperliquid-recon/models"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// dataDirPerm keeps the data directory private to the service's user and group
+const dataDirPerm = 0o750
+
+// DataDir is the standard layout of a data directory, so a single binary needs
+// nothing but a directory to keep its state in:
+//
+//	db/                 address book, breaks, closes, periods, ledger, event log, runs and trade snapshots
+//	db/tenants/<id>/    the same for each hosted tenant without its own dataDir
+//	exports/            scheduled exports in the S3 partition layout, if no bucket is configured
+//	logs/recon.log      a copy of the log
+//	fixtures/           recorded Hyperliquid tapes
+//	archive/            raw fills responses, if RECON_FILL_ARCHIVE=1
+//	downloads/          generated files behind signed download URLs, until they expire
+//	restore.tar.zst     a snapshot uploaded to be restored on the next startup
+type DataDir struct {
+	Root     string
+	DB       string
+	Exports  string
+	Logs     string
+	Fixtures string
+}
+
+// NewDataDir returns the layout of the data directory at root
+func NewDataDir(root string) DataDir {
+	root = filepath.Clean(root)
+	return DataDir{
+		Root:     root,
+		DB:       filepath.Join(root, "db"),
+		Exports:  filepath.Join(root, "exports"),
+		Logs:     filepath.Join(root, "logs"),
+		Fixtures: filepath.Join(root, "fixtures"),
+	}
+}
+
+// PrepareDataDir creates any part of the layout at root that doesn't exist yet,
+// readable only by the service's user and group. Existing directories are left
+// as they are, but a warning is logged if other users can write to them.
+func PrepareDataDir(root string) (DataDir, error) {
+	d := NewDataDir(root)
+	for _, dir := range []string{d.Root, d.DB, d.Exports, d.Logs, d.Fixtures} {
+		if err := os.MkdirAll(dir, dataDirPerm); err != nil {
+			return DataDir{}, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return DataDir{}, err
+		}
+		if info.Mode().Perm()&0o002 != 0 {
+			log.Printf("Warning: data directory %s is writable by other users", dir)
+		}
+	}
+	return d, nil
+}
+
+// TenantDir returns the directory a tenant's storage is kept in
+func (d DataDir) TenantDir(id string) string {
+	if id == DefaultTenantID {
+		return d.DB
+	}
+	return filepath.Join(d.DB, "tenants", id)
+}
+
+// PendingRestore returns the path a snapshot is staged at until it is restored on startup
+func (d DataDir) PendingRestore() string {
+	return filepath.Join(d.Root, "restore.tar.zst")
+}
+
+// OpenLog opens logs/recon.log for appending
+func (d DataDir) OpenLog() (*os.File, error) {
+	return os.OpenFile(filepath.Join(d.Logs, "recon.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+}
+
+// storageFiles maps the file names a tenant's stores are saved under in its
+// data directory to the settings holding their paths
+func storageFiles(cfg *models.TenantConfig) map[string]*string {
+	return map[string]*string{
+		"addressbook.json":      &cfg.AddressBookFile,
+		"breaks.json":           &cfg.BreaksFile,
+		"closes.json":           &cfg.ClosesFile,
+		"periods.json":          &cfg.PeriodsFile,
+		"ledger.json":           &cfg.LedgerFile,
+		"events.jsonl":          &cfg.EventsFile,
+		"runs.json":             &cfg.RunsFile,
+		"snapshots.json":        &cfg.SnapshotsFile,
+		"reconciliations.json":  &cfg.ReconciliationsFile,
+		"export-templates.json": &cfg.ExportTemplatesFile,
+		"views.json":            &cfg.ViewsFile,
+		"quarantine.json":       &cfg.QuarantineFile,
+	}
+}
+
+// DirStore writes exported objects to files under a directory, keyed the same
+// way as in a bucket, so the exports can be read back with -import
+type DirStore struct {
+	dir string
+}
+
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{dir: dir}
+}
+
+// Location returns the directory objects are written to
+func (s *DirStore) Location() string {
+	return s.dir
+}
+
+// PutObject writes body to the file at key. It writes to a temporary file first
+// so a crash can't leave a truncated export behind.
+func (s *DirStore) PutObject(key, contentType string, body []byte) error {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), dataDirPerm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, body, 0o640); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	return os.Rename(tmp, path)
+}
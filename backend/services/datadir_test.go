@@ -0,0 +1,89 @@
+package services
+
+import (
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test the data directory layout and the storage placed in it
+func TestDataDir(t *testing.T) {
+	t.Run("should create the layout on first run", func(t *testing.T) {
+		root := filepath.Join(t.TempDir(), "recon")
+		dataDir, err := PrepareDataDir(root)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		for _, dir := range []string{dataDir.DB, dataDir.Exports, dataDir.Logs, dataDir.Fixtures} {
+			info, err := os.Stat(dir)
+			if err != nil || !info.IsDir() {
+				t.Fatalf("Expected directory %s, got %v", dir, err)
+			}
+			if info.Mode().Perm()&0o007 != 0 {
+				t.Errorf("Expected %s to be private, got %s", dir, info.Mode().Perm())
+			}
+		}
+
+		if _, err := PrepareDataDir(root); err != nil {
+			t.Errorf("Expected an existing layout to be reused, got %v", err)
+		}
+	})
+
+	t.Run("should keep unset tenant stores in db", func(t *testing.T) {
+		root := t.TempDir()
+		defer func(dataDir, breaksFile string) { config.DataDir, config.BreaksFile = dataDir, breaksFile }(config.DataDir, config.BreaksFile)
+		config.DataDir = root
+		config.BreaksFile = filepath.Join(root, "elsewhere.json")
+
+		cfg := DefaultTenantConfig()
+		if cfg.EventsFile != filepath.Join(root, "db", "events.jsonl") {
+			t.Errorf("Expected the event log in db, got %q", cfg.EventsFile)
+		}
+		if cfg.BreaksFile != config.BreaksFile {
+			t.Errorf("Expected RECON_BREAKS_FILE to be kept, got %q", cfg.BreaksFile)
+		}
+
+		path := filepath.Join(root, "tenants.json")
+		if err := os.WriteFile(path, []byte(`[{"id": "alpha", "apiKeys": ["alpha-key"]}]`), 0o644); err != nil {
+			t.Fatalf("Failed to write tenants: %v", err)
+		}
+		configs, err := LoadTenantConfigs(path, EnvSecrets{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if configs[0].RunsFile != filepath.Join(root, "db", "tenants", "alpha", "runs.json") {
+			t.Errorf("Expected hosted tenant storage under db/tenants, got %q", configs[0].RunsFile)
+		}
+	})
+
+	t.Run("should export to a directory -import reads back", func(t *testing.T) {
+		dataDir, err := PrepareDataDir(t.TempDir())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		rs := NewReconciliationService()
+		rs.accountCache[testAddress] = &AccountCache{trades: []models.Trade{
+			createTestTrade("2025-01-01T10:00:00Z", "BTC", "B", 50000, 1),
+			createTestTrade("2025-01-02T10:00:00Z", "BTC", "A", 51000, 1),
+		}}
+		result, err := NewS3Exporter(rs, NewDirStore(dataDir.Exports), "", FormatCSV).Export()
+		if err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		if result.ObjectsWritten != 4 || result.Bucket != dataDir.Exports {
+			t.Errorf("Expected 4 files in %s, got %d in %s", dataDir.Exports, result.ObjectsWritten, result.Bucket)
+		}
+
+		imported, err := NewReconciliationService().ImportDirectory(dataDir.Exports)
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if len(imported) != 1 || imported[0].TradesAdded != 2 {
+			t.Errorf("Expected 2 trades imported back, got %+v", imported)
+		}
+	})
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ingestTallyKey is the context key of the tally of a refresh
+type ingestTallyKey struct{}
+
+// ingestTally counts what the fetches and merges of one refresh ran into
+type ingestTally struct {
+	fills         atomic.Int64 // Fills the API returned
+	parseFailures atomic.Int64 // Fills among them that couldn't be parsed
+	collisions    atomic.Int64 // Fills dropped for sharing a key with a different fill
+}
+
+// withIngestTally returns a context whose fetches and merges are counted in the returned tally
+func withIngestTally(ctx context.Context) (context.Context, *ingestTally) {
+	tally := &ingestTally{}
+	return context.WithValue(ctx, ingestTallyKey{}, tally), tally
+}
+
+// tallyFrom returns the tally of ctx, or nil if its fetches aren't counted
+func tallyFrom(ctx context.Context) *ingestTally {
+	tally, _ := ctx.Value(ingestTallyKey{}).(*ingestTally)
+	return tally
+}
+
+// addFills counts a batch of fetched fills; it does nothing on a nil tally
+func (t *ingestTally) addFills(fills, failed int) {
+	if t == nil {
+		return
+	}
+	t.fills.Add(int64(fills))
+	t.parseFailures.Add(int64(failed))
+}
+
+// addCollisions counts fills lost to key collisions; it does nothing on a nil tally
+func (t *ingestTally) addCollisions(collisions int) {
+	if t == nil {
+		return
+	}
+	t.collisions.Add(int64(collisions))
+}
+
+// keyCollisions counts the trades that share a millisecond, coin and side
+// with an earlier, different trade, and so would be dropped by mergeTrades.
+// Identical repeats, such as a fill returned by two pages, aren't counted.
+func keyCollisions(trades []models.Trade) int {
+	trades = sortedByTime(trades)
+	collisions := 0
+	for group := 0; group < len(trades); {
+		end := group
+		for end < len(trades) && trades[end].Time.UnixMilli() == trades[group].Time.UnixMilli() {
+			end++
+		}
+		for i := group + 1; i < end; i++ {
+			for j := group; j < i; j++ {
+				if trades[j].Coin == trades[i].Coin && trades[j].Side == trades[i].Side && !sameFill(trades[j], trades[i]) {
+					collisions++
+					break
+				}
+			}
+		}
+		group = end
+	}
+	return collisions
+}
+
+// ingestCheck is what a refresh of an address left in its cache, for the data-quality checks
+type ingestCheck struct {
+	address       string
+	label         string
+	trades        []models.Trade // Every cached trade, in time order
+	coverageStart time.Time
+	missing       []models.TimeRange
+	discrepancies []models.PositionDiscrepancy
+	tally         *ingestTally
+}
+
+// DataQualityMonitor raises alerts for anomalies found while ingesting
+// trades: an address that usually trades going a day without fills, fills
+// that couldn't be parsed, fills lost to key collisions in a merge, and
+// coverage gaps. Alerts go to the same webhook and email recipients as the
+// end-of-day close. An anomaly isn't raised again within DataQualityCooldown.
+type DataQualityMonitor struct {
+	webhooks *WebhookDispatcher
+	mailer   *Mailer
+	settings func() models.AlertConfig // Where alerts are sent and how the checks are tuned
+	raised   map[string]time.Time      // key: anomaly key; when it was last raised
+	recent   []models.DataQualityAlert // Oldest first
+	push     *PushHub                  // Optional; alerts are also sent to its subscribers
+	mu       sync.Mutex
+}
+
+// NewDataQualityMonitor creates a monitor that reads its alert settings from settings on every check
+func NewDataQualityMonitor(webhooks *WebhookDispatcher, mailer *Mailer, settings func() models.AlertConfig) *DataQualityMonitor {
+	return &DataQualityMonitor{
+		webhooks: webhooks,
+		mailer:   mailer,
+		settings: settings,
+		raised:   make(map[string]time.Time),
+	}
+}
+
+// UsePushHub sets the hub live subscribers are sent each alert through
+func (m *DataQualityMonitor) UsePushHub(push *PushHub) {
+	m.push = push
+}
+
+// Recent returns the alerts raised for address (for every address if it is empty), newest first
+func (m *DataQualityMonitor) Recent(address string) []models.DataQualityAlert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alerts := []models.DataQualityAlert{}
+	for i := len(m.recent) - 1; i >= 0; i-- {
+		if address == "" || m.recent[i].Address == address {
+			alerts = append(alerts, m.recent[i])
+		}
+	}
+	return alerts
+}
+
+// dataQualityThresholds fills the zero fields of cfg with the defaults
+func dataQualityThresholds(cfg *models.DataQualityConfig) models.DataQualityConfig {
+	thresholds := models.DataQualityConfig{ActiveDays: config.DataQualityActiveDays, ParseFailureRate: config.DataQualityParseFailureRate}
+	if cfg == nil {
+		return thresholds
+	}
+	thresholds.Mute = cfg.Mute
+	if cfg.ActiveDays > 0 {
+		thresholds.ActiveDays = cfg.ActiveDays
+	}
+	if cfg.ParseFailureRate > 0 {
+		thresholds.ParseFailureRate = cfg.ParseFailureRate
+	}
+	return thresholds
+}
+
+// validateDataQuality checks data-quality settings from the config file
+func validateDataQuality(cfg models.DataQualityConfig) error {
+	for _, kind := range cfg.Mute {
+		switch kind {
+		case models.AnomalyNoFills, models.AnomalyParseFailures, models.AnomalyKeyCollisions, models.AnomalyCoverageGap:
+		default:
+			return fmt.Errorf("mute: unknown anomaly %q (want no_fills, parse_failures, key_collisions or coverage_gap)", kind)
+		}
+	}
+	if cfg.ActiveDays < 0 || cfg.ActiveDays > 7 {
+		return fmt.Errorf("activeDays must be at most 7")
+	}
+	if cfg.ParseFailureRate < 0 || cfg.ParseFailureRate > 1 {
+		return fmt.Errorf("parseFailureRate must be between 0 and 1")
+	}
+	return nil
+}
+
+// check runs the data-quality checks on a refresh and raises an alert for
+// each new anomaly, returning them. It is safe to call on a nil monitor.
+func (m *DataQualityMonitor) check(c ingestCheck, now time.Time) []models.DataQualityAlert {
+	if m == nil {
+		return nil
+	}
+	settings := m.settings()
+	thresholds := dataQualityThresholds(settings.DataQuality)
+
+	type anomaly struct {
+		kind    models.AnomalyKind
+		key     string // Tells one occurrence of the kind from another
+		message string
+	}
+	var found []anomaly
+
+	if c.tally != nil {
+		fills, failed := c.tally.fills.Load(), c.tally.parseFailures.Load()
+		if failed > 0 && float64(failed) > float64(fills)*thresholds.ParseFailureRate {
+			found = append(found, anomaly{models.AnomalyParseFailures, "",
+				fmt.Sprintf("%d of %d fetched fills couldn't be parsed and were skipped", failed, fills)})
+		}
+		if collisions := c.tally.collisions.Load(); collisions > 0 {
+			found = append(found, anomaly{models.AnomalyKeyCollisions, "",
+				fmt.Sprintf("%d fetched fills shared a millisecond, coin and side with a different fill and were merged away", collisions)})
+		}
+	}
+
+	for _, missing := range c.missing {
+		found = append(found, anomaly{models.AnomalyCoverageGap, missing.Start.UTC().Format(time.RFC3339),
+			fmt.Sprintf("fills from %s to %s couldn't be fetched", missing.Start.Format(time.RFC3339), missing.End.Format(time.RFC3339))})
+	}
+	for _, gap := range groupGaps(c.discrepancies) {
+		found = append(found, anomaly{models.AnomalyCoverageGap, gap.Coin + "/" + gap.Date,
+			fmt.Sprintf("%d %s start positions on %s don't follow from the fills before them; fills may be missing", gap.Count, gap.Coin, gap.Date)})
+	}
+
+	if day, ok := quietDay(c, thresholds.ActiveDays, now); ok {
+		found = append(found, anomaly{models.AnomalyNoFills, day,
+			fmt.Sprintf("no fills on %s, though the address traded on at least %d of the 7 days before", day, thresholds.ActiveDays)})
+	}
+
+	m.mu.Lock()
+	for key, at := range m.raised {
+		if now.Sub(at) >= config.DataQualityCooldown {
+			delete(m.raised, key)
+		}
+	}
+	var alerts []models.DataQualityAlert
+	for _, a := range found {
+		key := fmt.Sprintf("%s/%s/%s", a.kind, c.address, a.key)
+		if _, raised := m.raised[key]; raised || slices.Contains(thresholds.Mute, a.kind) {
+			continue
+		}
+		m.raised[key] = now
+		alerts = append(alerts, models.DataQualityAlert{Kind: a.kind, Address: c.address, Label: c.label, Message: a.message, RaisedAt: now})
+	}
+	m.recent = append(m.recent, alerts...)
+	if excess := len(m.recent) - config.DataQualityHistoryLimit; excess > 0 {
+		m.recent = append(m.recent[:0], m.recent[excess:]...)
+	}
+	m.mu.Unlock()
+
+	if len(alerts) > 0 {
+		m.emit(settings, c.address, alerts)
+	}
+	return alerts
+}
+
+// quietDay returns the last finished day, on the configured day basis, if
+// the address had no fills on it but traded on at least activeDays of the 7
+// days before. Days the cache doesn't fully cover aren't judged.
+func quietDay(c ingestCheck, activeDays int, now time.Time) (string, bool) {
+	location := dayLocation(models.DayBasis(config.DayBasis))
+	local := now.In(location)
+	dayEnd := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	dayStart := dayEnd.AddDate(0, 0, -1)
+	weekStart := dayStart.AddDate(0, 0, -7)
+	if c.coverageStart.After(weekStart) {
+		return "", false
+	}
+	for _, missing := range c.missing {
+		if missing.Start.Before(dayEnd) && missing.End.After(weekStart) {
+			return "", false
+		}
+	}
+
+	active := make(map[string]bool)
+	for _, trade := range c.trades {
+		if trade.Time.Before(weekStart) || !trade.Time.Before(dayEnd) {
+			continue
+		}
+		if !trade.Time.Before(dayStart) {
+			return "", false
+		}
+		active[trade.Time.In(location).Format("2006-01-02")] = true
+	}
+	if len(active) < activeDays {
+		return "", false
+	}
+	return dayStart.Format("2006-01-02"), true
+}
+
+// emit sends new alerts for address as a "dataquality.alert" webhook and push
+// event each and one email listing them all
+func (m *DataQualityMonitor) emit(settings models.AlertConfig, address string, alerts []models.DataQualityAlert) {
+	for _, alert := range alerts {
+		log.Printf("Data-quality alert for %s (%s): %s", address, alert.Kind, alert.Message)
+		m.push.publish(PushAlert, address, alert)
+		if settings.EODWebhookURL != "" && m.webhooks != nil {
+			if _, err := m.webhooks.Enqueue(settings.EODWebhookURL, "dataquality.alert", alert); err != nil {
+				log.Printf("Failed to queue data-quality webhook: %v", err)
+			}
+		}
+	}
+
+	if len(settings.EODReportTo) == 0 || m.mailer == nil {
+		return
+	}
+	name := address
+	if label := alerts[0].Label; label != "" {
+		name = fmt.Sprintf("%s (%s)", label, address)
+	}
+	var body strings.Builder
+	fmt.Fprintf(&body, "Data-quality anomalies found while refreshing %s:\n\n", name)
+	for _, alert := range alerts {
+		fmt.Fprintf(&body, "- %s: %s\n", alert.Kind, alert.Message)
+	}
+	subject := fmt.Sprintf("Data quality: %d anomalies for %s", len(alerts), name)
+	if err := m.mailer.Send(settings.EODReportTo, subject, body.String()); err != nil {
+		log.Printf("Failed to email data-quality alerts: %v", err)
+	}
+}
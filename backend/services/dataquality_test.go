@@ -0,0 +1,81 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"testing"
+	"time"
+)
+
+// Test raising alerts for anomalies found by a refresh
+func TestDataQualityMonitor(t *testing.T) {
+	settings := models.AlertConfig{}
+	monitor := NewDataQualityMonitor(nil, nil, func() models.AlertConfig { return settings })
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	var trades []models.Trade
+	for days := 8; days >= 2; days-- {
+		trades = append(trades, models.Trade{Time: today.AddDate(0, 0, -days).Add(12 * time.Hour), Coin: "ETH", Side: "B", Price: 3000, Size: 1})
+	}
+	check := ingestCheck{address: testAddress, trades: trades, coverageStart: today.AddDate(0, 0, -30)}
+
+	t.Run("should alert when an active address has a day without fills", func(t *testing.T) {
+		alerts := monitor.check(check, now)
+		if len(alerts) != 1 || alerts[0].Kind != models.AnomalyNoFills {
+			t.Fatalf("Expected a no_fills alert, got %+v", alerts)
+		}
+		if again := monitor.check(check, now.Add(time.Hour)); len(again) != 0 {
+			t.Errorf("Expected the anomaly not to be raised again, got %+v", again)
+		}
+	})
+
+	t.Run("should not judge days the cache doesn't cover", func(t *testing.T) {
+		uncovered := check
+		uncovered.address, uncovered.coverageStart = "0xother", today.AddDate(0, 0, -3)
+		if alerts := monitor.check(uncovered, now); len(alerts) != 0 {
+			t.Errorf("Expected no alerts, got %+v", alerts)
+		}
+	})
+
+	t.Run("should alert on parse failures, collisions and gaps", func(t *testing.T) {
+		tally := &ingestTally{}
+		tally.addFills(100, 2)
+		at := today.AddDate(0, 0, -1)
+		tally.addCollisions(keyCollisions([]models.Trade{
+			{Time: at, Coin: "ETH", Side: "B", Price: 3000, Size: 1},
+			{Time: at, Coin: "ETH", Side: "B", Price: 3000, Size: 1},
+			{Time: at, Coin: "ETH", Side: "B", Price: 3001, Size: 2},
+			{Time: at, Coin: "ETH", Side: "A", Price: 3001, Size: 2},
+		}))
+		ingest := ingestCheck{
+			address: "0xingest",
+			tally:   tally,
+			missing: []models.TimeRange{{Start: at, End: at.Add(time.Hour)}},
+		}
+
+		kinds := make(map[models.AnomalyKind]int)
+		for _, alert := range monitor.check(ingest, now) {
+			kinds[alert.Kind]++
+		}
+		if kinds[models.AnomalyParseFailures] != 1 || kinds[models.AnomalyKeyCollisions] != 1 || kinds[models.AnomalyCoverageGap] != 1 {
+			t.Errorf("Expected one alert of each kind, got %v", kinds)
+		}
+		if tally.collisions.Load() != 1 {
+			t.Errorf("Expected only the differing fill to collide, got %d", tally.collisions.Load())
+		}
+	})
+
+	t.Run("should skip muted kinds and tolerate failures under the rate", func(t *testing.T) {
+		settings.DataQuality = &models.DataQualityConfig{Mute: []models.AnomalyKind{models.AnomalyCoverageGap}, ParseFailureRate: 0.05}
+		tally := &ingestTally{}
+		tally.addFills(100, 2)
+		ingest := ingestCheck{address: "0xmuted", tally: tally, missing: []models.TimeRange{{Start: today, End: now}}}
+		if alerts := monitor.check(ingest, now); len(alerts) != 0 {
+			t.Errorf("Expected no alerts, got %+v", alerts)
+		}
+	})
+
+	if recent := monitor.Recent(testAddress); len(recent) != 1 || len(monitor.Recent("")) != 4 {
+		t.Errorf("Expected 4 alerts, 1 of them for %s, got %+v", testAddress, monitor.Recent(""))
+	}
+}
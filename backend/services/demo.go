@@ -0,0 +1,224 @@
+package services
+
+import (
+	"archive/tar"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"io"
+	"math"
+	mathrand "math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrInvalidDemoOptions is returned for demo options that can't produce a valid dataset
+var ErrInvalidDemoOptions = errors.New("invalid demo options")
+
+// DemoOptions controls how cached trades are disguised for a demo dataset
+type DemoOptions struct {
+	Scale       float64       // Multiplies every size; 1 if zero
+	Jitter      float64       // Each size is moved by up to this fraction either way, at most 0.5
+	PriceJitter float64       // Each price is moved by between half of and this fraction either way, at most 0.1; the default if zero
+	TimeJitter  time.Duration // Each time is moved back by up to this much after the shift, at most a day; the default if zero
+	Shift       time.Duration // Added to every time; 0 moves the newest trade into the last day by whole days
+	Seed        uint64        // Makes addresses and jitter reproducible; random if zero
+}
+
+// DefaultDemoOptions jitters sizes by up to 20%, prices by 0.1% to 0.2% and
+// times by up to an hour
+var DefaultDemoOptions = DemoOptions{Scale: 1, Jitter: 0.2, PriceJitter: 0.002, TimeJitter: time.Hour}
+
+// AnonymizeTrades returns a copy of the trades of every address that can be
+// shown publicly without revealing real positions: each address is replaced
+// by a random one, each size is scaled and jittered, each price is moved a
+// little and every time is moved by the shift and then back by up to the time
+// jitter. Fills are public, so exact prices or times of day would
+// lead back to the real address; the price and time jitter can't be turned
+// off. Values and fees follow the new sizes and prices, start positions are
+// recomputed from them, and realized P&L is dropped so it is attributed again
+// when the copy is imported.
+func AnonymizeTrades(tradesByAddress map[string][]models.Trade, opts DemoOptions) (map[string][]models.Trade, error) {
+	if opts.Scale == 0 {
+		opts.Scale = 1
+	}
+	if opts.PriceJitter == 0 {
+		opts.PriceJitter = DefaultDemoOptions.PriceJitter
+	}
+	if opts.TimeJitter == 0 {
+		opts.TimeJitter = DefaultDemoOptions.TimeJitter
+	}
+	for _, value := range []float64{opts.Scale, opts.Jitter, opts.PriceJitter} {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("%w: scale and jitter must be finite", ErrInvalidDemoOptions)
+		}
+	}
+	if opts.Scale < 0 || opts.Jitter < 0 || opts.Jitter > 0.5 {
+		return nil, fmt.Errorf("%w: scale must be positive and jitter between 0 and 0.5", ErrInvalidDemoOptions)
+	}
+	if opts.PriceJitter < 0 || opts.PriceJitter > 0.1 || opts.TimeJitter < 0 || opts.TimeJitter > 24*time.Hour {
+		return nil, fmt.Errorf("%w: price jitter must be between 0 and 0.1 and time jitter between 0 and 24h", ErrInvalidDemoOptions)
+	}
+	if opts.Seed == 0 {
+		var seed [8]byte
+		rand.Read(seed[:])
+		opts.Seed = binary.LittleEndian.Uint64(seed[:])
+	}
+
+	var newest time.Time
+	for _, trades := range tradesByAddress {
+		for _, trade := range trades {
+			if trade.Time.After(newest) {
+				newest = trade.Time
+			}
+		}
+	}
+	now := time.Now()
+	if opts.Shift == 0 && !newest.IsZero() {
+		// Whole days, so trades stay on about the same days of the week and hours of the day
+		opts.Shift = now.Sub(newest).Truncate(24 * time.Hour)
+	}
+	if newest.Add(opts.Shift).After(now) {
+		return nil, fmt.Errorf("%w: shift %s moves trades into the future", ErrInvalidDemoOptions, opts.Shift)
+	}
+
+	// Addresses are anonymized in a fixed order so a seed always gives the same copy
+	addresses := make([]string, 0, len(tradesByAddress))
+	for address := range tradesByAddress {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	rng := mathrand.New(mathrand.NewPCG(opts.Seed, 0))
+	anonymized := make(map[string][]models.Trade, len(addresses))
+	for _, address := range addresses {
+		var raw [20]byte
+		for i := range raw {
+			raw[i] = byte(rng.Uint32())
+		}
+		anonymized["0x"+hex.EncodeToString(raw[:])] = anonymizeTrades(tradesByAddress[address], opts, rng)
+	}
+	return anonymized, nil
+}
+
+// anonymizeTrades scales, jitters and shifts one address's trades
+func anonymizeTrades(trades []models.Trade, opts DemoOptions, rng *mathrand.Rand) []models.Trade {
+	sorted := append([]models.Trade(nil), trades...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	positions := make(map[string]float64) // Running position of each coin, once known
+	result := make([]models.Trade, 0, len(sorted))
+	var previous time.Time
+	for _, trade := range sorted {
+		size := roundTo(float64(trade.Size)*opts.Scale*(1+opts.Jitter*(2*rng.Float64()-1)), 6)
+		if size <= 0 {
+			continue
+		}
+		price := demoPrice(trade.Price, opts.PriceJitter, rng)
+		// Moved back by at least a millisecond, so no time of day is kept and
+		// the shift can't reach the future; kept in order so the recomputed
+		// start positions still follow each other
+		when := trade.Time.Add(opts.Shift - time.Millisecond - time.Duration(rng.Int64N(int64(opts.TimeJitter))))
+		if when.Before(previous) {
+			when = previous
+		}
+		previous = when
+
+		disguised := models.Trade{
+			Time:  when,
+			Coin:  trade.Coin,
+			Side:  trade.Side,
+			Price: price,
+			Size:  models.Quantity(size),
+			Value: models.Money(float64(price) * size).Round(),
+		}
+		if trade.Value != 0 {
+			disguised.Fee = (trade.Fee * disguised.Value / trade.Value).Round()
+		}
+
+		position, known := positions[trade.Coin]
+		if !known && trade.StartPosition != nil {
+			position, known = roundTo(float64(*trade.StartPosition)*opts.Scale, 6), true
+		}
+		if known {
+			start := models.Position(position)
+			disguised.StartPosition = &start
+			if trade.Side == "B" {
+				position += size
+			} else {
+				position -= size
+			}
+			positions[trade.Coin] = roundTo(position, 6)
+		}
+		result = append(result, disguised)
+	}
+	return result
+}
+
+// demoPrice moves price by between half of and all of jitter, up or down, so
+// the result never equals the original
+func demoPrice(price models.Money, jitter float64, rng *mathrand.Rand) models.Money {
+	move := jitter * (0.5 + 0.5*rng.Float64())
+	if rng.IntN(2) == 0 {
+		move = -move
+	}
+	moved := models.Money(float64(price) * (1 + move)).Round()
+	if moved == price && price != 0 {
+		// A price too small to move at 8 decimals moves by the smallest step
+		moved = price + 1e-8
+	}
+	return moved
+}
+
+// WriteDemo writes a zstd-compressed tar of a CSV file of trades per
+// address, named like trade downloads so -import loads a directory it is
+// unpacked into
+func WriteDemo(w io.Writer, tradesByAddress map[string][]models.Trade) error {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(zw)
+
+	addresses := make([]string, 0, len(tradesByAddress))
+	for address := range tradesByAddress {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	created := time.Now().UTC()
+	for _, address := range addresses {
+		data, err := EncodeTrades(FormatCSV, tradesByAddress[address])
+		if err != nil {
+			return err
+		}
+		if err := writeTarFile(tw, DemoFileName(address), data, created); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// DemoFileName is the name of an address's file in a demo dataset
+func DemoFileName(address string) string {
+	return fmt.Sprintf("trades_%s.%s", address, FormatCSV)
+}
+
+// DemoTrades returns an anonymized copy of every address's cached trades
+func (rs *ReconciliationService) DemoTrades(opts DemoOptions) (map[string][]models.Trade, error) {
+	tradesByAddress := make(map[string][]models.Trade)
+	for _, address := range rs.CachedAddresses() {
+		if trades, exists := rs.CachedTrades(address); exists && len(trades) > 0 {
+			tradesByAddress[address] = trades
+		}
+	}
+	return AnonymizeTrades(tradesByAddress, opts)
+}
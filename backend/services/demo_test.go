@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test disguising cached trades for a demo dataset
+func TestAnonymizeTrades(t *testing.T) {
+	start := time.Now().AddDate(0, 0, -40).Truncate(time.Second)
+	position := models.Position(2)
+	cached := map[string][]models.Trade{
+		testAddress: {
+			{Time: start, Coin: "BTC", Side: "B", Price: 100, Size: 1, Value: 100, Fee: 0.1, StartPosition: &position},
+			{Time: start.Add(time.Hour), Coin: "BTC", Side: "A", Price: 110, Size: 2, Value: 220, Fee: 0.2},
+		},
+	}
+	opts := DemoOptions{Scale: 10, Jitter: 0.2, Seed: 7}
+
+	demo, err := AnonymizeTrades(cached, opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	t.Run("should replace addresses and disguise sizes", func(t *testing.T) {
+		if len(demo) != 1 || demo[testAddress] != nil {
+			t.Fatalf("Expected one new address, got %v", demo)
+		}
+		for address, trades := range demo {
+			if !addressPattern.MatchString(address) || len(trades) != 2 {
+				t.Fatalf("Expected 2 trades for a valid address, got %s %+v", address, trades)
+			}
+			for i, trade := range trades {
+				ratio := trade.Size / cached[testAddress][i].Size
+				priceRatio := float64(trade.Price / cached[testAddress][i].Price)
+				if ratio < 8 || ratio > 12 || math.Abs(priceRatio-1) > 0.002+1e-9 || math.Abs(float64(trade.Value)-float64(trade.Price)*float64(trade.Size)) > 1e-8 {
+					t.Errorf("Expected a scaled, jittered size at a nearby price, got %+v", trade)
+				}
+				if err := validateImportedTrade(trade); err != nil {
+					t.Errorf("Expected an importable trade, got %v", err)
+				}
+			}
+			if *trades[0].StartPosition != 20 || float64(*trades[1].StartPosition) != roundTo(20+float64(trades[0].Size), 6) {
+				t.Errorf("Expected start positions recomputed from the new sizes, got %v and %v", *trades[0].StartPosition, *trades[1].StartPosition)
+			}
+			if shift := trades[0].Time.Sub(start); shift < 38*24*time.Hour || trades[0].Time.After(time.Now()) {
+				t.Errorf("Expected trades moved into the last days, got %s", shift)
+			}
+			if trades[1].Time.Before(trades[0].Time) {
+				t.Errorf("Expected trades to stay in order, got %s before %s", trades[1].Time, trades[0].Time)
+			}
+		}
+	})
+
+	t.Run("should keep no price and time of day of a real fill", func(t *testing.T) {
+		// Fills are public, so an exact price at an exact time of day would
+		// identify the real address whatever whole-day shift was applied
+		const day = 24 * time.Hour
+		var many []models.Trade
+		for i := 0; i < 500; i++ {
+			many = append(many, models.Trade{
+				Time: start.Add(time.Duration(i) * 7 * time.Minute).Add(time.Duration(i) * time.Millisecond),
+				Coin: "ETH", Side: "B", Price: models.Money(3000 + i), Size: 0.5, Value: models.Money(1500 + i/2), Fee: 0.1,
+			})
+		}
+		real := make(map[[2]int64]bool)
+		for _, trade := range many {
+			real[[2]int64{int64(math.Round(float64(trade.Price) * 1e8)), int64(trade.Time.Sub(time.Time{}) % day)}] = true
+		}
+		copies, err := AnonymizeTrades(map[string][]models.Trade{testAddress: many}, DemoOptions{Seed: 11})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, trades := range copies {
+			for _, trade := range trades {
+				if real[[2]int64{int64(math.Round(float64(trade.Price) * 1e8)), int64(trade.Time.Sub(time.Time{}) % day)}] {
+					t.Errorf("Expected no real price and time of day, got %v at %s", trade.Price, trade.Time)
+				}
+			}
+		}
+	})
+
+	t.Run("should give the same copy for the same seed", func(t *testing.T) {
+		again, _ := AnonymizeTrades(cached, opts)
+		if !reflect.DeepEqual(demo, again) {
+			t.Errorf("Expected the same copy, got %+v and %+v", demo, again)
+		}
+	})
+
+	t.Run("should reject options it can't apply", func(t *testing.T) {
+		for _, invalid := range []DemoOptions{
+			{Jitter: 0.9}, {Scale: -1}, {Shift: 60 * 24 * time.Hour},
+			{Scale: math.NaN()}, {Scale: math.Inf(1)}, {Jitter: math.NaN()}, {PriceJitter: math.Inf(-1)},
+			{PriceJitter: 0.5}, {TimeJitter: 48 * time.Hour}, {TimeJitter: -time.Minute},
+		} {
+			if _, err := AnonymizeTrades(cached, invalid); !errors.Is(err, ErrInvalidDemoOptions) {
+				t.Errorf("Expected ErrInvalidDemoOptions for %+v, got %v", invalid, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"math"
+	"sort"
+	"time"
+)
+
+// AnalyzeDirection classes each day since since as opening, closing or churn.
+// Positions are rebuilt from the first trade, which is assumed to open from
+// flat, and each fill is split into the part that reduced its coin's position
+// and the part that added to it; a fill that flips a position does both. A
+// day whose opened and closed notional differ by no more than ChurnShare of
+// their sum is churn. P&L is realized P&L less fees, so days that only build
+// positions aren't charged for what they bought.
+func AnalyzeDirection(address string, trades []models.Trade, since time.Time, basis models.DayBasis) models.DirectionAnalysis {
+	analysis := models.DirectionAnalysis{
+		Address:    address,
+		DayBasis:   basis,
+		ChurnShare: config.ChurnShare,
+		Classes:    make([]models.DirectionClass, 0, 3),
+		Days:       []models.DirectionDay{},
+	}
+	location := dayLocation(basis)
+
+	attributed := AttributeRealizedPnL(trades)
+	positions := make(map[string]float64)
+	days := make(map[string]*models.DirectionDay)
+	for _, trade := range attributed {
+		delta := float64(trade.Size)
+		if trade.Side == "A" {
+			delta = -delta
+		}
+		position := positions[trade.Coin]
+		closed := 0.0
+		if position != 0 && (position > 0) != (delta > 0) {
+			closed = math.Min(math.Abs(delta), math.Abs(position))
+		}
+		opened := math.Abs(delta) - closed
+		position += delta
+		if math.Abs(position) < positionEpsilon {
+			position = 0
+		}
+		positions[trade.Coin] = position
+
+		if trade.Time.Before(since) {
+			continue
+		}
+		date := trade.Time.In(location).Format("2006-01-02")
+		day, exists := days[date]
+		if !exists {
+			day = &models.DirectionDay{Date: date}
+			days[date] = day
+		}
+		day.Fills++
+		day.OpenedNotional += opened * float64(trade.Price)
+		day.ClosedNotional += closed * float64(trade.Price)
+		day.RealizedPnL += float64(*trade.RealizedPnL)
+		day.Fees += float64(trade.Fee)
+	}
+
+	dates := make([]string, 0, len(days))
+	for date := range days {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	classes := make(map[string]*models.DirectionClass)
+	for _, class := range []string{models.DayOpening, models.DayClosing, models.DayChurn} {
+		analysis.Classes = append(analysis.Classes, models.DirectionClass{Class: class})
+		classes[class] = &analysis.Classes[len(analysis.Classes)-1]
+	}
+	for _, date := range dates {
+		day := days[date]
+		day.NetPnL = day.RealizedPnL - day.Fees
+		if total := day.OpenedNotional + day.ClosedNotional; total > 0 {
+			day.NetShare = (day.OpenedNotional - day.ClosedNotional) / total
+		}
+		switch {
+		case math.Abs(day.NetShare) <= config.ChurnShare:
+			day.Class = models.DayChurn
+		case day.NetShare > 0:
+			day.Class = models.DayOpening
+		default:
+			day.Class = models.DayClosing
+		}
+		analysis.Days = append(analysis.Days, *day)
+
+		class := classes[day.Class]
+		class.Days++
+		if day.NetPnL > 0 {
+			class.WinningDays++
+		}
+		class.Fills += day.Fills
+		class.OpenedNotional += day.OpenedNotional
+		class.ClosedNotional += day.ClosedNotional
+		class.RealizedPnL += day.RealizedPnL
+		class.Fees += day.Fees
+		class.NetPnL += day.NetPnL
+	}
+	for i := range analysis.Classes {
+		if analysis.Classes[i].Days > 0 {
+			analysis.Classes[i].AvgNetPnL = analysis.Classes[i].NetPnL / float64(analysis.Classes[i].Days)
+		}
+	}
+	return analysis
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"testing"
+	"time"
+)
+
+// Test classing days as opening, closing or churn
+func TestAnalyzeDirection(t *testing.T) {
+	day := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	trades := []models.Trade{
+		// Opening: buy 10 BTC
+		{Time: day, Coin: "BTC", Side: "B", Price: 100, Size: 10, Value: 1000, Fee: 1},
+		// Churn: sell 5 and buy 5 back
+		{Time: day.AddDate(0, 0, 1), Coin: "BTC", Side: "A", Price: 110, Size: 5, Value: 550, Fee: 0.5},
+		{Time: day.AddDate(0, 0, 1).Add(time.Hour), Coin: "BTC", Side: "B", Price: 110, Size: 5, Value: 550, Fee: 0.5},
+		// Closing: sell 12, closing 10 and flipping 2 short
+		{Time: day.AddDate(0, 0, 2), Coin: "BTC", Side: "A", Price: 120, Size: 12, Value: 1440, Fee: 1.5},
+	}
+
+	analysis := AnalyzeDirection(testAddress, trades, time.Time{}, models.DayUTC)
+	if len(analysis.Days) != 3 {
+		t.Fatalf("Expected 3 days, got %+v", analysis.Days)
+	}
+	expected := []struct {
+		class          string
+		opened, closed float64
+	}{
+		{models.DayOpening, 1000, 0},
+		{models.DayChurn, 550, 550},
+		{models.DayClosing, 240, 1200},
+	}
+	for i, e := range expected {
+		got := analysis.Days[i]
+		if got.Class != e.class || got.OpenedNotional != e.opened || got.ClosedNotional != e.closed {
+			t.Errorf("Day %s: expected %s with %v opened and %v closed, got %+v", got.Date, e.class, e.opened, e.closed, got)
+		}
+	}
+
+	closing := analysis.Classes[1]
+	if closing.Class != models.DayClosing || closing.Days != 1 || closing.RealizedPnL != 150 || closing.NetPnL != 148.5 || closing.WinningDays != 1 {
+		t.Errorf("Expected the closing day to realize 150 against an average entry of 105, less 1.5 in fees, got %+v", closing)
+	}
+	opening := analysis.Classes[0]
+	if opening.NetPnL != -1 || opening.WinningDays != 0 {
+		t.Errorf("Expected the opening day to cost only its fee, got %+v", opening)
+	}
+
+	t.Run("should rebuild positions from before the window", func(t *testing.T) {
+		recent := AnalyzeDirection(testAddress, trades, day.AddDate(0, 0, 2), models.DayUTC)
+		if len(recent.Days) != 1 || recent.Days[0].Class != models.DayClosing {
+			t.Errorf("Expected only the closing day, got %+v", recent.Days)
+		}
+	})
+}
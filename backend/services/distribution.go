@@ -0,0 +1,141 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"sort"
+	"time"
+)
+
+// ErrInvalidPeriod is returned for a distribution period other than "month" or "week"
+var ErrInvalidPeriod = errors.New(`period must be "month" or "week"`)
+
+// notionalBounds are the lower bounds of the fill notional histogram buckets
+var notionalBounds = []float64{0, 100, 1000, 10000, 100000, 1000000}
+
+// topTradesCount is the number of most profitable fills reported per period
+const topTradesCount = 5
+
+// BuildTradeDistribution reports the notional histogram of the trades made
+// since since, and per month or week (local time) the share of realized P&L
+// that came from the top trades and the concentration of volume by coin.
+// Positions are rebuilt from the first trade, which is assumed to open from flat.
+func BuildTradeDistribution(address string, trades []models.Trade, since time.Time, period string) (models.TradeDistribution, error) {
+	if period == "" {
+		period = "month"
+	}
+	if period != "month" && period != "week" {
+		return models.TradeDistribution{}, ErrInvalidPeriod
+	}
+
+	distribution := models.TradeDistribution{
+		Address:   address,
+		Period:    period,
+		Histogram: make([]models.NotionalBucket, len(notionalBounds)),
+		Periods:   []models.DistributionPeriod{},
+	}
+	for i, bound := range notionalBounds {
+		distribution.Histogram[i].Min = bound
+		if i+1 < len(notionalBounds) {
+			upper := notionalBounds[i+1]
+			distribution.Histogram[i].Max = &upper
+		}
+	}
+
+	type periodFills struct {
+		summary models.DistributionPeriod
+		coins   map[string]*models.CoinShare
+		winners []models.TopTrade
+	}
+	periods := make(map[string]*periodFills)
+
+	pnl, closing := realizedPnL(trades)
+	for i, trade := range trades {
+		if trade.Time.Before(since) {
+			continue
+		}
+
+		bucket := sort.SearchFloat64s(notionalBounds, float64(trade.Value))
+		if bucket == len(notionalBounds) || notionalBounds[bucket] > float64(trade.Value) {
+			bucket--
+		}
+		distribution.Histogram[bucket].Fills++
+		distribution.Histogram[bucket].Volume += float64(trade.Value)
+
+		key := periodKey(trade.Time, period)
+		p, exists := periods[key]
+		if !exists {
+			p = &periodFills{summary: models.DistributionPeriod{Period: key}, coins: make(map[string]*models.CoinShare)}
+			periods[key] = p
+		}
+		p.summary.Fills++
+		p.summary.Volume += float64(trade.Value)
+		p.summary.RealizedPnL += pnl[i]
+
+		coin, exists := p.coins[trade.Coin]
+		if !exists {
+			coin = &models.CoinShare{Coin: trade.Coin}
+			p.coins[trade.Coin] = coin
+		}
+		coin.Volume += float64(trade.Value)
+		coin.RealizedPnL += pnl[i]
+
+		if closing[i] && pnl[i] > 0 {
+			p.winners = append(p.winners, models.TopTrade{Time: trade.Time, Coin: trade.Coin, Side: trade.Side, Value: float64(trade.Value), RealizedPnL: pnl[i]})
+		}
+	}
+
+	keys := make([]string, 0, len(periods))
+	for key := range periods {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		p := periods[key]
+		summary := p.summary
+
+		sort.Slice(p.winners, func(i, j int) bool { return p.winners[i].RealizedPnL > p.winners[j].RealizedPnL })
+		summary.TopTrades = p.winners[:min(topTradesCount, len(p.winners))]
+		if summary.TopTrades == nil {
+			summary.TopTrades = []models.TopTrade{}
+		}
+		for _, top := range summary.TopTrades {
+			summary.TopTradesPnL += top.RealizedPnL
+		}
+		if summary.RealizedPnL > 0 {
+			share := summary.TopTradesPnL / summary.RealizedPnL
+			summary.TopTradesShare = &share
+		}
+
+		summary.Coins = make([]models.CoinShare, 0, len(p.coins))
+		for _, coin := range p.coins {
+			if summary.Volume > 0 {
+				coin.Share = coin.Volume / summary.Volume
+			}
+			summary.CoinConcentration += coin.Share * coin.Share
+			summary.Coins = append(summary.Coins, *coin)
+		}
+		sort.Slice(summary.Coins, func(i, j int) bool {
+			if summary.Coins[i].Volume != summary.Coins[j].Volume {
+				return summary.Coins[i].Volume > summary.Coins[j].Volume
+			}
+			return summary.Coins[i].Coin < summary.Coins[j].Coin
+		})
+
+		distribution.Periods = append(distribution.Periods, summary)
+	}
+
+	return distribution, nil
+}
+
+// periodKey names the month ("2006-01") or ISO week ("2006-W01") of t in local time
+func periodKey(t time.Time, period string) string {
+	t = t.Local()
+	if period == "week" {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return t.Format("2006-01")
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"math"
+	"testing"
+	"time"
+)
+
+// Test notional histogram and concentration metrics
+func TestBuildTradeDistribution(t *testing.T) {
+	june := time.Date(2025, 6, 10, 12, 0, 0, 0, time.Local)
+	trades := []models.Trade{
+		{Time: june, Coin: "BTC", Side: "B", Price: 100, Size: 10, Value: 1000},
+		{Time: june.Add(time.Hour), Coin: "BTC", Side: "A", Price: 190, Size: 10, Value: 1900},
+		{Time: june.Add(2 * time.Hour), Coin: "ETH", Side: "B", Price: 10, Size: 5, Value: 50},
+		{Time: june.Add(3 * time.Hour), Coin: "ETH", Side: "A", Price: 8, Size: 5, Value: 40},
+	}
+
+	distribution, err := BuildTradeDistribution("0xabc", trades, time.Time{}, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if distribution.Histogram[0].Fills != 2 || distribution.Histogram[2].Fills != 2 {
+		t.Errorf("Expected two fills under $100 and two from $1,000, got %+v", distribution.Histogram)
+	}
+	if len(distribution.Periods) != 1 || distribution.Periods[0].Period != "2025-06" {
+		t.Fatalf("Expected one period 2025-06, got %+v", distribution.Periods)
+	}
+
+	month := distribution.Periods[0]
+	if month.RealizedPnL != 890 || month.TopTradesPnL != 900 || len(month.TopTrades) != 1 {
+		t.Errorf("Expected P&L 890 with one top trade of 900, got %+v", month)
+	}
+	if month.TopTradesShare == nil || math.Abs(*month.TopTradesShare-900.0/890) > 1e-9 {
+		t.Errorf("Expected the top trade to carry more than the month, got %v", month.TopTradesShare)
+	}
+	btcShare := 2900.0 / 2990
+	if expected := btcShare*btcShare + (1-btcShare)*(1-btcShare); math.Abs(month.CoinConcentration-expected) > 1e-9 {
+		t.Errorf("Expected concentration %v, got %v", expected, month.CoinConcentration)
+	}
+
+	if _, err := BuildTradeDistribution("0xabc", trades, time.Time{}, "year"); !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("Expected ErrInvalidPeriod, got %v", err)
+	}
+}
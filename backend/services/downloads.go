@@ -0,0 +1,271 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrDownloadNotFound is returned for a download token that isn't genuine or whose file is gone
+	ErrDownloadNotFound = errors.New("download not found")
+	// ErrDownloadExpired is returned for a genuine download token past its expiry
+	ErrDownloadExpired = errors.New("download link has expired")
+	// ErrDownloadsDisabled is returned when a download is requested but no download store is in use
+	ErrDownloadsDisabled = errors.New("downloads are not configured")
+)
+
+// downloadPathPrefix is the path signed download tokens are served under
+const downloadPathPrefix = "/api/v1/downloads/"
+
+// downloads keeps generated files for download if set, shared by every
+// tenant; each tenant's files are kept apart and tokens name their tenant
+var downloads atomic.Pointer[DownloadStore]
+
+// UseDownloads serves generated files from store; nil turns downloads off
+func UseDownloads(store *DownloadStore) {
+	downloads.Store(store)
+}
+
+// CurrentDownloads returns the download store in effect, or nil if downloads are off
+func CurrentDownloads() *DownloadStore {
+	return downloads.Load()
+}
+
+// DownloadStore keeps generated files on disk and hands out time-limited
+// signed URLs to them, so a large file is written once and fetched later
+// without an API key or holding it in memory. Each file is kept as
+// <tenant>/<id>.data next to its description in <id>.json, until it expires;
+// the description is encrypted like other stored files, the data is not.
+type DownloadStore struct {
+	dir string
+	key []byte
+}
+
+// NewDownloadStore creates a store in dir, creating it if needed. URLs are
+// signed with key, or with a random key if it is empty.
+func NewDownloadStore(dir, key string) (*DownloadStore, error) {
+	if err := os.MkdirAll(dir, dataDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create downloads directory: %w", err)
+	}
+	secret := []byte(key)
+	if key == "" {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate download key: %w", err)
+		}
+	}
+	return &DownloadStore{dir: dir, key: secret}, nil
+}
+
+// downloadToken is what a signed download URL carries
+type downloadToken struct {
+	Tenant  string `json:"t"`
+	ID      string `json:"i"`
+	Expires int64  `json:"e"` // Unix seconds
+}
+
+// sign returns the token for a download of tenant
+func (s *DownloadStore) sign(tenant string, download models.Download) string {
+	payload, _ := json.Marshal(downloadToken{Tenant: tenant, ID: download.ID, Expires: download.ExpiresAt.Unix()})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(encoded))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify returns what token names if it was signed by this store
+func (s *DownloadStore) verify(token string) (downloadToken, bool) {
+	encoded, signature, found := strings.Cut(token, ".")
+	sum, err := base64.RawURLEncoding.DecodeString(signature)
+	if !found || err != nil {
+		return downloadToken{}, false
+	}
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(encoded))
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return downloadToken{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	var t downloadToken
+	if err != nil || json.Unmarshal(payload, &t) != nil {
+		return downloadToken{}, false
+	}
+	return t, true
+}
+
+// URL returns the signed path download of tenant can be fetched from until it expires
+func (s *DownloadStore) URL(tenant string, download models.Download) string {
+	return downloadPathPrefix + s.sign(tenant, download)
+}
+
+// tenantDir returns the directory a tenant's files are kept in
+func (s *DownloadStore) tenantDir(tenant string) string {
+	return filepath.Join(s.dir, filepath.Base(tenant))
+}
+
+// DownloadFile is a download being written. Write it in as many pieces as
+// needed, then Finish it to make it available or Discard it.
+type DownloadFile struct {
+	store    *DownloadStore
+	tenant   string
+	download models.Download
+	file     *os.File
+	hasher   *exportHasher
+}
+
+// Create starts a file named name for tenant, digested and signed by signer
+// as it is written like an export download
+func (s *DownloadStore) Create(tenant, name, contentType string, signer *ExportSigner) (*DownloadFile, error) {
+	if s == nil {
+		return nil, ErrDownloadsDisabled
+	}
+	s.Prune(time.Now())
+
+	dir := s.tenantDir(tenant)
+	if err := os.MkdirAll(dir, dataDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create downloads directory: %w", err)
+	}
+	id := newID()
+	file, err := os.OpenFile(filepath.Join(dir, id+".part"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download: %w", err)
+	}
+	return &DownloadFile{
+		store:    s,
+		tenant:   tenant,
+		download: models.Download{ID: id, Name: name, ContentType: contentType},
+		file:     file,
+		hasher:   signer.hasher(),
+	}, nil
+}
+
+// Write appends p to the file
+func (f *DownloadFile) Write(p []byte) (int, error) {
+	n, err := f.file.Write(p)
+	f.hasher.Write(p[:n])
+	f.download.Bytes += int64(n)
+	return n, err
+}
+
+// Finish completes the file and returns it with its signed URL
+func (f *DownloadFile) Finish() (models.Download, error) {
+	if err := f.file.Close(); err != nil {
+		f.Discard()
+		return models.Download{}, fmt.Errorf("failed to write download: %w", err)
+	}
+	now := time.Now().UTC()
+	f.download.CreatedAt, f.download.ExpiresAt = now, now.Add(config.DownloadTTL)
+	f.download.Digest, f.download.Signature = f.hasher.sums()
+
+	dir := f.store.tenantDir(f.tenant)
+	err := writeJSONFile(filepath.Join(dir, f.download.ID+".json"), f.download)
+	if err == nil {
+		err = os.Rename(f.file.Name(), filepath.Join(dir, f.download.ID+".data"))
+	}
+	if err != nil {
+		f.Discard()
+		return models.Download{}, fmt.Errorf("failed to save download: %w", err)
+	}
+
+	download := f.download
+	download.URL = f.store.URL(f.tenant, download)
+	return download, nil
+}
+
+// Discard abandons the file
+func (f *DownloadFile) Discard() {
+	f.file.Close()
+	dir := f.store.tenantDir(f.tenant)
+	for _, name := range []string{f.download.ID + ".part", f.download.ID + ".json", f.download.ID + ".data"} {
+		os.Remove(filepath.Join(dir, name))
+	}
+}
+
+// Open returns the download a token names and its file, which the caller
+// must close. A token that isn't genuine, or whose file was pruned, is
+// ErrDownloadNotFound; one that has expired is ErrDownloadExpired.
+func (s *DownloadStore) Open(token string, now time.Time) (models.Download, *os.File, error) {
+	if s == nil {
+		return models.Download{}, nil, ErrDownloadsDisabled
+	}
+	t, ok := s.verify(token)
+	if !ok {
+		return models.Download{}, nil, ErrDownloadNotFound
+	}
+	if now.Unix() >= t.Expires {
+		return models.Download{}, nil, ErrDownloadExpired
+	}
+
+	dir := s.tenantDir(t.Tenant)
+	var download models.Download
+	data, err := readStoreFile(filepath.Join(dir, filepath.Base(t.ID)+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return models.Download{}, nil, ErrDownloadNotFound
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &download)
+	}
+	if err != nil {
+		return models.Download{}, nil, fmt.Errorf("failed to read download: %w", err)
+	}
+	file, err := os.Open(filepath.Join(dir, download.ID+".data"))
+	if errors.Is(err, os.ErrNotExist) {
+		return models.Download{}, nil, ErrDownloadNotFound
+	}
+	if err != nil {
+		return models.Download{}, nil, fmt.Errorf("failed to open download: %w", err)
+	}
+	return download, file, nil
+}
+
+// Prune deletes files that expired before now, and any left half written by
+// a crash, returning how many were deleted
+func (s *DownloadStore) Prune(now time.Time) int {
+	tenants, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0
+	}
+	pruned := 0
+	for _, tenant := range tenants {
+		dir := filepath.Join(s.dir, tenant.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			id, isDescription := strings.CutSuffix(entry.Name(), ".json")
+			if !isDescription {
+				// Parts still being written are only abandoned once old enough to have expired
+				if info, err := entry.Info(); err == nil && strings.HasSuffix(entry.Name(), ".part") && now.Sub(info.ModTime()) > config.DownloadTTL {
+					os.Remove(filepath.Join(dir, entry.Name()))
+				}
+				continue
+			}
+			var download models.Download
+			data, err := readStoreFile(filepath.Join(dir, entry.Name()))
+			if err != nil || json.Unmarshal(data, &download) != nil || now.Before(download.ExpiresAt) {
+				continue
+			}
+			os.Remove(filepath.Join(dir, id+".data"))
+			os.Remove(filepath.Join(dir, entry.Name()))
+			pruned++
+		}
+	}
+	if pruned > 0 {
+		log.Printf("Deleted %d expired downloads", pruned)
+	}
+	return pruned
+}
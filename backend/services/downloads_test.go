@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test writing files to the download store and fetching them by signed token
+func TestDownloadStore(t *testing.T) {
+	body := "time,coin,side,px,sz\n2025-01-01T00:00:00Z,BTC,B,100,1\n"
+
+	create := func(t *testing.T, store *DownloadStore, tenant string) (token string) {
+		t.Helper()
+		file, err := store.Create(tenant, "trades.csv", "text/csv", NewExportSigner("statement-key"))
+		if err != nil {
+			t.Fatalf("Failed to create download: %v", err)
+		}
+		// Written in pieces, as a streaming export would
+		for _, line := range strings.SplitAfter(body, "\n") {
+			io.WriteString(file, line)
+		}
+		download, err := file.Finish()
+		if err != nil {
+			t.Fatalf("Failed to finish download: %v", err)
+		}
+		if download.Bytes != int64(len(body)) || download.Digest != sha256Hex([]byte(body)) || download.Signature == "" {
+			t.Errorf("Expected the size, digest and signature of the file, got %+v", download)
+		}
+		token, found := strings.CutPrefix(download.URL, "/api/v1/downloads/")
+		if !found {
+			t.Fatalf("Expected a signed download URL, got %q", download.URL)
+		}
+		return token
+	}
+
+	t.Run("should serve a finished file by its token until it expires", func(t *testing.T) {
+		store, err := NewDownloadStore(t.TempDir(), "")
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		token := create(t, store, "default")
+
+		download, file, err := store.Open(token, time.Now())
+		if err != nil {
+			t.Fatalf("Failed to open download: %v", err)
+		}
+		data, _ := io.ReadAll(file)
+		file.Close()
+		if string(data) != body || download.Name != "trades.csv" || download.ContentType != "text/csv" {
+			t.Errorf("Expected the file as written, got %+v %q", download, data)
+		}
+
+		if _, _, err := store.Open(token, time.Now().Add(25*time.Hour)); !errors.Is(err, ErrDownloadExpired) {
+			t.Errorf("Expected ErrDownloadExpired, got %v", err)
+		}
+		if pruned := store.Prune(time.Now().Add(25 * time.Hour)); pruned != 1 {
+			t.Errorf("Expected the expired file to be pruned, got %d", pruned)
+		}
+		if _, _, err := store.Open(token, time.Now()); !errors.Is(err, ErrDownloadNotFound) {
+			t.Errorf("Expected ErrDownloadNotFound after pruning, got %v", err)
+		}
+	})
+
+	t.Run("should reject altered and foreign tokens", func(t *testing.T) {
+		dir := t.TempDir()
+		store, _ := NewDownloadStore(dir, "download-key")
+		token := create(t, store, "default")
+
+		// Any change to what the token names breaks its signature
+		payload, signature, _ := strings.Cut(token, ".")
+		altered := payload[:len(payload)-2] + "fQ." + signature
+		for _, bad := range []string{altered, payload, "", "not-a-token"} {
+			if _, _, err := store.Open(bad, time.Now()); !errors.Is(err, ErrDownloadNotFound) {
+				t.Errorf("Expected ErrDownloadNotFound for %q, got %v", bad, err)
+			}
+		}
+
+		other, _ := NewDownloadStore(dir, "another-key")
+		if _, _, err := other.Open(token, time.Now()); !errors.Is(err, ErrDownloadNotFound) {
+			t.Errorf("Expected a token signed with another key to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("should leave nothing behind when discarded", func(t *testing.T) {
+		store, _ := NewDownloadStore(t.TempDir(), "")
+		file, _ := store.Create("default", "trades.csv", "text/csv", NewExportSigner(""))
+		io.WriteString(file, body)
+		file.Discard()
+		if pruned := store.Prune(time.Now().Add(48 * time.Hour)); pruned != 0 {
+			t.Errorf("Expected nothing to prune, got %d", pruned)
+		}
+	})
+
+	t.Run("should report downloads off on a nil store", func(t *testing.T) {
+		var store *DownloadStore
+		if _, err := store.Create("default", "trades.csv", "text/csv", nil); !errors.Is(err, ErrDownloadsDisabled) {
+			t.Errorf("Expected ErrDownloadsDisabled, got %v", err)
+		}
+	})
+}
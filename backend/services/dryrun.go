@@ -0,0 +1,112 @@
+package services
+
+import (
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"math"
+	"time"
+)
+
+// PlanRefresh reports what FetchAndReconcile would fetch for the last days days
+// of address now, and the fills, batches and rate limit weight it would take,
+// without making any requests. Fills in windows the cache covers are counted
+// from it; the rest are extrapolated from the rate the cached history traded at.
+func (rs *ReconciliationService) PlanRefresh(address string, days int) models.RefreshPlan {
+	now := exchangeNow()
+	plan := models.RefreshPlan{Address: address, Days: days, Mode: models.RefreshFull, PlannedAt: now}
+
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	// The same choice updateCache makes
+	cache, exists := rs.accountCache[address]
+	if exists && !cache.lastFetchTime.IsZero() && days <= cache.cachedDays && cache.usable(now) {
+		plan.Mode = models.RefreshIncremental
+		for _, r := range cache.missingRanges {
+			plan.Fetches = append(plan.Fetches, planFetch(cache, r, "missing"))
+		}
+		window := models.TimeRange{Start: rs.incrementalStart(cache), End: now}
+		plan.Fetches = append(plan.Fetches, planFetch(cache, window, models.RefreshIncremental))
+	} else {
+		window := models.TimeRange{Start: now.Add(-time.Duration(fullFetchDays(cache, days)) * 24 * time.Hour), End: now}
+		plan.Fetches = append(plan.Fetches, planFetch(cache, window, models.RefreshFull))
+	}
+
+	for _, fetch := range plan.Fetches {
+		plan.EstimatedFills += fetch.EstimatedFills
+		plan.EstimatedBatches += fetch.EstimatedBatches
+		plan.EstimatedWeight += fetch.EstimatedWeight
+	}
+
+	// Batches are paced by the current pacing delay, and the weight can't
+	// exceed the per-minute allowance
+	paced := time.Duration(plan.EstimatedBatches-len(plan.Fetches)) * PacingDelay()
+	limited := time.Duration(float64(plan.EstimatedWeight) / config.UpstreamWeightPerMinute * float64(time.Minute))
+	plan.EstimatedDurationMs = max(paced, limited).Milliseconds()
+	return plan
+}
+
+// planFetch estimates the fetch of window into cache, which may be nil.
+// Pagination stops at the first batch with fewer than a full batch of fills.
+func planFetch(cache *AccountCache, window models.TimeRange, reason string) models.PlannedFetch {
+	fills, basis := estimateFills(cache, window)
+	batches := fills/config.MaxTradesPerBatch + 1
+	return models.PlannedFetch{
+		Start:            window.Start,
+		End:              window.End,
+		Reason:           reason,
+		EstimatedFills:   fills,
+		EstimatedBatches: batches,
+		EstimatedWeight:  batches*config.InfoRequestWeight + fills/config.UserFillsItemsPerWeight,
+		EstimateBasis:    basis,
+	}
+}
+
+// estimateFills estimates how many fills the exchange has in window: the
+// cached trades in the part of it the cache covers, plus the cache's average
+// rate over the rest. Missing ranges count as not covered.
+func estimateFills(cache *AccountCache, window models.TimeRange) (int, string) {
+	if cache == nil || cache.lastFetchTime.IsZero() {
+		return 0, models.EstimateNone
+	}
+
+	coverage := models.TimeRange{Start: cache.coverageStart, End: cache.lastFetchTime}
+	covered := intersectRange(window, coverage)
+	fills := len(tradesInRange(cache.trades, covered))
+
+	known, coveredDuration := rangeDuration(coverage), rangeDuration(covered)
+	for _, missing := range cache.missingRanges {
+		known -= rangeDuration(intersectRange(missing, coverage))
+		coveredDuration -= rangeDuration(intersectRange(missing, covered))
+	}
+
+	uncovered := rangeDuration(window) - max(coveredDuration, 0)
+	if uncovered <= 0 {
+		return fills, models.EstimateFromCache
+	}
+	if known <= 0 {
+		return fills, models.EstimateNone
+	}
+	rate := float64(len(cache.trades)) / known.Hours()
+	return fills + int(math.Round(rate*uncovered.Hours())), models.EstimateFromRate
+}
+
+// intersectRange returns the part of a that is also in b; empty if none is
+func intersectRange(a, b models.TimeRange) models.TimeRange {
+	start, end := a.Start, a.End
+	if b.Start.After(start) {
+		start = b.Start
+	}
+	if b.End.Before(end) {
+		end = b.End
+	}
+	if end.Before(start) {
+		end = start
+	}
+	return models.TimeRange{Start: start, End: end}
+}
+
+// rangeDuration returns the length of r
+func rangeDuration(r models.TimeRange) time.Duration {
+	return r.End.Sub(r.Start)
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"testing"
+	"time"
+)
+
+// Test planning a refresh without fetching
+func TestPlanRefresh(t *testing.T) {
+	SetRateLimitDelay(0)
+	defer SetRateLimitDelay(config.RateLimitDelay)
+
+	t.Run("should plan a full fetch of an uncached address", func(t *testing.T) {
+		rs := NewReconciliationService()
+		plan := rs.PlanRefresh(testAddress, 365)
+
+		if plan.Mode != models.RefreshFull || len(plan.Fetches) != 1 {
+			t.Fatalf("Expected one full fetch, got %+v", plan)
+		}
+		fetch := plan.Fetches[0]
+		if days := fetch.End.Sub(fetch.Start).Hours() / 24; days != 365 {
+			t.Errorf("Expected a 365 day window, got %v days", days)
+		}
+		if fetch.EstimateBasis != models.EstimateNone || plan.EstimatedBatches != 1 || plan.EstimatedWeight != config.InfoRequestWeight {
+			t.Errorf("Expected only the first batch to be counted, got %+v", plan)
+		}
+	})
+
+	t.Run("should plan missing ranges and the incremental window of a cached address", func(t *testing.T) {
+		fills := newTestSyntheticFills(t, 100)
+		UseHyperliquidTransport(fills)
+		defer UseHyperliquidTransport(nil)
+
+		rs := NewReconciliationService()
+		ctx := WithFreshResponses(context.Background())
+		if err := rs.FetchAndReconcile(ctx, testAddress, 2); err != nil {
+			t.Fatalf("Refresh failed: %v", err)
+		}
+		cache, _ := rs.cached(testAddress)
+		missing := models.TimeRange{Start: cache.coverageStart.Add(time.Hour), End: cache.coverageStart.Add(2 * time.Hour)}
+		cache.missingRanges = []models.TimeRange{missing}
+
+		requests := UpstreamStats().Requests
+		plan := rs.PlanRefresh(testAddress, 2)
+		if UpstreamStats().Requests != requests {
+			t.Error("Expected a dry run not to make requests")
+		}
+
+		if plan.Mode != models.RefreshIncremental || len(plan.Fetches) != 2 {
+			t.Fatalf("Expected a missing range and an incremental fetch, got %+v", plan)
+		}
+		if plan.Fetches[0].Reason != "missing" || !plan.Fetches[0].Start.Equal(missing.Start) || plan.Fetches[0].EstimateBasis != models.EstimateFromRate {
+			t.Errorf("Expected the missing range estimated from the trading rate, got %+v", plan.Fetches[0])
+		}
+		incremental := plan.Fetches[1]
+		if !incremental.Start.Equal(cache.lastFetchTime.Add(-rs.overlap)) || incremental.EstimatedFills == 0 {
+			t.Errorf("Expected the incremental fetch to start an overlap before the last fetch, got %+v", incremental)
+		}
+
+		// A longer range than is cached is fetched again in full, mostly from the cached rate
+		plan = rs.PlanRefresh(testAddress, 10)
+		if plan.Mode != models.RefreshFull || plan.EstimatedFills < len(cache.trades)*4 || plan.EstimatedBatches < 2 {
+			t.Errorf("Expected a full fetch extrapolated to about %d fills, got %+v", len(cache.trades)*5, plan)
+		}
+	})
+}
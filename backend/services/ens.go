@@ -0,0 +1,135 @@
+package services
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// ensRegistry is the ENS registry contract address on Ethereum mainnet
+const ensRegistry = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"
+
+// Function selectors for the ENS calls we make
+const (
+	selectorResolver = "0178b8bf" // resolver(bytes32)
+	selectorAddr     = "3b3b57de" // addr(bytes32)
+)
+
+// ErrENSNotFound is returned when a name has no resolver or no address record
+var ErrENSNotFound = errors.New("ENS name not found")
+
+// ENSResolver resolves ENS names to addresses through an Ethereum JSON-RPC endpoint
+type ENSResolver struct {
+	httpClient *http.Client
+	rpcURL     string
+}
+
+func NewENSResolver(rpcURL string, timeout time.Duration) *ENSResolver {
+	return &ENSResolver{
+		httpClient: &http.Client{Timeout: timeout},
+		rpcURL:     rpcURL,
+	}
+}
+
+// Resolve returns the lowercase hex address an ENS name points to
+func (r *ENSResolver) Resolve(name string) (string, error) {
+	node := namehash(name)
+
+	resolver, err := r.ethCall(ensRegistry, selectorResolver, node)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up resolver for %s: %w", name, err)
+	}
+	if isZeroAddress(resolver) {
+		return "", ErrENSNotFound
+	}
+
+	address, err := r.ethCall(resolver, selectorAddr, node)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+	if isZeroAddress(address) {
+		return "", ErrENSNotFound
+	}
+
+	return address, nil
+}
+
+// ethCall calls a single-bytes32-argument view function and returns the result as an address
+func (r *ENSResolver) ethCall(to, selector string, node [32]byte) (string, error) {
+	request := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "eth_call",
+		"params": []interface{}{
+			map[string]string{"to": to, "data": "0x" + selector + hex.EncodeToString(node[:])},
+			"latest",
+		},
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := r.httpClient.Post(r.rpcURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("RPC returned status %d", resp.StatusCode)
+	}
+
+	var rpcResp struct {
+		Result string `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return "", fmt.Errorf("failed to decode RPC response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return "", fmt.Errorf("RPC error: %s", rpcResp.Error.Message)
+	}
+
+	// An address is returned ABI-encoded as the low 20 bytes of a 32-byte word
+	result := strings.TrimPrefix(rpcResp.Result, "0x")
+	if len(result) < 64 {
+		return "", fmt.Errorf("unexpected RPC result %q", rpcResp.Result)
+	}
+	return "0x" + strings.ToLower(result[24:64]), nil
+}
+
+// namehash implements the ENS name hashing algorithm (EIP-137)
+func namehash(name string) [32]byte {
+	var node [32]byte
+	if name == "" {
+		return node
+	}
+
+	labels := strings.Split(strings.ToLower(name), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		labelHash := keccak256([]byte(labels[i]))
+		copy(node[:], keccak256(append(node[:], labelHash...)))
+	}
+	return node
+}
+
+func keccak256(data []byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(data)
+	return hash.Sum(nil)
+}
+
+func isZeroAddress(address string) bool {
+	return strings.Trim(strings.TrimPrefix(address, "0x"), "0") == ""
+}
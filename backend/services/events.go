@@ -0,0 +1,390 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EventStore is an append-only log of trade events, one JSON object per line.
+// Events are never changed once written, so the cached trades and everything
+// computed from them can always be rebuilt by replaying the log.
+type EventStore struct {
+	path string
+	file *os.File
+	seq  int64 // Sequence number of the last event
+	mu   sync.Mutex
+}
+
+// OpenEventStore opens the event log at path for appending, creating it if
+// it doesn't exist. Plain events are encrypted if a storage key is in use.
+func OpenEventStore(path string) (*EventStore, error) {
+	es := &EventStore{path: path}
+	plain := false
+	if err := es.replay(func(event models.TradeEvent, sealed bool) { es.seq, plain = event.Seq, plain || !sealed }); err != nil {
+		return nil, err
+	}
+	if plain && storageCipher.Load() != nil {
+		if err := es.rewrite(func(*models.TradeEvent) bool { return false }); err != nil {
+			return nil, fmt.Errorf("failed to encrypt event log: %w", err)
+		}
+		log.Printf("Encrypted event log %s", path)
+	}
+	if es.file != nil {
+		return es, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, storeFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	es.file = file
+	return es, nil
+}
+
+// Append assigns event the next sequence number and writes it to the end of the log
+func (es *EventStore) Append(event *models.TradeEvent) error {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	event.Seq = es.seq + 1
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := es.file.Write(append(sealLine(data), '\n')); err != nil {
+		return fmt.Errorf("failed to append event: %w", err)
+	}
+	es.seq = event.Seq
+	return nil
+}
+
+// Replay calls apply with every event in the log, oldest first
+func (es *EventStore) Replay(apply func(models.TradeEvent)) error {
+	return es.replay(func(event models.TradeEvent, _ bool) { apply(event) })
+}
+
+// replay calls apply with every event in the log, oldest first, and whether
+// its line was encrypted
+func (es *EventStore) replay(apply func(event models.TradeEvent, sealed bool)) error {
+	file, err := os.Open(es.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read event log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 1<<20), 1<<30)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		data, sealed, err := openLine(scanner.Bytes())
+		if err != nil {
+			return fmt.Errorf("event log line %d: %w", line, err)
+		}
+		var event models.TradeEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return fmt.Errorf("event log line %d: %w", line, err)
+		}
+		apply(event, sealed)
+	}
+	return scanner.Err()
+}
+
+// Len returns the number of events in the log
+func (es *EventStore) Len() int64 {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return es.seq
+}
+
+// Compact rewrites the log without the trades before cutoff in events up to
+// and including sequence number through, returning how many were removed.
+// Events themselves are kept, so sequence numbers don't change; replaying the
+// compacted log gives the same cache as long as a pruned event for cutoff
+// follows them.
+func (es *EventStore) Compact(cutoff time.Time, through int64) (int, error) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	removed := 0
+	err := es.rewrite(func(event *models.TradeEvent) bool {
+		if event.Seq > through {
+			return false
+		}
+		kept := event.Trades[:0:0]
+		for _, trade := range event.Trades {
+			if !trade.Time.Before(cutoff) {
+				kept = append(kept, trade)
+			}
+		}
+		pruned := len(event.Trades) - len(kept)
+		removed += pruned
+		event.Trades = kept
+		return pruned > 0
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to compact event log: %w", err)
+	}
+	return removed, nil
+}
+
+// rewrite replaces the log with its events as changed by edit, which reports
+// whether it changed an event. Every event is written as the storage key
+// requires, and the log is only replaced if an event changed or wasn't. Caller
+// must hold es.mu.
+func (es *EventStore) rewrite(edit func(event *models.TradeEvent) bool) error {
+	temp, err := os.CreateTemp(filepath.Dir(es.path), filepath.Base(es.path)+".rewrite-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	encrypt := storageCipher.Load() != nil
+	changed := false
+	writer := bufio.NewWriter(temp)
+	err = es.replay(func(event models.TradeEvent, sealed bool) {
+		if edit(&event) || sealed != encrypt {
+			changed = true
+		}
+		data, _ := json.Marshal(event)
+		writer.Write(append(sealLine(data), '\n'))
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || !changed {
+		return err
+	}
+
+	if err := os.Rename(temp.Name(), es.path); err != nil {
+		return fmt.Errorf("failed to replace event log: %w", err)
+	}
+	file, err := os.OpenFile(es.path, os.O_APPEND|os.O_WRONLY, storeFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to reopen event log: %w", err)
+	}
+	if es.file != nil {
+		es.file.Close()
+	}
+	es.file = file
+	return nil
+}
+
+// Snapshot returns the whole log, without any event that is being appended
+func (es *EventStore) Snapshot() ([]byte, error) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return os.ReadFile(es.path)
+}
+
+// Close closes the log
+func (es *EventStore) Close() error {
+	return es.file.Close()
+}
+
+// record stamps event, appends it to the event log if one is configured and
+// applies it to the cache, returning the address's updated cache. A failure to
+// append is logged rather than returned, so a full disk doesn't stop
+// reconciliation. Caller must hold the address's lock, so each address's
+// events are recorded in the order they happened.
+func (rs *ReconciliationService) record(ctx context.Context, event models.TradeEvent) *AccountCache {
+	_, span := tracer.Start(ctx, "ReconciliationService.recordEvent",
+		trace.WithAttributes(attribute.String("type", string(event.Type)), attribute.Int("trades", len(event.Trades))))
+	defer span.End()
+
+	tallyFrom(ctx).addCollisions(keyCollisions(event.Trades))
+	event.RecordedAt = time.Now()
+	if rs.events != nil {
+		if err := rs.events.Append(&event); err != nil {
+			log.Printf("Failed to record %s event for %s: %v", event.Type, event.Address, err)
+		}
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.apply(event)
+}
+
+// apply projects event onto the cached trades of its address and returns the
+// address's cache. It depends only on the event and the state before it, so
+// replaying the same events always produces the same cache. Caller must hold
+// rs.mu.
+func (rs *ReconciliationService) apply(event models.TradeEvent) *AccountCache {
+	cache, exists := rs.accountCache[event.Address]
+
+	switch event.Type {
+	case models.EventTradesReset:
+		cache = &AccountCache{
+			trades:        event.Trades,
+			lastFetchTime: event.Window.End,
+			coverageStart: event.Window.Start,
+			cachedDays:    event.Days,
+		}
+		if event.Missing != nil {
+			cache.missingRanges = append(cache.missingRanges, *event.Missing)
+		}
+		rs.accountCache[event.Address] = cache
+
+	case models.EventTradesFetched:
+		if !exists {
+			return nil
+		}
+		cache.missingRanges = rangesOutside(cache.missingRanges, *event.Window)
+		if event.Missing != nil {
+			cache.missingRanges = append(cache.missingRanges, *event.Missing)
+		}
+		if len(event.Trades) > 0 {
+			cache.trades = rs.mergeTrades(cache.trades, event.Trades)
+		}
+		if event.Window.End.After(cache.lastFetchTime) {
+			cache.lastFetchTime = event.Window.End
+			cache.imported = false
+		}
+
+	case models.EventTradesRefetched:
+		if !exists {
+			return nil
+		}
+		window := *event.Window
+		if event.Missing == nil {
+			kept := cache.trades[:0:0]
+			for _, trade := range cache.trades {
+				if trade.Time.Before(window.Start) || !trade.Time.Before(window.End) {
+					kept = append(kept, trade)
+				}
+			}
+			cache.trades = kept
+			// The window is now complete
+			cache.missingRanges = rangesOutside(cache.missingRanges, window)
+		} else {
+			cache.missingRanges = append(cache.missingRanges, *event.Missing)
+		}
+		cache.trades = rs.mergeTrades(cache.trades, event.Trades)
+
+	case models.EventTradesInvalidated:
+		if !exists {
+			return nil
+		}
+		window := *event.Window
+		kept := cache.trades[:0:0]
+		for _, trade := range cache.trades {
+			if trade.Time.Before(window.Start) || !trade.Time.Before(window.End) {
+				kept = append(kept, trade)
+			}
+		}
+		cache.trades = kept
+		cache.missingRanges = append(rangesOutside(cache.missingRanges, window), window)
+
+	case models.EventTradesPruned:
+		if !exists {
+			return nil
+		}
+		cutoff := event.Window.End
+		kept := cache.trades[:0:0]
+		for _, trade := range cache.trades {
+			if !trade.Time.Before(cutoff) {
+				kept = append(kept, trade)
+			}
+		}
+		cache.trades = kept
+		cache.missingRanges = rangesEndingAfter(cache.missingRanges, cutoff)
+		if cache.coverageStart.Before(cutoff) {
+			cache.coverageStart = cutoff
+		}
+		// Requests for more history than is left fetch it again
+		if days := int(cache.lastFetchTime.Sub(cutoff).Hours() / 24); days < cache.cachedDays {
+			cache.cachedDays = max(days, 0)
+		}
+
+	case models.EventTradesRecovered:
+		if !exists {
+			return nil
+		}
+		cache.trades = rs.mergeTrades(cache.trades, event.Trades)
+
+	case models.EventTradesImported:
+		if len(event.Trades) == 0 {
+			return cache
+		}
+		if !exists {
+			cache = &AccountCache{imported: true}
+			rs.accountCache[event.Address] = cache
+		}
+		cache.trades = rs.mergeTrades(cache.trades, event.Trades)
+
+		oldest, newest := cache.trades[0].Time, cache.trades[len(cache.trades)-1].Time
+		if cache.coverageStart.IsZero() || oldest.Before(cache.coverageStart) {
+			cache.coverageStart = oldest
+		}
+		if cache.imported && newest.After(cache.lastFetchTime) {
+			cache.lastFetchTime = newest.Add(time.Millisecond)
+		}
+		if days := int(math.Ceil(event.RecordedAt.Sub(cache.coverageStart).Hours() / 24)); days > cache.cachedDays {
+			cache.cachedDays = days
+		}
+	}
+
+	return cache
+}
+
+// Rebuild discards every cached trade and replays the event log, so the cache
+// and everything computed from it reflect the current calculation code. It
+// returns the number of events replayed.
+func (rs *ReconciliationService) Rebuild() (int, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.accountCache = make(map[string]*AccountCache)
+	if rs.events == nil {
+		return 0, nil
+	}
+
+	replayed := 0
+	err := rs.events.Replay(func(event models.TradeEvent) {
+		rs.apply(event)
+		replayed++
+	})
+	return replayed, err
+}
+
+// partialRange returns the window a *PartialError says is missing
+func partialRange(err error) (*models.TimeRange, bool) {
+	var partial *PartialError
+	if !errors.As(err, &partial) {
+		return nil, false
+	}
+	return &models.TimeRange{Start: partial.MissingStart, End: partial.MissingEnd}, true
+}
+
+// rangesOutside returns the ranges not contained in window
+func rangesOutside(ranges []models.TimeRange, window models.TimeRange) []models.TimeRange {
+	var result []models.TimeRange
+	for _, r := range ranges {
+		if r.Start.Before(window.Start) || r.End.After(window.End) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
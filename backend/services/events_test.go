@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"hyperliquid-recon/models"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test that replaying the event log rebuilds the same caches as were built live
+func TestRebuildFromEvents(t *testing.T) {
+	UseHyperliquidTransport(newTestSyntheticFills(t, 6))
+	defer UseHyperliquidTransport(nil)
+
+	previousDelay := RateLimitDelay()
+	SetRateLimitDelay(0)
+	defer SetRateLimitDelay(previousDelay)
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	events, err := OpenEventStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open event log: %v", err)
+	}
+	live := NewReconciliationService()
+	live.UseEventStore(events)
+
+	ctx := context.Background()
+	if err := live.FetchAndReconcile(ctx, testAddress, 3); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := live.FetchAndReconcile(ctx, testAddress, 2); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	older := time.Now().AddDate(0, 0, -5)
+	live.ImportTrades(testAddress, []models.Trade{{Time: older, Coin: "ETH", Side: "B", Price: 100, Size: 1, Value: 100}})
+	events.Close()
+
+	t.Run("should record every change to the cache", func(t *testing.T) {
+		var types []models.TradeEventType
+		var seqs []int64
+		reopened, err := OpenEventStore(path)
+		if err != nil {
+			t.Fatalf("Failed to reopen event log: %v", err)
+		}
+		defer reopened.Close()
+		reopened.Replay(func(event models.TradeEvent) {
+			types = append(types, event.Type)
+			seqs = append(seqs, event.Seq)
+		})
+
+		expected := []models.TradeEventType{models.EventTradesReset, models.EventTradesFetched, models.EventTradesImported}
+		if !reflect.DeepEqual(types, expected) {
+			t.Errorf("Expected events %v, got %v", expected, types)
+		}
+		if !reflect.DeepEqual(seqs, []int64{1, 2, 3}) || reopened.Len() != 3 {
+			t.Errorf("Expected sequence numbers 1 to 3, got %v", seqs)
+		}
+	})
+
+	t.Run("should rebuild the same trades and daily P&L", func(t *testing.T) {
+		reopened, err := OpenEventStore(path)
+		if err != nil {
+			t.Fatalf("Failed to reopen event log: %v", err)
+		}
+		defer reopened.Close()
+
+		rebuilt := NewReconciliationService()
+		rebuilt.UseEventStore(reopened)
+		replayed, err := rebuilt.Rebuild()
+		if err != nil || replayed != 3 {
+			t.Fatalf("Expected 3 events replayed, got %d (%v)", replayed, err)
+		}
+
+		liveTrades, _ := live.CachedTrades(testAddress)
+		rebuiltTrades, _ := rebuilt.CachedTrades(testAddress)
+		if len(rebuiltTrades) != len(liveTrades) {
+			t.Fatalf("Expected %d trades, got %d", len(liveTrades), len(rebuiltTrades))
+		}
+
+		liveRecords, _ := live.CachedDailyRecords(testAddress)
+		rebuiltRecords, _ := rebuilt.CachedDailyRecords(testAddress)
+		if !reflect.DeepEqual(rebuiltRecords, liveRecords) {
+			t.Errorf("Expected rebuilt daily P&L %+v, got %+v", liveRecords, rebuiltRecords)
+		}
+
+		liveStart, _ := live.CachedCoverageStart(testAddress)
+		rebuiltStart, _ := rebuilt.CachedCoverageStart(testAddress)
+		if !rebuiltStart.Equal(liveStart) || !rebuiltStart.Equal(older) {
+			t.Errorf("Expected coverage from %s, got %s", liveStart, rebuiltStart)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"time"
+)
+
+// excursionInterval picks the shortest candle interval that covers a hold
+// from start to end in one candle request
+func excursionInterval(start, end time.Time) (string, time.Duration) {
+	span := end.Sub(start)
+	for _, candidate := range chartIntervals {
+		if span/candidate.length < maxCandles {
+			return candidate.name, candidate.length
+		}
+	}
+	last := chartIntervals[len(chartIntervals)-1]
+	return last.name, last.length
+}
+
+// measureExcursion returns the excursion of a round trip over candles
+// covering its hold. Candles are assumed to trade through the whole hold, so
+// the first and last may include prices from just before entry or after exit.
+func measureExcursion(roundTrip models.RoundTrip, interval string, candles []models.Candle) *models.Excursion {
+	if len(candles) == 0 || roundTrip.EntryPrice == 0 {
+		return nil
+	}
+
+	long := roundTrip.Direction == "long"
+	excursion := &models.Excursion{Interval: interval}
+	worst, best := roundTrip.EntryPrice, roundTrip.EntryPrice
+	for _, candle := range candles {
+		low, high := models.Money(candle.Low), models.Money(candle.High)
+		if !long {
+			low, high = high, low
+		}
+		if (long && low < worst) || (!long && low > worst) {
+			worst, excursion.WorstTime = low, candle.Time
+		}
+		if (long && high > best) || (!long && high < best) {
+			best, excursion.BestTime = high, candle.Time
+		}
+	}
+	if excursion.WorstTime.IsZero() {
+		excursion.WorstTime = roundTrip.EntryTime
+	}
+	if excursion.BestTime.IsZero() {
+		excursion.BestTime = roundTrip.EntryTime
+	}
+
+	adverse, favorable := roundTrip.EntryPrice-worst, best-roundTrip.EntryPrice
+	if !long {
+		adverse, favorable = -adverse, -favorable
+	}
+	excursion.WorstPrice = worst
+	excursion.BestPrice = best
+	excursion.MAE = models.Money(roundTo(float64(adverse)*float64(roundTrip.Size), 2))
+	excursion.MFE = models.Money(roundTo(float64(favorable)*float64(roundTrip.Size), 2))
+	excursion.MAEPct = roundTo(float64(adverse/roundTrip.EntryPrice)*100, 2)
+	excursion.MFEPct = roundTo(float64(favorable/roundTrip.EntryPrice)*100, 2)
+	if excursion.MFE > 0 {
+		capture := roundTo(float64(roundTrip.GrossPnL/excursion.MFE), 4)
+		excursion.Capture = &capture
+	}
+	return excursion
+}
+
+// AddExcursions measures the maximum adverse and favorable excursion of the
+// newest ExcursionMaxRoundTrips round trips of report from candles of their
+// coin, on the shortest interval that covers each hold in one request (1m for
+// holds under about three days). Older round trips are counted as skipped.
+func (rs *ReconciliationService) AddExcursions(ctx context.Context, report *models.RoundTripReport) error {
+	first := 0
+	if len(report.RoundTrips) > config.ExcursionMaxRoundTrips {
+		first = len(report.RoundTrips) - config.ExcursionMaxRoundTrips
+	}
+	report.ExcursionsSkipped = first
+
+	for i := first; i < len(report.RoundTrips); i++ {
+		roundTrip := &report.RoundTrips[i]
+		interval, length := excursionInterval(roundTrip.EntryTime, roundTrip.ExitTime)
+		coin := exchangeCoin(roundTrip.Coin, roundTrip.EntryTime.Format("2006-01-02"))
+		candles, err := rs.hlClient.FetchCandles(ctx, coin, interval, roundTrip.EntryTime.Truncate(length), roundTrip.ExitTime)
+		if err != nil {
+			return err
+		}
+		roundTrip.Excursion = measureExcursion(*roundTrip, interval, candles)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test measuring how far round trips went against and for their positions
+func TestAddExcursions(t *testing.T) {
+	entry := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	var asked []candleRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request candleSnapshotRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		asked = append(asked, request.Req)
+		fmt.Fprintf(w, `[
+			{"t": %d, "o": "100", "c": "96", "h": "101", "l": "95", "v": "1", "n": 3},
+			{"t": %d, "o": "96", "c": "108", "h": "110", "l": "96", "v": "1", "n": 3}
+		]`, entry.UnixMilli(), entry.Add(time.Minute).UnixMilli())
+	}))
+	defer server.Close()
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = server.URL
+
+	report := models.RoundTripReport{RoundTrips: []models.RoundTrip{
+		{Coin: "BTC", Direction: "long", EntryTime: entry, ExitTime: entry.Add(2 * time.Minute), Size: 2, EntryPrice: 100, ExitPrice: 105, GrossPnL: 10},
+		{Coin: "BTC", Direction: "short", EntryTime: entry, ExitTime: entry.Add(2 * time.Minute), Size: 1, EntryPrice: 100, ExitPrice: 99, GrossPnL: 1},
+	}}
+	if err := rs.AddExcursions(context.Background(), &report); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(asked) != 2 || asked[0].Interval != "1m" || asked[0].Coin != "BTC" || asked[0].StartTime != entry.UnixMilli() {
+		t.Errorf("Unexpected candle requests %+v", asked)
+	}
+
+	t.Run("should measure a long from lows and highs", func(t *testing.T) {
+		e := report.RoundTrips[0].Excursion
+		if e == nil || e.MAE != 10 || e.MFE != 20 || e.MAEPct != 5 || e.MFEPct != 10 || e.WorstPrice != 95 || e.BestPrice != 110 {
+			t.Fatalf("Unexpected excursion %+v", e)
+		}
+		if !e.BestTime.Equal(entry.Add(time.Minute)) || e.Capture == nil || *e.Capture != 0.5 {
+			t.Errorf("Expected the best price in the second candle and half of it captured, got %+v", e)
+		}
+	})
+
+	t.Run("should measure a short the other way", func(t *testing.T) {
+		e := report.RoundTrips[1].Excursion
+		if e == nil || e.MAE != 10 || e.MFE != 5 || e.WorstPrice != 110 || e.BestPrice != 95 {
+			t.Errorf("Unexpected excursion %+v", e)
+		}
+	})
+
+	t.Run("should pick a longer interval for long holds", func(t *testing.T) {
+		if interval, _ := excursionInterval(entry, entry.AddDate(0, 0, 10)); interval != "3m" {
+			t.Errorf("Expected 3m candles for a 10-day hold, got %s", interval)
+		}
+	})
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"hyperliquid-recon/models"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Supported export formats
+const (
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+)
+
+// ContentType returns the MIME type for an export format
+func ContentType(format string) string {
+	if format == FormatParquet {
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv"
+}
+
+// tradesCSVHeader is the header row of trade CSV exports
+var tradesCSVHeader = []string{"time", "coin", "side", "px", "sz", "value", "fee", "startPosition", "realizedPnl"}
+
+// tradeRow is the Parquet schema for exported trades
+type tradeRow struct {
+	Time  int64   `parquet:"time,timestamp(millisecond)"`
+	Coin  string  `parquet:"coin,dict"`
+	Side  string  `parquet:"side,dict"`
+	Price float64 `parquet:"px"`
+	Size  float64 `parquet:"sz"`
+	Value float64 `parquet:"value"`
+	Fee   float64 `parquet:"fee"`
+	// StartPosition is null when unknown
+	StartPosition *float64 `parquet:"startPosition,optional"`
+	// RealizedPnL is null in files exported before it was added
+	RealizedPnL *float64 `parquet:"realizedPnl,optional"`
+}
+
+// dailyPnLRow is the Parquet schema for exported daily P&L records
+type dailyPnLRow struct {
+	Date          int32   `parquet:"date,date"` // Days since the Unix epoch
+	TradeCount    int32   `parquet:"tradeCount"`
+	DailyPnL      float64 `parquet:"dailyPnL"`
+	CumulativePnL float64 `parquet:"cumulativePnL"`
+}
+
+// EncodeTrades encodes trades in the given export format, identifying the
+// build that produced them in a CSV footer or the Parquet metadata. Trades
+// without a realized P&L are attributed one with AttributeRealizedPnL.
+func EncodeTrades(format string, trades []models.Trade) ([]byte, error) {
+	return EncodeTradesAs(format, trades, DefaultCSVFormat)
+}
+
+// EncodeTradesAs is EncodeTrades writing CSV in csvFormat, which is described
+// in the footer unless it is the default
+func EncodeTradesAs(format string, trades []models.Trade, csvFormat CSVFormat) ([]byte, error) {
+	var buf bytes.Buffer
+	err := WriteTradesAs(&buf, format, trades, csvFormat)
+	return buf.Bytes(), err
+}
+
+// WriteTradesAs is EncodeTradesAs writing to w, for exports too large to hold in memory
+func WriteTradesAs(w io.Writer, format string, trades []models.Trade, csvFormat CSVFormat) error {
+	trades = AttributeRealizedPnL(trades)
+
+	switch format {
+	case FormatCSV:
+		if err := WriteTradesCSV(w, trades, csvFormat); err != nil {
+			return err
+		}
+		return WriteExportFooter(w, csvFormat)
+	case FormatParquet:
+		return WriteTradesParquet(w, trades)
+	}
+	return fmt.Errorf("unsupported export format %q", format)
+}
+
+// EncodeDailyPnL encodes daily P&L records in the given export format, like EncodeTrades
+func EncodeDailyPnL(format string, records []models.DailyPnL) ([]byte, error) {
+	return EncodeDailyPnLAs(format, records, DefaultCSVFormat)
+}
+
+// EncodeDailyPnLAs is EncodeDailyPnL writing CSV in csvFormat, like EncodeTradesAs
+func EncodeDailyPnLAs(format string, records []models.DailyPnL, csvFormat CSVFormat) ([]byte, error) {
+	var buf bytes.Buffer
+	err := WriteDailyPnLAs(&buf, format, records, csvFormat)
+	return buf.Bytes(), err
+}
+
+// WriteDailyPnLAs is EncodeDailyPnLAs writing to w, like WriteTradesAs
+func WriteDailyPnLAs(w io.Writer, format string, records []models.DailyPnL, csvFormat CSVFormat) error {
+	switch format {
+	case FormatCSV:
+		if err := WriteDailyPnLCSV(w, records, csvFormat); err != nil {
+			return err
+		}
+		return WriteExportFooter(w, csvFormat)
+	case FormatParquet:
+		return WriteDailyPnLParquet(w, records)
+	}
+	return fmt.Errorf("unsupported export format %q", format)
+}
+
+// csvColumn is a column of a CSV export of T, with how to format its value
+type csvColumn[T any] struct {
+	name  string
+	value func(f CSVFormat, row T) string
+}
+
+// tradeColumns are the columns of trade CSV exports, in tradesCSVHeader order
+var tradeColumns = []csvColumn[models.Trade]{
+	{"time", func(f CSVFormat, trade models.Trade) string { return f.time(trade.Time) }},
+	{"coin", func(f CSVFormat, trade models.Trade) string { return trade.Coin }},
+	{"side", func(f CSVFormat, trade models.Trade) string { return trade.Side }},
+	{"px", func(f CSVFormat, trade models.Trade) string { return f.float(float64(trade.Price)) }},
+	{"sz", func(f CSVFormat, trade models.Trade) string { return f.float(float64(trade.Size)) }},
+	{"value", func(f CSVFormat, trade models.Trade) string { return f.float(float64(trade.Value)) }},
+	{"fee", func(f CSVFormat, trade models.Trade) string { return f.float(float64(trade.Fee)) }},
+	{"startPosition", func(f CSVFormat, trade models.Trade) string { return f.optionalFloat(floatPtr(trade.StartPosition)) }},
+	{"realizedPnl", func(f CSVFormat, trade models.Trade) string { return f.optionalFloat(floatPtr(trade.RealizedPnL)) }},
+}
+
+// floatPtr returns the amount v points to as a *float64, or nil if v is nil
+func floatPtr[T ~float64](v *T) *float64 {
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
+
+// dailyPnLColumns are the columns of daily P&L CSV exports
+var dailyPnLColumns = []csvColumn[models.DailyPnL]{
+	{"date", func(f CSVFormat, record models.DailyPnL) string { return f.date(record.Date) }},
+	{"tradeCount", func(f CSVFormat, record models.DailyPnL) string { return f.int(record.TradeCount) }},
+	{"dailyPnL", func(f CSVFormat, record models.DailyPnL) string { return f.float(float64(record.DailyPnL)) }},
+	{"cumulativePnL", func(f CSVFormat, record models.DailyPnL) string { return f.float(float64(record.CumulativePnL)) }},
+}
+
+// WriteTradesCSV writes trades as CSV with a header row in format f
+func WriteTradesCSV(w io.Writer, trades []models.Trade, f CSVFormat) error {
+	return writeCSV(w, f, tradeColumns, trades)
+}
+
+// WriteDailyPnLCSV writes daily P&L records as CSV with a header row in format f
+func WriteDailyPnLCSV(w io.Writer, records []models.DailyPnL, f CSVFormat) error {
+	return writeCSV(w, f, dailyPnLColumns, records)
+}
+
+// writeCSV writes rows as CSV with a header row, in the columns of f or all
+// of columns if f doesn't choose them
+func writeCSV[T any](w io.Writer, f CSVFormat, columns []csvColumn[T], rows []T) error {
+	table, err := newCSVTable(w, f, columns)
+	if err != nil {
+		return err
+	}
+	return table.write(rows)
+}
+
+// csvTable is a CSV export being written, which can take its rows in chunks
+type csvTable[T any] struct {
+	writer  *csv.Writer
+	format  CSVFormat
+	columns []csvColumn[T]
+	record  []string
+}
+
+// newCSVTable writes the header row of a CSV export to w, in the columns of f
+// or all of columns if f doesn't choose them
+func newCSVTable[T any](w io.Writer, f CSVFormat, columns []csvColumn[T]) (*csvTable[T], error) {
+	selected := columns
+	if len(f.Columns) > 0 {
+		selected = nil
+		for _, name := range f.Columns {
+			for _, column := range columns {
+				if column.name == name {
+					selected = append(selected, column)
+				}
+			}
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = f.Delimiter
+	header := make([]string, len(selected))
+	for i, column := range selected {
+		header[i] = column.name
+		if renamed := f.Headers[column.name]; renamed != "" {
+			header[i] = renamed
+		}
+	}
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	return &csvTable[T]{writer: writer, format: f, columns: selected, record: make([]string, len(selected))}, nil
+}
+
+// write writes rows and flushes them to the underlying writer
+func (t *csvTable[T]) write(rows []T) error {
+	for _, row := range rows {
+		for i, column := range t.columns {
+			t.record[i] = column.value(t.format, row)
+		}
+		if err := t.writer.Write(t.record); err != nil {
+			return err
+		}
+	}
+
+	t.writer.Flush()
+	return t.writer.Error()
+}
+
+// WriteRoundTripsCSV writes round trips as CSV with a header row in format f
+func WriteRoundTripsCSV(w io.Writer, roundTrips []models.RoundTrip, f CSVFormat) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = f.Delimiter
+	header := []string{"coin", "direction", "entryTime", "exitTime", "durationMs", "size", "entryPx", "exitPx", "grossPnl", "fees", "netPnl", "returnPct"}
+	// Excursion columns are only added when they were measured
+	excursions := false
+	for _, roundTrip := range roundTrips {
+		excursions = excursions || roundTrip.Excursion != nil
+	}
+	if excursions {
+		header = append(header, "mae", "mfe", "maePct", "mfePct")
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, roundTrip := range roundTrips {
+		row := []string{
+			roundTrip.Coin,
+			roundTrip.Direction,
+			f.time(roundTrip.EntryTime),
+			f.time(roundTrip.ExitTime),
+			f.int(int(roundTrip.DurationMs)),
+			f.float(float64(roundTrip.Size)),
+			f.float(float64(roundTrip.EntryPrice)),
+			f.float(float64(roundTrip.ExitPrice)),
+			f.float(float64(roundTrip.GrossPnL)),
+			f.float(float64(roundTrip.Fees)),
+			f.float(float64(roundTrip.NetPnL)),
+			f.float(roundTrip.ReturnPct),
+		}
+		if excursions {
+			if e := roundTrip.Excursion; e != nil {
+				row = append(row, f.float(float64(e.MAE)), f.float(float64(e.MFE)), f.float(e.MAEPct), f.float(e.MFEPct))
+			} else {
+				row = append(row, "", "", "", "")
+			}
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteStatementCSV writes the daily lines of a statement as CSV with a header
+// row in format f, followed by a totals row dated with the period
+func WriteStatementCSV(w io.Writer, statement models.Statement, f CSVFormat) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = f.Delimiter
+	header := []string{"date", "tradeCount", "pnl", "equity", "highWaterMark", "drawdown", "drawdownPct", "accruedFee"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	tradeCount := 0
+	for _, line := range statement.Lines {
+		tradeCount += line.TradeCount
+		row := []string{
+			f.date(line.Date),
+			f.int(line.TradeCount),
+			f.float(float64(line.PnL)),
+			f.float(float64(line.Equity)),
+			f.float(float64(line.HighWaterMark)),
+			f.float(float64(line.Drawdown)),
+			f.optionalFloat(line.DrawdownPct),
+			f.float(float64(line.AccruedFee)),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	total := []string{
+		f.date(statement.From) + "/" + f.date(statement.To),
+		f.int(tradeCount),
+		f.float(float64(statement.PeriodPnL)),
+		f.float(float64(statement.ClosingEquity)),
+		f.float(float64(statement.HighWaterMark)),
+		f.float(float64(statement.MaxDrawdown)),
+		f.optionalFloat(statement.MaxDrawdownPct),
+		f.float(float64(statement.PerformanceFee)),
+	}
+	if err := writer.Write(total); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteTradesParquet writes trades as a zstd-compressed Parquet file
+func WriteTradesParquet(w io.Writer, trades []models.Trade) error {
+	rows, _ := tradeRows(trades)
+	return parquet.Write(w, rows, append(exportMetadata(), parquet.Compression(&parquet.Zstd))...)
+}
+
+// WriteDailyPnLParquet writes daily P&L records as a zstd-compressed Parquet file
+func WriteDailyPnLParquet(w io.Writer, records []models.DailyPnL) error {
+	rows, err := dailyPnLRows(records)
+	if err != nil {
+		return err
+	}
+	return parquet.Write(w, rows, append(exportMetadata(), parquet.Compression(&parquet.Zstd))...)
+}
+
+// tradeRows converts trades to Parquet rows; it never fails
+func tradeRows(trades []models.Trade) ([]tradeRow, error) {
+	rows := make([]tradeRow, len(trades))
+	for i, trade := range trades {
+		rows[i] = tradeRow{
+			Time:          trade.Time.UnixMilli(),
+			Coin:          trade.Coin,
+			Side:          trade.Side,
+			Price:         float64(trade.Price),
+			Size:          float64(trade.Size),
+			Value:         float64(trade.Value),
+			Fee:           float64(trade.Fee),
+			StartPosition: floatPtr(trade.StartPosition),
+			RealizedPnL:   floatPtr(trade.RealizedPnL),
+		}
+	}
+	return rows, nil
+}
+
+// dailyPnLRows converts daily P&L records to Parquet rows
+func dailyPnLRows(records []models.DailyPnL) ([]dailyPnLRow, error) {
+	rows := make([]dailyPnLRow, len(records))
+	for i, record := range records {
+		date, err := time.Parse("2006-01-02", record.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid record date %q: %w", record.Date, err)
+		}
+		rows[i] = dailyPnLRow{
+			Date:          int32(date.Unix() / 86400),
+			TradeCount:    int32(record.TradeCount),
+			DailyPnL:      float64(record.DailyPnL),
+			CumulativePnL: float64(record.CumulativePnL),
+		}
+	}
+	return rows, nil
+}
+
+// formatOptionalFloat formats v, or returns an empty string if it is nil
+func formatOptionalFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return formatFloat(*v)
+}
+
+// formatFloat formats a float with the minimum digits needed to round-trip it
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"bytes"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Test EncodeTrades
+func TestEncodeTrades(t *testing.T) {
+	trades := []models.Trade{
+		createTestTrade("2025-01-01T10:00:00Z", "BTC", "B", 50000, 0.5),
+		createTestTrade("2025-01-01T11:00:00Z", "ETH", "A", 3000.25, 2),
+	}
+
+	t.Run("should encode CSV with header", func(t *testing.T) {
+		body, err := EncodeTrades(FormatCSV, trades)
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+		if len(lines) != 4 {
+			t.Fatalf("Expected 4 lines, got %d", len(lines))
+		}
+		if !strings.HasPrefix(lines[3], "# hyperliquid-recon commit ") {
+			t.Errorf("Expected a build footer, got %s", lines[3])
+		}
+		if lines[2] != "2025-01-01T11:00:00Z,ETH,A,3000.25,2,6000.5,0,,0" {
+			t.Errorf("Unexpected row: %s", lines[2])
+		}
+	})
+
+	t.Run("should localize CSV numbers and dates", func(t *testing.T) {
+		csvFormat, err := NewCSVFormat(",", ".", "", "DD.MM.YYYY")
+		if err != nil {
+			t.Fatalf("Expected a valid format, got %v", err)
+		}
+		body, err := EncodeTradesAs(FormatCSV, trades, csvFormat)
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+		if len(lines) != 5 || !strings.HasPrefix(lines[3], "# format decimal=\",\" thousands=\".\" delimiter=\";\"") {
+			t.Fatalf("Expected a format footer, got %q", lines)
+		}
+		if lines[2] != "01.01.2025 11:00:00.000;ETH;A;3.000,25;2;6.000,5;0;;0" {
+			t.Errorf("Unexpected row: %s", lines[2])
+		}
+	})
+
+	t.Run("should reject conflicting CSV separators", func(t *testing.T) {
+		for _, options := range [][4]string{{",", ",", "", ""}, {".", "", ".", ""}, {"", "", "", "YYYY-MM"}, {"", "", "", "DD-MM-YYYYx"}} {
+			if _, err := NewCSVFormat(options[0], options[1], options[2], options[3]); err == nil {
+				t.Errorf("Expected options %q to be rejected", options)
+			}
+		}
+	})
+
+	t.Run("should round-trip typed Parquet columns", func(t *testing.T) {
+		body, err := EncodeTrades(FormatParquet, trades)
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+
+		rows, err := parquet.Read[tradeRow](bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			t.Fatalf("Failed to read Parquet: %v", err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("Expected 2 rows, got %d", len(rows))
+		}
+		if rows[1].Time != trades[1].Time.UnixMilli() || rows[1].Coin != "ETH" || rows[1].Price != 3000.25 {
+			t.Errorf("Unexpected row: %+v", rows[1])
+		}
+
+		file, err := parquet.OpenFile(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			t.Fatalf("Failed to open Parquet: %v", err)
+		}
+		if version, ok := file.Lookup("hyperliquid-recon.calculatorVersion"); !ok || version != config.CalculatorVersion {
+			t.Errorf("Expected the calculator version in the metadata, got %q", version)
+		}
+	})
+
+	t.Run("should reject unknown format", func(t *testing.T) {
+		if _, err := EncodeTrades("xlsx", trades); err == nil {
+			t.Errorf("Expected error for unknown format")
+		}
+	})
+}
+
+// Test EncodeDailyPnL
+func TestEncodeDailyPnLParquet(t *testing.T) {
+	records := []models.DailyPnL{{Date: "2025-01-02", TradeCount: 3, DailyPnL: 100, CumulativePnL: 250}}
+
+	body, err := EncodeDailyPnL(FormatParquet, records)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	rows, err := parquet.Read[dailyPnLRow](bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("Failed to read Parquet: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Date != 20090 || rows[0].TradeCount != 3 {
+		t.Errorf("Unexpected row: %+v", rows)
+	}
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// ExportRequest is an export for a job to write to the download store
+type ExportRequest struct {
+	Address   string
+	Dataset   string        // ExportKindTrades or ExportKindPnL
+	Format    string        // FormatCSV or FormatParquet
+	CSVFormat CSVFormat     // Layout of CSV exports
+	Tenant    string        // Tenant the download belongs to
+	Signer    *ExportSigner // Digests and signs the download like a direct export
+}
+
+// filename returns the name the export is downloaded as, like a direct export
+func (req ExportRequest) filename() string {
+	return fmt.Sprintf("%s_%s.%s", req.Dataset, req.Address, req.Format)
+}
+
+// WriteTradesInChunks is WriteTradesAs writing config.ExportChunkRows trades
+// at a time, calling progress with the number written after each chunk. It
+// stops with ctx's error if ctx is done between chunks.
+func WriteTradesInChunks(ctx context.Context, w io.Writer, format string, trades []models.Trade, csvFormat CSVFormat, progress func(written int)) error {
+	trades = AttributeRealizedPnL(trades)
+	return writeChunked(ctx, w, format, config.ExportChunkRows, trades, csvFormat, tradeColumns, tradeRows, progress)
+}
+
+// WriteDailyPnLInChunks is WriteDailyPnLAs writing in chunks, like WriteTradesInChunks
+func WriteDailyPnLInChunks(ctx context.Context, w io.Writer, format string, records []models.DailyPnL, csvFormat CSVFormat, progress func(written int)) error {
+	return writeChunked(ctx, w, format, config.ExportChunkRows, records, csvFormat, dailyPnLColumns, dailyPnLRows, progress)
+}
+
+// writeChunked writes rows in format chunkRows at a time. CSV chunks are
+// flushed as they are written; each Parquet chunk is written out as a row
+// group, so neither format holds more than a chunk of encoded rows.
+func writeChunked[T, R any](ctx context.Context, w io.Writer, format string, chunkRows int, rows []T, csvFormat CSVFormat,
+	columns []csvColumn[T], toParquet func([]T) ([]R, error), progress func(written int)) error {
+	var writeChunk func([]T) error
+	var finish func() error
+	switch format {
+	case FormatCSV:
+		table, err := newCSVTable(w, csvFormat, columns)
+		if err != nil {
+			return err
+		}
+		writeChunk = table.write
+		finish = func() error { return WriteExportFooter(w, csvFormat) }
+	case FormatParquet:
+		writer := parquet.NewGenericWriter[R](w, append(exportMetadata(), parquet.Compression(&parquet.Zstd))...)
+		writeChunk = func(chunk []T) error {
+			converted, err := toParquet(chunk)
+			if err != nil {
+				return err
+			}
+			if _, err := writer.Write(converted); err != nil {
+				return err
+			}
+			return writer.Flush()
+		}
+		finish = writer.Close
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+
+	for start := 0; start < len(rows); start += chunkRows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := min(start+chunkRows, len(rows))
+		if err := writeChunk(rows[start:end]); err != nil {
+			return err
+		}
+		progress(end)
+	}
+	return finish()
+}
+
+// runExport writes the export req to the download store, reporting progress
+// on the job as it goes
+func (jm *JobManager) runExport(ctx context.Context, job *models.RefreshJob, req ExportRequest) error {
+	var write func(w io.Writer, progress func(int)) error
+	var rows int
+	switch req.Dataset {
+	case ExportKindTrades:
+		trades, exists := jm.reconService.CachedTrades(req.Address)
+		if !exists {
+			return ErrNotCached
+		}
+		rows = len(trades)
+		write = func(w io.Writer, progress func(int)) error {
+			return WriteTradesInChunks(ctx, w, req.Format, trades, req.CSVFormat, progress)
+		}
+	case ExportKindPnL:
+		records, exists := jm.reconService.CachedDailyRecords(req.Address)
+		if !exists {
+			return ErrNotCached
+		}
+		rows = len(records)
+		write = func(w io.Writer, progress func(int)) error {
+			return WriteDailyPnLInChunks(ctx, w, req.Format, records, req.CSVFormat, progress)
+		}
+	default:
+		return fmt.Errorf("unknown export dataset %q", req.Dataset)
+	}
+	jm.updateExport(job, func(export *models.ExportJob) { export.Rows = rows })
+
+	file, err := CurrentDownloads().Create(req.Tenant, req.filename(), ContentType(req.Format), req.Signer)
+	if err != nil {
+		return err
+	}
+	err = write(file, func(written int) {
+		jm.updateExport(job, func(export *models.ExportJob) {
+			export.RowsWritten = written
+			export.Progress = float64(written) / float64(rows)
+		})
+	})
+	if err != nil {
+		file.Discard()
+		return err
+	}
+	download, err := file.Finish()
+	if err != nil {
+		return err
+	}
+	jm.updateExport(job, func(export *models.ExportJob) {
+		export.Progress = 1
+		export.Download = &download
+	})
+	return nil
+}
+
+// updateExport changes the progress of an export job. The progress is
+// replaced rather than changed in place, as copies of the job handed out
+// earlier share it.
+func (jm *JobManager) updateExport(job *models.RefreshJob, update func(export *models.ExportJob)) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	export := *job.Export
+	update(&export)
+	job.Export = &export
+}
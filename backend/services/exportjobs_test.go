@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Test writing exports in chunks and running them as jobs
+func TestExportJobs(t *testing.T) {
+	var trades []models.Trade
+	for i := 0; i < 5; i++ {
+		trades = append(trades, createTestTrade(fmt.Sprintf("2025-01-0%dT10:00:00Z", i+1), "BTC", "B", 50000+float64(i), 0.5))
+	}
+
+	t.Run("should write the same CSV in chunks as at once", func(t *testing.T) {
+		var whole, chunked bytes.Buffer
+		if err := WriteTradesAs(&whole, FormatCSV, trades, DefaultCSVFormat); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		var progress []int
+		err := writeChunked(context.Background(), &chunked, FormatCSV, 2, AttributeRealizedPnL(trades), DefaultCSVFormat,
+			tradeColumns, tradeRows, func(written int) { progress = append(progress, written) })
+		if err != nil {
+			t.Fatalf("Chunked write failed: %v", err)
+		}
+		if chunked.String() != whole.String() {
+			t.Errorf("Expected identical CSV, got\n%s\nwant\n%s", chunked.String(), whole.String())
+		}
+		if fmt.Sprint(progress) != "[2 4 5]" {
+			t.Errorf("Expected progress after each chunk, got %v", progress)
+		}
+	})
+
+	t.Run("should write every chunk to one Parquet file", func(t *testing.T) {
+		var body bytes.Buffer
+		err := writeChunked(context.Background(), &body, FormatParquet, 2, trades, DefaultCSVFormat, tradeColumns, tradeRows, func(int) {})
+		if err != nil {
+			t.Fatalf("Chunked write failed: %v", err)
+		}
+		rows, err := parquet.Read[tradeRow](bytes.NewReader(body.Bytes()), int64(body.Len()))
+		if err != nil || len(rows) != len(trades) || rows[4].Price != 50004 {
+			t.Errorf("Expected %d rows in order, got %d (%v)", len(trades), len(rows), err)
+		}
+	})
+
+	t.Run("should stop between chunks once cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		err := writeChunked(ctx, io.Discard, FormatCSV, 2, trades, DefaultCSVFormat, tradeColumns, tradeRows, func(int) { cancel() })
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("should export in the background to a download", func(t *testing.T) {
+		store, err := NewDownloadStore(t.TempDir(), "")
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		defer UseDownloads(CurrentDownloads())
+		UseDownloads(store)
+
+		rs := NewReconciliationService()
+		rs.ImportTrades(testAddress, trades)
+		jm := NewJobManager(rs, NewWebhookDispatcher(""))
+
+		job := jm.SubmitExport(context.Background(), ExportRequest{
+			Address: testAddress, Dataset: ExportKindTrades, Format: FormatCSV, CSVFormat: DefaultCSVFormat,
+			Tenant: "default", Signer: NewExportSigner(""),
+		})
+		if job.Kind != models.JobExport || job.Export == nil || job.Export.Dataset != ExportKindTrades {
+			t.Fatalf("Expected an export job, got %+v", job)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for job.FinishedAt == nil && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			job, _ = jm.GetJob(job.ID)
+		}
+		if job.Status != models.JobSucceeded || job.Export.Download == nil {
+			t.Fatalf("Expected the export to succeed with a download, got %+v (%s)", job.Export, job.Error)
+		}
+		if job.Export.Rows != 5 || job.Export.RowsWritten != 5 || job.Export.Progress != 1 {
+			t.Errorf("Expected every row written, got %+v", job.Export)
+		}
+
+		token := job.Export.Download.URL[len("/api/v1/downloads/"):]
+		download, file, err := store.Open(token, time.Now())
+		if err != nil {
+			t.Fatalf("Failed to open download: %v", err)
+		}
+		defer file.Close()
+		body, _ := io.ReadAll(file)
+		expected, _ := EncodeTrades(FormatCSV, trades)
+		if download.Name != "trades_"+testAddress+".csv" || !bytes.Equal(body, expected) {
+			t.Errorf("Expected the trades export, got %s %q", download.Name, body)
+		}
+	})
+
+	t.Run("should fail an export of an address that was never refreshed", func(t *testing.T) {
+		defer UseDownloads(CurrentDownloads())
+		store, _ := NewDownloadStore(t.TempDir(), "")
+		UseDownloads(store)
+
+		jm := NewJobManager(NewReconciliationService(), NewWebhookDispatcher(""))
+		job := jm.SubmitExport(context.Background(), ExportRequest{Address: testAddress, Dataset: ExportKindPnL, Format: FormatCSV, Tenant: "default"})
+		deadline := time.Now().Add(5 * time.Second)
+		for job.FinishedAt == nil && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			job, _ = jm.GetJob(job.ID)
+		}
+		if job.Status != models.JobFailed || job.Export.Download != nil {
+			t.Errorf("Expected the export to fail, got %s %+v", job.Status, job.Export)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Test digesting, signing and verifying export files
+func TestExportSigner(t *testing.T) {
+	body := []byte("time,coin,side,px,sz\n2025-01-01T00:00:00Z,BTC,B,100,1\n")
+
+	t.Run("should verify an unaltered file and reject an altered one", func(t *testing.T) {
+		signer := NewExportSigner("statement-key")
+		digest, signature := signer.Sign(body)
+		if len(digest) != 64 || len(signature) != 64 {
+			t.Fatalf("Expected hex SHA-256 digest and signature, got %q %q", digest, signature)
+		}
+
+		result, err := signer.Verify(body, "sha256="+digest, "sha256="+signature)
+		if err != nil || !result.Valid || !*result.DigestValid || !*result.SignatureValid {
+			t.Errorf("Expected the file to verify, got %+v (%v)", result, err)
+		}
+
+		altered := []byte(strings.Replace(string(body), "100", "101", 1))
+		result, _ = signer.Verify(altered, digest, signature)
+		if result.Valid || *result.DigestValid || *result.SignatureValid {
+			t.Errorf("Expected the altered file to fail, got %+v", result)
+		}
+
+		// A recipient who recomputes the digest still can't forge the signature
+		result, _ = signer.Verify(altered, sha256Hex(altered), signature)
+		if result.Valid || !*result.DigestValid || *result.SignatureValid {
+			t.Errorf("Expected only the signature to fail, got %+v", result)
+		}
+	})
+
+	t.Run("should only digest without a key", func(t *testing.T) {
+		signer := NewExportSigner("")
+		digest, signature := signer.Sign(body)
+		if digest != sha256Hex(body) || signature != "" {
+			t.Errorf("Expected a digest only, got %q %q", digest, signature)
+		}
+		if _, err := signer.Verify(body, "", "abc"); !errors.Is(err, ErrExportSigningDisabled) {
+			t.Errorf("Expected ErrExportSigningDisabled, got %v", err)
+		}
+		result, err := signer.Verify(body, digest, "")
+		if err != nil || !result.Valid || result.SignatureValid != nil {
+			t.Errorf("Expected the digest to verify, got %+v (%v)", result, err)
+		}
+	})
+
+}
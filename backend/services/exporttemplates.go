@@ -0,0 +1,128 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrExportTemplateNotFound is returned for an unknown export template name
+var ErrExportTemplateNotFound = errors.New("export template not found")
+
+// templateNamePattern is what export template names may look like, so they can be used in URLs
+var templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// ExportTemplateStore keeps a tenant's named CSV export templates, persisted
+// as JSON to path if one is set
+type ExportTemplateStore struct {
+	templates map[string]*models.ExportTemplate // key: name
+	mu        sync.RWMutex
+	path      string
+}
+
+// NewExportTemplateStore creates a template store, loading saved templates from path if it exists
+func NewExportTemplateStore(path string) (*ExportTemplateStore, error) {
+	s := &ExportTemplateStore{templates: make(map[string]*models.ExportTemplate), path: path}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := readStoreFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export templates: %w", err)
+	}
+	var templates []models.ExportTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("failed to parse export templates: %w", err)
+	}
+	for i := range templates {
+		s.templates[templates[i].Name] = &templates[i]
+	}
+	return s, nil
+}
+
+// TemplateFormat returns the CSV format a template describes
+func TemplateFormat(template models.ExportTemplate) (CSVFormat, error) {
+	f, err := NewCSVFormat(template.Decimal, template.Thousands, template.Delimiter, template.DateFormat)
+	if err != nil {
+		return CSVFormat{}, err
+	}
+	return f.WithColumns(template.Kind, template.Columns, template.Headers)
+}
+
+// Save creates or replaces the template with template's name. It returns
+// ErrInvalidCSVFormat if the name or layout is invalid.
+func (s *ExportTemplateStore) Save(template models.ExportTemplate) (models.ExportTemplate, error) {
+	if !templateNamePattern.MatchString(template.Name) {
+		return models.ExportTemplate{}, fmt.Errorf("%w: template names are up to 64 letters, digits, '.', '_' or '-'", ErrInvalidCSVFormat)
+	}
+	if _, err := TemplateFormat(template); err != nil {
+		return models.ExportTemplate{}, err
+	}
+	template.UpdatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.templates[template.Name] = &template
+	return template, s.persist()
+}
+
+// Get returns the template with the given name
+func (s *ExportTemplateStore) Get(name string) (models.ExportTemplate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	template, exists := s.templates[name]
+	if !exists {
+		return models.ExportTemplate{}, ErrExportTemplateNotFound
+	}
+	return *template, nil
+}
+
+// List returns all templates sorted by name
+func (s *ExportTemplateStore) List() []models.ExportTemplate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	templates := make([]models.ExportTemplate, 0, len(s.templates))
+	for _, template := range s.templates {
+		templates = append(templates, *template)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+// Delete removes a template and reports whether it existed
+func (s *ExportTemplateStore) Delete(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.templates[name]; !exists {
+		return false, nil
+	}
+	delete(s.templates, name)
+	return true, s.persist()
+}
+
+// persist writes all templates to the templates file; caller must hold s.mu
+func (s *ExportTemplateStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	templates := make([]models.ExportTemplate, 0, len(s.templates))
+	for _, template := range s.templates {
+		templates = append(templates, *template)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return writeJSONFile(s.path, templates)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test saving export templates and exporting with them
+func TestExportTemplates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export-templates.json")
+	store, _ := NewExportTemplateStore(path)
+
+	t.Run("should export the chosen columns under their headers", func(t *testing.T) {
+		_, err := store.Save(models.ExportTemplate{
+			Name:      "back-office",
+			Kind:      ExportKindTrades,
+			Columns:   []string{"coin", "sz", "px"},
+			Headers:   map[string]string{"px": "Price"},
+			Decimal:   ",",
+			Thousands: ".",
+		})
+		if err != nil {
+			t.Fatalf("Expected the template to be saved, got %v", err)
+		}
+
+		reopened, _ := NewExportTemplateStore(path)
+		template, err := reopened.Get("back-office")
+		if err != nil {
+			t.Fatalf("Expected the template to be persisted, got %v", err)
+		}
+		csvFormat, err := TemplateFormat(template)
+		if err != nil {
+			t.Fatalf("Expected a valid layout, got %v", err)
+		}
+
+		body, _ := EncodeTradesAs(FormatCSV, []models.Trade{createTestTrade("2025-01-01T10:00:00Z", "BTC", "B", 50000.5, 0.5)}, csvFormat)
+		lines := strings.Split(string(body), "\n")
+		if lines[0] != "coin;sz;Price" || lines[1] != "BTC;0,5;50.000,5" {
+			t.Errorf("Unexpected export %q", lines[:2])
+		}
+	})
+
+	t.Run("should reject unknown columns, kinds and names", func(t *testing.T) {
+		for _, template := range []models.ExportTemplate{
+			{Name: "typo", Kind: ExportKindTrades, Columns: []string{"price"}},
+			{Name: "twice", Kind: ExportKindPnL, Columns: []string{"date", "date"}},
+			{Name: "positions", Kind: "positions"},
+			{Name: "../escape", Kind: ExportKindPnL},
+		} {
+			if _, err := store.Save(template); !errors.Is(err, ErrInvalidCSVFormat) {
+				t.Errorf("Expected template %s to be rejected, got %v", template.Name, err)
+			}
+		}
+		if len(store.List()) != 1 {
+			t.Errorf("Expected only the valid template to be kept, got %+v", store.List())
+		}
+	})
+
+	t.Run("should list every trade column in export order", func(t *testing.T) {
+		if !reflect.DeepEqual(CSVColumns(ExportKindTrades), tradesCSVHeader) {
+			t.Errorf("Trade columns %v don't match the header %v", CSVColumns(ExportKindTrades), tradesCSVHeader)
+		}
+	})
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrReconciliationNotFound is returned for an unknown external reconciliation ID
+	ErrReconciliationNotFound = errors.New("reconciliation not found")
+	// ErrInvalidResolution is returned for a resolution that doesn't apply to its reconciliation
+	ErrInvalidResolution = errors.New("invalid resolution")
+)
+
+// DefaultMatchTolerance returns the configured matching tolerances
+func DefaultMatchTolerance() models.MatchTolerance {
+	return models.MatchTolerance{
+		TimeWindowMs:   config.MatchTimeWindow.Milliseconds(),
+		PriceEpsilon:   config.MatchPriceEpsilon,
+		AggregateSizes: config.MatchAggregateSizes,
+	}
+}
+
+// ReconcileExternal matches the rows of an external file against the cached
+// trades of address within tolerance. Fills are compared from the first row
+// to the last, widened by the time window.
+func ReconcileExternal(address string, cached, external []models.Trade, tolerance models.MatchTolerance) models.ExternalRecon {
+	var window models.TimeRange
+	for _, row := range external {
+		if window.Start.IsZero() || row.Time.Before(window.Start) {
+			window.Start = row.Time
+		}
+		if row.Time.After(window.End) {
+			window.End = row.Time
+		}
+	}
+	margin := time.Duration(tolerance.TimeWindowMs) * time.Millisecond
+	window.Start, window.End = window.Start.Add(-margin), window.End.Add(margin)
+
+	var internal []models.Trade
+	for _, trade := range cached {
+		if len(external) > 0 && !trade.Time.Before(window.Start) && !trade.Time.After(window.End) {
+			internal = append(internal, trade)
+		}
+	}
+
+	recon := MatchFills(internal, external, tolerance)
+	recon.Address = address
+	recon.Window = window
+	return recon
+}
+
+// ExternalReconStore keeps the most recent external reconciliations of each
+// address and every match resolution, persisted as JSON to path if one is set
+type ExternalReconStore struct {
+	recons      []models.ExternalRecon   // Oldest first
+	resolutions []models.MatchResolution // Oldest first
+	mu          sync.RWMutex
+	path        string
+}
+
+// externalReconFile is the layout of the reconciliations file
+type externalReconFile struct {
+	Reconciliations []models.ExternalRecon   `json:"reconciliations"`
+	Resolutions     []models.MatchResolution `json:"resolutions"`
+}
+
+// NewExternalReconStore creates a store, loading saved reconciliations and resolutions from path if it exists
+func NewExternalReconStore(path string) (*ExternalReconStore, error) {
+	s := &ExternalReconStore{recons: []models.ExternalRecon{}, resolutions: []models.MatchResolution{}, path: path}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := readStoreFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reconciliations: %w", err)
+	}
+	var file externalReconFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse reconciliations: %w", err)
+	}
+	if file.Reconciliations != nil {
+		s.recons = file.Reconciliations
+	}
+	if file.Resolutions != nil {
+		s.resolutions = file.Resolutions
+	}
+	return s, nil
+}
+
+// Record applies the saved resolutions of recon's address that still apply
+// and stores it under a new ID, dropping the oldest reconciliations of its
+// address beyond the history limit
+func (s *ExternalReconStore) Record(recon models.ExternalRecon) models.ExternalRecon {
+	recon.ID = newID()
+	recon.CreatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, resolution := range s.resolutions {
+		if resolution.Address == recon.Address {
+			// Resolutions whose fills or rows are gone or matched are skipped
+			applyResolution(&recon, resolution)
+		}
+	}
+
+	s.recons = append(s.recons, recon)
+
+	excess := -config.ExternalReconHistoryLimit
+	for _, stored := range s.recons {
+		if stored.Address == recon.Address {
+			excess++
+		}
+	}
+	kept := s.recons[:0]
+	for _, stored := range s.recons {
+		if stored.Address == recon.Address && excess > 0 {
+			excess--
+			continue
+		}
+		kept = append(kept, stored)
+	}
+	s.recons = kept
+
+	if err := s.persist(); err != nil {
+		log.Printf("Failed to save reconciliations: %v", err)
+	}
+	return recon
+}
+
+// Get returns the reconciliation with the given ID
+func (s *ExternalReconStore) Get(id string) (models.ExternalRecon, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, recon := range s.recons {
+		if recon.ID == id {
+			return recon, nil
+		}
+	}
+	return models.ExternalRecon{}, ErrReconciliationNotFound
+}
+
+// LastDigest returns the digest of the file of the latest reconciliation of
+// address pulled by source, or "" if there is none
+func (s *ExternalReconStore) LastDigest(address, source string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := len(s.recons) - 1; i >= 0; i-- {
+		if s.recons[i].Address == address && s.recons[i].Source == source {
+			return s.recons[i].Digest
+		}
+	}
+	return ""
+}
+
+// Prune deletes the reconciliations created before cutoff and returns how
+// many there were; a dry run only counts them. Resolutions are kept, since
+// they still apply to later reconciliations.
+func (s *ExternalReconStore) Prune(cutoff time.Time, dryRun bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := make([]models.ExternalRecon, 0, len(s.recons))
+	for _, recon := range s.recons {
+		if !recon.CreatedAt.Before(cutoff) {
+			kept = append(kept, recon)
+		}
+	}
+	pruned := len(s.recons) - len(kept)
+	if dryRun || pruned == 0 {
+		return pruned, nil
+	}
+	s.recons = kept
+	return pruned, s.persist()
+}
+
+// Resolve links the unmatched fills and rows of reconciliation id named in
+// resolution, or marks them explained, and saves the resolution so later
+// reconciliations of the address apply it too
+func (s *ExternalReconStore) Resolve(id string, resolution models.MatchResolution) (models.ExternalRecon, error) {
+	if len(resolution.Internal) == 0 && len(resolution.External) == 0 {
+		return models.ExternalRecon{}, fmt.Errorf("%w: name at least one fill or row", ErrInvalidResolution)
+	}
+	if !resolution.Explained && (len(resolution.Internal) == 0 || len(resolution.External) == 0) {
+		return models.ExternalRecon{}, fmt.Errorf("%w: link at least one fill to one row, or mark them explained", ErrInvalidResolution)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.recons {
+		if s.recons[i].ID != id {
+			continue
+		}
+		resolution.ID = newID()
+		resolution.Address = s.recons[i].Address
+		resolution.ReconID = id
+		resolution.CreatedAt = time.Now()
+		if !applyResolution(&s.recons[i], resolution) {
+			return models.ExternalRecon{}, fmt.Errorf("%w: every fill and row must be unmatched in the reconciliation", ErrInvalidResolution)
+		}
+		s.resolutions = append(s.resolutions, resolution)
+
+		if err := s.persist(); err != nil {
+			log.Printf("Failed to save reconciliations: %v", err)
+		}
+		return s.recons[i], nil
+	}
+	return models.ExternalRecon{}, ErrReconciliationNotFound
+}
+
+// applyResolution moves the fills and rows of resolution from the unmatched
+// ones of recon into a manual or explained match. It reports false, leaving
+// recon unchanged, unless all of them are unmatched.
+func applyResolution(recon *models.ExternalRecon, resolution models.MatchResolution) bool {
+	internal, unmatchedInternal, ok := takeRefs(recon.UnmatchedInternal, resolution.Internal)
+	if !ok {
+		return false
+	}
+	external, unmatchedExternal, ok := takeRefs(recon.UnmatchedExternal, resolution.External)
+	if !ok {
+		return false
+	}
+	recon.UnmatchedInternal, recon.UnmatchedExternal = unmatchedInternal, unmatchedExternal
+
+	match := models.FillMatch{
+		Kind:         models.MatchManual,
+		Internal:     internal,
+		External:     external,
+		Score:        1,
+		Candidates:   1,
+		ResolutionID: resolution.ID,
+		Note:         resolution.Note,
+	}
+	if resolution.Explained {
+		match.Kind = models.MatchExplained
+		recon.Explained++
+	} else {
+		recon.Manual++
+	}
+	recon.Matches = append(recon.Matches, match)
+	sortMatches(recon.Matches)
+	return true
+}
+
+// takeRefs removes the fill of each ref from fills, returning the removed
+// fills and those left, or false if any ref isn't among them
+func takeRefs(fills []models.ReconFill, refs []string) ([]models.ReconFill, []models.ReconFill, bool) {
+	left := append([]models.ReconFill(nil), fills...)
+	taken := []models.ReconFill{}
+	for _, ref := range refs {
+		found := false
+		for i, fill := range left {
+			if fill.Ref == ref {
+				taken = append(taken, fill)
+				left = append(left[:i], left[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, false
+		}
+	}
+	return taken, left, true
+}
+
+// persist writes all reconciliations and resolutions to the file; caller must hold s.mu
+func (s *ExternalReconStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	return writeJSONFile(s.path, externalReconFile{Reconciliations: s.recons, Resolutions: s.resolutions})
+}
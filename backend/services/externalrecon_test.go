@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"path/filepath"
+	"testing"
+)
+
+// Test recording external reconciliations of the fills near the file's rows
+func TestReconcileExternal(t *testing.T) {
+	cached := []models.Trade{
+		createTestTrade("2025-01-01T09:00:00Z", "BTC", "B", 100, 1),
+		createTestTrade("2025-01-01T10:00:00Z", "BTC", "B", 100, 1),
+	}
+	external := []models.Trade{createTestTrade("2025-01-01T10:00:00.3Z", "BTC", "B", 100, 1)}
+
+	recon := ReconcileExternal("0xabc", cached, external, models.MatchTolerance{TimeWindowMs: 500})
+	if recon.InternalFills != 1 || recon.Fuzzy != 1 || len(recon.UnmatchedInternal) != 0 {
+		t.Errorf("Expected only the fill near the row compared and matched, got %+v", recon)
+	}
+
+	store, _ := NewExternalReconStore("")
+	recorded := store.Record(recon)
+	if got, err := store.Get(recorded.ID); err != nil || got.Address != "0xabc" {
+		t.Errorf("Expected the recorded reconciliation, got %+v, %v", got, err)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, ErrReconciliationNotFound) {
+		t.Errorf("Expected ErrReconciliationNotFound, got %v", err)
+	}
+}
+
+// Test linking fills to rows by hand and applying the resolution to later runs
+func TestResolveMatch(t *testing.T) {
+	cached := []models.Trade{
+		createTestTrade("2025-01-01T10:00:02.2Z", "BTC", "B", 100, 1),
+		createTestTrade("2025-01-01T10:00:03Z", "ETH", "A", 10, 1),
+	}
+	external := []models.Trade{
+		createTestTrade("2025-01-01T10:00:02Z", "BTC", "B", 100.5, 1),
+		createTestTrade("2025-01-01T10:00:04Z", "SOL", "A", 5, 1),
+	}
+	tolerance := models.MatchTolerance{TimeWindowMs: 500, PriceEpsilon: 0.0001}
+
+	path := filepath.Join(t.TempDir(), "reconciliations.json")
+	store, _ := NewExternalReconStore(path)
+	recon := store.Record(ReconcileExternal("0xabc", cached, external, tolerance))
+	if len(recon.UnmatchedInternal) != 2 || len(recon.UnmatchedExternal) != 2 {
+		t.Fatalf("Expected nothing matched outside the tolerances, got %+v", recon)
+	}
+	btc, eth := recon.UnmatchedInternal[0].Ref, recon.UnmatchedInternal[1].Ref
+
+	if _, err := store.Resolve(recon.ID, models.MatchResolution{Internal: []string{btc}}); !errors.Is(err, ErrInvalidResolution) {
+		t.Errorf("Expected a link without a row rejected, got %v", err)
+	}
+	if _, err := store.Resolve(recon.ID, models.MatchResolution{Internal: []string{"0:BTC:B:1:1"}, Explained: true}); !errors.Is(err, ErrInvalidResolution) {
+		t.Errorf("Expected an unknown ref rejected, got %v", err)
+	}
+	if _, err := store.Resolve("missing", models.MatchResolution{Internal: []string{btc}, Explained: true}); !errors.Is(err, ErrReconciliationNotFound) {
+		t.Errorf("Expected ErrReconciliationNotFound, got %v", err)
+	}
+
+	resolved, err := store.Resolve(recon.ID, models.MatchResolution{Internal: []string{btc}, External: []string{recon.UnmatchedExternal[0].Ref}, Note: "price improvement"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resolved.Manual != 1 || len(resolved.UnmatchedInternal) != 1 || resolved.Matches[0].Note != "price improvement" {
+		t.Errorf("Expected the BTC fill linked by hand, got %+v", resolved)
+	}
+	if _, err := store.Resolve(recon.ID, models.MatchResolution{Internal: []string{eth}, Explained: true}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// A later run of the same file, after a restart, gets both resolutions
+	reloaded, err := NewExternalReconStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	again := reloaded.Record(ReconcileExternal("0xabc", cached, external, tolerance))
+	if again.Manual != 1 || again.Explained != 1 || len(again.UnmatchedInternal) != 0 || len(again.UnmatchedExternal) != 1 {
+		t.Errorf("Expected the saved resolutions applied, got %+v", again)
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUnknownStakingTier is returned when a fee simulation names a staking tier that doesn't exist
+var ErrUnknownStakingTier = errors.New("unknown staking tier")
+
+// FeeTiers is Hyperliquid's perpetuals fee schedule by 14-day volume, lowest tier first
+var FeeTiers = []models.FeeTier{
+	{Name: "Tier 0", MinVolume14d: 0, TakerRate: 0.00045, MakerRate: 0.00015},
+	{Name: "Tier 1", MinVolume14d: 5e6, TakerRate: 0.00040, MakerRate: 0.00012},
+	{Name: "Tier 2", MinVolume14d: 25e6, TakerRate: 0.00035, MakerRate: 0.00008},
+	{Name: "Tier 3", MinVolume14d: 100e6, TakerRate: 0.00030, MakerRate: 0.00004},
+	{Name: "Tier 4", MinVolume14d: 500e6, TakerRate: 0.00028, MakerRate: 0},
+	{Name: "Tier 5", MinVolume14d: 2e9, TakerRate: 0.00026, MakerRate: 0},
+	{Name: "Tier 6", MinVolume14d: 7e9, TakerRate: 0.00024, MakerRate: 0},
+}
+
+// StakingTiers are the fee discounts for staking HYPE
+var StakingTiers = []models.StakingTier{
+	{Name: "none", MinStaked: 0, Discount: 0},
+	{Name: "wood", MinStaked: 10, Discount: 0.05},
+	{Name: "bronze", MinStaked: 100, Discount: 0.10},
+	{Name: "silver", MinStaked: 1000, Discount: 0.15},
+	{Name: "gold", MinStaked: 10000, Discount: 0.20},
+	{Name: "platinum", MinStaked: 100000, Discount: 0.30},
+	{Name: "diamond", MinStaked: 500000, Discount: 0.40},
+}
+
+// makerFeeRateLimit is the highest fee, as a fraction of fill value, a maker
+// fill is charged. Fills don't record which side added liquidity, so fills
+// charged at most this much (including rebates) are treated as maker fills.
+const makerFeeRateLimit = 0.00015
+
+// volumeBasedTier names the scenario whose tier follows the address's own 14-day volume
+const volumeBasedTier = "By 14-day volume"
+
+// SimulateFees recomputes the fees paid on trades under every fee tier with
+// the named staking discount, and under the tier the address's own 14-day
+// volume would have earned on each day
+func SimulateFees(address string, trades []models.Trade, staking string) (models.FeeSimulation, error) {
+	discount, err := stakingDiscount(staking)
+	if err != nil {
+		return models.FeeSimulation{}, err
+	}
+
+	simulation := models.FeeSimulation{Address: address, Fills: len(trades), Staking: strings.ToLower(staking), Scenarios: []models.FeeScenario{}}
+	if simulation.Staking == "" {
+		simulation.Staking = StakingTiers[0].Name
+	}
+	if len(trades) == 0 {
+		return simulation, nil
+	}
+	start, end := trades[0].Time, trades[len(trades)-1].Time
+	simulation.Start, simulation.End = &start, &end
+
+	makers := make([]bool, len(trades))
+	for i, trade := range trades {
+		simulation.Volume = (simulation.Volume + trade.Value).Round()
+		simulation.ActualFees = (simulation.ActualFees + trade.Fee).Round()
+		// Allow for rounding in the reported fee
+		makers[i] = trade.Fee <= trade.Value*makerFeeRateLimit*(1+1e-6)
+		if makers[i] {
+			simulation.MakerVolume = (simulation.MakerVolume + trade.Value).Round()
+		} else {
+			simulation.TakerVolume = (simulation.TakerVolume + trade.Value).Round()
+		}
+	}
+
+	for _, tier := range FeeTiers {
+		taker, maker := tier.TakerRate*(1-discount), tier.MakerRate*(1-discount)
+		scenario := models.FeeScenario{
+			Tier:         tier.Name,
+			MinVolume14d: tier.MinVolume14d,
+			TakerRate:    taker,
+			MakerRate:    maker,
+			Fees:         models.Money(float64(simulation.TakerVolume)*taker + float64(simulation.MakerVolume)*maker).Round(),
+		}
+		simulation.Scenarios = append(simulation.Scenarios, withSavings(scenario, simulation.ActualFees))
+	}
+
+	earned := models.FeeScenario{Tier: volumeBasedTier}
+	var earnedFees float64
+	volumes := trailingVolumes(trades)
+	for i, trade := range trades {
+		tier := tierForVolume(volumes[trade.Time.UTC().Format("2006-01-02")])
+		rate := tier.TakerRate
+		if makers[i] {
+			rate = tier.MakerRate
+		}
+		earnedFees += float64(trade.Value) * rate * (1 - discount)
+	}
+	earned.Fees = models.Money(earnedFees).Round()
+	simulation.Scenarios = append(simulation.Scenarios, withSavings(earned, simulation.ActualFees))
+
+	return simulation, nil
+}
+
+// stakingDiscount returns the fee discount of the named staking tier; "" means none
+func stakingDiscount(name string) (float64, error) {
+	if name == "" {
+		return 0, nil
+	}
+	for _, tier := range StakingTiers {
+		if strings.EqualFold(tier.Name, name) {
+			return tier.Discount, nil
+		}
+	}
+	return 0, fmt.Errorf("%w %q", ErrUnknownStakingTier, name)
+}
+
+// withSavings fills in how much scenario saves compared with actualFees
+func withSavings(scenario models.FeeScenario, actualFees models.Money) models.FeeScenario {
+	scenario.Savings = (actualFees - scenario.Fees).Round()
+	if actualFees != 0 {
+		scenario.SavingsPct = float64(scenario.Savings) / math.Abs(float64(actualFees)) * 100
+	}
+	return scenario
+}
+
+// trailingVolumes returns, for each UTC day with fills, the volume traded in
+// the 14 days before it, which sets that day's fee tier
+func trailingVolumes(trades []models.Trade) map[string]models.Money {
+	daily := make(map[string]models.Money)
+	for _, trade := range trades {
+		day := trade.Time.UTC().Format("2006-01-02")
+		daily[day] = (daily[day] + trade.Value).Round()
+	}
+
+	days := make([]string, 0, len(daily))
+	for day := range daily {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	trailing := make(map[string]models.Money, len(days))
+	for i, day := range days {
+		date, _ := time.Parse("2006-01-02", day)
+		windowStart := date.AddDate(0, 0, -14).Format("2006-01-02")
+		for j := i - 1; j >= 0 && days[j] >= windowStart; j-- {
+			trailing[day] = (trailing[day] + daily[days[j]]).Round()
+		}
+	}
+	return trailing
+}
+
+// tierForVolume returns the highest tier whose volume requirement volume meets
+func tierForVolume(volume models.Money) models.FeeTier {
+	tier := FeeTiers[0]
+	for _, candidate := range FeeTiers {
+		if volume > candidate.MinVolume14d {
+			tier = candidate
+		}
+	}
+	return tier
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"testing"
+	"time"
+)
+
+// Test fee tier simulation
+func TestSimulateFees(t *testing.T) {
+	day := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	trades := []models.Trade{
+		// Taker fill at the Tier 0 rate
+		{Time: day, Coin: "BTC", Side: "B", Price: 100000, Size: 1, Value: 100000, Fee: 45},
+		// Maker fill at the Tier 0 rate
+		{Time: day.Add(time.Hour), Coin: "BTC", Side: "A", Price: 100000, Size: 1, Value: 100000, Fee: 15},
+	}
+
+	t.Run("should price fills under every tier", func(t *testing.T) {
+		simulation, err := SimulateFees("0xabc", trades, "")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if simulation.MakerVolume != 100000 || simulation.TakerVolume != 100000 {
+			t.Errorf("Expected 100000 maker and taker volume, got %v and %v", simulation.MakerVolume, simulation.TakerVolume)
+		}
+		if simulation.ActualFees != 60 {
+			t.Errorf("Expected actual fees 60, got %v", simulation.ActualFees)
+		}
+		if len(simulation.Scenarios) != len(FeeTiers)+1 {
+			t.Fatalf("Expected %d scenarios, got %d", len(FeeTiers)+1, len(simulation.Scenarios))
+		}
+
+		tier0, tier3 := simulation.Scenarios[0], simulation.Scenarios[3]
+		if tier0.Savings != 0 {
+			t.Errorf("Expected no savings at the tier actually paid, got %v", tier0.Savings)
+		}
+		if tier3.Fees != 34 || tier3.Savings != 26 {
+			t.Errorf("Expected Tier 3 fees 34 saving 26, got %v saving %v", tier3.Fees, tier3.Savings)
+		}
+	})
+
+	t.Run("should apply the staking discount", func(t *testing.T) {
+		simulation, err := SimulateFees("0xabc", trades, "Diamond")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if fees := simulation.Scenarios[0].Fees; fees != 36 {
+			t.Errorf("Expected discounted Tier 0 fees 36, got %v", fees)
+		}
+	})
+
+	t.Run("should reject unknown staking tiers", func(t *testing.T) {
+		if _, err := SimulateFees("0xabc", trades, "mithril"); !errors.Is(err, ErrUnknownStakingTier) {
+			t.Errorf("Expected ErrUnknownStakingTier, got %v", err)
+		}
+	})
+
+	t.Run("should earn tiers from trailing volume", func(t *testing.T) {
+		heavy := []models.Trade{
+			{Time: day, Coin: "BTC", Side: "B", Price: 100000, Size: 300, Value: 30e6, Fee: 13500},
+			{Time: day.AddDate(0, 0, 1), Coin: "BTC", Side: "A", Price: 100000, Size: 1, Value: 100000, Fee: 45},
+		}
+		simulation, err := SimulateFees("0xabc", heavy, "")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		// The second day's fill is charged at Tier 2 after $30M the day before
+		earned := simulation.Scenarios[len(simulation.Scenarios)-1]
+		if expected := models.Money(30e6*0.00045 + 100000*0.00035).Round(); earned.Fees != expected {
+			t.Errorf("Expected volume-based fees %v, got %v", expected, earned.Fees)
+		}
+	})
+}
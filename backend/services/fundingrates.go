@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fundingRates is the funding rate store in effect, shared by all tenants
+var fundingRates atomic.Pointer[FundingRateStore]
+
+func init() {
+	// Without a file loading can't fail
+	store, _ := NewFundingRateStore("")
+	fundingRates.Store(store)
+}
+
+// UseFundingRates replaces the funding rate store in effect
+func UseFundingRates(store *FundingRateStore) {
+	fundingRates.Store(store)
+}
+
+// CurrentFundingRates returns the funding rate store in effect
+func CurrentFundingRates() *FundingRateStore {
+	return fundingRates.Load()
+}
+
+// fundingHistoryRequest asks for a coin's hourly funding rates
+type fundingHistoryRequest struct {
+	Type      string `json:"type"`
+	Coin      string `json:"coin"`
+	StartTime int64  `json:"startTime"`
+	EndTime   int64  `json:"endTime"`
+}
+
+// fundingHistoryResponse is one hourly funding rate from the API
+type fundingHistoryResponse struct {
+	Coin        string `json:"coin"`
+	FundingRate string `json:"fundingRate"`
+	Time        int64  `json:"time"`
+}
+
+// FetchFundingHistory fetches the hourly funding rates of coin between start
+// and end, oldest first
+func (c *HyperliquidClient) FetchFundingHistory(ctx context.Context, coin string, start, end time.Time) (samples []models.FundingSample, err error) {
+	ctx, span := tracer.Start(ctx, "HyperliquidClient.FetchFundingHistory", trace.WithAttributes(attribute.String("coin", coin)))
+	defer func() { endSpan(span, err) }()
+
+	startTime, endTime := start.UnixMilli(), end.UnixMilli()
+	for batch := 1; ; batch++ {
+		if batch > 1 {
+			upstreamPacer.pause(ctx)
+		}
+
+		var page []fundingHistoryResponse
+		request := fundingHistoryRequest{Type: "fundingHistory", Coin: coin, StartTime: startTime, EndTime: endTime}
+		if err := c.postInfo(ctx, request, &page); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s funding history batch %d: %w", coin, batch, err)
+		}
+		for _, item := range page {
+			rate, err := strconv.ParseFloat(item.FundingRate, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse funding rate '%s': %w", item.FundingRate, err)
+			}
+			samples = append(samples, models.FundingSample{Time: time.UnixMilli(item.Time).UTC(), Rate: rate})
+		}
+		upstreamUsage.record(0, len(page)/config.UserFillsItemsPerWeight)
+
+		if len(page) < ledgerUpdatesBatchSize {
+			return samples, nil
+		}
+		startTime = page[len(page)-1].Time + 1
+	}
+}
+
+// FundingRateStore keeps the hourly funding rates of each coin per UTC day,
+// fetched when first asked for. Only complete days are stored, persisted as
+// JSON to path if one is set.
+type FundingRateStore struct {
+	days     map[string]map[string]*models.FundingRateDay // key: coin, date
+	mu       sync.Mutex                                   // Serializes fetches and guards days
+	path     string
+	hlClient *HyperliquidClient
+}
+
+// NewFundingRateStore creates a store, loading stored days from path if it exists
+func NewFundingRateStore(path string) (*FundingRateStore, error) {
+	s := &FundingRateStore{
+		days:     make(map[string]map[string]*models.FundingRateDay),
+		path:     path,
+		hlClient: NewHyperliquidClient(),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := readStoreFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read funding rates: %w", err)
+	}
+
+	var days []models.FundingRateDay
+	if err := json.Unmarshal(data, &days); err != nil {
+		return nil, fmt.Errorf("failed to parse funding rates: %w", err)
+	}
+	for i := range days {
+		s.store(&days[i])
+	}
+
+	return s, nil
+}
+
+// Days returns the funding rates of coin on each UTC date in dates, fetching
+// the dates not stored yet in one range. Dates without any rate, such as
+// future ones, are left out.
+func (s *FundingRateStore) Days(ctx context.Context, coin string, dates []string) (map[string]models.FundingRateDay, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	days := make(map[string]models.FundingRateDay, len(dates))
+	wanted := make(map[string]bool, len(dates))
+	var first, last time.Time
+	for _, date := range dates {
+		wanted[date] = true
+		if day, exists := s.days[coin][date]; exists {
+			days[date] = *day
+			continue
+		}
+		start, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", date)
+		}
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+	}
+	if first.IsZero() {
+		return days, nil
+	}
+
+	samples, err := s.hlClient.FetchFundingHistory(ctx, coin, first, last.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]*models.FundingRateDay)
+	for _, sample := range samples {
+		date := sample.Time.Format("2006-01-02")
+		day, exists := byDate[date]
+		if !exists {
+			day = &models.FundingRateDay{Coin: coin, Date: date}
+			byDate[date] = day
+		}
+		day.Samples = append(day.Samples, sample)
+	}
+
+	today := time.Now().UTC().Format("2006-01-02")
+	stored := false
+	for date, day := range byDate {
+		for _, sample := range day.Samples {
+			day.AvgRate += sample.Rate
+		}
+		day.AvgRate /= float64(len(day.Samples))
+		if wanted[date] {
+			days[date] = *day
+		}
+		if date < today {
+			s.store(day)
+			stored = true
+		}
+	}
+	if stored {
+		if err := s.persist(); err != nil {
+			log.Printf("Error saving funding rates: %v", err)
+		}
+	}
+	return days, nil
+}
+
+// store adds day to the stored days. Caller must hold s.mu or own s.
+func (s *FundingRateStore) store(day *models.FundingRateDay) {
+	if s.days[day.Coin] == nil {
+		s.days[day.Coin] = make(map[string]*models.FundingRateDay)
+	}
+	s.days[day.Coin][day.Date] = day
+}
+
+// persist writes every stored day to the funding rates file. Caller must hold s.mu.
+func (s *FundingRateStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	var days []models.FundingRateDay
+	for _, byDate := range s.days {
+		for _, day := range byDate {
+			days = append(days, *day)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool {
+		if days[i].Coin != days[j].Coin {
+			return days[i].Coin < days[j].Coin
+		}
+		return days[i].Date < days[j].Date
+	})
+
+	return writeJSONFile(s.path, days)
+}
+
+// hasFunding reports whether coin is a perpetual, which pays funding, rather
+// than a spot pair such as "PURR/USDC" or "@107"
+func hasFunding(coin string) bool {
+	return !strings.Contains(coin, "/") && !strings.HasPrefix(coin, "@")
+}
+
+// BuildFundingContext sets the funding of address against the market's for
+// every UTC day and coin with a fill or funding payment in entries. Rates
+// are fetched with store; a coin whose rates can't be fetched is logged and
+// reported without them.
+func BuildFundingContext(ctx context.Context, store *FundingRateStore, address string, entries []models.JournalEntry) models.FundingContext {
+	result := models.FundingContext{Address: address, Days: []models.FundingDay{}}
+
+	type key struct{ date, coin string }
+	byDay := make(map[key]*models.FundingDay)
+	paidAt := make(map[key][]time.Time)
+	datesByCoin := make(map[string][]string)
+	for _, entry := range entries {
+		if (entry.Kind != EntryFill && entry.Kind != EntryFunding) || entry.Coin == "" {
+			continue
+		}
+		// Fills are posted under the names trades are grouped by, funding
+		// under the exchange's symbols
+		coin := entry.Coin
+		if entry.Kind == EntryFunding {
+			coin = canonicalCoin(coin, entry.Time)
+		}
+		k := key{entry.Time.UTC().Format("2006-01-02"), coin}
+		day, exists := byDay[k]
+		if !exists {
+			day = &models.FundingDay{Date: k.date, Coin: k.coin}
+			byDay[k] = day
+			datesByCoin[k.coin] = append(datesByCoin[k.coin], k.date)
+		}
+		if entry.Kind != EntryFunding {
+			continue
+		}
+		for _, posting := range entry.Postings {
+			if posting.Account == AccountFunding {
+				day.Funding -= float64(posting.Amount)
+				result.Funding -= float64(posting.Amount)
+			}
+		}
+		day.Payments++
+		paidAt[k] = append(paidAt[k], entry.Time)
+	}
+
+	for coin, dates := range datesByCoin {
+		if !hasFunding(coin) {
+			continue
+		}
+		rates, err := store.Days(ctx, exchangeCoin(coin, dates[0]), dates)
+		if err != nil {
+			log.Printf("Error fetching funding rates of %s: %v", coin, err)
+			continue
+		}
+		for _, date := range dates {
+			rate, exists := rates[date]
+			if !exists {
+				continue
+			}
+			k := key{date, coin}
+			day := byDay[k]
+			avg := rate.AvgRate
+			day.AvgRate = &avg
+			if held, ok := heldRate(rate.Samples, paidAt[k]); ok {
+				day.HeldRate = &held
+			}
+		}
+	}
+
+	for _, day := range byDay {
+		result.Days = append(result.Days, *day)
+	}
+	sort.Slice(result.Days, func(i, j int) bool {
+		if result.Days[i].Date != result.Days[j].Date {
+			return result.Days[i].Date < result.Days[j].Date
+		}
+		return result.Days[i].Coin < result.Days[j].Coin
+	})
+	return result
+}
+
+// heldRate averages the samples of the hours payments were made in
+func heldRate(samples []models.FundingSample, payments []time.Time) (float64, bool) {
+	byHour := make(map[int64]float64, len(samples))
+	for _, sample := range samples {
+		byHour[sample.Time.Truncate(time.Hour).Unix()] = sample.Rate
+	}
+
+	var sum float64
+	var n int
+	for _, paid := range payments {
+		if rate, exists := byHour[paid.Truncate(time.Hour).Unix()]; exists {
+			sum += rate
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// canonicalCoin returns the name trades of the exchange's symbol coin are
+// grouped by at t
+func canonicalCoin(coin string, t time.Time) string {
+	trades := []models.Trade{{Coin: coin, Time: t}}
+	canonicalize(trades)
+	return trades[0].Coin
+}
+
+// exchangeCoin returns the symbol Hyperliquid used for coin, a name trades
+// are grouped by, on date (YYYY-MM-DD)
+func exchangeCoin(coin, date string) string {
+	at, _ := time.Parse("2006-01-02", date)
+	trades := []models.Trade{{Coin: coin, Time: at}}
+	toExchange(trades)
+	return trades[0].Coin
+}
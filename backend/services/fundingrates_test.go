@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hyperliquid-recon/models"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test setting an address's funding against the market's funding rates
+func TestFundingContext(t *testing.T) {
+	day := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req fundingHistoryRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests++
+
+		// ETH pays 0.0001 an hour in the morning and 0.0003 in the afternoon
+		var items []string
+		for hour := time.UnixMilli(req.StartTime).UTC(); hour.UnixMilli() < req.EndTime; hour = hour.Add(time.Hour) {
+			rate := "0.0001"
+			if hour.Hour() >= 12 {
+				rate = "0.0003"
+			}
+			items = append(items, fmt.Sprintf(`{"coin": %q, "fundingRate": %q, "premium": "0", "time": %d}`, req.Coin, rate, hour.UnixMilli()))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(items, ","))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "funding-rates.json")
+	store, _ := NewFundingRateStore(path)
+	store.hlClient.apiURL = server.URL
+
+	funding := func(at time.Time, amount models.Money) models.JournalEntry {
+		return models.JournalEntry{Time: at, Kind: EntryFunding, Coin: "ETH", Postings: []models.Posting{
+			{Account: AccountCash, Amount: amount}, {Account: AccountFunding, Amount: -amount},
+		}}
+	}
+	entries := []models.JournalEntry{
+		{Time: day.Add(11 * time.Hour), Kind: EntryFill, Coin: "ETH"},
+		funding(day.Add(12*time.Hour), -3),
+		funding(day.Add(13*time.Hour), -3),
+		{Time: day.Add(30 * time.Hour), Kind: EntryFill, Coin: "PURR/USDC"},
+	}
+
+	result := BuildFundingContext(context.Background(), store, testAddress, entries)
+	if len(result.Days) != 2 || result.Funding != -6 {
+		t.Fatalf("Expected an ETH day and a spot day, got %+v", result)
+	}
+	eth := result.Days[0]
+	if eth.Coin != "ETH" || eth.Payments != 2 || eth.Funding != -6 || eth.AvgRate == nil || eth.HeldRate == nil {
+		t.Fatalf("Unexpected ETH day %+v", eth)
+	}
+	if math.Abs(*eth.AvgRate-0.0002) > 1e-12 || math.Abs(*eth.HeldRate-0.0003) > 1e-12 {
+		t.Errorf("Expected average 0.0002 and held 0.0003, got %v and %v", *eth.AvgRate, *eth.HeldRate)
+	}
+	if spot := result.Days[1]; spot.AvgRate != nil || spot.Coin != "PURR/USDC" {
+		t.Errorf("Expected no funding rate for a spot pair, got %+v", spot)
+	}
+
+	// Complete days are stored and not fetched again
+	reloaded, _ := NewFundingRateStore(path)
+	reloaded.hlClient.apiURL = server.URL
+	BuildFundingContext(context.Background(), reloaded, testAddress, entries)
+	if requests != 1 {
+		t.Errorf("Expected one fetch, got %d", requests)
+	}
+}
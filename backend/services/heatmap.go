@@ -0,0 +1,60 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// heatmapLevels is the number of intensity levels on each side of zero
+const heatmapLevels = 4
+
+// BuildPnLHeatmap buckets the daily records of year into intensity levels.
+// Levels are quantiles of the year's absolute daily P&L, so a single outlier
+// day doesn't wash out the rest of the calendar.
+func BuildPnLHeatmap(address string, records []models.DailyPnL, year int) models.PnLHeatmap {
+	heatmap := models.PnLHeatmap{
+		Address:    address,
+		Year:       year,
+		Days:       make(map[string]models.HeatmapDay),
+		Thresholds: []float64{},
+		MaxLevel:   heatmapLevels,
+	}
+
+	prefix := strconv.Itoa(year) + "-"
+	var magnitudes []float64
+	for _, record := range records {
+		if !strings.HasPrefix(record.Date, prefix) {
+			continue
+		}
+		heatmap.Days[record.Date] = models.HeatmapDay{PnL: float64(record.DailyPnL), TradeCount: record.TradeCount}
+		if record.DailyPnL != 0 {
+			magnitudes = append(magnitudes, math.Abs(float64(record.DailyPnL)))
+		}
+	}
+	if len(magnitudes) == 0 {
+		return heatmap
+	}
+
+	sort.Float64s(magnitudes)
+	for level := 1; level < heatmapLevels; level++ {
+		index := int(math.Ceil(float64(level*len(magnitudes))/heatmapLevels)) - 1
+		heatmap.Thresholds = append(heatmap.Thresholds, magnitudes[max(index, 0)])
+	}
+
+	for date, day := range heatmap.Days {
+		if day.PnL == 0 {
+			continue
+		}
+		level := sort.SearchFloat64s(heatmap.Thresholds, math.Abs(day.PnL)) + 1
+		if day.PnL < 0 {
+			level = -level
+		}
+		day.Level = level
+		heatmap.Days[date] = day
+	}
+
+	return heatmap
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"testing"
+)
+
+// Test P&L heatmap levels
+func TestBuildPnLHeatmap(t *testing.T) {
+	records := []models.DailyPnL{
+		{Date: "2025-03-04", TradeCount: 1, DailyPnL: 10},
+		{Date: "2025-03-03", TradeCount: 1, DailyPnL: -20},
+		{Date: "2025-03-02", TradeCount: 1, DailyPnL: 30},
+		{Date: "2025-03-01", TradeCount: 1, DailyPnL: 5000},
+		{Date: "2024-12-31", TradeCount: 1, DailyPnL: 99},
+	}
+
+	heatmap := BuildPnLHeatmap("0xabc", records, 2025)
+
+	if len(heatmap.Days) != 4 {
+		t.Fatalf("Expected 4 days in 2025, got %d", len(heatmap.Days))
+	}
+	expected := map[string]int{"2025-03-04": 1, "2025-03-03": -2, "2025-03-02": 3, "2025-03-01": 4}
+	for date, level := range expected {
+		if got := heatmap.Days[date].Level; got != level {
+			t.Errorf("Expected level %d on %s, got %d", level, date, got)
+		}
+	}
+	if len(heatmap.Thresholds) != heatmap.MaxLevel-1 {
+		t.Errorf("Expected %d thresholds, got %v", heatmap.MaxLevel-1, heatmap.Thresholds)
+	}
+}
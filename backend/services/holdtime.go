@@ -0,0 +1,94 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"sort"
+	"time"
+)
+
+// holdTimeBuckets are the hold-time ranges P&L is bucketed into; a round trip
+// falls in the first bucket whose limit its duration is below
+var holdTimeBuckets = []struct {
+	name  string
+	limit time.Duration
+}{
+	{"<1h", time.Hour},
+	{"1-24h", 24 * time.Hour},
+	{">1d", 1<<63 - 1},
+}
+
+// AnalyzeHoldTimes computes holding durations and P&L by hold time from
+// round trips, for each coin and overall
+func AnalyzeHoldTimes(report models.RoundTripReport) models.HoldTimeAnalysis {
+	analysis := models.HoldTimeAnalysis{
+		Address: report.Address,
+		Label:   report.Label,
+		Method:  report.Method,
+		Overall: holdTimeStats("", report.RoundTrips),
+		Coins:   []models.HoldTimeStats{},
+	}
+
+	byCoin := make(map[string][]models.RoundTrip)
+	for _, roundTrip := range report.RoundTrips {
+		byCoin[roundTrip.Coin] = append(byCoin[roundTrip.Coin], roundTrip)
+	}
+	coins := make([]string, 0, len(byCoin))
+	for coin := range byCoin {
+		coins = append(coins, coin)
+	}
+	sort.Strings(coins)
+
+	for _, coin := range coins {
+		analysis.Coins = append(analysis.Coins, holdTimeStats(coin, byCoin[coin]))
+	}
+	return analysis
+}
+
+// holdTimeStats summarizes the durations and bucketed P&L of roundTrips
+func holdTimeStats(coin string, roundTrips []models.RoundTrip) models.HoldTimeStats {
+	stats := models.HoldTimeStats{Coin: coin, RoundTrips: len(roundTrips), Buckets: make([]models.HoldTimeBucket, len(holdTimeBuckets))}
+	for i, bucket := range holdTimeBuckets {
+		stats.Buckets[i].Name = bucket.name
+	}
+	if len(roundTrips) == 0 {
+		stats.MeanHold, stats.MedianHold = time.Duration(0).String(), time.Duration(0).String()
+		return stats
+	}
+
+	durations := make([]time.Duration, len(roundTrips))
+	var total time.Duration
+	for i, roundTrip := range roundTrips {
+		duration := roundTrip.ExitTime.Sub(roundTrip.EntryTime)
+		durations[i] = duration
+		total += duration
+
+		index := 0
+		for duration >= holdTimeBuckets[index].limit {
+			index++
+		}
+		bucket := &stats.Buckets[index]
+		bucket.RoundTrips++
+		bucket.NetPnL += float64(roundTrip.NetPnL)
+		if roundTrip.NetPnL > 0 {
+			bucket.Wins++
+		}
+	}
+
+	for i := range stats.Buckets {
+		if bucket := &stats.Buckets[i]; bucket.RoundTrips > 0 {
+			bucket.WinRate = float64(bucket.Wins) / float64(bucket.RoundTrips)
+			bucket.AvgNetPnL = bucket.NetPnL / float64(bucket.RoundTrips)
+		}
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	median := durations[len(durations)/2]
+	if len(durations)%2 == 0 {
+		median = (durations[len(durations)/2-1] + durations[len(durations)/2]) / 2
+	}
+	mean := total / time.Duration(len(durations))
+
+	stats.MeanHold, stats.MeanHoldMs = mean.String(), mean.Milliseconds()
+	stats.MedianHold, stats.MedianHoldMs = median.String(), median.Milliseconds()
+	return stats
+}
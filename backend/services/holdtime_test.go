@@ -0,0 +1,65 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"testing"
+	"time"
+)
+
+// Test hold-time analysis
+func TestAnalyzeHoldTimes(t *testing.T) {
+	base := time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC)
+	roundTrip := func(coin string, hold time.Duration, netPnL models.Money) models.RoundTrip {
+		return models.RoundTrip{Coin: coin, EntryTime: base, ExitTime: base.Add(hold), NetPnL: netPnL}
+	}
+	report := models.RoundTripReport{
+		Address: "0xabc",
+		Method:  LotsFIFO,
+		RoundTrips: []models.RoundTrip{
+			roundTrip("ETH", 10*time.Minute, 5),
+			roundTrip("ETH", 30*time.Minute, -3),
+			roundTrip("BTC", 2*time.Hour, 10),
+			roundTrip("BTC", 48*time.Hour, -20),
+		},
+	}
+
+	analysis := AnalyzeHoldTimes(report)
+
+	t.Run("should bucket P&L by hold time", func(t *testing.T) {
+		buckets := analysis.Overall.Buckets
+		if len(buckets) != 3 || buckets[0].Name != "<1h" || buckets[2].Name != ">1d" {
+			t.Fatalf("Unexpected buckets %+v", buckets)
+		}
+		if buckets[0].RoundTrips != 2 || buckets[0].NetPnL != 2 || buckets[0].WinRate != 0.5 || buckets[0].AvgNetPnL != 1 {
+			t.Errorf("Unexpected <1h bucket %+v", buckets[0])
+		}
+		if buckets[1].RoundTrips != 1 || buckets[1].Wins != 1 || buckets[2].NetPnL != -20 {
+			t.Errorf("Unexpected longer buckets %+v", buckets[1:])
+		}
+	})
+
+	t.Run("should compute mean and median holds", func(t *testing.T) {
+		if analysis.Overall.MedianHoldMs != (75 * time.Minute).Milliseconds() {
+			t.Errorf("Expected a 1h15m median, got %s", analysis.Overall.MedianHold)
+		}
+		if analysis.Overall.MeanHoldMs != (50*time.Hour+40*time.Minute).Milliseconds()/4 {
+			t.Errorf("Unexpected mean %s", analysis.Overall.MeanHold)
+		}
+	})
+
+	t.Run("should break down by coin", func(t *testing.T) {
+		if len(analysis.Coins) != 2 || analysis.Coins[0].Coin != "BTC" || analysis.Coins[1].Coin != "ETH" {
+			t.Fatalf("Expected BTC and ETH, got %+v", analysis.Coins)
+		}
+		if analysis.Coins[1].MeanHoldMs != (20 * time.Minute).Milliseconds() {
+			t.Errorf("Expected a 20m mean for ETH, got %s", analysis.Coins[1].MeanHold)
+		}
+	})
+
+	t.Run("should handle no round trips", func(t *testing.T) {
+		empty := AnalyzeHoldTimes(models.RoundTripReport{Address: "0xabc"})
+		if empty.Overall.RoundTrips != 0 || len(empty.Coins) != 0 || len(empty.Overall.Buckets) != 3 {
+			t.Errorf("Unexpected empty analysis %+v", empty)
+		}
+	})
+}
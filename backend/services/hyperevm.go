@@ -0,0 +1,341 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// transferTopic is the topic of the ERC-20 Transfer(address,address,uint256) event
+const transferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+// selectorDecimals is the function selector of ERC-20 decimals()
+const selectorDecimals = "313ce567"
+
+// hypeSystemAddress receives and sends HYPE moving between HyperCore and
+// HyperEVM; token system addresses are 0x20 followed by the token index
+const hypeSystemAddress = "0x2222222222222222222222222222222222222222"
+
+// EVMToken is an ERC-20 token on HyperEVM
+type EVMToken struct {
+	Symbol   string
+	Contract string // Lowercase
+}
+
+// EVMTransfer is one token transfer to or from an address on HyperEVM
+type EVMTransfer struct {
+	Hash     string
+	LogIndex int64
+	Block    int64
+	Time     time.Time
+	Token    string // Symbol
+	From     string // Lowercase
+	To       string // Lowercase
+	Amount   float64
+}
+
+// Bridged reports whether the transfer moved tokens between HyperEVM and
+// HyperCore, which happens through the system addresses
+func (t EVMTransfer) Bridged() bool {
+	return isSystemAddress(t.From) || isSystemAddress(t.To)
+}
+
+// HyperEVMClient fetches token transfers from a HyperEVM JSON-RPC endpoint
+type HyperEVMClient struct {
+	httpClient *http.Client
+	rpcURL     string
+	tokens     []EVMToken
+	logRange   int64
+	decimals   map[string]int // key: contract
+	mu         sync.Mutex     // Guards decimals
+}
+
+func NewHyperEVMClient(rpcURL string, tokens []EVMToken, logRange int64, timeout time.Duration) *HyperEVMClient {
+	return &HyperEVMClient{
+		httpClient: &http.Client{Timeout: timeout},
+		rpcURL:     rpcURL,
+		tokens:     tokens,
+		logRange:   max(logRange, 1),
+		decimals:   make(map[string]int),
+	}
+}
+
+// ParseEVMTokens parses a comma-separated list of SYMBOL:contract tokens
+func ParseEVMTokens(list string) ([]EVMToken, error) {
+	var tokens []EVMToken
+	for _, item := range strings.Split(list, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		symbol, contract, ok := strings.Cut(item, ":")
+		symbol, contract = strings.TrimSpace(symbol), strings.ToLower(strings.TrimSpace(contract))
+		if !ok || symbol == "" || !addressPattern.MatchString(contract) {
+			return nil, fmt.Errorf("invalid token %q: want SYMBOL:0x<contract address>", strings.TrimSpace(item))
+		}
+		tokens = append(tokens, EVMToken{Symbol: symbol, Contract: contract})
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no tokens configured")
+	}
+	return tokens, nil
+}
+
+// FetchTransfers returns the transfers of the configured tokens to and from
+// address in blocks produced between start and end, oldest first
+func (c *HyperEVMClient) FetchTransfers(ctx context.Context, address string, start, end time.Time) ([]EVMTransfer, error) {
+	latest, err := c.blockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	blockTimes := make(map[int64]time.Time)
+	first, err := c.firstBlockAfter(ctx, start, latest, blockTimes)
+	if err != nil {
+		return nil, err
+	}
+	last, err := c.firstBlockAfter(ctx, end, latest, blockTimes)
+	if err != nil {
+		return nil, err
+	}
+	last-- // The last block before end
+
+	contracts := make([]string, len(c.tokens))
+	symbols := make(map[string]string, len(c.tokens))
+	for i, token := range c.tokens {
+		contracts[i] = token.Contract
+		symbols[token.Contract] = token.Symbol
+	}
+	topic := "0x" + strings.Repeat("0", 24) + strings.TrimPrefix(strings.ToLower(address), "0x")
+
+	var transfers []EVMTransfer
+	for from := first; from <= last; from += c.logRange {
+		to := min(from+c.logRange-1, last)
+		// Transfers out have address as the first indexed argument, transfers in as the second
+		for _, topics := range [][]interface{}{{transferTopic, topic}, {transferTopic, nil, topic}} {
+			logs, err := c.getLogs(ctx, contracts, topics, from, to)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get transfers in blocks %d to %d: %w", from, to, err)
+			}
+			for _, entry := range logs {
+				transfer, err := c.decodeTransfer(ctx, entry, symbols, blockTimes)
+				if err != nil {
+					return nil, err
+				}
+				transfers = append(transfers, transfer)
+			}
+		}
+	}
+
+	sort.Slice(transfers, func(i, j int) bool {
+		if transfers[i].Block != transfers[j].Block {
+			return transfers[i].Block < transfers[j].Block
+		}
+		return transfers[i].LogIndex < transfers[j].LogIndex
+	})
+	return transfers, nil
+}
+
+// evmLog is a log entry returned by eth_getLogs
+type evmLog struct {
+	Address         string   `json:"address"`
+	Topics          []string `json:"topics"`
+	Data            string   `json:"data"`
+	BlockNumber     string   `json:"blockNumber"`
+	TransactionHash string   `json:"transactionHash"`
+	LogIndex        string   `json:"logIndex"`
+}
+
+// decodeTransfer turns a Transfer log into a transfer, looking up its block's
+// time and its token's decimals
+func (c *HyperEVMClient) decodeTransfer(ctx context.Context, entry evmLog, symbols map[string]string, blockTimes map[int64]time.Time) (EVMTransfer, error) {
+	if len(entry.Topics) != 3 || len(entry.Topics[1]) != 66 || len(entry.Topics[2]) != 66 {
+		return EVMTransfer{}, fmt.Errorf("unexpected Transfer log in %s", entry.TransactionHash)
+	}
+	block, err := parseHexInt(entry.BlockNumber)
+	if err != nil {
+		return EVMTransfer{}, fmt.Errorf("invalid block number %q: %w", entry.BlockNumber, err)
+	}
+	logIndex, err := parseHexInt(entry.LogIndex)
+	if err != nil {
+		return EVMTransfer{}, fmt.Errorf("invalid log index %q: %w", entry.LogIndex, err)
+	}
+	raw, ok := new(big.Int).SetString(strings.TrimPrefix(entry.Data, "0x"), 16)
+	if !ok {
+		return EVMTransfer{}, fmt.Errorf("invalid Transfer amount %q in %s", entry.Data, entry.TransactionHash)
+	}
+
+	contract := strings.ToLower(entry.Address)
+	decimals, err := c.tokenDecimals(ctx, contract)
+	if err != nil {
+		return EVMTransfer{}, err
+	}
+	blockTime, err := c.blockTime(ctx, block, blockTimes)
+	if err != nil {
+		return EVMTransfer{}, err
+	}
+
+	amount, _ := new(big.Float).Quo(new(big.Float).SetInt(raw), new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))).Float64()
+	return EVMTransfer{
+		Hash:     entry.TransactionHash,
+		LogIndex: logIndex,
+		Block:    block,
+		Time:     blockTime,
+		Token:    symbols[contract],
+		From:     "0x" + strings.ToLower(entry.Topics[1][26:]),
+		To:       "0x" + strings.ToLower(entry.Topics[2][26:]),
+		Amount:   amount,
+	}, nil
+}
+
+// firstBlockAfter returns the first block produced at or after t, or latest+1
+// if there is none yet, by binary search over block times
+func (c *HyperEVMClient) firstBlockAfter(ctx context.Context, t time.Time, latest int64, blockTimes map[int64]time.Time) (int64, error) {
+	lo, hi := int64(0), latest+1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		blockTime, err := c.blockTime(ctx, mid, blockTimes)
+		if err != nil {
+			return 0, err
+		}
+		if blockTime.Before(t) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+// blockTime returns the time block was produced, remembering it in blockTimes
+func (c *HyperEVMClient) blockTime(ctx context.Context, block int64, blockTimes map[int64]time.Time) (time.Time, error) {
+	if t, exists := blockTimes[block]; exists {
+		return t, nil
+	}
+
+	var header struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if err := c.call(ctx, "eth_getBlockByNumber", []interface{}{hexInt(block), false}, &header); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get block %d: %w", block, err)
+	}
+	seconds, err := parseHexInt(header.Timestamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q of block %d", header.Timestamp, block)
+	}
+
+	t := time.Unix(seconds, 0)
+	blockTimes[block] = t
+	return t, nil
+}
+
+// blockNumber returns the number of the latest block
+func (c *HyperEVMClient) blockNumber(ctx context.Context) (int64, error) {
+	var result string
+	if err := c.call(ctx, "eth_blockNumber", []interface{}{}, &result); err != nil {
+		return 0, err
+	}
+	return parseHexInt(result)
+}
+
+// getLogs returns the logs of contracts matching topics in blocks from to to
+func (c *HyperEVMClient) getLogs(ctx context.Context, contracts []string, topics []interface{}, from, to int64) ([]evmLog, error) {
+	filter := map[string]interface{}{
+		"address":   contracts,
+		"topics":    topics,
+		"fromBlock": hexInt(from),
+		"toBlock":   hexInt(to),
+	}
+	var logs []evmLog
+	err := c.call(ctx, "eth_getLogs", []interface{}{filter}, &logs)
+	return logs, err
+}
+
+// tokenDecimals returns the decimals of the token at contract, asking the
+// contract the first time
+func (c *HyperEVMClient) tokenDecimals(ctx context.Context, contract string) (int, error) {
+	c.mu.Lock()
+	decimals, exists := c.decimals[contract]
+	c.mu.Unlock()
+	if exists {
+		return decimals, nil
+	}
+
+	var result string
+	call := map[string]string{"to": contract, "data": "0x" + selectorDecimals}
+	if err := c.call(ctx, "eth_call", []interface{}{call, "latest"}, &result); err != nil {
+		return 0, fmt.Errorf("failed to get decimals of %s: %w", contract, err)
+	}
+	parsed, ok := new(big.Int).SetString(strings.TrimPrefix(result, "0x"), 16)
+	if !ok || !parsed.IsInt64() || parsed.Int64() > 36 {
+		return 0, fmt.Errorf("unexpected decimals %q of %s", result, contract)
+	}
+
+	c.mu.Lock()
+	c.decimals[contract] = int(parsed.Int64())
+	c.mu.Unlock()
+	return int(parsed.Int64()), nil
+}
+
+// call makes a JSON-RPC call and decodes its result into out
+func (c *HyperEVMClient) call(ctx context.Context, method string, params []interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("RPC returned status %d", resp.StatusCode)
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("failed to decode RPC response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("RPC error: %s", rpcResp.Error.Message)
+	}
+	if err := json.Unmarshal(rpcResp.Result, out); err != nil {
+		return fmt.Errorf("unexpected RPC result: %w", err)
+	}
+	return nil
+}
+
+// isSystemAddress reports whether address is the HYPE system address or a
+// token system address (0x20 followed by zeros and the token index)
+func isSystemAddress(address string) bool {
+	return address == hypeSystemAddress || strings.HasPrefix(address, "0x20000000000000000000")
+}
+
+// hexInt encodes n as a JSON-RPC quantity
+func hexInt(n int64) string {
+	return "0x" + strconv.FormatInt(n, 16)
+}
+
+// parseHexInt decodes a JSON-RPC quantity
+func parseHexInt(s string) (int64, error) {
+	return strconv.ParseInt(strings.TrimPrefix(s, "0x"), 16, 64)
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testTokenContract = "0x00000000000000000000000000000000000000aa"
+
+// testTransferLog is a Transfer log served by the test chain
+type testTransferLog struct {
+	block    int64
+	from, to string
+	amount   string // Raw hex amount
+}
+
+// newTestHyperEVMServer serves a chain of 100 blocks a minute apart from genesis
+// with the given Transfer logs of a 6-decimal token
+func newTestHyperEVMServer(t *testing.T, genesis time.Time, logs []testTransferLog) *httptest.Server {
+	word := func(addr string) string {
+		return "0x" + strings.Repeat("0", 24) + strings.TrimPrefix(addr, "0x")
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		var result interface{}
+		switch req.Method {
+		case "eth_blockNumber":
+			result = hexInt(99)
+		case "eth_getBlockByNumber":
+			var number string
+			json.Unmarshal(req.Params[0], &number)
+			block, _ := parseHexInt(number)
+			result = map[string]string{"timestamp": hexInt(genesis.Add(time.Duration(block) * time.Minute).Unix())}
+		case "eth_call":
+			result = "0x" + strings.Repeat("0", 63) + "6"
+		case "eth_getLogs":
+			var filter struct {
+				Topics    []*string `json:"topics"`
+				FromBlock string    `json:"fromBlock"`
+				ToBlock   string    `json:"toBlock"`
+			}
+			json.Unmarshal(req.Params[0], &filter)
+			from, _ := parseHexInt(filter.FromBlock)
+			to, _ := parseHexInt(filter.ToBlock)
+
+			matches := []evmLog{}
+			for i, l := range logs {
+				topics := []string{transferTopic, word(l.from), word(l.to)}
+				matched := l.block >= from && l.block <= to
+				for j, topic := range filter.Topics {
+					if topic != nil && *topic != topics[j] {
+						matched = false
+					}
+				}
+				if matched {
+					matches = append(matches, evmLog{
+						Address:         testTokenContract,
+						Topics:          topics,
+						Data:            l.amount,
+						BlockNumber:     hexInt(l.block),
+						TransactionHash: "0xhash" + hexInt(int64(i)),
+						LogIndex:        "0x0",
+					})
+				}
+			}
+			result = matches
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+}
+
+// Test fetching HyperEVM token transfers and posting them to the ledger
+func TestHyperEVMTransfers(t *testing.T) {
+	genesis := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	const other = "0x9999999999999999999999999999999999999999"
+	const tokenSystem = "0x20000000000000000000000000000000000000c8"
+
+	server := newTestHyperEVMServer(t, genesis, []testTransferLog{
+		{block: 5, from: other, to: testAddress, amount: "0x0f4240"},        // 1 before the window
+		{block: 10, from: other, to: testAddress, amount: "0x1e8480"},       // 2 in
+		{block: 20, from: tokenSystem, to: testAddress, amount: "0x2dc6c0"}, // 3 bridged from HyperCore
+		{block: 30, from: testAddress, to: other, amount: "0x3d0900"},       // 4 out
+		{block: 40, from: other, to: other, amount: "0x4c4b40"},             // Not the address's
+	})
+	defer server.Close()
+
+	tokens, err := ParseEVMTokens("USDT0:" + testTokenContract)
+	if err != nil {
+		t.Fatalf("Failed to parse tokens: %v", err)
+	}
+	client := NewHyperEVMClient(server.URL, tokens, 7, time.Second)
+
+	transfers, err := client.FetchTransfers(context.Background(), testAddress, genesis.Add(8*time.Minute), genesis.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to fetch transfers: %v", err)
+	}
+	if len(transfers) != 3 {
+		t.Fatalf("Expected 3 transfers in the window, got %+v", transfers)
+	}
+	if in := transfers[0]; in.Amount != 2 || in.Token != "USDT0" || !in.Time.Equal(genesis.Add(10*time.Minute)) || in.Bridged() {
+		t.Errorf("Unexpected incoming transfer %+v", in)
+	}
+	if !transfers[1].Bridged() || transfers[2].From != testAddress {
+		t.Errorf("Expected a bridged transfer then an outgoing one, got %+v", transfers[1:])
+	}
+
+	var kinds []string
+	balances := make(map[string]float64)
+	for _, transfer := range transfers {
+		entry, ok := transferEntry(testAddress, transfer)
+		if !ok {
+			t.Fatalf("Expected %+v to be posted", transfer)
+		}
+		kinds = append(kinds, entry.Kind)
+		for _, posting := range entry.Postings {
+			balances[posting.Account] += float64(posting.Amount)
+		}
+	}
+	if strings.Join(kinds, ",") != "deposit,bridge,withdrawal" {
+		t.Errorf("Unexpected entry kinds %v", kinds)
+	}
+	if balances[AccountHyperEVM+"USDT0"] != 1 || balances[AccountHyperCore+"USDT0"] != -3 || balances[AccountDeposits] != -2 || balances[AccountWithdrawals] != 4 {
+		t.Errorf("Unexpected balances %v", balances)
+	}
+
+	if _, err := ParseEVMTokens("USDT0"); err == nil {
+		t.Error("Expected a token without a contract to be rejected")
+	}
+}
@@ -2,26 +2,112 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"hyperliquid-recon/config"
 	"hyperliquid-recon/models"
 	"io"
 	"log"
 	"net/http"
-	"strconv"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// rateLimitDelay is the pause between paginated requests, shared by every
+// client since they all draw on the same upstream rate limit
+var rateLimitDelay atomic.Int64
+
+func init() {
+	rateLimitDelay.Store(int64(config.RateLimitDelay))
+}
+
+// SetRateLimitDelay changes the pause between paginated requests, which
+// adaptive pacing starts again from
+func SetRateLimitDelay(delay time.Duration) {
+	rateLimitDelay.Store(int64(delay))
+	upstreamPacer.reset(delay)
+}
+
+// RateLimitDelay returns the configured pause between paginated requests;
+// PacingDelay returns the one in effect
+func RateLimitDelay() time.Duration {
+	return time.Duration(rateLimitDelay.Load())
+}
+
+// hyperliquidTransport carries the requests of clients created afterwards, e.g.
+// to synthetic fills or a tape
+var hyperliquidTransport http.RoundTripper = upstreamTransport
+
+// UseHyperliquidTransport makes every Hyperliquid client created afterwards
+// send its requests through rt; nil restores the shared upstream transport
+func UseHyperliquidTransport(rt http.RoundTripper) {
+	if rt == nil {
+		rt = upstreamTransport
+	}
+	hyperliquidTransport = rt
+}
+
+// HyperliquidTransport returns the transport new Hyperliquid clients use
+func HyperliquidTransport() http.RoundTripper {
+	return hyperliquidTransport
+}
+
+// ErrResponseTooLarge is returned when an API response is larger than the client accepts
+var ErrResponseTooLarge = errors.New("API response too large")
+
+// requestBuffers holds buffers request bodies are encoded into, reused across requests
+var requestBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
 // HyperliquidClient Client for interacting with the Hyperliquid API
 type HyperliquidClient struct {
-	httpClient *http.Client
+	httpClient       *http.Client
+	apiURL           string
+	headers          http.Header // Sent with every request
+	maxResponseBytes int64
+	quarantine       *QuarantineStore // Optional; keeps fills that fail to parse
 }
 
 func NewHyperliquidClient() *HyperliquidClient {
 	return &HyperliquidClient{
-		httpClient: &http.Client{Timeout: config.APITimeout},
+		httpClient:       &http.Client{Timeout: config.APITimeout, Transport: hyperliquidTransport},
+		apiURL:           config.HyperliquidAPIURL,
+		headers:          upstreamHeaders.Clone(),
+		maxResponseBytes: config.MaxResponseBytes,
+	}
+}
+
+// PartialError is returned by FetchTradesInRange when one or more batches were
+// fetched successfully before a later batch failed. The trades returned with it
+// are the successfully fetched prefix, which may be empty if the earlier
+// slices of a sliced fetch had no trades; MissingStart..MissingEnd is the
+// window that could not be fetched.
+type PartialError struct {
+	Batch        int // Batch that failed, counted within its slice
+	Slice        int // Slice that failed, counted from 1, or 0 if the range wasn't sliced
+	MissingStart time.Time
+	MissingEnd   time.Time
+	Err          error
+}
+
+func (e *PartialError) Error() string {
+	batch := fmt.Sprintf("batch %d", e.Batch)
+	if e.Slice > 0 {
+		batch += fmt.Sprintf(" of slice %d", e.Slice)
 	}
+	return fmt.Sprintf("partial fetch: %s failed, missing %s to %s: %v",
+		batch, e.MissingStart.Format(time.RFC3339), e.MissingEnd.Format(time.RFC3339), e.Err)
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
 }
 
 // UserFillsRequest represents the request body for fetching user fills
@@ -43,40 +129,145 @@ type FillResponse struct {
 	StartPosition string `json:"startPosition"`
 	Dir           string `json:"dir"`
 	ClosedPnl     string `json:"closedPnl"`
+	Fee           string `json:"fee"`
 }
 
 // FetchTrades fetches historical trades for a given address from Hyperliquid API
 // It handles pagination automatically and returns all trades within the specified history period
-func (c *HyperliquidClient) FetchTrades(address string, days int) ([]models.Trade, error) {
+func (c *HyperliquidClient) FetchTrades(ctx context.Context, address string, days int) ([]models.Trade, error) {
 	// Calculate start time based on specified history days
-	now := time.Now()
+	now := exchangeNow()
 	historyStart := now.Add(-time.Duration(days) * 24 * time.Hour)
 
-	return c.FetchTradesInRange(address, historyStart, now)
+	return c.FetchTradesInRange(ctx, address, historyStart, now)
 }
 
-// FetchTradesInRange fetches trades for a given address within a specific time range
-func (c *HyperliquidClient) FetchTradesInRange(address string, start, end time.Time) ([]models.Trade, error) {
-	startTime := start.UnixMilli()
-	endTime := end.UnixMilli()
+// FetchTradesInRange fetches trades for a given address within a specific time range.
+// If a batch fails after earlier batches succeeded, the trades fetched so far are
+// returned together with a *PartialError describing the missing window.
+// Ranges of at least ParallelFetchMinRange are split into slices fetched
+// concurrently, sharing the request pacing.
+func (c *HyperliquidClient) FetchTradesInRange(ctx context.Context, address string, start, end time.Time) (trades []models.Trade, err error) {
+	ctx, span := tracer.Start(ctx, "HyperliquidClient.FetchTradesInRange", trace.WithAttributes(
+		attribute.String("address", address),
+		attribute.String("start", start.Format(time.RFC3339)),
+		attribute.String("end", end.Format(time.RFC3339)),
+	))
+	defer func() {
+		span.SetAttributes(attribute.Int("trades", len(trades)))
+		endSpan(span, err)
+	}()
+	defer upstreamBudget.hold(ctx)()
 
 	log.Printf("Fetching trades for %s from %s to %s", address, start.Format(time.RFC3339), end.Format(time.RFC3339))
 
+	if slices := splitRange(start, end); len(slices) > 1 {
+		span.SetAttributes(attribute.Int("slices", len(slices)))
+		return c.fetchSlices(ctx, address, slices)
+	}
+	return c.fetchPages(ctx, address, start, end)
+}
+
+// splitRange splits start..end into ParallelFetchSlices disjoint slices if
+// it is at least ParallelFetchMinRange long, or else returns it whole
+func splitRange(start, end time.Time) []models.TimeRange {
+	if end.Sub(start) < config.ParallelFetchMinRange || config.ParallelFetchSlices < 2 {
+		return []models.TimeRange{{Start: start, End: end}}
+	}
+	startTime, endTime := start.UnixMilli(), end.UnixMilli()
+	step := (endTime - startTime) / int64(config.ParallelFetchSlices)
+	slices := make([]models.TimeRange, 0, config.ParallelFetchSlices)
+	for i := int64(0); i < int64(config.ParallelFetchSlices); i++ {
+		sliceEnd := startTime + (i+1)*step - 1 // Windows include their end
+		if i == int64(config.ParallelFetchSlices)-1 {
+			sliceEnd = endTime
+		}
+		slices = append(slices, models.TimeRange{Start: time.UnixMilli(startTime + i*step), End: time.UnixMilli(sliceEnd)})
+	}
+	return slices
+}
+
+// fetchSlices fetches each of slices concurrently and returns their trades in
+// order. If a slice fails, the trades of the slices before it and what it
+// fetched itself are returned with a *PartialError from where it stopped to
+// the end of the last slice; the later slices' trades are dropped, so the
+// result stays a prefix of the range. The error is only returned alone if
+// nothing was fetched before it, i.e. the first batch of the first slice failed.
+func (c *HyperliquidClient) fetchSlices(ctx context.Context, address string, slices []models.TimeRange) ([]models.Trade, error) {
+	type result struct {
+		trades []models.Trade
+		err    error
+	}
+	results := make([]result, len(slices))
+	var wg sync.WaitGroup
+	for i, slice := range slices {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// The first slice starts straight away like any fetch; the others take their turn
+			if i > 0 {
+				upstreamPacer.pause(ctx)
+			}
+			trades, err := c.fetchPages(ctx, address, slice.Start, slice.End)
+			results[i] = result{trades, err}
+		}()
+	}
+	wg.Wait()
+
+	end := slices[len(slices)-1].End
+	allTrades := make([]models.Trade, 0)
+	for i, r := range results {
+		allTrades = append(allTrades, r.trades...)
+		if r.err == nil {
+			continue
+		}
+		// fetchPages only fails alone on a slice's first batch
+		partial := &PartialError{Batch: 1, Slice: i + 1, MissingStart: slices[i].Start, MissingEnd: end, Err: r.err}
+		var slicePartial *PartialError
+		if errors.As(r.err, &slicePartial) {
+			partial = &PartialError{Batch: slicePartial.Batch, Slice: i + 1, MissingStart: slicePartial.MissingStart, MissingEnd: end, Err: slicePartial.Err}
+		} else if i == 0 {
+			return nil, r.err
+		}
+		log.Printf("Slice %d of %d failed after fetching %d trades, returning partial result", i+1, len(slices), len(allTrades))
+		return allTrades, partial
+	}
+	log.Printf("Fetched %d trades in %d slices", len(allTrades), len(slices))
+	return allTrades, nil
+}
+
+// fetchPages fetches the trades between start and end batch by batch, with
+// the same partial results as FetchTradesInRange
+func (c *HyperliquidClient) fetchPages(ctx context.Context, address string, start, end time.Time) ([]models.Trade, error) {
+	span := trace.SpanFromContext(ctx)
+	startTime := start.UnixMilli()
+	endTime := end.UnixMilli()
+
 	allTrades := make([]models.Trade, 0)
 	currentStartTime := startTime
 
 	// Pagination loop: fetch in batches
 	batchCount := 0
 	for {
-		// Add delay between requests to avoid rate limiting (except first request)
+		// Pace requests to avoid rate limiting (except first request)
 		if batchCount > 0 {
-			time.Sleep(config.RateLimitDelay)
+			upstreamPacer.pause(ctx)
 		}
 		batchCount++
+		span.SetAttributes(attribute.Int("batches", batchCount))
 
-		fills, err := c.fetchBatch(address, currentStartTime, endTime)
+		fills, err := c.fetchBatch(ctx, batchCount, address, currentStartTime, endTime)
 		if err != nil {
-			return nil, fmt.Errorf("failed to fetch batch %d: %w", batchCount, err)
+			if batchCount == 1 {
+				return nil, fmt.Errorf("failed to fetch batch %d: %w", batchCount, err)
+			}
+			log.Printf("Batch %d failed after fetching %d trades, returning partial result", batchCount, len(allTrades))
+			return allTrades, &PartialError{
+				Batch:        batchCount,
+				MissingStart: time.UnixMilli(currentStartTime),
+				MissingEnd:   end,
+				Err:          err,
+			}
 		}
 
 		// If no more fills, break
@@ -86,14 +277,18 @@ func (c *HyperliquidClient) FetchTradesInRange(address string, start, end time.T
 		}
 
 		// Convert fills to trades
+		failed := 0
 		for _, fill := range fills {
 			trade, err := c.convertFillToTrade(fill)
 			if err != nil {
-				log.Printf("Warning: Failed to convert fill: %v", err)
+				log.Printf("Warning: Failed to convert fill, quarantining it: %v", err)
+				c.quarantine.add(address, fill, err)
+				failed++
 				continue
 			}
 			allTrades = append(allTrades, trade)
 		}
+		tallyFrom(ctx).addFills(len(fills), failed)
 
 		// If we got less than max batch size, we've reached the end
 		if len(fills) < config.MaxTradesPerBatch {
@@ -109,8 +304,31 @@ func (c *HyperliquidClient) FetchTradesInRange(address string, start, end time.T
 	return allTrades, nil
 }
 
-// fetchBatch fetches a single batch of trades from the API
-func (c *HyperliquidClient) fetchBatch(address string, startTime, endTime int64) ([]FillResponse, error) {
+// fetchBatch fetches a single batch of trades, from the response cache if the
+// same window was fetched moments ago or is being fetched now, or else from the
+// API. A low-priority fetch from the API first waits for running high-priority
+// fetches to finish.
+func (c *HyperliquidClient) fetchBatch(ctx context.Context, batch int, address string, startTime, endTime int64) (fills []FillResponse, err error) {
+	ctx, span := tracer.Start(ctx, "HyperliquidClient.fetchBatch", trace.WithAttributes(attribute.Int("batch", batch)))
+	defer func() {
+		span.SetAttributes(attribute.Int("fills", len(fills)))
+		endSpan(span, err)
+	}()
+
+	key := responseKey{apiURL: c.apiURL, address: strings.ToLower(address), start: startTime, end: endTime}
+	fills, cached, err := upstreamResponses.get(ctx, key, func() ([]FillResponse, error) {
+		return c.requestBatch(ctx, address, startTime, endTime)
+	})
+	span.SetAttributes(attribute.Bool("cached", cached))
+	return fills, err
+}
+
+// requestBatch requests a single batch of trades from the API
+func (c *HyperliquidClient) requestBatch(ctx context.Context, address string, startTime, endTime int64) (fills []FillResponse, err error) {
+	if err := upstreamBudget.wait(ctx); err != nil {
+		return nil, err
+	}
+
 	requestBody := UserFillsRequest{
 		Type:            "userFillsByTime",
 		User:            address,
@@ -119,53 +337,356 @@ func (c *HyperliquidClient) fetchBatch(address string, startTime, endTime int64)
 		AggregateByTime: true,
 	}
 
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	resp, err := c.httpClient.Post(config.HyperliquidAPIURL, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := c.post(ctx, requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch trades: %w", err)
 	}
 	defer resp.Body.Close()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
 		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	// Decode fill by fill straight from the response instead of reading it all
+	// first, keeping a copy of the bytes if responses are archived
+	body, err := c.limitBody(resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
-
-	var fills []FillResponse
-	if err := json.Unmarshal(body, &fills); err != nil {
+	archive := fillArchive.Load()
+	var raw bytes.Buffer
+	if archive != nil {
+		body = io.TeeReader(body, &raw)
+	}
+	decoder := json.NewDecoder(body)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", unexpectedToken(token, err))
+	}
+	fills = make([]FillResponse, 0, config.MaxTradesPerBatch)
+	for decoder.More() {
+		var fill FillResponse
+		if err := decoder.Decode(&fill); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		fills = append(fills, fill)
+	}
+	if _, err := decoder.Token(); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+	if archive != nil {
+		// Read whatever follows the array too, so the copy is the whole body
+		io.Copy(io.Discard, body)
+		archive.put(address, startTime, endTime, time.Now(), raw.Bytes())
+	}
+	upstreamUsage.record(0, len(fills)/config.UserFillsItemsPerWeight)
+	if len(fills) > 0 {
+		upstreamClock.observeFill(time.UnixMilli(fills[len(fills)-1].Time), time.Now())
+	}
 
 	return fills, nil
 }
 
+// post sends request as JSON to the info endpoint, encoding it into a pooled buffer
+func (c *HyperliquidClient) post(ctx context.Context, request interface{}) (*http.Response, error) {
+	buf := requestBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := json.NewEncoder(buf).Encode(request); err != nil {
+		requestBuffers.Put(buf)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	body := &pooledBody{Reader: bytes.NewReader(buf.Bytes()), buf: buf}
+	req, err := http.NewRequestWithContext(withConnTrace(ctx), http.MethodPost, c.apiURL, body)
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = int64(buf.Len())
+	for name, values := range c.headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	sent := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Requests the caller gave up on say nothing about the API
+		if !errors.Is(ctx.Err(), context.Canceled) {
+			upstreamHealth.observe(time.Since(sent), err)
+			upstreamPacer.observe(nil, time.Since(sent))
+		}
+		return nil, err
+	}
+	countResponse(resp)
+	upstreamPacer.observe(resp, time.Since(sent))
+	upstreamUsage.record(1, config.InfoRequestWeight)
+	upstreamClock.observeDate(resp.Header.Get("Date"), sent, time.Now())
+	if resp.StatusCode >= http.StatusInternalServerError {
+		upstreamHealth.observe(time.Since(sent), fmt.Errorf("API returned status %d", resp.StatusCode))
+	} else {
+		upstreamHealth.observe(time.Since(sent), nil)
+	}
+	return resp, nil
+}
+
+// pooledBody is a request body that returns its buffer to requestBuffers when
+// the transport closes it, which may be after the response has arrived
+type pooledBody struct {
+	*bytes.Reader
+	buf  *bytes.Buffer
+	once sync.Once
+}
+
+func (b *pooledBody) Close() error {
+	b.once.Do(func() { requestBuffers.Put(b.buf) })
+	return nil
+}
+
+// limitBody returns the body of resp, failing with ErrResponseTooLarge once
+// more than the client's limit has been read
+func (c *HyperliquidClient) limitBody(resp *http.Response) (io.Reader, error) {
+	if resp.ContentLength > c.maxResponseBytes {
+		return nil, fmt.Errorf("%w: %d bytes, limit is %d", ErrResponseTooLarge, resp.ContentLength, c.maxResponseBytes)
+	}
+	return &limitedReader{r: resp.Body, remaining: c.maxResponseBytes, limit: c.maxResponseBytes}, nil
+}
+
+// limitedReader reads from r until more than limit bytes have been read, then
+// fails with ErrResponseTooLarge
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, fmt.Errorf("%w: limit is %d bytes", ErrResponseTooLarge, l.limit)
+	}
+	// Read one byte past the limit to tell a body of exactly limit bytes from a larger one
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n - 1, fmt.Errorf("%w: limit is %d bytes", ErrResponseTooLarge, l.limit)
+	}
+	return n, err
+}
+
+// unexpectedToken describes why a response didn't start with the expected JSON array
+func unexpectedToken(token json.Token, err error) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("expected an array, got %v", token)
+}
+
 // convertFillToTrade converts a FillResponse to a Trade model
 func (c *HyperliquidClient) convertFillToTrade(fill FillResponse) (models.Trade, error) {
-	price, err := strconv.ParseFloat(fill.Price, 64)
+	// NaN, Inf and negative sizes parse as floats but would poison every
+	// total, so they fail here and the fill is quarantined instead
+	price, err := models.ParseMoney(fill.Price)
+	if err != nil {
+		return models.Trade{}, fmt.Errorf("failed to parse price: %w", err)
+	}
+
+	size, err := models.ParseQuantity(fill.Size)
 	if err != nil {
-		return models.Trade{}, fmt.Errorf("failed to parse price '%s': %w", fill.Price, err)
+		return models.Trade{}, fmt.Errorf("failed to parse size: %w", err)
 	}
 
-	size, err := strconv.ParseFloat(fill.Size, 64)
+	value, err := models.NewMoney(float64(price) * float64(size))
 	if err != nil {
-		return models.Trade{}, fmt.Errorf("failed to parse size '%s': %w", fill.Size, err)
+		return models.Trade{}, fmt.Errorf("failed to value fill: %w", err)
+	}
+
+	// Older fills may not carry a fee
+	var fee models.Money
+	if fill.Fee != "" {
+		fee, err = models.ParseMoney(fill.Fee)
+		if err != nil {
+			return models.Trade{}, fmt.Errorf("failed to parse fee: %w", err)
+		}
+	}
+
+	var startPosition *models.Position
+	if fill.StartPosition != "" {
+		parsed, err := models.ParsePosition(fill.StartPosition)
+		if err != nil {
+			return models.Trade{}, fmt.Errorf("failed to parse startPosition: %w", err)
+		}
+		startPosition = &parsed
+	}
+
+	var realizedPnL *models.Money
+	if fill.ClosedPnl != "" {
+		parsed, err := models.ParseMoney(fill.ClosedPnl)
+		if err != nil {
+			return models.Trade{}, fmt.Errorf("failed to parse closedPnl: %w", err)
+		}
+		realizedPnL = &parsed
 	}
 
 	return models.Trade{
-		Time:  time.UnixMilli(fill.Time),
-		Coin:  fill.Coin,
-		Side:  fill.Side,
-		Price: price,
-		Size:  size,
-		Value: price * size,
+		Time:          time.UnixMilli(fill.Time),
+		Coin:          fill.Coin,
+		Side:          fill.Side,
+		Price:         price,
+		Size:          size,
+		Value:         value,
+		Fee:           fee,
+		StartPosition: startPosition,
+		RealizedPnL:   realizedPnL,
 	}, nil
 }
+
+// portfolioRequest asks for an account's value and P&L history
+type portfolioRequest struct {
+	Type string `json:"type"`
+	User string `json:"user"`
+}
+
+// FetchAccountValueHistory fetches the all-time account value history of
+// address, oldest first
+func (c *HyperliquidClient) FetchAccountValueHistory(ctx context.Context, address string) (history []models.AccountValue, err error) {
+	ctx, span := tracer.Start(ctx, "HyperliquidClient.FetchAccountValueHistory", trace.WithAttributes(attribute.String("address", address)))
+	defer func() { endSpan(span, err) }()
+
+	// The response pairs each period name with its history, e.g.
+	// [["day", {"accountValueHistory": [[1700000000000, "1234.5"], ...]}], ...]
+	var periods [][2]json.RawMessage
+	if err := c.postInfo(ctx, portfolioRequest{Type: "portfolio", User: address}, &periods); err != nil {
+		return nil, fmt.Errorf("failed to fetch portfolio: %w", err)
+	}
+
+	for _, period := range periods {
+		var name string
+		if err := json.Unmarshal(period[0], &name); err != nil || name != "allTime" {
+			continue
+		}
+
+		var data struct {
+			AccountValueHistory [][2]json.RawMessage `json:"accountValueHistory"`
+		}
+		if err := json.Unmarshal(period[1], &data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal account value history: %w", err)
+		}
+
+		history = make([]models.AccountValue, 0, len(data.AccountValueHistory))
+		for _, point := range data.AccountValueHistory {
+			var ms int64
+			var value string
+			if err := json.Unmarshal(point[0], &ms); err != nil {
+				return nil, fmt.Errorf("invalid account value time %s", point[0])
+			}
+			if err := json.Unmarshal(point[1], &value); err != nil {
+				return nil, fmt.Errorf("invalid account value %s", point[1])
+			}
+			parsed, err := models.ParseMoney(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse account value '%s': %w", value, err)
+			}
+			history = append(history, models.AccountValue{Time: time.UnixMilli(ms), Value: parsed})
+		}
+		sort.Slice(history, func(i, j int) bool { return history[i].Time.Before(history[j].Time) })
+		return history, nil
+	}
+
+	return nil, fmt.Errorf("portfolio for %s has no allTime history", address)
+}
+
+// ledgerUpdatesBatchSize is the most updates the API returns per request
+const ledgerUpdatesBatchSize = 500
+
+// Ledger update request types
+const (
+	LedgerUpdatesFunding    = "userFunding"
+	LedgerUpdatesNonFunding = "userNonFundingLedgerUpdates"
+)
+
+// LedgerUpdateResponse is one funding payment, deposit, withdrawal or other
+// account update from the Hyperliquid API
+type LedgerUpdateResponse struct {
+	Time  int64  `json:"time"`
+	Hash  string `json:"hash"`
+	Delta struct {
+		Type  string `json:"type"` // e.g. "funding", "deposit", "withdraw" or "vaultDeposit"
+		Coin  string `json:"coin"`
+		USDC  string `json:"usdc"`
+		Fee   string `json:"fee"`
+		Vault string `json:"vault"` // Vault deposits and withdrawals
+		// Amount is the USDC paid out by a rewards claim
+		Amount string `json:"amount"`
+		// Basis is the cost of the vault equity withdrawn, and NetWithdrawnUSD
+		// what was paid out for it after commission and closing costs
+		Basis           string `json:"basis"`
+		NetWithdrawnUSD string `json:"netWithdrawnUsd"`
+	} `json:"delta"`
+}
+
+// FetchLedgerUpdates fetches the account updates of address of the given
+// request type (LedgerUpdatesFunding or LedgerUpdatesNonFunding) between start
+// and end, oldest first
+func (c *HyperliquidClient) FetchLedgerUpdates(ctx context.Context, updateType, address string, start, end time.Time) (updates []LedgerUpdateResponse, err error) {
+	ctx, span := tracer.Start(ctx, "HyperliquidClient.FetchLedgerUpdates", trace.WithAttributes(
+		attribute.String("address", address),
+		attribute.String("type", updateType),
+	))
+	defer func() {
+		span.SetAttributes(attribute.Int("updates", len(updates)))
+		endSpan(span, err)
+	}()
+	defer upstreamBudget.hold(ctx)()
+
+	startTime, endTime := start.UnixMilli(), end.UnixMilli()
+	for batch := 1; ; batch++ {
+		if batch > 1 {
+			upstreamPacer.pause(ctx)
+		}
+
+		var page []LedgerUpdateResponse
+		request := UserFillsRequest{Type: updateType, User: address, StartTime: &startTime, EndTime: &endTime}
+		if err := c.postInfo(ctx, request, &page); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s batch %d: %w", updateType, batch, err)
+		}
+		updates = append(updates, page...)
+		upstreamUsage.record(0, len(page)/config.UserFillsItemsPerWeight)
+
+		if len(page) < ledgerUpdatesBatchSize {
+			return updates, nil
+		}
+		startTime = page[len(page)-1].Time + 1
+	}
+}
+
+// postInfo posts request to the info endpoint and decodes the JSON response
+// into out, waiting first if it is low priority and high-priority fetches are running
+func (c *HyperliquidClient) postInfo(ctx context.Context, request interface{}, out interface{}) error {
+	if err := upstreamBudget.wait(ctx); err != nil {
+		return err
+	}
+
+	resp, err := c.post(ctx, request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	body, err := c.limitBody(resp)
+	if err != nil {
+		return err
+	}
+	if err := json.NewDecoder(body).Decode(out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyServer returns a server that answers the first request with a full
+// batch of fills and fails every request after that
+func newFlakyServer(t *testing.T, base time.Time) *httptest.Server {
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 1 {
+			http.Error(w, "upstream unavailable", http.StatusInternalServerError)
+			return
+		}
+
+		fills := make([]FillResponse, config.MaxTradesPerBatch)
+		for i := range fills {
+			fills[i] = FillResponse{
+				Time:  base.Add(time.Duration(i) * time.Second).UnixMilli(),
+				Coin:  "BTC",
+				Side:  "B",
+				Price: "50000",
+				Size:  "0.1",
+			}
+		}
+		if err := json.NewEncoder(w).Encode(fills); err != nil {
+			t.Errorf("Failed to encode fills: %v", err)
+		}
+	}))
+}
+
+// Test FetchTradesInRange partial failures
+func TestFetchTradesInRangePartial(t *testing.T) {
+	end := time.Now()
+	start := end.Add(-24 * time.Hour)
+
+	t.Run("should return fetched prefix with PartialError", func(t *testing.T) {
+		server := newFlakyServer(t, start)
+		defer server.Close()
+
+		client := NewHyperliquidClient()
+		client.apiURL = server.URL
+
+		trades, err := client.FetchTradesInRange(context.Background(), "0xabc", start, end)
+
+		var partial *PartialError
+		if !errors.As(err, &partial) {
+			t.Fatalf("Expected PartialError, got %v", err)
+		}
+		if len(trades) != config.MaxTradesPerBatch {
+			t.Errorf("Expected %d trades, got %d", config.MaxTradesPerBatch, len(trades))
+		}
+		if partial.Batch != 2 {
+			t.Errorf("Expected batch 2 to fail, got %d", partial.Batch)
+		}
+
+		lastTrade := trades[len(trades)-1].Time
+		if !partial.MissingStart.After(lastTrade) {
+			t.Errorf("Missing window should start after last fetched trade")
+		}
+		if !partial.MissingEnd.Equal(end) {
+			t.Errorf("Missing window should end at requested end")
+		}
+	})
+
+	t.Run("should return plain error when first batch fails", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "upstream unavailable", http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		client := NewHyperliquidClient()
+		client.apiURL = server.URL
+
+		trades, err := client.FetchTradesInRange(context.Background(), "0xabc", start, end)
+
+		var partial *PartialError
+		if err == nil || errors.As(err, &partial) {
+			t.Fatalf("Expected non-partial error, got %v", err)
+		}
+		if trades != nil {
+			t.Errorf("Expected no trades, got %d", len(trades))
+		}
+	})
+}
+
+// Test splitting long ranges into slices fetched concurrently
+func TestFetchTradesInRangeSlices(t *testing.T) {
+	previousDelay := RateLimitDelay()
+	SetRateLimitDelay(0)
+	defer SetRateLimitDelay(previousDelay)
+
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	var failFrom, emptyBefore atomic.Int64
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var req UserFillsRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if from := failFrom.Load(); from > 0 && *req.StartTime >= from {
+			http.Error(w, "upstream unavailable", http.StatusInternalServerError)
+			return
+		}
+		// One fill at noon every day, served from the requested window
+		fills := []FillResponse{}
+		if *req.EndTime < emptyBefore.Load() {
+			json.NewEncoder(w).Encode(fills)
+			return
+		}
+		for day := start.Add(12 * time.Hour); day.Before(end); day = day.AddDate(0, 0, 1) {
+			if ms := day.UnixMilli(); ms >= *req.StartTime && ms <= *req.EndTime {
+				fills = append(fills, FillResponse{Time: ms, Coin: "BTC", Side: "B", Price: "50000", Size: "0.1"})
+			}
+		}
+		json.NewEncoder(w).Encode(fills)
+	}))
+	defer server.Close()
+
+	client := NewHyperliquidClient()
+	client.apiURL = server.URL
+
+	slices := splitRange(start, end)
+	if len(slices) != config.ParallelFetchSlices || !slices[0].Start.Equal(start) || !slices[len(slices)-1].End.Equal(end) {
+		t.Fatalf("Expected %d slices covering the range, got %+v", config.ParallelFetchSlices, slices)
+	}
+	for i := 1; i < len(slices); i++ {
+		if slices[i].Start.UnixMilli() != slices[i-1].End.UnixMilli()+1 {
+			t.Errorf("Expected slice %d to start just after slice %d ends", i, i-1)
+		}
+	}
+	if short := splitRange(end.AddDate(0, 0, -10), end); len(short) != 1 {
+		t.Errorf("Expected a short range not to be split, got %d slices", len(short))
+	}
+
+	t.Run("should fetch every slice and keep the trades in order", func(t *testing.T) {
+		trades, err := client.FetchTradesInRange(context.Background(), "0xabc", start, end)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(trades) != 365 || requests.Load() != int32(config.ParallelFetchSlices) {
+			t.Fatalf("Expected 365 trades from %d requests, got %d from %d", config.ParallelFetchSlices, len(trades), requests.Load())
+		}
+		for i := 1; i < len(trades); i++ {
+			if !trades[i].Time.After(trades[i-1].Time) {
+				t.Fatalf("Expected trades in time order, got %s after %s", trades[i].Time, trades[i-1].Time)
+			}
+		}
+	})
+
+	t.Run("should return the slices before a failed one as a prefix", func(t *testing.T) {
+		// Another address, so the batches aren't answered from the response cache
+		failFrom.Store(slices[2].Start.UnixMilli())
+		trades, err := client.FetchTradesInRange(context.Background(), "0xdef", start, end)
+
+		var partial *PartialError
+		if !errors.As(err, &partial) {
+			t.Fatalf("Expected PartialError, got %v", err)
+		}
+		if !partial.MissingStart.Equal(slices[2].Start) || !partial.MissingEnd.Equal(end) {
+			t.Errorf("Expected %s to %s missing, got %s to %s", slices[2].Start, end, partial.MissingStart, partial.MissingEnd)
+		}
+		if partial.Slice != 3 || partial.Batch != 1 {
+			t.Errorf("Expected batch 1 of slice 3 to fail, got batch %d of slice %d", partial.Batch, partial.Slice)
+		}
+		if len(trades) == 0 || !trades[len(trades)-1].Time.Before(slices[2].Start) {
+			t.Errorf("Expected only trades before the failed slice, got %d", len(trades))
+		}
+	})
+
+	t.Run("should return a partial error after slices without trades", func(t *testing.T) {
+		// The slices before the failed one complete, but hold no trades
+		emptyBefore.Store(slices[2].Start.UnixMilli())
+		defer emptyBefore.Store(0)
+		trades, err := client.FetchTradesInRange(context.Background(), "0x123", start, end)
+
+		var partial *PartialError
+		if !errors.As(err, &partial) {
+			t.Fatalf("Expected PartialError, got %v", err)
+		}
+		if len(trades) != 0 || partial.Slice != 3 || !partial.MissingStart.Equal(slices[2].Start) {
+			t.Errorf("Expected no trades and slice 3 missing from %s, got %d trades and slice %d from %s", slices[2].Start, len(trades), partial.Slice, partial.MissingStart)
+		}
+	})
+
+	t.Run("should fail outright if the first slice fails", func(t *testing.T) {
+		failFrom.Store(start.UnixMilli())
+		trades, err := client.FetchTradesInRange(context.Background(), "0x456", start, end)
+
+		var partial *PartialError
+		if err == nil || errors.As(err, &partial) || trades != nil {
+			t.Errorf("Expected a plain error and no trades, got %d trades (%v)", len(trades), err)
+		}
+	})
+}
+
+// Test FetchAndReconcile caching partial data
+func TestFetchAndReconcilePartial(t *testing.T) {
+	server := newFlakyServer(t, time.Now().Add(-12*time.Hour))
+	defer server.Close()
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = server.URL
+
+	err := rs.FetchAndReconcile(context.Background(), "0xabc", 1)
+
+	var partial *PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Expected PartialError, got %v", err)
+	}
+
+	cache := rs.accountCache["0xabc"]
+	if cache == nil || len(cache.trades) != config.MaxTradesPerBatch {
+		t.Fatalf("Expected partial trades to be cached")
+	}
+
+	summary := rs.GetPnLSummary()
+	if !summary.Incomplete {
+		t.Errorf("Expected summary to be marked incomplete")
+	}
+	if len(summary.MissingRanges) != 1 {
+		t.Errorf("Expected 1 missing range, got %d", len(summary.MissingRanges))
+	}
+}
+
+// Test decoding fill batches and rejecting oversized responses
+func TestFetchBatchResponses(t *testing.T) {
+	fill := `{"time":1735725600000,"coin":"ETH","side":"B","px":"100","sz":"1"}`
+	newServer := func(body string, chunked bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if chunked {
+				// Flushing before the body is written leaves out Content-Length
+				w.(http.Flusher).Flush()
+			}
+			w.Write([]byte(body))
+		}))
+	}
+
+	t.Run("should decode every fill", func(t *testing.T) {
+		server := newServer("["+fill+","+fill+"]", false)
+		defer server.Close()
+		client := NewHyperliquidClient()
+		client.apiURL = server.URL
+
+		fills, err := client.fetchBatch(context.Background(), 1, "0xabc", 0, 1)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(fills) != 2 || fills[1].Coin != "ETH" {
+			t.Errorf("Expected 2 ETH fills, got %+v", fills)
+		}
+	})
+
+	t.Run("should reject a response that isn't an array", func(t *testing.T) {
+		server := newServer(`{"error":"bad"}`, false)
+		defer server.Close()
+		client := NewHyperliquidClient()
+		client.apiURL = server.URL
+
+		if _, err := client.fetchBatch(context.Background(), 1, "0xabc", 0, 1); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	for _, chunked := range []bool{false, true} {
+		t.Run(fmt.Sprintf("should reject oversized responses (chunked %v)", chunked), func(t *testing.T) {
+			body := "[" + strings.Repeat(fill+",", 20) + fill + "]"
+			server := newServer(body, chunked)
+			defer server.Close()
+			client := NewHyperliquidClient()
+			client.apiURL = server.URL
+			client.maxResponseBytes = int64(len(body)) - 1
+
+			if _, err := client.fetchBatch(context.Background(), 1, "0xabc", 0, 1); !errors.Is(err, ErrResponseTooLarge) {
+				t.Errorf("Expected ErrResponseTooLarge, got %v", err)
+			}
+
+			client.maxResponseBytes = int64(len(body))
+			if fills, err := client.fetchBatch(context.Background(), 1, "0xabc", 0, 1); err != nil || len(fills) != 21 {
+				t.Errorf("Expected 21 fills at exactly the limit, got %d: %v", len(fills), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"io"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Export file names carry the address either as a partition directory
+// (address=0x...) or in the download file name (trades_0x....csv)
+var (
+	partitionAddressPattern = regexp.MustCompile(`^address=(0x[0-9a-fA-F]{40})$`)
+	fileAddressPattern      = regexp.MustCompile(`^trades_(0x[0-9a-fA-F]{40})\.(csv|parquet)$`)
+)
+
+// legacyTradeColumns is the number of columns in the oldest trade CSV exports
+const legacyTradeColumns = 6
+
+// ReadTradesCSV reads and validates trades in the CSV export format
+func ReadTradesCSV(r io.Reader) ([]models.Trade, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#' // The build footer
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	// Older exports stop before the fee, startPosition and realizedPnl columns, so any
+	// prefix of the current header that includes value is accepted
+	columns := len(header)
+	if columns < legacyTradeColumns || columns > len(tradesCSVHeader) ||
+		strings.Join(header, ",") != strings.Join(tradesCSVHeader[:columns], ",") {
+		return nil, fmt.Errorf("unexpected CSV header %q, want %q", strings.Join(header, ","), strings.Join(tradesCSVHeader, ","))
+	}
+
+	var trades []models.Trade
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		tradeTime, err := time.Parse(time.RFC3339Nano, record[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid time %q", line, record[0])
+		}
+		price, err := models.ParseMoney(record[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid px %q", line, record[3])
+		}
+		size, err := models.ParseQuantity(record[4])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid sz %q", line, record[4])
+		}
+		value, err := models.ParseMoney(record[5])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid value %q", line, record[5])
+		}
+
+		var fee models.Money
+		if columns > 6 {
+			fee, err = models.ParseMoney(record[6])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid fee %q", line, record[6])
+			}
+		}
+		var startPosition *models.Position
+		if columns > 7 && record[7] != "" {
+			parsed, err := models.ParsePosition(record[7])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid startPosition %q", line, record[7])
+			}
+			startPosition = &parsed
+		}
+		var realizedPnL *models.Money
+		if columns > 8 && record[8] != "" {
+			parsed, err := models.ParseMoney(record[8])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid realizedPnl %q", line, record[8])
+			}
+			realizedPnL = &parsed
+		}
+
+		trade := models.Trade{Time: tradeTime.Local(), Coin: record[1], Side: record[2], Price: price, Size: size, Value: value, Fee: fee, StartPosition: startPosition, RealizedPnL: realizedPnL}
+		if err := validateImportedTrade(trade); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		trades = append(trades, trade)
+	}
+
+	return trades, nil
+}
+
+// ReadTradesParquet reads and validates trades in the Parquet export format
+func ReadTradesParquet(r io.ReaderAt, size int64) ([]models.Trade, error) {
+	rows, err := parquet.Read[tradeRow](r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Parquet: %w", err)
+	}
+
+	trades := make([]models.Trade, 0, len(rows))
+	for i, row := range rows {
+		trade := models.Trade{
+			Time:  time.UnixMilli(row.Time),
+			Coin:  row.Coin,
+			Side:  row.Side,
+			Price: models.Money(row.Price).Round(),
+			Size:  models.Quantity(row.Size).Round(),
+			Value: models.Money(row.Value).Round(),
+			Fee:   models.Money(row.Fee).Round(),
+		}
+		if row.StartPosition != nil {
+			position := models.Position(*row.StartPosition).Round()
+			trade.StartPosition = &position
+		}
+		if row.RealizedPnL != nil {
+			pnl := models.Money(*row.RealizedPnL).Round()
+			trade.RealizedPnL = &pnl
+		}
+		if err := validateImportedTrade(trade); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		trades = append(trades, trade)
+	}
+
+	return trades, nil
+}
+
+// DecodeTrades reads trades from an export file body in the given format
+func DecodeTrades(format string, body []byte) ([]models.Trade, error) {
+	switch format {
+	case FormatCSV:
+		return ReadTradesCSV(bytes.NewReader(body))
+	case FormatParquet:
+		return ReadTradesParquet(bytes.NewReader(body), int64(len(body)))
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// validateImportedTrade rejects trades that could not have come from the exchange
+func validateImportedTrade(trade models.Trade) error {
+	if trade.Coin == "" {
+		return errors.New("missing coin")
+	}
+	if trade.Side != "B" && trade.Side != "A" {
+		return fmt.Errorf("invalid side %q", trade.Side)
+	}
+	if trade.Time.IsZero() || trade.Time.After(time.Now()) {
+		return fmt.Errorf("invalid time %s", trade.Time.Format(time.RFC3339))
+	}
+	for name, v := range map[string]float64{"px": float64(trade.Price), "sz": float64(trade.Size), "value": float64(trade.Value)} {
+		if math.IsNaN(v) || math.IsInf(v, 0) || v <= 0 {
+			return fmt.Errorf("invalid %s %v", name, v)
+		}
+	}
+	fee := float64(trade.Fee)
+	if math.IsNaN(fee) || math.IsInf(fee, 0) {
+		return fmt.Errorf("invalid fee %v", trade.Fee)
+	}
+	if trade.StartPosition != nil && (math.IsNaN(float64(*trade.StartPosition)) || math.IsInf(float64(*trade.StartPosition), 0)) {
+		return fmt.Errorf("invalid startPosition %v", *trade.StartPosition)
+	}
+	if trade.RealizedPnL != nil && (math.IsNaN(float64(*trade.RealizedPnL)) || math.IsInf(float64(*trade.RealizedPnL), 0)) {
+		return fmt.Errorf("invalid realizedPnl %v", *trade.RealizedPnL)
+	}
+	notional := float64(trade.Price) * float64(trade.Size)
+	if math.Abs(float64(trade.Value)-notional) > 1e-6*math.Max(1, float64(trade.Value)) {
+		return fmt.Errorf("value %v does not match px*sz %v", trade.Value, notional)
+	}
+	return nil
+}
+
+// ImportTrades merges validated, previously exported trades for address into its
+// cache, dropping duplicates. Imported history is trusted by the next refresh,
+// which only fetches trades newer than the newest imported one. Coins are
+// translated to the symbols Hyperliquid fills use, so files written under
+// canonical names or another source's tickers merge with fetched fills.
+func (rs *ReconciliationService) ImportTrades(address string, trades []models.Trade) models.ImportResult {
+	result := models.ImportResult{Address: address, Label: rs.Label(address), TradesRead: len(trades)}
+	if len(trades) == 0 {
+		return result
+	}
+	trades = append([]models.Trade(nil), trades...)
+	toExchange(trades)
+
+	// Without a deadline the wait can't fail
+	unlock, _ := rs.lockAddress(context.Background(), address)
+	defer unlock()
+
+	var before int
+	if cache, exists := rs.cached(address); exists {
+		before = len(cache.trades)
+	}
+
+	cache := rs.record(context.Background(), models.TradeEvent{Type: models.EventTradesImported, Address: address, Trades: trades})
+	result.TradesAdded = len(cache.trades) - before
+	result.Duplicates = result.TradesRead - result.TradesAdded
+
+	rs.checkPeriods(address, cache, "import")
+
+	log.Printf("Imported %d trades for %s (%d new, %d duplicates)", result.TradesRead, address, result.TradesAdded, result.Duplicates)
+	return result
+}
+
+// ImportDirectory imports every trade export file found under dir. Files are
+// recognised by the layouts this service writes: S3 partitions
+// (.../address=<address>/.../trades.<format>) and downloads (trades_<address>.<format>).
+func (rs *ReconciliationService) ImportDirectory(dir string) ([]models.ImportResult, error) {
+	tradesByAddress := make(map[string][]models.Trade)
+	files := make(map[string]int)
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		address, format := exportFileInfo(path)
+		if address == "" {
+			return nil
+		}
+
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		trades, err := DecodeTrades(format, body)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		tradesByAddress[address] = append(tradesByAddress[address], trades...)
+		files[address]++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.ImportResult, 0, len(tradesByAddress))
+	for address, trades := range tradesByAddress {
+		result := rs.ImportTrades(address, trades)
+		result.Files = files[address]
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// exportFileInfo returns the address and format of a trade export file, or an
+// empty address if path is not one
+func exportFileInfo(path string) (string, string) {
+	name := filepath.Base(path)
+	if match := fileAddressPattern.FindStringSubmatch(name); match != nil {
+		return strings.ToLower(match[1]), match[2]
+	}
+
+	if name != "trades."+FormatCSV && name != "trades."+FormatParquet {
+		return "", ""
+	}
+	format := strings.TrimPrefix(filepath.Ext(name), ".")
+
+	for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if match := partitionAddressPattern.FindStringSubmatch(filepath.Base(dir)); match != nil {
+			return strings.ToLower(match[1]), format
+		}
+	}
+	return "", ""
+}
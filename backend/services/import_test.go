@@ -0,0 +1,141 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+const testAddress = "0x091144e651b334341eabdbbbfed644ad0100023e"
+
+// Test DecodeTrades validation
+func TestDecodeTrades(t *testing.T) {
+	trades := []models.Trade{
+		createTestTrade("2025-01-01T10:00:00Z", "BTC", "B", 50000, 0.5),
+		createTestTrade("2025-01-01T11:00:00Z", "ETH", "A", 3000, 2),
+	}
+
+	for _, format := range []string{FormatCSV, FormatParquet} {
+		t.Run("should round-trip "+format+" exports", func(t *testing.T) {
+			body, _ := EncodeTrades(format, trades)
+			decoded, err := DecodeTrades(format, body)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if len(decoded) != 2 || !decoded[1].Time.Equal(trades[1].Time) || decoded[1].Value != 6000 {
+				t.Errorf("Unexpected trades: %+v", decoded)
+			}
+		})
+	}
+
+	t.Run("should read exports made before fees were recorded", func(t *testing.T) {
+		legacyCSV := "time,coin,side,px,sz,value\n2025-01-01T10:00:00Z,BTC,B,2,2,4\n"
+		if decoded, err := DecodeTrades(FormatCSV, []byte(legacyCSV)); err != nil || len(decoded) != 1 {
+			t.Errorf("Expected legacy CSV to decode, got %v (%v)", decoded, err)
+		}
+
+		type legacyRow struct {
+			Time  int64   `parquet:"time,timestamp(millisecond)"`
+			Coin  string  `parquet:"coin,dict"`
+			Side  string  `parquet:"side,dict"`
+			Price float64 `parquet:"px"`
+			Size  float64 `parquet:"sz"`
+			Value float64 `parquet:"value"`
+		}
+		var buf bytes.Buffer
+		parquet.Write(&buf, []legacyRow{{Time: trades[0].Time.UnixMilli(), Coin: "BTC", Side: "B", Price: 50000, Size: 0.5, Value: 25000}})
+		if decoded, err := DecodeTrades(FormatParquet, buf.Bytes()); err != nil || len(decoded) != 1 || decoded[0].Fee != 0 {
+			t.Errorf("Expected legacy Parquet to decode, got %v (%v)", decoded, err)
+		}
+	})
+
+	invalid := map[string]string{
+		"bad header": "when,coin,side,px,sz,value\n",
+		"bad side":   "time,coin,side,px,sz,value\n2025-01-01T10:00:00Z,BTC,X,1,1,1\n",
+		"bad size":   "time,coin,side,px,sz,value\n2025-01-01T10:00:00Z,BTC,B,1,-1,-1\n",
+		"bad value":  "time,coin,side,px,sz,value\n2025-01-01T10:00:00Z,BTC,B,2,2,5\n",
+	}
+	for name, body := range invalid {
+		t.Run("should reject "+name, func(t *testing.T) {
+			if _, err := DecodeTrades(FormatCSV, []byte(body)); err == nil {
+				t.Errorf("Expected validation error")
+			}
+		})
+	}
+}
+
+// Test ImportDirectory and the refresh that follows it
+func TestImportDirectory(t *testing.T) {
+	dir := t.TempDir()
+	newest := time.Now().Add(-48 * time.Hour).Truncate(time.Millisecond)
+	trades := []models.Trade{
+		{Time: newest.Add(-24 * time.Hour), Coin: "BTC", Side: "B", Price: 50000, Size: 1, Value: 50000},
+		{Time: newest, Coin: "BTC", Side: "A", Price: 51000, Size: 1, Value: 51000},
+	}
+
+	partition := filepath.Join(dir, "trades", "address="+testAddress, "date=2025-01-01")
+	os.MkdirAll(partition, 0755)
+	csvBody, _ := EncodeTrades(FormatCSV, trades)
+	os.WriteFile(filepath.Join(partition, "trades.csv"), csvBody, 0644)
+
+	// The same trades again as a download, to check deduplication
+	parquetBody, _ := EncodeTrades(FormatParquet, trades)
+	os.WriteFile(filepath.Join(dir, "trades_"+testAddress+".parquet"), parquetBody, 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644)
+
+	var requestedStart int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req UserFillsRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requestedStart = *req.StartTime
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = server.URL
+
+	results, err := rs.ImportDirectory(dir)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Files != 2 || results[0].TradesAdded != 2 || results[0].Duplicates != 2 {
+		t.Fatalf("Unexpected import results: %+v", results)
+	}
+
+	if err := rs.FetchAndReconcile(context.Background(), testAddress, 3); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if requestedStart != newest.UnixMilli()+1 {
+		t.Errorf("Expected refresh to start after newest imported trade, started at %d", requestedStart)
+	}
+	if summary := rs.GetPnLSummary(); len(summary.DailyRecords) == 0 {
+		t.Errorf("Expected imported trades in summary")
+	}
+}
+
+// Test exportFileInfo
+func TestExportFileInfo(t *testing.T) {
+	upper := strings.ToUpper(testAddress[2:])
+	cases := map[string]string{
+		"data/trades/address=" + testAddress + "/date=2025-01-01/trades.parquet": testAddress,
+		"downloads/trades_0x" + upper + ".csv":                                   testAddress,
+		"data/trades/address=" + testAddress + "/date=2025-01-01/other.csv":      "",
+		"data/trades/trades.csv":                                                 "",
+	}
+	for path, expected := range cases {
+		if address, _ := exportFileInfo(path); address != expected {
+			t.Errorf("%s: expected address %q, got %q", path, expected, address)
+		}
+	}
+}
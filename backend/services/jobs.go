@@ -0,0 +1,447 @@
+package services
+
+import (
+	"container/heap"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"log"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// JobManager runs refreshes, cache rebuilds and exports asynchronously and
+// keeps track of their status.
+// Submitted jobs wait in a queue that workers take high-priority jobs from
+// first, oldest first within a priority.
+type JobManager struct {
+	jobs         map[string]*models.RefreshJob   // key: job ID
+	batches      map[string]*models.RefreshBatch // key: batch ID; item jobs point into jobs
+	queue        jobQueue
+	queued       int64         // Jobs ever queued, to keep the queue first-in first-out within a priority
+	retention    time.Duration // How long finished jobs and batches are kept
+	ready        *sync.Cond    // Signalled when a job is queued; uses mu
+	mu           sync.RWMutex
+	reconService *ReconciliationService
+	webhooks     *WebhookDispatcher
+}
+
+// queuedJob is a job waiting for a worker
+type queuedJob struct {
+	ctx  context.Context
+	job  *models.RefreshJob
+	seq  int64
+	done func() // Optional; called once the job has run
+	// export is what an export job writes
+	export ExportRequest
+}
+
+// jobQueue is a heap of queued jobs, high priority first and then in the order queued
+type jobQueue []*queuedJob
+
+func (q jobQueue) Len() int { return len(q) }
+func (q jobQueue) Less(i, j int) bool {
+	if q[i].job.Priority != q[j].job.Priority {
+		return q[i].job.Priority == models.PriorityHigh
+	}
+	return q[i].seq < q[j].seq
+}
+func (q jobQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *jobQueue) Push(x interface{}) { *q = append(*q, x.(*queuedJob)) }
+func (q *jobQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// NewJobManager creates a job manager that runs refreshes on reconService and
+// delivers job callbacks through webhooks
+func NewJobManager(reconService *ReconciliationService, webhooks *WebhookDispatcher) *JobManager {
+	jm := &JobManager{
+		jobs:         make(map[string]*models.RefreshJob),
+		batches:      make(map[string]*models.RefreshBatch),
+		retention:    config.JobRetention,
+		reconService: reconService,
+		webhooks:     webhooks,
+	}
+	jm.ready = sync.NewCond(&jm.mu)
+
+	for i := 0; i < config.JobWorkers; i++ {
+		go jm.worker()
+	}
+
+	return jm
+}
+
+// SubmitRefresh queues a background refresh of address at priority and
+// returns the new job. If callbackURL is set, the job and resulting summary are
+// POSTed to it when the refresh finishes. The refresh is traced as part of
+// ctx's trace but is not cancelled with it.
+func (jm *JobManager) SubmitRefresh(ctx context.Context, address string, days int, priority models.JobPriority, callbackURL string) models.RefreshJob {
+	job := &models.RefreshJob{
+		ID:          newID(),
+		Kind:        models.JobRefresh,
+		Address:     address,
+		Label:       jm.reconService.Label(address),
+		Days:        days,
+		Priority:    priority,
+		Status:      models.JobPending,
+		CallbackURL: callbackURL,
+		CreatedAt:   time.Now(),
+	}
+
+	jm.mu.Lock()
+	jm.pruneFinished()
+	jm.jobs[job.ID] = job
+	jm.enqueue(context.WithoutCancel(ctx), job, nil)
+	submitted := *job
+	jm.mu.Unlock()
+
+	return submitted
+}
+
+// SubmitCacheRebuild queues a low-priority background rebuild of the cache of
+// address for the last days days and returns the new job; see
+// ReconciliationService.RebuildCache. The job's Rebuild is set when it finishes.
+func (jm *JobManager) SubmitCacheRebuild(ctx context.Context, address string, days int) models.RefreshJob {
+	job := &models.RefreshJob{
+		ID:        newID(),
+		Kind:      models.JobCacheRebuild,
+		Address:   address,
+		Label:     jm.reconService.Label(address),
+		Days:      days,
+		Priority:  models.PriorityLow,
+		Status:    models.JobPending,
+		CreatedAt: time.Now(),
+	}
+
+	jm.mu.Lock()
+	jm.pruneFinished()
+	jm.jobs[job.ID] = job
+	jm.enqueue(context.WithoutCancel(ctx), job, nil)
+	submitted := *job
+	jm.mu.Unlock()
+
+	return submitted
+}
+
+// SubmitExport queues a low-priority background export and returns the new
+// job. The export is written to the download store in chunks, and the job's
+// Export reports its progress and, once it has succeeded, the download.
+func (jm *JobManager) SubmitExport(ctx context.Context, req ExportRequest) models.RefreshJob {
+	job := &models.RefreshJob{
+		ID:        newID(),
+		Kind:      models.JobExport,
+		Address:   req.Address,
+		Label:     jm.reconService.Label(req.Address),
+		Priority:  models.PriorityLow,
+		Status:    models.JobPending,
+		Export:    &models.ExportJob{Dataset: req.Dataset, Format: req.Format},
+		CreatedAt: time.Now(),
+	}
+
+	jm.mu.Lock()
+	jm.pruneFinished()
+	jm.jobs[job.ID] = job
+	jm.queued++
+	heap.Push(&jm.queue, &queuedJob{ctx: context.WithoutCancel(ctx), job: job, seq: jm.queued, export: req})
+	jm.ready.Signal()
+	submitted := *job
+	jm.mu.Unlock()
+
+	return submitted
+}
+
+// SubmitBatch queues a background refresh at priority of every item that has no
+// Error and returns the new batch. The refreshes are queued in the order given;
+// each is also a job of its own. If callbackURL is set, the batch is POSTed to
+// it once every refresh has finished.
+func (jm *JobManager) SubmitBatch(ctx context.Context, items []models.RefreshBatchItem, priority models.JobPriority, callbackURL string) models.RefreshBatch {
+	batch := &models.RefreshBatch{
+		ID:          newID(),
+		Items:       items,
+		CallbackURL: callbackURL,
+		CreatedAt:   time.Now(),
+	}
+
+	var jobs []*models.RefreshJob
+	for i := range batch.Items {
+		item := &batch.Items[i]
+		if item.Error != "" {
+			continue
+		}
+		item.Job = &models.RefreshJob{
+			ID:        newID(),
+			Kind:      models.JobRefresh,
+			Address:   item.Address,
+			Label:     jm.reconService.Label(item.Address),
+			Days:      item.Days,
+			Priority:  priority,
+			Status:    models.JobPending,
+			CreatedAt: batch.CreatedAt,
+		}
+		jobs = append(jobs, item.Job)
+	}
+
+	ctx, span := tracer.Start(context.WithoutCancel(ctx), "JobManager.runBatch",
+		trace.WithAttributes(attribute.String("batch", batch.ID), attribute.Int("jobs", len(jobs))))
+
+	var pending sync.WaitGroup
+	pending.Add(len(jobs))
+
+	jm.mu.Lock()
+	jm.pruneFinished()
+	jm.batches[batch.ID] = batch
+	for _, job := range jobs {
+		jm.jobs[job.ID] = job
+		jm.enqueue(ctx, job, pending.Done)
+	}
+	submitted := snapshotBatch(batch)
+	jm.mu.Unlock()
+
+	go func() {
+		defer span.End()
+		pending.Wait()
+		jm.finishBatch(batch)
+	}()
+
+	return submitted
+}
+
+// GetBatch returns a copy of the batch with the given ID and the current status of its jobs
+func (jm *JobManager) GetBatch(id string) (models.RefreshBatch, bool) {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+
+	batch, exists := jm.batches[id]
+	if !exists {
+		return models.RefreshBatch{}, false
+	}
+	return snapshotBatch(batch), true
+}
+
+// finishBatch marks a batch whose jobs have all run as finished and delivers its callback
+func (jm *JobManager) finishBatch(batch *models.RefreshBatch) {
+	jm.mu.Lock()
+	finishedAt := time.Now()
+	batch.FinishedAt = &finishedAt
+	finished := snapshotBatch(batch)
+	jm.mu.Unlock()
+
+	log.Printf("Refresh batch %s finished: %s (%d items)", batch.ID, finished.Status, len(finished.Items))
+	if batch.CallbackURL == "" {
+		return
+	}
+	if _, err := jm.webhooks.Enqueue(batch.CallbackURL, "refresh.batch.completed", finished); err != nil {
+		log.Printf("Callback for batch %s to %s failed: %v", batch.ID, batch.CallbackURL, err)
+	}
+}
+
+// GetJob returns a copy of the job with the given ID
+func (jm *JobManager) GetJob(id string) (models.RefreshJob, bool) {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+
+	job, exists := jm.jobs[id]
+	if !exists {
+		return models.RefreshJob{}, false
+	}
+	return *job, true
+}
+
+// Len returns the number of jobs kept for status lookups
+func (jm *JobManager) Len() int {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	return len(jm.jobs)
+}
+
+// enqueue queues job to run with ctx, calling done (if set) after it has run;
+// caller must hold jm.mu
+func (jm *JobManager) enqueue(ctx context.Context, job *models.RefreshJob, done func()) {
+	jm.queued++
+	heap.Push(&jm.queue, &queuedJob{ctx: ctx, job: job, seq: jm.queued, done: done})
+	jm.ready.Signal()
+}
+
+// worker runs queued jobs, highest priority first; it never returns
+func (jm *JobManager) worker() {
+	for {
+		jm.mu.Lock()
+		for jm.queue.Len() == 0 {
+			jm.ready.Wait()
+		}
+		next := heap.Pop(&jm.queue).(*queuedJob)
+		jm.mu.Unlock()
+
+		jm.run(next.ctx, next.job, next.export)
+		if next.done != nil {
+			next.done()
+		}
+	}
+}
+
+// run executes a job, writing export if it is an export job, and delivers its
+// callback. Its upstream requests are made at the job's priority.
+func (jm *JobManager) run(ctx context.Context, job *models.RefreshJob, export ExportRequest) {
+	ctx, span := tracer.Start(ctx, "JobManager.run",
+		trace.WithAttributes(attribute.String("job", job.ID), attribute.String("kind", string(job.Kind)), attribute.String("priority", string(job.Priority))))
+	defer span.End()
+	ctx = WithPriority(ctx, job.Priority)
+
+	jm.setStatus(job, models.JobRunning, nil)
+
+	var err error
+	switch job.Kind {
+	case models.JobCacheRebuild:
+		var rebuild models.CacheRebuild
+		rebuild, err = jm.reconService.RebuildCache(ctx, job.Address, job.Days)
+		jm.mu.Lock()
+		job.Rebuild = &rebuild
+		jm.mu.Unlock()
+	case models.JobExport:
+		err = jm.runExport(ctx, job, export)
+		if err != nil {
+			log.Printf("Export job %s for %s failed: %v", job.ID, job.Address, err)
+		}
+	default:
+		err = jm.reconService.FetchAndReconcile(ctx, job.Address, job.Days)
+	}
+
+	var partial *PartialError
+	switch {
+	case errors.As(err, &partial):
+		jm.setStatus(job, models.JobPartial, err)
+	case err != nil:
+		jm.setStatus(job, models.JobFailed, err)
+	default:
+		jm.setStatus(job, models.JobSucceeded, nil)
+	}
+
+	if job.CallbackURL == "" {
+		return
+	}
+
+	jm.mu.RLock()
+	callback := models.RefreshCallback{Job: *job}
+	jm.mu.RUnlock()
+
+	if callback.Job.Status != models.JobFailed {
+		summary := jm.reconService.GetPnLSummary()
+		callback.Summary = &summary
+	}
+
+	if _, err := jm.webhooks.Enqueue(job.CallbackURL, "refresh.completed", callback); err != nil {
+		log.Printf("Callback for job %s to %s failed: %v", job.ID, job.CallbackURL, err)
+	}
+}
+
+// setStatus updates a job's status, recording the error and finish time for terminal states
+func (jm *JobManager) setStatus(job *models.RefreshJob, status models.JobStatus, err error) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	job.Status = status
+	if err != nil {
+		job.Error = err.Error()
+	}
+	if status != models.JobPending && status != models.JobRunning {
+		finishedAt := time.Now()
+		job.FinishedAt = &finishedAt
+	}
+}
+
+// SetRetention sets how long finished jobs and batches are kept
+func (jm *JobManager) SetRetention(retention time.Duration) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.retention = retention
+}
+
+// Prune deletes the jobs and batches that finished before cutoff and returns
+// how many there were; a dry run only counts them
+func (jm *JobManager) Prune(cutoff time.Time, dryRun bool) int {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	return jm.pruneBefore(cutoff, dryRun)
+}
+
+// pruneFinished drops finished jobs and batches older than the retention period; caller must hold jm.mu
+func (jm *JobManager) pruneFinished() {
+	jm.pruneBefore(time.Now().Add(-jm.retention), false)
+}
+
+// pruneBefore drops, or only counts, the jobs and batches that finished before cutoff; caller must hold jm.mu
+func (jm *JobManager) pruneBefore(cutoff time.Time, dryRun bool) int {
+	pruned := 0
+	for id, job := range jm.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			if !dryRun {
+				delete(jm.jobs, id)
+			}
+			pruned++
+		}
+	}
+	for id, batch := range jm.batches {
+		if batch.FinishedAt != nil && batch.FinishedAt.Before(cutoff) {
+			if !dryRun {
+				delete(jm.batches, id)
+			}
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// snapshotBatch returns a copy of batch with copies of its jobs and its
+// status and counts derived from them; caller must hold jm.mu
+func snapshotBatch(batch *models.RefreshBatch) models.RefreshBatch {
+	snapshot := *batch
+	snapshot.Items = make([]models.RefreshBatchItem, len(batch.Items))
+	snapshot.Counts = make(map[models.JobStatus]int)
+
+	rejected := 0
+	for i, item := range batch.Items {
+		if item.Job != nil {
+			job := *item.Job
+			item.Job = &job
+			snapshot.Counts[job.Status]++
+		} else {
+			rejected++
+			snapshot.Counts[models.JobFailed]++
+		}
+		snapshot.Items[i] = item
+	}
+
+	total := len(batch.Items)
+	switch {
+	case snapshot.FinishedAt == nil && snapshot.Counts[models.JobPending] == total-rejected:
+		snapshot.Status = models.JobPending
+	case snapshot.FinishedAt == nil:
+		snapshot.Status = models.JobRunning
+	case snapshot.Counts[models.JobSucceeded] == total:
+		snapshot.Status = models.JobSucceeded
+	case snapshot.Counts[models.JobFailed] == total:
+		snapshot.Status = models.JobFailed
+	default:
+		snapshot.Status = models.JobPartial
+	}
+	return snapshot
+}
+
+// newID returns a random hex identifier for jobs and deliveries
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand only fails if the OS entropy source is unavailable
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
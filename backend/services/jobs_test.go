@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"hyperliquid-recon/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test SubmitRefresh with a callback URL
+func TestSubmitRefreshCallback(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer upstream.Close()
+
+	type delivery struct {
+		timestamp string
+		signature string
+		body      []byte
+	}
+	received := make(chan delivery, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- delivery{
+			timestamp: r.Header.Get(TimestampHeader),
+			signature: r.Header.Get(SignatureHeader),
+			body:      body,
+		}
+	}))
+	defer receiver.Close()
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = upstream.URL
+	jm := NewJobManager(rs, NewWebhookDispatcher("secret"))
+
+	job := jm.SubmitRefresh(context.Background(), "0xabc", 1, models.PriorityHigh, receiver.URL)
+	if job.Status != models.JobPending {
+		t.Errorf("Expected new job to be pending, got %s", job.Status)
+	}
+
+	var got delivery
+	select {
+	case got = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Callback was not delivered")
+	}
+
+	expected := "sha256=" + SignPayload([]byte("secret"), got.timestamp, got.body)
+	if got.signature != expected {
+		t.Errorf("Expected signature %s, got %s", expected, got.signature)
+	}
+
+	var callback models.RefreshCallback
+	if err := json.Unmarshal(got.body, &callback); err != nil {
+		t.Fatalf("Invalid callback body: %v", err)
+	}
+	if callback.Job.ID != job.ID || callback.Job.Status != models.JobSucceeded {
+		t.Errorf("Expected succeeded job %s, got %s %s", job.ID, callback.Job.ID, callback.Job.Status)
+	}
+	if callback.Summary == nil {
+		t.Errorf("Expected summary in callback")
+	}
+
+	stored, exists := jm.GetJob(job.ID)
+	if !exists || stored.FinishedAt == nil {
+		t.Errorf("Expected finished job to be retrievable")
+	}
+}
+
+// Test failed refresh jobs
+func TestSubmitRefreshFailure(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer upstream.Close()
+
+	received := make(chan models.RefreshCallback, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var callback models.RefreshCallback
+		json.NewDecoder(r.Body).Decode(&callback)
+		received <- callback
+	}))
+	defer receiver.Close()
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = upstream.URL
+	jm := NewJobManager(rs, NewWebhookDispatcher(""))
+
+	jm.SubmitRefresh(context.Background(), "0xabc", 1, models.PriorityHigh, receiver.URL)
+
+	select {
+	case callback := <-received:
+		if callback.Job.Status != models.JobFailed || callback.Job.Error == "" {
+			t.Errorf("Expected failed job with error, got %s", callback.Job.Status)
+		}
+		if callback.Summary != nil {
+			t.Errorf("Expected no summary for failed job")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Callback was not delivered")
+	}
+}
+
+// Test a batch of refreshes with a rejected item and a failing one
+func TestSubmitBatch(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request UserFillsRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		if request.User == "0xdef" {
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer upstream.Close()
+
+	received := make(chan models.RefreshBatch, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch models.RefreshBatch
+		json.NewDecoder(r.Body).Decode(&batch)
+		received <- batch
+	}))
+	defer receiver.Close()
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = upstream.URL
+	jm := NewJobManager(rs, NewWebhookDispatcher(""))
+
+	batch := jm.SubmitBatch(context.Background(), []models.RefreshBatchItem{
+		{Address: "0xabc", Days: 1},
+		{Address: "bad", Days: 1, Error: "invalid address"},
+		{Address: "0xdef", Days: 2},
+	}, models.PriorityLow, receiver.URL)
+	if batch.Status != models.JobPending || batch.Counts[models.JobPending] != 2 || batch.Items[1].Job != nil {
+		t.Errorf("Expected two pending jobs and a rejected item, got %+v", batch)
+	}
+
+	select {
+	case finished := <-received:
+		if finished.ID != batch.ID || finished.Status != models.JobPartial || finished.FinishedAt == nil {
+			t.Errorf("Expected partial finished batch %s, got %s %s", batch.ID, finished.ID, finished.Status)
+		}
+		if finished.Counts[models.JobSucceeded] != 1 || finished.Counts[models.JobFailed] != 2 {
+			t.Errorf("Unexpected counts %v", finished.Counts)
+		}
+		if job := finished.Items[2].Job; job == nil || job.Status != models.JobFailed || job.Days != 2 {
+			t.Errorf("Expected the third item's job to fail, got %+v", job)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Batch callback was not delivered")
+	}
+
+	if _, exists := jm.GetJob(batch.Items[0].Job.ID); !exists {
+		t.Error("Expected batch jobs to be retrievable as jobs")
+	}
+	if stored, exists := jm.GetBatch(batch.ID); !exists || stored.Status != models.JobPartial {
+		t.Errorf("Expected finished batch to be retrievable, got %+v", stored)
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"sync"
+	"time"
+)
+
+// Reasons a key policy refuses a refresh; PolicyError wraps them
+var (
+	ErrRangeNotAllowed = errors.New("history range not allowed for this API key")
+	ErrRefreshTooSoon  = errors.New("refreshed too recently for this API key")
+)
+
+// ErrInvalidDays is returned for a refresh of no days or a negative number of days
+var ErrInvalidDays = errors.New("days must be a positive integer")
+
+// PolicyError is a refresh refused by the policy of the API key's role
+type PolicyError struct {
+	Role    string
+	Reason  error     // ErrRangeNotAllowed or ErrRefreshTooSoon
+	Days    int       // Days requested
+	MaxDays int       // Set for ErrRangeNotAllowed
+	Address string    // Set for ErrRefreshTooSoon
+	RetryAt time.Time // Set for ErrRefreshTooSoon; when the address may be refreshed again
+}
+
+func (e *PolicyError) Error() string {
+	if errors.Is(e.Reason, ErrRefreshTooSoon) {
+		return fmt.Sprintf("%s keys may not refresh %s again until %s", e.Role, e.Address, e.RetryAt.UTC().Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s keys may refresh at most %d days of history, not %d", e.Role, e.MaxDays, e.Days)
+}
+
+func (e *PolicyError) Unwrap() error {
+	return e.Reason
+}
+
+// KeyPolicy enforces a key role's limits on refreshes. Every key of the role
+// shares one policy, so the refresh interval applies to the role, not to each
+// key. A nil policy allows everything, as for keys without a role.
+type KeyPolicy struct {
+	role        string
+	maxDays     int
+	interval    time.Duration
+	refreshedAt map[string]time.Time // key: address
+	mu          sync.Mutex
+}
+
+// keyPolicyKey is the context key of the policy of the API key a request was made with
+type keyPolicyKey struct{}
+
+// WithKeyPolicy returns a context whose refreshes are limited by policy
+func WithKeyPolicy(ctx context.Context, policy *KeyPolicy) context.Context {
+	return context.WithValue(ctx, keyPolicyKey{}, policy)
+}
+
+// KeyPolicyFrom returns the policy limiting ctx's refreshes, or nil if they
+// are unlimited, as for scheduled work and keys without a role
+func KeyPolicyFrom(ctx context.Context) *KeyPolicy {
+	policy, _ := ctx.Value(keyPolicyKey{}).(*KeyPolicy)
+	return policy
+}
+
+// NewKeyPolicy creates the policy of role, checking its limits
+func NewKeyPolicy(role models.KeyRole) (*KeyPolicy, error) {
+	if role.Name == "" {
+		return nil, errors.New("role name is required")
+	}
+	if role.MaxDays < 0 {
+		return nil, fmt.Errorf("role %q: maxDays must not be negative", role.Name)
+	}
+	var interval time.Duration
+	if role.MinRefreshInterval != "" {
+		var err error
+		interval, err = time.ParseDuration(role.MinRefreshInterval)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("role %q: minRefreshInterval %q must be a positive duration such as 15m", role.Name, role.MinRefreshInterval)
+		}
+	}
+	return &KeyPolicy{role: role.Name, maxDays: role.MaxDays, interval: interval, refreshedAt: make(map[string]time.Time)}, nil
+}
+
+// Role returns the name of the policy's role, or "" for a nil policy
+func (p *KeyPolicy) Role() string {
+	if p == nil {
+		return ""
+	}
+	return p.role
+}
+
+// CheckDays returns a *PolicyError if a refresh of days is longer than the
+// role allows
+func (p *KeyPolicy) CheckDays(days int) error {
+	if p == nil || p.maxDays == 0 || days <= p.maxDays {
+		return nil
+	}
+	return &PolicyError{Role: p.role, Reason: ErrRangeNotAllowed, Days: days, MaxDays: p.maxDays}
+}
+
+// CheckRefresh returns a *PolicyError if a refresh of days of address at now
+// is longer than the role allows, or comes too soon after the role's last
+// recorded refresh of address. It doesn't count as a refresh; RecordRefresh
+// does once the refresh has gone ahead.
+func (p *KeyPolicy) CheckRefresh(address string, days int, now time.Time) error {
+	if err := p.CheckDays(days); err != nil || p == nil || p.interval == 0 {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if last, ok := p.refreshedAt[address]; ok && now.Before(last.Add(p.interval)) {
+		return &PolicyError{Role: p.role, Reason: ErrRefreshTooSoon, Days: days, Address: address, RetryAt: last.Add(p.interval)}
+	}
+	return nil
+}
+
+// RecordRefresh starts the role's refresh interval for address again at now
+func (p *KeyPolicy) RecordRefresh(address string, now time.Time) {
+	if p == nil || p.interval == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if now.After(p.refreshedAt[address]) {
+		p.refreshedAt[address] = now
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test limiting refreshes by the role of an API key
+func TestKeyPolicy(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("should refuse ranges longer than the role allows", func(t *testing.T) {
+		policy, _ := NewKeyPolicy(models.KeyRole{Name: "analyst", MaxDays: 30})
+		if err := policy.CheckRefresh(testAddress, 30, now); err != nil {
+			t.Errorf("Expected 30 days to be allowed, got %v", err)
+		}
+
+		var refusal *PolicyError
+		err := policy.CheckRefresh(testAddress, 31, now)
+		if !errors.As(err, &refusal) || !errors.Is(err, ErrRangeNotAllowed) || refusal.MaxDays != 30 || refusal.Role != "analyst" {
+			t.Errorf("Expected a range refusal, got %v", err)
+		}
+	})
+
+	t.Run("should refuse refreshes of an address too soon after the last", func(t *testing.T) {
+		policy, _ := NewKeyPolicy(models.KeyRole{Name: "analyst", MinRefreshInterval: "15m"})
+		if err := policy.CheckRefresh(testAddress, 10, now); err != nil {
+			t.Fatalf("Expected the first refresh to be allowed, got %v", err)
+		}
+		if err := policy.CheckRefresh(testAddress, 10, now); err != nil {
+			t.Fatalf("Expected a check not to count as a refresh, got %v", err)
+		}
+		policy.RecordRefresh(testAddress, now)
+
+		var refusal *PolicyError
+		err := policy.CheckRefresh(testAddress, 10, now.Add(5*time.Minute))
+		if !errors.As(err, &refusal) || !errors.Is(err, ErrRefreshTooSoon) || !refusal.RetryAt.Equal(now.Add(15*time.Minute)) {
+			t.Errorf("Expected a frequency refusal until 12:15, got %v", err)
+		}
+		if err := policy.CheckRefresh("0xother", 10, now.Add(5*time.Minute)); err != nil {
+			t.Errorf("Expected another address to be allowed, got %v", err)
+		}
+		if err := policy.CheckRefresh(testAddress, 10, now.Add(15*time.Minute)); err != nil {
+			t.Errorf("Expected a refresh once the interval has passed, got %v", err)
+		}
+	})
+
+	t.Run("should allow everything without a role", func(t *testing.T) {
+		var policy *KeyPolicy
+		policy.RecordRefresh(testAddress, now)
+		if err := policy.CheckRefresh(testAddress, 3650, now); err != nil || policy.Role() != "" {
+			t.Errorf("Expected a nil policy to allow any refresh, got %v", err)
+		}
+	})
+
+	t.Run("should only count refreshes that fetched something", func(t *testing.T) {
+		var failing atomic.Bool
+		failing.Store(true)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if failing.Load() {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("[]"))
+		}))
+		defer server.Close()
+
+		rs := NewReconciliationService()
+		rs.hlClient.apiURL = server.URL
+		policy, _ := NewKeyPolicy(models.KeyRole{Name: "analyst", MaxDays: 7, MinRefreshInterval: "1h"})
+		ctx := WithKeyPolicy(context.Background(), policy)
+
+		if _, err := rs.RefreshCache(ctx, testAddress, 30); !errors.Is(err, ErrRangeNotAllowed) {
+			t.Errorf("Expected the range to be refused, got %v", err)
+		}
+		if _, err := rs.RefreshCache(ctx, testAddress, 1); err == nil {
+			t.Fatal("Expected the refresh to fail")
+		}
+		failing.Store(false)
+		if err := rs.FetchAndReconcile(ctx, testAddress, 1); err != nil {
+			t.Fatalf("Expected a failed refresh not to start the interval, got %v", err)
+		}
+		if _, err := rs.RefreshCache(ctx, testAddress, 1); !errors.Is(err, ErrRefreshTooSoon) {
+			t.Errorf("Expected the next refresh to be refused, got %v", err)
+		}
+		if _, err := rs.RefreshCache(context.Background(), testAddress, 1); err != nil {
+			t.Errorf("Expected a refresh without a policy to be allowed, got %v", err)
+		}
+	})
+
+	t.Run("should authenticate role keys with their role's policy", func(t *testing.T) {
+		tenant := &Tenant{ID: "desk"}
+		registry := NewTenantRegistry([]*Tenant{tenant}, []models.TenantConfig{{
+			ID:      "desk",
+			APIKeys: []string{"trader-key"},
+			Roles:   []models.KeyRole{{Name: "analyst", APIKeys: []string{"analyst-1", "analyst-2"}, MaxDays: 30, MinRefreshInterval: "1h"}},
+		}})
+
+		if got, ok := registry.Authenticate("analyst-1"); !ok || got != tenant {
+			t.Fatal("Expected a role key to authenticate as its tenant")
+		}
+		if registry.Policy("trader-key") != nil {
+			t.Error("Expected a key without a role to be unlimited")
+		}
+
+		// The role's keys share one refresh interval
+		registry.Policy("analyst-1").RecordRefresh(testAddress, now)
+		if err := registry.Policy("analyst-2").CheckRefresh(testAddress, 10, now.Add(time.Minute)); !errors.Is(err, ErrRefreshTooSoon) {
+			t.Errorf("Expected the role's other key to be refused, got %v", err)
+		}
+	})
+}
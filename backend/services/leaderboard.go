@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"hyperliquid-recon/models"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LeaderboardWindows are the rolling windows the leaderboard reports, shortest first
+var LeaderboardWindows = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+// Leaderboard sort keys
+const (
+	SortByPnL    = "pnl"
+	SortByVolume = "volume"
+)
+
+// LeaderboardService tracks every address in the address book and ranks them by
+// rolling-window P&L and volume. Stats are only recomputed by Refresh, which the
+// scheduler calls, so reads never hit the exchange API.
+type LeaderboardService struct {
+	reconService *ReconciliationService
+	addressBook  *AddressBook
+	entries      map[string]models.LeaderboardEntry // key: address
+	refreshedAt  time.Time
+	mu           sync.RWMutex
+	refreshing   sync.Mutex // Prevents overlapping refreshes
+}
+
+func NewLeaderboardService(reconService *ReconciliationService, addressBook *AddressBook) *LeaderboardService {
+	return &LeaderboardService{
+		reconService: reconService,
+		addressBook:  addressBook,
+		entries:      make(map[string]models.LeaderboardEntry),
+	}
+}
+
+// Refresh fetches new trades for every tracked address and recomputes its stats.
+// An address whose fetch fails keeps its previous stats along with the error.
+func (ls *LeaderboardService) Refresh() {
+	if !ls.refreshing.TryLock() {
+		log.Printf("Leaderboard refresh already running, skipping")
+		return
+	}
+	defer ls.refreshing.Unlock()
+
+	// Scheduled refreshes give way to interactive ones
+	ctx, span := tracer.Start(WithPriority(context.Background(), models.PriorityLow), "LeaderboardService.Refresh")
+	defer span.End()
+
+	longest := LeaderboardWindows[len(LeaderboardWindows)-1].Duration
+	days := int(longest / (24 * time.Hour))
+
+	tracked := make(map[string]bool)
+	for _, saved := range ls.addressBook.List() {
+		tracked[saved.Address] = true
+
+		trades, err := ls.reconService.RefreshCache(ctx, saved.Address, days)
+
+		var partial *PartialError
+		if err != nil && !errors.As(err, &partial) {
+			log.Printf("Leaderboard refresh for %s failed: %v", saved.Address, err)
+			ls.mu.Lock()
+			entry := ls.entries[saved.Address]
+			entry.Address, entry.Label, entry.Error = saved.Address, saved.Label, err.Error()
+			ls.entries[saved.Address] = entry
+			ls.mu.Unlock()
+			continue
+		}
+
+		entry := models.LeaderboardEntry{
+			Address:     saved.Address,
+			Label:       saved.Label,
+			Windows:     windowStats(trades, time.Now()),
+			Incomplete:  partial != nil,
+			RefreshedAt: time.Now(),
+		}
+
+		ls.mu.Lock()
+		ls.entries[saved.Address] = entry
+		ls.mu.Unlock()
+	}
+
+	ls.mu.Lock()
+	// Drop addresses that were removed from the address book
+	for address := range ls.entries {
+		if !tracked[address] {
+			delete(ls.entries, address)
+		}
+	}
+	ls.refreshedAt = time.Now()
+	ls.mu.Unlock()
+
+	log.Printf("Leaderboard refreshed for %d addresses", len(tracked))
+}
+
+// Leaderboard returns tracked addresses ranked by sortBy over window, best first
+func (ls *LeaderboardService) Leaderboard(window, sortBy string) models.Leaderboard {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	board := models.Leaderboard{
+		Window:  window,
+		SortBy:  sortBy,
+		Entries: make([]models.LeaderboardEntry, 0, len(ls.entries)),
+	}
+	if !ls.refreshedAt.IsZero() {
+		refreshedAt := ls.refreshedAt
+		board.RefreshedAt = &refreshedAt
+	}
+
+	for _, entry := range ls.entries {
+		entry.Label = ls.addressBook.Label(entry.Address)
+		board.Entries = append(board.Entries, entry)
+	}
+
+	key := func(entry models.LeaderboardEntry) models.Money {
+		if sortBy == SortByVolume {
+			return entry.Windows[window].Volume
+		}
+		return entry.Windows[window].PnL
+	}
+	sort.SliceStable(board.Entries, func(i, j int) bool {
+		if key(board.Entries[i]) != key(board.Entries[j]) {
+			return key(board.Entries[i]) > key(board.Entries[j])
+		}
+		return board.Entries[i].Address < board.Entries[j].Address
+	})
+
+	for i := range board.Entries {
+		board.Entries[i].Rank = i + 1
+	}
+
+	return board
+}
+
+// IsLeaderboardWindow reports whether name is one of LeaderboardWindows
+func IsLeaderboardWindow(name string) bool {
+	for _, window := range LeaderboardWindows {
+		if window.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// windowStats computes P&L and volume for each leaderboard window ending at now.
+// P&L uses the same buy/sell value convention as the daily P&L calculation.
+func windowStats(trades []models.Trade, now time.Time) map[string]models.WindowStats {
+	stats := make(map[string]models.WindowStats, len(LeaderboardWindows))
+	for _, window := range LeaderboardWindows {
+		cutoff := now.Add(-window.Duration)
+
+		var s models.WindowStats
+		for _, trade := range trades {
+			if trade.Time.Before(cutoff) {
+				continue
+			}
+			switch trade.Side {
+			case "B":
+				s.PnL = (s.PnL - trade.Value).Round()
+			case "A":
+				s.PnL = (s.PnL + trade.Value).Round()
+			}
+			s.Volume = (s.Volume + trade.Value).Round()
+			s.TradeCount++
+		}
+		stats[window.Name] = s
+	}
+	return stats
+}
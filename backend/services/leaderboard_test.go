@@ -0,0 +1,108 @@
+package services
+
+import (
+	"encoding/json"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test windowStats window boundaries
+func TestWindowStats(t *testing.T) {
+	now := time.Now()
+	trade := func(age time.Duration, side string, value models.Money) models.Trade {
+		return models.Trade{Time: now.Add(-age), Coin: "BTC", Side: side, Price: value, Size: 1, Value: value}
+	}
+
+	stats := windowStats([]models.Trade{
+		trade(20*24*time.Hour, "B", 1000),
+		trade(3*24*time.Hour, "A", 1200),
+		trade(time.Hour, "B", 500),
+	}, now)
+
+	expected := map[string]models.WindowStats{
+		"24h": {PnL: -500, Volume: 500, TradeCount: 1},
+		"7d":  {PnL: 700, Volume: 1700, TradeCount: 2},
+		"30d": {PnL: -300, Volume: 2700, TradeCount: 3},
+	}
+	for window, want := range expected {
+		if stats[window] != want {
+			t.Errorf("Expected %s stats %+v, got %+v", window, want, stats[window])
+		}
+	}
+}
+
+// Test LeaderboardService refresh and ranking
+func TestLeaderboard(t *testing.T) {
+	const (
+		winner = "0x1111111111111111111111111111111111111111"
+		loser  = "0x2222222222222222222222222222222222222222"
+	)
+
+	now := time.Now()
+	fills := map[string][]FillResponse{
+		winner: {
+			{Time: now.Add(-2 * time.Hour).UnixMilli(), Coin: "BTC", Side: "B", Price: "100", Size: "1"},
+			{Time: now.Add(-time.Hour).UnixMilli(), Coin: "BTC", Side: "A", Price: "150", Size: "1"},
+		},
+		loser: {
+			{Time: now.Add(-2 * time.Hour).UnixMilli(), Coin: "ETH", Side: "B", Price: "1000", Size: "1"},
+			{Time: now.Add(-time.Hour).UnixMilli(), Coin: "ETH", Side: "A", Price: "900", Size: "1"},
+		},
+	}
+
+	// Serve each user's fills on the first request only, so incremental fetches see nothing new
+	served := make(map[string]bool)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			User string `json:"user"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if served[req.User] {
+			w.Write([]byte("[]"))
+			return
+		}
+		served[req.User] = true
+		json.NewEncoder(w).Encode(fills[req.User])
+	}))
+	defer upstream.Close()
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = upstream.URL
+
+	ab, _ := NewAddressBook("", nil)
+	ab.Save(winner, "Winner")
+	ab.Save(loser, "Loser")
+
+	ls := NewLeaderboardService(rs, ab)
+	ls.Refresh()
+
+	board := ls.Leaderboard("24h", SortByPnL)
+	if len(board.Entries) != 2 || board.RefreshedAt == nil {
+		t.Fatalf("Expected 2 refreshed entries, got %+v", board)
+	}
+	if board.Entries[0].Label != "Winner" || board.Entries[0].Rank != 1 || board.Entries[0].Windows["24h"].PnL != 50 {
+		t.Errorf("Expected Winner ranked first by P&L, got %+v", board.Entries[0])
+	}
+
+	board = ls.Leaderboard("24h", SortByVolume)
+	if board.Entries[0].Label != "Loser" {
+		t.Errorf("Expected Loser ranked first by volume, got %+v", board.Entries[0])
+	}
+
+	t.Run("should not change the current summary", func(t *testing.T) {
+		if summary := rs.GetPnLSummary(); summary.Address != "" || len(summary.DailyRecords) != 0 {
+			t.Errorf("Expected leaderboard refresh to leave the summary alone, got %+v", summary)
+		}
+	})
+
+	t.Run("should drop addresses removed from the address book", func(t *testing.T) {
+		ab.Delete(loser)
+		ls.Refresh()
+		if board := ls.Leaderboard("7d", SortByPnL); len(board.Entries) != 1 {
+			t.Errorf("Expected 1 entry after removal, got %d", len(board.Entries))
+		}
+	})
+}
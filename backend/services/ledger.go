@@ -0,0 +1,452 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ledger accounts. Each coin has its own position account, AccountPositions + coin.
+const (
+	AccountCash        = "assets:cash"
+	AccountPositions   = "assets:positions:"
+	AccountFees        = "expenses:fees"
+	AccountFunding     = "income:funding"
+	AccountDeposits    = "equity:deposits"
+	AccountWithdrawals = "equity:withdrawals"
+	// Each HyperEVM token has its own account, AccountHyperEVM + symbol, and
+	// so does its HyperCore balance it is bridged from and to
+	AccountHyperEVM  = "assets:hyperevm:"
+	AccountHyperCore = "assets:hypercore:"
+	// Each vault has its own account, AccountVaults + vault address, holding
+	// the cost of the equity deposited in it
+	AccountVaults   = "assets:vaults:"
+	AccountVaultPnL = "income:vaults"
+	// Referral rewards are earned into AccountRewards until they are claimed
+	AccountRewards   = "assets:rewards"
+	AccountReferrals = "income:referrals"
+	// Other income accrues per program, AccountOtherIncome + program, into
+	// AccountOtherAssets + asset
+	AccountOtherAssets = "assets:other:"
+	AccountOtherIncome = "income:other:"
+)
+
+// Kinds of journal entries
+const (
+	EntryFill            = "fill"
+	EntryFee             = "fee"
+	EntryFunding         = "funding"
+	EntryDeposit         = "deposit"
+	EntryWithdrawal      = "withdrawal"
+	EntryBridge          = "bridge"
+	EntryVaultDeposit    = "vaultDeposit"
+	EntryVaultWithdrawal = "vaultWithdrawal"
+	EntryReferral        = "referral"
+	EntryRewardsClaim    = "rewardsClaim"
+	EntryOtherIncome     = "otherIncome"
+)
+
+// Ledger keeps the double-entry postings of every address's cash flows.
+// Fills and fees are posted from the cached trades whenever the ledger is
+// read; funding payments, deposits, withdrawals, vault transfers and
+// referral rewards are fetched from the API, and token transfers from
+// HyperEVM if it is configured, and stored with the vault equities and
+// staking history, persisted as JSON to path if one is set.
+type Ledger struct {
+	accounts  map[string]*addressLedger // key: address
+	mu        sync.Mutex                // Serializes syncs and guards accounts
+	path      string
+	hlClient  *HyperliquidClient
+	evm       *HyperEVMClient // nil unless HyperEVM is configured
+	sources   []IncomeSource  // Other income, such as points and airdrops
+	valuation Valuation       // Prices accruals without a value; nil if none
+}
+
+// addressLedger is the stored cash-flow entries of one address
+type addressLedger struct {
+	Address     string                `json:"address"`
+	Entries     []models.JournalEntry `json:"entries"` // Oldest first
+	SyncedFrom  time.Time             `json:"syncedFrom"`
+	SyncedUntil time.Time             `json:"syncedUntil"`
+	// Vault equities recorded at each sync and the staking history, oldest first
+	VaultValues []models.VaultValue    `json:"vaultValues,omitempty"`
+	Staking     []models.StakingAction `json:"staking,omitempty"`
+	// ReferralRewards is the total referral rewards earned as of the last sync
+	ReferralRewards float64 `json:"referralRewards,omitempty"`
+}
+
+// NewLedger creates a ledger, loading stored entries from path if it exists
+func NewLedger(path string) (*Ledger, error) {
+	l := &Ledger{
+		accounts: make(map[string]*addressLedger),
+		path:     path,
+		hlClient: NewHyperliquidClient(),
+	}
+	if path == "" {
+		return l, nil
+	}
+
+	data, err := readStoreFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ledger: %w", err)
+	}
+
+	var accounts []addressLedger
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to parse ledger: %w", err)
+	}
+	for i := range accounts {
+		l.accounts[accounts[i].Address] = &accounts[i]
+	}
+
+	return l, nil
+}
+
+// UseHyperEVM makes Sync also fetch the address's token transfers on HyperEVM
+func (l *Ledger) UseHyperEVM(evm *HyperEVMClient) {
+	l.evm = evm
+}
+
+// Sync fetches the funding payments, deposits, withdrawals, vault deposits
+// and withdrawals and rewards claims of address since since that aren't
+// stored yet, and its HyperEVM token transfers. It also records its current
+// vault equities, its staking history and the referral rewards it earned
+// since the last sync.
+func (l *Ledger) Sync(ctx context.Context, address string, since time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	account, exists := l.accounts[address]
+	if !exists {
+		account = &addressLedger{Address: address, Entries: []models.JournalEntry{}}
+		l.accounts[address] = account
+	}
+
+	// Fetch everything again if the window reaches further back than before;
+	// otherwise only what happened since the last sync
+	start := account.SyncedUntil
+	if account.SyncedFrom.IsZero() || since.Before(account.SyncedFrom) {
+		start = since
+	}
+	end := time.Now()
+
+	var entries []models.JournalEntry
+	for _, updateType := range []string{LedgerUpdatesFunding, LedgerUpdatesNonFunding} {
+		updates, err := l.hlClient.FetchLedgerUpdates(ctx, updateType, address, start, end)
+		if err != nil {
+			return err
+		}
+		for _, update := range updates {
+			if entry, ok := cashFlowEntry(update); ok {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	if l.evm != nil {
+		transfers, err := l.evm.FetchTransfers(ctx, address, start, end)
+		if err != nil {
+			return fmt.Errorf("failed to fetch HyperEVM transfers: %w", err)
+		}
+		for _, transfer := range transfers {
+			if entry, ok := transferEntry(address, transfer); ok {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	// Accruals may be recorded after the fact, so every source is asked for
+	// the whole synced period; entries already stored are skipped below
+	otherStart := start
+	if !account.SyncedFrom.IsZero() && account.SyncedFrom.Before(otherStart) {
+		otherStart = account.SyncedFrom
+	}
+	other, err := l.otherIncomeEntries(ctx, address, otherStart, end)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, other...)
+
+	if err := l.syncVaults(ctx, account); err != nil {
+		return err
+	}
+	referral, err := l.syncReferrals(ctx, account, end)
+	if err != nil {
+		return err
+	}
+	if referral != nil {
+		entries = append(entries, *referral)
+	}
+
+	seen := make(map[string]bool, len(account.Entries))
+	for _, entry := range account.Entries {
+		seen[entry.ID] = true
+	}
+	added := 0
+	for _, entry := range entries {
+		if !seen[entry.ID] {
+			seen[entry.ID] = true
+			account.Entries = append(account.Entries, entry)
+			added++
+		}
+	}
+	sort.SliceStable(account.Entries, func(i, j int) bool { return account.Entries[i].Time.Before(account.Entries[j].Time) })
+
+	if account.SyncedFrom.IsZero() || start.Before(account.SyncedFrom) {
+		account.SyncedFrom = start
+	}
+	account.SyncedUntil = end
+
+	log.Printf("Synced ledger for %s: %d new funding, transfer and income entries", address, added)
+	return l.persist()
+}
+
+// Entries returns the journal of address, oldest first: fills and fees posted
+// from trades plus the stored cash flows. from and to (YYYY-MM-DD, local,
+// inclusive) limit it to a period; empty bounds are open.
+func (l *Ledger) Entries(address string, trades []models.Trade, from, to string) []models.JournalEntry {
+	entries := tradeEntries(trades)
+
+	l.mu.Lock()
+	if account, exists := l.accounts[address]; exists {
+		entries = append(entries, account.Entries...)
+	}
+	l.mu.Unlock()
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+
+	filtered := entries[:0]
+	for _, entry := range entries {
+		date := entry.Time.Local().Format("2006-01-02")
+		if (from == "" || date >= from) && (to == "" || date <= to) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// BuildTrialBalance totals entries per account and derives the P&L figures from the balances
+func BuildTrialBalance(address string, entries []models.JournalEntry, from, to string) models.TrialBalance {
+	balance := models.TrialBalance{Address: address, From: from, To: to, Entries: len(entries), Accounts: []models.AccountBalance{}}
+
+	byAccount := make(map[string]*models.AccountBalance)
+	for _, entry := range entries {
+		for _, posting := range entry.Postings {
+			account, exists := byAccount[posting.Account]
+			if !exists {
+				account = &models.AccountBalance{Account: posting.Account}
+				byAccount[posting.Account] = account
+			}
+			if posting.Amount >= 0 {
+				account.Debits += posting.Amount
+				balance.TotalDebits += posting.Amount
+			} else {
+				account.Credits -= posting.Amount
+				balance.TotalCredits -= posting.Amount
+			}
+		}
+	}
+
+	for _, account := range byAccount {
+		account.Balance = account.Debits - account.Credits
+		balance.Accounts = append(balance.Accounts, *account)
+
+		switch {
+		case strings.HasPrefix(account.Account, AccountPositions):
+			balance.TradingPnL -= account.Balance
+		case account.Account == AccountFees:
+			balance.Fees += account.Balance
+		case account.Account == AccountFunding:
+			balance.Funding -= account.Balance
+		case account.Account == AccountVaultPnL:
+			balance.VaultPnL -= account.Balance
+		case account.Account == AccountReferrals:
+			balance.Referrals -= account.Balance
+		case strings.HasPrefix(account.Account, AccountOtherIncome):
+			balance.OtherIncome -= account.Balance
+		case account.Account == AccountDeposits, account.Account == AccountWithdrawals:
+			balance.NetDeposits -= account.Balance
+		}
+	}
+	sort.Slice(balance.Accounts, func(i, j int) bool { return balance.Accounts[i].Account < balance.Accounts[j].Account })
+
+	balance.Balanced = math.Abs(float64(balance.TotalDebits-balance.TotalCredits)) <= 1e-6*math.Max(1, float64(balance.TotalDebits))
+	balance.NetPnL = balance.TradingPnL - balance.Fees + balance.Funding
+	return balance
+}
+
+// tradeEntries posts each trade as a fill entry moving its value between cash
+// and the coin's position account, and its fee, if any, as a fee entry
+func tradeEntries(trades []models.Trade) []models.JournalEntry {
+	entries := make([]models.JournalEntry, 0, len(trades))
+	for i, trade := range trades {
+		id := fmt.Sprintf("%d-%s-%d", trade.Time.UnixMilli(), trade.Coin, i)
+		value := trade.Value
+		if trade.Side == "A" {
+			value = -value
+		}
+
+		entries = append(entries, models.JournalEntry{
+			ID:   EntryFill + "-" + id,
+			Time: trade.Time,
+			Kind: EntryFill,
+			Coin: trade.Coin,
+			Ref:  fmt.Sprintf("%s %s %s @ %s", trade.Side, formatFloat(float64(trade.Size)), trade.Coin, formatFloat(float64(trade.Price))),
+			Postings: []models.Posting{
+				{Account: AccountPositions + trade.Coin, Amount: value},
+				{Account: AccountCash, Amount: -value},
+			},
+		})
+
+		if trade.Fee != 0 {
+			entries = append(entries, models.JournalEntry{
+				ID:   EntryFee + "-" + id,
+				Time: trade.Time,
+				Kind: EntryFee,
+				Coin: trade.Coin,
+				Postings: []models.Posting{
+					{Account: AccountFees, Amount: trade.Fee},
+					{Account: AccountCash, Amount: -trade.Fee},
+				},
+			})
+		}
+	}
+	return entries
+}
+
+// cashFlowEntry posts a funding payment, deposit, withdrawal, vault deposit or
+// withdrawal or rewards claim. Other account updates, such as transfers
+// between an address's own accounts, aren't posted.
+func cashFlowEntry(update LedgerUpdateResponse) (models.JournalEntry, bool) {
+	switch update.Delta.Type {
+	case "vaultWithdraw":
+		return vaultWithdrawalEntry(update)
+	case "rewardsClaim":
+		return rewardsClaimEntry(update)
+	}
+	amount, err := models.ParseMoney(update.Delta.USDC)
+	if err != nil {
+		return models.JournalEntry{}, false
+	}
+	entry := models.JournalEntry{
+		ID:   update.Delta.Type + "-" + update.Hash + "-" + strconv.FormatInt(update.Time, 10),
+		Time: time.UnixMilli(update.Time),
+		Coin: update.Delta.Coin,
+		Ref:  update.Hash,
+	}
+
+	switch update.Delta.Type {
+	case "funding":
+		entry.Kind = EntryFunding
+		entry.Postings = []models.Posting{{Account: AccountCash, Amount: amount}, {Account: AccountFunding, Amount: -amount}}
+	case "deposit":
+		entry.Kind = EntryDeposit
+		entry.Postings = []models.Posting{{Account: AccountCash, Amount: amount}, {Account: AccountDeposits, Amount: -amount}}
+	case "withdraw":
+		fee, _ := models.ParseMoney(update.Delta.Fee)
+		entry.Kind = EntryWithdrawal
+		entry.Postings = []models.Posting{{Account: AccountWithdrawals, Amount: amount}, {Account: AccountCash, Amount: -amount - fee}}
+		if fee != 0 {
+			entry.Postings = append(entry.Postings, models.Posting{Account: AccountFees, Amount: fee})
+		}
+	case "vaultDeposit":
+		entry.Kind = EntryVaultDeposit
+		entry.Coin = strings.ToLower(update.Delta.Vault)
+		entry.Postings = []models.Posting{{Account: AccountVaults + entry.Coin, Amount: amount}, {Account: AccountCash, Amount: -amount}}
+	default:
+		return models.JournalEntry{}, false
+	}
+	return entry, true
+}
+
+// vaultWithdrawalEntry posts a vault withdrawal: the cost basis withdrawn
+// leaves the vault account, and what was paid out above or below it is
+// realized vault P&L. Without a basis the payout is taken as the cost.
+func vaultWithdrawalEntry(update LedgerUpdateResponse) (models.JournalEntry, bool) {
+	net, err := models.ParseMoney(update.Delta.NetWithdrawnUSD)
+	if err != nil {
+		return models.JournalEntry{}, false
+	}
+	basis, err := models.ParseMoney(update.Delta.Basis)
+	if err != nil {
+		basis = net
+	}
+
+	vault := strings.ToLower(update.Delta.Vault)
+	entry := models.JournalEntry{
+		ID:       update.Delta.Type + "-" + update.Hash + "-" + strconv.FormatInt(update.Time, 10),
+		Time:     time.UnixMilli(update.Time),
+		Kind:     EntryVaultWithdrawal,
+		Coin:     vault,
+		Ref:      update.Hash,
+		Postings: []models.Posting{{Account: AccountCash, Amount: net}, {Account: AccountVaults + vault, Amount: -basis}},
+	}
+	if net != basis {
+		entry.Postings = append(entry.Postings, models.Posting{Account: AccountVaultPnL, Amount: basis - net})
+	}
+	return entry, true
+}
+
+// transferEntry posts a HyperEVM token transfer of address in token units.
+// Transfers with other addresses are deposits and withdrawals; transfers with
+// a system address move the token between the address's HyperCore and
+// HyperEVM balances. Transfers to itself aren't posted.
+func transferEntry(address string, transfer EVMTransfer) (models.JournalEntry, bool) {
+	incoming, outgoing := transfer.To == address, transfer.From == address
+	if incoming == outgoing {
+		return models.JournalEntry{}, false
+	}
+
+	entry := models.JournalEntry{
+		ID:   "evm-" + transfer.Hash + "-" + strconv.FormatInt(transfer.LogIndex, 10),
+		Time: transfer.Time,
+		Coin: transfer.Token,
+		Ref:  transfer.Hash,
+	}
+	evmAccount := AccountHyperEVM + transfer.Token
+
+	counterAccount := AccountDeposits
+	entry.Kind = EntryDeposit
+	switch {
+	case transfer.Bridged():
+		counterAccount = AccountHyperCore + transfer.Token
+		entry.Kind = EntryBridge
+	case outgoing:
+		counterAccount = AccountWithdrawals
+		entry.Kind = EntryWithdrawal
+	}
+
+	amount := models.Money(transfer.Amount).Round()
+	if outgoing {
+		amount = -amount
+	}
+	entry.Postings = []models.Posting{{Account: evmAccount, Amount: amount}, {Account: counterAccount, Amount: -amount}}
+	return entry, true
+}
+
+// persist writes every address's stored entries to the ledger file; caller must hold l.mu
+func (l *Ledger) persist() error {
+	if l.path == "" {
+		return nil
+	}
+
+	accounts := make([]addressLedger, 0, len(l.accounts))
+	for _, account := range l.accounts {
+		accounts = append(accounts, *account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Address < accounts[j].Address })
+
+	return writeJSONFile(l.path, accounts)
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hyperliquid-recon/models"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestLedgerServer serves one funding payment, one deposit, one withdrawal
+// and one transfer that isn't posted, and no vaults, staking or referral rewards
+func newTestLedgerServer(t *testing.T, base time.Time) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req UserFillsRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		body := `[]`
+		switch req.Type {
+		case LedgerUpdatesFunding:
+			body = `[{"time": %d, "hash": "0x01", "delta": {"type": "funding", "coin": "ETH", "usdc": "-2.5"}}]`
+		case LedgerUpdatesNonFunding:
+			body = `[
+				{"time": %d, "hash": "0x02", "delta": {"type": "deposit", "usdc": "1000"}},
+				{"time": %[1]d, "hash": "0x03", "delta": {"type": "withdraw", "usdc": "100", "fee": "1"}},
+				{"time": %[1]d, "hash": "0x04", "delta": {"type": "accountClassTransfer", "usdc": "50"}}
+			]`
+		case "userVaultEquities", "delegatorHistory":
+		case "referral":
+			body = `{}`
+		default:
+			t.Errorf("Unexpected request type %q", req.Type)
+		}
+		if body != `[]` && body != `{}` {
+			body = fmt.Sprintf(body, base.UnixMilli())
+		}
+		w.Write([]byte(body))
+	}))
+}
+
+// Test double-entry postings and the trial balance
+func TestLedger(t *testing.T) {
+	base := time.Date(2025, 2, 1, 12, 0, 0, 0, time.Local)
+	trades := []models.Trade{
+		{Time: base, Coin: "ETH", Side: "B", Price: 100, Size: 2, Value: 200, Fee: 0.1},
+		{Time: base.Add(time.Hour), Coin: "ETH", Side: "A", Price: 110, Size: 2, Value: 220, Fee: -0.05},
+		{Time: base.AddDate(0, 0, 1), Coin: "BTC", Side: "B", Price: 50000, Size: 0.01, Value: 500},
+	}
+
+	server := newTestLedgerServer(t, base)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	ledger, _ := NewLedger(path)
+	ledger.hlClient.apiURL = server.URL
+
+	if err := ledger.Sync(context.Background(), testAddress, base.Add(-time.Hour)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Syncing again must not duplicate entries
+	if err := ledger.Sync(context.Background(), testAddress, base.Add(-2*time.Hour)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	entries := ledger.Entries(testAddress, trades, "", "")
+
+	t.Run("should post every cash flow once", func(t *testing.T) {
+		// 3 fills, 2 fees, funding, deposit and withdrawal
+		if len(entries) != 8 {
+			t.Fatalf("Expected 8 entries, got %d", len(entries))
+		}
+		for _, entry := range entries {
+			var sum models.Money
+			for _, posting := range entry.Postings {
+				sum += posting.Amount
+			}
+			if math.Abs(float64(sum)) > 1e-9 {
+				t.Errorf("Entry %s doesn't balance: %+v", entry.ID, entry.Postings)
+			}
+		}
+	})
+
+	t.Run("should trace P&L to the account balances", func(t *testing.T) {
+		balance := BuildTrialBalance(testAddress, entries, "", "")
+		if !balance.Balanced {
+			t.Errorf("Expected debits %v to equal credits %v", balance.TotalDebits, balance.TotalCredits)
+		}
+		// Sell value less buy value, as in the summary
+		if math.Abs(float64(balance.TradingPnL)-(220-200-500)) > 1e-9 {
+			t.Errorf("Expected trading P&L of -480, got %v", balance.TradingPnL)
+		}
+		if math.Abs(float64(balance.Fees)-1.05) > 1e-9 || balance.Funding != -2.5 || balance.NetDeposits != 900 {
+			t.Errorf("Unexpected fees %v, funding %v or net deposits %v", balance.Fees, balance.Funding, balance.NetDeposits)
+		}
+		if math.Abs(float64(balance.NetPnL)-(-480-1.05-2.5)) > 1e-9 {
+			t.Errorf("Unexpected net P&L %v", balance.NetPnL)
+		}
+	})
+
+	t.Run("should limit entries to the period", func(t *testing.T) {
+		day := base.Format("2006-01-02")
+		if got := ledger.Entries(testAddress, trades, day, day); len(got) != 7 {
+			t.Errorf("Expected 7 entries on %s, got %d", day, len(got))
+		}
+	})
+
+	t.Run("should persist fetched cash flows", func(t *testing.T) {
+		reloaded, err := NewLedger(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got := reloaded.Entries(testAddress, nil, "", ""); len(got) != 3 {
+			t.Errorf("Expected 3 stored entries, got %d", len(got))
+		}
+	})
+}
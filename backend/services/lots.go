@@ -0,0 +1,132 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"math"
+	"strings"
+	"time"
+)
+
+// Lot matching methods: which open lot a closing fill is matched against
+const (
+	LotsFIFO = "fifo" // Oldest lot first
+	LotsLIFO = "lifo" // Newest lot first
+)
+
+// ErrInvalidLotMethod is returned for a lot matching method the engine doesn't support
+var ErrInvalidLotMethod = errors.New("lot method must be fifo or lifo")
+
+// lot is the open remainder of one entry fill
+type lot struct {
+	time  time.Time
+	price float64
+	size  float64 // Unsigned remaining size
+	fee   float64 // Share of the entry fee not yet matched
+}
+
+// lotMatch is part of an entry lot closed by part of an exit fill
+type lotMatch struct {
+	coin       string
+	long       bool
+	episode    int // Counts the coin's flat-to-flat position episodes
+	entryTime  time.Time
+	exitTime   time.Time
+	entryPrice float64
+	exitPrice  float64
+	size       float64
+	entryFee   float64
+	exitFee    float64
+}
+
+// grossPnL is the P&L of the match before fees
+func (m lotMatch) grossPnL() float64 {
+	if m.long {
+		return (m.exitPrice - m.entryPrice) * m.size
+	}
+	return (m.entryPrice - m.exitPrice) * m.size
+}
+
+// coinLots is the open position of one coin as a list of lots, oldest first
+type coinLots struct {
+	long    bool
+	lots    []lot
+	episode int
+}
+
+// matchLots replays trades in time order, opening a lot for each fill that
+// adds to a position and matching fills that reduce it against open lots by
+// method. A fill that flips a position closes every lot and opens the
+// remainder as a new lot. Fees are split in proportion to the size matched.
+// It returns the matches and, for each coin with an open position, its current episode.
+func matchLots(trades []models.Trade, method string) ([]lotMatch, map[string]int, error) {
+	method = strings.ToLower(method)
+	if method != LotsFIFO && method != LotsLIFO {
+		return nil, nil, ErrInvalidLotMethod
+	}
+
+	positions := make(map[string]*coinLots)
+	var matches []lotMatch
+
+	for _, trade := range trades {
+		pos, exists := positions[trade.Coin]
+		if !exists {
+			pos = &coinLots{}
+			positions[trade.Coin] = pos
+		}
+		buy := trade.Side == "B"
+		remaining := float64(trade.Size)
+		feePerUnit := 0.0
+		if trade.Size > 0 {
+			feePerUnit = float64(trade.Fee) / float64(trade.Size)
+		}
+
+		// Reduce the open position first
+		for remaining > positionEpsilon && len(pos.lots) > 0 && pos.long != buy {
+			index := 0
+			if method == LotsLIFO {
+				index = len(pos.lots) - 1
+			}
+			open := &pos.lots[index]
+
+			size := math.Min(remaining, open.size)
+			entryFee := open.fee * size / open.size
+			matches = append(matches, lotMatch{
+				coin:       trade.Coin,
+				long:       pos.long,
+				episode:    pos.episode,
+				entryTime:  open.time,
+				exitTime:   trade.Time,
+				entryPrice: open.price,
+				exitPrice:  float64(trade.Price),
+				size:       size,
+				entryFee:   entryFee,
+				exitFee:    feePerUnit * size,
+			})
+
+			open.size -= size
+			open.fee -= entryFee
+			remaining -= size
+			if open.size < positionEpsilon {
+				pos.lots = append(pos.lots[:index], pos.lots[index+1:]...)
+				if len(pos.lots) == 0 {
+					pos.episode++
+				}
+			}
+		}
+
+		// Whatever is left opens or adds to a position in the fill's direction
+		if remaining > positionEpsilon {
+			pos.long = buy
+			pos.lots = append(pos.lots, lot{time: trade.Time, price: float64(trade.Price), size: remaining, fee: feePerUnit * remaining})
+		}
+	}
+
+	openEpisodes := make(map[string]int)
+	for coin, pos := range positions {
+		if len(pos.lots) > 0 {
+			openEpisodes[coin] = pos.episode
+		}
+	}
+	return matches, openEpisodes, nil
+}
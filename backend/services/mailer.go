@@ -0,0 +1,53 @@
+package services
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Mailer sends plain-text email through an SMTP server. The connection is
+// upgraded with STARTTLS when the server supports it.
+type Mailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewMailer creates a mailer. If username is empty, mail is sent without authentication.
+func NewMailer(host, port, username, password, from string) *Mailer {
+	m := &Mailer{
+		addr: net.JoinHostPort(host, port),
+		from: from,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send emails body to every recipient in to
+func (m *Mailer) Send(to []string, subject, body string) error {
+	msg := buildMessage(m.from, to, subject, body, time.Now())
+	if err := smtp.SendMail(m.addr, m.auth, m.from, to, msg); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", strings.Join(to, ", "), err)
+	}
+	return nil
+}
+
+// buildMessage formats an RFC 5322 message with CRLF line endings
+func buildMessage(from string, to []string, subject, body string, now time.Time) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
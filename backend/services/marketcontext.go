@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// marketContexts is the market context store in effect, shared by all tenants
+var marketContexts atomic.Pointer[MarketContextStore]
+
+func init() {
+	// Without a file loading can't fail
+	store, _ := NewMarketContextStore("")
+	marketContexts.Store(store)
+}
+
+// UseMarketContexts replaces the market context store in effect
+func UseMarketContexts(store *MarketContextStore) {
+	marketContexts.Store(store)
+}
+
+// CurrentMarketContexts returns the market context store in effect
+func CurrentMarketContexts() *MarketContextStore {
+	return marketContexts.Load()
+}
+
+// assetContextResponse is the current activity of one perpetual from the API
+type assetContextResponse struct {
+	DayNtlVlm    string `json:"dayNtlVlm"`
+	OpenInterest string `json:"openInterest"`
+	MarkPx       string `json:"markPx"`
+}
+
+// FetchMarketContexts fetches the current 24-hour volume, open interest and
+// mark price of every perpetual
+func (c *HyperliquidClient) FetchMarketContexts(ctx context.Context) (contexts []models.MarketContext, err error) {
+	ctx, span := tracer.Start(ctx, "HyperliquidClient.FetchMarketContexts")
+	defer func() { endSpan(span, err) }()
+
+	// The response pairs the universe with each asset's context, in the same order:
+	// [{"universe": [{"name": "BTC", ...}, ...]}, [{"dayNtlVlm": "...", ...}, ...]]
+	var response [2]json.RawMessage
+	if err := c.postInfo(ctx, map[string]string{"type": "metaAndAssetCtxs"}, &response); err != nil {
+		return nil, fmt.Errorf("failed to fetch asset contexts: %w", err)
+	}
+	var meta struct {
+		Universe []struct {
+			Name string `json:"name"`
+		} `json:"universe"`
+	}
+	var assets []assetContextResponse
+	if err := json.Unmarshal(response[0], &meta); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal meta: %w", err)
+	}
+	if err := json.Unmarshal(response[1], &assets); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal asset contexts: %w", err)
+	}
+	if len(assets) != len(meta.Universe) {
+		return nil, fmt.Errorf("%d asset contexts for %d assets", len(assets), len(meta.Universe))
+	}
+
+	now := time.Now()
+	for i, asset := range assets {
+		market := models.MarketContext{Coin: meta.Universe[i].Name, Date: now.Format("2006-01-02"), RecordedAt: now}
+		for _, field := range []struct {
+			value string
+			into  *float64
+		}{
+			{asset.DayNtlVlm, &market.DayVolume},
+			{asset.OpenInterest, &market.OpenInterest},
+			{asset.MarkPx, &market.MarkPrice},
+		} {
+			if *field.into, err = strconv.ParseFloat(field.value, 64); err != nil {
+				return nil, fmt.Errorf("failed to parse %s asset context '%s': %w", market.Coin, field.value, err)
+			}
+		}
+		market.OpenInterestUSD = market.OpenInterest * market.MarkPrice
+		contexts = append(contexts, market)
+	}
+	return contexts, nil
+}
+
+// MarketContextStore keeps each coin's exchange-wide volume and open interest
+// per local day. The API only reports current values, so a day's context is
+// what was recorded last that day; days nothing was recorded on have none.
+// Contexts are persisted as JSON to path if one is set.
+type MarketContextStore struct {
+	days     map[string]map[string]models.MarketContext // key: coin, date
+	mu       sync.RWMutex
+	path     string
+	hlClient *HyperliquidClient
+}
+
+// NewMarketContextStore creates a store, loading recorded contexts from path if it exists
+func NewMarketContextStore(path string) (*MarketContextStore, error) {
+	s := &MarketContextStore{
+		days:     make(map[string]map[string]models.MarketContext),
+		path:     path,
+		hlClient: NewHyperliquidClient(),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := readStoreFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read market context: %w", err)
+	}
+
+	var contexts []models.MarketContext
+	if err := json.Unmarshal(data, &contexts); err != nil {
+		return nil, fmt.Errorf("failed to parse market context: %w", err)
+	}
+	for _, market := range contexts {
+		s.store(market)
+	}
+
+	return s, nil
+}
+
+// Record fetches the current context of every perpetual and records it as
+// today's, replacing what was recorded earlier today
+func (s *MarketContextStore) Record(ctx context.Context) error {
+	contexts, err := s.hlClient.FetchMarketContexts(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, market := range contexts {
+		s.store(market)
+	}
+	log.Printf("Recorded market context of %d coins", len(contexts))
+	return s.persist()
+}
+
+// RecordScheduled records the market context, logging any error; for use as a scheduled job
+func (s *MarketContextStore) RecordScheduled() {
+	if err := s.Record(context.Background()); err != nil {
+		log.Printf("Error recording market context: %v", err)
+	}
+}
+
+// Context returns the context of coin recorded on date (YYYY-MM-DD)
+func (s *MarketContextStore) Context(coin, date string) (models.MarketContext, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	market, exists := s.days[coin][date]
+	return market, exists
+}
+
+// store adds market to the recorded contexts. Caller must hold s.mu or own s.
+func (s *MarketContextStore) store(market models.MarketContext) {
+	if s.days[market.Coin] == nil {
+		s.days[market.Coin] = make(map[string]models.MarketContext)
+	}
+	s.days[market.Coin][market.Date] = market
+}
+
+// persist writes every recorded context to the market context file. Caller must hold s.mu.
+func (s *MarketContextStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	var contexts []models.MarketContext
+	for _, byDate := range s.days {
+		for _, market := range byDate {
+			contexts = append(contexts, market)
+		}
+	}
+	sort.Slice(contexts, func(i, j int) bool {
+		if contexts[i].Coin != contexts[j].Coin {
+			return contexts[i].Coin < contexts[j].Coin
+		}
+		return contexts[i].Date < contexts[j].Date
+	})
+
+	return writeJSONFile(s.path, contexts)
+}
+
+// AddMarketContext sets the exchange-wide volume and open interest recorded
+// in store on each date of each coin line of series
+func AddMarketContext(series *models.PnLSeries, store *MarketContextStore) {
+	for i := range series.Coins {
+		line := &series.Coins[i]
+		line.MarketVolume = make([]*float64, len(series.Dates))
+		line.OpenInterestUSD = make([]*float64, len(series.Dates))
+		for j, date := range series.Dates {
+			market, exists := store.Context(exchangeCoin(line.Coin, date), date)
+			if !exists {
+				continue
+			}
+			volume, openInterest := market.DayVolume, market.OpenInterestUSD
+			line.MarketVolume[j] = &volume
+			line.OpenInterestUSD[j] = &openInterest
+		}
+	}
+}
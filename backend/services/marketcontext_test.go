@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test recording exchange-wide volume and open interest and adding them to a series
+func TestMarketContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"universe": [{"name": "BTC"}, {"name": "ETH"}]},
+			[
+				{"dayNtlVlm": "1500000000.5", "openInterest": "10", "markPx": "60000"},
+				{"dayNtlVlm": "800000000", "openInterest": "2000", "markPx": "3000"}
+			]
+		]`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "market-context.json")
+	store, err := NewMarketContextStore(path)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	store.hlClient.apiURL = server.URL
+
+	if err := store.Record(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	today := time.Now().Format("2006-01-02")
+	btc, exists := store.Context("BTC", today)
+	if !exists || btc.DayVolume != 1500000000.5 || btc.OpenInterestUSD != 600000 {
+		t.Fatalf("Unexpected BTC context %+v", btc)
+	}
+
+	// Recordings survive a restart
+	reloaded, err := NewMarketContextStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reload store: %v", err)
+	}
+	if eth, exists := reloaded.Context("ETH", today); !exists || eth.OpenInterestUSD != 6000000 {
+		t.Errorf("Expected the ETH context to be reloaded, got %+v", eth)
+	}
+
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	series := models.PnLSeries{
+		Dates: []string{yesterday, today},
+		Coins: []models.SeriesLine{{Coin: "ETH"}, {Coin: "PURR/USDC"}},
+	}
+	AddMarketContext(&series, reloaded)
+	eth := series.Coins[0]
+	if eth.MarketVolume[0] != nil || eth.MarketVolume[1] == nil || *eth.MarketVolume[1] != 800000000 {
+		t.Errorf("Expected only today's ETH volume, got %v", eth.MarketVolume)
+	}
+	if spot := series.Coins[1]; spot.OpenInterestUSD[1] != nil {
+		t.Errorf("Expected no open interest for a spot pair, got %v", *spot.OpenInterestUSD[1])
+	}
+}
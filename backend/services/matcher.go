@@ -0,0 +1,263 @@
+package services
+
+import (
+	"fmt"
+	"hyperliquid-recon/models"
+	"math"
+	"sort"
+	"time"
+)
+
+// sizeEpsilon is the relative difference below which two sizes are the same
+const sizeEpsilon = 1e-9
+
+// reconFill converts trade to the fill or row of an external reconciliation
+func reconFill(trade models.Trade) models.ReconFill {
+	return models.ReconFill{
+		Ref:   fillRef(trade),
+		Time:  trade.Time,
+		Coin:  trade.Coin,
+		Side:  trade.Side,
+		Price: trade.Price,
+		Size:  trade.Size,
+	}
+}
+
+// fillRef identifies trade by its time, coin, side, price and size
+func fillRef(trade models.Trade) string {
+	return fmt.Sprintf("%d:%s:%s:%s:%s", trade.Time.UnixMilli(), trade.Coin, trade.Side, formatFloat(float64(trade.Price)), formatFloat(float64(trade.Size)))
+}
+
+// relativeDiff returns how far a is from b as a fraction of b
+func relativeDiff(a, b float64) float64 {
+	if b == 0 {
+		return math.Abs(a)
+	}
+	return math.Abs(a-b) / math.Abs(b)
+}
+
+// matchScore scores a match 1 when time and price agree, losing up to half
+// for each as its difference approaches its tolerance
+func matchScore(dt time.Duration, window time.Duration, priceDiff, epsilon float64) float64 {
+	score := 1.0
+	if window > 0 {
+		score -= 0.5 * math.Min(float64(dt.Abs())/float64(window), 1)
+	}
+	if epsilon > 0 {
+		score -= 0.5 * math.Min(priceDiff/epsilon, 1)
+	}
+	return score
+}
+
+// MatchFills matches the external rows to the fills of internal within
+// tolerance. Exact matches come first; the rest are matched one to one by
+// best score, then, if sizes may be aggregated, as groups of fills and rows
+// close together in time whose total sizes agree. Only the counts, matches
+// and unmatched sides of the result are set.
+func MatchFills(internal, external []models.Trade, tolerance models.MatchTolerance) models.ExternalRecon {
+	result := models.ExternalRecon{
+		Tolerance:         tolerance,
+		ExternalRows:      len(external),
+		InternalFills:     len(internal),
+		Matches:           []models.FillMatch{},
+		Ambiguous:         []models.FillMatch{},
+		UnmatchedInternal: []models.ReconFill{},
+		UnmatchedExternal: []models.ReconFill{},
+	}
+	window := time.Duration(tolerance.TimeWindowMs) * time.Millisecond
+	internalMatched := make([]bool, len(internal))
+	externalMatched := make([]bool, len(external))
+
+	// Exact matches
+	exact := make(map[string][]int)
+	for i, trade := range internal {
+		ref := fillRef(trade)
+		exact[ref] = append(exact[ref], i)
+	}
+	for j, row := range external {
+		ref := fillRef(row)
+		if candidates := exact[ref]; len(candidates) > 0 {
+			internalMatched[candidates[0]], externalMatched[j] = true, true
+			exact[ref] = candidates[1:]
+			result.Exact++
+		}
+	}
+
+	// One-to-one matches within the tolerances, best score first
+	type pair struct {
+		i, j  int
+		score float64
+	}
+	var pairs []pair
+	candidatesOfInternal := make(map[int][]pair)
+	candidatesOfExternal := make(map[int][]pair)
+	byCoinSide := make(map[string][]int) // Internal fills of each coin and side, oldest first
+	for i, trade := range internal {
+		if !internalMatched[i] {
+			byCoinSide[trade.Coin+"/"+trade.Side] = append(byCoinSide[trade.Coin+"/"+trade.Side], i)
+		}
+	}
+	for _, indices := range byCoinSide {
+		sort.SliceStable(indices, func(a, b int) bool { return internal[indices[a]].Time.Before(internal[indices[b]].Time) })
+	}
+	for j, row := range external {
+		if externalMatched[j] {
+			continue
+		}
+		indices := byCoinSide[row.Coin+"/"+row.Side]
+		first := sort.Search(len(indices), func(k int) bool { return !internal[indices[k]].Time.Before(row.Time.Add(-window)) })
+		for _, i := range indices[first:] {
+			trade := internal[i]
+			if trade.Time.After(row.Time.Add(window)) {
+				break
+			}
+			if relativeDiff(float64(row.Size), float64(trade.Size)) > sizeEpsilon {
+				continue
+			}
+			dt := row.Time.Sub(trade.Time)
+			priceDiff := relativeDiff(float64(row.Price), float64(trade.Price))
+			if priceDiff > tolerance.PriceEpsilon {
+				continue
+			}
+			p := pair{i, j, matchScore(dt, window, priceDiff, tolerance.PriceEpsilon)}
+			pairs = append(pairs, p)
+			candidatesOfInternal[i] = append(candidatesOfInternal[i], p)
+			candidatesOfExternal[j] = append(candidatesOfExternal[j], p)
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool { return pairs[a].score > pairs[b].score })
+	for _, p := range pairs {
+		if internalMatched[p.i] || externalMatched[p.j] {
+			continue
+		}
+		internalMatched[p.i], externalMatched[p.j] = true, true
+
+		match := models.FillMatch{
+			Kind:       models.MatchFuzzy,
+			Internal:   []models.ReconFill{reconFill(internal[p.i])},
+			External:   []models.ReconFill{reconFill(external[p.j])},
+			Score:      p.score,
+			Candidates: max(len(candidatesOfInternal[p.i]), len(candidatesOfExternal[p.j])),
+		}
+		for _, candidates := range [][]pair{candidatesOfInternal[p.i], candidatesOfExternal[p.j]} {
+			for _, other := range candidates {
+				if other != p && (match.RunnerUpScore == nil || other.score > *match.RunnerUpScore) {
+					score := other.score
+					match.RunnerUpScore = &score
+				}
+			}
+		}
+		result.Matches = append(result.Matches, match)
+		result.Fuzzy++
+	}
+
+	if tolerance.AggregateSizes {
+		for _, match := range aggregateMatches(internal, external, internalMatched, externalMatched, window, tolerance.PriceEpsilon) {
+			result.Matches = append(result.Matches, match)
+			result.Aggregated++
+		}
+	}
+
+	for i, trade := range internal {
+		if !internalMatched[i] {
+			result.UnmatchedInternal = append(result.UnmatchedInternal, reconFill(trade))
+		}
+	}
+	for j, row := range external {
+		if !externalMatched[j] {
+			result.UnmatchedExternal = append(result.UnmatchedExternal, reconFill(row))
+		}
+	}
+
+	sortMatches(result.Matches)
+	for _, match := range result.Matches {
+		if match.Candidates > 1 {
+			result.Ambiguous = append(result.Ambiguous, match)
+		}
+	}
+	sort.SliceStable(result.Ambiguous, func(a, b int) bool { return result.Ambiguous[a].Score < result.Ambiguous[b].Score })
+	return result
+}
+
+// sortMatches sorts matches by the time of their first fill, or of their
+// first row if they have no fill, oldest first
+func sortMatches(matches []models.FillMatch) {
+	first := func(match models.FillMatch) time.Time {
+		if len(match.Internal) > 0 {
+			return match.Internal[0].Time
+		}
+		return match.External[0].Time
+	}
+	sort.SliceStable(matches, func(a, b int) bool { return first(matches[a]).Before(first(matches[b])) })
+}
+
+// aggregateMatches groups the unmatched fills and rows of each coin and side
+// into runs no more than window apart, and matches the runs with both fills
+// and rows whose total sizes agree and whose average prices are within
+// epsilon, marking them matched
+func aggregateMatches(internal, external []models.Trade, internalMatched, externalMatched []bool, window time.Duration, epsilon float64) []models.FillMatch {
+	type item struct {
+		trade    models.Trade
+		external bool
+		index    int
+	}
+	groups := make(map[string][]item)
+	for i, trade := range internal {
+		if !internalMatched[i] {
+			groups[trade.Coin+"/"+trade.Side] = append(groups[trade.Coin+"/"+trade.Side], item{trade, false, i})
+		}
+	}
+	for j, row := range external {
+		if !externalMatched[j] {
+			groups[row.Coin+"/"+row.Side] = append(groups[row.Coin+"/"+row.Side], item{row, true, j})
+		}
+	}
+
+	var matches []models.FillMatch
+	for _, items := range groups {
+		sort.SliceStable(items, func(a, b int) bool { return items[a].trade.Time.Before(items[b].trade.Time) })
+
+		for start := 0; start < len(items); {
+			end := start + 1
+			for end < len(items) && items[end].trade.Time.Sub(items[end-1].trade.Time) <= window {
+				end++
+			}
+			run := items[start:end]
+			start = end
+
+			var sizes, values, times [2]float64
+			var counts [2]int
+			for _, it := range run {
+				side := 0
+				if it.external {
+					side = 1
+				}
+				sizes[side] += float64(it.trade.Size)
+				values[side] += float64(it.trade.Price) * float64(it.trade.Size)
+				times[side] += float64(it.trade.Time.UnixMilli())
+				counts[side]++
+			}
+			if counts[0] == 0 || counts[1] == 0 || relativeDiff(sizes[1], sizes[0]) > sizeEpsilon {
+				continue
+			}
+			priceDiff := relativeDiff(values[1]/sizes[1], values[0]/sizes[0])
+			if priceDiff > epsilon {
+				continue
+			}
+
+			dt := time.Duration(times[1]/float64(counts[1])-times[0]/float64(counts[0])) * time.Millisecond
+			match := models.FillMatch{Kind: models.MatchAggregated, Score: matchScore(dt, window, priceDiff, epsilon), Candidates: 1}
+			for _, it := range run {
+				if it.external {
+					externalMatched[it.index] = true
+					match.External = append(match.External, reconFill(it.trade))
+				} else {
+					internalMatched[it.index] = true
+					match.Internal = append(match.Internal, reconFill(it.trade))
+				}
+			}
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
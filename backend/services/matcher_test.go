@@ -0,0 +1,67 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"testing"
+)
+
+// Test matching external rows to fills within tolerances
+func TestMatchFills(t *testing.T) {
+	internal := []models.Trade{
+		createTestTrade("2025-01-01T10:00:00Z", "BTC", "B", 100, 1),
+		createTestTrade("2025-01-01T10:00:10Z", "BTC", "B", 101, 1),
+		createTestTrade("2025-01-01T10:00:20Z", "ETH", "A", 10, 0.5),
+		createTestTrade("2025-01-01T10:00:20.1Z", "ETH", "A", 10, 0.5),
+		createTestTrade("2025-01-01T10:00:30Z", "SOL", "B", 5, 2),
+		createTestTrade("2025-01-01T10:00:30.2Z", "SOL", "B", 5, 2),
+		createTestTrade("2025-01-01T10:00:40Z", "HYPE", "B", 20, 1),
+	}
+	external := []models.Trade{
+		createTestTrade("2025-01-01T10:00:00Z", "BTC", "B", 100, 1),
+		createTestTrade("2025-01-01T10:00:10.2Z", "BTC", "B", 101.005, 1),
+		createTestTrade("2025-01-01T10:00:20.05Z", "ETH", "A", 10, 1),
+		createTestTrade("2025-01-01T10:00:30.1Z", "SOL", "B", 5, 2),
+		createTestTrade("2025-01-01T10:00:30.3Z", "SOL", "B", 5, 2),
+		createTestTrade("2025-01-01T10:00:50Z", "HYPE", "A", 21, 1),
+	}
+	tolerance := models.MatchTolerance{TimeWindowMs: 500, PriceEpsilon: 0.0001, AggregateSizes: true}
+
+	t.Run("should match exactly, fuzzily and by aggregated size", func(t *testing.T) {
+		recon := MatchFills(internal, external, tolerance)
+
+		if recon.Exact != 1 || recon.Fuzzy != 3 || recon.Aggregated != 1 {
+			t.Fatalf("Expected 1 exact, 3 fuzzy and 1 aggregated match, got %d, %d and %d", recon.Exact, recon.Fuzzy, recon.Aggregated)
+		}
+		btc := recon.Matches[0]
+		if btc.Kind != models.MatchFuzzy || btc.Score >= 1 || btc.Score < 0.5 || btc.Candidates != 1 {
+			t.Errorf("Unexpected BTC match %+v", btc)
+		}
+		eth := recon.Matches[1]
+		if eth.Kind != models.MatchAggregated || len(eth.Internal) != 2 || len(eth.External) != 1 {
+			t.Errorf("Expected two ETH fills matched to one row, got %+v", eth)
+		}
+		if len(recon.Ambiguous) != 2 || recon.Ambiguous[0].Internal[0].Coin != "SOL" || recon.Ambiguous[0].RunnerUpScore == nil {
+			t.Errorf("Expected both SOL matches reported as ambiguous, got %+v", recon.Ambiguous)
+		}
+		if len(recon.UnmatchedInternal) != 1 || recon.UnmatchedInternal[0].Coin != "HYPE" ||
+			len(recon.UnmatchedExternal) != 1 || recon.UnmatchedExternal[0].Side != "A" {
+			t.Errorf("Expected the HYPE fill and row unmatched, got %+v and %+v", recon.UnmatchedInternal, recon.UnmatchedExternal)
+		}
+	})
+
+	t.Run("should leave split sizes unmatched without aggregation", func(t *testing.T) {
+		strict := tolerance
+		strict.AggregateSizes = false
+		recon := MatchFills(internal, external, strict)
+		if recon.Aggregated != 0 || len(recon.UnmatchedInternal) != 3 || len(recon.UnmatchedExternal) != 2 {
+			t.Errorf("Expected the ETH fills and row unmatched, got %+v", recon)
+		}
+	})
+
+	t.Run("should only match exact times and prices without tolerance", func(t *testing.T) {
+		recon := MatchFills(internal, external, models.MatchTolerance{})
+		if recon.Exact != 1 || recon.Fuzzy != 0 || len(recon.UnmatchedExternal) != 5 {
+			t.Errorf("Expected one exact match only, got %+v", recon)
+		}
+	})
+}
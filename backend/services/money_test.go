@@ -0,0 +1,87 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"hyperliquid-recon/models"
+	"math"
+	"testing"
+)
+
+// Test the validated money and quantity types and their use on incoming fills
+func TestMoney(t *testing.T) {
+	t.Run("should reject amounts that aren't finite", func(t *testing.T) {
+		for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+			if _, err := models.NewMoney(v); !errors.Is(err, models.ErrInvalidMoney) {
+				t.Errorf("Expected ErrInvalidMoney for %v, got %v", v, err)
+			}
+		}
+		for _, s := range []string{"NaN", "Inf", "-infinity", "3,100", ""} {
+			if _, err := models.ParseMoney(s); !errors.Is(err, models.ErrInvalidMoney) {
+				t.Errorf("Expected ErrInvalidMoney for %q, got %v", s, err)
+			}
+		}
+		if m, err := models.ParseMoney("-12.5"); err != nil || m != -12.5 {
+			t.Errorf("Expected -12.5, got %v (%v)", m, err)
+		}
+	})
+
+	t.Run("should reject negative quantities", func(t *testing.T) {
+		if _, err := models.NewQuantity(-0.1); !errors.Is(err, models.ErrInvalidQuantity) {
+			t.Errorf("Expected ErrInvalidQuantity, got %v", err)
+		}
+		if _, err := models.ParseQuantity("NaN"); !errors.Is(err, models.ErrInvalidQuantity) {
+			t.Errorf("Expected ErrInvalidQuantity, got %v", err)
+		}
+		if q, err := models.NewQuantity(0); err != nil || q != 0 {
+			t.Errorf("Expected a zero quantity, got %v (%v)", q, err)
+		}
+	})
+
+	t.Run("should round half away from zero", func(t *testing.T) {
+		tenth, fifth := 0.1, 0.2
+		cases := map[float64]models.Money{0.123456785: 0.12345679, -0.123456785: -0.12345679, tenth + fifth: 0.3}
+		for v, expected := range cases {
+			if m, _ := models.NewMoney(v); m != expected {
+				t.Errorf("Expected %v to round to %v, got %v", v, expected, m)
+			}
+		}
+	})
+
+	t.Run("should marshal rounded numbers and read numbers or strings", func(t *testing.T) {
+		tenth, fifth := 0.1, 0.2
+		data, err := json.Marshal(struct {
+			PnL models.Money    `json:"pnl"`
+			Sz  models.Quantity `json:"sz"`
+		}{models.Money(tenth + fifth), 2})
+		if err != nil || string(data) != `{"pnl":0.3,"sz":2}` {
+			t.Errorf("Expected rounded JSON, got %s (%v)", data, err)
+		}
+		if _, err := json.Marshal(models.Money(math.NaN())); !errors.Is(err, models.ErrInvalidMoney) {
+			t.Errorf("Expected marshaling NaN to fail, got %v", err)
+		}
+
+		var record models.DailyPnL
+		if err := json.Unmarshal([]byte(`{"volume":"3100.5","dailyPnL":-12}`), &record); err != nil || record.Volume != 3100.5 || record.DailyPnL != -12 {
+			t.Errorf("Expected a string volume and number P&L to read, got %+v (%v)", record, err)
+		}
+		if err := json.Unmarshal([]byte(`{"dailyPnL":"NaN"}`), &record); !errors.Is(err, models.ErrInvalidMoney) {
+			t.Errorf("Expected a NaN P&L to fail, got %v", err)
+		}
+	})
+
+	t.Run("should not convert fills with garbage values", func(t *testing.T) {
+		client := NewHyperliquidClient()
+		for _, fill := range []FillResponse{
+			{Coin: "ETH", Side: "B", Price: "NaN", Size: "1"},
+			{Coin: "ETH", Side: "B", Price: "3000", Size: "-1"},
+			{Coin: "ETH", Side: "B", Price: "3000", Size: "1", Fee: "Inf"},
+			{Coin: "ETH", Side: "B", Price: "3000", Size: "1", ClosedPnl: "-Inf"},
+			{Coin: "ETH", Side: "B", Price: "3000", Size: "1", StartPosition: "NaN"},
+		} {
+			if trade, err := client.convertFillToTrade(fill); err == nil {
+				t.Errorf("Expected %+v to fail, got %+v", fill, trade)
+			}
+		}
+	})
+}
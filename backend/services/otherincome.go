@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoPrice is returned by a Valuation that can't price an asset
+var ErrNoPrice = errors.New("no price for asset")
+
+// IncomeSource is a plugin recording value an address accrues outside
+// trading, such as points programs and airdrops. The ledger asks each source
+// for the accruals of an address when it syncs and posts them as other
+// income, apart from trading P&L.
+type IncomeSource interface {
+	// Name identifies the source; accrual IDs only need be unique within it
+	Name() string
+	// Accruals returns the accruals of address between start and end
+	Accruals(ctx context.Context, address string, start, end time.Time) ([]models.Accrual, error)
+}
+
+// Valuation prices accruals that carry a quantity of an asset but no value
+type Valuation interface {
+	// Price returns the USD price of one unit of asset at t
+	Price(ctx context.Context, asset string, at time.Time) (float64, error)
+}
+
+// StaticPrices values each asset at a fixed USD price
+type StaticPrices map[string]float64
+
+// ParseStaticPrices parses a comma-separated list of ASSET:price pairs
+func ParseStaticPrices(list string) (StaticPrices, error) {
+	prices := make(StaticPrices)
+	for _, item := range strings.Split(list, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		asset, price, ok := strings.Cut(item, ":")
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(price), 64)
+		if !ok || strings.TrimSpace(asset) == "" || err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid price %q: want ASSET:price", strings.TrimSpace(item))
+		}
+		prices[strings.TrimSpace(asset)] = parsed
+	}
+	return prices, nil
+}
+
+// Price returns the configured price of asset, whatever the time
+func (p StaticPrices) Price(_ context.Context, asset string, _ time.Time) (float64, error) {
+	price, exists := p[asset]
+	if !exists {
+		return 0, fmt.Errorf("%w %s", ErrNoPrice, asset)
+	}
+	return price, nil
+}
+
+// FileIncomeSource reads accruals of every address from a JSON file, e.g.
+// points or airdrops recorded by hand or by an external job. The file is read
+// on every sync, so changes to it apply without a restart.
+type FileIncomeSource struct {
+	path string
+}
+
+// NewFileIncomeSource creates a source reading the JSON array of accruals at path
+func NewFileIncomeSource(path string) *FileIncomeSource {
+	return &FileIncomeSource{path: path}
+}
+
+// Name returns "file"
+func (s *FileIncomeSource) Name() string {
+	return "file"
+}
+
+// Accruals returns the accruals in the file of address between start and end.
+// A missing file has none.
+func (s *FileIncomeSource) Accruals(_ context.Context, address string, start, end time.Time) ([]models.Accrual, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read other income: %w", err)
+	}
+
+	var all []models.Accrual
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to parse other income: %w", err)
+	}
+
+	var accruals []models.Accrual
+	for _, accrual := range all {
+		if strings.EqualFold(accrual.Address, address) && !accrual.Time.Before(start) && accrual.Time.Before(end) {
+			accruals = append(accruals, accrual)
+		}
+	}
+	return accruals, nil
+}
+
+// UseIncomeSource makes Sync also post the accruals of source
+func (l *Ledger) UseIncomeSource(source IncomeSource) {
+	l.sources = append(l.sources, source)
+}
+
+// UseValuation makes Sync value accruals without a value with valuation
+func (l *Ledger) UseValuation(valuation Valuation) {
+	l.valuation = valuation
+}
+
+// otherIncomeEntries returns the entries of the accruals of address between
+// start and end from every income source. Caller must hold l.mu.
+func (l *Ledger) otherIncomeEntries(ctx context.Context, address string, start, end time.Time) ([]models.JournalEntry, error) {
+	var entries []models.JournalEntry
+	for _, source := range l.sources {
+		accruals, err := source.Accruals(ctx, address, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch accruals from %s: %w", source.Name(), err)
+		}
+		for _, accrual := range accruals {
+			if accrual.Value == 0 && accrual.Quantity != 0 {
+				accrual.Value = l.value(ctx, accrual)
+			}
+			entries = append(entries, accrualEntry(source.Name(), accrual))
+		}
+	}
+	return entries, nil
+}
+
+// value prices accrual's quantity with the valuation source, or returns zero
+// and logs why it can't
+func (l *Ledger) value(ctx context.Context, accrual models.Accrual) models.Money {
+	if l.valuation == nil {
+		log.Printf("Accrual %s of %g %s has no value and no valuation source is configured", accrual.ID, accrual.Quantity, accrual.Asset)
+		return 0
+	}
+	price, err := l.valuation.Price(ctx, accrual.Asset, accrual.Time)
+	if err != nil {
+		log.Printf("Failed to value accrual %s: %v", accrual.ID, err)
+		return 0
+	}
+	return models.Money(float64(accrual.Quantity) * price).Round()
+}
+
+// accrualEntry posts the value of accrual from source as other income of its
+// program, held in the asset's other-income account until it is realized
+func accrualEntry(source string, accrual models.Accrual) models.JournalEntry {
+	if accrual.Program == "" {
+		accrual.Program = "other"
+	}
+	asset := accrual.Asset
+	if asset == "" {
+		asset = accrual.Program
+	}
+	ref := accrual.Note
+	if ref == "" && accrual.Quantity != 0 {
+		ref = fmt.Sprintf("%s %s", formatFloat(float64(accrual.Quantity)), asset)
+	}
+	return models.JournalEntry{
+		ID:   EntryOtherIncome + "-" + source + "-" + accrual.ID,
+		Time: accrual.Time,
+		Kind: EntryOtherIncome,
+		Coin: accrual.Asset,
+		Ref:  ref,
+		Postings: []models.Posting{
+			{Account: AccountOtherAssets + asset, Amount: accrual.Value},
+			{Account: AccountOtherIncome + accrual.Program, Amount: -accrual.Value},
+		},
+	}
+}
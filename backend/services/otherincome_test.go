@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"hyperliquid-recon/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// pluginIncomeSource is an income source plugin returning fixed accruals
+type pluginIncomeSource []models.Accrual
+
+func (s pluginIncomeSource) Name() string { return "plugin" }
+
+func (s pluginIncomeSource) Accruals(_ context.Context, _ string, start, end time.Time) ([]models.Accrual, error) {
+	var accruals []models.Accrual
+	for _, accrual := range s {
+		if !accrual.Time.Before(start) && accrual.Time.Before(end) {
+			accruals = append(accruals, accrual)
+		}
+	}
+	return accruals, nil
+}
+
+// Test posting points and airdrops from income sources as other income
+func TestOtherIncome(t *testing.T) {
+	base := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	// Synthetic data has no funding, transfers or rewards of its own
+	UseHyperliquidTransport(newTestSyntheticFills(t, 100))
+	defer UseHyperliquidTransport(nil)
+
+	path := filepath.Join(t.TempDir(), "other-income.json")
+	os.WriteFile(path, []byte(`[
+		{"id": "s1", "address": "`+testAddress+`", "time": "`+base.Format(time.RFC3339)+`", "program": "airdrop", "asset": "HYPE", "quantity": 100}
+	]`), 0o644)
+
+	prices, err := ParseStaticPrices("HYPE:25, PURR:0.2")
+	if err != nil {
+		t.Fatalf("Failed to parse prices: %v", err)
+	}
+
+	ledger, _ := NewLedger("")
+	ledger.UseIncomeSource(NewFileIncomeSource(path))
+	ledger.UseIncomeSource(pluginIncomeSource{
+		{ID: "p1", Time: base.Add(time.Minute), Program: "points", Value: 40},
+		{ID: "p2", Time: base.Add(2 * time.Minute), Program: "points", Asset: "PURR", Quantity: 10, Value: 0},
+		{ID: "p3", Time: base.Add(3 * time.Minute), Program: "points", Asset: "UNPRICED", Quantity: 10},
+	})
+	ledger.UseValuation(prices)
+
+	if err := ledger.Sync(context.Background(), testAddress, base.Add(-time.Hour)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// A later sync must not post the accruals again
+	ledger.Sync(context.Background(), testAddress, base.Add(-time.Hour))
+
+	entries := ledger.Entries(testAddress, []models.Trade{{Time: base, Coin: "ETH", Side: "A", Price: 10, Size: 1, Value: 10}}, "", "")
+	balance := BuildTrialBalance(testAddress, entries, "", "")
+	if balance.Entries != 5 || !balance.Balanced {
+		t.Fatalf("Expected a fill and 4 accruals, got %+v", balance)
+	}
+	if balance.OtherIncome != 2500+40+2 || balance.NetPnL != 10 {
+		t.Errorf("Expected other income kept out of net P&L, got %v and %v", balance.OtherIncome, balance.NetPnL)
+	}
+
+	income := BuildIncomeSummary(testAddress, entries, "month")
+	if income.Totals.OtherIncome != 2542 || income.Totals.TradingPnL != 10 || income.Totals.Total != 2552 {
+		t.Errorf("Unexpected income totals %+v", income.Totals)
+	}
+
+	if _, err := prices.Price(context.Background(), "BTC", base); !errors.Is(err, ErrNoPrice) {
+		t.Errorf("Expected ErrNoPrice, got %v", err)
+	}
+	if _, err := ParseStaticPrices("HYPE"); err == nil {
+		t.Error("Expected a price without a value to be rejected")
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"hyperliquid-recon/config"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// upstreamPacer sets the pause between paginated Hyperliquid requests, shared
+// by every client since they all draw on the same upstream rate limit
+var upstreamPacer = newPacer(config.RateLimitDelay, !config.FixedPacing)
+
+// pacer adapts the pause between paginated requests to how the API responds,
+// AIMD-style: every quick, successful response raises the request rate by a
+// fixed step, and every sign of pressure divides it. Large backfills speed up while the
+// API is healthy and back off by themselves when it isn't.
+type pacer struct {
+	adaptive bool
+	base     time.Duration // The configured rate limit delay; backoffs start from at least this
+	delay    time.Duration
+	next     time.Time // Earliest start of the next paced request
+	backoffs int64
+	mu       sync.Mutex
+}
+
+func newPacer(base time.Duration, adaptive bool) *pacer {
+	return &pacer{adaptive: adaptive, base: base, delay: base}
+}
+
+// PacingDelay returns the pause between paginated requests in effect
+func PacingDelay() time.Duration {
+	return upstreamPacer.Delay()
+}
+
+// reset starts pacing again from base
+func (p *pacer) reset(base time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.base, p.delay, p.next = base, base, time.Time{}
+}
+
+// Delay returns the current pause between paginated requests
+func (p *pacer) Delay() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.adaptive {
+		return p.base
+	}
+	return p.delay
+}
+
+// Backoffs returns how many times pacing has backed off since startup
+func (p *pacer) Backoffs() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.backoffs
+}
+
+// pause waits for the next request slot, or until ctx is done. Slots are the
+// current delay apart across every caller, so concurrent paginations share
+// one pace instead of each keeping its own.
+func (p *pacer) pause(ctx context.Context) {
+	p.mu.Lock()
+	now := time.Now()
+	slot := now
+	if p.next.After(now) {
+		slot = p.next
+	}
+	delay := p.base
+	if p.adaptive {
+		delay = p.delay
+	}
+	p.next = slot.Add(delay)
+	p.mu.Unlock()
+
+	if !slot.After(now) {
+		return
+	}
+	timer := time.NewTimer(slot.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// observe adapts the delay to a response that took latency; resp is nil if
+// the request failed without one
+func (p *pacer) observe(resp *http.Response, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.adaptive {
+		return
+	}
+
+	reason := pressure(resp, latency)
+	if reason == "" {
+		// Raise the rate additively; a base below the floor, e.g. 0 in tests, is the floor
+		floor := min(config.PacingMinDelay, p.base)
+		if p.delay > floor {
+			rate := 1/p.delay.Seconds() + config.PacingRateStep
+			p.delay = max(time.Duration(float64(time.Second)/rate), floor)
+		}
+		return
+	}
+
+	before := p.delay
+	p.delay = max(time.Duration(float64(p.delay)*config.PacingBackoffFactor), p.base)
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			p.delay = max(p.delay, time.Duration(seconds)*time.Second)
+		}
+	}
+	p.delay = min(p.delay, config.PacingMaxDelay)
+	p.backoffs++
+	if p.delay != before {
+		log.Printf("Slowing Hyperliquid requests to one every %s: %s", p.delay, reason)
+	}
+}
+
+// pressure returns why a response shows the API is under pressure, or "" if it doesn't
+func pressure(resp *http.Response, latency time.Duration) string {
+	switch {
+	case resp == nil:
+		return "request failed"
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "rate limited"
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return "server error " + strconv.Itoa(resp.StatusCode)
+	case latency > config.PacingSlowLatency:
+		return "slow response took " + latency.Round(time.Millisecond).String()
+	}
+
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil || limit <= 0 {
+		return ""
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err == nil && float64(remaining) < float64(limit)*config.PacingHeadroom {
+		return "rate limit nearly used up"
+	}
+	return ""
+}
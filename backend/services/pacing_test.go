@@ -0,0 +1,72 @@
+package services
+
+import (
+	"hyperliquid-recon/config"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Test speeding up pacing while the API is healthy and backing off under pressure
+func TestPacer(t *testing.T) {
+	p := newPacer(300*time.Millisecond, true)
+	ok := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+
+	t.Run("should raise the rate step by step up to the floor delay", func(t *testing.T) {
+		p.observe(ok, 100*time.Millisecond)
+		// From 3.33 to 3.43 requests a second
+		if p.Delay().Round(time.Millisecond) != 291*time.Millisecond {
+			t.Errorf("Expected the rate to rise by one step, got %s", p.Delay())
+		}
+		for i := 0; i < 200; i++ {
+			p.observe(ok, 100*time.Millisecond)
+		}
+		if p.Delay() != config.PacingMinDelay {
+			t.Errorf("Expected the floor %s, got %s", config.PacingMinDelay, p.Delay())
+		}
+	})
+
+	t.Run("should back off to at least the configured delay", func(t *testing.T) {
+		p.observe(&http.Response{StatusCode: http.StatusBadGateway, Header: http.Header{}}, 100*time.Millisecond)
+		if p.Delay() != 300*time.Millisecond {
+			t.Errorf("Expected the configured delay, got %s", p.Delay())
+		}
+		p.observe(ok, 3*config.PacingSlowLatency)
+		if p.Delay() != 600*time.Millisecond {
+			t.Errorf("Expected a slow response to double the delay, got %s", p.Delay())
+		}
+	})
+
+	t.Run("should honour Retry-After and the ceiling", func(t *testing.T) {
+		limited := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"5"}}}
+		p.observe(limited, 100*time.Millisecond)
+		if p.Delay() != 5*time.Second {
+			t.Errorf("Expected the Retry-After delay, got %s", p.Delay())
+		}
+		for i := 0; i < 10; i++ {
+			p.observe(nil, time.Second)
+		}
+		if p.Delay() != config.PacingMaxDelay {
+			t.Errorf("Expected the ceiling %s, got %s", config.PacingMaxDelay, p.Delay())
+		}
+	})
+
+	t.Run("should back off when the rate limit headers show little headroom", func(t *testing.T) {
+		p.reset(100 * time.Millisecond)
+		p.observe(&http.Response{StatusCode: http.StatusOK, Header: http.Header{
+			"X-Ratelimit-Limit":     {"1200"},
+			"X-Ratelimit-Remaining": {"60"},
+		}}, 100*time.Millisecond)
+		if p.Delay() != 200*time.Millisecond || p.Backoffs() != 14 {
+			t.Errorf("Expected the delay to double on the 14th backoff, got %s after %d", p.Delay(), p.Backoffs())
+		}
+	})
+
+	t.Run("should keep the configured delay when pacing is fixed", func(t *testing.T) {
+		fixed := newPacer(300*time.Millisecond, false)
+		fixed.observe(nil, time.Second)
+		if fixed.Delay() != 300*time.Millisecond {
+			t.Errorf("Expected the configured delay, got %s", fixed.Delay())
+		}
+	})
+}
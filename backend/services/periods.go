@@ -0,0 +1,319 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Errors returned when a period can't be locked
+var (
+	ErrInvalidMonth      = errors.New("month must be in YYYY-MM format")
+	ErrPeriodLocked      = errors.New("period is already locked")
+	ErrPeriodNotFinished = errors.New("only finished months can be locked")
+	ErrPeriodNotCovered  = errors.New("cached trades don't cover the whole month; refresh it first")
+)
+
+// Numbers of a locked day that restatements are recorded for
+const (
+	fieldDailyPnL   = "dailyPnL"
+	fieldTradeCount = "tradeCount"
+	fieldVolume     = "volume"
+)
+
+// PeriodStore keeps locked periods and the restatements recorded against
+// them. Both are persisted as JSON to path if one is set.
+type PeriodStore struct {
+	locks        map[string]*models.PeriodLock // key: address + "/" + month
+	restatements []models.Restatement          // Oldest first
+	mu           sync.RWMutex
+	path         string
+}
+
+// periodsFile is the layout of the periods file
+type periodsFile struct {
+	Locks        []models.PeriodLock  `json:"locks"`
+	Restatements []models.Restatement `json:"restatements"`
+}
+
+// NewPeriodStore creates a period store, loading saved locks and restatements from path if it exists
+func NewPeriodStore(path string) (*PeriodStore, error) {
+	ps := &PeriodStore{
+		locks:        make(map[string]*models.PeriodLock),
+		restatements: []models.Restatement{},
+		path:         path,
+	}
+	if path == "" {
+		return ps, nil
+	}
+
+	data, err := readStoreFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ps, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read periods: %w", err)
+	}
+
+	var saved periodsFile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse periods: %w", err)
+	}
+	for i := range saved.Locks {
+		ps.locks[closeKey(saved.Locks[i].Address, saved.Locks[i].Month)] = &saved.Locks[i]
+	}
+	if saved.Restatements != nil {
+		ps.restatements = saved.Restatements
+	}
+
+	return ps, nil
+}
+
+// MonthRange returns the local start of month (YYYY-MM) and of the month after it
+func MonthRange(month string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidMonth
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// Lock freezes the days of month in records (daily P&L of address, which may
+// cover any range) as the locked numbers of the period. coverageStart and
+// missing describe the cached trades the records were computed from, which
+// must cover the whole month.
+func (ps *PeriodStore) Lock(address, label, month string, records []models.DailyPnL, coverageStart time.Time, missing []models.TimeRange) (models.PeriodLock, error) {
+	start, end, err := MonthRange(month)
+	if err != nil {
+		return models.PeriodLock{}, err
+	}
+	if end.After(time.Now()) {
+		return models.PeriodLock{}, ErrPeriodNotFinished
+	}
+	if !coversPeriod(start, end, coverageStart, missing) {
+		return models.PeriodLock{}, ErrPeriodNotCovered
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	key := closeKey(address, month)
+	if _, locked := ps.locks[key]; locked {
+		return models.PeriodLock{}, ErrPeriodLocked
+	}
+
+	lock := &models.PeriodLock{Address: address, Label: label, Month: month, Days: monthDays(records, month), LockedAt: time.Now()}
+	// Cumulative P&L restarts at the start of the period
+	for i := len(lock.Days) - 1; i >= 0; i-- {
+		day := &lock.Days[i]
+		lock.TotalPnL = (lock.TotalPnL + day.DailyPnL).Round()
+		lock.TradeCount += day.TradeCount
+		lock.Volume = (lock.Volume + day.Volume).Round()
+		day.CumulativePnL = lock.TotalPnL
+	}
+	ps.locks[key] = lock
+
+	return *lock, ps.persist()
+}
+
+// Locks returns the locked periods of address (or of every address if it is
+// empty), newest month first
+func (ps *PeriodStore) Locks(address string) []models.PeriodLock {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	locks := []models.PeriodLock{}
+	for _, lock := range ps.locks {
+		if address == "" || lock.Address == address {
+			locks = append(locks, *lock)
+		}
+	}
+	sort.Slice(locks, func(i, j int) bool {
+		if locks[i].Month != locks[j].Month {
+			return locks[i].Month > locks[j].Month
+		}
+		return locks[i].Address < locks[j].Address
+	})
+	return locks
+}
+
+// Restatements returns the restatements of address and month, newest first.
+// Empty filters match everything. It is safe to call on a nil store.
+func (ps *PeriodStore) Restatements(address, month string) []models.Restatement {
+	restatements := []models.Restatement{}
+	if ps == nil {
+		return restatements
+	}
+
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	for i := len(ps.restatements) - 1; i >= 0; i-- {
+		restatement := ps.restatements[i]
+		if (address == "" || restatement.Address == address) && (month == "" || restatement.Month == month) {
+			restatements = append(restatements, restatement)
+		}
+	}
+	return restatements
+}
+
+// HasLocks reports whether address has any locked period. It is safe to call on a nil store.
+func (ps *PeriodStore) HasLocks(address string) bool {
+	if ps == nil {
+		return false
+	}
+
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	for _, lock := range ps.locks {
+		if lock.Address == address {
+			return true
+		}
+	}
+	return false
+}
+
+// Restate compares the daily records of address with its locked periods and
+// records a restatement, with reason, for every locked number they now
+// disagree with. A number is compared with its latest restated value, so a
+// change is recorded once. Months the cached trades don't fully cover are
+// skipped. It returns the new restatements.
+func (ps *PeriodStore) Restate(address string, records []models.DailyPnL, coverageStart time.Time, missing []models.TimeRange, reason string) []models.Restatement {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	// Latest value of each locked number: key is month/date/field
+	current := make(map[string]models.Money)
+	for _, restatement := range ps.restatements {
+		if restatement.Address == address {
+			current[restatement.Month+"/"+restatement.Date+"/"+restatement.Field] = restatement.NewValue
+		}
+	}
+
+	var recorded []models.Restatement
+	for _, lock := range ps.locks {
+		if lock.Address != address {
+			continue
+		}
+		start, end, err := MonthRange(lock.Month)
+		if err != nil || !coversPeriod(start, end, coverageStart, missing) {
+			continue
+		}
+
+		locked := dayValues(lock.Days)
+		latest := dayValues(monthDays(records, lock.Month))
+
+		dates := make(map[string]bool)
+		for date := range locked {
+			dates[date] = true
+		}
+		for date := range latest {
+			dates[date] = true
+		}
+
+		for date := range dates {
+			for _, field := range []string{fieldDailyPnL, fieldTradeCount, fieldVolume} {
+				key := lock.Month + "/" + date + "/" + field
+				oldValue, restated := current[key]
+				if !restated {
+					oldValue = locked[date][field]
+				}
+				newValue := latest[date][field]
+				if math.Abs(float64(newValue-oldValue)) <= 1e-9*math.Max(1, math.Abs(float64(oldValue))) {
+					continue
+				}
+
+				recorded = append(recorded, models.Restatement{
+					ID:         newID(),
+					Address:    address,
+					Month:      lock.Month,
+					Date:       date,
+					Field:      field,
+					OldValue:   oldValue,
+					NewValue:   newValue,
+					Reason:     reason,
+					RecordedAt: time.Now(),
+				})
+			}
+		}
+	}
+	if len(recorded) == 0 {
+		return nil
+	}
+
+	sort.Slice(recorded, func(i, j int) bool {
+		if recorded[i].Date != recorded[j].Date {
+			return recorded[i].Date < recorded[j].Date
+		}
+		return recorded[i].Field < recorded[j].Field
+	})
+	ps.restatements = append(ps.restatements, recorded...)
+	if err := ps.persist(); err != nil {
+		log.Printf("Failed to save restatements: %v", err)
+	}
+	return recorded
+}
+
+// persist writes all locks and restatements to the periods file; caller must hold ps.mu
+func (ps *PeriodStore) persist() error {
+	if ps.path == "" {
+		return nil
+	}
+
+	saved := periodsFile{Locks: make([]models.PeriodLock, 0, len(ps.locks)), Restatements: ps.restatements}
+	for _, lock := range ps.locks {
+		saved.Locks = append(saved.Locks, *lock)
+	}
+	sort.Slice(saved.Locks, func(i, j int) bool {
+		return closeKey(saved.Locks[i].Address, saved.Locks[i].Month) < closeKey(saved.Locks[j].Address, saved.Locks[j].Month)
+	})
+
+	return writeJSONFile(ps.path, saved)
+}
+
+// coversPeriod reports whether cached trades starting at coverageStart, with
+// the missing ranges, cover all of [start, end)
+func coversPeriod(start, end, coverageStart time.Time, missing []models.TimeRange) bool {
+	if coverageStart.IsZero() || coverageStart.After(start) {
+		return false
+	}
+	for _, r := range missing {
+		if r.Start.Before(end) && r.End.After(start) {
+			return false
+		}
+	}
+	return true
+}
+
+// monthDays returns the records dated in month (YYYY-MM)
+func monthDays(records []models.DailyPnL, month string) []models.DailyPnL {
+	days := []models.DailyPnL{}
+	for _, record := range records {
+		if strings.HasPrefix(record.Date, month+"-") {
+			days = append(days, record)
+		}
+	}
+	return days
+}
+
+// dayValues maps each day's date to its lockable numbers
+func dayValues(days []models.DailyPnL) map[string]map[string]models.Money {
+	values := make(map[string]map[string]models.Money, len(days))
+	for _, day := range days {
+		values[day.Date] = map[string]models.Money{
+			fieldDailyPnL:   day.DailyPnL,
+			fieldTradeCount: models.Money(day.TradeCount),
+			fieldVolume:     day.Volume,
+		}
+	}
+	return values
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test locking periods and recording restatements
+func TestPeriodStore(t *testing.T) {
+	const month = "2025-01"
+	start, _, _ := MonthRange(month)
+	coverageStart := start.Add(-time.Hour)
+	records := []models.DailyPnL{
+		{Date: "2025-02-01", TradeCount: 9, DailyPnL: 900},
+		{Date: "2025-01-31", TradeCount: 2, DailyPnL: 100, Volume: 5000},
+		{Date: "2025-01-05", TradeCount: 1, DailyPnL: -40, Volume: 1000},
+	}
+
+	path := filepath.Join(t.TempDir(), "periods.json")
+	ps, err := NewPeriodStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	t.Run("should refuse months it can't lock", func(t *testing.T) {
+		if _, err := ps.Lock(testAddress, "", "January", records, coverageStart, nil); !errors.Is(err, ErrInvalidMonth) {
+			t.Errorf("Expected ErrInvalidMonth, got %v", err)
+		}
+		if _, err := ps.Lock(testAddress, "", time.Now().Format("2006-01"), records, coverageStart, nil); !errors.Is(err, ErrPeriodNotFinished) {
+			t.Errorf("Expected ErrPeriodNotFinished, got %v", err)
+		}
+		if _, err := ps.Lock(testAddress, "", month, records, start.Add(time.Hour), nil); !errors.Is(err, ErrPeriodNotCovered) {
+			t.Errorf("Expected ErrPeriodNotCovered for late coverage, got %v", err)
+		}
+		missing := []models.TimeRange{{Start: start.AddDate(0, 0, 3), End: start.AddDate(0, 0, 4)}}
+		if _, err := ps.Lock(testAddress, "", month, records, coverageStart, missing); !errors.Is(err, ErrPeriodNotCovered) {
+			t.Errorf("Expected ErrPeriodNotCovered for a missing range, got %v", err)
+		}
+	})
+
+	t.Run("should freeze the month's days", func(t *testing.T) {
+		lock, err := ps.Lock(testAddress, "Main", month, records, coverageStart, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(lock.Days) != 2 || lock.TotalPnL != 60 || lock.TradeCount != 3 || lock.Volume != 6000 {
+			t.Errorf("Unexpected lock %+v", lock)
+		}
+		if lock.Days[0].CumulativePnL != 60 {
+			t.Errorf("Expected cumulative P&L from the start of the month, got %v", lock.Days[0].CumulativePnL)
+		}
+		if _, err := ps.Lock(testAddress, "Main", month, records, coverageStart, nil); !errors.Is(err, ErrPeriodLocked) {
+			t.Errorf("Expected ErrPeriodLocked, got %v", err)
+		}
+	})
+
+	t.Run("should record each change to a locked number once", func(t *testing.T) {
+		changed := []models.DailyPnL{
+			{Date: "2025-02-01", TradeCount: 10, DailyPnL: 1000},
+			{Date: "2025-01-31", TradeCount: 3, DailyPnL: 150, Volume: 5000},
+			{Date: "2025-01-05", TradeCount: 1, DailyPnL: -40, Volume: 1000},
+		}
+
+		restated := ps.Restate(testAddress, changed, coverageStart, nil, "refresh")
+		if len(restated) != 2 {
+			t.Fatalf("Expected 2 restatements, got %+v", restated)
+		}
+		if restated[0].Field != fieldDailyPnL || restated[0].OldValue != 100 || restated[0].NewValue != 150 || restated[0].Reason != "refresh" {
+			t.Errorf("Unexpected restatement %+v", restated[0])
+		}
+
+		if again := ps.Restate(testAddress, changed, coverageStart, nil, "refresh"); len(again) != 0 {
+			t.Errorf("Expected no new restatements, got %+v", again)
+		}
+	})
+
+	t.Run("should skip months the trades don't cover", func(t *testing.T) {
+		if restated := ps.Restate(testAddress, nil, start.AddDate(0, 0, 20), nil, "refresh"); len(restated) != 0 {
+			t.Errorf("Expected no restatements from partial coverage, got %+v", restated)
+		}
+	})
+
+	t.Run("should persist locks and restatements", func(t *testing.T) {
+		reloaded, err := NewPeriodStore(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(reloaded.Locks(testAddress)) != 1 || len(reloaded.Restatements(testAddress, month)) != 2 {
+			t.Errorf("Expected 1 lock and 2 restatements after reload")
+		}
+	})
+}
+
+// Test that imports into a locked period are recorded as restatements
+func TestImportRestatesLockedPeriod(t *testing.T) {
+	ps, _ := NewPeriodStore("")
+	rs := NewReconciliationService()
+	rs.UsePeriodStore(ps)
+
+	start, _, _ := MonthRange("2025-01")
+	trades := []models.Trade{
+		{Time: start.Add(-time.Hour), Coin: "ETH", Side: "B", Price: 100, Size: 1, Value: 100},
+		{Time: start.AddDate(0, 0, 2), Coin: "ETH", Side: "A", Price: 110, Size: 1, Value: 110},
+	}
+	rs.ImportTrades(testAddress, trades)
+	if _, err := rs.LockPeriod(testAddress, "2025-01"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	late := models.Trade{Time: start.AddDate(0, 0, 2).Add(time.Hour), Coin: "ETH", Side: "A", Price: 120, Size: 1, Value: 120}
+	rs.ImportTrades(testAddress, []models.Trade{late})
+
+	restatements := ps.Restatements(testAddress, "2025-01")
+	if len(restatements) != 3 {
+		t.Fatalf("Expected P&L, trade count and volume restatements, got %+v", restatements)
+	}
+	for _, restatement := range restatements {
+		if restatement.Reason != "import" || restatement.Date != start.AddDate(0, 0, 2).Format("2006-01-02") {
+			t.Errorf("Unexpected restatement %+v", restatement)
+		}
+	}
+}
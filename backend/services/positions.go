@@ -0,0 +1,117 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"math"
+)
+
+// positionEpsilon treats sizes this close to zero as flat, absorbing float error
+const positionEpsilon = 1e-9
+
+// ReconstructPositions replays fills for coin in time order and returns the
+// position size and average entry after each fill. The position is assumed to
+// be flat before the first fill. Adding to a position moves the average entry
+// to the size-weighted price, reducing it leaves the average unchanged, and a
+// fill that flips the position opens the remainder at the fill price.
+func ReconstructPositions(trades []models.Trade, coin string) []models.PositionPoint {
+	points := []models.PositionPoint{}
+	size, avgEntry := 0.0, 0.0
+
+	for _, trade := range trades {
+		if trade.Coin != coin {
+			continue
+		}
+
+		delta, price := float64(trade.Size), float64(trade.Price)
+		if trade.Side == "A" {
+			delta = -delta
+		}
+		newSize := size + delta
+
+		switch {
+		case math.Abs(newSize) < positionEpsilon:
+			newSize, avgEntry = 0, 0
+		case size == 0 || (size > 0) != (newSize > 0):
+			// Opened from flat or flipped through zero
+			avgEntry = price
+		case math.Abs(newSize) > math.Abs(size):
+			avgEntry = (avgEntry*math.Abs(size) + price*math.Abs(delta)) / math.Abs(newSize)
+		}
+		size = newSize
+
+		points = append(points, models.PositionPoint{
+			Time:      trade.Time,
+			Side:      trade.Side,
+			FillPrice: trade.Price,
+			FillSize:  trade.Size,
+			Size:      models.Position(size).Round(),
+			AvgEntry:  models.Money(avgEntry).Round(),
+		})
+	}
+
+	return points
+}
+
+// AttributeRealizedPnL returns a copy of trades, oldest first, in which every
+// trade has a realized P&L. Those without one, such as trades imported from
+// older files, are attributed the P&L they realized against the average entry
+// of their coin's position, as in position history.
+func AttributeRealizedPnL(trades []models.Trade) []models.Trade {
+	attributed := make([]models.Trade, len(trades))
+	copy(attributed, trades)
+
+	pnl, _ := realizedPnL(trades)
+	for i := range attributed {
+		if attributed[i].RealizedPnL == nil {
+			realized := models.Money(pnl[i]).Round()
+			attributed[i].RealizedPnL = &realized
+		}
+	}
+	return attributed
+}
+
+// realizedPnL returns the P&L each fill realizes against the average entry of
+// its coin's position, with the same conventions as ReconstructPositions, and
+// whether the fill closed any part of a position. Opening fills realize nothing.
+func realizedPnL(trades []models.Trade) ([]float64, []bool) {
+	type position struct{ size, avgEntry float64 }
+	positions := make(map[string]*position)
+	pnl := make([]float64, len(trades))
+	closing := make([]bool, len(trades))
+
+	for i, trade := range trades {
+		pos, exists := positions[trade.Coin]
+		if !exists {
+			pos = &position{}
+			positions[trade.Coin] = pos
+		}
+
+		delta, price := float64(trade.Size), float64(trade.Price)
+		if trade.Side == "A" {
+			delta = -delta
+		}
+
+		if pos.size != 0 && (pos.size > 0) != (delta > 0) {
+			closed := math.Min(math.Abs(delta), math.Abs(pos.size))
+			direction := 1.0
+			if pos.size < 0 {
+				direction = -1
+			}
+			pnl[i] = closed * (price - pos.avgEntry) * direction
+			closing[i] = true
+		}
+
+		newSize := pos.size + delta
+		switch {
+		case math.Abs(newSize) < positionEpsilon:
+			newSize, pos.avgEntry = 0, 0
+		case pos.size == 0 || (pos.size > 0) != (newSize > 0):
+			pos.avgEntry = price
+		case math.Abs(newSize) > math.Abs(pos.size):
+			pos.avgEntry = (pos.avgEntry*math.Abs(pos.size) + price*math.Abs(delta)) / math.Abs(newSize)
+		}
+		pos.size = newSize
+	}
+
+	return pnl, closing
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"testing"
+)
+
+// Test ReconstructPositions size and average entry
+func TestReconstructPositions(t *testing.T) {
+	trades := []models.Trade{
+		createTestTrade("2025-01-01T10:00:00Z", "BTC", "B", 100, 1),
+		createTestTrade("2025-01-01T10:30:00Z", "ETH", "B", 10, 5),
+		createTestTrade("2025-01-01T11:00:00Z", "BTC", "B", 200, 1),   // Add: avg 150
+		createTestTrade("2025-01-01T12:00:00Z", "BTC", "A", 300, 0.5), // Reduce: avg unchanged
+		createTestTrade("2025-01-01T13:00:00Z", "BTC", "A", 250, 2.5), // Flip to 1 short at 250
+		createTestTrade("2025-01-01T14:00:00Z", "BTC", "B", 240, 1),   // Close
+	}
+
+	points := ReconstructPositions(trades, "BTC")
+
+	expected := []struct{ size, avgEntry float64 }{
+		{1, 100},
+		{2, 150},
+		{1.5, 150},
+		{-1, 250},
+		{0, 0},
+	}
+	if len(points) != len(expected) {
+		t.Fatalf("Expected %d points, got %d", len(expected), len(points))
+	}
+	for i, want := range expected {
+		if float64(points[i].Size) != want.size || float64(points[i].AvgEntry) != want.avgEntry {
+			t.Errorf("Point %d: expected size %v avg %v, got size %v avg %v", i, want.size, want.avgEntry, points[i].Size, points[i].AvgEntry)
+		}
+	}
+}
+
+// Test attributing realized P&L to fills that don't carry the exchange's
+func TestAttributeRealizedPnL(t *testing.T) {
+	reported := models.Money(7.5)
+	trades := []models.Trade{
+		createTestTrade("2025-01-01T10:00:00Z", "BTC", "B", 100, 2),
+		createTestTrade("2025-01-01T11:00:00Z", "BTC", "A", 130, 1),
+		createTestTrade("2025-01-01T12:00:00Z", "BTC", "A", 120, 1),
+	}
+	trades[2].RealizedPnL = &reported
+
+	attributed := AttributeRealizedPnL(trades)
+	if *attributed[0].RealizedPnL != 0 || *attributed[1].RealizedPnL != 30 {
+		t.Errorf("Expected 0 and 30 matched against the average entry, got %v and %v", *attributed[0].RealizedPnL, *attributed[1].RealizedPnL)
+	}
+	if *attributed[2].RealizedPnL != 7.5 {
+		t.Errorf("Expected the reported P&L kept, got %v", *attributed[2].RealizedPnL)
+	}
+	if trades[0].RealizedPnL != nil {
+		t.Error("Expected the input left unchanged")
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"hyperliquid-recon/models"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// priorityKey is the context key of the priority of upstream requests
+type priorityKey struct{}
+
+// WithPriority returns a context whose upstream requests are made at priority
+func WithPriority(ctx context.Context, priority models.JobPriority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFrom returns the priority of ctx's upstream requests; requests
+// without one, such as those made for API requests, are high priority
+func PriorityFrom(ctx context.Context) models.JobPriority {
+	if priority, ok := ctx.Value(priorityKey{}).(models.JobPriority); ok {
+		return priority
+	}
+	return models.PriorityHigh
+}
+
+// rateBudget gives high-priority fetches the upstream rate limit to
+// themselves: while any is running, low-priority fetches pause before their
+// next request. High-priority fetches never wait on it, so a paused
+// low-priority fetch can't hold up the fetches it is waiting for.
+type rateBudget struct {
+	high int           // High-priority fetches running
+	idle chan struct{} // Closed while no high-priority fetch is running
+	mu   sync.Mutex
+}
+
+// upstreamBudget is shared by every client since they all draw on the same upstream rate limit
+var upstreamBudget = newRateBudget()
+
+func newRateBudget() *rateBudget {
+	idle := make(chan struct{})
+	close(idle)
+	return &rateBudget{idle: idle}
+}
+
+// hold marks a high-priority fetch as running until the returned function is
+// called. It does nothing for low-priority contexts.
+func (b *rateBudget) hold(ctx context.Context) func() {
+	if PriorityFrom(ctx) != models.PriorityHigh {
+		return func() {}
+	}
+
+	b.mu.Lock()
+	if b.high == 0 {
+		b.idle = make(chan struct{})
+	}
+	b.high++
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.high--
+		if b.high == 0 {
+			close(b.idle)
+		}
+	}
+}
+
+// wait blocks a low-priority request until no high-priority fetch is running
+// or ctx is done. High-priority requests return at once.
+func (b *rateBudget) wait(ctx context.Context) error {
+	if PriorityFrom(ctx) == models.PriorityHigh {
+		return nil
+	}
+
+	b.mu.Lock()
+	idle := b.idle
+	b.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	default:
+	}
+
+	start := time.Now()
+	select {
+	case <-idle:
+		addEvent(ctx, "paused for high-priority requests", attribute.Int64("waitMs", time.Since(start).Milliseconds()))
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
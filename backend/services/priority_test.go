@@ -0,0 +1,82 @@
+package services
+
+import (
+	"container/heap"
+	"context"
+	"hyperliquid-recon/models"
+	"testing"
+	"time"
+)
+
+// Test that queued jobs are taken high priority first, then in the order queued
+func TestJobQueue(t *testing.T) {
+	var q jobQueue
+	for i, priority := range []models.JobPriority{models.PriorityLow, models.PriorityHigh, models.PriorityLow, models.PriorityHigh} {
+		heap.Push(&q, &queuedJob{job: &models.RefreshJob{ID: string(rune('a' + i)), Priority: priority}, seq: int64(i)})
+	}
+
+	var order string
+	for q.Len() > 0 {
+		order += heap.Pop(&q).(*queuedJob).job.ID
+	}
+	if order != "bdac" {
+		t.Errorf("Expected jobs in order bdac, got %s", order)
+	}
+}
+
+// Test that low-priority requests pause while high-priority fetches run
+func TestRateBudget(t *testing.T) {
+	b := newRateBudget()
+	high := context.Background()
+	low := WithPriority(context.Background(), models.PriorityLow)
+
+	if PriorityFrom(high) != models.PriorityHigh || PriorityFrom(low) != models.PriorityLow {
+		t.Fatal("Expected contexts without a priority to be high priority")
+	}
+
+	t.Run("should not pause anything while idle", func(t *testing.T) {
+		if err := b.wait(low); err != nil {
+			t.Errorf("Expected no wait, got %v", err)
+		}
+	})
+
+	t.Run("should pause low priority until high-priority fetches finish", func(t *testing.T) {
+		release := b.hold(high)
+		releaseSecond := b.hold(high)
+		b.hold(low)() // Low-priority fetches don't hold the budget
+
+		if err := b.wait(high); err != nil {
+			t.Errorf("Expected high priority not to wait, got %v", err)
+		}
+
+		resumed := make(chan error, 1)
+		go func() { resumed <- b.wait(low) }()
+
+		release()
+		select {
+		case <-resumed:
+			t.Fatal("Expected low priority to wait while a high-priority fetch runs")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		releaseSecond()
+		select {
+		case err := <-resumed:
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected low priority to resume")
+		}
+	})
+
+	t.Run("should stop waiting when the context is done", func(t *testing.T) {
+		defer b.hold(high)()
+
+		ctx, cancel := context.WithCancel(low)
+		cancel()
+		if err := b.wait(ctx); err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
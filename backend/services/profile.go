@@ -0,0 +1,63 @@
+package services
+
+import (
+	"fmt"
+	"hyperliquid-recon/models"
+	"time"
+)
+
+// BuildPerformanceProfile aggregates the realized P&L and win rate of the
+// trades made since since by weekday and hour of day in loc. Positions are
+// rebuilt from the first trade, which is assumed to open from flat.
+func BuildPerformanceProfile(address string, trades []models.Trade, since time.Time, loc *time.Location) models.PerformanceProfile {
+	profile := models.PerformanceProfile{
+		Address:    address,
+		Timezone:   loc.String(),
+		Weekdays:   make([]models.ProfileBucket, 7),
+		Hours:      make([]models.ProfileBucket, 24),
+		NetPnLGrid: make([][]float64, 7),
+	}
+	for day := range profile.Weekdays {
+		profile.Weekdays[day].Name = time.Weekday(day).String()
+		profile.NetPnLGrid[day] = make([]float64, 24)
+	}
+	for hour := range profile.Hours {
+		profile.Hours[hour].Name = fmt.Sprintf("%02d:00", hour)
+	}
+
+	pnl, closing := realizedPnL(trades)
+	for i, trade := range trades {
+		if trade.Time.Before(since) {
+			continue
+		}
+		local := trade.Time.In(loc)
+		addToBucket(&profile.Weekdays[local.Weekday()], trade, pnl[i], closing[i])
+		addToBucket(&profile.Hours[local.Hour()], trade, pnl[i], closing[i])
+		profile.NetPnLGrid[local.Weekday()][local.Hour()] += pnl[i] - float64(trade.Fee)
+	}
+
+	for _, buckets := range [][]models.ProfileBucket{profile.Weekdays, profile.Hours} {
+		for i := range buckets {
+			if buckets[i].ClosingFills > 0 {
+				buckets[i].WinRate = float64(buckets[i].Wins) / float64(buckets[i].ClosingFills)
+			}
+		}
+	}
+
+	return profile
+}
+
+// addToBucket adds one fill and the P&L it realized to bucket
+func addToBucket(bucket *models.ProfileBucket, trade models.Trade, pnl float64, closing bool) {
+	bucket.Fills++
+	bucket.Volume += float64(trade.Value)
+	bucket.RealizedPnL += pnl
+	bucket.Fees += float64(trade.Fee)
+	bucket.NetPnL += pnl - float64(trade.Fee)
+	if closing {
+		bucket.ClosingFills++
+		if pnl > 0 {
+			bucket.Wins++
+		}
+	}
+}
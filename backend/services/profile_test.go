@@ -0,0 +1,58 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"math"
+	"testing"
+	"time"
+)
+
+// Test P&L realized against the average entry
+func TestRealizedPnL(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := []models.Trade{
+		{Time: base, Coin: "ETH", Side: "B", Price: 100, Size: 2},
+		{Time: base.Add(time.Minute), Coin: "ETH", Side: "A", Price: 110, Size: 1},
+		// Flips from long 1 to short 1: closes the long at a loss
+		{Time: base.Add(2 * time.Minute), Coin: "ETH", Side: "A", Price: 90, Size: 2},
+		{Time: base.Add(3 * time.Minute), Coin: "ETH", Side: "B", Price: 80, Size: 1},
+	}
+
+	pnl, closing := realizedPnL(trades)
+	expected := []float64{0, 10, -10, 10}
+	for i := range expected {
+		if math.Abs(pnl[i]-expected[i]) > 1e-9 {
+			t.Errorf("Fill %d: expected realized P&L %v, got %v", i, expected[i], pnl[i])
+		}
+	}
+	if closing[0] || !closing[1] || !closing[2] || !closing[3] {
+		t.Errorf("Unexpected closing flags %v", closing)
+	}
+}
+
+// Test weekday and hour performance profile
+func TestBuildPerformanceProfile(t *testing.T) {
+	// Sunday 2025-06-01 22:00 in New York is Monday 02:00 UTC
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Timezone data unavailable: %v", err)
+	}
+	open := time.Date(2025, 6, 1, 22, 0, 0, 0, loc)
+	trades := []models.Trade{
+		{Time: open.AddDate(0, 0, -7), Coin: "BTC", Side: "B", Price: 100, Size: 1, Value: 100, Fee: 1},
+		{Time: open, Coin: "BTC", Side: "A", Price: 90, Size: 1, Value: 90, Fee: 1},
+	}
+
+	profile := BuildPerformanceProfile("0xabc", trades, open.Add(-time.Hour), loc)
+
+	sunday := profile.Weekdays[time.Sunday]
+	if sunday.Fills != 1 || sunday.RealizedPnL != -10 || sunday.NetPnL != -11 || sunday.WinRate != 0 {
+		t.Errorf("Unexpected Sunday bucket %+v", sunday)
+	}
+	if hour := profile.Hours[22]; hour.ClosingFills != 1 || hour.Name != "22:00" {
+		t.Errorf("Unexpected 22:00 bucket %+v", hour)
+	}
+	if profile.NetPnLGrid[time.Sunday][22] != -11 {
+		t.Errorf("Expected Sunday 22:00 net P&L -11, got %v", profile.NetPnLGrid[time.Sunday][22])
+	}
+}
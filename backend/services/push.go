@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types pushed to WebSocket subscribers
+const (
+	PushPnLUpdated   = "pnl.updated"       // A refresh finished; data is a models.PnLUpdate
+	PushAlert        = "dataquality.alert" // Data is a models.DataQualityAlert
+	PushBreakRaised  = "break.raised"      // Data is the new models.Break
+	PushBreakUpdated = "break.updated"     // A break's status, assignee or notes changed; data is the break
+)
+
+// PushEventTypes lists every event type a subscriber can filter on
+var PushEventTypes = []string{PushPnLUpdated, PushAlert, PushBreakRaised, PushBreakUpdated}
+
+// ErrInvalidPushType is returned for a subscription filter naming an unknown event type
+var ErrInvalidPushType = errors.New("unknown event type")
+
+// PushHub fans events out to a tenant's live subscribers, such as WebSocket
+// connections from the frontend. Publishing never waits: each subscriber has
+// PushBufferSize events of room, and one that is further behind misses events.
+type PushHub struct {
+	subscribers map[*PushSubscription]struct{}
+	mu          sync.RWMutex
+}
+
+// NewPushHub creates a hub with no subscribers
+func NewPushHub() *PushHub {
+	return &PushHub{subscribers: make(map[*PushSubscription]struct{})}
+}
+
+// PushSubscription receives the events matching its filter until it is closed
+type PushSubscription struct {
+	hub     *PushHub
+	events  chan models.PushEvent
+	filter  models.PushFilter
+	dropped int
+	mu      sync.Mutex
+}
+
+// Subscribe starts a subscription to the events matching filter
+func (h *PushHub) Subscribe(filter models.PushFilter) (*PushSubscription, error) {
+	sub := &PushSubscription{hub: h, events: make(chan models.PushEvent, config.PushBufferSize)}
+	if err := sub.SetFilter(filter); err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub, nil
+}
+
+// Subscribers returns how many subscriptions are open
+func (h *PushHub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers)
+}
+
+// publish sends an event to every subscriber whose filter matches it. It is
+// safe to call on a nil hub.
+func (h *PushHub) publish(eventType, address string, data interface{}) {
+	if h == nil {
+		return
+	}
+	event := models.PushEvent{Type: eventType, Address: address, Time: time.Now(), Data: data}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subscribers {
+		sub.deliver(event)
+	}
+}
+
+// Events returns the channel events are delivered on. It is closed by Close.
+func (s *PushSubscription) Events() <-chan models.PushEvent {
+	return s.events
+}
+
+// Filter returns the subscription's filter
+func (s *PushSubscription) Filter() models.PushFilter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filter
+}
+
+// SetFilter replaces the subscription's filter, from the next event on.
+// Addresses must already be resolved.
+func (s *PushSubscription) SetFilter(filter models.PushFilter) error {
+	for _, eventType := range filter.Types {
+		if !slices.Contains(PushEventTypes, eventType) {
+			return fmt.Errorf("%w %q; expected one of %s", ErrInvalidPushType, eventType, strings.Join(PushEventTypes, ", "))
+		}
+	}
+	if filter.Addresses == nil {
+		filter.Addresses = []string{}
+	}
+	if filter.Types == nil {
+		filter.Types = []string{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filter = filter
+	return nil
+}
+
+// Close ends the subscription and closes its events channel
+func (s *PushSubscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if _, open := s.hub.subscribers[s]; !open {
+		return
+	}
+	delete(s.hub.subscribers, s)
+	close(s.events)
+}
+
+// deliver queues event if it matches the filter, dropping it if the
+// subscriber is too far behind; caller must hold the hub's read lock
+func (s *PushSubscription) deliver(event models.PushEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.filter.Types) > 0 && !slices.Contains(s.filter.Types, event.Type) {
+		return
+	}
+	if len(s.filter.Addresses) > 0 && !slices.ContainsFunc(s.filter.Addresses, func(address string) bool {
+		return strings.EqualFold(address, event.Address)
+	}) {
+		return
+	}
+
+	select {
+	case s.events <- event:
+	default:
+		s.dropped++
+		if s.dropped == 1 || s.dropped%100 == 0 {
+			log.Printf("Push subscriber is falling behind; %d events dropped", s.dropped)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"testing"
+)
+
+// Test pushing events to subscribers by their address and type filters
+func TestPushHub(t *testing.T) {
+	hub := NewPushHub()
+	breaks, _ := NewBreakStore("")
+	breaks.UsePushHub(hub)
+
+	all, err := hub.Subscribe(models.PushFilter{})
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer all.Close()
+	mine, _ := hub.Subscribe(models.PushFilter{Addresses: []string{testAddress}, Types: []string{PushBreakRaised}})
+	defer mine.Close()
+
+	t.Run("should reject unknown event types", func(t *testing.T) {
+		if _, err := hub.Subscribe(models.PushFilter{Types: []string{"fills"}}); !errors.Is(err, ErrInvalidPushType) {
+			t.Errorf("Expected ErrInvalidPushType, got %v", err)
+		}
+		if hub.Subscribers() != 2 {
+			t.Errorf("Expected 2 subscribers, got %d", hub.Subscribers())
+		}
+	})
+
+	t.Run("should send events only to matching subscribers", func(t *testing.T) {
+		other := "0x0000000000000000000000000000000000000001"
+		b, _ := breaks.Create(other, "BTC", "2025-01-01", "Missing fill")
+		if event := <-all.Events(); event.Type != PushBreakRaised || event.Address != other || event.Data.(models.Break).ID != b.ID {
+			t.Errorf("Expected the new break, got %+v", event)
+		}
+		if len(mine.Events()) != 0 {
+			t.Errorf("Expected no event for another address, got %d", len(mine.Events()))
+		}
+
+		b, _ = breaks.Create(testAddress, "ETH", "2025-01-02", "Missing fill")
+		resolved := models.BreakResolved
+		breaks.Update(b.ID, &resolved, nil)
+		<-all.Events()
+		if event := <-all.Events(); event.Type != PushBreakUpdated || event.Data.(models.Break).Status != models.BreakResolved {
+			t.Errorf("Expected the resolved break, got %+v", event)
+		}
+		if event := <-mine.Events(); event.Type != PushBreakRaised || event.Address != testAddress {
+			t.Errorf("Expected the break raised for the address, got %+v", event)
+		}
+		if len(mine.Events()) != 0 {
+			t.Errorf("Expected the update to be filtered out, got %d events", len(mine.Events()))
+		}
+	})
+
+	t.Run("should drop events for a subscriber that falls behind", func(t *testing.T) {
+		for i := 0; i < config.PushBufferSize+5; i++ {
+			hub.publish(PushAlert, testAddress, models.DataQualityAlert{})
+		}
+		if len(all.Events()) != config.PushBufferSize {
+			t.Errorf("Expected a full buffer of %d events, got %d", config.PushBufferSize, len(all.Events()))
+		}
+	})
+
+	t.Run("should close the channel when unsubscribed", func(t *testing.T) {
+		mine.Close()
+		mine.Close()
+		if _, open := <-mine.Events(); open {
+			t.Error("Expected the events channel to be closed")
+		}
+		if hub.Subscribers() != 1 {
+			t.Errorf("Expected 1 subscriber, got %d", hub.Subscribers())
+		}
+	})
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQuarantineNotFound is returned for an unknown quarantined fill ID
+	ErrQuarantineNotFound = errors.New("quarantined fill not found")
+	// ErrNotQuarantined is returned when changing a fill that has already left quarantine
+	ErrNotQuarantined = errors.New("fill is no longer quarantined")
+	// ErrInvalidFill is returned for a corrected fill that isn't in the API's fill format
+	ErrInvalidFill = errors.New("invalid fill")
+)
+
+// QuarantineStore keeps the fills that couldn't be parsed, persisted as JSON
+// to path if one is set
+type QuarantineStore struct {
+	fills map[string]*models.QuarantinedFill // key: ID
+	mu    sync.RWMutex
+	path  string
+}
+
+// NewQuarantineStore creates a quarantine store, loading saved fills from path if it exists
+func NewQuarantineStore(path string) (*QuarantineStore, error) {
+	s := &QuarantineStore{fills: make(map[string]*models.QuarantinedFill), path: path}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := readStoreFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantine: %w", err)
+	}
+	var fills []models.QuarantinedFill
+	if err := json.Unmarshal(data, &fills); err != nil {
+		return nil, fmt.Errorf("failed to parse quarantine: %w", err)
+	}
+	for i := range fills {
+		s.fills[fills[i].ID] = &fills[i]
+	}
+	return s, nil
+}
+
+// add quarantines a fill of address that failed to parse with err. A fill
+// seen before, in or out of quarantine, isn't added again. It is safe to
+// call on a nil store.
+func (s *QuarantineStore) add(address string, fill FillResponse, err error) {
+	if s == nil {
+		return
+	}
+	raw, marshalErr := json.Marshal(fill)
+	if marshalErr != nil {
+		return
+	}
+	sum := sha256.Sum256(append([]byte(address+"\n"), raw...))
+	id := hex.EncodeToString(sum[:8])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.fills[id]; exists {
+		return
+	}
+	s.fills[id] = &models.QuarantinedFill{
+		ID:            id,
+		Address:       address,
+		Fill:          raw,
+		Error:         err.Error(),
+		Status:        models.QuarantinePending,
+		QuarantinedAt: time.Now(),
+	}
+	if err := s.persist(); err != nil {
+		log.Printf("Failed to save quarantine: %v", err)
+	}
+}
+
+// List returns the fills of address (of every address if it is empty) in
+// status (any status if it is empty), newest first
+func (s *QuarantineStore) List(address string, status models.QuarantineStatus) []models.QuarantinedFill {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fills := []models.QuarantinedFill{}
+	for _, fill := range s.fills {
+		if (address == "" || fill.Address == address) && (status == "" || fill.Status == status) {
+			fills = append(fills, *fill)
+		}
+	}
+	sort.Slice(fills, func(i, j int) bool {
+		if !fills[i].QuarantinedAt.Equal(fills[j].QuarantinedAt) {
+			return fills[i].QuarantinedAt.After(fills[j].QuarantinedAt)
+		}
+		return fills[i].ID < fills[j].ID
+	})
+	return fills
+}
+
+// Count returns how many fills of address are still quarantined. It is safe
+// to call on a nil store.
+func (s *QuarantineStore) Count(address string) int {
+	if s == nil {
+		return 0
+	}
+	return len(s.List(address, models.QuarantinePending))
+}
+
+// Fix replaces a quarantined fill with a corrected copy, to be parsed at the next reprocess
+func (s *QuarantineStore) Fix(id string, fill json.RawMessage) (models.QuarantinedFill, error) {
+	var parsed FillResponse
+	if err := json.Unmarshal(fill, &parsed); err != nil {
+		return models.QuarantinedFill{}, fmt.Errorf("%w: %v", ErrInvalidFill, err)
+	}
+	raw, _ := json.Marshal(parsed)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.pending(id)
+	if err != nil {
+		return models.QuarantinedFill{}, err
+	}
+	now := time.Now()
+	stored.Fill = raw
+	stored.FixedAt = &now
+	return *stored, s.persist()
+}
+
+// Discard takes a fill out of quarantine without merging it
+func (s *QuarantineStore) Discard(id string) (models.QuarantinedFill, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.pending(id)
+	if err != nil {
+		return models.QuarantinedFill{}, err
+	}
+	now := time.Now()
+	stored.Status = models.QuarantineDiscarded
+	stored.ResolvedAt = &now
+	return *stored, s.persist()
+}
+
+// pending returns the quarantined fill with the given ID; caller must hold s.mu
+func (s *QuarantineStore) pending(id string) (*models.QuarantinedFill, error) {
+	stored, exists := s.fills[id]
+	if !exists {
+		return nil, ErrQuarantineNotFound
+	}
+	if stored.Status != models.QuarantinePending {
+		return nil, ErrNotQuarantined
+	}
+	return stored, nil
+}
+
+// persist writes all fills to the quarantine file; caller must hold s.mu
+func (s *QuarantineStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	fills := make([]models.QuarantinedFill, 0, len(s.fills))
+	for _, fill := range s.fills {
+		fills = append(fills, *fill)
+	}
+	sort.Slice(fills, func(i, j int) bool { return fills[i].ID < fills[j].ID })
+	return writeJSONFile(s.path, fills)
+}
+
+// UseQuarantineStore sets the store fills that fail to parse are kept in
+// instead of being dropped
+func (rs *ReconciliationService) UseQuarantineStore(quarantine *QuarantineStore) {
+	rs.quarantine = quarantine
+	rs.hlClient.quarantine = quarantine
+}
+
+// ReprocessQuarantine parses the quarantined fills of address (of every
+// address if it is empty) again. Those that now parse are merged into their
+// address's cache and leave quarantine; the rest stay with their new error.
+// Fills of an address that is no longer cached can't be merged and stay too.
+func (rs *ReconciliationService) ReprocessQuarantine(address string) (models.QuarantineReprocess, error) {
+	result := models.QuarantineReprocess{Fills: []models.QuarantinedFill{}}
+	if rs.quarantine == nil {
+		return result, nil
+	}
+	s := rs.quarantine
+
+	// Parse everything first, then merge each address under its own lock
+	s.mu.Lock()
+	recovered := make(map[string][]models.Trade)
+	var reprocessed []*models.QuarantinedFill
+	for _, fill := range s.fills {
+		if fill.Status != models.QuarantinePending || (address != "" && fill.Address != address) {
+			continue
+		}
+		var raw FillResponse
+		err := json.Unmarshal(fill.Fill, &raw)
+		var trade models.Trade
+		if err == nil {
+			trade, err = rs.hlClient.convertFillToTrade(raw)
+		}
+		if err == nil {
+			if _, cached := rs.cached(fill.Address); !cached {
+				err = ErrNotCached
+			}
+		}
+		reprocessed = append(reprocessed, fill)
+		if err != nil {
+			fill.Error = err.Error()
+			result.Failed++
+			continue
+		}
+		now := time.Now()
+		fill.Status = models.QuarantineReprocessed
+		fill.ResolvedAt = &now
+		recovered[fill.Address] = append(recovered[fill.Address], trade)
+		result.Recovered++
+	}
+	sort.Slice(reprocessed, func(i, j int) bool { return reprocessed[i].ID < reprocessed[j].ID })
+	for _, fill := range reprocessed {
+		result.Fills = append(result.Fills, *fill)
+	}
+	err := s.persist()
+	s.mu.Unlock()
+	if err != nil {
+		return result, err
+	}
+
+	for fillAddress, trades := range recovered {
+		unlock, _ := rs.lockAddress(context.Background(), fillAddress)
+		if cache := rs.record(context.Background(), models.TradeEvent{Type: models.EventTradesRecovered, Address: fillAddress, Trades: trades}); cache != nil {
+			rs.checkPeriods(fillAddress, cache, "quarantine")
+		}
+		unlock()
+		log.Printf("Recovered %d quarantined fills for %s", len(trades), fillAddress)
+	}
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test quarantining fills that fail to parse and reprocessing them once fixed
+func TestQuarantine(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]FillResponse{
+			{Time: start.Add(time.Hour).UnixMilli(), Coin: "ETH", Side: "B", Price: "3000", Size: "1", Fee: "0.5"},
+			{Time: start.Add(2 * time.Hour).UnixMilli(), Coin: "ETH", Side: "A", Price: "3,100", Size: "1", Fee: "0.5"},
+		})
+	}))
+	defer server.Close()
+
+	quarantine, err := NewQuarantineStore(filepath.Join(t.TempDir(), "quarantine.json"))
+	if err != nil {
+		t.Fatalf("Failed to create quarantine: %v", err)
+	}
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = server.URL
+	rs.UseQuarantineStore(quarantine)
+
+	trades, err := rs.hlClient.FetchTradesInRange(context.Background(), testAddress, start, start.Add(24*time.Hour))
+	if err != nil || len(trades) != 1 {
+		t.Fatalf("Expected the parseable fill, got %v (%v)", trades, err)
+	}
+	rs.ImportTrades(testAddress, trades)
+
+	fills := quarantine.List(testAddress, "")
+	if len(fills) != 1 || fills[0].Status != models.QuarantinePending || quarantine.Count(testAddress) != 1 {
+		t.Fatalf("Expected the unparseable fill to be quarantined, got %+v", fills)
+	}
+	id := fills[0].ID
+
+	t.Run("should not quarantine a fill twice", func(t *testing.T) {
+		rs.hlClient.FetchTradesInRange(context.Background(), testAddress, start, start.Add(25*time.Hour))
+		if quarantine.Count(testAddress) != 1 {
+			t.Errorf("Expected 1 quarantined fill, got %d", quarantine.Count(testAddress))
+		}
+	})
+
+	t.Run("should leave fills that still fail in quarantine", func(t *testing.T) {
+		result, err := rs.ReprocessQuarantine(testAddress)
+		if err != nil || result.Recovered != 0 || result.Failed != 1 {
+			t.Errorf("Expected the fill to fail again, got %+v (%v)", result, err)
+		}
+	})
+
+	t.Run("should merge fixed fills into the cache", func(t *testing.T) {
+		if _, err := quarantine.Fix(id, json.RawMessage(`{"px": "oops`)); !errors.Is(err, ErrInvalidFill) {
+			t.Errorf("Expected ErrInvalidFill, got %v", err)
+		}
+		fixed := FillResponse{Time: start.Add(2 * time.Hour).UnixMilli(), Coin: "ETH", Side: "A", Price: "3100", Size: "1", Fee: "0.5"}
+		raw, _ := json.Marshal(fixed)
+		if _, err := quarantine.Fix(id, raw); err != nil {
+			t.Fatalf("Expected the fix to be saved, got %v", err)
+		}
+
+		result, err := rs.ReprocessQuarantine("")
+		if err != nil || result.Recovered != 1 || len(result.Fills) != 1 || result.Fills[0].Status != models.QuarantineReprocessed {
+			t.Fatalf("Expected the fill to be recovered, got %+v (%v)", result, err)
+		}
+		cached, _ := rs.CachedTrades(testAddress)
+		if len(cached) != 2 || cached[1].Price != 3100 {
+			t.Errorf("Expected the recovered fill in the cache, got %+v", cached)
+		}
+		if quarantine.Count(testAddress) != 0 {
+			t.Errorf("Expected nothing left in quarantine, got %d", quarantine.Count(testAddress))
+		}
+		if _, err := quarantine.Discard(id); !errors.Is(err, ErrNotQuarantined) {
+			t.Errorf("Expected ErrNotQuarantined, got %v", err)
+		}
+	})
+
+	t.Run("should keep the quarantine across restarts", func(t *testing.T) {
+		reloaded, err := NewQuarantineStore(quarantine.path)
+		if err != nil || len(reloaded.List("", models.QuarantineReprocessed)) != 1 {
+			t.Errorf("Expected the reprocessed fill to be saved, got %+v (%v)", reloaded.List("", ""), err)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"log"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrRebuildRejected is returned when a rebuilt cache fails its checks and the
+// old cache is kept
+var ErrRebuildRejected = errors.New("rebuilt cache rejected")
+
+// RebuildCache fetches the last days days of trades for address from scratch
+// and, if the fetch is complete and its fills pass the start position check,
+// replaces the address's cache with them in one step. The fetch runs without
+// the address's lock, so refreshes and reads carry on with the old cache until
+// the swap; trades made after the fetch started are picked up by the next
+// refresh. A rejected rebuild leaves the old cache as it was and returns an
+// error wrapping ErrRebuildRejected.
+func (rs *ReconciliationService) RebuildCache(ctx context.Context, address string, days int) (rebuild models.CacheRebuild, err error) {
+	ctx, span := tracer.Start(ctx, "ReconciliationService.RebuildCache",
+		trace.WithAttributes(attribute.String("address", address), attribute.Int("days", days)))
+	defer func() { endSpan(span, err) }()
+
+	rebuild.Address = address
+	now := exchangeNow()
+	start := now.Add(-time.Duration(days) * 24 * time.Hour)
+
+	log.Printf("Rebuilding cache for %s: fetching all trades for last %d days", address, days)
+	trades, err := rs.hlClient.FetchTradesInRange(WithFreshResponses(ctx), address, start, now)
+	rebuild.TradesFetched = len(trades)
+	var partial *PartialError
+	if errors.As(err, &partial) {
+		return rebuild, fmt.Errorf("%w: %v", ErrRebuildRejected, err)
+	}
+	if err != nil {
+		return rebuild, err
+	}
+
+	discrepancies, _, _ := CheckStartPositions(trades)
+	rebuild.Discrepancies = len(discrepancies)
+	if len(discrepancies) > 0 {
+		return rebuild, fmt.Errorf("%w: %d start position discrepancies in the fetched fills", ErrRebuildRejected, len(discrepancies))
+	}
+
+	unlock, err := rs.lockAddress(ctx, address)
+	if err != nil {
+		return rebuild, err
+	}
+	defer unlock()
+
+	if old, exists := rs.cached(address); exists {
+		rebuild.TradesReplaced = len(old.trades)
+	}
+	cache := rs.record(ctx, models.TradeEvent{
+		Type:    models.EventTradesReset,
+		Address: address,
+		Window:  &models.TimeRange{Start: start, End: now},
+		Days:    days,
+		Trades:  trades,
+	})
+	rebuild.Swapped = true
+	rs.checkPeriods(address, cache, "cache rebuild")
+
+	log.Printf("Rebuilt cache for %s: %d trades replaced %d", address, len(trades), rebuild.TradesReplaced)
+	return rebuild, nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Test RebuildCache swapping in a fresh cache only when it passes its checks
+func TestRebuildCache(t *testing.T) {
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	// newServer returns fills of 1 ETH each, the last reporting startPosition
+	// off by skew
+	newServer := func(skew float64) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var fills []FillResponse
+			for i := 0; i < 3; i++ {
+				start := float64(i)
+				if i == 2 {
+					start += skew
+				}
+				fills = append(fills, FillResponse{
+					Time:          base.Add(time.Duration(i) * time.Minute).UnixMilli(),
+					Coin:          "ETH",
+					Side:          "B",
+					Price:         "100",
+					Size:          "1",
+					StartPosition: strconv.FormatFloat(start, 'f', -1, 64),
+				})
+			}
+			json.NewEncoder(w).Encode(fills)
+		}))
+	}
+
+	corrupt := []models.Trade{
+		createTestTrade(base.Format(time.RFC3339), "ETH", "B", 100, 1),
+		createTestTrade(base.Add(30*time.Second).Format(time.RFC3339), "ETH", "A", 100, 1),
+	}
+
+	t.Run("should replace the cache when the rebuild passes its checks", func(t *testing.T) {
+		server := newServer(0)
+		defer server.Close()
+		rs := NewReconciliationService()
+		rs.hlClient.apiURL = server.URL
+		rs.ImportTrades(testAddress, corrupt)
+
+		rebuild, err := rs.RebuildCache(context.Background(), testAddress, 1)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !rebuild.Swapped || rebuild.TradesFetched != 3 || rebuild.TradesReplaced != 2 {
+			t.Errorf("Unexpected rebuild %+v", rebuild)
+		}
+
+		trades, _ := rs.CachedTrades(testAddress)
+		if len(trades) != 3 || trades[0].StartPosition == nil {
+			t.Errorf("Expected the rebuilt trades to be cached, got %d", len(trades))
+		}
+	})
+
+	t.Run("should keep the old cache when the rebuild fails its checks", func(t *testing.T) {
+		server := newServer(0.5)
+		defer server.Close()
+		rs := NewReconciliationService()
+		rs.hlClient.apiURL = server.URL
+		rs.ImportTrades(testAddress, corrupt)
+
+		rebuild, err := rs.RebuildCache(context.Background(), testAddress, 1)
+		if !errors.Is(err, ErrRebuildRejected) {
+			t.Fatalf("Expected ErrRebuildRejected, got %v", err)
+		}
+		if rebuild.Swapped || rebuild.Discrepancies != 1 {
+			t.Errorf("Unexpected rebuild %+v", rebuild)
+		}
+
+		if trades, _ := rs.CachedTrades(testAddress); len(trades) != 2 {
+			t.Errorf("Expected the old cache to be kept, got %d trades", len(trades))
+		}
+	})
+
+	t.Run("should run as a background job", func(t *testing.T) {
+		server := newServer(0)
+		defer server.Close()
+		rs := NewReconciliationService()
+		rs.hlClient.apiURL = server.URL
+		jm := NewJobManager(rs, NewWebhookDispatcher("secret"))
+
+		job := jm.SubmitCacheRebuild(context.Background(), testAddress, 1)
+		if job.Kind != models.JobCacheRebuild || job.Priority != models.PriorityLow {
+			t.Errorf("Expected a low-priority rebuild job, got %s at %s", job.Kind, job.Priority)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			job, _ = jm.GetJob(job.ID)
+			if job.FinishedAt != nil || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if job.Status != models.JobSucceeded || job.Rebuild == nil || !job.Rebuild.Swapped {
+			t.Errorf("Expected a successful rebuild, got %s with %+v", job.Status, job.Rebuild)
+		}
+	})
+}
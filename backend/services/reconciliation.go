@@ -1,131 +1,704 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"hyperliquid-recon/config"
 	"hyperliquid-recon/models"
 	"log"
+	"math"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Errors returned when a range of cached trades can't be invalidated
+var (
+	ErrNotCached      = errors.New("no cached trades for address; refresh it first")
+	ErrRangeNotCached = errors.New("range is outside the cached period")
 )
 
 // AccountCache stores cached data for a specific account
 type AccountCache struct {
 	trades        []models.Trade
 	lastFetchTime time.Time
-	cachedDays    int // Maximum days of data we have in cache
+	coverageStart time.Time          // Earliest time the cached trades cover
+	cachedDays    int                // Maximum days of data we have in cache
+	missingRanges []models.TimeRange // Windows lost to partial fetch failures
+	imported      bool               // Bootstrapped from imported files and not yet topped up from the API
 }
 
 // ReconciliationService handles trade reconciliation and P&L calculations
 type ReconciliationService struct {
-	accountCache map[string]*AccountCache // key: address
-	dailyPnL     map[string]*models.DailyPnL
-	mu           sync.RWMutex
+	accountCache map[string]*AccountCache        // key: address
+	summary      atomic.Pointer[summarySnapshot] // Current summary; read without locking
+	revalidating atomic.Bool
+	overlap      time.Duration // Newest cached history fetched again by each incremental refresh
+	addressLocks *addressLocks // Serialize fetches and cache writes per address
+	mu           sync.RWMutex  // Guards the cache map; held only briefly
 	hlClient     *HyperliquidClient
+	addressBook  *AddressBook        // Optional; supplies labels for summaries
+	breaks       *BreakStore         // Optional; receives breaks found by checks
+	periods      *PeriodStore        // Optional; records restatements of locked periods
+	events       *EventStore         // Optional; log of the events the caches are built from
+	runs         *RunStore           // Optional; records the result of each reconciliation
+	snapshots    *TradeSnapshotStore // Optional; keeps the trades each refresh changed
+	dataQuality  *DataQualityMonitor // Optional; raises alerts for anomalies found by refreshes
+	quarantine   *QuarantineStore    // Optional; keeps fills that fail to parse
+	push         *PushHub            // Optional; told when a refresh finishes
 }
 
 // NewReconciliationService creates a new reconciliation service
 func NewReconciliationService() *ReconciliationService {
-	return &ReconciliationService{
+	rs := &ReconciliationService{
 		accountCache: make(map[string]*AccountCache),
-		dailyPnL:     make(map[string]*models.DailyPnL),
+		overlap:      config.RevalidationOverlap,
+		addressLocks: newAddressLocks(),
 		hlClient:     NewHyperliquidClient(),
 	}
+	rs.summary.Store(&summarySnapshot{dailyPnL: make(map[string]*models.DailyPnL)})
+	return rs
+}
+
+// UseAddressBook sets the address book used to label summaries and results
+func (rs *ReconciliationService) UseAddressBook(addressBook *AddressBook) {
+	rs.addressBook = addressBook
+}
+
+// UseBreakStore sets the store that breaks found by consistency checks are raised in
+func (rs *ReconciliationService) UseBreakStore(breaks *BreakStore) {
+	rs.breaks = breaks
+}
+
+// UsePeriodStore sets the store of locked periods that changes to cached trades are checked against
+func (rs *ReconciliationService) UsePeriodStore(periods *PeriodStore) {
+	rs.periods = periods
+}
+
+// UseEventStore sets the log that changes to cached trades are recorded in.
+// Call Rebuild afterwards to load the caches from it.
+func (rs *ReconciliationService) UseEventStore(events *EventStore) {
+	rs.events = events
+}
+
+// UseRunStore sets the store each reconciliation run is recorded in
+func (rs *ReconciliationService) UseRunStore(runs *RunStore) {
+	rs.runs = runs
+}
+
+// UseTradeSnapshotStore sets the store the trades of an address are kept in
+// when a refresh changes them
+func (rs *ReconciliationService) UseTradeSnapshotStore(snapshots *TradeSnapshotStore) {
+	rs.snapshots = snapshots
+}
+
+// UseDataQualityMonitor sets the monitor that checks what each refresh ingested
+func (rs *ReconciliationService) UseDataQualityMonitor(monitor *DataQualityMonitor) {
+	rs.dataQuality = monitor
+}
+
+// UsePushHub sets the hub live subscribers are sent "pnl.updated" events through
+func (rs *ReconciliationService) UsePushHub(push *PushHub) {
+	rs.push = push
+}
+
+// Label returns the address book label for address, or "" if it has none
+func (rs *ReconciliationService) Label(address string) string {
+	return rs.addressBook.Label(address)
 }
 
 // FetchAndReconcile fetches trades for an address and calculates P&L
 // Uses intelligent caching: incremental fetch for same range, cache reuse for smaller range
-func (rs *ReconciliationService) FetchAndReconcile(address string, days int) error {
-	rs.mu.Lock()
-	defer rs.mu.Unlock()
+// If only part of the range could be fetched, the partial data is cached and used,
+// and the returned *PartialError describes the window that is missing.
+// Calls for the same address run one at a time in the order they were made;
+// calls for different addresses run in parallel, and the last to finish sets
+// the current summary. The summary is published in one step once it is
+// complete, so readers never wait for a refresh or see part of one.
+func (rs *ReconciliationService) FetchAndReconcile(ctx context.Context, address string, days int) (err error) {
+	ctx, span := tracer.Start(ctx, "ReconciliationService.FetchAndReconcile",
+		trace.WithAttributes(attribute.String("address", address), attribute.Int("days", days)))
+	defer func() { endSpan(span, err) }()
+
+	if err := rs.admitRefresh(ctx, address, days); err != nil {
+		return err
+	}
+	unlock, err := rs.lockAddress(ctx, address)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// Another refresh of address may have been recorded while this one waited
+	policy := KeyPolicyFrom(ctx)
+	if err := policy.CheckRefresh(address, days, time.Now()); err != nil {
+		return err
+	}
+	ctx, tally := withIngestTally(ctx)
+
+	// The trades before the refresh are kept if it changes them, for /api/diff
+	var before []models.Trade
+	var hadCache bool
+	if rs.snapshots != nil {
+		before, hadCache = rs.CachedTrades(address)
+	}
+
+	now := exchangeNow()
+	cache, trades, coverageStart, err := rs.updateCache(ctx, address, days, now)
+	if cache == nil {
+		return err
+	}
+	policy.RecordRefresh(address, time.Now())
+	if hadCache {
+		after, _ := rs.CachedTrades(address)
+		rs.snapshots.Record(address, before, after, time.Now())
+	}
+
+	_, pnlSpan := tracer.Start(ctx, "ReconciliationService.calculateDailyPnL", trace.WithAttributes(attribute.Int("trades", len(trades))))
+	dailyPnL, dailyPnLUTC := rs.buildDailyPnL(trades), rs.buildDailyPnLIn(trades, time.UTC)
+	pnlSpan.End()
+	missing := rangesEndingAfter(cache.missingRanges, coverageStart)
+
+	discrepancies := rs.runChecks(address, trades, missing)
+	positionGaps := groupGaps(discrepancies)
+	rs.checkPeriods(address, cache, "refresh")
+	rs.checkIngest(address, cache, tally, missing, discrepancies, now)
+	accountValues := rs.fetchAccountValues(ctx, address)
+
+	rs.summary.Store(&summarySnapshot{
+		dailyPnL:      dailyPnL,
+		dailyPnLUTC:   dailyPnLUTC,
+		coverage:      models.TimeRange{Start: coverageStart, End: now},
+		missing:       missing,
+		positionGaps:  positionGaps,
+		accountValues: accountValues,
+		refreshedAt:   time.Now(),
+		address:       address,
+		days:          days,
+	})
+
+	records, total := sortedDailyRecords(dailyPnL)
+	if rs.runs != nil {
+		rs.runs.Record(address, rs.Label(address), days, models.TimeRange{Start: coverageStart, End: now}, trades, records, total)
+	}
+	rs.push.publish(PushPnLUpdated, address, models.PnLUpdate{
+		Label:      rs.Label(address),
+		Days:       days,
+		TradeCount: len(trades),
+		TotalPnL:   total,
+		Incomplete: len(missing) > 0,
+	})
+
+	log.Printf("Reconciliation complete for %s: %d trades, %d days", address, len(trades), len(dailyPnL))
+	return err
+}
+
+// fetchAccountValues fetches the account value history of address if its
+// returns are measured against it. Failures are logged and leave the summary
+// without returns rather than failing the refresh.
+func (rs *ReconciliationService) fetchAccountValues(ctx context.Context, address string) []models.AccountValue {
+	if entry, ok := rs.addressBook.Entry(address); !ok || !entry.DeriveCapital {
+		return nil
+	}
+
+	history, err := rs.hlClient.FetchAccountValueHistory(ctx, address)
+	if err != nil {
+		log.Printf("Failed to fetch account value history for %s: %v", address, err)
+		return nil
+	}
+	return history
+}
+
+// checkPeriods records restatements of the locked periods of address that its
+// cached trades now disagree with; caller must hold the address's lock
+func (rs *ReconciliationService) checkPeriods(address string, cache *AccountCache, reason string) {
+	if !rs.periods.HasLocks(address) {
+		return
+	}
+
+	records, _ := sortedDailyRecords(rs.buildDailyPnL(cache.trades))
+	if restated := rs.periods.Restate(address, records, cache.coverageStart, cache.missingRanges, reason); len(restated) > 0 {
+		log.Printf("Recorded %d restatements of locked periods for %s (%s)", len(restated), address, reason)
+	}
+}
+
+// checkIngest runs the data-quality checks on what a refresh of address
+// ingested; caller must hold the address's lock
+func (rs *ReconciliationService) checkIngest(address string, cache *AccountCache, tally *ingestTally, missing []models.TimeRange, discrepancies []models.PositionDiscrepancy, now time.Time) {
+	rs.dataQuality.check(ingestCheck{
+		address:       address,
+		label:         rs.Label(address),
+		trades:        cache.trades,
+		coverageStart: cache.coverageStart,
+		missing:       missing,
+		discrepancies: discrepancies,
+		tally:         tally,
+	}, now)
+}
+
+// LockPeriod locks month (YYYY-MM) for address with the daily P&L of its cached trades
+func (rs *ReconciliationService) LockPeriod(address, month string) (models.PeriodLock, error) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
 
 	cache, exists := rs.accountCache[address]
-	now := time.Now()
+	if !exists {
+		return models.PeriodLock{}, ErrPeriodNotCovered
+	}
+
+	records, _ := sortedDailyRecords(rs.buildDailyPnL(cache.trades))
+	return rs.periods.Lock(address, rs.Label(address), month, records, cache.coverageStart, cache.missingRanges)
+}
+
+// RefreshCache brings the cache for address up to date for the last days days
+// without changing the current summary, and returns a copy of the trades in that range
+func (rs *ReconciliationService) RefreshCache(ctx context.Context, address string, days int) (_ []models.Trade, err error) {
+	ctx, span := tracer.Start(ctx, "ReconciliationService.RefreshCache",
+		trace.WithAttributes(attribute.String("address", address), attribute.Int("days", days)))
+	defer func() { endSpan(span, err) }()
+
+	if err := rs.admitRefresh(ctx, address, days); err != nil {
+		return nil, err
+	}
+	unlock, err := rs.lockAddress(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	// Another refresh of address may have been recorded while this one waited
+	policy := KeyPolicyFrom(ctx)
+	if err := policy.CheckRefresh(address, days, time.Now()); err != nil {
+		return nil, err
+	}
+	ctx, tally := withIngestTally(ctx)
+
+	now := exchangeNow()
+	cache, trades, coverageStart, err := rs.updateCache(ctx, address, days, now)
+	if cache == nil {
+		return nil, err
+	}
+	policy.RecordRefresh(address, time.Now())
+
+	missing := rangesEndingAfter(cache.missingRanges, coverageStart)
+	discrepancies := rs.runChecks(address, trades, missing)
+	rs.checkPeriods(address, cache, "refresh")
+	rs.checkIngest(address, cache, tally, missing, discrepancies, now)
+	trades = append([]models.Trade(nil), trades...)
+	canonicalize(trades)
+	return trades, err
+}
+
+// RefetchRange fetches [start, end) for address again and replaces the cached
+// trades in that window with the result, dropping any that the exchange no
+// longer reports. The window is always fetched from the API, never the response
+// cache. If the fetch is partial, the result is merged instead and the
+// missing window recorded. It returns a copy of all cached trades before end,
+// so the window's first fill can be checked against the one before it. A
+// re-fetch repairs the cache rather than refreshing it, so the key policy of
+// ctx only limits the window's length.
+func (rs *ReconciliationService) RefetchRange(ctx context.Context, address string, start, end time.Time) (_ []models.Trade, err error) {
+	ctx, span := tracer.Start(ctx, "ReconciliationService.RefetchRange", trace.WithAttributes(attribute.String("address", address)))
+	defer func() { endSpan(span, err) }()
+
+	if err := KeyPolicyFrom(ctx).CheckDays(int(math.Ceil(end.Sub(start).Hours() / 24))); err != nil {
+		return nil, err
+	}
+	unlock, err := rs.lockAddress(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, exists := rs.cached(address); !exists {
+		return nil, fmt.Errorf("no cached trades for %s", address)
+	}
+
+	log.Printf("Re-fetching %s from %s to %s", address, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	trades, err := rs.hlClient.FetchTradesInRange(WithFreshResponses(ctx), address, start, end.Add(-time.Millisecond))
+	missing, partial := partialRange(err)
+	if err != nil && !partial {
+		return nil, err
+	}
+
+	cache := rs.record(ctx, models.TradeEvent{
+		Type:    models.EventTradesRefetched,
+		Address: address,
+		Window:  &models.TimeRange{Start: start, End: end},
+		Missing: missing,
+		Trades:  trades,
+	})
+	rs.checkPeriods(address, cache, fmt.Sprintf("re-fetch of %s to %s", start.Format(time.RFC3339), end.Format(time.RFC3339)))
+
+	var before []models.Trade
+	for _, trade := range cache.trades {
+		if trade.Time.Before(end) {
+			before = append(before, trade)
+		}
+	}
+	canonicalize(before)
+	return before, err
+}
+
+// InvalidateRange drops the cached trades of address in [start, end) and marks
+// the window missing, so summaries report it as incomplete until it is fetched
+// again by RefetchRange or the next refresh. The window is clipped to the
+// period the cache covers. The dropped trades stay in the event log.
+func (rs *ReconciliationService) InvalidateRange(ctx context.Context, address string, start, end time.Time) (models.CacheInvalidation, error) {
+	unlock, err := rs.lockAddress(ctx, address)
+	if err != nil {
+		return models.CacheInvalidation{}, err
+	}
+	defer unlock()
+
+	cache, exists := rs.cached(address)
+	if !exists {
+		return models.CacheInvalidation{}, ErrNotCached
+	}
+	if start.Before(cache.coverageStart) {
+		start = cache.coverageStart
+	}
+	if end.After(cache.lastFetchTime) {
+		end = cache.lastFetchTime
+	}
+	if !start.Before(end) {
+		return models.CacheInvalidation{}, ErrRangeNotCached
+	}
+
+	before := len(cache.trades)
+	window := models.TimeRange{Start: start, End: end}
+	cache = rs.record(ctx, models.TradeEvent{Type: models.EventTradesInvalidated, Address: address, Window: &window})
+	rs.checkPeriods(address, cache, fmt.Sprintf("invalidation of %s to %s", start.Format(time.RFC3339), end.Format(time.RFC3339)))
+
+	log.Printf("Invalidated %s from %s to %s (%d trades dropped)", address, start.Format(time.RFC3339), end.Format(time.RFC3339), before-len(cache.trades))
+	return models.CacheInvalidation{Address: address, Label: rs.Label(address), Window: window, TradesRemoved: before - len(cache.trades)}, nil
+}
+
+// PruneTrades drops the cached trades of address before cutoff and returns
+// how many there were; a dry run only counts them. Unlike an invalidation,
+// the pruned period isn't fetched again and locked periods aren't restated.
+func (rs *ReconciliationService) PruneTrades(ctx context.Context, address string, cutoff time.Time, dryRun bool) (int, error) {
+	unlock, err := rs.lockAddress(ctx, address)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	cache, exists := rs.cached(address)
+	if !exists {
+		return 0, ErrNotCached
+	}
+	pruned := sort.Search(len(cache.trades), func(i int) bool { return !cache.trades[i].Time.Before(cutoff) })
+	if dryRun || pruned == 0 {
+		return pruned, nil
+	}
+
+	window := models.TimeRange{Start: cache.coverageStart, End: cutoff}
+	rs.record(ctx, models.TradeEvent{Type: models.EventTradesPruned, Address: address, Window: &window})
+	return pruned, nil
+}
+
+// runChecks checks freshly fetched trades for start position gaps and raises
+// breaks for them and for missing ranges. It returns the discrepancies found.
+func (rs *ReconciliationService) runChecks(address string, trades []models.Trade, missing []models.TimeRange) []models.PositionDiscrepancy {
+	discrepancies, _, _ := CheckStartPositions(trades)
+	if len(discrepancies) > 0 {
+		log.Printf("Warning: %d start position discrepancies for %s; fills may be missing or duplicated", len(discrepancies), address)
+	}
+
+	rs.breaks.RecordChecks(address, discrepancies, missing)
+	return discrepancies
+}
+
+// updateCache fetches whatever the cache for address is missing for the last
+// days days and returns the cache, the trades in the requested range and the
+// start of the period they cover. The cache is nil if nothing could be fetched.
+// Caller must hold the address's lock.
+func (rs *ReconciliationService) updateCache(ctx context.Context, address string, days int, now time.Time) (*AccountCache, []models.Trade, time.Time, error) {
+	cache, exists := rs.cached(address)
 
 	if exists && !cache.lastFetchTime.IsZero() {
-		timeSinceLastFetch := now.Sub(cache.lastFetchTime)
+		cacheUsable := cache.usable(now)
 
 		// Case 1: Requesting SMALLER time range than cached (e.g., 7D when we have 30D)
-		if days <= cache.cachedDays && timeSinceLastFetch < time.Hour {
+		if days <= cache.cachedDays && cacheUsable {
 			log.Printf("Cache reuse for %s: requested %d days, have %d days cached", address, days, cache.cachedDays)
 
-			// Fetch only new trades since last fetch
-			newTrades, err := rs.hlClient.FetchTradesInRange(address, cache.lastFetchTime, now)
+			rs.refetchMissingRanges(ctx, address, cache)
+
+			// Fetch only new trades since last fetch (keep original cachedDays)
+			newTrades, err := rs.fetchNewTrades(ctx, address, cache, now)
 			if err != nil {
-				return err
+				return nil, nil, time.Time{}, err
 			}
-
 			if len(newTrades) > 0 {
-				log.Printf("Found %d new trades, merging with %d cached trades", len(newTrades), len(cache.trades))
-				cache.trades = rs.mergeTrades(cache.trades, newTrades)
+				log.Printf("Found %d new trades, %d trades now cached", len(newTrades), len(cache.trades))
 			} else {
 				log.Printf("No new trades found, using cached trades")
 			}
 
-			// Update last fetch time (keep original cachedDays)
-			cache.lastFetchTime = now
-
 			// Filter trades to requested time range
 			cutoffTime := now.Add(-time.Duration(days) * 24 * time.Hour)
 			filteredTrades := rs.filterTradesByTime(cache.trades, cutoffTime)
 
 			log.Printf("Filtered %d trades to %d trades for %d days", len(cache.trades), len(filteredTrades), days)
-
-			// Calculate P&L from filtered trades
-			rs.calculateDailyPnLFromTrades(filteredTrades)
-
-			log.Printf("Cache reuse complete: %d trades, %d days", len(filteredTrades), len(rs.dailyPnL))
-			return nil
+			return cache, filteredTrades, cutoffTime, err
 		}
 
 		// Case 2: Requesting SAME time range as cached
-		if days == cache.cachedDays && timeSinceLastFetch < time.Hour {
+		if days == cache.cachedDays && cacheUsable {
 			log.Printf("Incremental fetch for %s: fetching new trades since %s", address, cache.lastFetchTime.Format(time.RFC3339))
 
+			rs.refetchMissingRanges(ctx, address, cache)
+
 			// Fetch only new trades since last fetch
-			newTrades, err := rs.hlClient.FetchTradesInRange(address, cache.lastFetchTime, now)
+			newTrades, err := rs.fetchNewTrades(ctx, address, cache, now)
 			if err != nil {
-				return err
+				return nil, nil, time.Time{}, err
 			}
-
 			if len(newTrades) > 0 {
-				log.Printf("Found %d new trades, merging with %d cached trades", len(newTrades), len(cache.trades))
-				cache.trades = rs.mergeTrades(cache.trades, newTrades)
+				log.Printf("Found %d new trades, %d trades now cached", len(newTrades), len(cache.trades))
 			} else {
 				log.Printf("No new trades found, using cached %d trades", len(cache.trades))
 			}
 
-			// Update last fetch time
-			cache.lastFetchTime = now
+			return cache, cache.trades, cache.coverageStart, err
+		}
+	}
+
+	// Case 3: Full fetch needed (no cache, larger range requested, or cache too old).
+	// A stale cache is fetched again for its whole range, so a shorter request
+	// never shrinks it.
+	fetchDays := fullFetchDays(cache, days)
+	log.Printf("Full fetch for %s: fetching all trades for last %d days", address, fetchDays)
+
+	trades, err := rs.hlClient.FetchTrades(ctx, address, fetchDays)
+	missing, partial := partialRange(err)
+	if err != nil && !partial {
+		return nil, nil, time.Time{}, err
+	}
+
+	// Replace the cache
+	cache = rs.record(ctx, models.TradeEvent{
+		Type:    models.EventTradesReset,
+		Address: address,
+		Window:  &models.TimeRange{Start: now.Add(-time.Duration(fetchDays) * 24 * time.Hour), End: now},
+		Missing: missing,
+		Days:    fetchDays,
+		Trades:  trades,
+	})
+
+	if fetchDays > days {
+		cutoffTime := now.Add(-time.Duration(days) * 24 * time.Hour)
+		return cache, rs.filterTradesByTime(trades, cutoffTime), cutoffTime, err
+	}
+	return cache, trades, cache.coverageStart, err
+}
+
+// fullFetchDays returns how many days a full fetch for a request of days
+// fetches: the longer of days and the range cache, which may be nil, covers
+func fullFetchDays(cache *AccountCache, days int) int {
+	if cache == nil {
+		return days
+	}
+	return max(days, cache.cachedDays)
+}
+
+// fetchNewTrades fetches the trades of address since the cache was last
+// fetched and records them, returning the new trades. The newest rs.overlap of
+// cached trades is fetched again with them, since the exchange sometimes
+// back-fills or corrects recent fills; if the re-fetch differs from the cache,
+// the changes are logged and the overlap replaced. Imported history is trusted
+// and not re-fetched. A
+// partial failure is recorded as a missing range and returned with the trades
+// that were fetched; any other failure leaves the cache as it was. Caller must
+// hold the address's lock.
+func (rs *ReconciliationService) fetchNewTrades(ctx context.Context, address string, cache *AccountCache, now time.Time) ([]models.Trade, error) {
+	lastFetchTime := cache.lastFetchTime
+	start := rs.incrementalStart(cache)
+
+	trades, err := rs.hlClient.FetchTradesInRange(ctx, address, start, now)
+	missing, partial := partialRange(err)
+	if err != nil && !partial {
+		return nil, err
+	}
+
+	trades = sortedByTime(trades)
+	overlap := models.TimeRange{Start: start, End: lastFetchTime}
+	refetched := tradesInRange(trades, overlap)
+	newTrades := trades[len(refetched):]
+
+	// A partial re-fetch can't show that a fill was removed, so it is only merged
+	window := models.TimeRange{Start: start, End: now}
+	if !partial {
+		if changes := diffTrades(tradesInRange(cache.trades, overlap), refetched); len(changes) > 0 {
+			log.Printf("Revalidation of %s from %s to %s found %d changed fills:", address, start.Format(time.RFC3339), lastFetchTime.Format(time.RFC3339), len(changes))
+			for _, change := range changes {
+				log.Printf("  %s", change)
+			}
+			rs.record(ctx, models.TradeEvent{Type: models.EventTradesRefetched, Address: address, Window: &overlap, Trades: refetched})
+		}
+		window.Start, trades = lastFetchTime, newTrades
+	}
+
+	rs.record(ctx, models.TradeEvent{
+		Type:    models.EventTradesFetched,
+		Address: address,
+		Window:  &window,
+		Missing: missing,
+		Trades:  trades,
+	})
+	return newTrades, err
+}
 
-			// Calculate P&L from cached trades
-			rs.calculateDailyPnLFromTrades(cache.trades)
+// usable reports whether the cache can be topped up by an incremental fetch at
+// now rather than fetched again. Imported history is trusted regardless of
+// age, so only the gap since the newest imported trade is fetched.
+func (cache *AccountCache) usable(now time.Time) bool {
+	return now.Sub(cache.lastFetchTime) < time.Hour || cache.imported
+}
 
-			log.Printf("Incremental reconciliation complete: %d total trades, %d days", len(cache.trades), len(rs.dailyPnL))
-			return nil
+// incrementalStart returns where an incremental fetch into cache starts: the
+// overlap before its last fetch, within its coverage
+func (rs *ReconciliationService) incrementalStart(cache *AccountCache) time.Time {
+	start := cache.lastFetchTime.Add(-rs.overlap)
+	if cache.imported {
+		return cache.lastFetchTime
+	}
+	if start.Before(cache.coverageStart) {
+		return cache.coverageStart
+	}
+	return start
+}
+
+// tradesInRange returns the trades in r from trades sorted by time
+func tradesInRange(trades []models.Trade, r models.TimeRange) []models.Trade {
+	from := sort.Search(len(trades), func(i int) bool { return !trades[i].Time.Before(r.Start) })
+	to := sort.Search(len(trades), func(i int) bool { return !trades[i].Time.Before(r.End) })
+	return trades[from:max(from, to)]
+}
+
+// fillKey identifies a fill when comparing two sets of trades: fills are
+// matched as in mergeTrades, by millisecond, coin and side
+type fillKey struct {
+	ms         int64
+	coin, side string
+}
+
+// keyOfFill returns the key trade is matched by
+func keyOfFill(trade models.Trade) fillKey {
+	return fillKey{trade.Time.UnixMilli(), trade.Coin, trade.Side}
+}
+
+// diffTrades describes how refetched differs from cached, both in time order
+func diffTrades(cached, refetched []models.Trade) []string {
+	remaining := make(map[fillKey]models.Trade, len(cached))
+	for _, trade := range cached {
+		remaining[keyOfFill(trade)] = trade
+	}
+
+	var changes []string
+	for _, trade := range refetched {
+		key := keyOfFill(trade)
+		old, exists := remaining[key]
+		delete(remaining, key)
+		switch {
+		case !exists:
+			changes = append(changes, "added "+describeFill(trade))
+		case !sameFill(old, trade):
+			changes = append(changes, fmt.Sprintf("changed %s to %s", describeFill(old), describeFill(trade)))
+		}
+	}
+	for _, trade := range cached {
+		if _, removed := remaining[keyOfFill(trade)]; removed {
+			changes = append(changes, "removed "+describeFill(trade))
 		}
 	}
+	return changes
+}
 
-	// Case 3: Full fetch needed (no cache, larger range requested, or cache too old)
-	log.Printf("Full fetch for %s: fetching all trades for last %d days", address, days)
+// sameFill reports whether a and b carry the same price, size, value, fee and start position
+func sameFill(a, b models.Trade) bool {
+	if a.Price != b.Price || a.Size != b.Size || a.Value != b.Value || a.Fee != b.Fee {
+		return false
+	}
+	if a.StartPosition == nil || b.StartPosition == nil {
+		return a.StartPosition == b.StartPosition
+	}
+	return *a.StartPosition == *b.StartPosition
+}
 
-	trades, err := rs.hlClient.FetchTrades(address, days)
-	if err != nil {
-		return err
+// describeFill formats a trade for the revalidation log
+func describeFill(trade models.Trade) string {
+	return fmt.Sprintf("%s %s %s %v @ %v (fee %v)", trade.Time.Format(time.RFC3339Nano), trade.Coin, trade.Side, trade.Size, trade.Price, trade.Fee)
+}
+
+// RevalidateIfStale returns the age of the current summary and, if it is older
+// than maxAge, starts a background incremental refresh for the same address and
+// range so later reads get fresh data. At most one background refresh runs at a
+// time, limited by the key policy of ctx like any other refresh. It returns
+// false if no refresh has completed yet.
+func (rs *ReconciliationService) RevalidateIfStale(ctx context.Context, maxAge time.Duration) (time.Duration, bool) {
+	current := rs.snapshot()
+	refreshedAt, address, days := current.refreshedAt, current.address, current.days
+
+	if refreshedAt.IsZero() {
+		return 0, false
 	}
 
-	// Create or update cache
-	rs.accountCache[address] = &AccountCache{
-		trades:        trades,
-		lastFetchTime: now,
-		cachedDays:    days,
+	age := time.Since(refreshedAt)
+	if age > maxAge && rs.revalidating.CompareAndSwap(false, true) {
+		go func() {
+			defer rs.revalidating.Store(false)
+
+			log.Printf("Summary for %s is %s old, revalidating in background", address, age.Round(time.Second))
+			if err := rs.FetchAndReconcile(WithKeyPolicy(context.Background(), KeyPolicyFrom(ctx)), address, days); err != nil {
+				log.Printf("Background revalidation for %s failed: %v", address, err)
+			}
+		}()
 	}
 
-	rs.calculateDailyPnLFromTrades(trades)
+	return age, true
+}
+
+// refetchMissingRanges retries windows left unfetched by earlier partial failures.
+// Windows that still cannot be fetched stay marked as missing.
+func (rs *ReconciliationService) refetchMissingRanges(ctx context.Context, address string, cache *AccountCache) {
+	pending := append([]models.TimeRange(nil), cache.missingRanges...)
 
-	log.Printf("Full reconciliation complete: %d trades, %d days", len(trades), len(rs.dailyPnL))
+	for _, r := range pending {
+		log.Printf("Retrying missing range for %s: %s to %s", address, r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339))
 
-	return nil
+		trades, err := rs.hlClient.FetchTradesInRange(ctx, address, r.Start, r.End)
+		missing, partial := partialRange(err)
+		if err != nil && !partial {
+			log.Printf("Missing range still unavailable: %v", err)
+			continue
+		}
+
+		window := r
+		rs.record(ctx, models.TradeEvent{
+			Type:    models.EventTradesFetched,
+			Address: address,
+			Window:  &window,
+			Missing: missing,
+			Trades:  trades,
+		})
+	}
+}
+
+// rangesEndingAfter returns the ranges that overlap the period after the cutoff time
+func rangesEndingAfter(ranges []models.TimeRange, cutoffTime time.Time) []models.TimeRange {
+	var result []models.TimeRange
+	for _, r := range ranges {
+		if !r.End.Before(cutoffTime) {
+			result = append(result, r)
+		}
+	}
+	return result
 }
 
 // filterTradesByTime filters trades to only include those after the cutoff time
@@ -139,58 +712,137 @@ func (rs *ReconciliationService) filterTradesByTime(trades []models.Trade, cutof
 	return filtered
 }
 
-// mergeTrades combines existing and new trades, removing duplicates
+// lockAddress waits for the lock on address's cache, recording how long the
+// wait took on the current span, and returns the function that releases it.
+// The cache of an address is only changed by the holder of its lock, which
+// may read it without rs.mu.
+func (rs *ReconciliationService) lockAddress(ctx context.Context, address string) (func(), error) {
+	start := time.Now()
+	unlock, err := rs.addressLocks.lock(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	addEvent(ctx, "address lock acquired", attribute.Int64("waitMs", time.Since(start).Milliseconds()))
+	return unlock, nil
+}
+
+// cached returns the cache of address
+func (rs *ReconciliationService) cached(address string) (*AccountCache, bool) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	cache, exists := rs.accountCache[address]
+	return cache, exists
+}
+
+// mergeTrades combines existing and new trades, removing duplicates. Trades
+// are the same if they share a millisecond, coin and side; the one from new is
+// kept. Both inputs are normally already in time order, so they are merged in
+// one pass instead of being re-sorted; an input that isn't is sorted first.
 func (rs *ReconciliationService) mergeTrades(existing, new []models.Trade) []models.Trade {
-	// Use a map to track unique trades by timestamp+coin+side to avoid duplicates
-	tradeMap := make(map[string]models.Trade)
+	existing, new = sortedByTime(existing), sortedByTime(new)
+	merged := make([]models.Trade, 0, len(existing)+len(new))
 
-	// Add existing trades to map
-	for _, trade := range existing {
-		key := fmt.Sprintf("%d_%s_%s", trade.Time.UnixMilli(), trade.Coin, trade.Side)
-		tradeMap[key] = trade
-	}
+	for i, j := 0, 0; i < len(existing) || j < len(new); {
+		// Take every trade in the next millisecond from both inputs, existing first
+		// so duplicates from new replace them
+		var ms int64
+		switch {
+		case j == len(new):
+			ms = existing[i].Time.UnixMilli()
+		case i == len(existing):
+			ms = new[j].Time.UnixMilli()
+		default:
+			ms = min(existing[i].Time.UnixMilli(), new[j].Time.UnixMilli())
+		}
 
-	// Add new trades (will overwrite if duplicate)
-	for _, trade := range new {
-		key := fmt.Sprintf("%d_%s_%s", trade.Time.UnixMilli(), trade.Coin, trade.Side)
-		tradeMap[key] = trade
+		group := len(merged)
+		for ; i < len(existing) && existing[i].Time.UnixMilli() == ms; i++ {
+			merged = appendUnique(merged, group, existing[i])
+		}
+		for ; j < len(new) && new[j].Time.UnixMilli() == ms; j++ {
+			merged = appendUnique(merged, group, new[j])
+		}
 	}
 
-	// Convert map back to slice
-	merged := make([]models.Trade, 0, len(tradeMap))
-	for _, trade := range tradeMap {
-		merged = append(merged, trade)
-	}
+	return merged
+}
 
-	// Sort by time ascending
-	sort.Slice(merged, func(i, j int) bool {
-		return merged[i].Time.Before(merged[j].Time)
-	})
+// appendUnique appends trade to trades, or replaces the trade from trades[from:]
+// with the same coin and side
+func appendUnique(trades []models.Trade, from int, trade models.Trade) []models.Trade {
+	for k := from; k < len(trades); k++ {
+		if trades[k].Coin == trade.Coin && trades[k].Side == trade.Side {
+			trades[k] = trade
+			return trades
+		}
+	}
+	return append(trades, trade)
+}
 
-	return merged
+// sortedByTime returns trades if they are in time order to the millisecond, or
+// a sorted copy if not
+func sortedByTime(trades []models.Trade) []models.Trade {
+	for k := 1; k < len(trades); k++ {
+		if trades[k].Time.UnixMilli() < trades[k-1].Time.UnixMilli() {
+			sorted := append([]models.Trade(nil), trades...)
+			sort.SliceStable(sorted, func(a, b int) bool { return sorted[a].Time.UnixMilli() < sorted[b].Time.UnixMilli() })
+			return sorted
+		}
+	}
+	return trades
 }
 
 // calculateDailyPnLFromTrades groups trades by date and calculates daily P&L
 func (rs *ReconciliationService) calculateDailyPnLFromTrades(trades []models.Trade) {
+	dailyPnL, dailyPnLUTC := rs.buildDailyPnL(trades), rs.buildDailyPnLIn(trades, time.UTC)
+	rs.publish(func(s *summarySnapshot) { s.dailyPnL, s.dailyPnLUTC = dailyPnL, dailyPnLUTC })
+}
+
+// buildDailyPnL groups trades by local date and calculates P&L for each day
+func (rs *ReconciliationService) buildDailyPnL(trades []models.Trade) map[string]*models.DailyPnL {
+	return rs.buildDailyPnLIn(trades, time.Local)
+}
+
+// buildDailyPnLIn groups trades by their date in loc and calculates P&L for each day
+func (rs *ReconciliationService) buildDailyPnLIn(trades []models.Trade, loc *time.Location) map[string]*models.DailyPnL {
 	// Group trades by date
 	tradesByDate := make(map[string][]models.Trade)
 
 	for _, trade := range trades {
-		dateKey := trade.Time.Format("2006-01-02") // Go's reference date format
+		dateKey := trade.Time.In(loc).Format("2006-01-02") // Go's reference date format
 		tradesByDate[dateKey] = append(tradesByDate[dateKey], trade)
 	}
 
 	// Calculate P&L for each day
-	rs.dailyPnL = make(map[string]*models.DailyPnL)
+	dailyPnL := make(map[string]*models.DailyPnL)
 
 	for date, dayTrades := range tradesByDate {
 		pnl := rs.calculatePnLForDay(dayTrades)
-		rs.dailyPnL[date] = &models.DailyPnL{
+		volume := 0.0
+		for _, trade := range dayTrades {
+			volume += float64(trade.Value)
+		}
+		dailyPnL[date] = &models.DailyPnL{
 			Date:       date,
 			TradeCount: len(dayTrades),
-			DailyPnL:   pnl,
+			Volume:     finiteMoney(volume, date),
+			DailyPnL:   finiteMoney(pnl, date),
 		}
 	}
+
+	return dailyPnL
+}
+
+// finiteMoney returns a day's total as Money, or zero with a log line if it
+// isn't finite. Trades are validated as they arrive, so this only guards
+// against an overflowing sum.
+func finiteMoney(v float64, date string) models.Money {
+	money, err := models.NewMoney(v)
+	if err != nil {
+		log.Printf("Dropping total for %s: %v", date, err)
+	}
+	return money
 }
 
 // calculatePnLForDay calculates P&L for a single day's trades
@@ -207,9 +859,9 @@ func (rs *ReconciliationService) calculatePnLForDay(trades []models.Trade) float
 		}
 
 		if trade.Side == "B" {
-			coinPositions[trade.Coin].BuyValue += trade.Value
+			coinPositions[trade.Coin].BuyValue += float64(trade.Value)
 		} else if trade.Side == "A" {
-			coinPositions[trade.Coin].SellValue += trade.Value
+			coinPositions[trade.Coin].SellValue += float64(trade.Value)
 		}
 	}
 
@@ -227,14 +879,75 @@ type Position struct {
 	SellValue float64
 }
 
-// GetPnLSummary returns a summary of all P&L calculations
+// GetPnLSummary returns a summary of all P&L calculations, with days bucketed
+// by the configured day basis. It reads the current summary without locking,
+// so it never waits for a refresh.
 func (rs *ReconciliationService) GetPnLSummary() models.PnLSummary {
-	rs.mu.RLock()
-	defer rs.mu.RUnlock()
+	return rs.GetPnLSummaryByDay(models.DayBasis(config.DayBasis))
+}
+
+// GetPnLSummaryByDay returns the current summary with days bucketed by basis.
+// Both calendars are computed on every refresh, so either can be read at any
+// time. The summary is marked degraded while the Hyperliquid API isn't
+// answering normally.
+func (rs *ReconciliationService) GetPnLSummaryByDay(basis models.DayBasis) models.PnLSummary {
+	summary := rs.summarize(rs.snapshot(), basis, time.Now())
+	summary.Degraded = upstreamHealth.Degraded()
+	return summary
+}
+
+// summarize builds the summary of a snapshot with days bucketed by basis and
+// trailing windows ending on the day of now
+func (rs *ReconciliationService) summarize(current *summarySnapshot, basis models.DayBasis, now time.Time) models.PnLSummary {
+	dailyPnL := current.dailyPnL
+	if basis == models.DayUTC {
+		dailyPnL = current.dailyPnLUTC
+	} else {
+		basis = models.DayLocal
+	}
+	records, totalPnL := sortedDailyRecords(dailyPnL)
+
+	summary := models.PnLSummary{
+		Address:       current.address,
+		Label:         rs.addressBook.Label(current.address),
+		DayBasis:      basis,
+		DailyRecords:  records,
+		TotalPnL:      totalPnL,
+		Incomplete:    len(current.missing) > 0,
+		MissingRanges: append([]models.TimeRange(nil), current.missing...),
+		PositionGaps:  append([]models.CoinDayGaps(nil), current.positionGaps...),
+	}
 
+	// Coverage is only known once a refresh has run
+	if !current.refreshedAt.IsZero() {
+		coverageStart, coverageEnd, refreshedAt := current.coverage.Start, current.coverage.End, current.refreshedAt
+		summary.CoverageStart = &coverageStart
+		summary.CoverageEnd = &coverageEnd
+		summary.LastRefreshedAt = &refreshedAt
+	}
+
+	summary.Windows = performanceWindows(records, summary.CoverageStart, now.In(dayLocation(basis)))
+	summary.Stats = performanceStats(records)
+
+	if current.address != "" {
+		summary.DataQuality.QuarantinedFills = rs.quarantine.Count(current.address)
+		if rs.dataQuality != nil {
+			summary.DataQuality.Alerts = len(rs.dataQuality.Recent(current.address))
+		}
+	}
+
+	entry, _ := rs.addressBook.Entry(current.address)
+	summary.CapitalSource, summary.BaseCapital, summary.TotalReturnPct = applyReturns(records, entry, current.accountValues, dayLocation(basis))
+
+	return summary
+}
+
+// sortedDailyRecords returns the daily records sorted newest first with
+// cumulative P&L filled in, along with the total P&L
+func sortedDailyRecords(dailyPnL map[string]*models.DailyPnL) ([]models.DailyPnL, models.Money) {
 	// Convert map to sorted slice
-	records := make([]models.DailyPnL, 0, len(rs.dailyPnL))
-	for _, record := range rs.dailyPnL {
+	records := make([]models.DailyPnL, 0, len(dailyPnL))
+	for _, record := range dailyPnL {
 		records = append(records, *record)
 	}
 
@@ -244,19 +957,79 @@ func (rs *ReconciliationService) GetPnLSummary() models.PnLSummary {
 	})
 
 	// Calculate cumulative P&L
-	cumulative := 0.0
+	var cumulative models.Money
 	for i := len(records) - 1; i >= 0; i-- {
-		cumulative += records[i].DailyPnL
+		cumulative = (cumulative + records[i].DailyPnL).Round()
 		records[i].CumulativePnL = cumulative
 	}
 
-	totalPnL := 0.0
+	var totalPnL models.Money
 	for _, record := range records {
 		totalPnL += record.DailyPnL
 	}
 
-	return models.PnLSummary{
-		DailyRecords: records,
-		TotalPnL:     totalPnL,
+	return records, totalPnL.Round()
+}
+
+// CachedAddresses returns the addresses that have cached trades, sorted
+func (rs *ReconciliationService) CachedAddresses() []string {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	addresses := make([]string, 0, len(rs.accountCache))
+	for address := range rs.accountCache {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// CachedTrades returns a copy of all cached trades for address, oldest first,
+// under canonical coin names
+func (rs *ReconciliationService) CachedTrades(address string) ([]models.Trade, bool) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	cache, exists := rs.accountCache[address]
+	if !exists {
+		return nil, false
+	}
+	trades := append([]models.Trade(nil), cache.trades...)
+	canonicalize(trades)
+	return trades, true
+}
+
+// CachedDailyRecords returns daily P&L records, newest first, computed from all cached trades for address
+func (rs *ReconciliationService) CachedDailyRecords(address string) ([]models.DailyPnL, bool) {
+	trades, exists := rs.CachedTrades(address)
+	if !exists {
+		return nil, false
+	}
+
+	records, _ := sortedDailyRecords(rs.buildDailyPnL(trades))
+	return records, true
+}
+
+// CachedCoverageStart returns the earliest time the cached trades of address cover
+func (rs *ReconciliationService) CachedCoverageStart(address string) (time.Time, bool) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	cache, exists := rs.accountCache[address]
+	if !exists {
+		return time.Time{}, false
+	}
+	return cache.coverageStart, true
+}
+
+// CachedMissingRanges returns the windows that could not be fetched for address
+func (rs *ReconciliationService) CachedMissingRanges(address string) []models.TimeRange {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	cache, exists := rs.accountCache[address]
+	if !exists {
+		return nil
 	}
+	return append([]models.TimeRange(nil), cache.missingRanges...)
 }
@@ -1,7 +1,17 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"hyperliquid-recon/models"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -13,9 +23,9 @@ func createTestTrade(timeStr string, coin string, side string, price float64, si
 		Time:  t,
 		Coin:  coin,
 		Side:  side,
-		Price: price,
-		Size:  size,
-		Value: price * size,
+		Price: models.Money(price),
+		Size:  models.Quantity(size),
+		Value: models.Money(price * size),
 	}
 }
 
@@ -167,6 +177,95 @@ func TestMergeTrades(t *testing.T) {
 			}
 		}
 	})
+	t.Run("should keep the new version of a duplicate", func(t *testing.T) {
+		existing := []models.Trade{
+			createTestTrade("2025-01-01T10:00:00Z", "BTC", "B", 50000, 1),
+			createTestTrade("2025-01-01T10:00:00Z", "ETH", "B", 3000, 1),
+		}
+		new := []models.Trade{
+			createTestTrade("2025-01-01T10:00:00Z", "BTC", "B", 50001, 1),
+		}
+
+		merged := rs.mergeTrades(existing, new)
+
+		if len(merged) != 2 || merged[0].Price != 50001 || merged[1].Coin != "ETH" {
+			t.Errorf("Expected the new BTC trade and the ETH trade, got %+v", merged)
+		}
+	})
+
+	t.Run("should match a full re-sort on random input", func(t *testing.T) {
+		rng := rand.New(rand.NewPCG(1, 2))
+		random := func(n int) []models.Trade {
+			trades := make([]models.Trade, n)
+			for i := range trades {
+				trades[i] = models.Trade{Time: time.UnixMilli(rng.Int64N(500)), Coin: []string{"BTC", "ETH"}[rng.IntN(2)], Side: []string{"B", "A"}[rng.IntN(2)], Price: models.Money(i)}
+			}
+			return trades
+		}
+
+		for round := 0; round < 50; round++ {
+			existing, new := mergeTradesBySort(nil, random(200)), random(100)
+			if round%2 == 0 {
+				new = mergeTradesBySort(nil, new)
+			}
+
+			got, want := rs.mergeTrades(existing, new), mergeTradesBySort(existing, new)
+			if len(got) != len(want) {
+				t.Fatalf("Expected %d trades, got %d", len(want), len(got))
+			}
+			for i := range got {
+				if got[i].Time.UnixMilli() != want[i].Time.UnixMilli() {
+					t.Fatalf("Trades not in time order at index %d", i)
+				}
+			}
+		}
+	})
+}
+
+// mergeTradesBySort is the previous mergeTrades, which de-duplicates through a
+// map and re-sorts everything; kept to compare against
+func mergeTradesBySort(existing, new []models.Trade) []models.Trade {
+	tradeMap := make(map[string]models.Trade)
+	for _, trade := range append(append([]models.Trade(nil), existing...), new...) {
+		tradeMap[fmt.Sprintf("%d_%s_%s", trade.Time.UnixMilli(), trade.Coin, trade.Side)] = trade
+	}
+
+	merged := make([]models.Trade, 0, len(tradeMap))
+	for _, trade := range tradeMap {
+		merged = append(merged, trade)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Time.Before(merged[j].Time)
+	})
+	return merged
+}
+
+// benchmarkTrades returns n fills a second apart, oldest first, starting at start
+func benchmarkTrades(start time.Time, n int) []models.Trade {
+	trades := make([]models.Trade, n)
+	for i := range trades {
+		trades[i] = models.Trade{Time: start.Add(time.Duration(i) * time.Second), Coin: "BTC", Side: "B", Price: 50000, Size: 0.01, Value: 500}
+	}
+	return trades
+}
+
+// Benchmark merging an incremental batch into a 500k-trade cache
+func BenchmarkMergeTrades(b *testing.B) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cached := benchmarkTrades(start, 500_000)
+	batch := benchmarkTrades(start.Add(499_000*time.Second), 2_000) // Overlaps the newest 1000 cached trades
+	rs := NewReconciliationService()
+
+	b.Run("sorted merge", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			rs.mergeTrades(cached, batch)
+		}
+	})
+	b.Run("map and re-sort", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			mergeTradesBySort(cached, batch)
+		}
+	})
 }
 
 // Test calculatePnLForDay
@@ -267,12 +366,12 @@ func TestCalculateDailyPnLFromTrades(t *testing.T) {
 
 		rs.calculateDailyPnLFromTrades(trades)
 
-		if len(rs.dailyPnL) != 2 {
-			t.Errorf("Expected 2 days, got %d", len(rs.dailyPnL))
+		if len(rs.snapshot().dailyPnL) != 2 {
+			t.Errorf("Expected 2 days, got %d", len(rs.snapshot().dailyPnL))
 		}
 
 		// Check 2025-01-01
-		day1, exists := rs.dailyPnL["2025-01-01"]
+		day1, exists := rs.snapshot().dailyPnL["2025-01-01"]
 		if !exists {
 			t.Error("Expected data for 2025-01-01")
 		}
@@ -284,7 +383,7 @@ func TestCalculateDailyPnLFromTrades(t *testing.T) {
 		}
 
 		// Check 2025-01-02
-		day2, exists := rs.dailyPnL["2025-01-02"]
+		day2, exists := rs.snapshot().dailyPnL["2025-01-02"]
 		if !exists {
 			t.Error("Expected data for 2025-01-02")
 		}
@@ -304,8 +403,8 @@ func TestCalculateDailyPnLFromTrades(t *testing.T) {
 
 		rs.calculateDailyPnLFromTrades(trades)
 
-		if len(rs.dailyPnL) != 2 {
-			t.Errorf("Expected 2 days, got %d", len(rs.dailyPnL))
+		if len(rs.snapshot().dailyPnL) != 2 {
+			t.Errorf("Expected 2 days, got %d", len(rs.snapshot().dailyPnL))
 		}
 	})
 
@@ -313,8 +412,8 @@ func TestCalculateDailyPnLFromTrades(t *testing.T) {
 		trades := []models.Trade{}
 		rs.calculateDailyPnLFromTrades(trades)
 
-		if len(rs.dailyPnL) != 0 {
-			t.Errorf("Expected 0 days, got %d", len(rs.dailyPnL))
+		if len(rs.snapshot().dailyPnL) != 0 {
+			t.Errorf("Expected 0 days, got %d", len(rs.snapshot().dailyPnL))
 		}
 	})
 }
@@ -413,7 +512,7 @@ func TestGetPnLSummary(t *testing.T) {
 		rs.calculateDailyPnLFromTrades(trades)
 		summary := rs.GetPnLSummary()
 
-		expected := 900.0 // 1000 + (-100)
+		expected := models.Money(900) // 1000 + (-100)
 		if summary.TotalPnL != expected {
 			t.Errorf("Expected total P&L %f, got %f", expected, summary.TotalPnL)
 		}
@@ -428,7 +527,7 @@ func TestGetPnLSummary(t *testing.T) {
 		rs.calculateDailyPnLFromTrades(trades)
 		summary := rs.GetPnLSummary()
 
-		expected := -1000.0
+		expected := models.Money(-1000)
 		if summary.TotalPnL != expected {
 			t.Errorf("Expected total P&L %f, got %f", expected, summary.TotalPnL)
 		}
@@ -446,4 +545,299 @@ func TestGetPnLSummary(t *testing.T) {
 			t.Errorf("Expected total P&L 0, got %f", summary.TotalPnL)
 		}
 	})
-}
\ No newline at end of file
+}
+// Test coverage fields in GetPnLSummary
+func TestGetPnLSummaryCoverage(t *testing.T) {
+	t.Run("should omit coverage before any refresh", func(t *testing.T) {
+		rs := NewReconciliationService()
+		summary := rs.GetPnLSummary()
+
+		if summary.CoverageStart != nil || summary.CoverageEnd != nil || summary.LastRefreshedAt != nil {
+			t.Errorf("Expected no coverage before refresh")
+		}
+		if summary.Incomplete {
+			t.Errorf("Expected summary to be complete")
+		}
+	})
+
+	t.Run("should report coverage window and missing ranges", func(t *testing.T) {
+		rs := NewReconciliationService()
+		start, _ := time.Parse(time.RFC3339, "2025-01-01T00:00:00Z")
+		end, _ := time.Parse(time.RFC3339, "2025-01-10T00:00:00Z")
+		gapStart, _ := time.Parse(time.RFC3339, "2025-01-05T00:00:00Z")
+
+		rs.publish(func(s *summarySnapshot) {
+			s.coverage = models.TimeRange{Start: start, End: end}
+			s.missing = []models.TimeRange{{Start: gapStart, End: end}}
+			s.refreshedAt = time.Now()
+		})
+		summary := rs.GetPnLSummary()
+
+		if summary.CoverageStart == nil || !summary.CoverageStart.Equal(start) {
+			t.Errorf("Expected coverage start %v, got %v", start, summary.CoverageStart)
+		}
+		if summary.CoverageEnd == nil || !summary.CoverageEnd.Equal(end) {
+			t.Errorf("Expected coverage end %v, got %v", end, summary.CoverageEnd)
+		}
+		if summary.LastRefreshedAt == nil {
+			t.Errorf("Expected last refreshed time to be set")
+		}
+		if !summary.Incomplete || len(summary.MissingRanges) != 1 {
+			t.Errorf("Expected 1 missing range, got %d", len(summary.MissingRanges))
+		}
+	})
+}
+
+// Test rangesEndingAfter
+func TestRangesEndingAfter(t *testing.T) {
+	jan1, _ := time.Parse(time.RFC3339, "2025-01-01T00:00:00Z")
+	jan2, _ := time.Parse(time.RFC3339, "2025-01-02T00:00:00Z")
+	jan5, _ := time.Parse(time.RFC3339, "2025-01-05T00:00:00Z")
+	jan6, _ := time.Parse(time.RFC3339, "2025-01-06T00:00:00Z")
+
+	ranges := []models.TimeRange{
+		{Start: jan1, End: jan2},
+		{Start: jan5, End: jan6},
+	}
+
+	result := rangesEndingAfter(ranges, jan5)
+	if len(result) != 1 || !result[0].Start.Equal(jan5) {
+		t.Errorf("Expected only the range after cutoff, got %v", result)
+	}
+}
+
+// Test RevalidateIfStale
+func TestRevalidateIfStale(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	t.Run("should not refresh before any data exists", func(t *testing.T) {
+		rs := NewReconciliationService()
+		rs.hlClient.apiURL = server.URL
+
+		if _, ok := rs.RevalidateIfStale(context.Background(), time.Second); ok {
+			t.Errorf("Expected no age before first refresh")
+		}
+		if rs.revalidating.Load() {
+			t.Errorf("Expected no background refresh")
+		}
+	})
+
+	t.Run("should not refresh fresh data", func(t *testing.T) {
+		rs := NewReconciliationService()
+		rs.hlClient.apiURL = server.URL
+		if err := rs.FetchAndReconcile(context.Background(), "0xabc", 1); err != nil {
+			t.Fatalf("Refresh failed: %v", err)
+		}
+
+		age, ok := rs.RevalidateIfStale(context.Background(), time.Hour)
+		if !ok || age > time.Hour {
+			t.Errorf("Expected fresh data, got age %v", age)
+		}
+		if rs.revalidating.Load() {
+			t.Errorf("Expected no background refresh")
+		}
+	})
+
+	t.Run("should refresh stale data in background", func(t *testing.T) {
+		rs := NewReconciliationService()
+		rs.hlClient.apiURL = server.URL
+		if err := rs.FetchAndReconcile(context.Background(), "0xabc", 1); err != nil {
+			t.Fatalf("Refresh failed: %v", err)
+		}
+
+		rs.publish(func(s *summarySnapshot) { s.refreshedAt = time.Now().Add(-time.Hour) })
+
+		age, ok := rs.RevalidateIfStale(context.Background(), time.Minute)
+		if !ok || age < time.Hour {
+			t.Errorf("Expected stale age, got %v", age)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for rs.revalidating.Load() && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if age, _ := rs.RevalidateIfStale(context.Background(), time.Minute); age > time.Minute {
+			t.Errorf("Expected data to be revalidated, age is %v", age)
+		}
+	})
+}
+
+// Test invalidating and re-fetching a window of cached trades
+func TestInvalidateRange(t *testing.T) {
+	base := time.Now().Add(-3 * time.Hour).Truncate(time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request UserFillsRequest
+		json.NewDecoder(r.Body).Decode(&request)
+
+		fills := []FillResponse{}
+		for i := 0; i < 3; i++ {
+			fillTime := base.Add(time.Duration(i) * time.Hour).UnixMilli()
+			if request.StartTime != nil && fillTime >= *request.StartTime && (request.EndTime == nil || fillTime <= *request.EndTime) {
+				fills = append(fills, FillResponse{Time: fillTime, Coin: "ETH", Side: "B", Price: "100", Size: "1"})
+			}
+		}
+		json.NewEncoder(w).Encode(fills)
+	}))
+	defer server.Close()
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = server.URL
+	if err := rs.FetchAndReconcile(context.Background(), testAddress, 1); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	t.Run("should drop the window's trades and mark it missing", func(t *testing.T) {
+		start, end := base.Add(30*time.Minute), base.Add(90*time.Minute)
+		invalidation, err := rs.InvalidateRange(context.Background(), testAddress, start, end)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if invalidation.TradesRemoved != 1 {
+			t.Errorf("Expected 1 trade removed, got %d", invalidation.TradesRemoved)
+		}
+
+		cache, _ := rs.cached(testAddress)
+		if len(cache.trades) != 2 {
+			t.Errorf("Expected 2 trades left, got %d", len(cache.trades))
+		}
+		if len(cache.missingRanges) != 1 || !cache.missingRanges[0].Start.Equal(start) {
+			t.Errorf("Expected the window to be missing, got %v", cache.missingRanges)
+		}
+	})
+
+	t.Run("should restore the window when it is fetched again", func(t *testing.T) {
+		if _, err := rs.RefetchRange(context.Background(), testAddress, base.Add(30*time.Minute), base.Add(90*time.Minute)); err != nil {
+			t.Fatalf("Re-fetch failed: %v", err)
+		}
+
+		cache, _ := rs.cached(testAddress)
+		if len(cache.trades) != 3 || len(cache.missingRanges) != 0 {
+			t.Errorf("Expected 3 trades and no missing ranges, got %d and %v", len(cache.trades), cache.missingRanges)
+		}
+	})
+
+	t.Run("should reject windows outside the cache", func(t *testing.T) {
+		if _, err := rs.InvalidateRange(context.Background(), "0x1111111111111111111111111111111111111111", base, base.Add(time.Hour)); !errors.Is(err, ErrNotCached) {
+			t.Errorf("Expected ErrNotCached, got %v", err)
+		}
+		if _, err := rs.InvalidateRange(context.Background(), testAddress, base.AddDate(0, 0, -10), base.AddDate(0, 0, -5)); !errors.Is(err, ErrRangeNotCached) {
+			t.Errorf("Expected ErrRangeNotCached, got %v", err)
+		}
+	})
+}
+
+// Test revalidating the newest cached hour on an incremental refresh
+func TestRevalidateOverlap(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	var mu sync.Mutex
+	fills := []FillResponse{
+		{Time: now.Add(-2 * time.Hour).UnixMilli(), Coin: "BTC", Side: "B", Price: "60000", Size: "1"},
+		{Time: now.Add(-50 * time.Minute).UnixMilli(), Coin: "ETH", Side: "A", Price: "3000", Size: "1"},
+		{Time: now.Add(-30 * time.Minute).UnixMilli(), Coin: "ETH", Side: "B", Price: "3000", Size: "1"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request UserFillsRequest
+		json.NewDecoder(r.Body).Decode(&request)
+
+		mu.Lock()
+		defer mu.Unlock()
+		window := []FillResponse{}
+		for _, fill := range fills {
+			if fill.Time >= *request.StartTime && (request.EndTime == nil || fill.Time <= *request.EndTime) {
+				window = append(window, fill)
+			}
+		}
+		json.NewEncoder(w).Encode(window)
+	}))
+	defer server.Close()
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = server.URL
+	if err := rs.FetchAndReconcile(context.Background(), testAddress, 1); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	// The exchange drops one fill, corrects another and back-fills a third,
+	// all within the last hour
+	mu.Lock()
+	fills = []FillResponse{
+		fills[0],
+		{Time: now.Add(-30 * time.Minute).UnixMilli(), Coin: "ETH", Side: "B", Price: "3000", Size: "2"},
+		{Time: now.Add(-20 * time.Minute).UnixMilli(), Coin: "SOL", Side: "B", Price: "150", Size: "1"},
+	}
+	mu.Unlock()
+
+	trades, err := rs.RefreshCache(context.Background(), testAddress, 1)
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	if len(trades) != 3 {
+		t.Fatalf("Expected 3 trades after revalidation, got %d: %v", len(trades), trades)
+	}
+	if trades[0].Coin != "BTC" || trades[1].Coin != "ETH" || trades[1].Size != 2 || trades[2].Coin != "SOL" {
+		t.Errorf("Expected the corrected overlap, got %v", trades)
+	}
+
+	t.Run("should describe each change", func(t *testing.T) {
+		cached := []models.Trade{{Time: now, Coin: "ETH", Side: "B", Price: 1, Size: 1, Value: 1}, {Time: now, Coin: "ETH", Side: "A", Price: 1, Size: 1, Value: 1}}
+		refetched := []models.Trade{{Time: now, Coin: "ETH", Side: "B", Price: 1, Size: 2, Value: 2}, {Time: now.Add(time.Second), Coin: "BTC", Side: "B", Price: 1, Size: 1, Value: 1}}
+
+		changes := diffTrades(cached, refetched)
+		if len(changes) != 3 || !strings.HasPrefix(changes[0], "changed") || !strings.HasPrefix(changes[1], "added") || !strings.HasPrefix(changes[2], "removed") {
+			t.Errorf("Expected a change, an addition and a removal, got %v", changes)
+		}
+		if diffTrades(cached, cached) != nil {
+			t.Errorf("Expected no changes for an identical re-fetch")
+		}
+	})
+}
+
+// Test that refreshing a stale cache for fewer days keeps its history
+func TestRefreshStaleCacheKeepsRange(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request UserFillsRequest
+		json.NewDecoder(r.Body).Decode(&request)
+
+		fills := []FillResponse{}
+		for _, age := range []time.Duration{20 * 24 * time.Hour, 5 * 24 * time.Hour, 2 * time.Hour} {
+			fillTime := now.Add(-age).UnixMilli()
+			if fillTime >= *request.StartTime && (request.EndTime == nil || fillTime <= *request.EndTime) {
+				fills = append(fills, FillResponse{Time: fillTime, Coin: "BTC", Side: "B", Price: "60000", Size: "1"})
+			}
+		}
+		json.NewEncoder(w).Encode(fills)
+	}))
+	defer server.Close()
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = server.URL
+	if _, err := rs.RefreshCache(context.Background(), testAddress, 30); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	// The cache is more than an hour old, so the next refresh is a full fetch
+	cache, _ := rs.cached(testAddress)
+	cache.lastFetchTime = cache.lastFetchTime.Add(-2 * time.Hour)
+
+	trades, err := rs.RefreshCache(context.Background(), testAddress, 1)
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if len(trades) != 1 {
+		t.Errorf("Expected only the last day's trade, got %d", len(trades))
+	}
+
+	cache, _ = rs.cached(testAddress)
+	if cache.cachedDays != 30 || len(cache.trades) != 3 {
+		t.Errorf("Expected 30 days and 3 trades still cached, got %d days and %d trades", cache.cachedDays, len(cache.trades))
+	}
+	if plan := rs.PlanRefresh(testAddress, 1); plan.Mode != models.RefreshIncremental {
+		t.Errorf("Expected the refreshed cache to serve a 1 day refresh, got %s", plan.Mode)
+	}
+}
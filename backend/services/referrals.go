@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hyperliquid-recon/models"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// referralResponse is the referral state of an address from the API
+type referralResponse struct {
+	ClaimedRewards   string `json:"claimedRewards"`
+	UnclaimedRewards string `json:"unclaimedRewards"`
+	BuilderRewards   string `json:"builderRewards"`
+}
+
+// FetchReferralRewards fetches the total referral and builder rewards address
+// has earned, claimed or not
+func (c *HyperliquidClient) FetchReferralRewards(ctx context.Context, address string) (total float64, err error) {
+	ctx, span := tracer.Start(ctx, "HyperliquidClient.FetchReferralRewards", trace.WithAttributes(attribute.String("address", address)))
+	defer func() { endSpan(span, err) }()
+
+	var referral referralResponse
+	if err := c.postInfo(ctx, portfolioRequest{Type: "referral", User: address}, &referral); err != nil {
+		return 0, fmt.Errorf("failed to fetch referral rewards: %w", err)
+	}
+
+	for _, amount := range []string{referral.ClaimedRewards, referral.UnclaimedRewards, referral.BuilderRewards} {
+		if amount == "" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(amount, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse referral rewards '%s': %w", amount, err)
+		}
+		total += parsed
+	}
+	return total, nil
+}
+
+// syncReferrals returns an entry posting the referral rewards account earned
+// since the last sync as of now, or nil if it earned none. The API only
+// reports the total, so the first sync posts everything earned so far.
+// Caller must hold l.mu.
+func (l *Ledger) syncReferrals(ctx context.Context, account *addressLedger, now time.Time) (*models.JournalEntry, error) {
+	total, err := l.hlClient.FetchReferralRewards(ctx, account.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	earned := total - account.ReferralRewards
+	account.ReferralRewards = total
+	if math.Abs(earned) < 1e-9 {
+		return nil, nil
+	}
+
+	return &models.JournalEntry{
+		// The total only grows, so it identifies the entry
+		ID:   EntryReferral + "-" + strconv.FormatFloat(total, 'f', -1, 64),
+		Time: now,
+		Kind: EntryReferral,
+		Ref:  "Referral rewards earned since the last sync",
+		Postings: []models.Posting{
+			{Account: AccountRewards, Amount: models.Money(earned).Round()},
+			{Account: AccountReferrals, Amount: -models.Money(earned).Round()},
+		},
+	}, nil
+}
+
+// rewardsClaimEntry posts claimed referral rewards moving to cash
+func rewardsClaimEntry(update LedgerUpdateResponse) (models.JournalEntry, bool) {
+	amount, err := models.ParseMoney(update.Delta.Amount)
+	if err != nil {
+		return models.JournalEntry{}, false
+	}
+	return models.JournalEntry{
+		ID:       update.Delta.Type + "-" + update.Hash + "-" + strconv.FormatInt(update.Time, 10),
+		Time:     time.UnixMilli(update.Time),
+		Kind:     EntryRewardsClaim,
+		Ref:      update.Hash,
+		Postings: []models.Posting{{Account: AccountCash, Amount: amount}, {Account: AccountRewards, Amount: -amount}},
+	}, true
+}
+
+// BuildIncomeSummary totals the income in entries per local day or month
+// ("day" or "month")
+func BuildIncomeSummary(address string, entries []models.JournalEntry, period string) models.IncomeSummary {
+	layout := "2006-01-02"
+	if period == "month" {
+		layout = "2006-01"
+	}
+
+	byPeriod := make(map[string]*models.IncomeLine)
+	for _, entry := range entries {
+		key := entry.Time.Local().Format(layout)
+		line, exists := byPeriod[key]
+		if !exists {
+			line = &models.IncomeLine{Period: key}
+			byPeriod[key] = line
+		}
+		addIncome(line, entry.Postings)
+	}
+
+	summary := models.IncomeSummary{Address: address, Period: period, Lines: make([]models.IncomeLine, 0, len(byPeriod))}
+	for _, line := range byPeriod {
+		line.Total = (line.TradingPnL - line.Fees + line.Rebates + line.Funding + line.Referrals + line.VaultPnL + line.OtherIncome).Round()
+		summary.Lines = append(summary.Lines, *line)
+
+		totals := &summary.Totals
+		totals.TradingPnL = (totals.TradingPnL + line.TradingPnL).Round()
+		totals.Fees = (totals.Fees + line.Fees).Round()
+		totals.Rebates = (totals.Rebates + line.Rebates).Round()
+		totals.Funding = (totals.Funding + line.Funding).Round()
+		totals.Referrals = (totals.Referrals + line.Referrals).Round()
+		totals.VaultPnL = (totals.VaultPnL + line.VaultPnL).Round()
+		totals.OtherIncome = (totals.OtherIncome + line.OtherIncome).Round()
+		totals.Total = (totals.Total + line.Total).Round()
+	}
+	sort.Slice(summary.Lines, func(i, j int) bool { return summary.Lines[i].Period < summary.Lines[j].Period })
+	return summary
+}
+
+// addIncome adds the income of postings to line
+func addIncome(line *models.IncomeLine, postings []models.Posting) {
+	for _, posting := range postings {
+		amount := posting.Amount
+		switch {
+		case strings.HasPrefix(posting.Account, AccountPositions):
+			line.TradingPnL = (line.TradingPnL - amount).Round()
+		case posting.Account == AccountFees && amount >= 0:
+			line.Fees = (line.Fees + amount).Round()
+		case posting.Account == AccountFees:
+			line.Rebates = (line.Rebates - amount).Round()
+		case posting.Account == AccountFunding:
+			line.Funding = (line.Funding - amount).Round()
+		case posting.Account == AccountReferrals:
+			line.Referrals = (line.Referrals - amount).Round()
+		case posting.Account == AccountVaultPnL:
+			line.VaultPnL = (line.VaultPnL - amount).Round()
+		case strings.HasPrefix(posting.Account, AccountOtherIncome):
+			line.OtherIncome = (line.OtherIncome - amount).Round()
+		}
+	}
+}
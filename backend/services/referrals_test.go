@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test posting referral rewards and totalling income per day and month
+func TestReferralIncome(t *testing.T) {
+	claimed := time.Date(2025, 3, 1, 12, 0, 0, 0, time.Local)
+	unclaimed := "10"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req UserFillsRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		switch req.Type {
+		case "referral":
+			fmt.Fprintf(w, `{"claimedRewards": "2", "unclaimedRewards": "%s", "builderRewards": "0"}`, unclaimed)
+		case LedgerUpdatesNonFunding:
+			fmt.Fprintf(w, `[{"time": %d, "hash": "0x01", "delta": {"type": "rewardsClaim", "amount": "2"}}]`, claimed.UnixMilli())
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	ledger, _ := NewLedger("")
+	ledger.hlClient.apiURL = server.URL
+
+	ledger.Sync(context.Background(), testAddress, claimed.Add(-time.Hour))
+	unclaimed = "15"
+	ledger.Sync(context.Background(), testAddress, claimed.Add(-time.Hour))
+	ledger.Sync(context.Background(), testAddress, claimed.Add(-time.Hour))
+
+	t.Run("should post rewards as they grow and claims to cash", func(t *testing.T) {
+		balance := BuildTrialBalance(testAddress, ledger.Entries(testAddress, nil, "", ""), "", "")
+		if balance.Entries != 3 || !balance.Balanced {
+			t.Fatalf("Expected 2 reward entries and a claim, got %+v", balance)
+		}
+		if balance.Referrals != 17 {
+			t.Errorf("Expected 17 of referral income, got %v", balance.Referrals)
+		}
+		for _, account := range balance.Accounts {
+			if account.Account == AccountRewards && account.Balance != 15 {
+				t.Errorf("Expected 15 of unclaimed rewards, got %v", account.Balance)
+			}
+		}
+	})
+
+	t.Run("should total income by source per period", func(t *testing.T) {
+		trades := []models.Trade{
+			{Time: claimed, Coin: "ETH", Side: "B", Price: 100, Size: 1, Value: 100, Fee: 0.5},
+			{Time: claimed.AddDate(0, 0, 1), Coin: "ETH", Side: "A", Price: 120, Size: 1, Value: 120, Fee: -0.2},
+		}
+		entries := ledger.Entries(testAddress, trades, "2025-03-01", "2025-03-31")
+
+		daily := BuildIncomeSummary(testAddress, entries, "day")
+		if len(daily.Lines) != 2 || daily.Lines[0].Fees != 0.5 || daily.Lines[1].Rebates != 0.2 {
+			t.Fatalf("Unexpected daily lines %+v", daily.Lines)
+		}
+
+		monthly := BuildIncomeSummary(testAddress, entries, "month")
+		if len(monthly.Lines) != 1 || monthly.Lines[0].Period != "2025-03" || monthly.Lines[0].TradingPnL != 20 || monthly.Totals.Total != 19.7 {
+			t.Errorf("Unexpected monthly summary %+v", monthly)
+		}
+	})
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ReportService builds daily P&L reports and emails them to subscribers
+type ReportService struct {
+	reconService  *ReconciliationService
+	addressBook   *AddressBook
+	mailer        *Mailer
+	subscriptions []models.ReportSubscription
+	ledger        *Ledger // Source of referral income, if set
+}
+
+func NewReportService(reconService *ReconciliationService, addressBook *AddressBook, mailer *Mailer, subscriptions []models.ReportSubscription) *ReportService {
+	return &ReportService{
+		reconService:  reconService,
+		addressBook:   addressBook,
+		mailer:        mailer,
+		subscriptions: subscriptions,
+	}
+}
+
+// UseLedger makes reports include the referral rewards posted to ledger
+func (r *ReportService) UseLedger(ledger *Ledger) {
+	r.ledger = ledger
+}
+
+// LoadReportSubscriptions reads and validates report subscriptions from a JSON file
+func LoadReportSubscriptions(path string) ([]models.ReportSubscription, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report subscriptions: %w", err)
+	}
+
+	var subscriptions []models.ReportSubscription
+	if err := json.Unmarshal(data, &subscriptions); err != nil {
+		return nil, fmt.Errorf("failed to parse report subscriptions: %w", err)
+	}
+
+	for i, sub := range subscriptions {
+		if sub.Name == "" || len(sub.Addresses) == 0 || len(sub.To) == 0 {
+			return nil, fmt.Errorf("report subscription %d: name, addresses and to are required", i+1)
+		}
+		if _, err := DailyCronSpec(sub.Time); err != nil {
+			return nil, fmt.Errorf("report subscription %q: %w", sub.Name, err)
+		}
+	}
+
+	return subscriptions, nil
+}
+
+// DailyCronSpec converts a "HH:MM" time of day into a daily cron expression
+func DailyCronSpec(at string) (string, error) {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return "", fmt.Errorf("invalid time %q, expected HH:MM", at)
+	}
+	return fmt.Sprintf("%d %d * * *", t.Minute(), t.Hour()), nil
+}
+
+// Subscriptions returns the configured report subscriptions
+func (r *ReportService) Subscriptions() []models.ReportSubscription {
+	return r.subscriptions
+}
+
+// SendScheduled emails sub's report for the previous day; it is called by the scheduler
+func (r *ReportService) SendScheduled(sub models.ReportSubscription) {
+	date := time.Now().AddDate(0, 0, -1)
+	if err := r.Send(context.Background(), sub, date); err != nil {
+		log.Printf("Daily report %q failed: %v", sub.Name, err)
+		return
+	}
+	log.Printf("Sent daily report %q for %s to %s", sub.Name, date.Format("2006-01-02"), strings.Join(sub.To, ", "))
+}
+
+// Send builds sub's report for date and emails it to the subscribers
+func (r *ReportService) Send(ctx context.Context, sub models.ReportSubscription, date time.Time) error {
+	if r.mailer == nil {
+		return errors.New("SMTP is not configured")
+	}
+
+	report := r.BuildDailyReport(ctx, sub.Name, sub.Addresses, date)
+
+	body, err := FormatReport(report)
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("%s daily P&L %s: %s", report.Name, report.Date, formatSignedUSD(report.TotalPnL))
+	return r.mailer.Send(sub.To, subject, body)
+}
+
+// BuildDailyReport summarizes each address's trading on the local calendar day
+// containing date, refreshing the cache first so the day is complete
+func (r *ReportService) BuildDailyReport(ctx context.Context, name string, inputs []string, date time.Time) models.DailyReport {
+	ctx, span := tracer.Start(ctx, "ReportService.BuildDailyReport", trace.WithAttributes(attribute.String("report", name)))
+	defer span.End()
+
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	report := models.DailyReport{
+		Name:        name,
+		Date:        dayStart.Format("2006-01-02"),
+		GeneratedAt: time.Now(),
+	}
+
+	// Fetch back to the start of the report day
+	days := int(math.Ceil(time.Since(dayStart).Hours() / 24))
+
+	for _, input := range inputs {
+		account := models.AccountReport{Address: input}
+
+		address, err := r.addressBook.Resolve(input)
+		if err != nil {
+			account.Error = err.Error()
+			report.Accounts = append(report.Accounts, account)
+			continue
+		}
+		account.Address = address
+		account.Label = r.addressBook.Label(address)
+
+		trades, err := r.reconService.RefreshCache(ctx, address, days)
+		var partial *PartialError
+		if err != nil && !errors.As(err, &partial) {
+			account.Error = err.Error()
+			report.Accounts = append(report.Accounts, account)
+			continue
+		}
+
+		reportDay(&account, trades, r.reconService.CachedMissingRanges(address), dayStart, dayEnd)
+		if r.ledger != nil {
+			r.reportLedgerIncome(ctx, &account, dayStart)
+		}
+
+		report.TotalPnL = (report.TotalPnL + account.PnL).Round()
+		report.TotalFees = (report.TotalFees + account.Fees).Round()
+		report.Accounts = append(report.Accounts, account)
+	}
+
+	return report
+}
+
+// reportLedgerIncome sets the referral rewards and other income account
+// earned on the local day starting at dayStart, syncing the ledger first. A
+// failed sync is logged and reports the income stored so far.
+func (r *ReportService) reportLedgerIncome(ctx context.Context, account *models.AccountReport, dayStart time.Time) {
+	if err := r.ledger.Sync(ctx, account.Address, dayStart); err != nil {
+		log.Printf("Error syncing ledger for %s report: %v", account.Address, err)
+	}
+	date := dayStart.Format("2006-01-02")
+	income := BuildIncomeSummary(account.Address, r.ledger.Entries(account.Address, nil, date, date), "day")
+	account.Referrals = income.Totals.Referrals
+	account.OtherIncome = income.Totals.OtherIncome
+}
+
+// reportDay fills in account from the trades and missing ranges of the day
+// [dayStart, dayEnd) and returns the day's trades and start position
+// discrepancies. trades may start before the day so that its first fill is
+// checked against the fill before it.
+func reportDay(account *models.AccountReport, trades []models.Trade, missing []models.TimeRange, dayStart, dayEnd time.Time) ([]models.Trade, []models.PositionDiscrepancy) {
+	var dayTrades []models.Trade
+	for _, trade := range trades {
+		if !trade.Time.Before(dayStart) && trade.Time.Before(dayEnd) {
+			dayTrades = append(dayTrades, trade)
+		}
+	}
+	summarizeDay(account, dayTrades)
+
+	date := dayStart.Format("2006-01-02")
+	discrepancies, _, _ := CheckStartPositions(trades)
+	var dayDiscrepancies []models.PositionDiscrepancy
+	for _, d := range discrepancies {
+		if d.Date == date {
+			dayDiscrepancies = append(dayDiscrepancies, d)
+		}
+	}
+	if len(dayDiscrepancies) > 0 {
+		account.PositionGaps = groupGaps(dayDiscrepancies)
+	}
+
+	for _, r := range missing {
+		if r.Start.Before(dayEnd) && !r.End.Before(dayStart) {
+			account.MissingRanges = append(account.MissingRanges, r)
+		}
+	}
+
+	return dayTrades, dayDiscrepancies
+}
+
+// summarizeDay fills in account's totals and per-coin winners and losers from one day's trades
+func summarizeDay(account *models.AccountReport, trades []models.Trade) {
+	byCoin := make(map[string]*models.CoinPnL)
+	for _, trade := range trades {
+		coin, exists := byCoin[trade.Coin]
+		if !exists {
+			coin = &models.CoinPnL{Coin: trade.Coin}
+			byCoin[trade.Coin] = coin
+		}
+
+		switch trade.Side {
+		case "B":
+			coin.PnL = (coin.PnL - trade.Value).Round()
+		case "A":
+			coin.PnL = (coin.PnL + trade.Value).Round()
+		}
+		coin.TradeCount++
+
+		account.TradeCount++
+		account.Volume = (account.Volume + trade.Value).Round()
+		account.Fees = (account.Fees + trade.Fee).Round()
+		if trade.Fee < 0 {
+			account.Rebates = (account.Rebates - trade.Fee).Round()
+		}
+	}
+
+	coins := make([]models.CoinPnL, 0, len(byCoin))
+	for _, coin := range byCoin {
+		account.PnL = (account.PnL + coin.PnL).Round()
+		coins = append(coins, *coin)
+	}
+	sort.Slice(coins, func(i, j int) bool {
+		if coins[i].PnL != coins[j].PnL {
+			return coins[i].PnL > coins[j].PnL
+		}
+		return coins[i].Coin < coins[j].Coin
+	})
+
+	account.TopWinners = []models.CoinPnL{}
+	account.TopLosers = []models.CoinPnL{}
+	for i := 0; i < len(coins) && len(account.TopWinners) < config.ReportTopCoins; i++ {
+		if coins[i].PnL > 0 {
+			account.TopWinners = append(account.TopWinners, coins[i])
+		}
+	}
+	for i := len(coins) - 1; i >= 0 && len(account.TopLosers) < config.ReportTopCoins; i-- {
+		if coins[i].PnL < 0 {
+			account.TopLosers = append(account.TopLosers, coins[i])
+		}
+	}
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"pnl":  formatSignedUSD,
+	"usd":  formatUSD,
+	"time": func(t time.Time) string { return t.Format("2006-01-02 15:04 MST") },
+}).Parse(`{{.Name}}: daily P&L for {{.Date}}
+
+Total P&L:  {{pnl .TotalPnL}}
+Total fees: {{usd .TotalFees}}
+{{range .Accounts}}
+== {{if .Label}}{{.Label}} ({{.Address}}){{else}}{{.Address}}{{end}} ==
+{{if .Error}}Could not refresh: {{.Error}}
+{{else}}P&L:    {{pnl .PnL}}
+Trades: {{.TradeCount}}
+Volume: {{usd .Volume}}
+Fees:   {{usd .Fees}}
+{{if .Rebates}}Rebates: {{usd .Rebates}} (included in fees)
+{{end}}{{if .Referrals}}Referrals: {{usd .Referrals}}
+{{end}}{{if .OtherIncome}}Other income (points, airdrops): {{pnl .OtherIncome}}
+{{end}}{{if .TopWinners}}
+Top winners:
+{{range .TopWinners}}  {{.Coin}}: {{pnl .PnL}} ({{.TradeCount}} trades)
+{{end}}{{end}}{{if .TopLosers}}
+Top losers:
+{{range .TopLosers}}  {{.Coin}}: {{pnl .PnL}} ({{.TradeCount}} trades)
+{{end}}{{end}}{{if .MissingRanges}}
+Reconciliation breaks (trades could not be fetched):
+{{range .MissingRanges}}  {{time .Start}} to {{time .End}}
+{{end}}{{end}}{{if .PositionGaps}}
+Position gaps (missing or duplicated fills):
+{{range .PositionGaps}}  {{.Coin}}: {{.Count}} mismatches, net size {{.NetDifference}}
+{{end}}{{end}}{{end}}{{end}}`))
+
+// FormatReport renders a daily report as the plain-text email body
+func FormatReport(report models.DailyReport) (string, error) {
+	var b strings.Builder
+	if err := reportTemplate.Execute(&b, report); err != nil {
+		return "", fmt.Errorf("failed to render report: %w", err)
+	}
+	return b.String(), nil
+}
+
+// formatUSD formats v as a dollar amount with thousands separators, e.g. "-$1,234.56"
+func formatUSD(v models.Money) string {
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+
+	whole := fmt.Sprintf("%.2f", v)
+	intPart, frac := whole[:len(whole)-3], whole[len(whole)-3:]
+	for i := len(intPart) - 3; i > 0; i -= 3 {
+		intPart = intPart[:i] + "," + intPart[i:]
+	}
+	return sign + "$" + intPart + frac
+}
+
+// formatSignedUSD is formatUSD with an explicit "+" on gains
+func formatSignedUSD(v models.Money) string {
+	if v > 0 {
+		return "+" + formatUSD(v)
+	}
+	return formatUSD(v)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test summarizeDay totals and winners/losers
+func TestSummarizeDay(t *testing.T) {
+	trade := func(coin, side string, value, fee models.Money) models.Trade {
+		return models.Trade{Coin: coin, Side: side, Price: value, Size: 1, Value: value, Fee: fee}
+	}
+
+	var account models.AccountReport
+	summarizeDay(&account, []models.Trade{
+		trade("BTC", "B", 100, 0.1),
+		trade("BTC", "A", 300, 0.3),
+		trade("ETH", "B", 50, 0.05),
+		trade("SOL", "A", 20, -0.01),
+	})
+
+	if account.TradeCount != 4 || account.PnL != 170 || account.Volume != 470 {
+		t.Errorf("Unexpected totals: %+v", account)
+	}
+	if account.Fees < 0.4399 || account.Fees > 0.4401 {
+		t.Errorf("Expected fees net of rebates to be 0.44, got %v", account.Fees)
+	}
+	if len(account.TopWinners) != 2 || account.TopWinners[0].Coin != "BTC" || account.TopWinners[1].Coin != "SOL" {
+		t.Errorf("Unexpected winners: %+v", account.TopWinners)
+	}
+	if len(account.TopLosers) != 1 || account.TopLosers[0].Coin != "ETH" {
+		t.Errorf("Unexpected losers: %+v", account.TopLosers)
+	}
+}
+
+// Test FormatReport output
+func TestFormatReport(t *testing.T) {
+	body, err := FormatReport(models.DailyReport{
+		Name:     "Main",
+		Date:     "2025-01-01",
+		TotalPnL: 1234.5,
+		Accounts: []models.AccountReport{
+			{
+				Address:    testAddress,
+				Label:      "Main account",
+				PnL:        1234.5,
+				TopWinners: []models.CoinPnL{{Coin: "BTC", PnL: 1234.5, TradeCount: 2}},
+				MissingRanges: []models.TimeRange{
+					{Start: time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 1, 4, 0, 0, 0, time.UTC)},
+				},
+			},
+			{Address: "nobody.eth", Error: "ENS name not found"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("FormatReport failed: %v", err)
+	}
+
+	for _, expected := range []string{
+		"Total P&L:  +$1,234.50",
+		"== Main account (" + testAddress + ") ==",
+		"  BTC: +$1,234.50 (2 trades)",
+		"Reconciliation breaks",
+		"Could not refresh: ENS name not found",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected report to contain %q, got:\n%s", expected, body)
+		}
+	}
+}
+
+// Test LoadReportSubscriptions validation
+func TestLoadReportSubscriptions(t *testing.T) {
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "reports.json")
+		os.WriteFile(path, []byte(content), 0644)
+		return path
+	}
+
+	subs, err := LoadReportSubscriptions(write(`[{"name": "Main", "addresses": ["main account"], "to": ["me@example.com"], "time": "07:30"}]`))
+	if err != nil || len(subs) != 1 {
+		t.Fatalf("Expected 1 subscription, got %v (%v)", subs, err)
+	}
+	if spec, _ := DailyCronSpec(subs[0].Time); spec != "30 7 * * *" {
+		t.Errorf("Expected cron spec \"30 7 * * *\", got %q", spec)
+	}
+
+	if _, err := LoadReportSubscriptions(write(`[{"name": "Main", "addresses": ["x"], "to": ["me@example.com"], "time": "7pm"}]`)); err == nil {
+		t.Errorf("Expected invalid time to be rejected")
+	}
+}
+
+// Test buildMessage headers and line endings
+func TestBuildMessage(t *testing.T) {
+	msg := string(buildMessage("recon@example.com", []string{"a@example.com", "b@example.com"}, "P&L", "line 1\nline 2", time.Now()))
+
+	if !strings.Contains(msg, "To: a@example.com, b@example.com\r\n") {
+		t.Errorf("Expected all recipients in To header, got:\n%s", msg)
+	}
+	if !strings.HasSuffix(msg, "\r\n\r\nline 1\r\nline 2") {
+		t.Errorf("Expected CRLF body after headers, got %q", msg)
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"hyperliquid-recon/config"
+	"sync"
+	"time"
+)
+
+// upstreamResponses is shared by every Hyperliquid client, so an address
+// tracked twice, e.g. by two tenants or under two labels, is fetched once
+var upstreamResponses = newResponseCache(config.UpstreamResponseTTL)
+
+// responseKey identifies a batch request
+type responseKey struct {
+	apiURL     string
+	address    string
+	start, end int64 // Unix milliseconds
+}
+
+// responseEntry is a batch that has been fetched or is being fetched
+type responseEntry struct {
+	done    chan struct{} // Closed once fills and err are set
+	fills   []FillResponse
+	err     error
+	expires time.Time
+}
+
+// responseCache answers a batch request with the fills of an identical request
+// made within the TTL, or waits for one that is still running, instead of
+// calling the API again. Failed requests are not cached. Cached fills are
+// shared, so callers must not modify them.
+type responseCache struct {
+	ttl     time.Duration
+	entries map[responseKey]*responseEntry
+	mu      sync.Mutex
+}
+
+// newResponseCache creates a cache that keeps responses for ttl; with a ttl of 0 nothing is cached
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{ttl: ttl, entries: make(map[responseKey]*responseEntry)}
+}
+
+type freshResponsesKey struct{}
+
+// WithFreshResponses returns a context whose fetches always go to the API, for
+// re-fetches that are meant to replace what was fetched before. Their
+// responses are still cached for other requests.
+func WithFreshResponses(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshResponsesKey{}, true)
+}
+
+// get returns the fills for key, calling fetch unless an identical request
+// has been answered within the TTL or is running. It also reports whether the
+// fills came from another request.
+func (c *responseCache) get(ctx context.Context, key responseKey, fetch func() ([]FillResponse, error)) ([]FillResponse, bool, error) {
+	if c.ttl <= 0 {
+		fills, err := fetch()
+		return fills, false, err
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	fresh, _ := ctx.Value(freshResponsesKey{}).(bool)
+	if entry, exists := c.entries[key]; exists && !fresh && (entry.expires.IsZero() || now.Before(entry.expires)) {
+		c.mu.Unlock()
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+		if entry.err == nil {
+			upstreamStats.cachedResponses.Add(1)
+			return entry.fills, true, nil
+		}
+		// The request this one waited for failed, so make one of its own
+		fills, err := fetch()
+		return fills, false, err
+	}
+	c.prune(now)
+	entry := &responseEntry{done: make(chan struct{})}
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	entry.fills, entry.err = fetch()
+
+	c.mu.Lock()
+	if entry.err != nil {
+		// A later request for the key may have replaced this entry
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+	} else {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	c.mu.Unlock()
+	close(entry.done)
+
+	return entry.fills, false, entry.err
+}
+
+// prune drops expired entries; caller must hold c.mu
+func (c *responseCache) prune(now time.Time) {
+	for key, entry := range c.entries {
+		if !entry.expires.IsZero() && !now.Before(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
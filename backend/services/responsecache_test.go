@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test answering identical batch requests from the response cache
+func TestResponseCache(t *testing.T) {
+	key := responseKey{apiURL: "http://api", address: "0xabc", start: 1, end: 2}
+
+	t.Run("should answer a repeated window within the TTL", func(t *testing.T) {
+		c := newResponseCache(time.Minute)
+		var calls atomic.Int32
+		fetch := func() ([]FillResponse, error) {
+			calls.Add(1)
+			return []FillResponse{{Coin: "ETH"}}, nil
+		}
+
+		c.get(context.Background(), key, fetch)
+		fills, cached, err := c.get(context.Background(), key, fetch)
+		if err != nil || !cached || len(fills) != 1 || calls.Load() != 1 {
+			t.Errorf("Expected a cached answer after 1 request, got cached %v after %d (%v)", cached, calls.Load(), err)
+		}
+
+		other := key
+		other.end = 3
+		if _, cached, _ := c.get(context.Background(), other, fetch); cached || calls.Load() != 2 {
+			t.Errorf("Expected a different window to be requested")
+		}
+	})
+
+	t.Run("should share a request that is still running", func(t *testing.T) {
+		c := newResponseCache(time.Minute)
+		var calls atomic.Int32
+		release := make(chan struct{})
+		fetch := func() ([]FillResponse, error) {
+			calls.Add(1)
+			<-release
+			return []FillResponse{{Coin: "ETH"}}, nil
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if fills, _, err := c.get(context.Background(), key, fetch); err != nil || len(fills) != 1 {
+					t.Errorf("Expected 1 fill, got %d (%v)", len(fills), err)
+				}
+			}()
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		if calls.Load() != 1 {
+			t.Errorf("Expected 1 request, got %d", calls.Load())
+		}
+	})
+
+	t.Run("should not cache failures", func(t *testing.T) {
+		c := newResponseCache(time.Minute)
+		failing := func() ([]FillResponse, error) { return nil, errors.New("upstream down") }
+		c.get(context.Background(), key, failing)
+
+		var calls atomic.Int32
+		if _, cached, err := c.get(context.Background(), key, func() ([]FillResponse, error) {
+			calls.Add(1)
+			return nil, nil
+		}); err != nil || cached || calls.Load() != 1 {
+			t.Errorf("Expected the window to be requested again after a failure")
+		}
+	})
+
+	t.Run("should bypass the cache for fresh fetches and when disabled", func(t *testing.T) {
+		var calls atomic.Int32
+		fetch := func() ([]FillResponse, error) {
+			calls.Add(1)
+			return nil, nil
+		}
+
+		c := newResponseCache(time.Minute)
+		c.get(context.Background(), key, fetch)
+		c.get(WithFreshResponses(context.Background()), key, fetch)
+
+		disabled := newResponseCache(0)
+		disabled.get(context.Background(), key, fetch)
+		disabled.get(context.Background(), key, fetch)
+
+		if calls.Load() != 4 {
+			t.Errorf("Expected every fetch to be requested, got %d requests", calls.Load())
+		}
+	})
+}
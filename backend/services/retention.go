@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"log"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetentionService deletes a tenant's records once they are older than the
+// retention policy: cached fills and their copies in the event log,
+// reconciliation runs and external reconciliations, and finished refresh
+// jobs. It keeps counts of what it deleted.
+type RetentionService struct {
+	reconService    *ReconciliationService
+	runs            *RunStore
+	reconciliations *ExternalReconStore
+	jobs            *JobManager
+	policy          models.RetentionPolicy
+	status          models.RetentionStatus
+	mu              sync.Mutex // Serializes pruning runs and guards policy and status
+}
+
+// NewRetentionService creates a retention service that keeps everything until a policy is set
+func NewRetentionService(reconService *ReconciliationService, runs *RunStore, reconciliations *ExternalReconStore, jobs *JobManager) *RetentionService {
+	return &RetentionService{
+		reconService:    reconService,
+		runs:            runs,
+		reconciliations: reconciliations,
+		jobs:            jobs,
+		status:          models.RetentionStatus{Deleted: make(map[string]int)},
+	}
+}
+
+// ParseRetention parses a retention period: a duration such as "720h" or a
+// number of days such as "730d". An empty period is zero.
+func ParseRetention(period string) (time.Duration, error) {
+	if period == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(period, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("%q must be a positive number of days such as 730d", period)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q must be a positive duration such as 720h or 30d", period)
+	}
+	return d, nil
+}
+
+// ValidateRetention checks every period of a retention policy
+func ValidateRetention(policy models.RetentionPolicy) error {
+	for kind, period := range map[string]string{models.RetentionFills: policy.Fills, models.RetentionAudit: policy.Audit, models.RetentionJobs: policy.Jobs} {
+		if _, err := ParseRetention(period); err != nil {
+			return fmt.Errorf("%s: %v", kind, err)
+		}
+	}
+	return nil
+}
+
+// SetPolicy replaces the retention policy; it must have passed
+// ValidateRetention. Finished jobs are kept for JobRetention unless the
+// policy sets their retention.
+func (s *RetentionService) SetPolicy(policy models.RetentionPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.policy = policy
+	jobs, _ := ParseRetention(policy.Jobs)
+	if jobs == 0 {
+		jobs = config.JobRetention
+	}
+	s.jobs.SetRetention(jobs)
+}
+
+// Status returns the retention policy and what has been pruned since startup
+func (s *RetentionService) Status() models.RetentionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.status
+	status.Policy = s.policy
+	status.Deleted = maps.Clone(s.status.Deleted)
+	return status
+}
+
+// RunScheduled prunes expired records; it is called by the scheduler
+func (s *RetentionService) RunScheduled() {
+	report := s.Prune(context.Background(), false)
+	for _, result := range report.Results {
+		if result.Error != "" {
+			log.Printf("Pruning %s failed: %s", result.Kind, result.Error)
+		} else if result.Deleted > 0 {
+			log.Printf("Pruned %d %s records older than %s", result.Deleted, result.Kind, result.Retention)
+		}
+	}
+}
+
+// Prune deletes every record older than the retention of its kind and
+// reports how many were deleted. A dry run deletes nothing and reports what
+// would have been. Kinds without a retention are skipped.
+func (s *RetentionService) Prune(ctx context.Context, dryRun bool) models.RetentionReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := models.RetentionReport{DryRun: dryRun, StartedAt: time.Now(), Results: []models.RetentionResult{}}
+	kinds := []struct {
+		kind   string
+		period string
+		prune  func(result *models.RetentionResult) error
+	}{
+		{models.RetentionFills, s.policy.Fills, func(result *models.RetentionResult) error { return s.pruneFills(ctx, result, dryRun) }},
+		{models.RetentionAudit, s.policy.Audit, func(result *models.RetentionResult) error {
+			runs, err := s.runs.Prune(result.Cutoff, dryRun)
+			result.Deleted += runs
+			if err != nil {
+				return err
+			}
+			recons, err := s.reconciliations.Prune(result.Cutoff, dryRun)
+			result.Deleted += recons
+			return err
+		}},
+		{models.RetentionJobs, s.policy.Jobs, func(result *models.RetentionResult) error {
+			result.Deleted = s.jobs.Prune(result.Cutoff, dryRun)
+			return nil
+		}},
+	}
+
+	failed := false
+	for _, kind := range kinds {
+		// The policy was validated by SetPolicy's caller
+		retention, _ := ParseRetention(kind.period)
+		if retention == 0 {
+			continue
+		}
+		result := models.RetentionResult{Kind: kind.kind, Retention: kind.period, Cutoff: report.StartedAt.Add(-retention)}
+		if err := kind.prune(&result); err != nil {
+			result.Error = err.Error()
+			failed = true
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+
+	if dryRun {
+		s.status.LastDryRun = &report
+		return report
+	}
+	s.status.Runs++
+	if failed {
+		s.status.Failures++
+	}
+	for _, result := range report.Results {
+		s.status.Deleted[result.Kind] += result.Deleted
+	}
+	s.status.LastRun = &report
+	return report
+}
+
+// pruneFills drops the cached fills of every address before the cutoff and
+// then compacts the event log so they are gone from it too
+func (s *RetentionService) pruneFills(ctx context.Context, result *models.RetentionResult, dryRun bool) error {
+	var errs []error
+	for _, address := range s.reconService.CachedAddresses() {
+		pruned, err := s.reconService.PruneTrades(ctx, address, result.Cutoff, dryRun)
+		if err != nil && !errors.Is(err, ErrNotCached) {
+			errs = append(errs, fmt.Errorf("%s: %w", address, err))
+			continue
+		}
+		if pruned > 0 {
+			if result.Addresses == nil {
+				result.Addresses = make(map[string]int)
+			}
+			result.Addresses[address] = pruned
+			result.Deleted += pruned
+		}
+	}
+
+	if events := s.reconService.events; events != nil && !dryRun && result.Deleted > 0 {
+		removed, err := events.Compact(result.Cutoff, events.Len())
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			log.Printf("Compacted the event log: %d fills older than %s removed", removed, result.Cutoff.Format(time.RFC3339))
+		}
+	}
+	return errors.Join(errs...)
+}
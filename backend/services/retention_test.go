@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"hyperliquid-recon/models"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test pruning fills, audit records and jobs past their retention
+func TestRetentionPrune(t *testing.T) {
+	events, err := OpenEventStore(filepath.Join(t.TempDir(), "events.jsonl"))
+	if err != nil {
+		t.Fatalf("Failed to open event log: %v", err)
+	}
+	defer events.Close()
+	rs := NewReconciliationService()
+	rs.UseEventStore(events)
+
+	now := time.Now()
+	rs.ImportTrades(testAddress, []models.Trade{
+		{Time: now.AddDate(-3, 0, 0), Coin: "BTC", Side: "B", Price: 100, Size: 1, Value: 100},
+		{Time: now.AddDate(-2, -1, 0), Coin: "BTC", Side: "A", Price: 110, Size: 1, Value: 110},
+		{Time: now.AddDate(0, -1, 0), Coin: "ETH", Side: "B", Price: 10, Size: 2, Value: 20},
+	})
+
+	runs, _ := NewRunStore("")
+	runs.Record(testAddress, "", 30, models.TimeRange{}, nil, nil, 0)
+	runs.Record(testAddress, "", 30, models.TimeRange{}, nil, nil, 0)
+	runs.runs[0].CreatedAt = now.AddDate(-2, 0, 0)
+	recons, _ := NewExternalReconStore("")
+	jobs := NewJobManager(rs, nil)
+
+	retention := NewRetentionService(rs, runs, recons, jobs)
+	policy := models.RetentionPolicy{Fills: "730d", Audit: "8760h"}
+	if err := ValidateRetention(policy); err != nil {
+		t.Fatalf("Expected a valid policy, got %v", err)
+	}
+	retention.SetPolicy(policy)
+
+	t.Run("should only report what a dry run would delete", func(t *testing.T) {
+		report := retention.Prune(context.Background(), true)
+		if len(report.Results) != 2 || report.Results[0].Deleted != 2 || report.Results[0].Addresses[testAddress] != 2 || report.Results[1].Deleted != 1 {
+			t.Errorf("Expected 2 fills and 1 run to be reported, got %+v", report.Results)
+		}
+		if trades, _ := rs.CachedTrades(testAddress); len(trades) != 3 {
+			t.Errorf("Expected a dry run to keep every trade, got %d", len(trades))
+		}
+		if status := retention.Status(); status.Runs != 0 || status.LastDryRun == nil {
+			t.Errorf("Expected only the dry run to be recorded, got %+v", status)
+		}
+	})
+
+	t.Run("should delete expired records and compact the event log", func(t *testing.T) {
+		report := retention.Prune(context.Background(), false)
+		for _, result := range report.Results {
+			if result.Error != "" {
+				t.Errorf("Expected %s to be pruned, got %s", result.Kind, result.Error)
+			}
+		}
+		trades, _ := rs.CachedTrades(testAddress)
+		if len(trades) != 1 || trades[0].Coin != "ETH" {
+			t.Errorf("Expected only the recent trade to be kept, got %+v", trades)
+		}
+		if len(runs.List("")) != 1 {
+			t.Errorf("Expected the old run to be deleted, got %d runs", len(runs.List("")))
+		}
+
+		stored := 0
+		events.Replay(func(event models.TradeEvent) { stored += len(event.Trades) })
+		if stored != 1 {
+			t.Errorf("Expected 1 trade left in the event log, got %d", stored)
+		}
+		rebuilt := NewReconciliationService()
+		rebuilt.UseEventStore(events)
+		if _, err := rebuilt.Rebuild(); err != nil {
+			t.Fatalf("Expected the compacted log to replay, got %v", err)
+		}
+		if trades, _ := rebuilt.CachedTrades(testAddress); len(trades) != 1 {
+			t.Errorf("Expected the rebuilt cache to match, got %d trades", len(trades))
+		}
+
+		// New events are appended to the compacted log
+		rs.ImportTrades(testAddress, []models.Trade{{Time: now, Coin: "ETH", Side: "A", Price: 11, Size: 2, Value: 22}})
+		if events.Len() != 3 {
+			t.Errorf("Expected 3 events, got %d", events.Len())
+		}
+
+		status := retention.Status()
+		if status.Runs != 1 || status.Deleted[models.RetentionFills] != 2 || status.Deleted[models.RetentionAudit] != 1 {
+			t.Errorf("Expected pruning metrics, got %+v", status)
+		}
+	})
+
+	t.Run("should reject invalid periods", func(t *testing.T) {
+		for _, period := range []string{"2y", "-5d", "0h", "d"} {
+			if err := ValidateRetention(models.RetentionPolicy{Jobs: period}); err == nil {
+				t.Errorf("Expected %q to be rejected", period)
+			}
+		}
+	})
+}
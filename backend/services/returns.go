@@ -0,0 +1,81 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"sort"
+	"time"
+)
+
+// Sources of the capital returns are measured against
+const (
+	CapitalFixed        = "fixed"        // A configured base capital plus the P&L since
+	CapitalAccountValue = "accountValue" // The exchange-reported account value history
+)
+
+// applyReturns fills in daily and cumulative returns on records (newest first)
+// and returns the capital source, the capital at the start of the first day
+// with a return, and the total return. Record dates are days in loc.
+//
+// With a fixed base, each day is measured against the base plus the P&L of
+// earlier days. With account values, it is measured against the last value
+// reported before the day started, so deposits and withdrawals don't count
+// as performance. Cumulative returns compound the daily returns, which for a
+// fixed base is simply cumulative P&L over the base. Days without a positive
+// starting capital get no return.
+func applyReturns(records []models.DailyPnL, entry models.AddressEntry, history []models.AccountValue, loc *time.Location) (string, *models.Money, *float64) {
+	source := CapitalFixed
+	if entry.DeriveCapital {
+		source = CapitalAccountValue
+	} else if entry.BaseCapital == nil {
+		return "", nil, nil
+	}
+
+	var base *models.Money
+	growth := 1.0
+	for i := len(records) - 1; i >= 0; i-- {
+		record := &records[i]
+
+		var capital models.Money
+		if source == CapitalFixed {
+			capital = (*entry.BaseCapital + record.CumulativePnL - record.DailyPnL).Round()
+		} else {
+			day, err := time.ParseInLocation(time.DateOnly, record.Date, loc)
+			if err != nil {
+				continue
+			}
+			value, ok := accountValueAt(history, day)
+			if !ok {
+				continue
+			}
+			capital = value
+		}
+		if capital <= 0 {
+			continue
+		}
+
+		if base == nil {
+			base = &capital
+		}
+		dailyReturn := float64(record.DailyPnL / capital)
+		growth *= 1 + dailyReturn
+
+		returnPct, cumulativePct := dailyReturn*100, (growth-1)*100
+		record.ReturnPct, record.CumulativeReturnPct = &returnPct, &cumulativePct
+	}
+
+	if base == nil {
+		return source, nil, nil
+	}
+	total := (growth - 1) * 100
+	return source, base, &total
+}
+
+// accountValueAt returns the last account value in history (oldest first)
+// reported at or before t
+func accountValueAt(history []models.AccountValue, t time.Time) (models.Money, bool) {
+	i := sort.Search(len(history), func(i int) bool { return history[i].Time.After(t) })
+	if i == 0 {
+		return 0, false
+	}
+	return history[i-1].Value, true
+}
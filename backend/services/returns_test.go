@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"hyperliquid-recon/models"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test percentage returns against fixed and derived capital
+func TestApplyReturns(t *testing.T) {
+	// Newest first, as in summaries
+	newRecords := func() []models.DailyPnL {
+		return []models.DailyPnL{
+			{Date: "2025-01-03", DailyPnL: -1100, CumulativePnL: -100},
+			{Date: "2025-01-02", DailyPnL: 1000, CumulativePnL: 1000},
+		}
+	}
+
+	t.Run("should measure days against base capital plus earlier P&L", func(t *testing.T) {
+		base := models.Money(10000)
+		records := newRecords()
+		source, start, total := applyReturns(records, models.AddressEntry{BaseCapital: &base}, nil, time.Local)
+
+		if source != CapitalFixed || start == nil || *start != 10000 {
+			t.Fatalf("Expected fixed capital of 10000, got %s %v", source, start)
+		}
+		if *records[1].ReturnPct != 10 || *records[0].ReturnPct != -10 {
+			t.Errorf("Expected +10%% then -10%%, got %v and %v", *records[1].ReturnPct, *records[0].ReturnPct)
+		}
+		if math.Abs(*total-(-1)) > 1e-9 || math.Abs(*records[0].CumulativeReturnPct-*total) > 1e-9 {
+			t.Errorf("Expected a total return of -1%%, got %v", *total)
+		}
+	})
+
+	t.Run("should measure days against the account value before them", func(t *testing.T) {
+		day := func(date string) time.Time {
+			d, _ := time.ParseInLocation(time.DateOnly, date, time.Local)
+			return d
+		}
+		history := []models.AccountValue{
+			{Time: day("2025-01-01"), Value: 5000},
+			// A deposit during the first day isn't counted as performance
+			{Time: day("2025-01-02").Add(12 * time.Hour), Value: 11000},
+		}
+		records := newRecords()
+		source, start, _ := applyReturns(records, models.AddressEntry{DeriveCapital: true}, history, time.Local)
+
+		if source != CapitalAccountValue || *start != 5000 {
+			t.Fatalf("Expected account value capital of 5000, got %s %v", source, start)
+		}
+		if *records[1].ReturnPct != 20 || *records[0].ReturnPct != -10 {
+			t.Errorf("Expected +20%% then -10%%, got %v and %v", *records[1].ReturnPct, *records[0].ReturnPct)
+		}
+		if math.Abs(*records[0].CumulativeReturnPct-8) > 1e-9 {
+			t.Errorf("Expected compounded return of 8%%, got %v", *records[0].CumulativeReturnPct)
+		}
+	})
+
+	t.Run("should leave returns out without capital", func(t *testing.T) {
+		records := newRecords()
+		source, start, total := applyReturns(records, models.AddressEntry{}, nil, time.Local)
+		if source != "" || start != nil || total != nil || records[0].ReturnPct != nil {
+			t.Errorf("Expected no returns, got %s %v %v", source, start, total)
+		}
+	})
+}
+
+// Test parsing the portfolio endpoint's account value history
+func TestFetchAccountValueHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			["day", {"accountValueHistory": [[1736000000000, "1.0"]]}],
+			["allTime", {"accountValueHistory": [[1736100000000, "2500.5"], [1736000000000, "2000.0"]], "pnlHistory": []}]
+		]`))
+	}))
+	defer server.Close()
+
+	client := NewHyperliquidClient()
+	client.apiURL = server.URL
+
+	history, err := client.FetchAccountValueHistory(context.Background(), "0xabc")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(history) != 2 || history[0].Value != 2000 || history[1].Value != 2500.5 {
+		t.Errorf("Expected the allTime history oldest first, got %+v", history)
+	}
+}
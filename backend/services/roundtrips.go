@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RoundTripsByPosition pairs each flat-to-flat position as one round trip,
+// in addition to the lot methods
+const RoundTripsByPosition = "position"
+
+// ErrInvalidRoundTripMethod is returned for an unknown round-trip pairing method
+var ErrInvalidRoundTripMethod = errors.New("method must be fifo, lifo or position")
+
+// BuildRoundTrips pairs entries and exits of trades into completed round
+// trips and returns those that exited since since. With fifo or lifo each
+// matched lot is a round trip; with position each position held from flat
+// back to flat is one, with size-weighted entry and exit prices. Positions
+// still open are left out.
+func BuildRoundTrips(address string, trades []models.Trade, method string, since time.Time) (models.RoundTripReport, error) {
+	method = strings.ToLower(method)
+	lotMethod := method
+	switch method {
+	case LotsFIFO, LotsLIFO:
+	case RoundTripsByPosition:
+		lotMethod = LotsFIFO
+	default:
+		return models.RoundTripReport{}, ErrInvalidRoundTripMethod
+	}
+
+	matches, openEpisodes, err := matchLots(trades, lotMethod)
+	if err != nil {
+		return models.RoundTripReport{}, err
+	}
+
+	var roundTrips []models.RoundTrip
+	if method == RoundTripsByPosition {
+		roundTrips = positionRoundTrips(matches, openEpisodes)
+	} else {
+		for _, match := range matches {
+			roundTrips = append(roundTrips, newRoundTrip(match.coin, match.long, match.entryTime, match.exitTime,
+				match.size, match.entryPrice*match.size, match.exitPrice*match.size, match.grossPnL(), match.entryFee+match.exitFee))
+		}
+	}
+
+	report := models.RoundTripReport{Address: address, Method: method, RoundTrips: []models.RoundTrip{}}
+	for _, roundTrip := range roundTrips {
+		if roundTrip.ExitTime.Before(since) {
+			continue
+		}
+		report.RoundTrips = append(report.RoundTrips, roundTrip)
+		report.NetPnL = (report.NetPnL + roundTrip.NetPnL).Round()
+		if roundTrip.NetPnL > 0 {
+			report.Wins++
+		}
+	}
+	sort.SliceStable(report.RoundTrips, func(i, j int) bool {
+		return report.RoundTrips[i].ExitTime.Before(report.RoundTrips[j].ExitTime)
+	})
+
+	report.Count = len(report.RoundTrips)
+	if report.Count > 0 {
+		report.WinRate = float64(report.Wins) / float64(report.Count)
+	}
+	return report, nil
+}
+
+// positionRoundTrips combines the lot matches of each closed position episode into one round trip
+func positionRoundTrips(matches []lotMatch, openEpisodes map[string]int) []models.RoundTrip {
+	type episodeKey struct {
+		coin    string
+		episode int
+	}
+	type episode struct {
+		first                       lotMatch
+		entryTime, exitTime         time.Time
+		size, entryValue, exitValue float64
+		grossPnL, fees              float64
+	}
+
+	episodes := make(map[episodeKey]*episode)
+	var order []episodeKey
+	for _, match := range matches {
+		if open, exists := openEpisodes[match.coin]; exists && open == match.episode {
+			continue
+		}
+
+		key := episodeKey{match.coin, match.episode}
+		e, exists := episodes[key]
+		if !exists {
+			e = &episode{first: match, entryTime: match.entryTime}
+			episodes[key] = e
+			order = append(order, key)
+		}
+		if match.entryTime.Before(e.entryTime) {
+			e.entryTime = match.entryTime
+		}
+		e.exitTime = match.exitTime
+		e.size += match.size
+		e.entryValue += match.entryPrice * match.size
+		e.exitValue += match.exitPrice * match.size
+		e.grossPnL += match.grossPnL()
+		e.fees += match.entryFee + match.exitFee
+	}
+
+	roundTrips := make([]models.RoundTrip, 0, len(order))
+	for _, key := range order {
+		e := episodes[key]
+		roundTrips = append(roundTrips, newRoundTrip(key.coin, e.first.long, e.entryTime, e.exitTime, e.size, e.entryValue, e.exitValue, e.grossPnL, e.fees))
+	}
+	return roundTrips
+}
+
+// newRoundTrip builds a round trip from its totals
+func newRoundTrip(coin string, long bool, entryTime, exitTime time.Time, size, entryValue, exitValue, grossPnL, fees float64) models.RoundTrip {
+	direction := "long"
+	if !long {
+		direction = "short"
+	}
+	duration := exitTime.Sub(entryTime)
+
+	roundTrip := models.RoundTrip{
+		Coin:       coin,
+		Direction:  direction,
+		EntryTime:  entryTime,
+		ExitTime:   exitTime,
+		Duration:   duration.String(),
+		DurationMs: duration.Milliseconds(),
+		Size:       models.Quantity(size).Round(),
+		GrossPnL:   models.Money(grossPnL).Round(),
+		Fees:       models.Money(fees).Round(),
+		NetPnL:     models.Money(grossPnL - fees).Round(),
+	}
+	if size > 0 {
+		roundTrip.EntryPrice = models.Money(entryValue / size).Round()
+		roundTrip.ExitPrice = models.Money(exitValue / size).Round()
+	}
+	if entryValue > 0 {
+		roundTrip.ReturnPct = float64(roundTrip.NetPnL) / entryValue * 100
+	}
+	return roundTrip
+}
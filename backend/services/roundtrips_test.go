@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"testing"
+	"time"
+)
+
+// Test round-trip pairing
+func TestBuildRoundTrips(t *testing.T) {
+	base := time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC)
+	trades := []models.Trade{
+		{Time: base, Coin: "ETH", Side: "B", Price: 100, Size: 1, Fee: 1},
+		{Time: base.Add(time.Hour), Coin: "ETH", Side: "B", Price: 110, Size: 1, Fee: 1},
+		{Time: base.Add(2 * time.Hour), Coin: "ETH", Side: "A", Price: 120, Size: 2, Fee: 2},
+		// Opens a short that is still open
+		{Time: base.Add(3 * time.Hour), Coin: "ETH", Side: "A", Price: 125, Size: 1},
+	}
+
+	t.Run("should pair lots oldest first", func(t *testing.T) {
+		report, err := BuildRoundTrips("0xabc", trades, LotsFIFO, time.Time{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if report.Count != 2 {
+			t.Fatalf("Expected 2 round trips, got %d", report.Count)
+		}
+
+		first := report.RoundTrips[0]
+		if first.EntryPrice != 100 || first.ExitPrice != 120 || first.GrossPnL != 20 || first.NetPnL != 18 {
+			t.Errorf("Unexpected first round trip %+v", first)
+		}
+		if first.Direction != "long" || first.DurationMs != 2*time.Hour.Milliseconds() {
+			t.Errorf("Expected a 2h long, got %s over %s", first.Direction, first.Duration)
+		}
+		if report.NetPnL != 26 || report.Wins != 2 {
+			t.Errorf("Expected net P&L 26 from 2 wins, got %v from %d", report.NetPnL, report.Wins)
+		}
+	})
+
+	t.Run("should pair lots newest first", func(t *testing.T) {
+		report, err := BuildRoundTrips("0xabc", trades, LotsLIFO, time.Time{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if report.RoundTrips[0].EntryPrice != 110 {
+			t.Errorf("Expected the newest lot to close first, got entry %v", report.RoundTrips[0].EntryPrice)
+		}
+	})
+
+	t.Run("should pair whole positions", func(t *testing.T) {
+		report, err := BuildRoundTrips("0xabc", trades, RoundTripsByPosition, time.Time{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if report.Count != 1 {
+			t.Fatalf("Expected 1 closed position, got %d", report.Count)
+		}
+		position := report.RoundTrips[0]
+		if position.Size != 2 || position.EntryPrice != 105 || position.NetPnL != 26 {
+			t.Errorf("Unexpected position round trip %+v", position)
+		}
+	})
+
+	t.Run("should split a flipping fill", func(t *testing.T) {
+		flip := []models.Trade{
+			{Time: base, Coin: "BTC", Side: "B", Price: 100, Size: 1},
+			{Time: base.Add(time.Hour), Coin: "BTC", Side: "A", Price: 90, Size: 3},
+			{Time: base.Add(2 * time.Hour), Coin: "BTC", Side: "B", Price: 80, Size: 2},
+		}
+		report, err := BuildRoundTrips("0xabc", flip, RoundTripsByPosition, time.Time{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if report.Count != 2 || report.RoundTrips[1].Direction != "short" || report.RoundTrips[1].GrossPnL != 20 {
+			t.Errorf("Expected a losing long then a winning short, got %+v", report.RoundTrips)
+		}
+	})
+
+	t.Run("should reject unknown methods", func(t *testing.T) {
+		if _, err := BuildRoundTrips("0xabc", trades, "hifo", time.Time{}); !errors.Is(err, ErrInvalidRoundTripMethod) {
+			t.Errorf("Expected ErrInvalidRoundTripMethod, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRunNotFound is returned for an unknown run ID
+var ErrRunNotFound = errors.New("run not found")
+
+// RunStore keeps the most recent reconciliation runs of each address,
+// persisted as JSON to path if one is set
+type RunStore struct {
+	runs []models.Run // Oldest first
+	mu   sync.RWMutex
+	path string
+}
+
+// NewRunStore creates a run store, loading saved runs from path if it exists
+func NewRunStore(path string) (*RunStore, error) {
+	rs := &RunStore{runs: []models.Run{}, path: path}
+	if path == "" {
+		return rs, nil
+	}
+
+	data, err := readStoreFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return rs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runs: %w", err)
+	}
+	if err := json.Unmarshal(data, &rs.runs); err != nil {
+		return nil, fmt.Errorf("failed to parse runs: %w", err)
+	}
+	return rs, nil
+}
+
+// Record stores a run of address computed from trades, dropping the oldest
+// runs of the address beyond the history limit
+func (s *RunStore) Record(address, label string, days int, coverage models.TimeRange, trades []models.Trade, records []models.DailyPnL, totalPnL models.Money) models.Run {
+	run := models.Run{
+		ID:                newID(),
+		Address:           address,
+		Label:             label,
+		Days:              days,
+		CalculatorVersion: config.CalculatorVersion,
+		InputsHash:        hashTrades(trades),
+		TradeCount:        len(trades),
+		Coverage:          coverage,
+		TotalPnL:          totalPnL,
+		Records:           records,
+		CreatedAt:         time.Now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runs = append(s.runs, run)
+
+	excess := -config.RunHistoryLimit
+	for _, stored := range s.runs {
+		if stored.Address == address {
+			excess++
+		}
+	}
+	kept := s.runs[:0]
+	for _, stored := range s.runs {
+		if stored.Address == address && excess > 0 {
+			excess--
+			continue
+		}
+		kept = append(kept, stored)
+	}
+	s.runs = kept
+
+	if err := s.persist(); err != nil {
+		log.Printf("Failed to save runs: %v", err)
+	}
+	return run
+}
+
+// List returns the runs of address (or of every address if it is empty),
+// newest first and without their daily records
+func (s *RunStore) List(address string) []models.Run {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	runs := []models.Run{}
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		if address == "" || s.runs[i].Address == address {
+			run := s.runs[i]
+			run.Records = nil
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+// Get returns the run with the given ID
+func (s *RunStore) Get(id string) (models.Run, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, run := range s.runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return models.Run{}, ErrRunNotFound
+}
+
+// Compare returns the days on which runs a and b differ
+func (s *RunStore) Compare(a, b string) (models.RunComparison, error) {
+	runA, err := s.Get(a)
+	if err != nil {
+		return models.RunComparison{}, fmt.Errorf("run %s: %w", a, err)
+	}
+	runB, err := s.Get(b)
+	if err != nil {
+		return models.RunComparison{}, fmt.Errorf("run %s: %w", b, err)
+	}
+	return CompareRuns(runA, runB), nil
+}
+
+// CompareRuns lists every day whose P&L, trade count or volume differs
+// between runs a and b. Days only one run has are included.
+func CompareRuns(a, b models.Run) models.RunComparison {
+	comparison := models.RunComparison{
+		A:            a,
+		B:            b,
+		SameVersion:  a.CalculatorVersion == b.CalculatorVersion,
+		SameInputs:   a.InputsHash == b.InputsHash,
+		TotalPnLDiff: (b.TotalPnL - a.TotalPnL).Round(),
+		Days:         []models.RunDayDiff{},
+	}
+	comparison.A.Records, comparison.B.Records = nil, nil
+
+	daysA, daysB := recordsByDate(a.Records), recordsByDate(b.Records)
+	dates := make(map[string]bool)
+	for date := range daysA {
+		dates[date] = true
+	}
+	for date := range daysB {
+		dates[date] = true
+	}
+	comparison.DaysCompared = len(dates)
+
+	for date := range dates {
+		dayA, inA := daysA[date]
+		dayB, inB := daysB[date]
+		if inA && inB && sameNumber(float64(dayA.DailyPnL), float64(dayB.DailyPnL)) && dayA.TradeCount == dayB.TradeCount && sameNumber(float64(dayA.Volume), float64(dayB.Volume)) {
+			continue
+		}
+
+		diff := models.RunDayDiff{
+			Date:        date,
+			PnLDiff:     (dayB.DailyPnL - dayA.DailyPnL).Round(),
+			TradeCountA: dayA.TradeCount,
+			TradeCountB: dayB.TradeCount,
+			VolumeA:     dayA.Volume,
+			VolumeB:     dayB.Volume,
+		}
+		if pnl := dayA.DailyPnL; inA {
+			diff.PnLA = &pnl
+		}
+		if pnl := dayB.DailyPnL; inB {
+			diff.PnLB = &pnl
+		}
+		comparison.Days = append(comparison.Days, diff)
+	}
+	sort.Slice(comparison.Days, func(i, j int) bool { return comparison.Days[i].Date < comparison.Days[j].Date })
+
+	return comparison
+}
+
+// Prune deletes the runs created before cutoff and returns how many there
+// were; a dry run only counts them
+func (s *RunStore) Prune(cutoff time.Time, dryRun bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := make([]models.Run, 0, len(s.runs))
+	for _, run := range s.runs {
+		if !run.CreatedAt.Before(cutoff) {
+			kept = append(kept, run)
+		}
+	}
+	pruned := len(s.runs) - len(kept)
+	if dryRun || pruned == 0 {
+		return pruned, nil
+	}
+	s.runs = kept
+	return pruned, s.persist()
+}
+
+// persist writes all runs to the runs file; caller must hold s.mu
+func (s *RunStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	return writeJSONFile(s.path, s.runs)
+}
+
+// hashTrades returns the hex SHA-256 of every field of trades, in order
+func hashTrades(trades []models.Trade) string {
+	h := sha256.New()
+	for _, trade := range trades {
+		startPosition := ""
+		if trade.StartPosition != nil {
+			startPosition = strconv.FormatFloat(float64(*trade.StartPosition), 'g', -1, 64)
+		}
+		fmt.Fprintf(h, "%d|%s|%s|%s|%s|%s|%s|%s\n", trade.Time.UnixMilli(), trade.Coin, trade.Side,
+			strconv.FormatFloat(float64(trade.Price), 'g', -1, 64), strconv.FormatFloat(float64(trade.Size), 'g', -1, 64),
+			strconv.FormatFloat(float64(trade.Value), 'g', -1, 64), strconv.FormatFloat(float64(trade.Fee), 'g', -1, 64), startPosition)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordsByDate maps daily records by date
+func recordsByDate(records []models.DailyPnL) map[string]models.DailyPnL {
+	byDate := make(map[string]models.DailyPnL, len(records))
+	for _, record := range records {
+		byDate[record.Date] = record
+	}
+	return byDate
+}
+
+// sameNumber reports whether a and b are equal to within rounding error
+func sameNumber(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(a))
+}
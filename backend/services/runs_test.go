@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test recording, listing and comparing reconciliation runs
+func TestRunStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.json")
+	s, _ := NewRunStore(path)
+
+	day := time.Date(2025, 3, 10, 12, 0, 0, 0, time.Local)
+	trades := []models.Trade{
+		{Time: day, Coin: "ETH", Side: "B", Price: 100, Size: 1, Value: 100},
+		{Time: day.Add(time.Hour), Coin: "ETH", Side: "A", Price: 110, Size: 1, Value: 110},
+	}
+	coverage := models.TimeRange{Start: day.AddDate(0, 0, -1), End: day.AddDate(0, 0, 2)}
+	a := s.Record(testAddress, "", 3, coverage, trades, []models.DailyPnL{
+		{Date: "2025-03-10", TradeCount: 2, Volume: 210, DailyPnL: 10},
+		{Date: "2025-03-09", TradeCount: 1, Volume: 50, DailyPnL: -50},
+	}, -40)
+	b := s.Record(testAddress, "", 3, coverage, trades, []models.DailyPnL{
+		{Date: "2025-03-11", TradeCount: 1, Volume: 20, DailyPnL: 20},
+		{Date: "2025-03-10", TradeCount: 2, Volume: 210, DailyPnL: 10},
+	}, 30)
+
+	t.Run("should record the calculator version and inputs hash", func(t *testing.T) {
+		if a.CalculatorVersion != config.CalculatorVersion || a.InputsHash != b.InputsHash || len(a.InputsHash) != 64 {
+			t.Errorf("Unexpected runs %+v and %+v", a, b)
+		}
+		if hashTrades(trades[:1]) == a.InputsHash {
+			t.Error("Expected different trades to hash differently")
+		}
+	})
+
+	t.Run("should list runs newest first without records", func(t *testing.T) {
+		reloaded, err := NewRunStore(path)
+		if err != nil {
+			t.Fatalf("Failed to reload runs: %v", err)
+		}
+		runs := reloaded.List(testAddress)
+		if len(runs) != 2 || runs[0].ID != b.ID || runs[0].Records != nil {
+			t.Fatalf("Unexpected runs %+v", runs)
+		}
+		if len(reloaded.List("0x0000000000000000000000000000000000000001")) != 0 {
+			t.Error("Expected no runs of another address")
+		}
+	})
+
+	t.Run("should list the days that differ", func(t *testing.T) {
+		comparison, err := s.Compare(a.ID, b.ID)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !comparison.SameVersion || !comparison.SameInputs || comparison.DaysCompared != 3 || comparison.TotalPnLDiff != 70 {
+			t.Errorf("Unexpected comparison %+v", comparison)
+		}
+		if len(comparison.Days) != 2 {
+			t.Fatalf("Expected 2 differing days, got %+v", comparison.Days)
+		}
+		removed, added := comparison.Days[0], comparison.Days[1]
+		if removed.Date != "2025-03-09" || removed.PnLA == nil || removed.PnLB != nil || removed.PnLDiff != 50 {
+			t.Errorf("Unexpected diff for a day only run a has: %+v", removed)
+		}
+		if added.Date != "2025-03-11" || added.PnLA != nil || *added.PnLB != 20 || added.TradeCountB != 1 {
+			t.Errorf("Unexpected diff for a day only run b has: %+v", added)
+		}
+	})
+
+	t.Run("should reject unknown runs", func(t *testing.T) {
+		if _, err := s.Compare(a.ID, "missing"); !errors.Is(err, ErrRunNotFound) {
+			t.Errorf("Expected ErrRunNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should keep a limited history per address", func(t *testing.T) {
+		for i := 0; i < config.RunHistoryLimit; i++ {
+			s.Record(testAddress, "", 3, coverage, trades, nil, 0)
+		}
+		if runs := s.List(testAddress); len(runs) != config.RunHistoryLimit {
+			t.Errorf("Expected %d runs kept, got %d", config.RunHistoryLimit, len(runs))
+		}
+		if _, err := s.Get(a.ID); !errors.Is(err, ErrRunNotFound) {
+			t.Error("Expected the oldest run to be dropped")
+		}
+	})
+}
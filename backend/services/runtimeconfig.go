@@ -0,0 +1,387 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"log"
+	"net/mail"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidConfig is returned when the config file fails validation; the
+// previous configuration stays in effect
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Names of the recurring jobs whose schedules can be reloaded
+const (
+	jobLeaderboard = "leaderboard"
+	jobS3Export    = "s3Export"
+	jobEODClose    = "eodClose"
+	jobRetention   = "retention"
+	jobConnectors  = "connectors/" // Followed by the tenant ID
+)
+
+// RuntimeConfigManager applies the live-reloadable configuration: the upstream
+// rate limit, the schedules of recurring jobs, the symbol map, and each
+// tenant's alert settings, tracked addresses and connectors. The file is re-read when it
+// changes or on demand; a file that fails validation is rejected as a whole.
+type RuntimeConfigManager struct {
+	path      string // Optional; without a file the defaults are applied once
+	tenants   *TenantRegistry
+	scheduler *cron.Cron
+	secrets   Secrets // Credentials of connectors
+	current   models.RuntimeConfig
+	applied   bool
+	entries   map[string][]cron.EntryID     // key: job name
+	alerts    map[string]models.AlertConfig // key: tenant ID; alert settings from startup
+	tracked   map[string]map[string]bool    // key: tenant ID; addresses added from the file
+	modTime   time.Time                     // Modification time of the file when last read
+	mu        sync.Mutex                    // Serializes reloads
+}
+
+// NewRuntimeConfigManager creates a manager that schedules jobs on scheduler
+// for every tenant. Call Reload once to apply the initial configuration.
+func NewRuntimeConfigManager(path string, tenants *TenantRegistry, scheduler *cron.Cron) *RuntimeConfigManager {
+	m := &RuntimeConfigManager{
+		path:      path,
+		tenants:   tenants,
+		scheduler: scheduler,
+		entries:   make(map[string][]cron.EntryID),
+		alerts:    make(map[string]models.AlertConfig),
+		tracked:   make(map[string]map[string]bool),
+	}
+	for _, tenant := range tenants.Tenants() {
+		m.alerts[tenant.ID] = tenant.Closes.Alerts()
+		m.tracked[tenant.ID] = make(map[string]bool)
+	}
+	return m
+}
+
+// UseSecrets sets the provider connectors read their credentials from; by
+// default they are read from the environment
+func (m *RuntimeConfigManager) UseSecrets(secrets Secrets) {
+	m.secrets = secrets
+}
+
+// DefaultRuntimeConfig returns the configuration used for anything the config file leaves unset
+func DefaultRuntimeConfig() models.RuntimeConfig {
+	return models.RuntimeConfig{
+		RateLimitDelay: config.RateLimitDelay.String(),
+		Schedules: models.ScheduleConfig{
+			Leaderboard: config.LeaderboardSchedule,
+			S3Export:    config.S3ExportSchedule,
+			EODClose:    config.EODCloseTime,
+			Retention:   config.RetentionSchedule,
+		},
+	}
+}
+
+// Reload reads, validates and applies the config file, returning what changed.
+// Validation errors wrap ErrInvalidConfig.
+func (m *RuntimeConfigManager) Reload() (models.ConfigReload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cfg := DefaultRuntimeConfig()
+	if m.path != "" {
+		info, err := os.Stat(m.path)
+		if err != nil {
+			return models.ConfigReload{}, fmt.Errorf("failed to read config: %w", err)
+		}
+		data, err := os.ReadFile(m.path)
+		if err != nil {
+			return models.ConfigReload{}, fmt.Errorf("failed to read config: %w", err)
+		}
+		m.modTime = info.ModTime()
+
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return models.ConfigReload{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	}
+
+	if err := m.validate(&cfg); err != nil {
+		return models.ConfigReload{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	reload := models.ConfigReload{Path: m.path, Config: cfg, Changes: m.apply(cfg), ReloadedAt: time.Now()}
+	if len(reload.Changes) > 0 {
+		log.Printf("Applied config: %s", strings.Join(reload.Changes, "; "))
+	}
+	return reload, nil
+}
+
+// Watch reloads the config file whenever its modification time changes,
+// checking every interval; it never returns. Failed reloads are logged and
+// the previous configuration stays in effect.
+func (m *RuntimeConfigManager) Watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		info, err := os.Stat(m.path)
+		if err != nil {
+			log.Printf("Failed to check config file: %v", err)
+			continue
+		}
+
+		m.mu.Lock()
+		changed := !info.ModTime().Equal(m.modTime)
+		m.mu.Unlock()
+		if !changed {
+			continue
+		}
+
+		if _, err := m.Reload(); err != nil {
+			log.Printf("Config reload failed, keeping previous config: %v", err)
+		}
+	}
+}
+
+// validate checks every setting in cfg and normalizes tracked addresses
+func (m *RuntimeConfigManager) validate(cfg *models.RuntimeConfig) error {
+	delay, err := time.ParseDuration(cfg.RateLimitDelay)
+	if err != nil || delay < 0 {
+		return fmt.Errorf("rateLimitDelay %q must be a non-negative duration such as 300ms", cfg.RateLimitDelay)
+	}
+
+	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	for name, spec := range map[string]string{jobLeaderboard: cfg.Schedules.Leaderboard, jobS3Export: cfg.Schedules.S3Export, jobRetention: cfg.Schedules.Retention} {
+		if _, err := parser.Parse(spec); err != nil {
+			return fmt.Errorf("schedules.%s %q: %v", name, spec, err)
+		}
+	}
+	if _, err := DailyCronSpec(cfg.Schedules.EODClose); err != nil {
+		return fmt.Errorf("schedules.eodClose: %v", err)
+	}
+
+	if err := ValidateRetention(cfg.Retention); err != nil {
+		return fmt.Errorf("retention.%v", err)
+	}
+
+	if _, err := NewSymbolMap(cfg.Symbols); err != nil {
+		return fmt.Errorf("symbols: %v", err)
+	}
+
+	for id, tenantCfg := range cfg.Tenants {
+		if _, ok := m.tenants.Tenant(id); !ok {
+			return fmt.Errorf("unknown tenant %q", id)
+		}
+
+		if alerts := tenantCfg.Alerts; alerts != nil {
+			if u, err := url.Parse(alerts.EODWebhookURL); alerts.EODWebhookURL != "" && (err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "") {
+				return fmt.Errorf("tenants.%s.alerts.eodWebhookUrl must be an absolute http or https URL", id)
+			}
+			for _, to := range alerts.EODReportTo {
+				if _, err := mail.ParseAddress(to); err != nil {
+					return fmt.Errorf("tenants.%s.alerts.eodReportTo: invalid recipient %q", id, to)
+				}
+			}
+			if alerts.DataQuality != nil {
+				if err := validateDataQuality(*alerts.DataQuality); err != nil {
+					return fmt.Errorf("tenants.%s.alerts.dataQuality.%v", id, err)
+				}
+			}
+		}
+
+		seen := make(map[string]bool)
+		for i, tracked := range tenantCfg.Addresses {
+			address := strings.ToLower(strings.TrimSpace(tracked.Address))
+			if !addressPattern.MatchString(address) {
+				return fmt.Errorf("tenants.%s.addresses: %q is not a valid address", id, tracked.Address)
+			}
+			if seen[address] {
+				return fmt.Errorf("tenants.%s.addresses: %s is listed twice", id, address)
+			}
+			seen[address] = true
+			tenantCfg.Addresses[i] = models.TrackedAddress{Address: address, Label: strings.TrimSpace(tracked.Label)}
+		}
+
+		names := make(map[string]bool)
+		for i := range tenantCfg.Connectors {
+			connector := &tenantCfg.Connectors[i]
+			if err := ValidateConnector(connector); err != nil {
+				return fmt.Errorf("tenants.%s.connectors[%d]: %v", id, i, err)
+			}
+			if names[connector.Name] {
+				return fmt.Errorf("tenants.%s.connectors: %s is listed twice", id, connector.Name)
+			}
+			names[connector.Name] = true
+			if _, err := parser.Parse(connector.Schedule); err != nil {
+				return fmt.Errorf("tenants.%s.connectors.%s.schedule %q: %v", id, connector.Name, connector.Schedule, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// apply puts a validated configuration into effect and describes what changed
+func (m *RuntimeConfigManager) apply(cfg models.RuntimeConfig) []string {
+	changes := []string{}
+	previous := m.current
+	first := !m.applied
+
+	if first || cfg.RateLimitDelay != previous.RateLimitDelay {
+		delay, _ := time.ParseDuration(cfg.RateLimitDelay)
+		SetRateLimitDelay(delay)
+		changes = append(changes, fmt.Sprintf("rateLimitDelay %s", delay))
+	}
+
+	if first || cfg.Schedules.Leaderboard != previous.Schedules.Leaderboard {
+		m.schedule(jobLeaderboard, cfg.Schedules.Leaderboard, func(t *Tenant) func() { return t.Leaderboard.Refresh })
+		changes = append(changes, fmt.Sprintf("schedules.leaderboard %q", cfg.Schedules.Leaderboard))
+	}
+	if first || cfg.Schedules.S3Export != previous.Schedules.S3Export {
+		m.schedule(jobS3Export, cfg.Schedules.S3Export, func(t *Tenant) func() {
+			if t.S3Export == nil {
+				return nil
+			}
+			return t.S3Export.RunScheduled
+		})
+		changes = append(changes, fmt.Sprintf("schedules.s3Export %q", cfg.Schedules.S3Export))
+	}
+	if first || cfg.Schedules.EODClose != previous.Schedules.EODClose {
+		spec, _ := DailyCronSpec(cfg.Schedules.EODClose)
+		m.schedule(jobEODClose, spec, func(t *Tenant) func() { return t.Closes.RunScheduled })
+		changes = append(changes, fmt.Sprintf("schedules.eodClose %s", cfg.Schedules.EODClose))
+	}
+	if first || cfg.Retention != previous.Retention || cfg.Schedules.Retention != previous.Schedules.Retention {
+		for _, tenant := range m.tenants.Tenants() {
+			tenant.Retention.SetPolicy(cfg.Retention)
+		}
+		// Pruning is only scheduled once there is something to prune
+		enabled := cfg.Retention != models.RetentionPolicy{}
+		m.schedule(jobRetention, cfg.Schedules.Retention, func(t *Tenant) func() {
+			if !enabled {
+				return nil
+			}
+			return t.Retention.RunScheduled
+		})
+		if !first || enabled {
+			changes = append(changes, fmt.Sprintf("retention fills=%q audit=%q jobs=%q at %q", cfg.Retention.Fills, cfg.Retention.Audit, cfg.Retention.Jobs, cfg.Schedules.Retention))
+		}
+	}
+
+	if first || !reflect.DeepEqual(cfg.Symbols, previous.Symbols) {
+		// cfg was validated, so NewSymbolMap can't fail
+		symbolMap, _ := NewSymbolMap(cfg.Symbols)
+		SetSymbols(symbolMap)
+		changes = append(changes, fmt.Sprintf("symbols: %d mappings", len(cfg.Symbols)))
+	}
+
+	for _, tenant := range m.tenants.Tenants() {
+		tenantCfg := cfg.Tenants[tenant.ID]
+
+		alerts := m.alerts[tenant.ID]
+		if tenantCfg.Alerts != nil {
+			alerts = *tenantCfg.Alerts
+		}
+		if current := tenant.Closes.Alerts(); !reflect.DeepEqual(current, alerts) {
+			tenant.Closes.SetAlerts(alerts)
+			changes = append(changes, fmt.Sprintf("%s: alerts updated", tenant.ID))
+		}
+
+		changes = append(changes, m.trackAddresses(tenant, tenantCfg.Addresses)...)
+
+		if connectors := tenantCfg.Connectors; first || !reflect.DeepEqual(connectors, previous.Tenants[tenant.ID].Connectors) {
+			m.scheduleConnectors(tenant, connectors)
+			if !first || len(connectors) > 0 {
+				changes = append(changes, fmt.Sprintf("%s: %d connectors", tenant.ID, len(connectors)))
+			}
+		}
+	}
+
+	m.current = cfg
+	m.applied = true
+	return changes
+}
+
+// schedule replaces every tenant's entry for the named job with one on spec.
+// job returns the function to run for a tenant, or nil if the job doesn't apply to it.
+func (m *RuntimeConfigManager) schedule(name, spec string, job func(*Tenant) func()) {
+	for _, id := range m.entries[name] {
+		m.scheduler.Remove(id)
+	}
+	m.entries[name] = nil
+
+	for _, tenant := range m.tenants.Tenants() {
+		run := job(tenant)
+		if run == nil {
+			continue
+		}
+		// spec was validated, so AddFunc can't fail
+		id, _ := m.scheduler.AddFunc(spec, run)
+		m.entries[name] = append(m.entries[name], id)
+	}
+}
+
+// scheduleConnectors replaces a tenant's connector jobs with one per connector
+func (m *RuntimeConfigManager) scheduleConnectors(tenant *Tenant, connectors []models.ConnectorConfig) {
+	name := jobConnectors + tenant.ID
+	for _, id := range m.entries[name] {
+		m.scheduler.Remove(id)
+	}
+	m.entries[name] = nil
+
+	for _, cfg := range connectors {
+		// The schedule was validated, so AddFunc can't fail
+		id, _ := m.scheduler.AddFunc(cfg.Schedule, NewConnector(cfg, tenant, m.secrets).RunScheduled)
+		m.entries[name] = append(m.entries[name], id)
+	}
+}
+
+// trackAddresses makes a tenant's address book match the addresses listed for
+// it, leaving entries saved through the API alone
+func (m *RuntimeConfigManager) trackAddresses(tenant *Tenant, addresses []models.TrackedAddress) []string {
+	var changes []string
+	tracked := m.tracked[tenant.ID]
+
+	labels := make(map[string]string)
+	for _, entry := range tenant.AddressBook.List() {
+		labels[entry.Address] = entry.Label
+	}
+
+	listed := make(map[string]bool)
+	for _, entry := range addresses {
+		listed[entry.Address] = true
+		tracked[entry.Address] = true
+
+		if label, exists := labels[entry.Address]; exists && label == entry.Label {
+			continue
+		}
+		if _, err := tenant.AddressBook.Save(entry.Address, entry.Label); err != nil {
+			log.Printf("Failed to save tracked address %s for %s: %v", entry.Address, tenant.ID, err)
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%s: tracking %s", tenant.ID, entry.Address))
+	}
+
+	var removed []string
+	for address := range tracked {
+		if !listed[address] {
+			removed = append(removed, address)
+		}
+	}
+	sort.Strings(removed)
+	for _, address := range removed {
+		delete(tracked, address)
+		if _, err := tenant.AddressBook.Delete(address); err != nil {
+			log.Printf("Failed to remove tracked address %s for %s: %v", address, tenant.ID, err)
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%s: stopped tracking %s", tenant.ID, address))
+	}
+
+	return changes
+}
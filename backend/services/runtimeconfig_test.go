@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Test RuntimeConfigManager reload, validation and rollback
+func TestRuntimeConfigReload(t *testing.T) {
+	t.Cleanup(func() {
+		SetRateLimitDelay(config.RateLimitDelay)
+		SetSymbols(&SymbolMap{})
+	})
+
+	const tracked = "0x3333333333333333333333333333333333333333"
+
+	tenant, err := NewTenant(DefaultTenantConfig(), SharedServices{})
+	if err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	tenant.AddressBook.Save(testAddress, "Saved through the API")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+	write(`
+rateLimitDelay: 1s
+schedules:
+  eodClose: "01:15"
+tenants:
+  default:
+    alerts:
+      eodWebhookUrl: https://hooks.example.com/eod
+      eodReportTo: [ops@example.com]
+    addresses:
+      - address: "0x3333333333333333333333333333333333333333"
+        label: Desk
+symbols:
+  - canonical: PEPE
+    hyperliquid: kPEPE
+    multiplier: 1000
+`)
+
+	scheduler := cron.New()
+	m := NewRuntimeConfigManager(path, NewSingleTenantRegistry(tenant), scheduler)
+
+	t.Run("should apply the config file", func(t *testing.T) {
+		reload, err := m.Reload()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if RateLimitDelay() != time.Second {
+			t.Errorf("Expected rate limit delay 1s, got %s", RateLimitDelay())
+		}
+		if reload.Config.Schedules.Leaderboard != config.LeaderboardSchedule {
+			t.Errorf("Expected the default leaderboard schedule, got %q", reload.Config.Schedules.Leaderboard)
+		}
+		// Leaderboard and close; neither S3 export nor retention is configured
+		if entries := len(scheduler.Entries()); entries != 2 {
+			t.Errorf("Expected 2 scheduled jobs, got %d", entries)
+		}
+		if alerts := tenant.Closes.Alerts(); alerts.EODWebhookURL != "https://hooks.example.com/eod" || len(alerts.EODReportTo) != 1 {
+			t.Errorf("Expected alerts from the config file, got %+v", alerts)
+		}
+		if label := tenant.AddressBook.Label(tracked); label != "Desk" {
+			t.Errorf("Expected tracked address labelled Desk, got %q", label)
+		}
+		if coin := Symbols().Canonical("kPEPE"); coin != "PEPE" {
+			t.Errorf("Expected kPEPE to map to PEPE, got %q", coin)
+		}
+	})
+
+	t.Run("should keep the previous config if the file is invalid", func(t *testing.T) {
+		write(`
+rateLimitDelay: 50ms
+schedules:
+  leaderboard: "every ten minutes"
+`)
+		if _, err := m.Reload(); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("Expected ErrInvalidConfig, got %v", err)
+		}
+		if RateLimitDelay() != time.Second {
+			t.Errorf("Expected rate limit delay to stay 1s, got %s", RateLimitDelay())
+		}
+
+		write(`
+tenants:
+  other:
+    addresses: []
+`)
+		if _, err := m.Reload(); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Expected unknown tenant to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("should revert settings removed from the file", func(t *testing.T) {
+		write(`schedules: {eodClose: "02:00"}`)
+		reload, err := m.Reload()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(reload.Changes) == 0 {
+			t.Error("Expected changes to be reported")
+		}
+		if RateLimitDelay() != config.RateLimitDelay {
+			t.Errorf("Expected the default rate limit delay, got %s", RateLimitDelay())
+		}
+		if entries := len(scheduler.Entries()); entries != 2 {
+			t.Errorf("Expected rescheduling to replace jobs, got %d entries", entries)
+		}
+		if alerts := tenant.Closes.Alerts(); alerts.EODWebhookURL != "" || len(alerts.EODReportTo) != 0 {
+			t.Errorf("Expected startup alerts to be restored, got %+v", alerts)
+		}
+		if label := tenant.AddressBook.Label(tracked); label != "" {
+			t.Errorf("Expected tracked address to be removed, got label %q", label)
+		}
+		if coin := Symbols().Canonical("kPEPE"); coin != "kPEPE" {
+			t.Errorf("Expected the symbol map to be cleared, got %q", coin)
+		}
+		if label := tenant.AddressBook.Label(testAddress); label != "Saved through the API" {
+			t.Errorf("Expected address saved through the API to remain, got %q", label)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"fmt"
+	"hyperliquid-recon/models"
+	"log"
+	"sync"
+	"time"
+)
+
+// S3Exporter writes CSV or Parquet snapshots of cached trades and daily P&L to
+// an S3-compatible bucket, partitioned Hive-style by address and trade date:
+//
+//	<prefix>trades/address=<address>/date=<YYYY-MM-DD>/trades.<format>
+//	<prefix>daily_pnl/address=<address>/date=<YYYY-MM-DD>/daily_pnl.<format>
+//
+// Partitions whose content has not changed since the last export are skipped.
+// Without a bucket, the same layout is written to the data directory's exports
+// folder instead.
+type S3Exporter struct {
+	store        ObjectStore
+	reconService *ReconciliationService
+	prefix       string
+	format       string
+	digests      map[string]string // key: object key, value: SHA-256 of last uploaded content
+	mu           sync.Mutex
+}
+
+// ObjectStore is where exported partitions are written: an S3-compatible bucket
+// or a local directory
+type ObjectStore interface {
+	PutObject(key, contentType string, body []byte) error
+	Location() string // Reported as the export's bucket
+}
+
+func NewS3Exporter(reconService *ReconciliationService, store ObjectStore, prefix, format string) *S3Exporter {
+	return &S3Exporter{
+		store:        store,
+		reconService: reconService,
+		prefix:       prefix,
+		format:       format,
+		digests:      make(map[string]string),
+	}
+}
+
+// Export uploads the changed partitions for every cached address
+func (e *S3Exporter) Export() (models.S3ExportResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := models.S3ExportResult{Bucket: e.store.Location()}
+
+	for _, address := range e.reconService.CachedAddresses() {
+		trades, _ := e.reconService.CachedTrades(address)
+		records, _ := e.reconService.CachedDailyRecords(address)
+		// Attribute realized P&L over the whole history, not day by day
+		trades = AttributeRealizedPnL(trades)
+
+		tradesByDate := make(map[string][]models.Trade)
+		for _, trade := range trades {
+			date := trade.Time.Format("2006-01-02")
+			tradesByDate[date] = append(tradesByDate[date], trade)
+		}
+
+		for date, dayTrades := range tradesByDate {
+			body, err := EncodeTrades(e.format, dayTrades)
+			if err != nil {
+				return result, fmt.Errorf("failed to encode trades for %s on %s: %w", address, date, err)
+			}
+			key := fmt.Sprintf("%strades/address=%s/date=%s/trades.%s", e.prefix, address, date, e.format)
+			if err := e.upload(key, body, &result); err != nil {
+				return result, err
+			}
+		}
+
+		for _, record := range records {
+			body, err := EncodeDailyPnL(e.format, []models.DailyPnL{record})
+			if err != nil {
+				return result, fmt.Errorf("failed to encode daily P&L for %s on %s: %w", address, record.Date, err)
+			}
+			key := fmt.Sprintf("%sdaily_pnl/address=%s/date=%s/daily_pnl.%s", e.prefix, address, record.Date, e.format)
+			if err := e.upload(key, body, &result); err != nil {
+				return result, err
+			}
+		}
+
+		result.Addresses++
+	}
+
+	result.ExportedAt = time.Now()
+	return result, nil
+}
+
+// RunScheduled runs an export and logs the outcome; used as the cron job
+func (e *S3Exporter) RunScheduled() {
+	result, err := e.Export()
+	if err != nil {
+		log.Printf("Scheduled export failed: %v", err)
+		return
+	}
+	log.Printf("Export to %s: %d addresses, %d objects written, %d unchanged",
+		result.Bucket, result.Addresses, result.ObjectsWritten, result.ObjectsUnchanged)
+}
+
+// upload puts an object unless identical content was already uploaded to the same key
+func (e *S3Exporter) upload(key string, body []byte, result *models.S3ExportResult) error {
+	digest := sha256Hex(body)
+	if e.digests[key] == digest {
+		result.ObjectsUnchanged++
+		return nil
+	}
+
+	if err := e.store.PutObject(key, ContentType(e.format), body); err != nil {
+		return err
+	}
+
+	e.digests[key] = digest
+	result.ObjectsWritten++
+	return nil
+}
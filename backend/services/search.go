@@ -0,0 +1,294 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidQuery is returned for a trade search query that can't be parsed
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Orders search results can be sorted in
+const (
+	SearchSortTime     = "time"     // Newest first
+	SearchSortNotional = "notional" // Largest first
+)
+
+// searchOperators are the comparisons a filter can make, longest first so
+// ">=" isn't read as ">"
+var searchOperators = []string{">=", "<=", "!=", "=", ">", "<"}
+
+// searchNumbers reads the numeric fields filters can compare; ok is false
+// for a trade that doesn't record the field
+var searchNumbers = map[string]func(trade models.Trade) (float64, bool){
+	"px":       func(trade models.Trade) (float64, bool) { return float64(trade.Price), true },
+	"sz":       func(trade models.Trade) (float64, bool) { return float64(trade.Size), true },
+	"notional": func(trade models.Trade) (float64, bool) { return float64(trade.Value), true },
+	"fee":      func(trade models.Trade) (float64, bool) { return float64(trade.Fee), true },
+	"pnl": func(trade models.Trade) (float64, bool) {
+		if trade.RealizedPnL == nil {
+			return 0, false
+		}
+		return float64(*trade.RealizedPnL), true
+	},
+}
+
+// searchAliases are other names accepted for fields
+var searchAliases = map[string]string{"price": "px", "size": "sz", "value": "notional", "symbol": "coin"}
+
+// TradeQuery is a parsed trade search: filters that must all match, and
+// words that must each appear in the coin, address or label
+type TradeQuery struct {
+	filters []searchFilter
+	words   []string
+	from    time.Time // Earliest time a match can have; zero if unbounded
+	to      time.Time // Time every match is before; zero if unbounded
+}
+
+// searchFilter is one field comparison of a query
+type searchFilter struct {
+	field    string
+	operator string
+	text     string
+	number   float64
+}
+
+// ParseTradeQuery parses a search such as "coin=ETH AND notional>10000 AND
+// side=B". Terms are separated by spaces or AND. A term is a filter on coin,
+// side (B, A, buy or sell), px, sz, notional, fee, pnl (realized), date
+// (YYYY-MM-DD or YYYY-MM, on the configured day basis) or time (RFC 3339)
+// with =, !=, >, >=, < or <=, or a word to look for in the coin, address and
+// label. Text comparisons ignore case.
+func ParseTradeQuery(query string) (TradeQuery, error) {
+	var q TradeQuery
+	for _, term := range strings.Fields(query) {
+		if strings.EqualFold(term, "AND") {
+			continue
+		}
+		if strings.EqualFold(term, "OR") || strings.EqualFold(term, "NOT") {
+			return TradeQuery{}, fmt.Errorf("%w: only AND is supported; use != to exclude", ErrInvalidQuery)
+		}
+
+		operator := ""
+		index := -1
+		for _, op := range searchOperators {
+			if i := strings.Index(term, op); i >= 0 && (index < 0 || i < index) {
+				operator, index = op, i
+			}
+		}
+		if index < 0 {
+			q.words = append(q.words, strings.ToLower(term))
+			continue
+		}
+
+		field := strings.ToLower(term[:index])
+		if alias, ok := searchAliases[field]; ok {
+			field = alias
+		}
+		value := term[index+len(operator):]
+		if field == "" || value == "" {
+			return TradeQuery{}, fmt.Errorf("%w: %q must be a field, an operator and a value", ErrInvalidQuery, term)
+		}
+		if err := q.add(field, operator, value); err != nil {
+			return TradeQuery{}, fmt.Errorf("%w: %s", ErrInvalidQuery, err)
+		}
+	}
+	return q, nil
+}
+
+// add adds a filter to the query, or narrows its time range for date and time filters
+func (q *TradeQuery) add(field, operator, value string) error {
+	switch field {
+	case "coin":
+		if operator != "=" && operator != "!=" {
+			return fmt.Errorf("coin can only be compared with = or !=")
+		}
+		q.filters = append(q.filters, searchFilter{field: field, operator: operator, text: value})
+	case "side":
+		side := map[string]string{"b": "B", "buy": "B", "a": "A", "sell": "A"}[strings.ToLower(value)]
+		if side == "" || (operator != "=" && operator != "!=") {
+			return fmt.Errorf("side must be compared with = or != to B, A, buy or sell")
+		}
+		q.filters = append(q.filters, searchFilter{field: field, operator: operator, text: side})
+	case "date", "time":
+		start, end, err := searchPeriod(field, value)
+		if err != nil {
+			return err
+		}
+		switch operator {
+		case "=":
+			q.after(start)
+			q.before(end)
+		case ">":
+			q.after(end)
+		case ">=":
+			q.after(start)
+		case "<":
+			q.before(start)
+		case "<=":
+			q.before(end)
+		default:
+			return fmt.Errorf("%s can't be compared with !=", field)
+		}
+	default:
+		if _, ok := searchNumbers[field]; !ok {
+			return fmt.Errorf("unknown field %q (want coin, side, px, sz, notional, fee, pnl, date or time)", field)
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return fmt.Errorf("%s must be compared with a number, got %q", field, value)
+		}
+		q.filters = append(q.filters, searchFilter{field: field, operator: operator, number: number})
+	}
+	return nil
+}
+
+// searchPeriod returns the span a date or time value stands for: a day or
+// month for dates, a single millisecond for times
+func searchPeriod(field, value string) (time.Time, time.Time, error) {
+	if field == "time" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("time must be RFC 3339, got %q", value)
+		}
+		return t, t.Add(time.Millisecond), nil
+	}
+
+	location := dayLocation(models.DayBasis(config.DayBasis))
+	if day, err := time.ParseInLocation("2006-01-02", value, location); err == nil {
+		return day, day.AddDate(0, 0, 1), nil
+	}
+	if month, err := time.ParseInLocation("2006-01", value, location); err == nil {
+		return month, month.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("date must be YYYY-MM-DD or YYYY-MM, got %q", value)
+}
+
+// after moves the start of the query's time range to t if that narrows it
+func (q *TradeQuery) after(t time.Time) {
+	if q.from.IsZero() || t.After(q.from) {
+		q.from = t
+	}
+}
+
+// before moves the end of the query's time range to t if that narrows it
+func (q *TradeQuery) before(t time.Time) {
+	if q.to.IsZero() || t.Before(q.to) {
+		q.to = t
+	}
+}
+
+// matches reports whether a trade of address, with the coin under its
+// canonical name, passes every filter and contains every word
+func (q TradeQuery) matches(address, label string, trade models.Trade) bool {
+	for _, f := range q.filters {
+		var ok bool
+		switch f.field {
+		case "coin":
+			ok = strings.EqualFold(trade.Coin, f.text) == (f.operator == "=")
+		case "side":
+			ok = (trade.Side == f.text) == (f.operator == "=")
+		default:
+			value, known := searchNumbers[f.field](trade)
+			ok = known && compareNumber(value, f.operator, f.number)
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	for _, word := range q.words {
+		if !strings.Contains(strings.ToLower(trade.Coin), word) && !strings.Contains(address, word) &&
+			!strings.Contains(strings.ToLower(label), word) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareNumber applies a filter's operator
+func compareNumber(value float64, operator string, number float64) bool {
+	switch operator {
+	case "=":
+		return value == number
+	case "!=":
+		return value != number
+	case ">":
+		return value > number
+	case ">=":
+		return value >= number
+	case "<":
+		return value < number
+	default:
+		return value <= number
+	}
+}
+
+// SearchTrades finds the cached trades of addresses (all cached addresses if
+// none) that match q, sorted by sortBy, and returns up to limit of them.
+// Date and time filters are looked up in each cache's time order, so only the
+// trades inside the range are read.
+func (rs *ReconciliationService) SearchTrades(q TradeQuery, addresses []string, sortBy string, limit int) models.TradeSearch {
+	if len(addresses) == 0 {
+		addresses = rs.CachedAddresses()
+	}
+	result := models.TradeSearch{Sort: sortBy, Hits: []models.SearchHit{}}
+
+	for _, address := range addresses {
+		trades := rs.tradesBetween(address, q.from, q.to)
+		if len(trades) == 0 {
+			continue
+		}
+		canonicalize(trades)
+		label := rs.Label(address)
+		for _, trade := range trades {
+			if q.matches(address, label, trade) {
+				result.Hits = append(result.Hits, models.SearchHit{Address: address, Label: label, Trade: trade})
+			}
+		}
+	}
+
+	sort.SliceStable(result.Hits, func(i, j int) bool {
+		if sortBy == SearchSortNotional && result.Hits[i].Value != result.Hits[j].Value {
+			return result.Hits[i].Value > result.Hits[j].Value
+		}
+		return result.Hits[i].Time.After(result.Hits[j].Time)
+	})
+	result.Matches = len(result.Hits)
+	if limit > 0 && len(result.Hits) > limit {
+		result.Hits = result.Hits[:limit]
+		result.Truncated = true
+	}
+	return result
+}
+
+// tradesBetween returns a copy of the cached trades of address from from up
+// to to; a zero bound leaves that side open
+func (rs *ReconciliationService) tradesBetween(address string, from, to time.Time) []models.Trade {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	cache, exists := rs.accountCache[address]
+	if !exists {
+		return nil
+	}
+	trades := cache.trades
+	start, end := 0, len(trades)
+	if !from.IsZero() {
+		start = sort.Search(len(trades), func(i int) bool { return !trades[i].Time.Before(from) })
+	}
+	if !to.IsZero() {
+		end = sort.Search(len(trades), func(i int) bool { return !trades[i].Time.Before(to) })
+	}
+	if start >= end {
+		return nil
+	}
+	return append([]models.Trade(nil), trades[start:end]...)
+}
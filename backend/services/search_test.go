@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"testing"
+)
+
+// Test searching cached trades with filters and words
+func TestSearchTrades(t *testing.T) {
+	rs := NewReconciliationService()
+	rs.ImportTrades(testAddress, []models.Trade{
+		createTestTrade("2025-02-27T10:00:00Z", "ETH", "B", 3000, 5),
+		createTestTrade("2025-03-14T10:00:00Z", "ETH", "B", 2000, 10),
+		createTestTrade("2025-03-15T10:00:00Z", "ETH", "A", 2000, 8),
+		createTestTrade("2025-03-16T10:00:00Z", "BTC", "B", 80000, 1),
+	})
+
+	tests := []struct {
+		query    string
+		expected []string // Times of the hits, newest first
+	}{
+		{"coin=ETH AND notional>10000 AND side=B", []string{"2025-03-14T10:00:00Z", "2025-02-27T10:00:00Z"}},
+		{"coin=eth date=2025-03", []string{"2025-03-15T10:00:00Z", "2025-03-14T10:00:00Z"}},
+		{"date>=2025-03-15 AND coin!=BTC", []string{"2025-03-15T10:00:00Z"}},
+		{"time<2025-03-14T10:00:00Z", []string{"2025-02-27T10:00:00Z"}},
+		{"btc side=buy sz>=1", []string{"2025-03-16T10:00:00Z"}},
+		{"pnl>0", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			q, err := ParseTradeQuery(test.query)
+			if err != nil {
+				t.Fatalf("Expected a valid query, got %v", err)
+			}
+			result := rs.SearchTrades(q, nil, SearchSortTime, 0)
+			var times []string
+			for _, hit := range result.Hits {
+				times = append(times, hit.Time.UTC().Format("2006-01-02T15:04:05Z"))
+			}
+			if len(times) != len(test.expected) || result.Matches != len(test.expected) {
+				t.Fatalf("Expected %v, got %v", test.expected, times)
+			}
+			for i := range times {
+				if times[i] != test.expected[i] {
+					t.Errorf("Expected %v, got %v", test.expected, times)
+				}
+			}
+		})
+	}
+
+	t.Run("should sort by notional and cap the hits", func(t *testing.T) {
+		q, _ := ParseTradeQuery("")
+		result := rs.SearchTrades(q, []string{testAddress}, SearchSortNotional, 2)
+		if result.Matches != 4 || !result.Truncated || len(result.Hits) != 2 || result.Hits[0].Coin != "BTC" || result.Hits[1].Value != 20000 {
+			t.Errorf("Expected the two largest of 4 trades, got %+v", result)
+		}
+	})
+
+	t.Run("should reject queries it can't parse", func(t *testing.T) {
+		for _, invalid := range []string{"colour=red", "px>abc", "coin>ETH", "side=long", "date=March", "coin=ETH OR coin=BTC", "=5"} {
+			if _, err := ParseTradeQuery(invalid); !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("Expected ErrInvalidQuery for %q, got %v", invalid, err)
+			}
+		}
+	})
+}
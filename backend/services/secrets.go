@@ -0,0 +1,173 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSecretNotFound is returned by a Secrets provider that has no value for a name
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Secrets provides sensitive values such as API keys, passwords and signing
+// secrets by name, so they don't have to be put in config files or arguments
+type Secrets interface {
+	Get(name string) (string, error)
+}
+
+// EnvSecrets reads secrets from environment variables. NAME_FILE may be set
+// instead of NAME to read the value from a file, e.g. a mounted secret.
+type EnvSecrets struct{}
+
+// Get returns the value of the environment variable name, or the contents of the file named by name_FILE
+func (EnvSecrets) Get(name string) (string, error) {
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		return readSecretFile(path)
+	}
+	return "", ErrSecretNotFound
+}
+
+// FileSecrets reads each secret from a file named after it in a directory, as
+// Docker and Kubernetes mount them (e.g. /run/secrets/RECON_SMTP_PASSWORD)
+type FileSecrets struct {
+	dir string
+}
+
+// NewFileSecrets creates a provider reading from dir
+func NewFileSecrets(dir string) *FileSecrets {
+	return &FileSecrets{dir: dir}
+}
+
+// Get returns the contents of the file dir/name
+func (s *FileSecrets) Get(name string) (string, error) {
+	value, err := readSecretFile(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrSecretNotFound
+	}
+	return value, err
+}
+
+// VaultSecrets reads secrets from one HashiCorp Vault KV version 2 secret,
+// whose keys are secret names. The secret is fetched once and cached.
+type VaultSecrets struct {
+	httpClient *http.Client
+	addr       string
+	token      string
+	path       string // API path of the secret, e.g. "secret/data/hyperliquid-recon"
+	values     map[string]string
+	mu         sync.Mutex
+}
+
+// NewVaultSecrets creates a provider reading the KV v2 secret at path
+func NewVaultSecrets(addr, token, path string, timeout time.Duration) *VaultSecrets {
+	return &VaultSecrets{
+		httpClient: &http.Client{Timeout: timeout},
+		addr:       strings.TrimRight(addr, "/"),
+		token:      token,
+		path:       strings.Trim(path, "/"),
+	}
+}
+
+// Get returns the value stored under name in the Vault secret
+func (s *VaultSecrets) Get(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.values == nil {
+		values, err := s.fetch()
+		if err != nil {
+			return "", err
+		}
+		s.values = values
+	}
+
+	value, ok := s.values[name]
+	if !ok || value == "" {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// fetch reads every key of the secret
+func (s *VaultSecrets) fetch() (map[string]string, error) {
+	req, err := http.NewRequest(http.MethodGet, s.addr+"/v1/"+s.path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets from Vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("vault returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("failed to decode Vault secret: %w", err)
+	}
+	if secret.Data.Data == nil {
+		return map[string]string{}, nil
+	}
+	return secret.Data.Data, nil
+}
+
+// NewSecretsProvider creates the secrets provider named by provider: "env"
+// (the default), "file" or "vault"
+func NewSecretsProvider(provider string) (Secrets, error) {
+	switch provider {
+	case "", "env":
+		return EnvSecrets{}, nil
+	case "file":
+		return NewFileSecrets(config.SecretsDir), nil
+	case "vault":
+		token, err := EnvSecrets{}.Get("VAULT_TOKEN")
+		if err != nil || config.VaultAddr == "" || config.VaultSecretPath == "" {
+			return nil, errors.New("the vault secrets provider needs VAULT_ADDR, VAULT_TOKEN (or VAULT_TOKEN_FILE) and RECON_VAULT_SECRET_PATH")
+		}
+		return NewVaultSecrets(config.VaultAddr, token, config.VaultSecretPath, config.VaultTimeout), nil
+	default:
+		return nil, fmt.Errorf("unknown secrets provider %q (want env, file or vault)", provider)
+	}
+}
+
+// LookupSecret returns the named secret, or def if the provider doesn't have it
+func LookupSecret(secrets Secrets, name, def string) (string, error) {
+	value, err := secrets.Get(name)
+	if errors.Is(err, ErrSecretNotFound) {
+		return def, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+	return value, nil
+}
+
+// readSecretFile returns a file's contents without surrounding whitespace
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
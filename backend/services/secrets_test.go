@@ -0,0 +1,89 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test the secrets providers
+func TestSecrets(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "RECON_SMTP_PASSWORD"), []byte("from-file\n"), 0o600)
+
+	t.Run("env should read NAME or NAME_FILE", func(t *testing.T) {
+		t.Setenv("RECON_TEST_SECRET", "from-env")
+		t.Setenv("RECON_TEST_FILE_SECRET_FILE", filepath.Join(dir, "RECON_SMTP_PASSWORD"))
+
+		if value, err := (EnvSecrets{}).Get("RECON_TEST_SECRET"); err != nil || value != "from-env" {
+			t.Errorf("Expected from-env, got %q (%v)", value, err)
+		}
+		if value, err := (EnvSecrets{}).Get("RECON_TEST_FILE_SECRET"); err != nil || value != "from-file" {
+			t.Errorf("Expected from-file, got %q (%v)", value, err)
+		}
+		if _, err := (EnvSecrets{}).Get("RECON_TEST_UNSET_SECRET"); !errors.Is(err, ErrSecretNotFound) {
+			t.Errorf("Expected ErrSecretNotFound, got %v", err)
+		}
+	})
+
+	t.Run("file should read a file per secret", func(t *testing.T) {
+		secrets := NewFileSecrets(dir)
+		if value, err := secrets.Get("RECON_SMTP_PASSWORD"); err != nil || value != "from-file" {
+			t.Errorf("Expected from-file, got %q (%v)", value, err)
+		}
+		if value, err := LookupSecret(secrets, "RECON_WEBHOOK_SECRET", "default"); err != nil || value != "default" {
+			t.Errorf("Expected fallback to default, got %q (%v)", value, err)
+		}
+	})
+
+	t.Run("vault should read a KV v2 secret once", func(t *testing.T) {
+		requests := 0
+		vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if r.URL.Path != "/v1/secret/data/recon" || r.Header.Get("X-Vault-Token") != "root" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"data": {"data": {"RECON_WEBHOOK_SECRET": "from-vault"}, "metadata": {"version": 3}}}`))
+		}))
+		defer vault.Close()
+
+		secrets := NewVaultSecrets(vault.URL+"/", "root", "/secret/data/recon", time.Second)
+		if value, err := secrets.Get("RECON_WEBHOOK_SECRET"); err != nil || value != "from-vault" {
+			t.Errorf("Expected from-vault, got %q (%v)", value, err)
+		}
+		if _, err := secrets.Get("RECON_SMTP_PASSWORD"); !errors.Is(err, ErrSecretNotFound) {
+			t.Errorf("Expected ErrSecretNotFound, got %v", err)
+		}
+		if requests != 1 {
+			t.Errorf("Expected the secret to be fetched once, got %d requests", requests)
+		}
+
+		denied := NewVaultSecrets(vault.URL, "wrong", "secret/data/recon", time.Second)
+		if _, err := LookupSecret(denied, "RECON_WEBHOOK_SECRET", "default"); err == nil {
+			t.Error("Expected a Vault error not to fall back to the default")
+		}
+	})
+
+	t.Run("tenant API keys should be read from secrets", func(t *testing.T) {
+		path := filepath.Join(dir, "tenants.json")
+		os.WriteFile(path, []byte(`[{"id": "desk", "apiKeySecrets": ["RECON_SMTP_PASSWORD"]}]`), 0o644)
+
+		configs, err := LoadTenantConfigs(path, NewFileSecrets(dir))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(configs[0].APIKeys) != 1 || configs[0].APIKeys[0] != "from-file" {
+			t.Errorf("Expected the API key from the secret, got %v", configs[0].APIKeys)
+		}
+
+		os.WriteFile(path, []byte(`[{"id": "desk", "apiKeySecrets": ["MISSING"]}]`), 0o644)
+		if _, err := LoadTenantConfigs(path, NewFileSecrets(dir)); err == nil {
+			t.Error("Expected a missing API key secret to be an error")
+		}
+	})
+}
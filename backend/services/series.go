@@ -0,0 +1,135 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"sort"
+	"time"
+)
+
+// seriesTotal is the coin name of the total line
+const seriesTotal = "total"
+
+// BuildPnLSeries returns the daily and cumulative P&L of each coin in trades,
+// or only of coin if it is set. P&L is counted as in the daily records (sell
+// value minus buy value) and dates run from the first to the last trade day.
+func BuildPnLSeries(address string, trades []models.Trade, coin string) models.PnLSeries {
+	series := models.PnLSeries{
+		Address:    address,
+		Dates:      []string{},
+		BucketDays: 1,
+		Coins:      []models.SeriesLine{},
+		Total:      models.SeriesLine{Coin: seriesTotal, Daily: []float64{}, Cumulative: []float64{}},
+	}
+
+	byCoin := make(map[string]map[string]float64) // coin -> date -> P&L
+	var first, last time.Time
+	for _, trade := range trades {
+		if coin != "" && trade.Coin != coin {
+			continue
+		}
+		if first.IsZero() {
+			first = trade.Time
+		}
+		last = trade.Time
+
+		daily, exists := byCoin[trade.Coin]
+		if !exists {
+			daily = make(map[string]float64)
+			byCoin[trade.Coin] = daily
+		}
+		switch trade.Side {
+		case "B":
+			daily[trade.Time.Format("2006-01-02")] -= float64(trade.Value)
+		case "A":
+			daily[trade.Time.Format("2006-01-02")] += float64(trade.Value)
+		}
+	}
+	if first.IsZero() {
+		return series
+	}
+
+	start := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, first.Location())
+	for day := start; !day.After(last); day = day.AddDate(0, 0, 1) {
+		series.Dates = append(series.Dates, day.Format("2006-01-02"))
+	}
+
+	coins := make([]string, 0, len(byCoin))
+	for c := range byCoin {
+		coins = append(coins, c)
+	}
+	sort.Strings(coins)
+
+	series.Total.Daily = make([]float64, len(series.Dates))
+	for _, c := range coins {
+		line := models.SeriesLine{Coin: c, Daily: make([]float64, len(series.Dates)), Cumulative: make([]float64, len(series.Dates))}
+		cumulative := 0.0
+		for i, date := range series.Dates {
+			line.Daily[i] = byCoin[c][date]
+			cumulative += line.Daily[i]
+			line.Cumulative[i] = cumulative
+			series.Total.Daily[i] += line.Daily[i]
+		}
+		series.Coins = append(series.Coins, line)
+	}
+
+	series.Total.Cumulative = make([]float64, len(series.Dates))
+	cumulative := 0.0
+	for i, daily := range series.Total.Daily {
+		cumulative += daily
+		series.Total.Cumulative[i] = cumulative
+	}
+
+	return series
+}
+
+// DownsampleSeries merges the days of series into at most points buckets of
+// equal length, the last of which may be shorter. Daily P&L and market volume
+// are summed over each bucket; cumulative P&L and open interest are the
+// bucket's last values. A series with no more than points dates is unchanged.
+func DownsampleSeries(series *models.PnLSeries, points int) {
+	if points < 1 || len(series.Dates) <= points {
+		return
+	}
+	size := (len(series.Dates) + points - 1) / points
+
+	dates := make([]string, 0, points)
+	for i := 0; i < len(series.Dates); i += size {
+		dates = append(dates, series.Dates[i])
+	}
+
+	downsample := func(line *models.SeriesLine) {
+		daily := make([]float64, len(dates))
+		cumulative := make([]float64, len(dates))
+		for i := range line.Daily {
+			daily[i/size] += line.Daily[i]
+			cumulative[i/size] = line.Cumulative[i]
+		}
+		line.Daily, line.Cumulative = daily, cumulative
+
+		if line.MarketVolume == nil {
+			return
+		}
+		volume := make([]*float64, len(dates))
+		openInterest := make([]*float64, len(dates))
+		for i := range line.MarketVolume {
+			if v := line.MarketVolume[i]; v != nil {
+				sum := *v
+				if volume[i/size] != nil {
+					sum += *volume[i/size]
+				}
+				volume[i/size] = &sum
+			}
+			if oi := line.OpenInterestUSD[i]; oi != nil {
+				openInterest[i/size] = oi
+			}
+		}
+		line.MarketVolume, line.OpenInterestUSD = volume, openInterest
+	}
+	for i := range series.Coins {
+		downsample(&series.Coins[i])
+	}
+	downsample(&series.Total)
+
+	series.Dates = dates
+	series.BucketDays = size
+}
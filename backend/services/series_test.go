@@ -0,0 +1,60 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test per-coin P&L series
+func TestBuildPnLSeries(t *testing.T) {
+	day := time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+	trades := []models.Trade{
+		{Time: day, Coin: "ETH", Side: "B", Price: 100, Size: 1, Value: 100},
+		{Time: day.Add(time.Hour), Coin: "BTC", Side: "A", Price: 50, Size: 1, Value: 50},
+		{Time: day.AddDate(0, 0, 2), Coin: "ETH", Side: "A", Price: 130, Size: 1, Value: 130},
+	}
+
+	t.Run("should fill every day for every coin", func(t *testing.T) {
+		series := BuildPnLSeries("0xabc", trades, "")
+
+		if !reflect.DeepEqual(series.Dates, []string{"2025-06-01", "2025-06-02", "2025-06-03"}) {
+			t.Fatalf("Unexpected dates %v", series.Dates)
+		}
+		if len(series.Coins) != 2 || series.Coins[0].Coin != "BTC" || series.Coins[1].Coin != "ETH" {
+			t.Fatalf("Expected BTC and ETH lines, got %+v", series.Coins)
+		}
+		if eth := series.Coins[1]; !reflect.DeepEqual(eth.Cumulative, []float64{-100, -100, 30}) {
+			t.Errorf("Unexpected ETH cumulative P&L %v", eth.Cumulative)
+		}
+		if !reflect.DeepEqual(series.Total.Daily, []float64{-50, 0, 130}) || series.Total.Cumulative[2] != 80 {
+			t.Errorf("Unexpected total %+v", series.Total)
+		}
+	})
+
+	t.Run("should limit the series to one coin", func(t *testing.T) {
+		series := BuildPnLSeries("0xabc", trades, "BTC")
+		if len(series.Coins) != 1 || len(series.Dates) != 1 || series.Total.Cumulative[0] != 50 {
+			t.Errorf("Expected a one-day BTC series, got %+v", series)
+		}
+	})
+	t.Run("should sum days into buckets when downsampled", func(t *testing.T) {
+		series := BuildPnLSeries("0xabc", trades, "")
+		volume, openInterest := 10.0, 500.0
+		series.Coins[1].MarketVolume = []*float64{&volume, &volume, nil}
+		series.Coins[1].OpenInterestUSD = []*float64{&openInterest, nil, nil}
+		DownsampleSeries(&series, 2)
+
+		if series.BucketDays != 2 || !reflect.DeepEqual(series.Dates, []string{"2025-06-01", "2025-06-03"}) {
+			t.Fatalf("Expected two-day buckets, got %d days %v", series.BucketDays, series.Dates)
+		}
+		if !reflect.DeepEqual(series.Total.Daily, []float64{-50, 130}) || !reflect.DeepEqual(series.Total.Cumulative, []float64{-50, 80}) {
+			t.Errorf("Unexpected downsampled total %+v", series.Total)
+		}
+		eth := series.Coins[1]
+		if *eth.MarketVolume[0] != 20 || eth.MarketVolume[1] != nil || *eth.OpenInterestUSD[0] != 500 {
+			t.Errorf("Unexpected downsampled market context %+v", eth)
+		}
+	})
+}
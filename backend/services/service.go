@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// Errors returned when installing or removing the system service
+var (
+	ErrServiceInstalled    = errors.New("the service is already installed; uninstall it first")
+	ErrServiceNotInstalled = errors.New("the service is not installed")
+	ErrServiceUnsupported  = errors.New("installing as a service needs systemd or Windows")
+)
+
+// serviceEnvPrefixes are the environment variables copied into the installed
+// service's environment, so settings made when installing apply after a reboot
+var serviceEnvPrefixes = []string{"RECON_", "AWS_", "OTEL_", "HTTPS_PROXY=", "HTTP_PROXY=", "NO_PROXY="}
+
+// serviceEnvironment returns the "NAME=value" settings from environ the
+// installed service is run with, sorted by name
+func serviceEnvironment(environ []string) []string {
+	var env []string
+	for _, setting := range environ {
+		for _, prefix := range serviceEnvPrefixes {
+			if strings.HasPrefix(setting, prefix) {
+				env = append(env, setting)
+				break
+			}
+		}
+	}
+	sort.Strings(env)
+	return env
+}
+
+// serviceCommand returns the arguments the installed service runs the binary with
+func serviceCommand(args []string) []string {
+	return append(append([]string{}, args...), "run")
+}
+
+// runUntilSignal calls serve, calling stop when the process is interrupted or
+// terminated so serve can return after shutting down gracefully
+func runUntilSignal(serve func() error, stop func()) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-signals:
+			stop()
+		case <-done:
+		}
+	}()
+
+	return serve()
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"hyperliquid-recon/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// systemdUnitDir is where the service's unit file is installed
+const systemdUnitDir = "/etc/systemd/system"
+
+// DefaultServiceDataDir is the data directory the installed service uses unless another is given
+func DefaultServiceDataDir() string {
+	return "/var/lib/" + config.ServiceName
+}
+
+// InstallService installs a systemd unit that runs this binary with args at
+// boot and restarts it if it fails, and starts it. The environment variables
+// the service reads are saved to an environment file only root can read.
+func InstallService(args []string) error {
+	unitFile, envFile := systemdPaths()
+	if _, err := os.Stat(unitFile); err == nil {
+		return ErrServiceInstalled
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(envFile, []byte(systemdEnvironmentFile(serviceEnvironment(os.Environ()))), 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", envFile, err)
+	}
+	if err := os.WriteFile(unitFile, []byte(systemdUnit(exe, args, envFile)), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", unitFile, err)
+	}
+
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	return systemctl("enable", "--now", config.ServiceName)
+}
+
+// UninstallService stops the service, disables it and removes its unit and
+// environment files. Its data directory is left in place.
+func UninstallService() error {
+	unitFile, envFile := systemdPaths()
+	if _, err := os.Stat(unitFile); os.IsNotExist(err) {
+		return ErrServiceNotInstalled
+	}
+
+	if err := systemctl("disable", "--now", config.ServiceName); err != nil {
+		return err
+	}
+	for _, path := range []string{unitFile, envFile} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return systemctl("daemon-reload")
+}
+
+// RunService calls serve until systemd stops the service, then calls stop so
+// serve can return after shutting down gracefully
+func RunService(serve func() error, stop func()) error {
+	return runUntilSignal(serve, stop)
+}
+
+// systemdPaths returns the paths of the service's unit and environment files
+func systemdPaths() (string, string) {
+	return filepath.Join(systemdUnitDir, config.ServiceName+".service"),
+		filepath.Join(systemdUnitDir, config.ServiceName+".env")
+}
+
+// systemdUnit returns the unit file running exe with args as the service
+func systemdUnit(exe string, args []string, envFile string) string {
+	command := []string{systemdQuote(exe)}
+	for _, arg := range serviceCommand(args) {
+		command = append(command, systemdQuote(arg))
+	}
+
+	return fmt.Sprintf(`[Unit]
+Description=%s
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+ExecStart=%s
+EnvironmentFile=-%s
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`, config.ServiceDisplayName, strings.Join(command, " "), envFile)
+}
+
+// systemdEnvironmentFile returns an environment file setting each "NAME=value" of env
+func systemdEnvironmentFile(env []string) string {
+	var b strings.Builder
+	for _, setting := range env {
+		name, value, _ := strings.Cut(setting, "=")
+		value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(value)
+		fmt.Fprintf(&b, "%s=\"%s\"\n", name, value)
+	}
+	return b.String()
+}
+
+// systemdQuote double-quotes s as a command line argument in a unit file,
+// escaping the characters systemd would otherwise interpret
+func systemdQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "%", "%%", "$", "$$").Replace(s)
+	return `"` + s + `"`
+}
+
+// systemctl runs systemctl with args, returning its output in the error if it fails
+func systemctl(args ...string) error {
+	output, err := exec.Command("systemctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
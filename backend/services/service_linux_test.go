@@ -0,0 +1,31 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test the systemd unit and environment file written by InstallService
+func TestSystemdUnit(t *testing.T) {
+	unit := systemdUnit("/opt/recon/hyperliquid-recon", []string{"-data-dir=/var/lib/my recon"}, "/etc/systemd/system/hyperliquid-recon.env")
+
+	for _, line := range []string{
+		`ExecStart="/opt/recon/hyperliquid-recon" "-data-dir=/var/lib/my recon" "run"`,
+		"EnvironmentFile=-/etc/systemd/system/hyperliquid-recon.env",
+		"Restart=on-failure",
+		"WantedBy=multi-user.target",
+	} {
+		if !strings.Contains(unit, line+"\n") {
+			t.Errorf("Expected unit to contain %q, got:\n%s", line, unit)
+		}
+	}
+
+	if got := systemdQuote(`50% "off" $HOME`); got != `"50%% \"off\" $$HOME"` {
+		t.Errorf("Unexpected quoting: %s", got)
+	}
+
+	env := systemdEnvironmentFile([]string{`RECON_SMTP_PASSWORD=p"a$s`, "RECON_EVENTS_FILE=/data/events.jsonl"})
+	if env != "RECON_SMTP_PASSWORD=\"p\\\"a\\$s\"\nRECON_EVENTS_FILE=\"/data/events.jsonl\"\n" {
+		t.Errorf("Unexpected environment file:\n%s", env)
+	}
+}
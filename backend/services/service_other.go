@@ -0,0 +1,24 @@
+//go:build !linux && !windows
+
+package services
+
+// DefaultServiceDataDir is the data directory the installed service uses unless another is given
+func DefaultServiceDataDir() string {
+	return ""
+}
+
+// InstallService is only supported with systemd and on Windows
+func InstallService(args []string) error {
+	return ErrServiceUnsupported
+}
+
+// UninstallService is only supported with systemd and on Windows
+func UninstallService() error {
+	return ErrServiceUnsupported
+}
+
+// RunService calls serve, calling stop when the process is interrupted or
+// terminated so serve can return after shutting down gracefully
+func RunService(serve func() error, stop func()) error {
+	return runUntilSignal(serve, stop)
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test which settings are copied into the installed service's environment
+func TestServiceEnvironment(t *testing.T) {
+	env := serviceEnvironment([]string{"PATH=/usr/bin", "RECON_EVENTS_FILE=/x", "AWS_ACCESS_KEY_ID=k", "HTTPS_PROXY=http://p", "HTTPS_PROXYISH=1", "HOME=/root"})
+	want := []string{"AWS_ACCESS_KEY_ID=k", "HTTPS_PROXY=http://p", "RECON_EVENTS_FILE=/x"}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("Expected %v, got %v", want, env)
+	}
+}
+
+// Test that runUntilSignal returns what serve does
+func TestRunUntilSignal(t *testing.T) {
+	stopped := errors.New("stopped")
+	stop := make(chan struct{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(stop)
+	}()
+
+	err := runUntilSignal(func() error {
+		<-stop
+		return stopped
+	}, func() {})
+	if err != stopped {
+		t.Errorf("Expected serve's error, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// serviceStopTimeout is how long uninstalling waits for the service to stop
+const serviceStopTimeout = 30 * time.Second
+
+// DefaultServiceDataDir is the data directory the installed service uses unless another is given
+func DefaultServiceDataDir() string {
+	return filepath.Join(os.Getenv("ProgramData"), config.ServiceName)
+}
+
+// InstallService registers a Windows service that runs this binary with args
+// when the machine starts and restarts it if it fails, and starts it. The
+// environment variables the service reads are saved with its registration.
+func InstallService(args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(config.ServiceName); err == nil {
+		s.Close()
+		return ErrServiceInstalled
+	}
+
+	s, err := m.CreateService(config.ServiceName, exe, mgr.Config{
+		DisplayName:      config.ServiceDisplayName,
+		Description:      "Fetches Hyperliquid fills and reconciles daily P&L",
+		StartType:        mgr.StartAutomatic,
+		DelayedAutoStart: true, // Wait for the network
+	}, serviceCommand(args)...)
+	if err != nil {
+		return fmt.Errorf("failed to create service: %w", err)
+	}
+	defer s.Close()
+
+	restart := mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: 5 * time.Second}
+	if err := s.SetRecoveryActions([]mgr.RecoveryAction{restart, restart, restart}, uint32(24*time.Hour/time.Second)); err != nil {
+		return fmt.Errorf("failed to set restart on failure: %w", err)
+	}
+
+	if env := serviceEnvironment(os.Environ()); len(env) > 0 {
+		key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Services\`+config.ServiceName, registry.SET_VALUE)
+		if err != nil {
+			return fmt.Errorf("failed to save service environment: %w", err)
+		}
+		defer key.Close()
+		if err := key.SetStringsValue("Environment", env); err != nil {
+			return fmt.Errorf("failed to save service environment: %w", err)
+		}
+	}
+
+	return s.Start()
+}
+
+// UninstallService stops the service and removes its registration. Its data
+// directory is left in place.
+func UninstallService() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(config.ServiceName)
+	if err != nil {
+		return ErrServiceNotInstalled
+	}
+	defer s.Close()
+
+	if status, err := s.Control(svc.Stop); err == nil {
+		deadline := time.Now().Add(serviceStopTimeout)
+		for status.State != svc.Stopped && time.Now().Before(deadline) {
+			time.Sleep(300 * time.Millisecond)
+			if status, err = s.Query(); err != nil {
+				break
+			}
+		}
+	}
+
+	return s.Delete()
+}
+
+// RunService calls serve, as a Windows service when started by the service
+// manager, and calls stop when the service or process is asked to stop so
+// serve can return after shutting down gracefully
+func RunService(serve func() error, stop func()) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+	if !isService {
+		return runUntilSignal(serve, stop)
+	}
+
+	service := &windowsService{serve: serve, stop: stop}
+	if err := svc.Run(config.ServiceName, service); err != nil {
+		return err
+	}
+	return service.err
+}
+
+// windowsService answers the service manager's requests while serve runs
+type windowsService struct {
+	serve func() error
+	stop  func()
+	err   error // Returned by serve
+}
+
+// Execute reports the service running until it is stopped or serve fails
+func (s *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	done := make(chan error, 1)
+	go func() { done <- s.serve() }()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case s.err = <-done:
+			// Exit with an error so the service manager restarts the service
+			if s.err != nil && !errors.Is(s.err, http.ErrServerClosed) {
+				return true, 1
+			}
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				s.stop()
+				s.err = <-done
+				return false, 0
+			}
+		}
+	}
+}
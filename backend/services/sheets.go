@@ -0,0 +1,250 @@
+package services
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// serviceAccount holds the fields of a Google service-account JSON key used for token exchange
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// SheetsExporter writes the daily P&L summary into a Google Sheet, authenticating
+// as a service account via the OAuth 2.0 JWT bearer flow
+type SheetsExporter struct {
+	httpClient    *http.Client
+	reconService  *ReconciliationService
+	account       serviceAccount
+	privateKey    *rsa.PrivateKey
+	spreadsheetID string
+	apiURL        string
+	accessToken   string
+	tokenExpiry   time.Time
+	mu            sync.Mutex // Serializes exports and guards the cached token
+}
+
+// NewSheetsExporter creates an exporter from a service-account key file
+func NewSheetsExporter(reconService *ReconciliationService, credentialsFile, spreadsheetID string) (*SheetsExporter, error) {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account credentials: %w", err)
+	}
+
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse service account credentials: %w", err)
+	}
+	if account.ClientEmail == "" || account.TokenURI == "" {
+		return nil, errors.New("service account credentials are missing client_email or token_uri")
+	}
+
+	privateKey, err := parseRSAPrivateKey(account.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SheetsExporter{
+		httpClient:    &http.Client{Timeout: config.SheetsTimeout},
+		reconService:  reconService,
+		account:       account,
+		privateKey:    privateKey,
+		spreadsheetID: spreadsheetID,
+		apiURL:        config.SheetsAPIURL,
+	}, nil
+}
+
+// Export replaces the contents of the configured sheet with the current daily P&L rows
+func (e *SheetsExporter) Export() (models.SheetsExportResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	token, err := e.token()
+	if err != nil {
+		return models.SheetsExportResult{}, err
+	}
+
+	summary := e.reconService.GetPnLSummary()
+	account := summary.Label
+	if account == "" {
+		account = summary.Address
+	}
+
+	rows := [][]interface{}{{"Account", "Date", "Trades", "Daily P&L", "Cumulative P&L"}}
+	for _, record := range summary.DailyRecords {
+		rows = append(rows, []interface{}{account, record.Date, record.TradeCount, record.DailyPnL, record.CumulativePnL})
+	}
+
+	sheetRange := fmt.Sprintf("'%s'", config.SheetsName)
+	valuesURL := fmt.Sprintf("%s/%s/values/%s", e.apiURL, url.PathEscape(e.spreadsheetID), url.PathEscape(sheetRange))
+
+	// Clear first so rows from a longer previous export don't linger below the new data
+	if err := e.call(http.MethodPost, valuesURL+":clear", token, struct{}{}); err != nil {
+		return models.SheetsExportResult{}, fmt.Errorf("failed to clear sheet: %w", err)
+	}
+
+	body := map[string]interface{}{
+		"range":          sheetRange,
+		"majorDimension": "ROWS",
+		"values":         rows,
+	}
+	if err := e.call(http.MethodPut, valuesURL+"?valueInputOption=RAW", token, body); err != nil {
+		return models.SheetsExportResult{}, fmt.Errorf("failed to write sheet: %w", err)
+	}
+
+	return models.SheetsExportResult{
+		SpreadsheetID: e.spreadsheetID,
+		Range:         sheetRange,
+		RowsWritten:   len(summary.DailyRecords),
+		ExportedAt:    time.Now(),
+	}, nil
+}
+
+// RunSchedule exports on every tick of interval; it never returns
+func (e *SheetsExporter) RunSchedule(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		result, err := e.Export()
+		if err != nil {
+			log.Printf("Scheduled Google Sheets export failed: %v", err)
+			continue
+		}
+		log.Printf("Exported %d rows to Google Sheet %s", result.RowsWritten, result.SpreadsheetID)
+	}
+}
+
+// call sends a JSON request to the Sheets API
+func (e *SheetsExporter) call(method, endpoint, token string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("sheets API returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+// token returns a cached access token, exchanging a freshly signed JWT when it is about to expire
+func (e *SheetsExporter) token() (string, error) {
+	if e.accessToken != "" && time.Now().Add(time.Minute).Before(e.tokenExpiry) {
+		return e.accessToken, nil
+	}
+
+	assertion, err := e.signJWT(time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	resp, err := e.httpClient.PostForm(e.account.TokenURI, form)
+	if err != nil {
+		return "", fmt.Errorf("failed to request access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("token endpoint returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("failed to decode access token: %w", err)
+	}
+
+	e.accessToken = tokenResp.AccessToken
+	e.tokenExpiry = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+
+	return e.accessToken, nil
+}
+
+// signJWT builds the RS256-signed assertion for the service-account token exchange
+func (e *SheetsExporter) signJWT(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   e.account.ClientEmail,
+		"scope": config.SheetsScope,
+		"aud":   e.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, e.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
+
+// parseRSAPrivateKey parses a PEM encoded PKCS#8 or PKCS#1 RSA private key
+func parseRSAPrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("service account private_key is not valid PEM")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account private key: %w", err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("service account private key is not an RSA key")
+	}
+	return key, nil
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestCredentials writes a service-account key file pointing at tokenURI
+func writeTestCredentials(t *testing.T, tokenURI string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	data, _ := json.Marshal(serviceAccount{
+		ClientEmail: "recon@example.iam.gserviceaccount.com",
+		PrivateKey:  string(pemKey),
+		TokenURI:    tokenURI,
+	})
+
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write credentials: %v", err)
+	}
+	return path
+}
+
+// Test SheetsExporter.Export
+func TestSheetsExport(t *testing.T) {
+	tokenRequests := 0
+	var written [][]interface{}
+	cleared := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			tokenRequests++
+			if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || strings.Count(r.FormValue("assertion"), ".") != 2 {
+				http.Error(w, "bad assertion", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"access_token":"token-1","expires_in":3600}`))
+		case r.Header.Get("Authorization") != "Bearer token-1":
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		case strings.HasSuffix(r.URL.Path, ":clear"):
+			cleared = true
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPut:
+			var body struct {
+				Values [][]interface{} `json:"values"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			written = body.Values
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	rs := NewReconciliationService()
+	rs.calculateDailyPnLFromTrades([]models.Trade{
+		createTestTrade("2025-01-01T10:00:00Z", "BTC", "B", 50000, 1),
+		createTestTrade("2025-01-01T11:00:00Z", "BTC", "A", 51000, 1),
+		createTestTrade("2025-01-02T10:00:00Z", "ETH", "B", 3000, 1),
+	})
+
+	exporter, err := NewSheetsExporter(rs, writeTestCredentials(t, server.URL+"/token"), "sheet-id")
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+	exporter.apiURL = server.URL
+
+	result, err := exporter.Export()
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if !cleared {
+		t.Errorf("Expected sheet to be cleared before writing")
+	}
+	if result.RowsWritten != 2 || len(written) != 3 {
+		t.Errorf("Expected header and 2 rows, got %d rows written", len(written))
+	}
+	if written[1][1] != "2025-01-02" {
+		t.Errorf("Expected newest day first, got %v", written[1][1])
+	}
+
+	if _, err := exporter.Export(); err != nil {
+		t.Fatalf("Second export failed: %v", err)
+	}
+	if tokenRequests != 1 {
+		t.Errorf("Expected access token to be reused, got %d token requests", tokenRequests)
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNoSnapshot is returned for an address no refresh has changed the trades of yet
+var ErrNoSnapshot = errors.New("no earlier snapshot of the address's trades; one is kept once a refresh changes them")
+
+// tradeSnapshot is the trades of an address as they were before a refresh changed them
+type tradeSnapshot struct {
+	Trades  []models.Trade `json:"trades"` // In time order
+	TakenAt time.Time      `json:"takenAt"`
+}
+
+// TradeSnapshotStore keeps the trades each address had before the last
+// refresh that changed them, persisted as JSON to path if one is set, so what
+// a refresh changed can be listed later, even after a restart. A refresh that
+// changes nothing keeps the snapshot, so it always shows the latest change.
+type TradeSnapshotStore struct {
+	snapshots map[string]tradeSnapshot // key: address
+	mu        sync.RWMutex
+	path      string
+}
+
+// NewTradeSnapshotStore creates a snapshot store, loading saved snapshots from path if it exists
+func NewTradeSnapshotStore(path string) (*TradeSnapshotStore, error) {
+	s := &TradeSnapshotStore{snapshots: make(map[string]tradeSnapshot), path: path}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := readStoreFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trade snapshots: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.snapshots); err != nil {
+		return nil, fmt.Errorf("failed to parse trade snapshots: %w", err)
+	}
+	return s, nil
+}
+
+// Record keeps before as the snapshot of address if a refresh at takenAt
+// changed its trades to after. It reports whether they changed.
+func (s *TradeSnapshotStore) Record(address string, before, after []models.Trade, takenAt time.Time) bool {
+	if hashTrades(before) == hashTrades(after) {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots[address] = tradeSnapshot{Trades: before, TakenAt: takenAt}
+	if err := s.persist(); err != nil {
+		log.Printf("Failed to save trade snapshots: %v", err)
+	}
+	return true
+}
+
+// Diff lists how current, the trades of address now in time order, differs
+// from its snapshot between start and end. A zero start or end leaves that
+// side of the window open.
+func (s *TradeSnapshotStore) Diff(address string, current []models.Trade, start, end time.Time) (models.TradeDiff, error) {
+	s.mu.RLock()
+	snapshot, exists := s.snapshots[address]
+	s.mu.RUnlock()
+	if !exists {
+		return models.TradeDiff{}, ErrNoSnapshot
+	}
+
+	before, after := tradesWithin(snapshot.Trades, start, end), tradesWithin(current, start, end)
+	diff := models.TradeDiff{
+		Address:      address,
+		SnapshotAt:   snapshot.TakenAt,
+		TradesBefore: len(before),
+		TradesAfter:  len(after),
+		Added:        []models.Trade{},
+		Removed:      []models.Trade{},
+		Changed:      []models.FillChange{},
+	}
+
+	remaining := make(map[fillKey]models.Trade, len(before))
+	for _, trade := range before {
+		remaining[keyOfFill(trade)] = trade
+	}
+	for _, trade := range after {
+		key := keyOfFill(trade)
+		old, existed := remaining[key]
+		delete(remaining, key)
+		switch {
+		case !existed:
+			diff.Added = append(diff.Added, trade)
+		case !sameFill(old, trade):
+			diff.Changed = append(diff.Changed, models.FillChange{Before: old, After: trade})
+		}
+	}
+	for _, trade := range before {
+		if _, removed := remaining[keyOfFill(trade)]; removed {
+			diff.Removed = append(diff.Removed, trade)
+		}
+	}
+	return diff, nil
+}
+
+// persist writes all snapshots to the snapshots file; caller must hold s.mu
+func (s *TradeSnapshotStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	return writeJSONFile(s.path, s.snapshots)
+}
+
+// tradesWithin returns the trades from start up to end of trades sorted by
+// time; a zero start or end leaves that side open
+func tradesWithin(trades []models.Trade, start, end time.Time) []models.Trade {
+	from := sort.Search(len(trades), func(i int) bool { return !trades[i].Time.Before(start) })
+	to := len(trades)
+	if !end.IsZero() {
+		to = sort.Search(len(trades), func(i int) bool { return !trades[i].Time.Before(end) })
+	}
+	return trades[from:max(from, to)]
+}
+
+// TradeDiff lists the fills of address added, removed or changed between
+// start and end by the last refresh that changed its trades; see
+// TradeSnapshotStore.Diff
+func (rs *ReconciliationService) TradeDiff(address string, start, end time.Time) (models.TradeDiff, error) {
+	if rs.snapshots == nil {
+		return models.TradeDiff{}, ErrNoSnapshot
+	}
+	current, exists := rs.CachedTrades(address)
+	if !exists {
+		return models.TradeDiff{}, ErrNotCached
+	}
+	diff, err := rs.snapshots.Diff(address, current, start, end)
+	diff.Label = rs.Label(address)
+	return diff, err
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test keeping trade snapshots and listing what a refresh changed
+func TestTradeSnapshotStore(t *testing.T) {
+	before := []models.Trade{
+		createTestTrade("2025-01-01T10:00:00Z", "BTC", "B", 50000, 0.5),
+		createTestTrade("2025-01-02T10:00:00Z", "ETH", "A", 3000, 2),
+		createTestTrade("2025-01-03T10:00:00Z", "SOL", "B", 150, 10),
+	}
+	after := []models.Trade{
+		before[0],
+		createTestTrade("2025-01-02T10:00:00Z", "ETH", "A", 3000, 2.5), // Size corrected
+		createTestTrade("2025-01-02T11:00:00Z", "ETH", "B", 3010, 1),   // Back-filled
+	}
+	refreshedAt := time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should list added, removed and changed fills", func(t *testing.T) {
+		store, _ := NewTradeSnapshotStore("")
+		if !store.Record(testAddress, before, after, refreshedAt) {
+			t.Fatal("Expected the changed trades to be recorded")
+		}
+
+		diff, err := store.Diff(testAddress, after, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("Diff failed: %v", err)
+		}
+		if diff.TradesBefore != 3 || diff.TradesAfter != 3 || !diff.SnapshotAt.Equal(refreshedAt) {
+			t.Errorf("Expected 3 trades on each side, got %+v", diff)
+		}
+		if len(diff.Added) != 1 || diff.Added[0].Side != "B" || diff.Added[0].Coin != "ETH" {
+			t.Errorf("Expected the back-filled ETH buy to be added, got %v", diff.Added)
+		}
+		if len(diff.Removed) != 1 || diff.Removed[0].Coin != "SOL" {
+			t.Errorf("Expected the SOL fill to be removed, got %v", diff.Removed)
+		}
+		if len(diff.Changed) != 1 || diff.Changed[0].Before.Size != 2 || diff.Changed[0].After.Size != 2.5 {
+			t.Errorf("Expected the ETH sell's size to change, got %v", diff.Changed)
+		}
+	})
+
+	t.Run("should only compare the window", func(t *testing.T) {
+		store, _ := NewTradeSnapshotStore("")
+		store.Record(testAddress, before, after, refreshedAt)
+
+		start := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+		diff, _ := store.Diff(testAddress, after, start, start.AddDate(0, 0, 1))
+		if diff.TradesBefore != 1 || diff.TradesAfter != 0 || len(diff.Removed) != 1 || len(diff.Added)+len(diff.Changed) != 0 {
+			t.Errorf("Expected only the SOL removal on the 3rd, got %+v", diff)
+		}
+	})
+
+	t.Run("should keep the last change through refreshes that change nothing", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snapshots.json")
+		store, _ := NewTradeSnapshotStore(path)
+		store.Record(testAddress, before, after, refreshedAt)
+		if store.Record(testAddress, after, after, refreshedAt.Add(time.Hour)) {
+			t.Error("Expected an unchanged refresh not to replace the snapshot")
+		}
+
+		reloaded, err := NewTradeSnapshotStore(path)
+		if err != nil {
+			t.Fatalf("Failed to reload snapshots: %v", err)
+		}
+		diff, err := reloaded.Diff(testAddress, after, time.Time{}, time.Time{})
+		if err != nil || !diff.SnapshotAt.Equal(refreshedAt) || len(diff.Changed) != 1 {
+			t.Errorf("Expected the saved snapshot of the first refresh, got %+v (%v)", diff, err)
+		}
+	})
+
+	t.Run("should report addresses without a snapshot", func(t *testing.T) {
+		store, _ := NewTradeSnapshotStore("")
+		if _, err := store.Diff(testAddress, after, time.Time{}, time.Time{}); !errors.Is(err, ErrNoSnapshot) {
+			t.Errorf("Expected ErrNoSnapshot, got %v", err)
+		}
+
+		rs := NewReconciliationService()
+		rs.UseTradeSnapshotStore(store)
+		if _, err := rs.TradeDiff(testAddress, time.Time{}, time.Time{}); !errors.Is(err, ErrNotCached) {
+			t.Errorf("Expected ErrNotCached for an address never refreshed, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"math"
+	"sort"
+)
+
+// BuildStatement builds the statement of address for the dates from to to
+// (YYYY-MM-DD, inclusive) from its daily records, which may cover any range.
+// The high-water mark starts at baseCapital and rises with equity on every
+// day before and during the period; the fee accrues at feeRate on equity
+// above the mark at the start of the period.
+func BuildStatement(address string, records []models.DailyPnL, baseCapital models.Money, from, to string, feeRate float64) models.Statement {
+	statement := models.Statement{
+		Address:     address,
+		From:        from,
+		To:          to,
+		BaseCapital: baseCapital,
+		FeeRate:     feeRate,
+		Lines:       []models.StatementLine{},
+	}
+
+	days := append([]models.DailyPnL(nil), records...)
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+
+	equity, highWaterMark := baseCapital, baseCapital
+	for _, day := range days {
+		if day.Date >= from {
+			break
+		}
+		equity = (equity + day.DailyPnL).Round()
+		highWaterMark = max(highWaterMark, equity)
+	}
+	statement.OpeningEquity, statement.OpeningHighWaterMark = equity, highWaterMark
+
+	for _, day := range days {
+		if day.Date < from || day.Date > to {
+			continue
+		}
+		equity = (equity + day.DailyPnL).Round()
+		highWaterMark = max(highWaterMark, equity)
+
+		line := models.StatementLine{
+			Date:          day.Date,
+			TradeCount:    day.TradeCount,
+			PnL:           day.DailyPnL,
+			Equity:        equity,
+			HighWaterMark: highWaterMark,
+			Drawdown:      (equity - highWaterMark).Round(),
+			AccruedFee:    accruedFee(equity, statement.OpeningHighWaterMark, feeRate),
+		}
+		if highWaterMark > 0 {
+			pct := float64(line.Drawdown / highWaterMark * 100)
+			line.DrawdownPct = &pct
+		}
+		if line.Drawdown < statement.MaxDrawdown {
+			statement.MaxDrawdown, statement.MaxDrawdownPct = line.Drawdown, line.DrawdownPct
+		}
+
+		statement.PeriodPnL = (statement.PeriodPnL + day.DailyPnL).Round()
+		statement.Lines = append(statement.Lines, line)
+	}
+
+	statement.ClosingEquity, statement.HighWaterMark = equity, highWaterMark
+	statement.PerformanceFee = accruedFee(equity, statement.OpeningHighWaterMark, feeRate)
+	statement.NetPnL = (statement.PeriodPnL - statement.PerformanceFee).Round()
+	return statement
+}
+
+// accruedFee is the performance fee at feeRate on equity above highWaterMark
+func accruedFee(equity, highWaterMark models.Money, feeRate float64) models.Money {
+	return models.Money(feeRate * math.Max(0, float64(equity-highWaterMark))).Round()
+}
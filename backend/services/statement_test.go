@@ -0,0 +1,67 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"testing"
+)
+
+// Test high-water mark, drawdown and performance fee accrual
+func TestBuildStatement(t *testing.T) {
+	// Newest first, as daily records are returned
+	records := []models.DailyPnL{
+		{Date: "2025-02-04", TradeCount: 1, DailyPnL: 3000},
+		{Date: "2025-02-03", TradeCount: 2, DailyPnL: -2000},
+		{Date: "2025-02-02", TradeCount: 1, DailyPnL: 500},
+		// Before the period: peaks at 11000, then closes at 10500
+		{Date: "2025-01-31", TradeCount: 1, DailyPnL: -500},
+		{Date: "2025-01-30", TradeCount: 1, DailyPnL: 1000},
+	}
+
+	statement := BuildStatement("0xabc", records, 10000, "2025-02-01", "2025-02-28", 0.2)
+
+	t.Run("should carry the high-water mark into the period", func(t *testing.T) {
+		if statement.OpeningEquity != 10500 || statement.OpeningHighWaterMark != 11000 {
+			t.Errorf("Expected opening equity 10500 under a mark of 11000, got %v and %v", statement.OpeningEquity, statement.OpeningHighWaterMark)
+		}
+		if statement.ClosingEquity != 12000 || statement.HighWaterMark != 12000 || statement.PeriodPnL != 1500 {
+			t.Errorf("Unexpected closing %+v", statement)
+		}
+	})
+
+	t.Run("should track drawdown from the high-water mark", func(t *testing.T) {
+		if len(statement.Lines) != 3 {
+			t.Fatalf("Expected 3 lines, got %d", len(statement.Lines))
+		}
+		low := statement.Lines[1]
+		if low.Equity != 9000 || low.Drawdown != -2000 || low.AccruedFee != 0 {
+			t.Errorf("Unexpected line %+v", low)
+		}
+		if statement.MaxDrawdown != -2000 || statement.MaxDrawdownPct == nil || *statement.MaxDrawdownPct != -2000.0/11000*100 {
+			t.Errorf("Expected a max drawdown of -2000, got %v", statement.MaxDrawdown)
+		}
+	})
+
+	t.Run("should only charge fees on gains above the mark", func(t *testing.T) {
+		if statement.PerformanceFee != 200 || statement.NetPnL != 1300 {
+			t.Errorf("Expected a fee of 200 on 1000 above the mark, got %v (net %v)", statement.PerformanceFee, statement.NetPnL)
+		}
+	})
+
+	t.Run("should charge nothing in a losing period", func(t *testing.T) {
+		losing := BuildStatement("0xabc", records, 10000, "2025-02-03", "2025-02-03", 0.2)
+		if losing.PerformanceFee != 0 || losing.NetPnL != -2000 {
+			t.Errorf("Expected no fee on a loss, got %v", losing.PerformanceFee)
+		}
+	})
+
+	t.Run("should round amounts as they are summed", func(t *testing.T) {
+		cents := []models.DailyPnL{
+			{Date: "2025-03-03", DailyPnL: 0.2},
+			{Date: "2025-03-02", DailyPnL: 0.1},
+		}
+		summed := BuildStatement("0xabc", cents, 0, "2025-03-01", "2025-03-31", 0.1)
+		if summed.PeriodPnL != 0.3 || summed.ClosingEquity != 0.3 || summed.PerformanceFee != 0.03 || summed.NetPnL != 0.27 {
+			t.Errorf("Expected 0.3 less a 0.03 fee, got %v less %v (net %v)", summed.PeriodPnL, summed.PerformanceFee, summed.NetPnL)
+		}
+	})
+}
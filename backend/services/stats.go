@@ -0,0 +1,94 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"runtime"
+	"sort"
+	"time"
+	"unsafe"
+)
+
+// startedAt is when the process started, for reporting uptime
+var startedAt = time.Now()
+
+// tradeBytes is the approximate memory held by one cached trade: the struct,
+// its start position and the typically short coin string
+const tradeBytes = int64(unsafe.Sizeof(models.Trade{})) + 8 + 8
+
+// CollectStats returns goroutine, heap and GC statistics along with what each tenant keeps in memory
+func CollectStats(tenants *TenantRegistry) models.RuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := models.RuntimeStats{
+		Goroutines: runtime.NumGoroutine(),
+		Heap: models.HeapStats{
+			Alloc:      mem.HeapAlloc,
+			InUse:      mem.HeapInuse,
+			Idle:       mem.HeapIdle,
+			Released:   mem.HeapReleased,
+			Sys:        mem.HeapSys,
+			Objects:    mem.HeapObjects,
+			TotalAlloc: mem.TotalAlloc,
+			ProcessSys: mem.Sys,
+		},
+		GC: models.GCStats{
+			NumGC:       mem.NumGC,
+			PauseTotal:  time.Duration(mem.PauseTotalNs).String(),
+			NextGCBytes: mem.NextGC,
+			CPUFraction: mem.GCCPUFraction,
+		},
+		Upstream:    UpstreamStats(),
+		Uptime:      time.Since(startedAt).Round(time.Second).String(),
+		CollectedAt: time.Now(),
+	}
+	if mem.NumGC > 0 {
+		stats.GC.LastPause = time.Duration(mem.PauseNs[(mem.NumGC+255)%256]).String()
+		lastGC := time.Unix(0, int64(mem.LastGC))
+		stats.GC.LastGC = &lastGC
+	}
+
+	for _, tenant := range tenants.Tenants() {
+		stats.Tenants = append(stats.Tenants, models.TenantStats{
+			ID:                tenant.ID,
+			Cache:             tenant.ReconService.CacheStats(),
+			Jobs:              tenant.Jobs.Len(),
+			WebhookDeliveries: len(tenant.Webhooks.Deliveries("")),
+			Breaks:            len(tenant.Breaks.List("", "", "")),
+			Closes:            len(tenant.Closes.Closes("", "")),
+			AddressBook:       len(tenant.AddressBook.List()),
+		})
+	}
+
+	return stats
+}
+
+// CacheStats returns the size of the trade cache, largest accounts first
+func (rs *ReconciliationService) CacheStats() models.CacheStats {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	stats := models.CacheStats{Addresses: len(rs.accountCache), Accounts: []models.AccountCacheStats{}}
+	for address, cache := range rs.accountCache {
+		account := models.AccountCacheStats{
+			Address:        address,
+			Trades:         len(cache.trades),
+			EstimatedBytes: int64(cap(cache.trades)) * tradeBytes,
+			CachedDays:     cache.cachedDays,
+			MissingRanges:  len(cache.missingRanges),
+		}
+		if !cache.lastFetchTime.IsZero() {
+			lastFetchTime := cache.lastFetchTime
+			account.LastFetchTime = &lastFetchTime
+		}
+
+		stats.Trades += account.Trades
+		stats.EstimatedBytes += account.EstimatedBytes
+		stats.Accounts = append(stats.Accounts, account)
+	}
+
+	sort.Slice(stats.Accounts, func(i, j int) bool {
+		return stats.Accounts[i].EstimatedBytes > stats.Accounts[j].EstimatedBytes
+	})
+	return stats
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"testing"
+	"time"
+)
+
+// Test cache statistics reported for memory diagnostics
+func TestCacheStats(t *testing.T) {
+	rs := NewReconciliationService()
+	now := time.Now()
+	small := "0x1111111111111111111111111111111111111111"
+	large := "0x2222222222222222222222222222222222222222"
+
+	rs.ImportTrades(small, []models.Trade{
+		{Time: now.Add(-time.Hour), Coin: "BTC", Side: "B", Price: 100, Size: 1, Value: 100},
+	})
+	rs.ImportTrades(large, []models.Trade{
+		{Time: now.Add(-3 * time.Hour), Coin: "ETH", Side: "B", Price: 10, Size: 1, Value: 10},
+		{Time: now.Add(-2 * time.Hour), Coin: "ETH", Side: "A", Price: 11, Size: 1, Value: 11},
+		{Time: now.Add(-time.Hour), Coin: "ETH", Side: "B", Price: 12, Size: 1, Value: 12},
+	})
+
+	stats := rs.CacheStats()
+	if stats.Addresses != 2 || stats.Trades != 4 {
+		t.Fatalf("Expected 2 addresses and 4 trades, got %d and %d", stats.Addresses, stats.Trades)
+	}
+	if stats.Accounts[0].Address != large || stats.Accounts[0].Trades != 3 {
+		t.Errorf("Expected the largest account first, got %+v", stats.Accounts[0])
+	}
+	if stats.EstimatedBytes < 4*tradeBytes {
+		t.Errorf("Expected at least %d estimated bytes, got %d", 4*tradeBytes, stats.EstimatedBytes)
+	}
+}
+
+// Test process statistics cover every tenant
+func TestCollectStats(t *testing.T) {
+	tenant, err := NewTenant(DefaultTenantConfig(), SharedServices{})
+	if err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+
+	stats := CollectStats(NewSingleTenantRegistry(tenant))
+	if stats.Goroutines == 0 || stats.Heap.Alloc == 0 {
+		t.Errorf("Expected goroutine and heap figures, got %+v", stats)
+	}
+	if len(stats.Tenants) != 1 || stats.Tenants[0].ID != DefaultTenantID {
+		t.Errorf("Expected stats for the default tenant, got %+v", stats.Tenants)
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+)
+
+// ErrStorageKey is returned for an encrypted store read without the key it was encrypted with
+var ErrStorageKey = errors.New("store is encrypted with another key, or RECON_STORAGE_KEY is not set")
+
+const (
+	encryptedFileMagic  = "RECONENC1\n" // Starts an encrypted store file
+	encryptedLinePrefix = "enc:"        // Starts an encrypted event log line
+)
+
+// storeFilePerm keeps stores, event logs and tapes private to the service's user
+const storeFilePerm = 0o600
+
+// storageCipher encrypts stores at rest; nil stores them as plain JSON
+var storageCipher atomic.Pointer[cipher.AEAD]
+
+// UseStorageKey encrypts stores with AES-256-GCM under key from now on. The
+// key is 32 bytes, as 64 hex digits or base64; an empty key turns encryption
+// off. Plain stores are still read, and encrypted when next saved.
+func UseStorageKey(key string) error {
+	if key == "" {
+		storageCipher.Store(nil)
+		return nil
+	}
+
+	raw, err := hex.DecodeString(key)
+	if err != nil {
+		raw, err = base64.StdEncoding.DecodeString(key)
+	}
+	if err != nil || len(raw) != 32 {
+		return errors.New("storage key must be 32 bytes, as 64 hex digits or base64")
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	storageCipher.Store(&aead)
+	return nil
+}
+
+// sealStorage encrypts data if a storage key is in use
+func sealStorage(data []byte) []byte {
+	aead := storageCipher.Load()
+	if aead == nil {
+		return data
+	}
+	nonce := make([]byte, (*aead).NonceSize())
+	rand.Read(nonce)
+	return (*aead).Seal(nonce, nonce, data, nil)
+}
+
+// openStorage decrypts data sealed by sealStorage
+func openStorage(sealed []byte) ([]byte, error) {
+	aead := storageCipher.Load()
+	if aead == nil || len(sealed) < (*aead).NonceSize() {
+		return nil, ErrStorageKey
+	}
+	size := (*aead).NonceSize()
+	data, err := (*aead).Open(nil, sealed[:size], sealed[size:], nil)
+	if err != nil {
+		return nil, ErrStorageKey
+	}
+	return data, nil
+}
+
+// readStoreFile reads a store saved by writeJSONFile, decrypting it if it is
+// encrypted. A plain store is encrypted in place if a storage key is in use.
+func readStoreFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if sealed, ok := bytes.CutPrefix(data, []byte(encryptedFileMagic)); ok {
+		data, err := openStorage(sealed)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return data, nil
+	}
+
+	if storageCipher.Load() != nil {
+		if err := writeStoreFile(path, data); err != nil {
+			return nil, err
+		}
+		log.Printf("Encrypted %s", path)
+	}
+	return data, nil
+}
+
+// writeJSONFile writes v as indented JSON to path, encrypted if a storage key
+// is in use. It writes to a temporary file first so a crash can't leave a
+// truncated file behind.
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeStoreFile(path, data)
+}
+
+// writeStoreFile replaces the file at path with data, encrypted if a storage key is in use
+func writeStoreFile(path string, data []byte) error {
+	return replaceFile(path, sealStoreData(data, false))
+}
+
+// replaceFile writes data to a temporary file readable by the service's user
+// only, then renames it over path
+func replaceFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, storeFilePerm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// sealStoreData encrypts the contents of a store file, or of an event log if
+// lines is set, as writeStoreFile and sealLine would if a storage key is in
+// use. Contents that are already encrypted are left as they are.
+func sealStoreData(data []byte, lines bool) []byte {
+	if storageCipher.Load() == nil {
+		return data
+	}
+	if !lines {
+		if bytes.HasPrefix(data, []byte(encryptedFileMagic)) {
+			return data
+		}
+		return append([]byte(encryptedFileMagic), sealStorage(data)...)
+	}
+
+	var sealed []byte
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		body := bytes.TrimSuffix(line, []byte("\n"))
+		if len(bytes.TrimSpace(body)) > 0 && !bytes.HasPrefix(body, []byte(encryptedLinePrefix)) {
+			line = append(sealLine(body), line[len(body):]...)
+		}
+		sealed = append(sealed, line...)
+	}
+	return sealed
+}
+
+// sealLine encodes one event log line, encrypted if a storage key is in use
+func sealLine(line []byte) []byte {
+	if storageCipher.Load() == nil {
+		return line
+	}
+	return []byte(encryptedLinePrefix + base64.StdEncoding.EncodeToString(sealStorage(line)))
+}
+
+// openLine decodes an event log line written by sealLine; it reports whether
+// the line was encrypted
+func openLine(line []byte) ([]byte, bool, error) {
+	encoded, ok := bytes.CutPrefix(line, []byte(encryptedLinePrefix))
+	if !ok {
+		return line, false, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return nil, true, err
+	}
+	data, err := openStorage(sealed)
+	return data, true, err
+}
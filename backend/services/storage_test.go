@@ -0,0 +1,156 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test encrypting stores and the event log at rest
+func TestStorageEncryption(t *testing.T) {
+	const key = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	defer UseStorageKey("")
+	dir := t.TempDir()
+
+	t.Run("should reject keys that aren't 32 bytes", func(t *testing.T) {
+		for _, invalid := range []string{"secret", key[:32], "AAEC"} {
+			if err := UseStorageKey(invalid); err == nil {
+				t.Errorf("Expected key %q to be rejected", invalid)
+			}
+		}
+	})
+
+	plainPath := filepath.Join(dir, "plain.json")
+	if err := writeJSONFile(plainPath, map[string]string{"note": "desk A"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := UseStorageKey(key); err != nil {
+		t.Fatalf("Expected a valid key, got %v", err)
+	}
+
+	t.Run("should encrypt plain stores when they are read", func(t *testing.T) {
+		data, err := readStoreFile(plainPath)
+		if err != nil || !strings.Contains(string(data), "desk A") {
+			t.Fatalf("Expected the plain store, got %q (%v)", data, err)
+		}
+		raw, _ := os.ReadFile(plainPath)
+		if !bytes.HasPrefix(raw, []byte(encryptedFileMagic)) || bytes.Contains(raw, []byte("desk A")) {
+			t.Errorf("Expected the store to be encrypted in place, got %q", raw)
+		}
+		if data, err := readStoreFile(plainPath); err != nil || !strings.Contains(string(data), "desk A") {
+			t.Errorf("Expected the encrypted store to read back, got %q (%v)", data, err)
+		}
+	})
+
+	eventsPath := filepath.Join(dir, "events.jsonl")
+	os.WriteFile(eventsPath, []byte(`{"seq":1,"type":"trades.reset","address":"`+testAddress+`"}`+"\n"), 0o644)
+	events, err := OpenEventStore(eventsPath)
+	if err != nil {
+		t.Fatalf("Failed to open event log: %v", err)
+	}
+	events.Append(&models.TradeEvent{Type: models.EventTradesImported, Address: testAddress})
+	events.Close()
+
+	t.Run("should encrypt the event log and replay it", func(t *testing.T) {
+		raw, _ := os.ReadFile(eventsPath)
+		for _, line := range strings.Split(strings.TrimSpace(string(raw)), "\n") {
+			if !strings.HasPrefix(line, encryptedLinePrefix) {
+				t.Errorf("Expected every line to be encrypted, got %q", line)
+			}
+		}
+		reopened, err := OpenEventStore(eventsPath)
+		if err != nil {
+			t.Fatalf("Failed to reopen event log: %v", err)
+		}
+		defer reopened.Close()
+		var seqs []int64
+		reopened.Replay(func(event models.TradeEvent) { seqs = append(seqs, event.Seq) })
+		if len(seqs) != 2 || seqs[1] != 2 {
+			t.Errorf("Expected events 1 and 2, got %v", seqs)
+		}
+	})
+
+	t.Run("should encrypt tapes, restored stores and staged snapshots", func(t *testing.T) {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`[{"coin":"BTC","px":"50000"}]`))
+		}))
+		defer upstream.Close()
+		tapePath := filepath.Join(dir, "tape.jsonl")
+		recording, err := NewRecordingTape(tapePath, nil)
+		if err != nil {
+			t.Fatalf("Failed to create tape: %v", err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, upstream.URL, strings.NewReader(`{"type":"userFills"}`))
+		if _, err := recording.RoundTrip(req); err != nil {
+			t.Fatalf("Expected the request to be recorded, got %v", err)
+		}
+		recording.Close()
+		raw, _ := os.ReadFile(tapePath)
+		if !bytes.HasPrefix(raw, []byte(encryptedLinePrefix)) || bytes.Contains(raw, []byte("BTC")) {
+			t.Errorf("Expected the tape to be encrypted, got %q", raw)
+		}
+		if tape, err := LoadTape(tapePath); err != nil || tape.Len() != 1 {
+			t.Errorf("Expected the encrypted tape to load, got %v", err)
+		}
+
+		source := models.TenantConfig{ID: DefaultTenantID, AddressBookFile: filepath.Join(dir, "source-addressbook.json"), EventsFile: filepath.Join(dir, "source-events.jsonl")}
+		os.WriteFile(source.AddressBookFile, []byte(`{"entries":["desk B"]}`), 0o644)
+		os.WriteFile(source.EventsFile, []byte("{\"seq\":1,\"address\":\"desk C\"}\n"), 0o644)
+		var snapshot bytes.Buffer
+		if _, err := WriteBackup(&snapshot, BackupPaths([]models.TenantConfig{source}), nil); err != nil {
+			t.Fatalf("Expected a snapshot, got %v", err)
+		}
+		backup, err := ReadBackup(bytes.NewReader(snapshot.Bytes()))
+		if err != nil {
+			t.Fatalf("Expected a valid snapshot, got %v", err)
+		}
+		target := models.TenantConfig{ID: DefaultTenantID, AddressBookFile: filepath.Join(dir, "addressbook.json"), EventsFile: filepath.Join(dir, "restored-events.jsonl")}
+		if _, _, err := backup.Restore(BackupPaths([]models.TenantConfig{target})); err != nil {
+			t.Fatalf("Expected the snapshot to be restored, got %v", err)
+		}
+		for _, path := range []string{target.AddressBookFile, target.EventsFile} {
+			raw, _ := os.ReadFile(path)
+			if bytes.Contains(raw, []byte("desk")) {
+				t.Errorf("Expected %s to be encrypted, got %q", filepath.Base(path), raw)
+			}
+			if info, err := os.Stat(path); err != nil || info.Mode().Perm() != storeFilePerm {
+				t.Errorf("Expected %s to be private, got %v (%v)", filepath.Base(path), info.Mode(), err)
+			}
+		}
+		if data, err := readStoreFile(target.AddressBookFile); err != nil || !strings.Contains(string(data), "desk B") {
+			t.Errorf("Expected the restored address book to read back, got %q (%v)", data, err)
+		}
+
+		dataDir := NewDataDir(dir)
+		if _, err := StageRestore(dataDir, snapshot.Bytes()); err != nil {
+			t.Fatalf("Expected the snapshot to be staged, got %v", err)
+		}
+		raw, _ = os.ReadFile(dataDir.PendingRestore())
+		if !bytes.HasPrefix(raw, []byte(encryptedFileMagic)) {
+			t.Errorf("Expected the staged snapshot to be encrypted")
+		}
+		if staged, err := ReadStagedRestore(dataDir); err != nil || len(staged.Manifest.Files) != 2 {
+			t.Errorf("Expected the staged snapshot to read back, got %v", err)
+		}
+	})
+
+	t.Run("should refuse encrypted stores without their key", func(t *testing.T) {
+		UseStorageKey(strings.Repeat("ff", 32))
+		if _, err := readStoreFile(plainPath); !errors.Is(err, ErrStorageKey) {
+			t.Errorf("Expected ErrStorageKey for another key, got %v", err)
+		}
+		UseStorageKey("")
+		if _, err := readStoreFile(plainPath); !errors.Is(err, ErrStorageKey) {
+			t.Errorf("Expected ErrStorageKey without a key, got %v", err)
+		}
+		if _, err := OpenEventStore(eventsPath); !errors.Is(err, ErrStorageKey) {
+			t.Errorf("Expected ErrStorageKey for the event log, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"fmt"
+	"hyperliquid-recon/models"
+	"time"
+)
+
+// summarySnapshot is a published P&L summary. A snapshot is never modified once
+// published; refreshes build a new one and swap it in, so readers use the
+// current snapshot without taking any lock.
+type summarySnapshot struct {
+	dailyPnL      map[string]*models.DailyPnL // key: local date
+	dailyPnLUTC   map[string]*models.DailyPnL // key: UTC date
+	coverage      models.TimeRange            // Time window covered by the summary
+	missing       []models.TimeRange          // Missing ranges within the summary
+	positionGaps  []models.CoinDayGaps
+	accountValues []models.AccountValue // History of the address, if it derives capital from it
+	refreshedAt   time.Time
+	address       string // Address and day range of the summary
+	days          int
+}
+
+// snapshot returns the current summary
+func (rs *ReconciliationService) snapshot() *summarySnapshot {
+	return rs.summary.Load()
+}
+
+// publish replaces the current summary with a copy changed by update. update
+// may be called more than once if another summary is published concurrently.
+func (rs *ReconciliationService) publish(update func(*summarySnapshot)) {
+	for {
+		current := rs.summary.Load()
+		next := *current
+		update(&next)
+		if rs.summary.CompareAndSwap(current, &next) {
+			return
+		}
+	}
+}
+
+// ParseDayBasis parses a day basis, "local" or "utc"
+func ParseDayBasis(s string) (models.DayBasis, error) {
+	switch basis := models.DayBasis(s); basis {
+	case models.DayLocal, models.DayUTC:
+		return basis, nil
+	default:
+		return "", fmt.Errorf("unknown day basis %q (want local or utc)", s)
+	}
+}
+
+// dayLocation returns the time zone whose calendar days basis buckets by
+func dayLocation(basis models.DayBasis) *time.Location {
+	if basis == models.DayUTC {
+		return time.UTC
+	}
+	return time.Local
+}
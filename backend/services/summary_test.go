@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test that summaries can be read while a refresh is running
+func TestSummaryReadsDuringRefresh(t *testing.T) {
+	t.Run("should serve the previous summary while a refresh is fetching", func(t *testing.T) {
+		release := make(chan struct{})
+		fetching := make(chan struct{}, 1)
+		var blocked atomic.Bool
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if blocked.Load() {
+				select {
+				case fetching <- struct{}{}:
+				default:
+				}
+				<-release
+			}
+			json.NewEncoder(w).Encode([]FillResponse{{Time: time.Now().Add(-time.Minute).UnixMilli(), Coin: "ETH", Side: "B", Price: "100", Size: "1"}})
+		}))
+		defer server.Close()
+
+		rs := NewReconciliationService()
+		rs.hlClient.apiURL = server.URL
+		if err := rs.FetchAndReconcile(context.Background(), testAddress, 1); err != nil {
+			t.Fatalf("Refresh failed: %v", err)
+		}
+		before := rs.GetPnLSummary()
+
+		blocked.Store(true)
+		done := make(chan error)
+		go func() { done <- rs.FetchAndReconcile(context.Background(), testAddress, 1) }()
+		<-fetching
+
+		read := make(chan struct{})
+		go func() {
+			if summary := rs.GetPnLSummary(); !summary.LastRefreshedAt.Equal(*before.LastRefreshedAt) {
+				t.Errorf("Expected the previous summary during the refresh")
+			}
+			close(read)
+		}()
+		select {
+		case <-read:
+		case <-time.After(time.Second):
+			t.Fatalf("Summary read blocked on the refresh")
+		}
+
+		close(release)
+		if err := <-done; err != nil {
+			t.Fatalf("Refresh failed: %v", err)
+		}
+		if after := rs.GetPnLSummary(); !after.LastRefreshedAt.After(*before.LastRefreshedAt) {
+			t.Errorf("Expected the new summary after the refresh")
+		}
+	})
+
+	t.Run("should keep concurrent partial updates", func(t *testing.T) {
+		rs := NewReconciliationService()
+		now := time.Now()
+
+		done := make(chan struct{})
+		go func() {
+			for i := 0; i < 100; i++ {
+				rs.publish(func(s *summarySnapshot) { s.days = i })
+			}
+			close(done)
+		}()
+		for i := 0; i < 100; i++ {
+			rs.publish(func(s *summarySnapshot) { s.refreshedAt = now })
+		}
+		<-done
+
+		if current := rs.snapshot(); current.days != 99 || !current.refreshedAt.Equal(now) {
+			t.Errorf("Expected both updates to be kept, got days %d refreshed %v", current.days, current.refreshedAt)
+		}
+	})
+}
+
+// Test bucketing daily P&L by local and UTC day
+func TestDayBasis(t *testing.T) {
+	rs := NewReconciliationService()
+	// 23:30 UTC is the next day ten hours east
+	late := time.Date(2025, 1, 27, 23, 30, 0, 0, time.UTC)
+	trades := []models.Trade{
+		{Time: late, Coin: "BTC", Side: "B", Price: 100, Size: 1, Value: 100},
+		{Time: late.Add(time.Hour), Coin: "BTC", Side: "A", Price: 110, Size: 1, Value: 110},
+	}
+
+	t.Run("should bucket trades by their date in the given zone", func(t *testing.T) {
+		utc := rs.buildDailyPnLIn(trades, time.UTC)
+		east := rs.buildDailyPnLIn(trades, time.FixedZone("UTC+10", 10*60*60))
+
+		if len(utc) != 2 || utc["2025-01-27"].DailyPnL != -100 || utc["2025-01-28"].DailyPnL != 110 {
+			t.Errorf("Expected one trade on each UTC day, got %v", utc)
+		}
+		if len(east) != 1 || east["2025-01-28"].DailyPnL != 10 {
+			t.Errorf("Expected both trades on 2025-01-28 ten hours east, got %v", east)
+		}
+	})
+
+	t.Run("should serve either calendar from the same summary", func(t *testing.T) {
+		rs.calculateDailyPnLFromTrades(trades)
+
+		summary := rs.GetPnLSummaryByDay(models.DayUTC)
+		if summary.DayBasis != models.DayUTC || len(summary.DailyRecords) != 2 || summary.DailyRecords[0].Date != "2025-01-28" {
+			t.Errorf("Expected 2 UTC days, got %s %v", summary.DayBasis, summary.DailyRecords)
+		}
+		if summary := rs.GetPnLSummaryByDay(models.DayLocal); summary.DayBasis != models.DayLocal || summary.TotalPnL != 10 {
+			t.Errorf("Expected the local calendar with the same total, got %s %v", summary.DayBasis, summary.TotalPnL)
+		}
+	})
+
+	t.Run("should reject unknown day bases", func(t *testing.T) {
+		if _, err := ParseDayBasis("exchange"); err == nil {
+			t.Errorf("Expected an error")
+		}
+		if basis, err := ParseDayBasis("utc"); err != nil || basis != models.DayUTC {
+			t.Errorf("Expected utc, got %q (%v)", basis, err)
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Errors returned when a symbol change can't be saved or found
+var (
+	ErrInvalidSymbolChange  = errors.New("invalid symbol change")
+	ErrSymbolChangeNotFound = errors.New("symbol change not found")
+)
+
+// symbolHistory is the symbol history in effect, shared by all tenants
+var symbolHistory atomic.Pointer[SymbolHistory]
+
+func init() {
+	// Without a file loading can't fail
+	history, _ := NewSymbolHistory("")
+	symbolHistory.Store(history)
+}
+
+// UseSymbolHistory replaces the symbol history in effect
+func UseSymbolHistory(history *SymbolHistory) {
+	symbolHistory.Store(history)
+}
+
+// CurrentSymbolHistory returns the symbol history in effect
+func CurrentSymbolHistory() *SymbolHistory {
+	return symbolHistory.Load()
+}
+
+// SymbolHistory keeps effective-dated renames and delistings, applied to
+// trades as they are grouped. Changes are persisted as JSON to path if one is set.
+type SymbolHistory struct {
+	changes map[string]*models.SymbolChange // key: ID
+	mu      sync.RWMutex
+	path    string
+}
+
+// NewSymbolHistory creates a symbol history, loading saved changes from path if it exists
+func NewSymbolHistory(path string) (*SymbolHistory, error) {
+	h := &SymbolHistory{changes: make(map[string]*models.SymbolChange), path: path}
+	if path == "" {
+		return h, nil
+	}
+
+	data, err := readStoreFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read symbol history: %w", err)
+	}
+
+	var changes []models.SymbolChange
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return nil, fmt.Errorf("failed to parse symbol history: %w", err)
+	}
+	for i := range changes {
+		h.changes[changes[i].ID] = &changes[i]
+	}
+
+	return h, nil
+}
+
+// Save adds change, or replaces the saved change with its ID if it has one.
+// Validation errors wrap ErrInvalidSymbolChange.
+func (h *SymbolHistory) Save(change models.SymbolChange) (models.SymbolChange, error) {
+	change.Symbol = strings.TrimSpace(change.Symbol)
+	change.GroupAs = strings.TrimSpace(change.GroupAs)
+	change.Note = strings.TrimSpace(change.Note)
+
+	switch {
+	case change.Symbol == "":
+		return models.SymbolChange{}, fmt.Errorf("%w: symbol is required", ErrInvalidSymbolChange)
+	case change.Effective.IsZero():
+		return models.SymbolChange{}, fmt.Errorf("%w: effective time is required", ErrInvalidSymbolChange)
+	}
+	switch change.Kind {
+	case models.SymbolRenamed:
+		if change.GroupAs == "" || change.GroupAs == change.Symbol {
+			return models.SymbolChange{}, fmt.Errorf("%w: a rename needs the new symbol in groupAs", ErrInvalidSymbolChange)
+		}
+	case models.SymbolDelisted:
+		if change.GroupAs == "" {
+			change.GroupAs = change.Symbol + " (delisted)"
+		}
+	default:
+		return models.SymbolChange{}, fmt.Errorf("%w: kind must be %s or %s", ErrInvalidSymbolChange, models.SymbolRenamed, models.SymbolDelisted)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if change.ID == "" {
+		change.ID = newID()
+	} else if _, exists := h.changes[change.ID]; !exists {
+		return models.SymbolChange{}, ErrSymbolChangeNotFound
+	}
+	for _, existing := range h.changes {
+		if existing.ID != change.ID && existing.Symbol == change.Symbol && existing.Effective.Equal(change.Effective) {
+			return models.SymbolChange{}, fmt.Errorf("%w: %s already has a change effective %s", ErrInvalidSymbolChange, change.Symbol, change.Effective.Format(time.RFC3339))
+		}
+	}
+
+	change.Effective = change.Effective.UTC()
+	change.UpdatedAt = time.Now()
+	h.changes[change.ID] = &change
+
+	return change, h.persist()
+}
+
+// Delete removes the change with id and reports whether it existed
+func (h *SymbolHistory) Delete(id string) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.changes[id]; !exists {
+		return false, nil
+	}
+	delete(h.changes, id)
+
+	return true, h.persist()
+}
+
+// List returns all changes sorted by symbol and effective time
+func (h *SymbolHistory) List() []models.SymbolChange {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	changes := make([]models.SymbolChange, 0, len(h.changes))
+	for _, change := range h.changes {
+		changes = append(changes, *change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Symbol != changes[j].Symbol {
+			return changes[i].Symbol < changes[j].Symbol
+		}
+		return changes[i].Effective.Before(changes[j].Effective)
+	})
+	return changes
+}
+
+// bySymbol returns each symbol's changes, earliest first
+func (h *SymbolHistory) bySymbol() map[string][]models.SymbolChange {
+	bySymbol := make(map[string][]models.SymbolChange)
+	for _, change := range h.List() {
+		bySymbol[change.Symbol] = append(bySymbol[change.Symbol], change)
+	}
+	return bySymbol
+}
+
+// Apply rewrites trades in place under the name each one is grouped by at its
+// time: a fill of a symbol before one of its changes takes the change's
+// GroupAs, and a renamed coin's changes are followed in turn
+func (h *SymbolHistory) Apply(trades []models.Trade) {
+	bySymbol := h.bySymbol()
+	if len(bySymbol) == 0 {
+		return
+	}
+
+	for i := range trades {
+		// Each change is followed at most once, so a cycle can't loop forever
+		for range len(bySymbol) {
+			change, found := changeBefore(bySymbol[trades[i].Coin], trades[i].Time)
+			if !found {
+				break
+			}
+			trades[i].Coin = change.GroupAs
+		}
+	}
+}
+
+// Revert undoes Apply, rewriting trades grouped under a change's name back to
+// the symbol the exchange reported them under, so exported history merges
+// with fetched fills. Fills under that name outside the period the change
+// covers are left alone.
+func (h *SymbolHistory) Revert(trades []models.Trade) {
+	bySymbol := h.bySymbol()
+	if len(bySymbol) == 0 {
+		return
+	}
+
+	byGroup := make(map[string][]models.SymbolChange)
+	for _, changes := range bySymbol {
+		for _, change := range changes {
+			byGroup[change.GroupAs] = append(byGroup[change.GroupAs], change)
+		}
+	}
+
+	for i := range trades {
+		for range len(bySymbol) {
+			reverted := false
+			for _, change := range byGroup[trades[i].Coin] {
+				if covering, found := changeBefore(bySymbol[change.Symbol], trades[i].Time); found && covering.ID == change.ID {
+					trades[i].Coin = change.Symbol
+					reverted = true
+					break
+				}
+			}
+			if !reverted {
+				break
+			}
+		}
+	}
+}
+
+// changeBefore returns the earliest of changes, sorted earliest first, that
+// takes effect after t
+func changeBefore(changes []models.SymbolChange, t time.Time) (models.SymbolChange, bool) {
+	for _, change := range changes {
+		if t.Before(change.Effective) {
+			return change, true
+		}
+	}
+	return models.SymbolChange{}, false
+}
+
+// persist writes all changes to the history file. Caller must hold h.mu.
+func (h *SymbolHistory) persist() error {
+	if h.path == "" {
+		return nil
+	}
+
+	changes := make([]models.SymbolChange, 0, len(h.changes))
+	for _, change := range h.changes {
+		changes = append(changes, *change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ID < changes[j].ID })
+
+	return writeJSONFile(h.path, changes)
+}
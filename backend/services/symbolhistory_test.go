@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test grouping fills by effective-dated renames and delistings
+func TestSymbolHistory(t *testing.T) {
+	renamed := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	delisted := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should group fills before each change under its name", func(t *testing.T) {
+		h, _ := NewSymbolHistory("")
+		h.Save(models.SymbolChange{Symbol: "OLD", Kind: models.SymbolRenamed, GroupAs: "NEW", Effective: renamed})
+		h.Save(models.SymbolChange{Symbol: "NEW", Kind: models.SymbolDelisted, Effective: delisted})
+
+		trades := []models.Trade{
+			{Time: renamed.Add(-time.Hour), Coin: "OLD"},   // Renamed, then delisted
+			{Time: renamed.Add(time.Hour), Coin: "NEW"},    // Delisted
+			{Time: delisted.Add(time.Hour), Coin: "NEW"},   // A new coin reusing the symbol
+			{Time: delisted.Add(time.Hour), Coin: "OLD"},   // A new coin reusing the old symbol
+			{Time: renamed.Add(-time.Hour), Coin: "OTHER"}, // Unchanged
+		}
+		h.Apply(trades)
+		for i, want := range []string{"NEW (delisted)", "NEW (delisted)", "NEW", "OLD", "OTHER"} {
+			if trades[i].Coin != want {
+				t.Errorf("Trade %d: expected %q, got %q", i, want, trades[i].Coin)
+			}
+		}
+
+		h.Revert(trades)
+		for i, want := range []string{"OLD", "NEW", "NEW", "OLD", "OTHER"} {
+			if trades[i].Coin != want {
+				t.Errorf("Trade %d: expected %q reverted, got %q", i, want, trades[i].Coin)
+			}
+		}
+	})
+
+	t.Run("should validate and persist changes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "symbol-history.json")
+		h, _ := NewSymbolHistory(path)
+
+		for _, change := range []models.SymbolChange{
+			{Kind: models.SymbolRenamed, GroupAs: "NEW", Effective: renamed},
+			{Symbol: "OLD", Kind: models.SymbolRenamed, Effective: renamed},
+			{Symbol: "OLD", Kind: "merge", GroupAs: "NEW", Effective: renamed},
+			{Symbol: "OLD", Kind: models.SymbolDelisted},
+		} {
+			if _, err := h.Save(change); !errors.Is(err, ErrInvalidSymbolChange) {
+				t.Errorf("%+v: expected ErrInvalidSymbolChange, got %v", change, err)
+			}
+		}
+		if _, err := h.Save(models.SymbolChange{ID: "missing", Symbol: "OLD", Kind: models.SymbolDelisted, Effective: delisted}); !errors.Is(err, ErrSymbolChangeNotFound) {
+			t.Errorf("Expected ErrSymbolChangeNotFound, got %v", err)
+		}
+
+		saved, err := h.Save(models.SymbolChange{Symbol: "OLD", Kind: models.SymbolRenamed, GroupAs: "NEW", Effective: renamed})
+		if err != nil {
+			t.Fatalf("Failed to save: %v", err)
+		}
+		if _, err := h.Save(models.SymbolChange{Symbol: "OLD", Kind: models.SymbolDelisted, Effective: renamed}); !errors.Is(err, ErrInvalidSymbolChange) {
+			t.Errorf("Expected a second change at the same time to be rejected, got %v", err)
+		}
+		saved.GroupAs = "RENAMED"
+		if _, err := h.Save(saved); err != nil {
+			t.Fatalf("Failed to update: %v", err)
+		}
+
+		loaded, err := NewSymbolHistory(path)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if changes := loaded.List(); len(changes) != 1 || changes[0].GroupAs != "RENAMED" {
+			t.Errorf("Expected the updated change to be loaded, got %+v", changes)
+		}
+
+		if deleted, _ := loaded.Delete(saved.ID); !deleted || len(loaded.List()) != 0 {
+			t.Error("Expected the change to be deleted")
+		}
+	})
+
+	t.Run("should apply to cached trades before the symbol map", func(t *testing.T) {
+		h, _ := NewSymbolHistory("")
+		h.Save(models.SymbolChange{Symbol: "kFROG", Kind: models.SymbolRenamed, GroupAs: "kPEPE", Effective: renamed})
+		UseSymbolHistory(h)
+		m, _ := NewSymbolMap([]models.SymbolMapping{{Canonical: "PEPE", Hyperliquid: "kPEPE", Multiplier: 1000}})
+		SetSymbols(m)
+		defer func() {
+			empty, _ := NewSymbolHistory("")
+			UseSymbolHistory(empty)
+			SetSymbols(&SymbolMap{})
+		}()
+
+		rs := NewReconciliationService()
+		rs.ImportTrades(testAddress, []models.Trade{
+			{Time: renamed.Add(-time.Hour), Coin: "kFROG", Side: "B", Price: 1, Size: 2, Value: 2},
+			{Time: renamed.Add(time.Hour), Coin: "kPEPE", Side: "B", Price: 1, Size: 2, Value: 2},
+		})
+
+		trades, _ := rs.CachedTrades(testAddress)
+		for _, trade := range trades {
+			if trade.Coin != "PEPE" || trade.Size != 2000 {
+				t.Errorf("Expected both fills grouped as PEPE, got %+v", trade)
+			}
+		}
+		if result := rs.ImportTrades(testAddress, trades); result.Duplicates != 2 {
+			t.Errorf("Expected the exported fills to be duplicates when re-imported, got %+v", result)
+		}
+	})
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"fmt"
+	"hyperliquid-recon/models"
+	"math"
+	"strings"
+	"sync/atomic"
+)
+
+// symbols is the symbol map in effect, shared by all tenants
+var symbols atomic.Pointer[SymbolMap]
+
+func init() {
+	symbols.Store(&SymbolMap{})
+}
+
+// SetSymbols replaces the symbol map in effect
+func SetSymbols(m *SymbolMap) {
+	symbols.Store(m)
+}
+
+// Symbols returns the symbol map in effect
+func Symbols() *SymbolMap {
+	return symbols.Load()
+}
+
+// canonicalize rewrites cached trades in place under the names they are grouped,
+// reported and exported by: the symbol history first, then the symbol map
+func canonicalize(trades []models.Trade) {
+	CurrentSymbolHistory().Apply(trades)
+	Symbols().ToCanonical(trades)
+}
+
+// toExchange undoes canonicalize, rewriting trades from any source in place
+// under the symbols Hyperliquid reported them under
+func toExchange(trades []models.Trade) {
+	Symbols().ToExchange(trades)
+	CurrentSymbolHistory().Revert(trades)
+}
+
+// SymbolMap translates coin symbols between Hyperliquid fills, other sources
+// and canonical names. Cached trades keep the exchange's symbols; they are
+// translated to canonical names as they are read, so a changed mapping applies
+// to history already fetched. The zero value maps every symbol to itself.
+type SymbolMap struct {
+	mappings    []models.SymbolMapping
+	bySymbol    map[string]*models.SymbolMapping // key: Hyperliquid symbol or alias
+	byCanonical map[string]*models.SymbolMapping
+}
+
+// NewSymbolMap validates mappings and returns a map of them. A symbol may only
+// be listed once, and no canonical name may be another coin's symbol, so a
+// symbol never translates more than once.
+func NewSymbolMap(mappings []models.SymbolMapping) (*SymbolMap, error) {
+	m := &SymbolMap{
+		mappings:    make([]models.SymbolMapping, 0, len(mappings)),
+		bySymbol:    make(map[string]*models.SymbolMapping),
+		byCanonical: make(map[string]*models.SymbolMapping),
+	}
+
+	for _, mapping := range mappings {
+		mapping.Canonical = strings.TrimSpace(mapping.Canonical)
+		mapping.Hyperliquid = strings.TrimSpace(mapping.Hyperliquid)
+		if mapping.Canonical == "" {
+			return nil, fmt.Errorf("a symbol mapping has no canonical name")
+		}
+		if _, exists := m.byCanonical[mapping.Canonical]; exists {
+			return nil, fmt.Errorf("%s is mapped twice", mapping.Canonical)
+		}
+		if mapping.Multiplier == 0 {
+			mapping.Multiplier = 1
+		}
+		if math.IsNaN(mapping.Multiplier) || math.IsInf(mapping.Multiplier, 0) || mapping.Multiplier < 0 {
+			return nil, fmt.Errorf("%s: multiplier must be positive", mapping.Canonical)
+		}
+		if mapping.Multiplier != 1 && (mapping.Hyperliquid == "" || mapping.Hyperliquid == mapping.Canonical) {
+			return nil, fmt.Errorf("%s: a multiplier needs the hyperliquid symbol it applies to", mapping.Canonical)
+		}
+		m.mappings = append(m.mappings, mapping)
+	}
+
+	for i := range m.mappings {
+		mapping := &m.mappings[i]
+		m.byCanonical[mapping.Canonical] = mapping
+
+		names := mapping.Aliases
+		if mapping.Hyperliquid != "" && mapping.Hyperliquid != mapping.Canonical {
+			names = append([]string{mapping.Hyperliquid}, names...)
+		}
+		for _, symbol := range names {
+			symbol = strings.TrimSpace(symbol)
+			if symbol == "" {
+				return nil, fmt.Errorf("%s: empty alias", mapping.Canonical)
+			}
+			if _, exists := m.bySymbol[symbol]; exists {
+				return nil, fmt.Errorf("%s is listed as a symbol twice", symbol)
+			}
+			m.bySymbol[symbol] = mapping
+		}
+	}
+	for symbol := range m.bySymbol {
+		if _, exists := m.byCanonical[symbol]; exists {
+			return nil, fmt.Errorf("%s is both a canonical name and another coin's symbol", symbol)
+		}
+	}
+
+	return m, nil
+}
+
+// Mappings returns the mappings in the order they were configured
+func (m *SymbolMap) Mappings() []models.SymbolMapping {
+	return append([]models.SymbolMapping(nil), m.mappings...)
+}
+
+// Canonical returns the canonical name of symbol, which is the symbol itself if
+// it isn't mapped
+func (m *SymbolMap) Canonical(symbol string) string {
+	if mapping, exists := m.bySymbol[symbol]; exists {
+		return mapping.Canonical
+	}
+	return symbol
+}
+
+// ToCanonical rewrites trades in place under their coins' canonical names,
+// scaling the sizes and prices of Hyperliquid symbols by their multipliers
+func (m *SymbolMap) ToCanonical(trades []models.Trade) {
+	if len(m.bySymbol) == 0 {
+		return
+	}
+	for i := range trades {
+		mapping, exists := m.bySymbol[trades[i].Coin]
+		if !exists {
+			continue
+		}
+		if trades[i].Coin == mapping.Hyperliquid {
+			scaleTrade(&trades[i], mapping.Multiplier)
+		}
+		trades[i].Coin = mapping.Canonical
+	}
+}
+
+// ToExchange rewrites trades from any source in place under the symbols
+// Hyperliquid fills use, so they merge with fetched fills. Aliases and
+// canonical names are in canonical units and are scaled back.
+func (m *SymbolMap) ToExchange(trades []models.Trade) {
+	if len(m.byCanonical) == 0 {
+		return
+	}
+	for i := range trades {
+		mapping, exists := m.bySymbol[trades[i].Coin]
+		if !exists {
+			mapping, exists = m.byCanonical[trades[i].Coin]
+		}
+		if !exists || trades[i].Coin == mapping.Hyperliquid {
+			continue
+		}
+		if mapping.Hyperliquid == "" {
+			trades[i].Coin = mapping.Canonical
+			continue
+		}
+		scaleTrade(&trades[i], 1/mapping.Multiplier)
+		trades[i].Coin = mapping.Hyperliquid
+	}
+}
+
+// scaleTrade expresses trade in units multiplier times smaller, keeping its value
+func scaleTrade(trade *models.Trade, multiplier float64) {
+	if multiplier == 1 {
+		return
+	}
+	trade.Size = models.Quantity(float64(trade.Size) * multiplier).Round()
+	trade.Price = models.Money(float64(trade.Price) / multiplier).Round()
+	if trade.StartPosition != nil {
+		startPosition := models.Position(float64(*trade.StartPosition) * multiplier).Round()
+		trade.StartPosition = &startPosition
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test translating coin symbols between sources and canonical names
+func TestSymbolMap(t *testing.T) {
+	mappings := []models.SymbolMapping{
+		{Canonical: "PEPE", Hyperliquid: "kPEPE", Multiplier: 1000, Aliases: []string{"1000PEPE"}},
+		{Canonical: "HYPE/USDC", Hyperliquid: "@107"},
+		{Canonical: "BTC", Aliases: []string{"XBT"}},
+	}
+
+	t.Run("should translate fills to canonical names and back", func(t *testing.T) {
+		m, err := NewSymbolMap(mappings)
+		if err != nil {
+			t.Fatalf("Expected a valid map, got %v", err)
+		}
+
+		startPosition := models.Position(2)
+		trades := []models.Trade{
+			{Coin: "kPEPE", Price: 0.01, Size: 5, Value: 0.05, StartPosition: &startPosition},
+			{Coin: "@107", Price: 20, Size: 1, Value: 20},
+			{Coin: "ETH", Price: 3000, Size: 1, Value: 3000},
+		}
+		m.ToCanonical(trades)
+		pepe := trades[0]
+		if pepe.Coin != "PEPE" || pepe.Size != 5000 || math.Abs(float64(pepe.Price)-0.00001) > 1e-15 || pepe.Value != 0.05 || *pepe.StartPosition != 2000 {
+			t.Errorf("Expected kPEPE scaled to PEPE units, got %+v", pepe)
+		}
+		if startPosition != 2 {
+			t.Error("Expected the start position not to be changed through the pointer")
+		}
+		if trades[1].Coin != "HYPE/USDC" || trades[1].Size != 1 || trades[2].Coin != "ETH" {
+			t.Errorf("Expected a plain rename and an unmapped coin, got %+v", trades[1:])
+		}
+
+		imported := append([]models.Trade{{Coin: "XBT", Size: 1}, {Coin: "1000PEPE", Size: 1000, Price: 0.00001}}, trades...)
+		m.ToExchange(imported)
+		for i, want := range []string{"BTC", "kPEPE", "kPEPE", "@107", "ETH"} {
+			if imported[i].Coin != want {
+				t.Errorf("Trade %d: expected %s, got %s", i, want, imported[i].Coin)
+			}
+		}
+		if imported[2].Size != 5 || imported[1].Size != 1 {
+			t.Errorf("Expected sizes scaled back to kPEPE units, got %v and %v", imported[1].Size, imported[2].Size)
+		}
+	})
+
+	t.Run("should reject ambiguous mappings", func(t *testing.T) {
+		for _, tc := range []struct {
+			mappings []models.SymbolMapping
+			want     string
+		}{
+			{[]models.SymbolMapping{{Hyperliquid: "kPEPE"}}, "no canonical name"},
+			{[]models.SymbolMapping{{Canonical: "PEPE", Multiplier: 1000}}, "needs the hyperliquid symbol"},
+			{[]models.SymbolMapping{{Canonical: "PEPE", Hyperliquid: "kPEPE", Multiplier: -1}}, "must be positive"},
+			{[]models.SymbolMapping{{Canonical: "A", Aliases: []string{"X"}}, {Canonical: "B", Aliases: []string{"X"}}}, "twice"},
+			{[]models.SymbolMapping{{Canonical: "A", Aliases: []string{"B"}}, {Canonical: "B"}}, "both a canonical name"},
+		} {
+			if _, err := NewSymbolMap(tc.mappings); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("%+v: expected an error containing %q, got %v", tc.mappings, tc.want, err)
+			}
+		}
+	})
+
+	t.Run("should group cached trades and merge imports under the configured names", func(t *testing.T) {
+		m, _ := NewSymbolMap(mappings)
+		SetSymbols(m)
+		defer SetSymbols(&SymbolMap{})
+
+		now := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+		rs := NewReconciliationService()
+		rs.ImportTrades(testAddress, []models.Trade{{Time: now, Coin: "kPEPE", Side: "B", Price: 0.01, Size: 5, Value: 0.05}})
+
+		// The same fill exported under its canonical name is a duplicate
+		exported, _ := rs.CachedTrades(testAddress)
+		if len(exported) != 1 || exported[0].Coin != "PEPE" || exported[0].Size != 5000 {
+			t.Fatalf("Expected the cached fill under its canonical name, got %+v", exported)
+		}
+		if result := rs.ImportTrades(testAddress, exported); result.Duplicates != 1 {
+			t.Errorf("Expected the re-imported fill to be a duplicate, got %+v", result)
+		}
+
+		cache, _ := rs.cached(testAddress)
+		if cache.trades[0].Coin != "kPEPE" {
+			t.Errorf("Expected the cache to keep the exchange's symbol, got %s", cache.trades[0].Coin)
+		}
+	})
+}
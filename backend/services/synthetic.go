@@ -0,0 +1,277 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"hyperliquid-recon/config"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// syntheticFeeRate is the taker fee charged on synthetic fills
+const syntheticFeeRate = 0.00035
+
+// syntheticBasePrices anchors the price paths of well-known coins so demo data
+// looks plausible; other coins get a price derived from their name
+var syntheticBasePrices = map[string]float64{
+	"BTC":  60000,
+	"ETH":  3000,
+	"SOL":  150,
+	"HYPE": 25,
+	"DOGE": 0.15,
+	"ARB":  0.8,
+}
+
+// SyntheticFills generates deterministic fills for any address, standing in for
+// the Hyperliquid API so the service can run offline. It answers
+// userFillsByTime requests as an http.RoundTripper, so the client's
+// pagination and rate limiting run unchanged.
+//
+// Fills are generated hour by hour from a seed derived from the address and
+// hour, so any window returns the same fills however it is split across
+// requests. Each coin's position moves from one hourly target to the next, so
+// startPosition is consistent from fill to fill.
+type SyntheticFills struct {
+	coins         []string
+	volatility    float64 // Daily volatility of the price paths, as a fraction
+	tradesPerHour float64 // Average fills per hour across all coins
+	seed          uint64
+}
+
+// NewSyntheticFills creates a generator for coins
+func NewSyntheticFills(coins []string, volatility, tradesPerHour float64, seed uint64) (*SyntheticFills, error) {
+	if len(coins) == 0 {
+		return nil, errors.New("synthetic data needs at least one coin")
+	}
+	if volatility <= 0 || volatility > 1 {
+		return nil, fmt.Errorf("synthetic volatility %v must be between 0 and 1", volatility)
+	}
+	if tradesPerHour <= 0 || tradesPerHour > float64(config.MaxTradesPerBatch) {
+		return nil, fmt.Errorf("synthetic trade rate %v must be between 0 and %d per hour", tradesPerHour, config.MaxTradesPerBatch)
+	}
+	return &SyntheticFills{coins: coins, volatility: volatility, tradesPerHour: tradesPerHour, seed: seed}, nil
+}
+
+// SyntheticFillsFromConfig creates the generator described by the RECON_FAKE_* settings
+func SyntheticFillsFromConfig() (*SyntheticFills, error) {
+	var coins []string
+	for _, coin := range strings.Split(config.FakeCoins, ",") {
+		if coin = strings.ToUpper(strings.TrimSpace(coin)); coin != "" {
+			coins = append(coins, coin)
+		}
+	}
+
+	volatility, err := strconv.ParseFloat(config.FakeVolatility, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RECON_FAKE_VOLATILITY %q", config.FakeVolatility)
+	}
+	tradesPerHour, err := strconv.ParseFloat(config.FakeTradesPerHour, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RECON_FAKE_TRADES_PER_HOUR %q", config.FakeTradesPerHour)
+	}
+	seed, err := strconv.ParseUint(config.FakeSeed, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RECON_FAKE_SEED %q", config.FakeSeed)
+	}
+
+	return NewSyntheticFills(coins, volatility, tradesPerHour, seed)
+}
+
+// RoundTrip answers a userFillsByTime request with up to a batch of synthetic fills
+func (g *SyntheticFills) RoundTrip(req *http.Request) (*http.Response, error) {
+	var request UserFillsRequest
+	if req.Body != nil {
+		defer req.Body.Close()
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			return newResponse(req, http.StatusBadRequest, jsonHeader(), []byte(`"invalid request body"`)), nil
+		}
+	}
+	// There are no synthetic funding payments or rates, transfers, vaults,
+	// staking, referrals, markets or candles
+	switch request.Type {
+	case LedgerUpdatesFunding, LedgerUpdatesNonFunding, "userVaultEquities", "delegatorHistory", "fundingHistory", "candleSnapshot":
+		return newResponse(req, http.StatusOK, jsonHeader(), []byte(`[]`)), nil
+	case "referral":
+		return newResponse(req, http.StatusOK, jsonHeader(), []byte(`{}`)), nil
+	case "metaAndAssetCtxs":
+		return newResponse(req, http.StatusOK, jsonHeader(), []byte(`[{"universe":[]},[]]`)), nil
+	}
+	// The status probe only needs an answer
+	if request.Type == "meta" {
+		return newResponse(req, http.StatusOK, jsonHeader(), []byte(`{"universe":[]}`)), nil
+	}
+	if request.Type != "userFillsByTime" || request.StartTime == nil {
+		return newResponse(req, http.StatusBadRequest, jsonHeader(), []byte(`"unsupported request"`)), nil
+	}
+
+	end := time.Now().UnixMilli()
+	if request.EndTime != nil && *request.EndTime < end {
+		end = *request.EndTime
+	}
+
+	body, err := json.Marshal(g.Fills(strings.ToLower(request.User), *request.StartTime, end, config.MaxTradesPerBatch))
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(req, http.StatusOK, jsonHeader(), body), nil
+}
+
+// Fills returns up to limit fills for address between start and end
+// (inclusive, in Unix milliseconds), oldest first
+func (g *SyntheticFills) Fills(address string, start, end int64, limit int) []FillResponse {
+	fills := []FillResponse{}
+	hourMs := time.Hour.Milliseconds()
+
+	for hour := start / hourMs; hour <= end/hourMs && len(fills) < limit; hour++ {
+		for _, fill := range g.hourFills(address, hour) {
+			if fill.Time >= start && fill.Time <= end {
+				fills = append(fills, fill)
+			}
+		}
+	}
+
+	if len(fills) > limit {
+		fills = fills[:limit]
+	}
+	return fills
+}
+
+// hourFills generates every fill for address in the hour starting at hour*1h since the epoch
+func (g *SyntheticFills) hourFills(address string, hour int64) []FillResponse {
+	hourMs := time.Hour.Milliseconds()
+	rng := g.rng(address, "fills", hour)
+	rate := g.tradesPerHour / float64(len(g.coins))
+
+	var fills []FillResponse
+	for _, coin := range g.coins {
+		unit := g.unit(coin)
+		position := float64(g.target(address, coin, hour-1)) * unit
+		target := float64(g.target(address, coin, hour)) * unit
+
+		count := poisson(rng, rate)
+		if count == 0 && position != target {
+			count = 1
+		}
+
+		times := make([]int64, count)
+		for i := range times {
+			// Leave room at the end of the hour for the de-duplication below
+			times[i] = hour*hourMs + rng.Int64N(hourMs-time.Second.Milliseconds())
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+		for i, t := range times {
+			next := target
+			if i < count-1 {
+				next = float64(rng.IntN(2*syntheticMaxUnits+1)-syntheticMaxUnits) * unit
+			}
+			size := roundTo(math.Abs(next-position), 6)
+			if size == 0 {
+				continue
+			}
+
+			side := "B"
+			if next < position {
+				side = "A"
+			}
+			price := g.price(coin, t)
+			fills = append(fills, FillResponse{
+				Time:          t,
+				Coin:          coin,
+				Side:          side,
+				Price:         strconv.FormatFloat(price, 'f', -1, 64),
+				Size:          strconv.FormatFloat(size, 'f', -1, 64),
+				StartPosition: strconv.FormatFloat(roundTo(position, 6), 'f', -1, 64),
+				Fee:           strconv.FormatFloat(roundTo(price*size*syntheticFeeRate, 6), 'f', -1, 64),
+			})
+			position = next
+		}
+	}
+
+	// The client pages on fill time, so fills must not share a millisecond
+	sort.SliceStable(fills, func(i, j int) bool { return fills[i].Time < fills[j].Time })
+	for i := 1; i < len(fills); i++ {
+		if fills[i].Time <= fills[i-1].Time {
+			fills[i].Time = fills[i-1].Time + 1
+		}
+	}
+	return fills
+}
+
+// syntheticMaxUnits bounds synthetic positions to this many units either side of flat
+const syntheticMaxUnits = 3
+
+// target returns the position, in units, coin is held at the end of hour
+func (g *SyntheticFills) target(address, coin string, hour int64) int {
+	return g.rng(address, "target:"+coin, hour).IntN(2*syntheticMaxUnits+1) - syntheticMaxUnits
+}
+
+// unit returns the position size step for coin, worth $1,000 to $10,000
+func (g *SyntheticFills) unit(coin string) float64 {
+	decimals := math.Floor(math.Log10(g.basePrice(coin)))
+	return math.Pow(10, 3-decimals)
+}
+
+// basePrice returns the price coin's path is centred on
+func (g *SyntheticFills) basePrice(coin string) float64 {
+	if price, ok := syntheticBasePrices[coin]; ok {
+		return price
+	}
+	return 1 + float64(hashString(coin)%1000)/10
+}
+
+// price returns coin's price at t (Unix milliseconds) to 5 significant figures.
+// The path is a sum of waves with periods of one to thirty days scaled by the
+// volatility, plus minute-level noise.
+func (g *SyntheticFills) price(coin string, t int64) float64 {
+	days := float64(t) / float64(24*time.Hour.Milliseconds())
+	phase := float64(hashString(coin)^g.seed) / math.MaxUint64 * 2 * math.Pi
+
+	var move float64
+	for i, period := range []float64{1, 3.7, 11, 29} {
+		move += g.volatility * math.Sqrt(period) * 0.5 * math.Sin(2*math.Pi*days/period+phase*float64(i+1))
+	}
+	minute := t / time.Minute.Milliseconds()
+	move += g.volatility * 0.1 * (g.rng("", "noise:"+coin, minute).Float64() - 0.5)
+
+	price := g.basePrice(coin) * math.Exp(move)
+	return roundTo(price, 4-int(math.Floor(math.Log10(price))))
+}
+
+// rng returns a generator seeded from the configured seed, address, stream name and n
+func (g *SyntheticFills) rng(address, stream string, n int64) *rand.Rand {
+	return rand.New(rand.NewPCG(g.seed^hashString(address+"|"+stream), uint64(n)))
+}
+
+// poisson draws from a Poisson distribution with mean rate
+func poisson(rng *rand.Rand, rate float64) int {
+	if rate > 30 {
+		return max(0, int(math.Round(rate+math.Sqrt(rate)*rng.NormFloat64())))
+	}
+	limit, product, count := math.Exp(-rate), rng.Float64(), 0
+	for product > limit {
+		product *= rng.Float64()
+		count++
+	}
+	return count
+}
+
+// roundTo rounds v to decimals decimal places
+func roundTo(v float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(v*scale) / scale
+}
+
+// hashString returns the FNV-1a hash of s
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"hyperliquid-recon/config"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestSyntheticFills returns a generator used by the synthetic data tests
+func newTestSyntheticFills(t *testing.T, tradesPerHour float64) *SyntheticFills {
+	t.Helper()
+
+	gen, err := NewSyntheticFills([]string{"BTC", "ETH", "XYZ"}, 0.03, tradesPerHour, 7)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	return gen
+}
+
+// Test synthetic fills are reproducible and internally consistent
+func TestSyntheticFills(t *testing.T) {
+	gen := newTestSyntheticFills(t, 6)
+	address := "0x1234567890abcdef1234567890abcdef12345678"
+	end := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC).UnixMilli()
+	start := end - 2*24*time.Hour.Milliseconds()
+
+	t.Run("should return the same fills however the window is split", func(t *testing.T) {
+		all := gen.Fills(address, start, end, math.MaxInt)
+		if len(all) == 0 {
+			t.Fatal("Expected fills")
+		}
+
+		mid := all[len(all)/2].Time
+		split := append(gen.Fills(address, start, mid, math.MaxInt), gen.Fills(address, mid+1, end, math.MaxInt)...)
+		if !reflect.DeepEqual(all, split) {
+			t.Error("Expected split windows to return the same fills")
+		}
+	})
+
+	t.Run("should chain start positions from fill to fill", func(t *testing.T) {
+		client := NewHyperliquidClient()
+		positions := make(map[string]float64)
+		seen := make(map[string]bool)
+
+		for _, fill := range gen.Fills(address, start, end, math.MaxInt) {
+			trade, err := client.convertFillToTrade(fill)
+			if err != nil {
+				t.Fatalf("Failed to convert fill: %v", err)
+			}
+			if seen[trade.Coin] && math.Abs(float64(*trade.StartPosition)-positions[trade.Coin]) > 1e-9 {
+				t.Fatalf("%s fill at %d starts at %v, expected %v", trade.Coin, fill.Time, *trade.StartPosition, positions[trade.Coin])
+			}
+
+			size := float64(trade.Size)
+			if trade.Side == "A" {
+				size = -size
+			}
+			positions[trade.Coin] = float64(*trade.StartPosition) + size
+			seen[trade.Coin] = true
+		}
+	})
+
+	t.Run("should give each address its own fills", func(t *testing.T) {
+		other := gen.Fills("0xabcdefabcdefabcdefabcdefabcdefabcdefabcd", start, end, math.MaxInt)
+		if reflect.DeepEqual(gen.Fills(address, start, end, math.MaxInt), other) {
+			t.Error("Expected different addresses to get different fills")
+		}
+	})
+}
+
+// Test the client pages through synthetic fills like the real API
+func TestSyntheticFillsClient(t *testing.T) {
+	UseHyperliquidTransport(newTestSyntheticFills(t, 300))
+	defer UseHyperliquidTransport(nil)
+
+	previousDelay := RateLimitDelay()
+	SetRateLimitDelay(0)
+	defer SetRateLimitDelay(previousDelay)
+
+	client := NewHyperliquidClient()
+	end := time.Now()
+	start := end.Add(-24 * time.Hour)
+	address := "0x1234567890abcdef1234567890abcdef12345678"
+
+	trades, err := client.FetchTradesInRange(context.Background(), address, start, end)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := newTestSyntheticFills(t, 300).Fills(address, start.UnixMilli(), end.UnixMilli(), math.MaxInt)
+	if len(expected) <= config.MaxTradesPerBatch {
+		t.Fatalf("Expected more than one batch of fills, got %d", len(expected))
+	}
+	if len(trades) != len(expected) {
+		t.Errorf("Expected %d trades across batches, got %d", len(expected), len(trades))
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tape modes
+const (
+	TapeRecord = "record"
+	TapeReplay = "replay"
+)
+
+// ErrTapeExhausted is returned when a replayed tape has no response left for a request
+var ErrTapeExhausted = errors.New("no recorded response for request")
+
+// tapeTimeFields are request body fields that depend on when a request was
+// made; they are ignored when a replayed request has no exact match
+var tapeTimeFields = []string{"startTime", "endTime"}
+
+// Tape records upstream requests and responses to a file, or replays a
+// recording in place of the upstream API, so a run can be reproduced offline.
+//
+// A replayed request gets the first unplayed recorded response to the same
+// request. If there is none, requests that differ only in their time window
+// match in recorded order, since a later run asks for windows ending at a
+// later time.
+type Tape struct {
+	mode    string
+	next    http.RoundTripper // Record mode: where requests are sent
+	file    *os.File          // Record mode: the tape being written
+	entries []models.TapeEntry
+	played  []bool
+	mu      sync.Mutex
+}
+
+// NewRecordingTape creates a tape that sends requests through next (the
+// default transport if nil) and appends each exchange to the file at path,
+// encrypted if a storage key is in use
+func NewRecordingTape(path string, next http.RoundTripper) (*Tape, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, storeFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tape: %w", err)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Tape{mode: TapeRecord, next: next, file: file}, nil
+}
+
+// LoadTape reads a recorded tape for replay
+func LoadTape(path string) (*Tape, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tape: %w", err)
+	}
+	defer file.Close()
+
+	tape := &Tape{mode: TapeReplay}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 256<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		data, _, err := openLine(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("tape line %d: %w", line, err)
+		}
+		var entry models.TapeEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("tape line %d: %w", line, err)
+		}
+		tape.entries = append(tape.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tape: %w", err)
+	}
+
+	tape.played = make([]bool, len(tape.entries))
+	return tape, nil
+}
+
+// Close closes the file a recording tape writes to
+func (t *Tape) Close() error {
+	if t.file == nil {
+		return nil
+	}
+	return t.file.Close()
+}
+
+// Len returns the number of recorded exchanges on a replayed tape
+func (t *Tape) Len() int {
+	return len(t.entries)
+}
+
+// RoundTrip records or replays one request
+func (t *Tape) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	recorded := models.TapeRequest{Method: req.Method, URL: req.URL.String(), Body: compactJSON(body)}
+
+	if t.mode == TapeReplay {
+		return t.replay(req, recorded)
+	}
+	return t.record(req, recorded)
+}
+
+// record sends req upstream and appends the exchange to the tape
+func (t *Tape) record(req *http.Request, recorded models.TapeRequest) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := models.TapeEntry{
+		Request:    recorded,
+		Response:   models.TapeResponse{Status: resp.StatusCode, Header: resp.Header.Clone()},
+		RecordedAt: time.Now(),
+	}
+	// The body may be compacted, so its recorded length would be wrong
+	entry.Response.Header.Del("Content-Length")
+	if raw := compactJSON(body); raw != nil {
+		entry.Response.Body = raw
+	} else {
+		entry.Response.Text = string(body)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	_, err = t.file.Write(append(sealLine(line), '\n'))
+	t.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write tape: %w", err)
+	}
+
+	return tapeResponse(req, entry.Response), nil
+}
+
+// replay answers req with the matching recorded response
+func (t *Tape) replay(req *http.Request, recorded models.TapeRequest) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	match := -1
+	for i, entry := range t.entries {
+		if !t.played[i] && tapeKey(entry.Request, nil) == tapeKey(recorded, nil) {
+			match = i
+			break
+		}
+	}
+	if match < 0 {
+		for i, entry := range t.entries {
+			if !t.played[i] && tapeKey(entry.Request, tapeTimeFields) == tapeKey(recorded, tapeTimeFields) {
+				match = i
+				break
+			}
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("%w: %s %s %s", ErrTapeExhausted, recorded.Method, recorded.URL, string(recorded.Body))
+	}
+
+	t.played[match] = true
+	return tapeResponse(req, t.entries[match].Response), nil
+}
+
+// tapeKey identifies a request for matching, leaving out the named top-level body fields
+func tapeKey(req models.TapeRequest, ignore []string) string {
+	body := string(req.Body)
+	if len(ignore) > 0 {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(req.Body, &fields) == nil {
+			for _, name := range ignore {
+				delete(fields, name)
+			}
+			// Marshal sorts the keys, so field order doesn't matter
+			normalized, _ := json.Marshal(fields)
+			body = string(normalized)
+		}
+	}
+	return strings.Join([]string{req.Method, req.URL, body}, " ")
+}
+
+// compactJSON returns body without insignificant whitespace, or nil if it is not JSON
+func compactJSON(body []byte) json.RawMessage {
+	var buf bytes.Buffer
+	if len(body) == 0 || json.Compact(&buf, body) != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// tapeResponse builds the response to req from a recorded one
+func tapeResponse(req *http.Request, recorded models.TapeResponse) *http.Response {
+	body := []byte(recorded.Body)
+	if recorded.Body == nil {
+		body = []byte(recorded.Text)
+	}
+	// The recorded Date would look like clock skew to the client
+	header := recorded.Header.Clone()
+	header.Del("Date")
+	return newResponse(req, recorded.Status, header, body)
+}
+
+// jsonHeader returns the headers of a JSON response
+func jsonHeader() http.Header {
+	return http.Header{"Content-Type": {"application/json"}}
+}
+
+// newResponse builds a response to req for transports that answer requests themselves
+func newResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode:    status,
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+	}
+}
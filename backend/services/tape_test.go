@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Test a recorded tape reproduces a refresh without the upstream API
+func TestTapeRecordAndReplay(t *testing.T) {
+	previousDelay := RateLimitDelay()
+	SetRateLimitDelay(0)
+	defer SetRateLimitDelay(previousDelay)
+
+	path := filepath.Join(t.TempDir(), "tape.jsonl")
+	address := "0x1234567890abcdef1234567890abcdef12345678"
+
+	recording, err := NewRecordingTape(path, newTestSyntheticFills(t, 100))
+	if err != nil {
+		t.Fatalf("Failed to create tape: %v", err)
+	}
+	recorded := NewReconciliationService()
+	recorded.hlClient.httpClient.Transport = recording
+	if err := recorded.FetchAndReconcile(context.Background(), address, 2); err != nil {
+		t.Fatalf("Recording refresh failed: %v", err)
+	}
+	recording.Close()
+
+	tape, err := LoadTape(path)
+	if err != nil {
+		t.Fatalf("Failed to load tape: %v", err)
+	}
+	if tape.Len() < 2 {
+		t.Fatalf("Expected several batches on the tape, got %d", tape.Len())
+	}
+
+	t.Run("should replay the same P&L", func(t *testing.T) {
+		replayed := NewReconciliationService()
+		replayed.hlClient.httpClient.Transport = tape
+		if err := replayed.FetchAndReconcile(context.Background(), address, 2); err != nil {
+			t.Fatalf("Replayed refresh failed: %v", err)
+		}
+
+		want, got := recorded.GetPnLSummary(), replayed.GetPnLSummary()
+		if !reflect.DeepEqual(want.DailyRecords, got.DailyRecords) || want.TotalPnL != got.TotalPnL {
+			t.Errorf("Expected replayed P&L %v, got %v", want.TotalPnL, got.TotalPnL)
+		}
+	})
+
+	t.Run("should fail once the tape is used up", func(t *testing.T) {
+		replayed := NewReconciliationService()
+		replayed.hlClient.httpClient.Transport = tape
+		err := replayed.FetchAndReconcile(context.Background(), address, 2)
+		if !errors.Is(err, ErrTapeExhausted) {
+			t.Errorf("Expected ErrTapeExhausted, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,409 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultTenantID is the ID of the only tenant when multi-tenancy is not configured
+const DefaultTenantID = "default"
+
+// SharedServices are the tenant-independent dependencies every tenant is built with
+type SharedServices struct {
+	ENS      *ENSResolver    // nil if ENS resolution is not configured
+	Mailer   *Mailer         // nil if SMTP is not configured
+	Exports  ObjectStore     // S3 bucket or data directory exports are written to; nil if neither is configured
+	HyperEVM *HyperEVMClient // nil if HyperEVM transfers are not configured
+	// IncomeSources record points, airdrops and other non-trade income
+	IncomeSources []IncomeSource
+	Valuation     Valuation // Prices other income; nil if not configured
+}
+
+// Tenant is one tenant's set of services. Each tenant has its own caches,
+// stores, jobs and schedules, so nothing one tenant caches, schedules or
+// stores is visible to another through the API.
+//
+// Some package-level state is shared by every tenant, because it belongs to
+// the exchange or the process rather than to a tenant:
+//   - upstream request handling: pacing (upstreamPacer), the priority budget
+//     (upstreamBudget), the API budget (upstreamUsage), health (upstreamHealth)
+//     and clock skew (upstreamClock), since all tenants draw on one Hyperliquid
+//     rate limit. One tenant's refreshes can delay or use up another's.
+//   - the response cache (upstreamResponses) and raw fill archive
+//     (fillArchive), keyed by request. Two tenants tracking the same address
+//     share its responses, which only hold what the exchange publishes.
+//   - exchange-wide data: the symbol map (symbols), symbol history
+//     (symbolHistory), funding rates (fundingRates) and market context
+//     (marketContexts).
+//   - the storage cipher (storageCipher) and the download store (downloads),
+//     whose files are kept apart per tenant.
+type Tenant struct {
+	ID              string
+	Config          models.TenantConfig // Settings the tenant was built from
+	ReconService    *ReconciliationService
+	AddressBook     *AddressBook
+	Breaks          *BreakStore
+	Periods         *PeriodStore
+	Ledger          *Ledger
+	Events          *EventStore // nil unless an event log is configured
+	Runs            *RunStore
+	Snapshots       *TradeSnapshotStore
+	Reconciliations *ExternalReconStore
+	ExportTemplates *ExportTemplateStore
+	Views           *ViewStore
+	Quarantine      *QuarantineStore
+	ExportSigner    *ExportSigner
+	Webhooks        *WebhookDispatcher
+	Push            *PushHub // Live events for WebSocket subscribers
+	Jobs            *JobManager
+	Retention       *RetentionService
+	Leaderboard     *LeaderboardService
+	Reports         *ReportService
+	Closes          *CloseService
+	DataQuality     *DataQualityMonitor
+	Sheets          *SheetsExporter // nil unless Google Sheets export is configured for the tenant
+	S3Export        *S3Exporter     // nil unless S3 export or a data directory is configured
+}
+
+// NewTenant builds the services for one tenant
+func NewTenant(cfg models.TenantConfig, shared SharedServices) (*Tenant, error) {
+	t := &Tenant{ID: cfg.ID, Config: cfg}
+
+	addressBook, err := NewAddressBook(cfg.AddressBookFile, shared.ENS)
+	if err != nil {
+		return nil, err
+	}
+	t.AddressBook = addressBook
+
+	breaks, err := NewBreakStore(cfg.BreaksFile)
+	if err != nil {
+		return nil, err
+	}
+	t.Breaks = breaks
+	t.Push = NewPushHub()
+	breaks.UsePushHub(t.Push)
+
+	t.ReconService = NewReconciliationService()
+	t.ReconService.UseAddressBook(addressBook)
+	t.ReconService.UseBreakStore(breaks)
+	t.ReconService.UsePushHub(t.Push)
+
+	t.Periods, err = NewPeriodStore(cfg.PeriodsFile)
+	if err != nil {
+		return nil, err
+	}
+	t.ReconService.UsePeriodStore(t.Periods)
+
+	t.Ledger, err = NewLedger(cfg.LedgerFile)
+	if err != nil {
+		return nil, err
+	}
+	if shared.HyperEVM != nil {
+		t.Ledger.UseHyperEVM(shared.HyperEVM)
+	}
+	for _, source := range shared.IncomeSources {
+		t.Ledger.UseIncomeSource(source)
+	}
+	if shared.Valuation != nil {
+		t.Ledger.UseValuation(shared.Valuation)
+	}
+
+	t.Runs, err = NewRunStore(cfg.RunsFile)
+	if err != nil {
+		return nil, err
+	}
+	t.ReconService.UseRunStore(t.Runs)
+
+	t.Snapshots, err = NewTradeSnapshotStore(cfg.SnapshotsFile)
+	if err != nil {
+		return nil, err
+	}
+	t.ReconService.UseTradeSnapshotStore(t.Snapshots)
+
+	t.Reconciliations, err = NewExternalReconStore(cfg.ReconciliationsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	t.ExportTemplates, err = NewExportTemplateStore(cfg.ExportTemplatesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Views, err = NewViewStore(cfg.ViewsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Quarantine, err = NewQuarantineStore(cfg.QuarantineFile)
+	if err != nil {
+		return nil, err
+	}
+	t.ReconService.UseQuarantineStore(t.Quarantine)
+
+	if cfg.EventsFile != "" {
+		t.Events, err = OpenEventStore(cfg.EventsFile)
+		if err != nil {
+			return nil, err
+		}
+		t.ReconService.UseEventStore(t.Events)
+		replayed, err := t.ReconService.Rebuild()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rebuild caches for tenant %q: %w", cfg.ID, err)
+		}
+		log.Printf("Rebuilt trade caches for tenant %s from %d events", cfg.ID, replayed)
+	}
+
+	t.Webhooks = NewWebhookDispatcher(config.WebhookSigningSecret)
+	t.ExportSigner = NewExportSigner(config.ExportSigningKey)
+	t.Jobs = NewJobManager(t.ReconService, t.Webhooks)
+	t.Retention = NewRetentionService(t.ReconService, t.Runs, t.Reconciliations, t.Jobs)
+	t.Leaderboard = NewLeaderboardService(t.ReconService, addressBook)
+
+	var subscriptions []models.ReportSubscription
+	if cfg.ReportsFile != "" {
+		subscriptions, err = LoadReportSubscriptions(cfg.ReportsFile)
+		if err != nil {
+			return nil, err
+		}
+		if shared.Mailer == nil && len(subscriptions) > 0 {
+			return nil, fmt.Errorf("report subscriptions are configured but SMTP is not (set RECON_SMTP_HOST and RECON_SMTP_FROM)")
+		}
+	}
+	t.Reports = NewReportService(t.ReconService, addressBook, shared.Mailer, subscriptions)
+	t.Reports.UseLedger(t.Ledger)
+
+	t.Closes, err = NewCloseService(t.ReconService, addressBook, breaks, t.Webhooks, shared.Mailer, cfg.EODWebhookURL, cfg.EODReportTo, cfg.ClosesFile)
+	if err != nil {
+		return nil, err
+	}
+	t.DataQuality = NewDataQualityMonitor(t.Webhooks, shared.Mailer, t.Closes.Alerts)
+	t.DataQuality.UsePushHub(t.Push)
+	t.ReconService.UseDataQualityMonitor(t.DataQuality)
+
+	if config.SheetsCredentialsFile != "" && cfg.SheetsSpreadsheetID != "" {
+		t.Sheets, err = NewSheetsExporter(t.ReconService, config.SheetsCredentialsFile, cfg.SheetsSpreadsheetID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Google Sheets export: %w", err)
+		}
+	}
+
+	if shared.Exports != nil {
+		t.S3Export = NewS3Exporter(t.ReconService, shared.Exports, cfg.S3Prefix, config.S3ExportFormat)
+	}
+
+	return t, nil
+}
+
+// DefaultTenantConfig returns the single tenant's configuration from the environment
+func DefaultTenantConfig() models.TenantConfig {
+	cfg := models.TenantConfig{
+		ID:                  DefaultTenantID,
+		ReportsFile:         config.ReportsFile,
+		EODWebhookURL:       config.EODWebhookURL,
+		SheetsSpreadsheetID: config.SheetsSpreadsheetID,
+		AddressBookFile:     config.AddressBookFile,
+		BreaksFile:          config.BreaksFile,
+		ClosesFile:          config.ClosesFile,
+		PeriodsFile:         config.PeriodsFile,
+		LedgerFile:          config.LedgerFile,
+		EventsFile:          config.EventsFile,
+		RunsFile:            config.RunsFile,
+		SnapshotsFile:       config.SnapshotsFile,
+		ReconciliationsFile: config.ReconciliationsFile,
+		ExportTemplatesFile: config.ExportTemplatesFile,
+		ViewsFile:           config.ViewsFile,
+		QuarantineFile:      config.QuarantineFile,
+		S3Prefix:            config.S3Prefix,
+	}
+	if config.EODReportTo != "" {
+		cfg.EODReportTo = strings.Split(config.EODReportTo, ",")
+	}
+
+	// Stores whose file isn't set are kept in the data directory's db folder
+	if config.DataDir != "" {
+		dir := NewDataDir(config.DataDir).TenantDir(cfg.ID)
+		for name, file := range storageFiles(&cfg) {
+			if *file == "" {
+				*file = filepath.Join(dir, name)
+			}
+		}
+	}
+	return cfg
+}
+
+// LoadTenantConfigs reads tenant definitions from a JSON file, reading API keys
+// named by apiKeySecrets from secrets. Each tenant's storage is placed in its
+// own data directory and S3 prefix, and IDs, API keys and data directories
+// must be unique so no state can be shared. Tenants without a data directory
+// are given one in the -data-dir layout if it is configured.
+func LoadTenantConfigs(path string, secrets Secrets) ([]models.TenantConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants: %w", err)
+	}
+
+	var configs []models.TenantConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants: %w", err)
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no tenants defined in %s", path)
+	}
+
+	ids := make(map[string]bool)
+	keys := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for i := range configs {
+		cfg := &configs[i]
+		if cfg.ID == "" || strings.ContainsAny(cfg.ID, `/\. `) {
+			return nil, fmt.Errorf("tenant %d: id is required and may not contain '/', '\\', '.' or spaces", i+1)
+		}
+		if ids[cfg.ID] {
+			return nil, fmt.Errorf("duplicate tenant id %q", cfg.ID)
+		}
+		ids[cfg.ID] = true
+
+		for _, name := range cfg.APIKeySecrets {
+			key, err := secrets.Get(name)
+			if err != nil {
+				return nil, fmt.Errorf("tenant %q: failed to read API key secret %s: %w", cfg.ID, name, err)
+			}
+			cfg.APIKeys = append(cfg.APIKeys, key)
+		}
+		tenantKeys := cfg.APIKeys
+		for j := range cfg.Roles {
+			role := &cfg.Roles[j]
+			if _, err := NewKeyPolicy(*role); err != nil {
+				return nil, fmt.Errorf("tenant %q: %w", cfg.ID, err)
+			}
+			for _, name := range role.APIKeySecrets {
+				key, err := secrets.Get(name)
+				if err != nil {
+					return nil, fmt.Errorf("tenant %q: failed to read API key secret %s: %w", cfg.ID, name, err)
+				}
+				role.APIKeys = append(role.APIKeys, key)
+			}
+			tenantKeys = append(tenantKeys, role.APIKeys...)
+		}
+		if len(tenantKeys) == 0 {
+			return nil, fmt.Errorf("tenant %q has no API keys", cfg.ID)
+		}
+		for _, key := range tenantKeys {
+			if keys[key] {
+				return nil, fmt.Errorf("tenant %q reuses an API key of another tenant or role", cfg.ID)
+			}
+			keys[key] = true
+		}
+
+		if cfg.DataDir == "" && config.DataDir != "" {
+			cfg.DataDir = NewDataDir(config.DataDir).TenantDir(cfg.ID)
+		}
+		if cfg.DataDir != "" {
+			dir := filepath.Clean(cfg.DataDir)
+			if dirs[dir] {
+				return nil, fmt.Errorf("tenant %q shares its data directory with another tenant", cfg.ID)
+			}
+			dirs[dir] = true
+
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return nil, fmt.Errorf("failed to create data directory for tenant %q: %w", cfg.ID, err)
+			}
+			for name, file := range storageFiles(cfg) {
+				*file = filepath.Join(dir, name)
+			}
+		}
+		cfg.S3Prefix = config.S3Prefix + "tenants/" + cfg.ID + "/"
+	}
+
+	return configs, nil
+}
+
+// TenantRegistry maps API keys to tenants and the policies of their roles
+type TenantRegistry struct {
+	tenants  []*Tenant
+	byKey    map[[32]byte]*Tenant    // key: SHA-256 of the API key
+	policies map[[32]byte]*KeyPolicy // key: SHA-256 of an API key with a role
+	multi    bool
+}
+
+// NewSingleTenantRegistry serves every request from one tenant without authentication
+func NewSingleTenantRegistry(tenant *Tenant) *TenantRegistry {
+	return &TenantRegistry{tenants: []*Tenant{tenant}}
+}
+
+// NewTenantRegistry creates a registry that authenticates requests by API key.
+// tenants and configs must be in the same order.
+func NewTenantRegistry(tenants []*Tenant, configs []models.TenantConfig) *TenantRegistry {
+	tr := &TenantRegistry{
+		tenants:  tenants,
+		byKey:    make(map[[32]byte]*Tenant),
+		policies: make(map[[32]byte]*KeyPolicy),
+		multi:    true,
+	}
+	for i, cfg := range configs {
+		for _, key := range cfg.APIKeys {
+			tr.byKey[sha256.Sum256([]byte(key))] = tenants[i]
+		}
+		for _, role := range cfg.Roles {
+			policy, err := NewKeyPolicy(role)
+			if err != nil {
+				log.Printf("Skipping invalid role of tenant %s: %v", cfg.ID, err)
+				continue
+			}
+			for _, key := range role.APIKeys {
+				hash := sha256.Sum256([]byte(key))
+				tr.byKey[hash] = tenants[i]
+				tr.policies[hash] = policy
+			}
+		}
+	}
+	log.Printf("Multi-tenant mode: %d tenants", len(tenants))
+	return tr
+}
+
+// Authenticate returns the tenant an API key belongs to. In single-tenant mode
+// every request belongs to the only tenant.
+func (tr *TenantRegistry) Authenticate(apiKey string) (*Tenant, bool) {
+	if !tr.multi {
+		return tr.tenants[0], true
+	}
+	tenant, ok := tr.byKey[sha256.Sum256([]byte(apiKey))]
+	return tenant, ok
+}
+
+// Policy returns the policy of an API key's role, or nil if the key has no
+// role and its refreshes are unlimited
+func (tr *TenantRegistry) Policy(apiKey string) *KeyPolicy {
+	if !tr.multi {
+		return nil
+	}
+	return tr.policies[sha256.Sum256([]byte(apiKey))]
+}
+
+// Tenants returns every tenant
+func (tr *TenantRegistry) Tenants() []*Tenant {
+	return tr.tenants
+}
+
+// Tenant returns the tenant with the given ID
+func (tr *TenantRegistry) Tenant(id string) (*Tenant, bool) {
+	for _, tenant := range tr.tenants {
+		if tenant.ID == id {
+			return tenant, true
+		}
+	}
+	return nil, false
+}
+
+// MultiTenant reports whether requests must be authenticated with an API key
+func (tr *TenantRegistry) MultiTenant() bool {
+	return tr.multi
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestTenants writes a tenants file for tenants "alpha" and "beta" and returns its path
+func writeTestTenants(t *testing.T, dir string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "tenants.json")
+	data := `[
+		{"id": "alpha", "apiKeys": ["alpha-key"], "dataDir": "` + filepath.Join(dir, "alpha") + `"},
+		{"id": "beta", "apiKeys": ["beta-key", "beta-key-2"], "dataDir": "` + filepath.Join(dir, "beta") + `"}
+	]`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write tenants: %v", err)
+	}
+	return path
+}
+
+// Test LoadTenantConfigs validation and storage layout
+func TestLoadTenantConfigs(t *testing.T) {
+	t.Run("should give each tenant its own storage", func(t *testing.T) {
+		dir := t.TempDir()
+		configs, err := LoadTenantConfigs(writeTestTenants(t, dir), EnvSecrets{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(configs) != 2 {
+			t.Fatalf("Expected 2 tenants, got %d", len(configs))
+		}
+
+		alpha, beta := configs[0], configs[1]
+		if alpha.BreaksFile != filepath.Join(dir, "alpha", "breaks.json") || beta.BreaksFile == alpha.BreaksFile {
+			t.Errorf("Expected separate breaks files, got %q and %q", alpha.BreaksFile, beta.BreaksFile)
+		}
+		if !strings.HasSuffix(alpha.S3Prefix, "tenants/alpha/") || !strings.HasSuffix(beta.S3Prefix, "tenants/beta/") {
+			t.Errorf("Expected per-tenant S3 prefixes, got %q and %q", alpha.S3Prefix, beta.S3Prefix)
+		}
+	})
+
+	invalid := map[string]string{
+		"duplicate id":    `[{"id": "a", "apiKeys": ["k1"]}, {"id": "a", "apiKeys": ["k2"]}]`,
+		"shared API key":  `[{"id": "a", "apiKeys": ["k1"]}, {"id": "b", "apiKeys": ["k1"]}]`,
+		"shared data dir": `[{"id": "a", "apiKeys": ["k1"], "dataDir": "DIR"}, {"id": "b", "apiKeys": ["k2"], "dataDir": "DIR/"}]`,
+		"missing API key": `[{"id": "a"}]`,
+		"path in id":      `[{"id": "../a", "apiKeys": ["k1"]}]`,
+		"no tenants":      `[]`,
+		"role key reused": `[{"id": "a", "apiKeys": ["k1"], "roles": [{"name": "analyst", "apiKeys": ["k1"]}]}]`,
+		"negative days":   `[{"id": "a", "apiKeys": ["k1"], "roles": [{"name": "analyst", "apiKeys": ["k2"], "maxDays": -1}]}]`,
+		"bad interval":    `[{"id": "a", "apiKeys": ["k1"], "roles": [{"name": "analyst", "apiKeys": ["k2"], "minRefreshInterval": "soon"}]}]`,
+	}
+	for name, data := range invalid {
+		t.Run("should reject "+name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "tenants.json")
+			os.WriteFile(path, []byte(strings.ReplaceAll(data, "DIR", filepath.Join(dir, "shared"))), 0o644)
+
+			if _, err := LoadTenantConfigs(path, EnvSecrets{}); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+// Test that no state leaks between tenants
+func TestTenantIsolation(t *testing.T) {
+	now := time.Now()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]FillResponse{
+			{Time: now.Add(-2 * time.Hour).UnixMilli(), Coin: "BTC", Side: "B", Price: "100", Size: "1"},
+			{Time: now.Add(-time.Hour).UnixMilli(), Coin: "BTC", Side: "A", Price: "150", Size: "1"},
+		})
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	configs, err := LoadTenantConfigs(writeTestTenants(t, dir), EnvSecrets{})
+	if err != nil {
+		t.Fatalf("Failed to load tenants: %v", err)
+	}
+	var tenants []*Tenant
+	for _, cfg := range configs {
+		tenant, err := NewTenant(cfg, SharedServices{})
+		if err != nil {
+			t.Fatalf("Failed to create tenant %s: %v", cfg.ID, err)
+		}
+		tenant.ReconService.hlClient.apiURL = upstream.URL
+		tenants = append(tenants, tenant)
+	}
+	registry := NewTenantRegistry(tenants, configs)
+
+	alpha, ok := registry.Authenticate("alpha-key")
+	if !ok || alpha.ID != "alpha" {
+		t.Fatalf("Expected alpha-key to authenticate alpha, got %v", alpha)
+	}
+	beta, ok := registry.Authenticate("beta-key-2")
+	if !ok || beta.ID != "beta" {
+		t.Fatalf("Expected beta-key-2 to authenticate beta, got %v", beta)
+	}
+	if _, ok := registry.Authenticate("unknown"); ok {
+		t.Error("Expected an unknown key to be rejected")
+	}
+	if _, ok := registry.Authenticate(""); ok {
+		t.Error("Expected a missing key to be rejected")
+	}
+
+	// All activity happens in alpha
+	if _, err := alpha.AddressBook.Save(testAddress, "Alpha desk"); err != nil {
+		t.Fatalf("Failed to save address: %v", err)
+	}
+	if err := alpha.ReconService.FetchAndReconcile(context.Background(), testAddress, 1); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	b, err := alpha.Breaks.Create(testAddress, "BTC", "2025-01-01", "Missing fill")
+	if err != nil {
+		t.Fatalf("Failed to create break: %v", err)
+	}
+	job := alpha.Jobs.SubmitRefresh(context.Background(), testAddress, 1, models.PriorityHigh, "")
+	// Let the job finish before the tenant's data directory is removed
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if finished, _ := alpha.Jobs.GetJob(job.ID); finished.FinishedAt != nil {
+			break
+		}
+	}
+
+	t.Run("should not share caches or summaries", func(t *testing.T) {
+		if summary := alpha.ReconService.GetPnLSummary(); summary.Label != "Alpha desk" || len(summary.DailyRecords) == 0 {
+			t.Errorf("Expected alpha's summary, got %+v", summary)
+		}
+		if summary := beta.ReconService.GetPnLSummary(); summary.Address != "" || len(summary.DailyRecords) != 0 {
+			t.Errorf("Expected an empty summary for beta, got %+v", summary)
+		}
+		if _, exists := beta.ReconService.CachedTrades(testAddress); exists {
+			t.Error("Expected beta to have no cached trades")
+		}
+		if addresses := beta.ReconService.CachedAddresses(); len(addresses) != 0 {
+			t.Errorf("Expected beta to have no cached addresses, got %v", addresses)
+		}
+	})
+
+	t.Run("should not share address books", func(t *testing.T) {
+		if entries := beta.AddressBook.List(); len(entries) != 0 {
+			t.Errorf("Expected beta's address book to be empty, got %+v", entries)
+		}
+		if _, err := beta.AddressBook.Resolve("Alpha desk"); err == nil {
+			t.Error("Expected alpha's label not to resolve for beta")
+		}
+	})
+
+	t.Run("should not share breaks, jobs or leaderboards", func(t *testing.T) {
+		if _, exists := beta.Breaks.Get(b.ID); exists {
+			t.Error("Expected alpha's break to be invisible to beta")
+		}
+		if breaks := beta.Breaks.List("", "", ""); len(breaks) != 0 {
+			t.Errorf("Expected no breaks for beta, got %+v", breaks)
+		}
+		if _, exists := beta.Jobs.GetJob(job.ID); exists {
+			t.Error("Expected alpha's job to be invisible to beta")
+		}
+		beta.Leaderboard.Refresh()
+		if entries := beta.Leaderboard.Leaderboard("24h", SortByPnL).Entries; len(entries) != 0 {
+			t.Errorf("Expected an empty leaderboard for beta, got %+v", entries)
+		}
+	})
+
+	t.Run("should persist to the tenant's own files", func(t *testing.T) {
+		if _, err := os.Stat(filepath.Join(dir, "alpha", "addressbook.json")); err != nil {
+			t.Errorf("Expected alpha's address book on disk: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "beta", "addressbook.json")); err == nil {
+			t.Error("Expected no address book file for beta")
+		}
+
+		reloaded, err := NewTenant(configs[1], SharedServices{})
+		if err != nil {
+			t.Fatalf("Failed to reload beta: %v", err)
+		}
+		if breaks := reloaded.Breaks.List("", "", ""); len(breaks) != 0 {
+			t.Errorf("Expected no breaks for reloaded beta, got %+v", breaks)
+		}
+	})
+}
+
+// Test that single-tenant mode needs no API key
+func TestSingleTenantRegistry(t *testing.T) {
+	tenant, err := NewTenant(DefaultTenantConfig(), SharedServices{})
+	if err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+
+	registry := NewSingleTenantRegistry(tenant)
+	if got, ok := registry.Authenticate(""); !ok || got != tenant {
+		t.Error("Expected every request to use the default tenant")
+	}
+	if registry.MultiTenant() {
+		t.Error("Expected single-tenant mode")
+	}
+}
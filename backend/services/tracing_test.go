@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Test the spans recorded for a refresh
+func TestFetchAndReconcileSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	server := newFlakyServer(t, time.Now().Add(-12*time.Hour))
+	defer server.Close()
+
+	rs := NewReconciliationService()
+	rs.hlClient.apiURL = server.URL
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	rs.FetchAndReconcile(ctx, "0xabc", 1)
+	parent.End()
+
+	spans := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+			t.Errorf("Expected span %s in the request's trace", span.Name())
+		}
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+
+	for name, count := range map[string]int{
+		"ReconciliationService.FetchAndReconcile": 1,
+		"HyperliquidClient.FetchTradesInRange":    1,
+		"HyperliquidClient.fetchBatch":            2,
+		"ReconciliationService.calculateDailyPnL": 1,
+	} {
+		if len(spans[name]) != count {
+			t.Errorf("Expected %d %s spans, got %d", count, name, len(spans[name]))
+		}
+	}
+
+	batches := spans["HyperliquidClient.fetchBatch"]
+	if len(batches) == 2 {
+		fetch := spans["HyperliquidClient.FetchTradesInRange"][0]
+		if batches[0].Parent().SpanID() != fetch.SpanContext().SpanID() {
+			t.Error("Expected batch spans to be children of the fetch span")
+		}
+		if batches[0].Status().Code == codes.Error || batches[1].Status().Code != codes.Error {
+			t.Errorf("Expected only the second batch to fail, got %v and %v", batches[0].Status(), batches[1].Status())
+		}
+	}
+	if refresh := spans["ReconciliationService.FetchAndReconcile"]; len(refresh) == 1 && refresh[0].Status().Code != codes.Error {
+		t.Error("Expected the partial refresh to be recorded as an error")
+	}
+}
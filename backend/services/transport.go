@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// upstreamTransport is the transport Hyperliquid clients use unless another is
+// set with UseHyperliquidTransport. It is shared by every client, so the
+// connections and TLS sessions of one pagination run are reused by the next.
+// Requests go through the proxy set with UseHyperliquidProxy, or else the one
+// named by HTTPS_PROXY, if set.
+var upstreamTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	// A custom TLS config turns off HTTP/2 unless it is asked for
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          config.UpstreamMaxIdleConns,
+	MaxIdleConnsPerHost:   config.UpstreamMaxIdleConnsPerHost,
+	IdleConnTimeout:       config.UpstreamIdleConnTimeout,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: time.Second,
+	TLSClientConfig: &tls.Config{
+		ClientSessionCache: tls.NewLRUClientSessionCache(config.UpstreamTLSSessionCacheSize),
+	},
+}
+
+// upstreamHeaders are sent with the requests of Hyperliquid clients created
+// afterwards; see UseHyperliquidHeaders
+var upstreamHeaders = http.Header{}
+
+// UseHyperliquidProxy sends Hyperliquid requests through the proxy at proxyURL,
+// an http, https or socks5 URL that may carry credentials
+func UseHyperliquidProxy(proxyURL string) error {
+	u, err := parseProxyURL(proxyURL)
+	if err != nil {
+		return err
+	}
+	upstreamTransport.Proxy = http.ProxyURL(u)
+	log.Printf("Sending Hyperliquid requests through proxy %s", u.Redacted())
+	return nil
+}
+
+// parseProxyURL parses and checks a proxy URL. It may hold a password, so it
+// is only ever shown redacted.
+func parseProxyURL(proxyURL string) (*url.URL, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, errors.New("invalid proxy URL: want http://, https:// or socks5://host:port")
+	}
+	if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+		return nil, fmt.Errorf("invalid proxy URL %q: want http://, https:// or socks5://host:port", u.Redacted())
+	}
+	return u, nil
+}
+
+// UseHyperliquidHeaders sets the User-Agent (if userAgent isn't empty) and the
+// extra headers, given as "Name: value" pairs separated by semicolons, that
+// Hyperliquid clients created afterwards send with every request
+func UseHyperliquidHeaders(userAgent, headers string) error {
+	parsed := http.Header{}
+	for _, pair := range strings.Split(headers, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || strings.ContainsAny(name, " \t\r\n") || strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("invalid header %q: want Name: value", strings.TrimSpace(pair))
+		}
+		parsed.Add(name, value)
+	}
+	if userAgent != "" {
+		parsed.Set("User-Agent", userAgent)
+	}
+
+	upstreamHeaders = parsed
+	return nil
+}
+
+// upstreamStats counts Hyperliquid requests and how their connections were obtained
+var upstreamStats struct {
+	requests, newConns, reusedConns, tlsHandshakes, tlsResumed, http2, cachedResponses atomic.Int64
+}
+
+// connTrace records whether each request reused a connection and whether its
+// TLS handshake resumed a session
+var connTrace = &httptrace.ClientTrace{
+	GotConn: func(info httptrace.GotConnInfo) {
+		if info.Reused {
+			upstreamStats.reusedConns.Add(1)
+		} else {
+			upstreamStats.newConns.Add(1)
+		}
+	},
+	TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+		if err != nil {
+			return
+		}
+		upstreamStats.tlsHandshakes.Add(1)
+		if state.DidResume {
+			upstreamStats.tlsResumed.Add(1)
+		}
+	},
+}
+
+// withConnTrace returns ctx with the connection counters attached
+func withConnTrace(ctx context.Context) context.Context {
+	upstreamStats.requests.Add(1)
+	return httptrace.WithClientTrace(ctx, connTrace)
+}
+
+// countResponse records the protocol of a Hyperliquid response
+func countResponse(resp *http.Response) {
+	if resp.ProtoMajor == 2 {
+		upstreamStats.http2.Add(1)
+	}
+}
+
+// UpstreamStats returns the Hyperliquid request and connection counters
+func UpstreamStats() models.UpstreamStats {
+	stats := models.UpstreamStats{
+		Requests:          upstreamStats.requests.Load(),
+		NewConnections:    upstreamStats.newConns.Load(),
+		ReusedConnections: upstreamStats.reusedConns.Load(),
+		TLSHandshakes:     upstreamStats.tlsHandshakes.Load(),
+		TLSResumed:        upstreamStats.tlsResumed.Load(),
+		HTTP2Responses:    upstreamStats.http2.Load(),
+		CachedResponses:   upstreamStats.cachedResponses.Load(),
+		ClockSkewMs:       upstreamClock.Skew().Milliseconds(),
+		PacingDelayMs:     upstreamPacer.Delay().Milliseconds(),
+		PacingBackoffs:    upstreamPacer.Backoffs(),
+	}
+	if conns := stats.NewConnections + stats.ReusedConnections; conns > 0 {
+		stats.ReuseRate = float64(stats.ReusedConnections) / float64(conns)
+	}
+	return stats
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test that consecutive batches reuse the upstream connection
+func TestUpstreamConnectionReuse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	client := NewHyperliquidClient()
+	client.apiURL = server.URL
+	before := UpstreamStats()
+
+	ctx := WithFreshResponses(context.Background())
+	for i := 0; i < 5; i++ {
+		if _, err := client.fetchBatch(ctx, i+1, "0xabc", 0, 1); err != nil {
+			t.Fatalf("Batch %d failed: %v", i+1, err)
+		}
+	}
+
+	// Requests left running by other tests may add to the counters
+	after := UpstreamStats()
+	if requests := after.Requests - before.Requests; requests < 5 {
+		t.Errorf("Expected 5 requests counted, got %d", requests)
+	}
+	if reused := after.ReusedConnections - before.ReusedConnections; reused < 4 {
+		t.Errorf("Expected 4 reused connections, got %d", reused)
+	}
+	if after.ReuseRate <= 0 {
+		t.Errorf("Expected a reuse rate, got %v", after.ReuseRate)
+	}
+}
+
+// Test sending Hyperliquid requests through a proxy with configured headers
+func TestUpstreamProxyAndHeaders(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+		w.Write([]byte("[]"))
+	}))
+	defer proxy.Close()
+
+	defer func(previous http.Header) { upstreamHeaders = previous }(upstreamHeaders)
+	if err := UseHyperliquidHeaders("recon-bot/1.0", "X-Team: desk; X-Env: prod"); err != nil {
+		t.Fatalf("Expected headers to parse, got %v", err)
+	}
+	proxyURL, err := parseProxyURL(proxy.URL)
+	if err != nil {
+		t.Fatalf("Expected proxy URL to parse, got %v", err)
+	}
+
+	client := NewHyperliquidClient()
+	client.httpClient = &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	client.apiURL = "http://api.hyperliquid.invalid/info"
+	if _, err := client.fetchBatch(WithFreshResponses(context.Background()), 1, "0xabc", 0, 1); err != nil {
+		t.Fatalf("Expected the request to go through the proxy, got %v", err)
+	}
+
+	r := <-received
+	if r.Host != "api.hyperliquid.invalid" {
+		t.Errorf("Expected the proxy to receive the API request, got host %q", r.Host)
+	}
+	if r.UserAgent() != "recon-bot/1.0" || r.Header.Get("X-Team") != "desk" || r.Header.Get("X-Env") != "prod" {
+		t.Errorf("Expected configured headers, got %v", r.Header)
+	}
+
+	t.Run("should reject invalid settings", func(t *testing.T) {
+		if err := UseHyperliquidHeaders("", "X-Team desk"); err == nil {
+			t.Error("Expected a header without a colon to be rejected")
+		}
+		if _, err := parseProxyURL("ftp://proxy:21"); err == nil {
+			t.Error("Expected an ftp proxy to be rejected")
+		}
+		if _, err := parseProxyURL("http://user:secret@"); err == nil || strings.Contains(err.Error(), "secret") {
+			t.Errorf("Expected a redacted error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// upstreamHealth tracks whether the Hyperliquid API is answering, from the
+// status probe and every other request
+var upstreamHealth = &healthTracker{slow: config.UpstreamSlowLatency, downAfter: config.UpstreamDownAfter}
+
+// healthTracker keeps the outcome of the latest requests to an API
+type healthTracker struct {
+	slow        time.Duration // Latency above which the API is degraded
+	downAfter   int           // Consecutive failures after which the API is down
+	lastCheck   time.Time
+	lastSuccess time.Time
+	latency     time.Duration
+	failures    int // Consecutive
+	lastError   string
+	mu          sync.Mutex
+}
+
+// observe records the outcome of a request that took latency, logging when
+// the API's state changes
+func (h *healthTracker) observe(latency time.Duration, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	before := h.state()
+	h.lastCheck = time.Now()
+	h.latency = latency
+	if err != nil {
+		h.failures++
+		h.lastError = err.Error()
+	} else {
+		h.failures = 0
+		h.lastError = ""
+		h.lastSuccess = h.lastCheck
+	}
+
+	if after := h.state(); after != before {
+		switch after {
+		case models.UpstreamOK:
+			log.Printf("Hyperliquid API is answering normally again")
+		case models.UpstreamDegraded:
+			if err != nil {
+				log.Printf("Hyperliquid API is degraded: %v", err)
+			} else {
+				log.Printf("Hyperliquid API is degraded: request took %s", latency.Round(time.Millisecond))
+			}
+		case models.UpstreamDown:
+			log.Printf("Hyperliquid API is down after %d failed requests: %v", h.failures, err)
+		}
+	}
+}
+
+// state returns the API's state from the latest requests. Caller must hold h.mu.
+func (h *healthTracker) state() models.UpstreamState {
+	switch {
+	case h.lastCheck.IsZero():
+		return models.UpstreamUnknown
+	case h.failures >= h.downAfter:
+		return models.UpstreamDown
+	case h.failures > 0 || h.latency > h.slow:
+		return models.UpstreamDegraded
+	default:
+		return models.UpstreamOK
+	}
+}
+
+// Status returns the API's health
+func (h *healthTracker) Status() models.UpstreamStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := models.UpstreamStatus{
+		State:               h.state(),
+		LatencyMs:           h.latency.Milliseconds(),
+		ConsecutiveFailures: h.failures,
+		LastError:           h.lastError,
+	}
+	if !h.lastCheck.IsZero() {
+		lastCheck := h.lastCheck
+		status.LastCheckAt = &lastCheck
+	}
+	if !h.lastSuccess.IsZero() {
+		lastSuccess := h.lastSuccess
+		status.LastSuccessAt = &lastSuccess
+	}
+	return status
+}
+
+// Degraded reports whether the API is degraded or down, so data fetched from
+// it may be stale
+func (h *healthTracker) Degraded() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state := h.state()
+	return state == models.UpstreamDegraded || state == models.UpstreamDown
+}
+
+// ProbeUpstream sends the exchange's cheap meta request; its outcome is
+// recorded in the upstream status like any other request's
+func ProbeUpstream(ctx context.Context) error {
+	var meta struct {
+		Universe []json.RawMessage `json:"universe"`
+	}
+	return NewHyperliquidClient().postInfo(ctx, map[string]string{"type": "meta"}, &meta)
+}
+
+// RunUpstreamProbe probes the Hyperliquid API every interval, so its status
+// stays current while no fetches are running
+func RunUpstreamProbe(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), config.APITimeout)
+		ProbeUpstream(ctx)
+		cancel()
+		<-ticker.C
+	}
+}
+
+// Status returns the Hyperliquid API's health and when each cached address was
+// last fetched successfully, as of now
+func (rs *ReconciliationService) Status(now time.Time) models.ServiceStatus {
+	return models.ServiceStatus{
+		Degraded:  upstreamHealth.Degraded(),
+		Upstream:  upstreamHealth.Status(),
+		Addresses: rs.FetchStatus(now),
+		CheckedAt: now,
+	}
+}
+
+// FetchStatus returns when each cached address was last fetched successfully, as of now
+func (rs *ReconciliationService) FetchStatus(now time.Time) []models.AddressFetchStatus {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	statuses := make([]models.AddressFetchStatus, 0, len(rs.accountCache))
+	for address, cache := range rs.accountCache {
+		age := now.Sub(cache.lastFetchTime)
+		statuses = append(statuses, models.AddressFetchStatus{
+			Address:     address,
+			Label:       rs.addressBook.Label(address),
+			LastFetchAt: cache.lastFetchTime,
+			AgeSeconds:  int64(age / time.Second),
+			Stale:       age > config.FetchStaleAfter,
+			Imported:    cache.imported,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Address < statuses[j].Address })
+	return statuses
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"hyperliquid-recon/models"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Test tracking the Hyperliquid API's health from request outcomes
+func TestHealthTracker(t *testing.T) {
+	t.Run("should move from ok to degraded to down and back", func(t *testing.T) {
+		h := &healthTracker{slow: time.Second, downAfter: 3}
+		if state := h.Status().State; state != models.UpstreamUnknown || h.Degraded() {
+			t.Fatalf("Expected unknown before any request, got %s", state)
+		}
+
+		h.observe(100*time.Millisecond, nil)
+		if state := h.Status().State; state != models.UpstreamOK {
+			t.Errorf("Expected ok, got %s", state)
+		}
+
+		h.observe(2*time.Second, nil)
+		if state := h.Status().State; state != models.UpstreamDegraded || !h.Degraded() {
+			t.Errorf("Expected a slow response to degrade, got %s", state)
+		}
+
+		for i := 0; i < 3; i++ {
+			h.observe(time.Millisecond, errors.New("connection refused"))
+		}
+		status := h.Status()
+		if status.State != models.UpstreamDown || status.ConsecutiveFailures != 3 || status.LastError != "connection refused" {
+			t.Errorf("Expected down after 3 failures, got %+v", status)
+		}
+		if status.LastSuccessAt == nil {
+			t.Error("Expected the last success to be kept")
+		}
+
+		h.observe(time.Millisecond, nil)
+		if status := h.Status(); status.State != models.UpstreamOK || status.LastError != "" {
+			t.Errorf("Expected ok after a success, got %+v", status)
+		}
+	})
+
+	t.Run("should record the probe and mark summaries degraded", func(t *testing.T) {
+		// Other tests' requests are recorded too, so start from a clean slate
+		upstreamHealth.mu.Lock()
+		upstreamHealth.lastCheck, upstreamHealth.failures, upstreamHealth.latency = time.Time{}, 0, 0
+		upstreamHealth.mu.Unlock()
+
+		UseHyperliquidTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return newResponse(req, http.StatusBadGateway, jsonHeader(), []byte(`"bad gateway"`)), nil
+		}))
+		defer UseHyperliquidTransport(nil)
+
+		if err := ProbeUpstream(context.Background()); err == nil {
+			t.Fatal("Expected the probe to fail")
+		}
+		rs := NewReconciliationService()
+		if !rs.GetPnLSummary().Degraded || !rs.Status(time.Now()).Degraded {
+			t.Error("Expected a failed probe to mark the summary and status degraded")
+		}
+
+		fills, _ := NewSyntheticFills([]string{"BTC"}, 0.03, 4, 1)
+		UseHyperliquidTransport(fills)
+		if err := ProbeUpstream(context.Background()); err != nil {
+			t.Fatalf("Expected the probe to succeed, got %v", err)
+		}
+		if rs.GetPnLSummary().Degraded {
+			t.Error("Expected a successful probe to clear the degraded flag")
+		}
+	})
+}
+
+// Test reporting when each address was last fetched
+func TestFetchStatus(t *testing.T) {
+	now := time.Now()
+	rs := NewReconciliationService()
+	rs.accountCache["0xa"] = &AccountCache{lastFetchTime: now.Add(-time.Minute)}
+	rs.accountCache["0xb"] = &AccountCache{lastFetchTime: now.Add(-2 * time.Hour), imported: true}
+
+	statuses := rs.FetchStatus(now)
+	if len(statuses) != 2 || statuses[0].Address != "0xa" {
+		t.Fatalf("Expected both addresses in order, got %+v", statuses)
+	}
+	if statuses[0].Stale || statuses[0].AgeSeconds != 60 {
+		t.Errorf("Expected a fresh fetch a minute old, got %+v", statuses[0])
+	}
+	if !statuses[1].Stale || !statuses[1].Imported {
+		t.Errorf("Expected a stale imported address, got %+v", statuses[1])
+	}
+}
+
+// roundTripFunc answers requests with a function
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hyperliquid-recon/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// vaultEquityResponse is an address's equity in one vault from the API
+type vaultEquityResponse struct {
+	VaultAddress string `json:"vaultAddress"`
+	Equity       string `json:"equity"`
+}
+
+// FetchVaultEquities fetches the current equity of address in each vault it
+// has deposited in, such as HLP
+func (c *HyperliquidClient) FetchVaultEquities(ctx context.Context, address string) (values []models.VaultValue, err error) {
+	ctx, span := tracer.Start(ctx, "HyperliquidClient.FetchVaultEquities", trace.WithAttributes(attribute.String("address", address)))
+	defer func() { endSpan(span, err) }()
+
+	var equities []vaultEquityResponse
+	if err := c.postInfo(ctx, portfolioRequest{Type: "userVaultEquities", User: address}, &equities); err != nil {
+		return nil, fmt.Errorf("failed to fetch vault equities: %w", err)
+	}
+
+	now := time.Now()
+	for _, equity := range equities {
+		parsed, err := strconv.ParseFloat(equity.Equity, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse vault equity '%s': %w", equity.Equity, err)
+		}
+		values = append(values, models.VaultValue{Time: now, Vault: strings.ToLower(equity.VaultAddress), Equity: parsed})
+	}
+	return values, nil
+}
+
+// delegatorHistoryResponse is one staking action from the API. Delta has one
+// of its fields set, e.g. {"delegate": {"validator": "0x...", "amount": "10.0", "isUndelegate": false}}.
+type delegatorHistoryResponse struct {
+	Time  int64  `json:"time"`
+	Hash  string `json:"hash"`
+	Delta struct {
+		Delegate *struct {
+			Validator    string `json:"validator"`
+			Amount       string `json:"amount"`
+			IsUndelegate bool   `json:"isUndelegate"`
+		} `json:"delegate"`
+		Deposit *struct {
+			Amount string `json:"amount"`
+		} `json:"cDeposit"`
+		Withdrawal *struct {
+			Amount string `json:"amount"`
+		} `json:"withdrawal"`
+	} `json:"delta"`
+}
+
+// FetchStakingHistory fetches the staking deposits, withdrawals and
+// delegations of address, oldest first. Actions of other kinds are skipped.
+func (c *HyperliquidClient) FetchStakingHistory(ctx context.Context, address string) (actions []models.StakingAction, err error) {
+	ctx, span := tracer.Start(ctx, "HyperliquidClient.FetchStakingHistory", trace.WithAttributes(attribute.String("address", address)))
+	defer func() { endSpan(span, err) }()
+
+	var history []delegatorHistoryResponse
+	if err := c.postInfo(ctx, portfolioRequest{Type: "delegatorHistory", User: address}, &history); err != nil {
+		return nil, fmt.Errorf("failed to fetch staking history: %w", err)
+	}
+
+	for _, item := range history {
+		action := models.StakingAction{Time: time.UnixMilli(item.Time), Hash: item.Hash}
+		var amount string
+		switch delta := item.Delta; {
+		case delta.Delegate != nil:
+			action.Kind, action.Validator, amount = models.StakingDelegate, strings.ToLower(delta.Delegate.Validator), delta.Delegate.Amount
+			if delta.Delegate.IsUndelegate {
+				action.Kind = models.StakingUndelegate
+			}
+		case delta.Deposit != nil:
+			action.Kind, amount = models.StakingDeposit, delta.Deposit.Amount
+		case delta.Withdrawal != nil:
+			action.Kind, amount = models.StakingWithdrawal, delta.Withdrawal.Amount
+		default:
+			continue
+		}
+		if action.Amount, err = strconv.ParseFloat(amount, 64); err != nil {
+			return nil, fmt.Errorf("failed to parse staking amount '%s': %w", amount, err)
+		}
+		actions = append(actions, action)
+	}
+	sort.SliceStable(actions, func(i, j int) bool { return actions[i].Time.Before(actions[j].Time) })
+	return actions, nil
+}
+
+// syncVaults records the current vault equities and the staking history of
+// account. A vault's equity is recorded at most once per hour; the latest
+// value of the hour replaces earlier ones. A vault no longer listed is
+// recorded as empty. Caller must hold l.mu.
+func (l *Ledger) syncVaults(ctx context.Context, account *addressLedger) error {
+	values, err := l.hlClient.FetchVaultEquities(ctx, account.Address)
+	if err != nil {
+		return err
+	}
+	staking, err := l.hlClient.FetchStakingHistory(ctx, account.Address)
+	if err != nil {
+		return err
+	}
+	account.Staking = staking
+
+	latest := latestVaultValues(account.VaultValues)
+	listed := make(map[string]bool, len(values))
+	for _, value := range values {
+		listed[value.Vault] = true
+	}
+	now := time.Now()
+	for vault, value := range latest {
+		if !listed[vault] && value.Equity != 0 {
+			values = append(values, models.VaultValue{Time: now, Vault: vault})
+		}
+	}
+
+	for _, value := range values {
+		last, exists := latest[value.Vault]
+		if exists && last.Time.Truncate(time.Hour).Equal(value.Time.Truncate(time.Hour)) {
+			for i := len(account.VaultValues) - 1; i >= 0; i-- {
+				if account.VaultValues[i].Vault == value.Vault {
+					account.VaultValues[i] = value
+					break
+				}
+			}
+			continue
+		}
+		account.VaultValues = append(account.VaultValues, value)
+	}
+	return nil
+}
+
+// latestVaultValues returns the last recorded value of each vault in values
+func latestVaultValues(values []models.VaultValue) map[string]models.VaultValue {
+	latest := make(map[string]models.VaultValue)
+	for _, value := range values {
+		latest[value.Vault] = value
+	}
+	return latest
+}
+
+// Vaults returns the vault positions of address as of its last sync, with
+// the vaults' value history and its staking actions. It reports false if
+// address never had a vault deposit or staking action.
+func (l *Ledger) Vaults(address string) (models.VaultSummary, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	account, exists := l.accounts[address]
+	if !exists {
+		return models.VaultSummary{}, false
+	}
+
+	byVault := make(map[string]*models.VaultPosition)
+	position := func(vault string) *models.VaultPosition {
+		if _, exists := byVault[vault]; !exists {
+			byVault[vault] = &models.VaultPosition{Vault: vault}
+		}
+		return byVault[vault]
+	}
+	for _, entry := range account.Entries {
+		if entry.Kind != EntryVaultDeposit && entry.Kind != EntryVaultWithdrawal {
+			continue
+		}
+		p := position(entry.Coin)
+		for _, posting := range entry.Postings {
+			switch {
+			case strings.HasPrefix(posting.Account, AccountVaults):
+				p.Cost += float64(posting.Amount)
+			case posting.Account == AccountVaultPnL:
+				p.RealizedPnL -= float64(posting.Amount)
+			}
+		}
+	}
+	for vault, value := range latestVaultValues(account.VaultValues) {
+		p := position(vault)
+		p.Equity, p.ValuedAt = value.Equity, value.Time
+	}
+	if len(byVault) == 0 && len(account.Staking) == 0 {
+		return models.VaultSummary{}, false
+	}
+
+	summary := models.VaultSummary{
+		Address: address,
+		Vaults:  make([]models.VaultPosition, 0, len(byVault)),
+		History: append([]models.VaultValue{}, account.VaultValues...),
+		Staking: append([]models.StakingAction{}, account.Staking...),
+	}
+	for _, p := range byVault {
+		p.UnrealizedPnL = p.Equity - p.Cost
+		p.PnL = p.RealizedPnL + p.UnrealizedPnL
+		summary.Vaults = append(summary.Vaults, *p)
+		summary.Equity += p.Equity
+		summary.PnL += p.PnL
+	}
+	sort.Slice(summary.Vaults, func(i, j int) bool { return summary.Vaults[i].Vault < summary.Vaults[j].Vault })
+
+	for _, action := range account.Staking {
+		switch action.Kind {
+		case models.StakingDeposit:
+			summary.Staked += action.Amount
+		case models.StakingWithdrawal:
+			summary.Staked -= action.Amount
+		}
+	}
+	return summary, true
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testVault = "0xdfc24b077bc1425ad1dea75bcb6f8158e10df303"
+
+// newTestVaultServer serves a vault deposit and withdrawal, the vault equity
+// equities returns, and staking actions
+func newTestVaultServer(t *testing.T, base time.Time, equities *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req UserFillsRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		body := `[]`
+		switch req.Type {
+		case LedgerUpdatesFunding:
+		case LedgerUpdatesNonFunding:
+			body = fmt.Sprintf(`[
+				{"time": %d, "hash": "0x01", "delta": {"type": "vaultDeposit", "vault": "0xDFC24B077BC1425AD1DEA75BCB6F8158E10DF303", "usdc": "1000"}},
+				{"time": %d, "hash": "0x02", "delta": {"type": "vaultWithdraw", "vault": "%s", "requestedUsd": "440", "commission": "0", "closingCost": "0", "basis": "400", "netWithdrawnUsd": "440"}},
+				{"time": %[2]d, "hash": "0x03", "delta": {"type": "cDeposit", "amount": "5"}}
+			]`, base.UnixMilli(), base.Add(time.Hour).UnixMilli(), testVault)
+		case "referral":
+			body = `{}`
+		case "userVaultEquities":
+			body = *equities
+		case "delegatorHistory":
+			body = fmt.Sprintf(`[
+				{"time": %d, "hash": "0x05", "delta": {"delegate": {"validator": "0xV", "amount": "5", "isUndelegate": false}}},
+				{"time": %d, "hash": "0x03", "delta": {"cDeposit": {"amount": "5"}}},
+				{"time": %[1]d, "hash": "0x06", "delta": {"withdrawal": {"amount": "1", "phase": "initiated"}}}
+			]`, base.Add(2*time.Hour).UnixMilli(), base.Add(time.Hour).UnixMilli())
+		default:
+			t.Errorf("Unexpected request type %q", req.Type)
+		}
+		w.Write([]byte(body))
+	}))
+}
+
+// Test vault deposits, withdrawals, value history and staking actions
+func TestVaults(t *testing.T) {
+	base := time.Now().Add(-3 * time.Hour)
+	equities := fmt.Sprintf(`[{"vaultAddress": "%s", "equity": "650"}]`, testVault)
+	server := newTestVaultServer(t, base, &equities)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	ledger, _ := NewLedger(path)
+	ledger.hlClient.apiURL = server.URL
+
+	if _, exists := ledger.Vaults(testAddress); exists {
+		t.Fatal("Expected no vaults before a sync")
+	}
+	if err := ledger.Sync(context.Background(), testAddress, base.Add(-time.Hour)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	t.Run("should post vault transfers and realize P&L on withdrawals", func(t *testing.T) {
+		balance := BuildTrialBalance(testAddress, ledger.Entries(testAddress, nil, "", ""), "", "")
+		if !balance.Balanced || balance.Entries != 2 {
+			t.Fatalf("Expected 2 balanced entries, got %+v", balance)
+		}
+		if balance.VaultPnL != 40 || balance.NetPnL != 0 {
+			t.Errorf("Expected 40 realized vault P&L outside net P&L, got %v and %v", balance.VaultPnL, balance.NetPnL)
+		}
+	})
+
+	t.Run("should value vault positions and total staking", func(t *testing.T) {
+		summary, exists := ledger.Vaults(testAddress)
+		if !exists || len(summary.Vaults) != 1 {
+			t.Fatalf("Expected one vault, got %+v", summary)
+		}
+		vault := summary.Vaults[0]
+		if vault.Vault != testVault || vault.Cost != 600 || vault.Equity != 650 || vault.RealizedPnL != 40 || vault.UnrealizedPnL != 50 || summary.PnL != 90 {
+			t.Errorf("Unexpected vault position %+v", vault)
+		}
+		if len(summary.Staking) != 3 || summary.Staking[0].Kind != "deposit" || summary.Staking[1].Validator != "0xv" || math.Abs(summary.Staked-4) > 1e-9 {
+			t.Errorf("Unexpected staking %+v (staked %v)", summary.Staking, summary.Staked)
+		}
+	})
+
+	t.Run("should record one value per hour and empty vaults once withdrawn", func(t *testing.T) {
+		equities = fmt.Sprintf(`[{"vaultAddress": "%s", "equity": "660"}]`, testVault)
+		ledger.Sync(context.Background(), testAddress, base)
+		if summary, _ := ledger.Vaults(testAddress); len(summary.History) != 1 || summary.History[0].Equity != 660 {
+			t.Errorf("Expected the hour's value to be replaced, got %+v", summary.History)
+		}
+
+		equities = `[]`
+		ledger.mu.Lock()
+		ledger.accounts[testAddress].VaultValues[0].Time = base
+		ledger.mu.Unlock()
+		ledger.Sync(context.Background(), testAddress, base)
+
+		reloaded, _ := NewLedger(path)
+		summary, _ := reloaded.Vaults(testAddress)
+		if len(summary.History) != 2 || summary.Equity != 0 || summary.Vaults[0].UnrealizedPnL != -600 {
+			t.Errorf("Expected the withdrawn vault recorded as empty, got %+v", summary)
+		}
+	})
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperliquid-recon/models"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrViewNotFound is returned for an unknown view name
+	ErrViewNotFound = errors.New("view not found")
+	// ErrInvalidView is returned for a view whose name or parameters are invalid
+	ErrInvalidView = errors.New("invalid view")
+)
+
+// ViewStore keeps a tenant's saved views, persisted as JSON to path if one is set
+type ViewStore struct {
+	views map[string]*models.View // key: name
+	mu    sync.RWMutex
+	path  string
+}
+
+// NewViewStore creates a view store, loading saved views from path if it exists
+func NewViewStore(path string) (*ViewStore, error) {
+	s := &ViewStore{views: make(map[string]*models.View), path: path}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := readStoreFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read views: %w", err)
+	}
+	var views []models.View
+	if err := json.Unmarshal(data, &views); err != nil {
+		return nil, fmt.Errorf("failed to parse views: %w", err)
+	}
+	for i := range views {
+		s.views[views[i].Name] = &views[i]
+	}
+	return s, nil
+}
+
+// ValidateView checks a view's name and parameters and normalizes its
+// addresses, coins and grouping. Addresses must already be resolved.
+func ValidateView(view *models.View) error {
+	if !templateNamePattern.MatchString(view.Name) {
+		return fmt.Errorf("%w: view names are up to 64 letters, digits, '.', '_' or '-'", ErrInvalidView)
+	}
+	for i, address := range view.Addresses {
+		view.Addresses[i] = strings.ToLower(address)
+		if !addressPattern.MatchString(view.Addresses[i]) {
+			return fmt.Errorf("%w: %q is not a valid address", ErrInvalidView, address)
+		}
+	}
+	for i, coin := range view.Coins {
+		view.Coins[i] = strings.TrimSpace(coin)
+		if view.Coins[i] == "" {
+			return fmt.Errorf("%w: coins must not be empty", ErrInvalidView)
+		}
+	}
+
+	var from, to time.Time
+	for _, date := range []struct {
+		name  string
+		value string
+		t     *time.Time
+	}{{"from", view.From, &from}, {"to", view.To, &to}} {
+		if date.value == "" {
+			continue
+		}
+		parsed, err := time.Parse("2006-01-02", date.value)
+		if err != nil {
+			return fmt.Errorf("%w: %s must be a YYYY-MM-DD date", ErrInvalidView, date.name)
+		}
+		*date.t = parsed
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return fmt.Errorf("%w: to is before from", ErrInvalidView)
+	}
+	if view.Days < 0 || (view.Days > 0 && (view.From != "" || view.To != "")) {
+		return fmt.Errorf("%w: days must be positive and can't be combined with from and to", ErrInvalidView)
+	}
+
+	view.Grouping = strings.ToLower(view.Grouping)
+	switch view.Grouping {
+	case "", "day", "week", "month":
+	default:
+		return fmt.Errorf("%w: grouping must be day, week or month", ErrInvalidView)
+	}
+	if view.DayBasis != "" {
+		if _, err := ParseDayBasis(string(view.DayBasis)); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidView, err)
+		}
+	}
+	view.Currency = strings.ToUpper(view.Currency)
+	if view.Currency != "" && view.Currency != "USD" && view.Currency != "USDC" {
+		return fmt.Errorf("%w: reports are in USDC, so currency must be USD or USDC", ErrInvalidView)
+	}
+	return nil
+}
+
+// ViewQuery returns the request parameters a view stands for: every address
+// and coin (endpoints that take one use the first), from, to, days, the
+// grouping as period and the day basis as day
+func ViewQuery(view models.View) url.Values {
+	query := url.Values{}
+	for _, address := range view.Addresses {
+		query.Add("address", address)
+	}
+	for _, coin := range view.Coins {
+		query.Add("coin", coin)
+	}
+	if view.From != "" {
+		query.Set("from", view.From)
+	}
+	if view.To != "" {
+		query.Set("to", view.To)
+	}
+	if view.Days > 0 {
+		query.Set("days", strconv.Itoa(view.Days))
+	}
+	if view.Grouping != "" && view.Grouping != "day" {
+		query.Set("period", view.Grouping)
+	}
+	if view.DayBasis != "" {
+		query.Set("day", string(view.DayBasis))
+	}
+	return query
+}
+
+// Save creates or replaces the view with view's name. It returns
+// ErrInvalidView if the name or parameters are invalid.
+func (s *ViewStore) Save(view models.View) (models.View, error) {
+	if err := ValidateView(&view); err != nil {
+		return models.View{}, err
+	}
+	view.UpdatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.views[view.Name] = &view
+	return view, s.persist()
+}
+
+// Get returns the view with the given name
+func (s *ViewStore) Get(name string) (models.View, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	view, exists := s.views[name]
+	if !exists {
+		return models.View{}, ErrViewNotFound
+	}
+	return *view, nil
+}
+
+// List returns all views sorted by name
+func (s *ViewStore) List() []models.View {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	views := make([]models.View, 0, len(s.views))
+	for _, view := range s.views {
+		views = append(views, *view)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views
+}
+
+// Delete removes a view and reports whether it existed
+func (s *ViewStore) Delete(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.views[name]; !exists {
+		return false, nil
+	}
+	delete(s.views, name)
+	return true, s.persist()
+}
+
+// persist writes all views to the views file; caller must hold s.mu
+func (s *ViewStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	views := make([]models.View, 0, len(s.views))
+	for _, view := range s.views {
+		views = append(views, *view)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return writeJSONFile(s.path, views)
+}
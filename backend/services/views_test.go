@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"hyperliquid-recon/models"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test saving views and the parameters they stand for
+func TestViews(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "views.json")
+	store, _ := NewViewStore(path)
+
+	t.Run("should persist a view and expand it into parameters", func(t *testing.T) {
+		_, err := store.Save(models.View{
+			Name:      "marchReview",
+			Addresses: []string{strings.ToUpper(testAddress[:2]) + testAddress[2:]},
+			From:      "2025-03-01",
+			To:        "2025-03-31",
+			Coins:     []string{"ETH", "BTC"},
+			Grouping:  "Week",
+			DayBasis:  models.DayUTC,
+			Currency:  "usd",
+		})
+		if err != nil {
+			t.Fatalf("Expected the view to be saved, got %v", err)
+		}
+
+		reopened, _ := NewViewStore(path)
+		view, err := reopened.Get("marchReview")
+		if err != nil {
+			t.Fatalf("Expected the view to be persisted, got %v", err)
+		}
+		query := ViewQuery(view)
+		expected := map[string][]string{
+			"address": {testAddress},
+			"coin":    {"ETH", "BTC"},
+			"from":    {"2025-03-01"},
+			"to":      {"2025-03-31"},
+			"period":  {"week"},
+			"day":     {"utc"},
+		}
+		if !reflect.DeepEqual(map[string][]string(query), expected) {
+			t.Errorf("Expected %v, got %v", expected, query)
+		}
+	})
+
+	t.Run("should reject invalid views", func(t *testing.T) {
+		for _, view := range []models.View{
+			{Name: "../escape"},
+			{Name: "bad-address", Addresses: []string{"0x1"}},
+			{Name: "backwards", From: "2025-03-31", To: "2025-03-01"},
+			{Name: "both", From: "2025-03-01", Days: 30},
+			{Name: "hourly", Grouping: "hour"},
+			{Name: "euros", Currency: "EUR"},
+		} {
+			if _, err := store.Save(view); !errors.Is(err, ErrInvalidView) {
+				t.Errorf("Expected view %s to be rejected, got %v", view.Name, err)
+			}
+		}
+		if _, err := store.Get("euros"); !errors.Is(err, ErrViewNotFound) {
+			t.Errorf("Expected ErrViewNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hyperliquid-recon/config"
+	"hyperliquid-recon/models"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers set on every outbound webhook
+const (
+	SignatureHeader = "X-Recon-Signature" // HMAC-SHA256 of "<timestamp>.<body>"
+	TimestampHeader = "X-Recon-Timestamp" // Unix time the webhook was signed at
+	EventHeader     = "X-Recon-Event"
+	DeliveryHeader  = "X-Recon-Delivery"
+)
+
+// WebhookDispatcher delivers signed JSON webhooks from a queue. Failed deliveries
+// are retried with exponential backoff; deliveries that exhaust their attempts
+// are kept as dead letters for inspection.
+type WebhookDispatcher struct {
+	httpClient     *http.Client
+	secret         []byte
+	queue          chan *models.WebhookDelivery
+	deliveries     map[string]*models.WebhookDelivery // key: delivery ID
+	order          []string                           // delivery IDs, oldest first
+	mu             sync.RWMutex
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// NewWebhookDispatcher creates a dispatcher and starts its delivery workers.
+// If secret is empty, webhooks are sent unsigned.
+func NewWebhookDispatcher(secret string) *WebhookDispatcher {
+	d := &WebhookDispatcher{
+		httpClient:     &http.Client{Timeout: config.WebhookTimeout},
+		secret:         []byte(secret),
+		queue:          make(chan *models.WebhookDelivery, config.WebhookHistoryLimit),
+		deliveries:     make(map[string]*models.WebhookDelivery),
+		maxAttempts:    config.WebhookMaxAttempts,
+		initialBackoff: config.WebhookInitialBackoff,
+		maxBackoff:     config.WebhookMaxBackoff,
+	}
+
+	for i := 0; i < config.WebhookWorkers; i++ {
+		go d.worker()
+	}
+
+	return d
+}
+
+// Enqueue queues payload for delivery to url as the given event type and
+// returns the delivery record
+func (d *WebhookDispatcher) Enqueue(url, event string, payload interface{}) (models.WebhookDelivery, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return models.WebhookDelivery{}, fmt.Errorf("failed to marshal webhook: %w", err)
+	}
+
+	delivery := &models.WebhookDelivery{
+		ID:        newID(),
+		URL:       url,
+		Event:     event,
+		Status:    models.DeliveryPending,
+		CreatedAt: time.Now(),
+		Payload:   body,
+	}
+
+	d.mu.Lock()
+	d.deliveries[delivery.ID] = delivery
+	d.order = append(d.order, delivery.ID)
+	d.pruneHistory()
+	queued := *delivery
+	d.mu.Unlock()
+
+	d.queue <- delivery
+
+	return queued, nil
+}
+
+// Deliveries returns recorded deliveries, newest first. If status is set, only
+// deliveries in that state are returned.
+func (d *WebhookDispatcher) Deliveries(status models.DeliveryStatus) []models.WebhookDelivery {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := make([]models.WebhookDelivery, 0, len(d.deliveries))
+	for _, delivery := range d.deliveries {
+		if status == "" || delivery.Status == status {
+			result = append(result, *delivery)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+
+	return result
+}
+
+// worker delivers queued webhooks until the queue is closed
+func (d *WebhookDispatcher) worker() {
+	for delivery := range d.queue {
+		d.attempt(delivery)
+	}
+}
+
+// attempt makes one delivery attempt and schedules a retry or dead-letters the delivery on failure
+func (d *WebhookDispatcher) attempt(delivery *models.WebhookDelivery) {
+	statusCode, err := d.send(delivery)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.NextAttemptAt = nil
+
+	if err == nil {
+		delivery.Status = models.DeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.LastError = err.Error()
+
+	if delivery.Attempts >= d.maxAttempts {
+		delivery.Status = models.DeliveryDead
+		log.Printf("Webhook %s (%s) to %s dead after %d attempts: %v", delivery.ID, delivery.Event, delivery.URL, delivery.Attempts, err)
+		return
+	}
+
+	backoff := d.backoff(delivery.Attempts)
+	nextAttempt := now.Add(backoff)
+	delivery.Status = models.DeliveryRetrying
+	delivery.NextAttemptAt = &nextAttempt
+
+	log.Printf("Webhook %s to %s failed (attempt %d), retrying in %s: %v", delivery.ID, delivery.URL, delivery.Attempts, backoff, err)
+	time.AfterFunc(backoff, func() {
+		d.queue <- delivery
+	})
+}
+
+// send POSTs the delivery's payload and returns the response status code
+func (d *WebhookDispatcher) send(delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+
+	if len(d.secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, "sha256="+SignPayload(d.secret, timestamp, delivery.Payload))
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the retry following the given attempt number
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	delay := d.initialBackoff
+	for i := 1; i < attempts && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.maxBackoff {
+		delay = d.maxBackoff
+	}
+	return delay
+}
+
+// pruneHistory drops the oldest finished deliveries beyond the history limit; caller must hold d.mu
+func (d *WebhookDispatcher) pruneHistory() {
+	excess := len(d.order) - config.WebhookHistoryLimit
+	if excess <= 0 {
+		return
+	}
+
+	kept := d.order[:0]
+	for _, id := range d.order {
+		delivery := d.deliveries[id]
+		finished := delivery.Status == models.DeliveryDelivered || delivery.Status == models.DeliveryDead
+		if excess > 0 && finished {
+			delete(d.deliveries, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	d.order = kept
+}
+
+// SignPayload returns the hex HMAC-SHA256 of "<timestamp>.<body>" under secret
+func SignPayload(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForDelivery polls until the delivery reaches the given status
+func waitForDelivery(t *testing.T, d *WebhookDispatcher, id string, status models.DeliveryStatus) models.WebhookDelivery {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, delivery := range d.Deliveries(status) {
+			if delivery.ID == id {
+				return delivery
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Delivery %s did not reach status %s", id, status)
+	return models.WebhookDelivery{}
+}
+
+// Test WebhookDispatcher delivery and retries
+func TestWebhookDispatcher(t *testing.T) {
+	t.Run("should retry until delivered", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			if r.Header.Get(EventHeader) != "test.event" {
+				t.Errorf("Expected event header, got %q", r.Header.Get(EventHeader))
+			}
+		}))
+		defer server.Close()
+
+		d := NewWebhookDispatcher("secret")
+		d.initialBackoff = 10 * time.Millisecond
+
+		queued, err := d.Enqueue(server.URL, "test.event", map[string]string{"hello": "world"})
+		if err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+
+		delivery := waitForDelivery(t, d, queued.ID, models.DeliveryDelivered)
+		if delivery.Attempts != 3 {
+			t.Errorf("Expected 3 attempts, got %d", delivery.Attempts)
+		}
+		if delivery.DeliveredAt == nil || delivery.LastError != "" {
+			t.Errorf("Expected clean delivered state")
+		}
+	})
+
+	t.Run("should dead-letter after max attempts", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		d := NewWebhookDispatcher("")
+		d.initialBackoff = 10 * time.Millisecond
+		d.maxAttempts = 2
+
+		queued, _ := d.Enqueue(server.URL, "test.event", nil)
+
+		delivery := waitForDelivery(t, d, queued.ID, models.DeliveryDead)
+		if delivery.Attempts != 2 {
+			t.Errorf("Expected 2 attempts, got %d", delivery.Attempts)
+		}
+		if delivery.LastStatusCode != http.StatusInternalServerError || delivery.LastError == "" {
+			t.Errorf("Expected last failure to be recorded")
+		}
+	})
+}
+
+// Test backoff
+func TestWebhookBackoff(t *testing.T) {
+	d := &WebhookDispatcher{initialBackoff: time.Second, maxBackoff: 5 * time.Second}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := d.backoff(i + 1); got != want {
+			t.Errorf("Attempt %d: expected backoff %v, got %v", i+1, want, got)
+		}
+	}
+}
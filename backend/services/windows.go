@@ -0,0 +1,108 @@
+package services
+
+import (
+	"fmt"
+	"hyperliquid-recon/models"
+	"math"
+	"time"
+)
+
+// windowDays are the trailing periods summarized in every P&L summary
+var windowDays = []int{7, 30, 90}
+
+// performanceWindows summarizes records (newest first) over each trailing
+// period ending today. coverageStart is the start of the fetched data, or nil
+// if nothing has been fetched.
+func performanceWindows(records []models.DailyPnL, coverageStart *time.Time, now time.Time) []models.PerformanceWindow {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	windows := make([]models.PerformanceWindow, 0, len(windowDays))
+	for _, days := range windowDays {
+		start := today.AddDate(0, 0, 1-days)
+		window := models.PerformanceWindow{
+			Name:     fmt.Sprintf("%dd", days),
+			Days:     days,
+			Start:    start.Format("2006-01-02"),
+			Complete: coverageStart != nil && !coverageStart.After(start),
+		}
+
+		for _, record := range records {
+			if record.Date < window.Start {
+				break
+			}
+			window.PnL += float64(record.DailyPnL)
+			window.TradeCount += record.TradeCount
+			window.Volume += float64(record.Volume)
+			window.TradingDays++
+			if record.DailyPnL > 0 {
+				window.WinningDays++
+			}
+		}
+
+		if window.TradingDays > 0 {
+			window.WinRate = float64(window.WinningDays) / float64(window.TradingDays)
+			window.AvgDailyPnL = window.PnL / float64(window.TradingDays)
+		}
+		windows = append(windows, window)
+	}
+
+	return windows
+}
+
+// performanceStats computes win/loss statistics over records (newest first),
+// one outcome per trading day. A flat day ends both streaks.
+func performanceStats(records []models.DailyPnL) models.PerformanceStats {
+	stats := models.PerformanceStats{TradingDays: len(records)}
+	if len(records) == 0 {
+		return stats
+	}
+
+	var grossProfit, grossLoss, total float64
+	streak := 0
+	for i := len(records) - 1; i >= 0; i-- {
+		pnl := float64(records[i].DailyPnL)
+		total += pnl
+
+		switch {
+		case pnl > 0:
+			stats.WinningDays++
+			grossProfit += pnl
+			stats.LargestWin = math.Max(stats.LargestWin, pnl)
+			streak = max(streak, 0) + 1
+		case pnl < 0:
+			stats.LosingDays++
+			grossLoss -= pnl
+			stats.LargestLoss = math.Min(stats.LargestLoss, pnl)
+			streak = min(streak, 0) - 1
+		default:
+			streak = 0
+		}
+		stats.LongestWinStreak = max(stats.LongestWinStreak, streak)
+		stats.LongestLossStreak = max(stats.LongestLossStreak, -streak)
+	}
+	stats.CurrentStreak = streak
+
+	stats.WinRate = float64(stats.WinningDays) / float64(stats.TradingDays)
+	stats.Expectancy = total / float64(stats.TradingDays)
+	if stats.WinningDays > 0 {
+		stats.AvgWin = grossProfit / float64(stats.WinningDays)
+	}
+	if stats.LosingDays > 0 {
+		stats.AvgLoss = -grossLoss / float64(stats.LosingDays)
+	}
+
+	if grossLoss > 0 {
+		profitFactor := grossProfit / grossLoss
+		stats.ProfitFactor = &profitFactor
+	}
+	if stats.AvgLoss < 0 {
+		payoff := stats.AvgWin / -stats.AvgLoss
+		stats.PayoffRatio = &payoff
+		if payoff > 0 {
+			kelly := stats.WinRate - (1-stats.WinRate)/payoff
+			stats.Kelly = &kelly
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"hyperliquid-recon/models"
+	"math"
+	"testing"
+	"time"
+)
+
+// Test trailing performance windows
+func TestPerformanceWindows(t *testing.T) {
+	now := time.Date(2025, 7, 31, 15, 0, 0, 0, time.Local)
+	records := []models.DailyPnL{
+		{Date: "2025-07-30", TradeCount: 4, Volume: 1000, DailyPnL: 50},
+		{Date: "2025-07-25", TradeCount: 2, Volume: 500, DailyPnL: -20},
+		{Date: "2025-07-10", TradeCount: 1, Volume: 100, DailyPnL: 10},
+	}
+	coverageStart := now.AddDate(0, 0, -30)
+
+	windows := performanceWindows(records, &coverageStart, now)
+	if len(windows) != 3 {
+		t.Fatalf("Expected 3 windows, got %d", len(windows))
+	}
+
+	week := windows[0]
+	if week.Name != "7d" || week.Start != "2025-07-25" {
+		t.Errorf("Expected 7d window from 2025-07-25, got %s from %s", week.Name, week.Start)
+	}
+	if week.PnL != 30 || week.TradeCount != 6 || week.Volume != 1500 || week.TradingDays != 2 {
+		t.Errorf("Unexpected 7d totals %+v", week)
+	}
+	if week.WinRate != 0.5 || week.AvgDailyPnL != 15 {
+		t.Errorf("Expected 50%% win rate and 15 average, got %v and %v", week.WinRate, week.AvgDailyPnL)
+	}
+
+	if month := windows[1]; month.PnL != 40 || !month.Complete {
+		t.Errorf("Expected a complete 30d window with P&L 40, got %+v", month)
+	}
+	if quarter := windows[2]; quarter.Complete {
+		t.Error("Expected the 90d window to be incomplete beyond the coverage")
+	}
+}
+
+// Test day-level trading statistics
+func TestPerformanceStats(t *testing.T) {
+	// Newest first: oldest to newest the days are +100, +50, -30, -10, -20, +40
+	records := []models.DailyPnL{
+		{Date: "2025-07-06", DailyPnL: 40},
+		{Date: "2025-07-05", DailyPnL: -20},
+		{Date: "2025-07-04", DailyPnL: -10},
+		{Date: "2025-07-03", DailyPnL: -30},
+		{Date: "2025-07-02", DailyPnL: 50},
+		{Date: "2025-07-01", DailyPnL: 100},
+	}
+
+	stats := performanceStats(records)
+
+	if stats.WinningDays != 3 || stats.LosingDays != 3 || stats.WinRate != 0.5 {
+		t.Errorf("Unexpected day counts %+v", stats)
+	}
+	if stats.AvgWin != 190.0/3 || stats.AvgLoss != -20 || stats.LargestWin != 100 || stats.LargestLoss != -30 {
+		t.Errorf("Unexpected averages %+v", stats)
+	}
+	if stats.ProfitFactor == nil || *stats.ProfitFactor != 190.0/60 {
+		t.Errorf("Expected profit factor %v, got %v", 190.0/60, stats.ProfitFactor)
+	}
+	if stats.Expectancy != 130.0/6 {
+		t.Errorf("Expected expectancy %v, got %v", 130.0/6, stats.Expectancy)
+	}
+	if payoff := (190.0 / 3) / 20; stats.Kelly == nil || math.Abs(*stats.Kelly-(0.5-0.5/payoff)) > 1e-9 {
+		t.Errorf("Unexpected Kelly fraction %v", stats.Kelly)
+	}
+	if stats.LongestWinStreak != 2 || stats.LongestLossStreak != 3 || stats.CurrentStreak != 1 {
+		t.Errorf("Unexpected streaks %+v", stats)
+	}
+}
@@ -31,7 +31,9 @@ echo ""
 # Step 3: Build Go binary with embedded frontend
 echo -e "${BLUE}Step 3/3: Building Go binary with embedded frontend...${NC}"
 cd backend
-CGO_ENABLED=0 go build -o ../hyperliquid-recon main.go
+COMMIT=$(git rev-parse --short HEAD 2>/dev/null || echo unknown)
+BUILD_TIME=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+CGO_ENABLED=0 go build -ldflags "-X hyperliquid-recon/config.GitCommit=${COMMIT} -X hyperliquid-recon/config.BuildTime=${BUILD_TIME}" -o ../hyperliquid-recon main.go
 cd ..
 echo -e "${GREEN}✓ Binary build complete${NC}"
 echo ""
@@ -1,7 +1,7 @@
 import React, { useState, useEffect, useCallback } from 'react';
 import PnLTable from './components/PnLTable';
 import TimeRangeSelector from './components/TimeRangeSelector';
-import { fetchPnLSummary, triggerRefresh } from './services/api';
+import { fetchPnLSummary, triggerRefresh, subscribeToEvents } from './services/api';
 import { ACCOUNTS, DEFAULT_ACCOUNT, AUTO_REFRESH_INTERVAL_MS, DEFAULT_TIME_RANGE } from './config/config';
 import './App.css';
 
@@ -12,6 +12,7 @@ function App() {
   const [refreshing, setRefreshing] = useState(false);
   const [selectedAccount, setSelectedAccount] = useState(DEFAULT_ACCOUNT);
   const [selectedTimeRange, setSelectedTimeRange] = useState(DEFAULT_TIME_RANGE);
+  const [alerts, setAlerts] = useState([]);
 
   // Fetch and load data for specific account and time range
   const fetchAndLoadData = useCallback(async (account, timeRange) => {
@@ -78,6 +79,18 @@ function App() {
     };
   }, [selectedAccount, selectedTimeRange, fetchAndLoadData]);
 
+  // Show alerts and breaks for the selected account as they are raised
+  useEffect(() => {
+    setAlerts([]);
+    return subscribeToEvents(selectedAccount, (event) => {
+      setAlerts((current) => [event, ...current].slice(0, 5));
+    });
+  }, [selectedAccount]);
+
+  const dismissAlert = (index) => {
+    setAlerts((current) => current.filter((_, i) => i !== index));
+  };
+
   const handleAccountChange = (e) => {
     setSelectedAccount(e.target.value);
   };
@@ -144,6 +157,20 @@ function App() {
         </div>
       </header>
       <main>
+        {data?.degraded && (
+          <div className="degraded-banner">
+            The Hyperliquid API is not responding normally. Figures may be out of date.
+          </div>
+        )}
+        {alerts.map((event, index) => (
+          <div key={`${event.time}-${index}`} className="alert-banner">
+            <span>
+              {event.type === 'break.raised' ? 'New break' : 'Data-quality alert'}:{' '}
+              {event.type === 'break.raised' ? event.data.description : event.data.message}
+            </span>
+            <button onClick={() => dismissAlert(index)} className="alert-dismiss">×</button>
+          </div>
+        ))}
         <PnLTable data={data} loading={loading} error={error} />
       </main>
     </div>
@@ -1,8 +1,14 @@
 // API Configuration
 // Use relative URL for production (embedded), absolute URL for development
 export const API_BASE_URL = process.env.NODE_ENV === 'production'
-  ? '/api'  // Production: relative URL (same server)
-  : 'http://localhost:8080/api';  // Development: absolute URL (CORS)
+  ? '/api/v1'  // Production: relative URL (same server)
+  : 'http://localhost:8080/api/v1';  // Development: absolute URL (CORS)
+
+// WebSocket URL for live alerts and breaks, on the same host as the API
+export const EVENTS_URL = process.env.NODE_ENV === 'production'
+  ? `${window.location.protocol === 'https:' ? 'wss' : 'ws'}://${window.location.host}/api/v1/ws`
+  : 'ws://localhost:8080/api/v1/ws';
+export const EVENTS_RECONNECT_MS = 5000;
 
 // Polling Configuration
 // With incremental caching, we can refresh more frequently without rate limiting
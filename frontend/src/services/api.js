@@ -1,4 +1,4 @@
-import { API_BASE_URL } from '../config/config';
+import { API_BASE_URL, EVENTS_URL, EVENTS_RECONNECT_MS } from '../config/config';
 
 export const fetchPnLSummary = async () => {
   const response = await fetch(`${API_BASE_URL}/pnl`);
@@ -17,4 +17,36 @@ export const triggerRefresh = async (address, days) => {
     throw new Error('Failed to refresh data');
   }
   return response.json();
-};
\ No newline at end of file
+};
+
+// Subscribes to live alert and break events for address, reconnecting if the
+// connection drops. Returns a function that ends the subscription.
+export const subscribeToEvents = (address, onEvent) => {
+  const types = ['dataquality.alert', 'break.raised'];
+  const url = `${EVENTS_URL}?address=${encodeURIComponent(address)}&${types.map((type) => `type=${type}`).join('&')}`;
+  let socket;
+  let reconnect;
+  let closed = false;
+
+  const connect = () => {
+    socket = new WebSocket(url);
+    socket.onmessage = (message) => {
+      const event = JSON.parse(message.data);
+      if (types.includes(event.type)) {
+        onEvent(event);
+      }
+    };
+    socket.onclose = () => {
+      if (!closed) {
+        reconnect = setTimeout(connect, EVENTS_RECONNECT_MS);
+      }
+    };
+  };
+  connect();
+
+  return () => {
+    closed = true;
+    clearTimeout(reconnect);
+    socket.close();
+  };
+};